@@ -0,0 +1,149 @@
+package gocurl
+
+import (
+	"sync"
+	"time"
+)
+
+// TrafficStats counts the requests made to a host and the bytes they
+// transferred. Bytes are those of request and response bodies, so headers
+// are not included; request bodies are counted once per attempt and the
+// bodies of streamed requests of unknown length are not counted.
+type TrafficStats struct {
+	Requests      int64 `json:"requests"`
+	Failures      int64 `json:"failures"`
+	Attempts      int64 `json:"attempts"`
+	BytesSent     int64 `json:"bytes_sent"`
+	BytesReceived int64 `json:"bytes_received"`
+}
+
+func (s *TrafficStats) add(other TrafficStats) {
+	s.Requests += other.Requests
+	s.Failures += other.Failures
+	s.Attempts += other.Attempts
+	s.BytesSent += other.BytesSent
+	s.BytesReceived += other.BytesReceived
+}
+
+// TrafficSnapshot is the traffic counted over a period.
+type TrafficSnapshot struct {
+	Since time.Time               `json:"since"`
+	Until time.Time               `json:"until"`
+	Total TrafficStats            `json:"total"`
+	Hosts map[string]TrafficStats `json:"hosts"`
+}
+
+// Accounting counts the outbound traffic of a Client per host, e.g. to
+// enforce quotas or bill it. It is safe for concurrent use.
+type Accounting struct {
+	mu    sync.Mutex
+	since time.Time
+	hosts map[string]*TrafficStats
+	now   func() time.Time
+
+	stop chan struct{}
+}
+
+// NewAccounting creates an Accounting starting to count now.
+func NewAccounting() *Accounting {
+	a := &Accounting{hosts: map[string]*TrafficStats{}, now: time.Now}
+	a.since = a.now()
+	return a
+}
+
+// SetAccounting counts the traffic of the client with accounting.
+func (c *Client) SetAccounting(accounting *Accounting) *Client {
+	c.accounting = accounting
+	return c
+}
+
+// Snapshot returns the traffic the client counted since its accounting was
+// set or last reset, and an empty snapshot without accounting.
+func (c *Client) Snapshot() TrafficSnapshot {
+	if c.accounting == nil {
+		return TrafficSnapshot{Hosts: map[string]TrafficStats{}}
+	}
+	return c.accounting.Snapshot()
+}
+
+// Record counts a request to host described by result, which failed when
+// failed is set.
+func (a *Accounting) Record(host string, result *Result, failed bool) {
+	stats := TrafficStats{Requests: 1, Attempts: int64(result.Attempts), BytesReceived: result.BytesReceived}
+	if failed {
+		stats.Failures = 1
+	}
+	if result.BytesSent > 0 {
+		stats.BytesSent = result.BytesSent * int64(result.Attempts)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	hostStats, ok := a.hosts[host]
+	if !ok {
+		hostStats = &TrafficStats{}
+		a.hosts[host] = hostStats
+	}
+	hostStats.add(stats)
+}
+
+// Snapshot returns the traffic counted so far.
+func (a *Accounting) Snapshot() TrafficSnapshot {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.snapshot()
+}
+
+// Reset returns the traffic counted so far and starts counting again from
+// zero, e.g. at the end of a billing period.
+func (a *Accounting) Reset() TrafficSnapshot {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	snapshot := a.snapshot()
+	a.hosts = map[string]*TrafficStats{}
+	a.since = snapshot.Until
+	return snapshot
+}
+
+func (a *Accounting) snapshot() TrafficSnapshot {
+	snapshot := TrafficSnapshot{Since: a.since, Until: a.now(), Hosts: make(map[string]TrafficStats, len(a.hosts))}
+	for host, stats := range a.hosts {
+		snapshot.Hosts[host] = *stats
+		snapshot.Total.add(*stats)
+	}
+	return snapshot
+}
+
+// FlushEvery calls flush every interval with the traffic counted since the
+// previous call, resetting the counters, until Stop is called. Only one
+// periodic flush runs at a time: calling FlushEvery again replaces it.
+func (a *Accounting) FlushEvery(interval time.Duration, flush func(TrafficSnapshot)) {
+	a.Stop()
+	stop := make(chan struct{})
+	a.mu.Lock()
+	a.stop = stop
+	a.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				flush(a.Reset())
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic flush started by FlushEvery, if any.
+func (a *Accounting) Stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stop != nil {
+		close(a.stop)
+		a.stop = nil
+	}
+}
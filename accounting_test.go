@@ -0,0 +1,72 @@
+package gocurl_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientAccounting(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			http.Error(w, "no", http.StatusBadRequest)
+			return
+		}
+		io.Copy(io.Discard, r.Body)
+		io.WriteString(w, "0123456789")
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	ctx := context.Background()
+
+	client := gocurl.NewClient()
+	assert.Empty(t, client.Snapshot().Hosts)
+	accounting := gocurl.NewAccounting()
+	client.SetAccounting(accounting)
+
+	_, _, err := client.Curl(ctx, "-s", server.URL+"/")
+	require.NoError(t, err)
+	var result gocurl.Result
+	_, _, err = client.Curl(gocurl.WithResult(ctx, &result), "-s", "-d", "hello", server.URL+"/")
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, result.BytesReceived, "the caller's result is still filled")
+	_, _, err = client.Curl(ctx, "-s", "--fail", server.URL+"/fail")
+	require.Error(t, err)
+
+	snapshot := client.Snapshot()
+	assert.Equal(t, gocurl.TrafficStats{Requests: 3, Failures: 1, Attempts: 3, BytesSent: 5, BytesReceived: 23}, snapshot.Hosts[host])
+	assert.Equal(t, snapshot.Hosts[host], snapshot.Total)
+
+	reset := accounting.Reset()
+	assert.Equal(t, snapshot.Total, reset.Total)
+	assert.Empty(t, accounting.Snapshot().Hosts)
+	assert.Equal(t, reset.Until, accounting.Snapshot().Since)
+
+	t.Run("Periodic flush", func(t *testing.T) {
+		flushed := make(chan gocurl.TrafficSnapshot, 10)
+		accounting.FlushEvery(10*time.Millisecond, func(s gocurl.TrafficSnapshot) { flushed <- s })
+		defer accounting.Stop()
+
+		_, _, err := client.Curl(ctx, "-s", server.URL+"/")
+		require.NoError(t, err)
+		var total int64
+		deadline := time.After(2 * time.Second)
+		for total == 0 {
+			select {
+			case s := <-flushed:
+				total += s.Total.Requests
+			case <-deadline:
+				t.Fatal("no flush")
+			}
+		}
+		assert.EqualValues(t, 1, total)
+	})
+}
@@ -0,0 +1,291 @@
+package gocurl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// altSvcMaxAge is how long an alternative service is used when its Alt-Svc
+// header sets no ma parameter.
+const altSvcMaxAge = 24 * time.Hour
+
+// altSvcTimeLayout is the layout of the expiry times of curl's alt-svc
+// cache file.
+const altSvcTimeLayout = "20060102 15:04:05"
+
+// SetAltSvcStore makes the requests of the client share store, learning
+// the alternative services of origins from the Alt-Svc headers of their
+// responses and connecting to them afterwards, for requests that do not set
+// their own AltSvcStore.
+func (c *Client) SetAltSvcStore(store options.AltSvcStore) *Client {
+	c.altSvc = store
+	return c
+}
+
+// AltSvcFile is an AltSvcStore kept in a file in the format of curl's
+// alt-svc cache, so that gocurl and curl can share it. The file is read
+// for every lookup, which suits command-line use; a long-running process
+// should prefer an options.MemoryAltSvcStore.
+type AltSvcFile struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewAltSvcFile returns the AltSvcStore of the file at path, which is
+// created by the first alternative service saved to it.
+func NewAltSvcFile(path string) *AltSvcFile {
+	return &AltSvcFile{path: path}
+}
+
+// AltServices returns the alternative services of origin saved in the file.
+func (f *AltSvcFile) AltServices(origin string) ([]options.AltService, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	return entries[origin], nil
+}
+
+// SetAltServices replaces the alternative services of origin saved in the
+// file.
+func (f *AltSvcFile) SetAltServices(origin string, services []options.AltService) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries, err := f.read()
+	if err != nil {
+		return err
+	}
+	if len(services) == 0 {
+		delete(entries, origin)
+	} else {
+		entries[origin] = services
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Your alt-svc cache. https://curl.se/docs/alt-svc.html\n")
+	buf.WriteString("# This file was generated by gocurl! Edit at your own risk.\n")
+	for _, origin := range sortedOrigins(entries) {
+		host, port, err := net.SplitHostPort(origin)
+		if err != nil {
+			continue
+		}
+		for _, s := range entries[origin] {
+			persist := 0
+			if s.Persist {
+				persist = 1
+			}
+			// gocurl does not track the protocol of the origin
+			// connection, which curl records first
+			fmt.Fprintf(&buf, "h1 %s %s %s %s %d \"%s\" %d 0\n",
+				altSvcFileHost(host), port, s.Protocol, altSvcFileHost(s.Host), s.Port,
+				s.Expires.UTC().Format(altSvcTimeLayout), persist)
+		}
+	}
+	return os.WriteFile(f.path, buf.Bytes(), 0644)
+}
+
+// read parses the file into the alternative services of each origin. A
+// missing file has none.
+func (f *AltSvcFile) read() (map[string][]options.AltService, error) {
+	entries := map[string][]options.AltService{}
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// src-alpn src-host src-port dst-alpn dst-host dst-port
+		// "YYYYMMDD HH:MM:SS" persist priority
+		fields := strings.Fields(line)
+		if len(fields) < 8 {
+			continue
+		}
+		port, err := strconv.Atoi(fields[5])
+		if err != nil {
+			continue
+		}
+		expires, err := time.Parse(altSvcTimeLayout, strings.Trim(fields[6]+" "+fields[7], `"`))
+		if err != nil {
+			continue
+		}
+		origin := net.JoinHostPort(strings.Trim(fields[1], "[]"), fields[2])
+		entries[origin] = append(entries[origin], options.AltService{
+			Protocol: fields[3],
+			Host:     strings.Trim(fields[4], "[]"),
+			Port:     port,
+			Expires:  expires,
+			Persist:  len(fields) > 8 && fields[8] == "1",
+		})
+	}
+	return entries, scanner.Err()
+}
+
+func altSvcFileHost(host string) string {
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+func sortedOrigins(entries map[string][]options.AltService) []string {
+	origins := make([]string, 0, len(entries))
+	for origin := range entries {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+	return origins
+}
+
+// altSvcStore returns the store of the alternative services of opts, if
+// any.
+func altSvcStore(opts *options.RequestOptions) options.AltSvcStore {
+	if opts.AltSvcStore != nil {
+		return opts.AltSvcStore
+	}
+	if opts.AltSvc != "" {
+		return NewAltSvcFile(opts.AltSvc)
+	}
+	return nil
+}
+
+// altSvcOrigin returns the "host:port" origin of u.
+func altSvcOrigin(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// altSvcRoute is carried by the context of an HTTPS request using
+// alternative services, for its connections to the origin to use them.
+type altSvcRoute struct {
+	store  options.AltSvcStore
+	origin string
+}
+
+// withAltSvc returns the context of a request to u using the alternative
+// services of opts. Like curl, only HTTPS origins use them, as the
+// certificate of the alternative must be valid for the origin.
+func withAltSvc(ctx context.Context, u *url.URL, opts *options.RequestOptions) context.Context {
+	store := altSvcStore(opts)
+	if store == nil || u.Scheme != "https" {
+		return ctx
+	}
+	return context.WithValue(ctx, altSvcKey, &altSvcRoute{store: store, origin: altSvcOrigin(u)})
+}
+
+// dialAltSvc connects to an unexpired alternative service of addr, falling
+// back to addr itself when there is none or it cannot be reached. Only
+// services spoken over TCP are used: HTTP/3 ones are kept but skipped, as
+// net/http does not speak QUIC. The TLS handshake still verifies the
+// certificate of the origin.
+func dialAltSvc(ctx context.Context, network, addr string, dial func(ctx context.Context, network, addr string) (net.Conn, error)) (net.Conn, error) {
+	route, _ := ctx.Value(altSvcKey).(*altSvcRoute)
+	if route == nil || addr != route.origin {
+		return dial(ctx, network, addr)
+	}
+	services, err := route.store.AltServices(addr)
+	if err != nil {
+		return dial(ctx, network, addr)
+	}
+	now := time.Now()
+	for _, s := range services {
+		if (s.Protocol != "h1" && s.Protocol != "h2") || !now.Before(s.Expires) {
+			continue
+		}
+		if conn, err := dial(ctx, network, net.JoinHostPort(s.Host, strconv.Itoa(s.Port))); err == nil {
+			return conn, nil
+		}
+	}
+	return dial(ctx, network, addr)
+}
+
+// recordAltSvc saves the alternative services the response to req
+// advertised to the store of opts.
+func recordAltSvc(req *http.Request, resp *http.Response, opts *options.RequestOptions) error {
+	header := strings.Join(resp.Header.Values("Alt-Svc"), ",")
+	if header == "" || req.URL.Scheme != "https" {
+		return nil
+	}
+	store := altSvcStore(opts)
+	if store == nil {
+		return nil
+	}
+	services := parseAltSvc(header, req.URL.Hostname(), time.Now())
+	if err := store.SetAltServices(altSvcOrigin(req.URL), services); err != nil {
+		return fmt.Errorf("failed to save alternative services: %v", err)
+	}
+	return nil
+}
+
+// parseAltSvc parses an Alt-Svc header into the alternative services it
+// advertises, which replace those known of the origin; "clear" advertises
+// none. Services without a host are on the origin host, and those of
+// unknown protocols are skipped.
+func parseAltSvc(header, originHost string, now time.Time) []options.AltService {
+	var services []options.AltService
+	for _, value := range strings.Split(header, ",") {
+		params := strings.Split(value, ";")
+		protocol, authority, ok := strings.Cut(strings.TrimSpace(params[0]), "=")
+		if !ok {
+			// "clear"
+			continue
+		}
+		switch protocol = strings.ToLower(protocol); protocol {
+		case "http/1.1":
+			protocol = "h1"
+		case "h2", "h3":
+		default:
+			continue
+		}
+		host, portText, err := net.SplitHostPort(strings.Trim(authority, `"`))
+		if err != nil {
+			continue
+		}
+		port, err := strconv.Atoi(portText)
+		if err != nil || port <= 0 || port > 65535 {
+			continue
+		}
+		if host == "" {
+			host = originHost
+		}
+		service := options.AltService{Protocol: protocol, Host: host, Port: port, Expires: now.Add(altSvcMaxAge)}
+		for _, param := range params[1:] {
+			key, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+			val = strings.Trim(val, `"`)
+			switch strings.ToLower(key) {
+			case "ma":
+				if seconds, err := strconv.ParseInt(val, 10, 64); err == nil {
+					service.Expires = now.Add(time.Duration(seconds) * time.Second)
+				}
+			case "persist":
+				service.Persist = val == "1"
+			}
+		}
+		services = append(services, service)
+	}
+	return services
+}
@@ -0,0 +1,95 @@
+package gocurl_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAltSvcServers starts an origin advertising an alternative service
+// on the port of another server, each answering with its name.
+func newAltSvcServers(t *testing.T, protocol string) (origin, alt *httptest.Server) {
+	alt = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "alt")
+	}))
+	t.Cleanup(alt.Close)
+	altURL, _ := url.Parse(alt.URL)
+
+	origin = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Alt-Svc", protocol+`=":`+altURL.Port()+`"; ma=3600`)
+		io.WriteString(w, "origin")
+	}))
+	// Every request needs a new connection
+	origin.Config.SetKeepAlivesEnabled(false)
+	origin.StartTLS()
+	t.Cleanup(origin.Close)
+	return origin, alt
+}
+
+func TestAltSvcFile(t *testing.T) {
+	origin, alt := newAltSvcServers(t, "h2")
+	file := filepath.Join(t.TempDir(), "altsvc.txt")
+	ctx := context.Background()
+
+	_, body, err := gocurl.Curl(ctx, "-s", "-k", "--alt-svc", file, origin.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "origin", body)
+
+	originURL, _ := url.Parse(origin.URL)
+	altURL, _ := url.Parse(alt.URL)
+	saved, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(saved), "h1 127.0.0.1 "+originURL.Port()+" h2 127.0.0.1 "+altURL.Port()+" \"")
+
+	_, body, err = gocurl.Curl(ctx, "-s", "-k", "--alt-svc", file, origin.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "alt", body)
+
+	services, err := gocurl.NewAltSvcFile(file).AltServices(originURL.Host)
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	assert.Equal(t, "h2", services[0].Protocol)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), services[0].Expires, time.Minute)
+}
+
+func TestAltSvcStoreSkipsHTTP3(t *testing.T) {
+	origin, _ := newAltSvcServers(t, "h3")
+	store := options.NewMemoryAltSvcStore()
+	client := gocurl.NewClient().SetAltSvcStore(store)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, body, err := client.Curl(ctx, "-s", "-k", origin.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "origin", body)
+	}
+	originURL, _ := url.Parse(origin.URL)
+	services, err := store.AltServices(originURL.Host)
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	assert.Equal(t, "h3", services[0].Protocol)
+}
+
+func TestAltSvcClientStore(t *testing.T) {
+	origin, _ := newAltSvcServers(t, "http/1.1")
+	client := gocurl.NewClient().SetAltSvcStore(options.NewMemoryAltSvcStore())
+	ctx := context.Background()
+
+	_, body, err := client.Curl(ctx, "-s", "-k", origin.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "origin", body)
+	_, body, err = client.Curl(ctx, "-s", "-k", origin.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "alt", body)
+}
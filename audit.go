@@ -0,0 +1,95 @@
+package gocurl
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/maniartech/gocurl/audit"
+	"github.com/maniartech/gocurl/options"
+	"github.com/maniartech/gocurl/redact"
+)
+
+// auditAttempt prepares req to be audited, counting the bytes of its body.
+// The returned function must be called with the outcome of the attempt; it
+// writes the event right away on failure, or once the response body is
+// closed so that the bytes read and the full duration are known.
+func auditAttempt(req *http.Request, opts *options.RequestOptions, start time.Time) (*http.Request, func(*http.Response, error) *http.Response) {
+	ctx := req.Context()
+	event := audit.Event{
+		Time:      start,
+		Principal: PrincipalFromContext(ctx),
+		Tenant:    TenantFromContext(ctx),
+		RequestID: RequestIDFromContext(ctx),
+		Attempt:   AttemptFromContext(ctx),
+		Method:    req.Method,
+		URL:       redact.Default.URL(req.URL.String()),
+	}
+
+	sent := &countingReader{}
+	if req.Body != nil && req.Body != http.NoBody {
+		sent.r = req.Body
+		req = req.Clone(ctx)
+		req.Body = sent
+	}
+
+	write := func(event audit.Event) {
+		if err := opts.Auditor.Write(event); err != nil && opts.Logger != nil {
+			opts.Logger.WarnContext(ctx, "gocurl audit failed", "error", err)
+		}
+	}
+
+	return req, func(resp *http.Response, err error) *http.Response {
+		event.BytesSent = atomic.LoadInt64(&sent.n)
+		if err != nil || resp.Body == nil {
+			event.Duration = time.Since(start)
+			if err != nil {
+				event.Error = redact.Default.String(err.Error())
+			} else {
+				event.Status = resp.StatusCode
+			}
+			write(event)
+			return resp
+		}
+		event.Status = resp.StatusCode
+		body := &auditBody{countingReader: countingReader{r: resp.Body}}
+		body.done = func() {
+			event.BytesRead = atomic.LoadInt64(&body.n)
+			event.Duration = time.Since(start)
+			write(event)
+		}
+		resp.Body = body
+		return resp
+	}
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	atomic.AddInt64(&c.n, int64(n))
+	return n, err
+}
+
+func (c *countingReader) Close() error {
+	return c.r.Close()
+}
+
+// auditBody is a response body calling done when it is first closed.
+type auditBody struct {
+	countingReader
+	once sync.Once
+	done func()
+}
+
+func (b *auditBody) Close() error {
+	err := b.r.Close()
+	b.once.Do(b.done)
+	return err
+}
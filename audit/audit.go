@@ -0,0 +1,145 @@
+// Package audit writes a trail of the outbound requests made with gocurl,
+// e.g. for compliance when it is used inside services. Events are emitted by
+// gocurl for every request attempt sent with RequestOptions.Auditor set, with
+// the secrets of their URL and error already masked.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Event describes a single request attempt.
+type Event struct {
+	Time      time.Time     `json:"time"`
+	Principal string        `json:"principal,omitempty"`
+	Tenant    string        `json:"tenant,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+	Attempt   int           `json:"attempt,omitempty"`
+	Method    string        `json:"method"`
+	URL       string        `json:"url"`
+	Status    int           `json:"status,omitempty"`
+	BytesSent int64         `json:"bytes_sent"`
+	BytesRead int64         `json:"bytes_read"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// String formats the event as a single line of text.
+func (e Event) String() string {
+	s := fmt.Sprintf("%s %s %s", e.Time.UTC().Format(time.RFC3339Nano), e.Method, e.URL)
+	if e.Error != "" {
+		s += fmt.Sprintf(" error=%q", e.Error)
+	} else {
+		s += fmt.Sprintf(" status=%d", e.Status)
+	}
+	s += fmt.Sprintf(" sent=%d read=%d duration=%s", e.BytesSent, e.BytesRead, e.Duration)
+	if e.Principal != "" {
+		s += fmt.Sprintf(" principal=%q", e.Principal)
+	}
+	if e.Tenant != "" {
+		s += fmt.Sprintf(" tenant=%q", e.Tenant)
+	}
+	if e.RequestID != "" {
+		s += fmt.Sprintf(" request_id=%q", e.RequestID)
+	}
+	if e.Attempt > 1 {
+		s += fmt.Sprintf(" attempt=%d", e.Attempt)
+	}
+	return s
+}
+
+// Sink receives audit events. Write is called from the goroutine that made
+// the request, so implementations must be safe for concurrent use. Its
+// errors are logged by gocurl and do not fail the request.
+type Sink interface {
+	Write(e Event) error
+}
+
+// SinkFunc adapts an ordinary function to the Sink interface.
+type SinkFunc func(e Event) error
+
+// Write calls f(e).
+func (f SinkFunc) Write(e Event) error {
+	return f(e)
+}
+
+// JSONSink writes events to an io.Writer as JSON lines.
+type JSONSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONSink returns a sink writing events to w, one JSON object per line.
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(w)}
+}
+
+// Write encodes e as a line of JSON.
+func (s *JSONSink) Write(e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.enc.Encode(e); err != nil {
+		return fmt.Errorf("failed to write audit event: %v", err)
+	}
+	return nil
+}
+
+// TextSink writes events to an io.Writer in the format of Event.String.
+type TextSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewTextSink returns a sink writing events to w, one per line.
+func NewTextSink(w io.Writer) *TextSink {
+	return &TextSink{w: w}
+}
+
+// Write writes e as a line of text.
+func (s *TextSink) Write(e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := io.WriteString(s.w, e.String()+"\n"); err != nil {
+		return fmt.Errorf("failed to write audit event: %v", err)
+	}
+	return nil
+}
+
+// FileSink appends events to a file as JSON lines.
+type FileSink struct {
+	*JSONSink
+	file *os.File
+}
+
+// OpenFile opens path for appending, creating it with mode 0600 if needed,
+// and returns a sink writing JSON lines to it.
+func OpenFile(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	return &FileSink{JSONSink: NewJSONSink(file), file: file}, nil
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// MultiSink writes events to every sink, returning the first error.
+func MultiSink(sinks ...Sink) Sink {
+	return SinkFunc(func(e Event) error {
+		var first error
+		for _, sink := range sinks {
+			if err := sink.Write(e); err != nil && first == nil {
+				first = err
+			}
+		}
+		return first
+	})
+}
@@ -0,0 +1,109 @@
+package audit_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl/audit"
+)
+
+func testEvent() audit.Event {
+	return audit.Event{
+		Time:      time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Principal: "alice",
+		Method:    "POST",
+		URL:       "https://api.example.com/orders",
+		Status:    201,
+		BytesSent: 12,
+		BytesRead: 34,
+		Duration:  150 * time.Millisecond,
+	}
+}
+
+func TestJSONSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := audit.NewJSONSink(&buf)
+	for i := 0; i < 2; i++ {
+		if err := sink.Write(testEvent()); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	var got audit.Event
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", lines[0], err)
+	}
+	if got != testEvent() {
+		t.Errorf("decoded %+v, want %+v", got, testEvent())
+	}
+}
+
+func TestTextSink(t *testing.T) {
+	var buf bytes.Buffer
+	if err := audit.NewTextSink(&buf).Write(testEvent()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := `2024-05-01T12:00:00Z POST https://api.example.com/orders status=201 sent=12 read=34 duration=150ms principal="alice"` + "\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	failed := audit.Event{Method: "GET", URL: "https://example.com", Error: "connection refused", Attempt: 2}
+	if s := failed.String(); !strings.Contains(s, `error="connection refused"`) || !strings.HasSuffix(s, "attempt=2") {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	for i := 0; i < 2; i++ {
+		sink, err := audit.OpenFile(path)
+		if err != nil {
+			t.Fatalf("OpenFile() error = %v", err)
+		}
+		if err := sink.Write(testEvent()); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		sink.Close()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 2 {
+		t.Errorf("file has %d lines, want 2 as it is appended to", n)
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm() != 0o600 {
+		t.Errorf("file mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestMultiSink(t *testing.T) {
+	var got []string
+	record := func(name string, err error) audit.Sink {
+		return audit.SinkFunc(func(e audit.Event) error {
+			got = append(got, name)
+			return err
+		})
+	}
+	failure := errors.New("sink down")
+
+	err := audit.MultiSink(record("a", nil), record("b", failure), record("c", nil)).Write(testEvent())
+	if err != failure {
+		t.Errorf("Write() error = %v, want %v", err, failure)
+	}
+	if strings.Join(got, ",") != "a,b,c" {
+		t.Errorf("sinks written = %v, want all of them", got)
+	}
+}
@@ -0,0 +1,47 @@
+//go:build !windows && !plan9
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+)
+
+// SyslogSink sends events to syslog as JSON messages.
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink connects to the syslog daemon at raddr over network, or to
+// the local daemon when network is empty, and returns a sink logging events
+// with the given tag at the info level of the facility.
+func NewSyslogSink(network, raddr string, facility syslog.Priority, tag string) (*SyslogSink, error) {
+	w, err := syslog.Dial(network, raddr, facility|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %v", err)
+	}
+	return &SyslogSink{w: w}, nil
+}
+
+// Write logs e. Failed requests are logged at the warning level.
+func (s *SyslogSink) Write(e Event) error {
+	msg, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit event: %v", err)
+	}
+	if e.Error != "" {
+		err = s.w.Warning(string(msg))
+	} else {
+		err = s.w.Info(string(msg))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write audit event: %v", err)
+	}
+	return nil
+}
+
+// Close closes the connection to syslog.
+func (s *SyslogSink) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,97 @@
+package gocurl_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/audit"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// auditTrail collects audit events.
+type auditTrail struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (a *auditTrail) Write(e audit.Event) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, e)
+	return nil
+}
+
+func TestAuditor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))
+	defer server.Close()
+
+	t.Run("Events", func(t *testing.T) {
+		trail := &auditTrail{}
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(server.URL + "/orders?token=s3cr3t").
+			SetMethod("POST").
+			SetBody(`{"id":1}`).
+			SetAuditor(trail).
+			Build()
+
+		ctx := gocurl.WithPrincipal(gocurl.WithTenant(context.Background(), "acme"), "alice")
+		_, body, err := gocurl.Process(ctx, opts)
+		require.NoError(t, err)
+		assert.Equal(t, "created", body)
+
+		require.Len(t, trail.events, 1)
+		event := trail.events[0]
+		assert.Equal(t, "alice", event.Principal)
+		assert.Equal(t, "acme", event.Tenant)
+		assert.Equal(t, 1, event.Attempt)
+		assert.Equal(t, "POST", event.Method)
+		assert.Equal(t, server.URL+"/orders?token=****", event.URL)
+		assert.Equal(t, http.StatusCreated, event.Status)
+		assert.EqualValues(t, 8, event.BytesSent)
+		assert.EqualValues(t, 7, event.BytesRead)
+		assert.False(t, event.Time.IsZero())
+		assert.Positive(t, event.Duration)
+	})
+
+	t.Run("Failures", func(t *testing.T) {
+		trail := &auditTrail{}
+		opts := options.NewRequestOptionsBuilder().
+			SetURL("http://127.0.0.1:1/").
+			SetAuditor(trail).
+			Build()
+
+		_, _, err := gocurl.Process(context.Background(), opts)
+		require.Error(t, err)
+		require.Len(t, trail.events, 1)
+		assert.Zero(t, trail.events[0].Status)
+		assert.True(t, strings.Contains(trail.events[0].Error, "refused"), trail.events[0].Error)
+	})
+
+	t.Run("Retries", func(t *testing.T) {
+		trail := &auditTrail{}
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(server.URL).
+			SetRetryConfig(&options.RetryConfig{MaxRetries: 2, RetryOnHTTP: []int{http.StatusCreated}}).
+			SetAuditor(trail).
+			Build()
+
+		_, _, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+		require.Len(t, trail.events, 3)
+		for i, event := range trail.events {
+			assert.Equal(t, i+1, event.Attempt)
+		}
+	})
+}
@@ -0,0 +1,206 @@
+package gocurl
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// BenchConfig controls a load test run by Bench.
+type BenchConfig struct {
+	// Requests is the total number of requests to send. When zero, requests
+	// are sent until Duration elapses.
+	Requests int
+
+	// Concurrency is the number of requests in flight at once (default 1)
+	Concurrency int
+
+	// Duration bounds the run; zero means no time limit
+	Duration time.Duration
+}
+
+// LatencyStats summarizes the latencies of successful requests.
+type LatencyStats struct {
+	Min  time.Duration `json:"min"`
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P95  time.Duration `json:"p95"`
+	P99  time.Duration `json:"p99"`
+	Max  time.Duration `json:"max"`
+}
+
+// BenchResult is the outcome of a load test.
+type BenchResult struct {
+	Requests   int            `json:"requests"`
+	Succeeded  int            `json:"succeeded"`
+	Failed     int            `json:"failed"`
+	Duration   time.Duration  `json:"duration"`
+	Throughput float64        `json:"throughput"` // requests per second
+	Bytes      int64          `json:"bytes"`
+	Latency    LatencyStats   `json:"latency"`
+	StatusCode map[int]int    `json:"status_codes"`
+	Errors     map[string]int `json:"errors,omitempty"`
+}
+
+// String renders the result as a human readable report.
+func (r *BenchResult) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Requests:    %d (%d succeeded, %d failed)\n", r.Requests, r.Succeeded, r.Failed)
+	fmt.Fprintf(&sb, "Duration:    %v\n", r.Duration.Round(time.Millisecond))
+	fmt.Fprintf(&sb, "Throughput:  %.2f req/s\n", r.Throughput)
+	fmt.Fprintf(&sb, "Transferred: %d bytes\n", r.Bytes)
+	fmt.Fprintf(&sb, "Latency:     min %v, mean %v, max %v\n", r.Latency.Min, r.Latency.Mean, r.Latency.Max)
+	fmt.Fprintf(&sb, "             p50 %v, p90 %v, p95 %v, p99 %v\n", r.Latency.P50, r.Latency.P90, r.Latency.P95, r.Latency.P99)
+
+	codes := make([]int, 0, len(r.StatusCode))
+	for code := range r.StatusCode {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Fprintf(&sb, "Status %d:  %d\n", code, r.StatusCode[code])
+	}
+
+	messages := make([]string, 0, len(r.Errors))
+	for msg := range r.Errors {
+		messages = append(messages, msg)
+	}
+	sort.Strings(messages)
+	for _, msg := range messages {
+		fmt.Fprintf(&sb, "Error:       %s (%d)\n", msg, r.Errors[msg])
+	}
+	return sb.String()
+}
+
+// Bench sends opts repeatedly with the configured concurrency through a
+// single shared HTTP client and reports latency percentiles, throughput and
+// a breakdown of status codes and errors. Response bodies are discarded.
+func Bench(ctx context.Context, opts *options.RequestOptions, config BenchConfig) (*BenchResult, error) {
+	if config.Requests <= 0 && config.Duration <= 0 {
+		return nil, fmt.Errorf("bench requires a request count or a duration")
+	}
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	opts = opts.Clone()
+	opts.Silent = true
+	opts.OutputFile = ""
+	if err := ValidateOptions(opts); err != nil {
+		return nil, err
+	}
+	client, err := CreateHTTPClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	transport := client.Transport
+	if decoding, ok := transport.(*decodingTransport); ok {
+		transport = decoding.next
+	}
+	if transport, ok := transport.(*http.Transport); ok {
+		// Keep a warm connection per worker instead of the default two
+		transport.MaxIdleConnsPerHost = concurrency
+	}
+
+	if config.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Duration)
+		defer cancel()
+	}
+
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		issued    int64
+		wg        sync.WaitGroup
+	)
+	result := &BenchResult{StatusCode: map[int]int{}, Errors: map[string]int{}}
+
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if config.Requests > 0 && atomic.AddInt64(&issued, 1) > int64(config.Requests) {
+					return
+				}
+
+				began := time.Now()
+				resp, body, err := processWithClient(ctx, client, opts)
+				elapsed := time.Since(began)
+
+				// Requests cut short by the end of a timed run are not counted
+				if err != nil && ctx.Err() != nil && config.Duration > 0 {
+					return
+				}
+
+				mu.Lock()
+				result.Requests++
+				if err != nil {
+					result.Failed++
+					result.Errors[err.Error()]++
+				} else {
+					result.Succeeded++
+					result.StatusCode[resp.StatusCode]++
+					result.Bytes += bodySize(resp, body)
+					resp.Body.Close()
+					latencies = append(latencies, elapsed)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	result.Duration = time.Since(start)
+	if result.Duration > 0 {
+		result.Throughput = float64(result.Requests) / result.Duration.Seconds()
+	}
+	result.Latency = latencyStats(latencies)
+
+	if config.Duration == 0 && ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	return result, nil
+}
+
+func latencyStats(latencies []time.Duration) LatencyStats {
+	if len(latencies) == 0 {
+		return LatencyStats{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+	return LatencyStats{
+		Min:  latencies[0],
+		Mean: total / time.Duration(len(latencies)),
+		P50:  percentile(latencies, 50),
+		P90:  percentile(latencies, 90),
+		P95:  percentile(latencies, 95),
+		P99:  percentile(latencies, 99),
+		Max:  latencies[len(latencies)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile p of sorted.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,63 @@
+package gocurl_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBench(t *testing.T) {
+	var hits int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&hits, 1)%10 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	opts := options.NewRequestOptionsBuilder().SetURL(server.URL).Build()
+
+	t.Run("Fixed request count", func(t *testing.T) {
+		result, err := gocurl.Bench(context.Background(), opts, gocurl.BenchConfig{Requests: 100, Concurrency: 8})
+		require.NoError(t, err)
+
+		assert.Equal(t, 100, result.Requests)
+		assert.Equal(t, 100, result.Succeeded)
+		assert.Equal(t, map[int]int{200: 90, 503: 10}, result.StatusCode)
+		assert.Equal(t, int64(200), result.Bytes)
+		assert.Greater(t, result.Throughput, 0.0)
+		assert.LessOrEqual(t, result.Latency.Min, result.Latency.P50)
+		assert.LessOrEqual(t, result.Latency.P50, result.Latency.P99)
+		assert.LessOrEqual(t, result.Latency.P99, result.Latency.Max)
+		assert.Contains(t, result.String(), "Status 503:  10")
+	})
+
+	t.Run("Timed run", func(t *testing.T) {
+		result, err := gocurl.Bench(context.Background(), opts, gocurl.BenchConfig{Duration: 50 * time.Millisecond, Concurrency: 2})
+		require.NoError(t, err)
+		assert.Greater(t, result.Requests, 0)
+		assert.Zero(t, result.Failed)
+	})
+
+	t.Run("Errors are broken down", func(t *testing.T) {
+		failing := options.NewRequestOptionsBuilder().SetURL("http://127.0.0.1:1").Build()
+		result, err := gocurl.Bench(context.Background(), failing, gocurl.BenchConfig{Requests: 5, Concurrency: 2})
+		require.NoError(t, err)
+		assert.Equal(t, 5, result.Failed)
+		assert.Len(t, result.Errors, 1)
+	})
+
+	t.Run("Invalid config", func(t *testing.T) {
+		_, err := gocurl.Bench(context.Background(), opts, gocurl.BenchConfig{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,136 @@
+package gocurl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// CurlString executes the curl command and returns the response body as a
+// string without printing it. A body spooled to disk (see SetSpoolThreshold)
+// is returned empty and read from resp.Body instead.
+func CurlString(ctx context.Context, command ...string) (*http.Response, string, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, "", err
+	}
+	opts.Silent = true
+
+	return Process(ctx, opts)
+}
+
+// CurlBytes executes the curl command and returns the response body. The
+// body is read into a pooled buffer and copied out once at its final size.
+// A body spooled to disk (see SetSpoolThreshold) is returned empty and read
+// from resp.Body instead, which removes its file when closed.
+func CurlBytes(ctx context.Context, command ...string) (*http.Response, []byte, error) {
+	buf := bodyBufferPool.Get().(*bytes.Buffer)
+	defer putBodyBuffer(buf)
+	buf.Reset()
+
+	resp, err := curlInto(ctx, buf, command)
+	body := append([]byte(nil), buf.Bytes()...)
+	if resp != nil && !isSpooled(resp) {
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return resp, body, err
+}
+
+// CurlBytesInto executes the curl command and reads the response body into
+// buf, which is grown only if it is too small. The returned slice shares
+// buf's memory when it fits, so callers fetching many responses can reuse
+// one buffer and avoid per-call allocations. Spooled bodies are left in
+// resp.Body as with CurlBytes.
+func CurlBytesInto(ctx context.Context, buf []byte, command ...string) (*http.Response, []byte, error) {
+	b := bytes.NewBuffer(buf[:0])
+
+	resp, err := curlInto(ctx, b, command)
+	body := b.Bytes()
+	if resp != nil && !isSpooled(resp) {
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return resp, body, err
+}
+
+// curlInto parses and executes command, appending the response body to buf.
+// A spooled body is left on disk as resp.Body instead.
+func curlInto(ctx context.Context, buf *bytes.Buffer, command []string) (*http.Response, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, err
+	}
+
+	return processInto(ctx, opts, buf)
+}
+
+// processInto is Process reading the response body into buf, or leaving it
+// spooled on disk as resp.Body when it exceeds opts.SpoolThreshold. The body
+// is only written out when an output file is set.
+func processInto(ctx context.Context, opts *options.RequestOptions, buf *bytes.Buffer) (*http.Response, error) {
+	opts = withDefaults(ctx, opts)
+	if err := ValidateOptions(opts); err != nil {
+		return nil, err
+	}
+	client, err := CreateHTTPClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := executeInto(ctx, client, opts, buf)
+	if opts.OutputFile == "" || resp == nil {
+		return resp, err
+	}
+	if err != nil && !(opts.FailWithBody && errorKind(err) == KindHTTP) {
+		return resp, err
+	}
+	httpErr := err
+
+	if spool, ok := resp.Body.(*spooledBody); ok {
+		err = spool.output(opts)
+	} else {
+		err = HandleOutput(buf.String(), opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, httpErr
+}
+
+// outputPath returns the file the response body is written to: OutputFile,
+// relative to OutputDir when set. With CreateDirs, the missing directories
+// of the path are created.
+func outputPath(opts *options.RequestOptions) (string, error) {
+	path := opts.OutputFile
+	if opts.OutputDir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(opts.OutputDir, path)
+	}
+	if opts.CreateDirs {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", fmt.Errorf("failed to create output directory: %v", err)
+		}
+	}
+	return path, nil
+}
+
+// writeOutputFile streams r to path.
+func writeOutputFile(path string, r io.Reader) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write response to file: %v", err)
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write response to file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write response to file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package gocurl_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurlBodies(t *testing.T) {
+	payload := strings.Repeat("gocurl", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(payload))
+	}))
+	defer server.Close()
+
+	t.Run("CurlString", func(t *testing.T) {
+		_, body, err := gocurl.CurlString(context.Background(), "curl", server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, payload, body)
+	})
+
+	t.Run("CurlBytes", func(t *testing.T) {
+		resp, body, err := gocurl.CurlBytes(context.Background(), "curl "+server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, payload, string(body))
+
+		// The response body is readable again
+		again, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, body, again)
+
+		_, other, err := gocurl.CurlBytes(context.Background(), "curl "+server.URL)
+		require.NoError(t, err)
+		other[0] = 'X'
+		assert.Equal(t, byte('g'), body[0], "results must not share pooled memory")
+	})
+
+	t.Run("CurlBytesInto reuses the buffer", func(t *testing.T) {
+		buf := make([]byte, 0, 1024)
+		_, body, err := gocurl.CurlBytesInto(context.Background(), buf, "curl", server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, payload, string(body))
+		assert.Equal(t, &buf[:1][0], &body[0])
+	})
+
+	t.Run("CurlBytesInto grows small buffers", func(t *testing.T) {
+		_, body, err := gocurl.CurlBytesInto(context.Background(), make([]byte, 4), "curl", server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, payload, string(body))
+	})
+
+	t.Run("Output directory", func(t *testing.T) {
+		dir := t.TempDir()
+		_, _, err := gocurl.CurlString(context.Background(), "curl", "-o", "a/b/out.txt", "--output-dir", dir, server.URL)
+		assert.Equal(t, 23, gocurl.ExitCode(err), "missing directories are not created by default")
+
+		_, _, err = gocurl.CurlString(context.Background(), "curl", "-o", "a/b/out.txt", "--output-dir", dir, "--create-dirs", server.URL)
+		require.NoError(t, err)
+		content, err := ioutil.ReadFile(filepath.Join(dir, "a", "b", "out.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, payload, string(content))
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, _, err := gocurl.CurlBytes(context.Background(), "curl http://127.0.0.1:1")
+		assert.Error(t, err)
+	})
+}
+
+func BenchmarkCurlBytesInto(b *testing.B) {
+	server := newBenchmarkServer(b)
+	buf := make([]byte, 0, 4096)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, body, err := gocurl.CurlBytesInto(context.Background(), buf, "curl", server.URL)
+		if err != nil {
+			b.Fatal(err)
+		}
+		buf = body[:0]
+	}
+}
@@ -0,0 +1,87 @@
+package gocurl
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// BodyCompression compresses the JSON request bodies of a Client, such as
+// those set with the JSON builder method, that exceed a size, sending them
+// with a Content-Encoding. Servers rarely accept compressed requests, so
+// only the hosts known to do so are sent compressed bodies.
+type BodyCompression struct {
+	// Threshold is the size in bytes above which bodies are compressed,
+	// 1 KiB by default
+	Threshold int
+	// Hosts are the hosts accepting compressed bodies, matched like the
+	// hosts of a Policy: "example.com", "*.example.com" or "10.0.0.0/8"
+	Hosts []string
+	// Encoding is the Content-Encoding of compressed bodies, "gzip" by
+	// default. Bodies are sent uncompressed when it is not registered with
+	// RegisterEncoding.
+	Encoding string
+}
+
+// SetBodyCompression compresses the large JSON request bodies the client
+// sends to the hosts of compression.
+func (c *Client) SetBodyCompression(compression *BodyCompression) *Client {
+	c.compression = compression
+	return c
+}
+
+// compress returns opts with its body compressed when it is a JSON body
+// over the threshold sent to an allowed host, and opts itself otherwise.
+func (b *BodyCompression) compress(opts *options.RequestOptions) (*options.RequestOptions, error) {
+	threshold := b.Threshold
+	if threshold == 0 {
+		threshold = 1024
+	}
+	if len(opts.Body) <= threshold || opts.Headers.Get("Content-Encoding") != "" || !isJSON(opts.Headers.Get("Content-Type")) {
+		return opts, nil
+	}
+	u, err := url.Parse(opts.URL)
+	if err != nil || !matchHosts(b.Hosts, u.Hostname()) {
+		return opts, nil
+	}
+
+	encoding := b.Encoding
+	if encoding == "" {
+		encoding = "gzip"
+	}
+	codec, ok := codecFor(encoding)
+	if !ok {
+		return opts, nil
+	}
+
+	var buf bytes.Buffer
+	w, err := codec.NewWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, opts.Body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	opts = opts.Clone()
+	opts.Body = buf.String()
+	if opts.Headers == nil {
+		opts.Headers = http.Header{}
+	}
+	opts.Headers.Set("Content-Encoding", encoding)
+	return opts, nil
+}
+
+// isJSON reports whether contentType is application/json or a +json type.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
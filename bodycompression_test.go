@@ -0,0 +1,67 @@
+package gocurl_test
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyCompression(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := io.Reader(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body = gz
+		}
+		data, _ := io.ReadAll(body)
+		io.WriteString(w, r.Header.Get("Content-Encoding")+"|"+string(data))
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	events := make([]map[string]string, 100)
+	for i := range events {
+		events[i] = map[string]string{"event": "page_view"}
+	}
+	large := options.NewRequestOptionsBuilder().SetURL(server.URL).SetMethod("POST").JSON(events).Build()
+	small := options.NewRequestOptionsBuilder().SetURL(server.URL).SetMethod("POST").JSON(events[:1]).Build()
+	text := options.NewRequestOptionsBuilder().SetURL(server.URL).SetMethod("POST").SetBody(large.Body).Build()
+
+	client := gocurl.NewClient().SetBodyCompression(&gocurl.BodyCompression{Hosts: []string{"127.0.0.1"}})
+	_, body, err := client.Process(ctx, large)
+	require.NoError(t, err)
+	assert.Equal(t, "gzip|"+large.Body, body)
+	assert.Empty(t, large.Headers.Get("Content-Encoding"), "the caller's options are left untouched")
+	assert.NotContains(t, client.LastCommand(), "Content-Encoding", "the command replays the plain body")
+
+	_, body, err = client.Process(ctx, small)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(body, "|"), "small bodies are sent as is")
+
+	_, body, err = client.Process(ctx, text)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(body, "|"), "only JSON bodies are compressed")
+
+	other := gocurl.NewClient().SetBodyCompression(&gocurl.BodyCompression{Hosts: []string{"*.example.com"}})
+	_, body, err = other.Process(ctx, large)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(body, "|"), "hosts not known to accept it get plain bodies")
+
+	unknown := gocurl.NewClient().SetBodyCompression(&gocurl.BodyCompression{Hosts: []string{"127.0.0.1"}, Encoding: "zstd"})
+	_, body, err = unknown.Process(ctx, large)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(body, "|"), "encodings without an encoder are not used")
+}
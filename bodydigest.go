@@ -0,0 +1,76 @@
+package gocurl
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// setBodyDigest sets the header carrying the digest of the body of req.
+// Bodies that can be read twice are hashed through GetBody; streamed ones
+// are hashed while they are sent, the digest following them as a trailer.
+func setBodyDigest(req *http.Request, algo options.BodyDigest) error {
+	var newHash func() hash.Hash
+	header, prefix := "Digest", string(algo)+"="
+	switch algo {
+	case options.BodyDigestMD5:
+		newHash, header, prefix = md5.New, "Content-MD5", ""
+	case options.BodyDigestSHA256:
+		newHash = sha256.New
+	case options.BodyDigestSHA512:
+		newHash = sha512.New
+	default:
+		return fmt.Errorf("unsupported body digest %q", algo)
+	}
+
+	h := newHash()
+	if req.Body == nil || req.Body == http.NoBody {
+		req.Header.Set(header, prefix+base64.StdEncoding.EncodeToString(h.Sum(nil)))
+		return nil
+	}
+	if req.GetBody == nil {
+		req.Header.Del(header)
+		req.Trailer = http.Header{header: nil}
+		req.ContentLength = -1
+		req.Body = &digestBody{ReadCloser: req.Body, hash: h, trailer: req.Trailer, header: header, prefix: prefix}
+		return nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return fmt.Errorf("failed to hash request body: %v", err)
+	}
+	_, err = io.Copy(h, body)
+	body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to hash request body: %v", err)
+	}
+	req.Header.Set(header, prefix+base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	return nil
+}
+
+// digestBody hashes a streamed request body as it is sent, setting its
+// digest in the request trailer once it is read.
+type digestBody struct {
+	io.ReadCloser
+	hash    hash.Hash
+	trailer http.Header
+	header  string
+	prefix  string
+}
+
+func (b *digestBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.hash.Write(p[:n])
+	if err == io.EOF {
+		b.trailer.Set(b.header, b.prefix+base64.StdEncoding.EncodeToString(b.hash.Sum(nil)))
+	}
+	return n, err
+}
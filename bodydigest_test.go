@@ -0,0 +1,99 @@
+package gocurl_test
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyDigest(t *testing.T) {
+	var header, trailer http.Header
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		header, trailer, body = r.Header.Clone(), r.Trailer.Clone(), string(data)
+	}))
+	defer server.Close()
+
+	payload := `{"amount":"10.00"}`
+	sha := sha256.Sum256([]byte(payload))
+	md := md5.Sum([]byte(payload))
+	ctx := context.Background()
+
+	t.Run("Digest header, signed", func(t *testing.T) {
+		var signed string
+		opts := options.NewRequestOptionsBuilder().
+			POST(server.URL, payload, http.Header{}).
+			SetSilent(true).
+			SetBodyDigest(options.BodyDigestSHA256).
+			SetSigner(options.SignerFunc(func(req *http.Request, bodyHash string) error {
+				signed = req.Header.Get("Digest")
+				return nil
+			})).
+			Build()
+		_, _, err := gocurl.Process(ctx, opts)
+		require.NoError(t, err)
+		want := "sha-256=" + base64.StdEncoding.EncodeToString(sha[:])
+		assert.Equal(t, want, header.Get("Digest"))
+		assert.Equal(t, want, signed)
+		assert.Equal(t, payload, body)
+	})
+
+	t.Run("Content-MD5", func(t *testing.T) {
+		opts := options.NewRequestOptionsBuilder().
+			PUT(server.URL, payload, http.Header{}).
+			SetSilent(true).
+			SetBodyDigest(options.BodyDigestMD5).
+			Build()
+		_, _, err := gocurl.Process(ctx, opts)
+		require.NoError(t, err)
+		assert.Equal(t, base64.StdEncoding.EncodeToString(md[:]), header.Get("Content-MD5"))
+	})
+
+	t.Run("Streamed body", func(t *testing.T) {
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(server.URL).
+			SetMethod("POST").
+			SetSilent(true).
+			SetBodyReader(io.MultiReader(strings.NewReader(payload[:5]), strings.NewReader(payload[5:]))).
+			SetBodyDigest(options.BodyDigestSHA256).
+			Build()
+		_, _, err := gocurl.Process(ctx, opts)
+		require.NoError(t, err)
+		assert.Empty(t, header.Get("Digest"))
+		assert.Equal(t, "sha-256="+base64.StdEncoding.EncodeToString(sha[:]), trailer.Get("Digest"))
+		assert.Equal(t, payload, body)
+	})
+
+	t.Run("Empty body", func(t *testing.T) {
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(server.URL).
+			SetSilent(true).
+			SetBodyDigest(options.BodyDigestSHA256).
+			Build()
+		_, _, err := gocurl.Process(ctx, opts)
+		require.NoError(t, err)
+		assert.Equal(t, "sha-256=47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=", header.Get("Digest"))
+	})
+
+	t.Run("Unsupported algorithm", func(t *testing.T) {
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(server.URL).
+			SetSilent(true).
+			SetBodyDigest("crc32").
+			Build()
+		_, _, err := gocurl.Process(ctx, opts)
+		assert.ErrorContains(t, err, `unsupported body digest "crc32"`)
+	})
+}
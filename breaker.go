@@ -0,0 +1,191 @@
+package gocurl
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is matched (via errors.Is) by the errors returned for
+// requests short-circuited by an open CircuitBreaker.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError is returned when a request is rejected because the circuit
+// for its host is open.
+type CircuitOpenError struct {
+	Host    string
+	RetryAt time.Time // When the circuit lets a probe request through
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is open for %s until %s", e.Host, e.RetryAt.Format(time.RFC3339))
+}
+
+// Is reports whether target is ErrCircuitOpen.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitState is the state of the circuit for a single host.
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects all requests until the cooldown elapses.
+	CircuitOpen
+	// CircuitHalfOpen lets a single probe request through.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// CircuitBreaker tracks consecutive failures per host. After Threshold
+// consecutive failures the circuit opens and requests to that host fail fast
+// with a *CircuitOpenError for Cooldown. Then a single probe request is let
+// through: if it succeeds the circuit closes, otherwise it opens again.
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	// OnStateChange, if set, is called whenever the circuit of a host changes
+	// state. It is called synchronously, outside of the breaker's lock.
+	OnStateChange func(host string, from, to CircuitState)
+
+	mu    sync.Mutex
+	hosts map[string]*circuit
+	now   func() time.Time
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker creates a CircuitBreaker that opens after threshold
+// consecutive failures and stays open for cooldown.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{
+		Threshold: threshold,
+		Cooldown:  cooldown,
+		hosts:     make(map[string]*circuit),
+	}
+}
+
+// Allow reports whether a request to host may proceed. It returns a
+// *CircuitOpenError when the circuit is open.
+func (cb *CircuitBreaker) Allow(host string) error {
+	cb.mu.Lock()
+	c := cb.circuit(host)
+
+	var changed bool
+	if c.state == CircuitOpen && !cb.clock().Before(c.openedAt.Add(cb.Cooldown)) {
+		c.state = CircuitHalfOpen
+		c.probing = false
+		changed = true
+	}
+
+	var err error
+	switch c.state {
+	case CircuitOpen:
+		err = &CircuitOpenError{Host: host, RetryAt: c.openedAt.Add(cb.Cooldown)}
+	case CircuitHalfOpen:
+		if c.probing {
+			err = &CircuitOpenError{Host: host, RetryAt: cb.clock()}
+		} else {
+			c.probing = true
+		}
+	}
+	cb.mu.Unlock()
+
+	if changed {
+		cb.notify(host, CircuitOpen, CircuitHalfOpen)
+	}
+	return err
+}
+
+// Record reports the outcome of a request to host.
+func (cb *CircuitBreaker) Record(host string, success bool) {
+	cb.mu.Lock()
+	c := cb.circuit(host)
+	from := c.state
+
+	if success {
+		c.failures = 0
+		c.state = CircuitClosed
+	} else {
+		c.failures++
+		if c.state == CircuitHalfOpen || c.failures >= cb.Threshold {
+			c.state = CircuitOpen
+			c.openedAt = cb.clock()
+		}
+	}
+	c.probing = false
+	to := c.state
+	cb.mu.Unlock()
+
+	if from != to {
+		cb.notify(host, from, to)
+	}
+}
+
+// State returns the current state of the circuit for host.
+func (cb *CircuitBreaker) State(host string) CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.circuit(host).state
+}
+
+// Reset closes the circuit for host.
+func (cb *CircuitBreaker) Reset(host string) {
+	cb.mu.Lock()
+	c := cb.circuit(host)
+	from := c.state
+	*c = circuit{}
+	cb.mu.Unlock()
+
+	if from != CircuitClosed {
+		cb.notify(host, from, CircuitClosed)
+	}
+}
+
+func (cb *CircuitBreaker) circuit(host string) *circuit {
+	if cb.hosts == nil {
+		cb.hosts = make(map[string]*circuit)
+	}
+	c, ok := cb.hosts[host]
+	if !ok {
+		c = &circuit{}
+		cb.hosts[host] = c
+	}
+	return c
+}
+
+func (cb *CircuitBreaker) clock() time.Time {
+	if cb.now != nil {
+		return cb.now()
+	}
+	return time.Now()
+}
+
+func (cb *CircuitBreaker) notify(host string, from, to CircuitState) {
+	if cb.OnStateChange != nil {
+		cb.OnStateChange(host, from, to)
+	}
+}
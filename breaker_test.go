@@ -0,0 +1,103 @@
+package gocurl_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Run("Opens after consecutive failures and recovers after cooldown", func(t *testing.T) {
+		var healthy atomic.Bool
+		var hits atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			if !healthy.Load() {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		}))
+		defer server.Close()
+
+		var transitions []string
+		breaker := gocurl.NewCircuitBreaker(2, 50*time.Millisecond)
+		breaker.OnStateChange = func(host string, from, to gocurl.CircuitState) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		}
+		client := gocurl.NewClient().SetCircuitBreaker(breaker)
+		opts := &options.RequestOptions{URL: server.URL, Silent: true}
+
+		for i := 0; i < 2; i++ {
+			_, _, err := client.Process(context.Background(), opts)
+			require.NoError(t, err)
+		}
+
+		_, _, err := client.Process(context.Background(), opts)
+		assert.True(t, errors.Is(err, gocurl.ErrCircuitOpen))
+		var openErr *gocurl.CircuitOpenError
+		require.True(t, errors.As(err, &openErr))
+		assert.Equal(t, server.Listener.Addr().String(), openErr.Host)
+		assert.EqualValues(t, 2, hits.Load(), "open circuit must not reach the server")
+
+		time.Sleep(60 * time.Millisecond)
+		healthy.Store(true)
+
+		resp, _, err := client.Process(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, gocurl.CircuitClosed, breaker.State(openErr.Host))
+		assert.Equal(t, []string{"closed->open", "open->half-open", "half-open->closed"}, transitions)
+	})
+
+	t.Run("Failed probe reopens the circuit", func(t *testing.T) {
+		breaker := gocurl.NewCircuitBreaker(1, 10*time.Millisecond)
+		breaker.Record("api.example.com", false)
+		assert.Equal(t, gocurl.CircuitOpen, breaker.State("api.example.com"))
+
+		time.Sleep(15 * time.Millisecond)
+		require.NoError(t, breaker.Allow("api.example.com"))
+		assert.ErrorIs(t, breaker.Allow("api.example.com"), gocurl.ErrCircuitOpen, "only one probe is allowed")
+
+		breaker.Record("api.example.com", false)
+		assert.Equal(t, gocurl.CircuitOpen, breaker.State("api.example.com"))
+		assert.Equal(t, gocurl.CircuitClosed, breaker.State("other.example.com"))
+	})
+
+	t.Run("Probe is not taken by requests failing to start", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+		host := server.Listener.Addr().String()
+
+		breaker := gocurl.NewCircuitBreaker(1, 50*time.Millisecond)
+		client := gocurl.NewClient().SetCircuitBreaker(breaker).SetRateLimit(0.5, 1)
+		opts := &options.RequestOptions{URL: server.URL, Silent: true}
+		_, _, err := client.Process(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, gocurl.CircuitOpen, breaker.State(host))
+		time.Sleep(60 * time.Millisecond)
+
+		// The rate limiter gives up before the breaker lets the probe through
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, _, err = client.Process(ctx, opts)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.NoError(t, breaker.Allow(host), "the probe must still be available")
+	})
+
+	t.Run("Zero value", func(t *testing.T) {
+		breaker := &gocurl.CircuitBreaker{Threshold: 1, Cooldown: time.Minute}
+		require.NoError(t, breaker.Allow("api"))
+		breaker.Record("api", false)
+		assert.ErrorIs(t, breaker.Allow("api"), gocurl.ErrCircuitOpen)
+	})
+}
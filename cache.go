@@ -0,0 +1,353 @@
+package gocurl
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// ResponseCache caches the responses to the GET and HEAD requests of a
+// Client, following their Cache-Control and Expires headers. Fresh
+// responses are served without a network call, and stale ones with an ETag
+// or Last-Modified validator are revalidated with a conditional request.
+//
+// In stale-while-revalidate mode, a stale response is served at once while
+// it is refreshed in the background, for as long as its
+// stale-while-revalidate directive or StaleWhileRevalidate allow, bounded
+// by MaxStale. It suits latency-sensitive dashboards that can show data
+// slightly out of date.
+//
+// Requests are cached under the key SetCoalescing matches them by, so the
+// requests it never coalesces, such as those authenticated by interceptors
+// or a client certificate, are not cached either.
+//
+// The cache holds at most MaxEntries responses and MaxBytes of bodies,
+// evicting the least recently used ones. Responses past their freshness
+// and stale window are dropped unless they have a validator to revalidate
+// them with. It is safe for concurrent use.
+type ResponseCache struct {
+	// MaxEntries bounds the number of cached responses; zero means
+	// DefaultCacheEntries
+	MaxEntries int
+	// MaxBytes bounds the total size of the cached bodies; zero means no
+	// bound
+	MaxBytes int64
+	// StaleWhileRevalidate is how long past its freshness a response is
+	// served while refreshed in the background, for responses without a
+	// stale-while-revalidate directive of their own. Zero serves them
+	// only as their directive allows.
+	StaleWhileRevalidate time.Duration
+	// MaxStale bounds the staleness of the responses served while
+	// refreshed in the background, whatever their directive; zero means
+	// no bound
+	MaxStale time.Duration
+	// OnRefresh is called when a background refresh lands fresh data,
+	// with the request that was refreshed and its new response
+	OnRefresh func(opts *options.RequestOptions, resp *http.Response, body string)
+
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	order      *list.List // Most recently used first
+	size       int64
+	refreshing map[string]bool
+}
+
+// DefaultCacheEntries is the number of responses a ResponseCache holds when
+// MaxEntries is zero.
+const DefaultCacheEntries = 1000
+
+type cacheEntry struct {
+	key      string
+	resp     *http.Response
+	body     string
+	stored   time.Time
+	fresh    time.Duration
+	swr      time.Duration
+	etag     string
+	modified string
+}
+
+// NewResponseCache creates an empty ResponseCache.
+func NewResponseCache() *ResponseCache {
+	return &ResponseCache{}
+}
+
+// SetCache makes the client cache the responses of its requests in cache.
+func (c *Client) SetCache(cache *ResponseCache) *Client {
+	c.cache = cache
+	return c
+}
+
+// Flush removes every cached response.
+func (rc *ResponseCache) Flush() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.entries, rc.order, rc.size = nil, nil, 0
+}
+
+// Len returns the number of cached responses.
+func (rc *ResponseCache) Len() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.entries)
+}
+
+// lookup returns the entry of key, if any, and its age. Expired entries
+// are dropped unless offline, which serves them however stale they are.
+func (rc *ResponseCache) lookup(key string, offline bool) (*cacheEntry, time.Duration) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	elem := rc.entries[key]
+	if elem == nil {
+		return nil, 0
+	}
+	entry := elem.Value.(*cacheEntry)
+	now := time.Now()
+	if !offline && rc.expired(entry, now) {
+		rc.removeElement(elem)
+		return nil, 0
+	}
+	rc.order.MoveToFront(elem)
+	return entry, now.Sub(entry.stored)
+}
+
+// store caches resp and body for key when their headers allow it, or
+// refreshes entry when resp is a 304 revalidating it. It returns the
+// response to serve and its body.
+func (rc *ResponseCache) store(key string, entry *cacheEntry, resp *http.Response, body string) (*http.Response, string) {
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		revalidated := copyResponse(entry.resp, entry.body)
+		for name, values := range resp.Header {
+			revalidated.Header[name] = values
+		}
+		updated := newCacheEntry(revalidated, entry.body, time.Now())
+		if updated == nil {
+			rc.remove(key)
+			return copyResponse(entry.resp, entry.body), entry.body
+		}
+		rc.put(key, updated)
+		return copyResponse(updated.resp, updated.body), updated.body
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, body
+	}
+	if updated := newCacheEntry(resp, body, time.Now()); updated != nil {
+		rc.put(key, updated)
+	} else {
+		rc.remove(key)
+	}
+	return resp, body
+}
+
+func (rc *ResponseCache) put(key string, entry *cacheEntry) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.entries == nil {
+		rc.entries, rc.order = map[string]*list.Element{}, list.New()
+	}
+	if elem := rc.entries[key]; elem != nil {
+		rc.removeElement(elem)
+	}
+	entry.key = key
+	rc.entries[key] = rc.order.PushFront(entry)
+	rc.size += int64(len(entry.body))
+	rc.evict()
+}
+
+func (rc *ResponseCache) remove(key string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if elem := rc.entries[key]; elem != nil {
+		rc.removeElement(elem)
+	}
+}
+
+func (rc *ResponseCache) removeElement(elem *list.Element) {
+	entry := rc.order.Remove(elem).(*cacheEntry)
+	delete(rc.entries, entry.key)
+	rc.size -= int64(len(entry.body))
+}
+
+// evict drops the expired entries once the cache is over its bounds, then
+// the least recently used ones until it is within them.
+func (rc *ResponseCache) evict() {
+	maxEntries := rc.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = DefaultCacheEntries
+	}
+	over := func() bool {
+		return rc.order.Len() > maxEntries || rc.MaxBytes > 0 && rc.size > rc.MaxBytes
+	}
+	if !over() {
+		return
+	}
+	now := time.Now()
+	for elem := rc.order.Front(); elem != nil; {
+		next := elem.Next()
+		if rc.expired(elem.Value.(*cacheEntry), now) {
+			rc.removeElement(elem)
+		}
+		elem = next
+	}
+	for over() && rc.order.Len() > 0 {
+		rc.removeElement(rc.order.Back())
+	}
+}
+
+// expired reports whether entry is past its freshness and stale window
+// without a validator to revalidate it with.
+func (rc *ResponseCache) expired(entry *cacheEntry, now time.Time) bool {
+	if entry.etag != "" || entry.modified != "" {
+		return false
+	}
+	return now.Sub(entry.stored) >= entry.fresh+rc.staleWindow(entry)
+}
+
+// startRefresh reports whether the caller should refresh key in the
+// background, as no other refresh of it is running.
+func (rc *ResponseCache) startRefresh(key string) bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.refreshing[key] {
+		return false
+	}
+	if rc.refreshing == nil {
+		rc.refreshing = map[string]bool{}
+	}
+	rc.refreshing[key] = true
+	return true
+}
+
+func (rc *ResponseCache) endRefresh(key string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	delete(rc.refreshing, key)
+}
+
+// servesStale reports whether entry, of age, may be served while it is
+// refreshed in the background.
+func (rc *ResponseCache) servesStale(entry *cacheEntry, age time.Duration) bool {
+	return age < entry.fresh+rc.staleWindow(entry)
+}
+
+// staleWindow returns how long past its freshness entry is served while it
+// is refreshed in the background.
+func (rc *ResponseCache) staleWindow(entry *cacheEntry) time.Duration {
+	window := entry.swr
+	if window == 0 {
+		window = rc.StaleWhileRevalidate
+	}
+	if rc.MaxStale > 0 && window > rc.MaxStale {
+		window = rc.MaxStale
+	}
+	return window
+}
+
+// newCacheEntry returns the cache entry of resp, or nil when it must not be
+// cached: it is marked no-store, or has neither a lifetime nor a
+// validator to revalidate it with.
+func newCacheEntry(resp *http.Response, body string, now time.Time) *cacheEntry {
+	entry := &cacheEntry{
+		resp:     copyResponse(resp, body),
+		body:     body,
+		stored:   now,
+		etag:     resp.Header.Get("ETag"),
+		modified: resp.Header.Get("Last-Modified"),
+	}
+	lifetime := false
+	for _, directive := range strings.Split(strings.Join(resp.Header.Values("Cache-Control"), ","), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+		switch strings.ToLower(name) {
+		case "no-store":
+			return nil
+		case "no-cache":
+			entry.fresh, lifetime = 0, true
+		case "max-age":
+			if err == nil && !lifetime {
+				entry.fresh, lifetime = time.Duration(seconds)*time.Second, true
+			}
+		case "stale-while-revalidate":
+			if err == nil {
+				entry.swr = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	if !lifetime {
+		if expires, err := http.ParseTime(resp.Header.Get("Expires")); err == nil {
+			date, err := http.ParseTime(resp.Header.Get("Date"))
+			if err != nil {
+				date = now
+			}
+			entry.fresh, lifetime = expires.Sub(date), true
+		}
+	}
+	if age, err := strconv.Atoi(resp.Header.Get("Age")); err == nil {
+		entry.stored = now.Add(-time.Duration(age) * time.Second)
+	}
+	if !lifetime && entry.etag == "" && entry.modified == "" {
+		return nil
+	}
+	return entry
+}
+
+// cached serves the request of opts from the client's cache, sending it
+// with fetch when there is no usable response.
+func (c *Client) cached(ctx context.Context, opts *options.RequestOptions, fetch func(context.Context, *options.RequestOptions) (*http.Response, string, error)) (*http.Response, string, error) {
+	rc := c.cache
+	key := coalesceKey(opts)
+	offline := c.isOffline()
+	entry, age := rc.lookup(key, offline)
+	if entry != nil && (age < entry.fresh || offline) {
+		return copyResponse(entry.resp, entry.body), entry.body, nil
+	}
+	if entry != nil && rc.servesStale(entry, age) {
+		if rc.startRefresh(key) {
+			// The refresh outlives the request serving the stale response
+			ctx := context.WithoutCancel(ctx)
+			go func() {
+				defer rc.endRefresh(key)
+				resp, body, err := fetch(ctx, conditional(opts, entry))
+				if err != nil {
+					return
+				}
+				resp, body = rc.store(key, entry, resp, body)
+				if rc.OnRefresh != nil && resp.StatusCode == http.StatusOK {
+					rc.OnRefresh(opts, resp, body)
+				}
+			}()
+		}
+		return copyResponse(entry.resp, entry.body), entry.body, nil
+	}
+
+	resp, body, err := fetch(ctx, conditional(opts, entry))
+	if err != nil {
+		return resp, body, err
+	}
+	resp, body = rc.store(key, entry, resp, body)
+	return resp, body, nil
+}
+
+// conditional returns opts revalidating entry, if it has validators.
+func conditional(opts *options.RequestOptions, entry *cacheEntry) *options.RequestOptions {
+	if entry == nil || (entry.etag == "" && entry.modified == "") {
+		return opts
+	}
+	opts = opts.Clone()
+	if opts.Headers == nil {
+		opts.Headers = http.Header{}
+	}
+	if entry.etag != "" && opts.Headers.Get("If-None-Match") == "" {
+		opts.Headers.Set("If-None-Match", entry.etag)
+	}
+	if entry.modified != "" && opts.Headers.Get("If-Modified-Since") == "" {
+		opts.Headers.Set("If-Modified-Since", entry.modified)
+	}
+	return opts
+}
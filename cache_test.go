@@ -0,0 +1,219 @@
+package gocurl_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/middlewares"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCache(t *testing.T) {
+	var hits, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/validated":
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				atomic.AddInt32(&notModified, 1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/nostore":
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+		}
+		fmt.Fprintf(w, "response %d", n)
+	}))
+	defer server.Close()
+	cache := gocurl.NewResponseCache()
+	client := gocurl.NewClient().SetCache(cache)
+	ctx := context.Background()
+
+	get := func(path string) string {
+		resp, body, err := client.Curl(ctx, "-s", server.URL+path)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		return body
+	}
+
+	assert.Equal(t, "response 1", get("/fresh"))
+	assert.Equal(t, "response 1", get("/fresh"))
+	assert.EqualValues(t, 1, hits)
+
+	assert.Equal(t, "response 2", get("/validated"))
+	assert.Equal(t, "response 2", get("/validated"))
+	assert.EqualValues(t, 3, hits)
+	assert.EqualValues(t, 1, notModified)
+
+	assert.Equal(t, "response 4", get("/nostore"))
+	assert.Equal(t, "response 5", get("/nostore"))
+	assert.Equal(t, 2, cache.Len())
+
+	// Requests with a body are not cached
+	_, body, err := client.Curl(ctx, "-s", "-d", "x", server.URL+"/fresh")
+	require.NoError(t, err)
+	assert.Equal(t, "response 6", body)
+
+	cache.Flush()
+	assert.Equal(t, "response 7", get("/fresh"))
+}
+
+func TestResponseCacheIdentity(t *testing.T) {
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			fmt.Fprintf(w, "%s data for %s", name, r.Header.Get("Authorization"))
+		}
+	}
+	server := httptest.NewServer(handler("origin"))
+	defer server.Close()
+	proxy := httptest.NewServer(handler("proxy"))
+	defer proxy.Close()
+	client := gocurl.NewClient().SetCache(gocurl.NewResponseCache())
+	ctx := context.Background()
+
+	// Credentials set by interceptors are not part of the key, so their
+	// responses are not cached for the next caller
+	get := func(tenant string) string {
+		opts := &options.RequestOptions{URL: server.URL, Silent: true}
+		opts.Interceptors = []middlewares.Interceptor{func(req *http.Request, next middlewares.RoundTripFunc) (*http.Response, error) {
+			req.Header.Set("Authorization", tenant)
+			return next(req)
+		}}
+		_, body, err := client.Process(ctx, opts)
+		require.NoError(t, err)
+		return body
+	}
+	assert.Equal(t, "origin data for tenant-A", get("tenant-A"))
+	assert.Equal(t, "origin data for tenant-B", get("tenant-B"))
+
+	// Nor are the responses of a proxy served for direct requests
+	_, body, err := client.Curl(ctx, "-s", "-x", proxy.URL, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "proxy data for ", body)
+	_, body, err = client.Curl(ctx, "-s", server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "origin data for ", body)
+}
+
+func TestResponseCacheStaleWhileRevalidate(t *testing.T) {
+	var hits int32
+	release := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		if n > 1 {
+			<-release
+		}
+		w.Header().Set("Cache-Control", "max-age=0, stale-while-revalidate=60")
+		fmt.Fprintf(w, "response %d", n)
+	}))
+	defer server.Close()
+
+	refreshed := make(chan string, 10)
+	cache := gocurl.NewResponseCache()
+	cache.OnRefresh = func(opts *options.RequestOptions, resp *http.Response, body string) {
+		refreshed <- body
+	}
+	client := gocurl.NewClient().SetCache(cache)
+	ctx := context.Background()
+
+	_, body, err := client.Curl(ctx, "-s", server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "response 1", body)
+
+	// Stale responses are served at once, with a single refresh running
+	for i := 0; i < 3; i++ {
+		_, body, err = client.Curl(ctx, "-s", server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "response 1", body)
+	}
+	release <- struct{}{}
+	select {
+	case body = <-refreshed:
+		assert.Equal(t, "response 2", body)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no refresh")
+	}
+	assert.EqualValues(t, 2, atomic.LoadInt32(&hits))
+
+	// MaxStale bounds the staleness served
+	cache.MaxStale = time.Nanosecond
+	release <- struct{}{}
+	_, body, err = client.Curl(ctx, "-s", server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "response 3", body)
+}
+
+func TestResponseCacheBounds(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		if r.URL.Path == "/expiring" {
+			w.Header().Set("Cache-Control", "max-age=1")
+		} else {
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+		fmt.Fprintf(w, "response %d", n)
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	t.Run("Least recently used entries are evicted", func(t *testing.T) {
+		cache := gocurl.NewResponseCache()
+		cache.MaxEntries = 2
+		client := gocurl.NewClient().SetCache(cache)
+		get := func(path string) string {
+			_, body, err := client.Curl(ctx, "-s", server.URL+path)
+			require.NoError(t, err)
+			return body
+		}
+
+		a, b := get("/a"), get("/b")
+		assert.Equal(t, a, get("/a"))
+		get("/c")
+		assert.Equal(t, 2, cache.Len())
+		assert.Equal(t, a, get("/a"))
+		assert.NotEqual(t, b, get("/b"))
+	})
+
+	t.Run("Bodies are bounded in size", func(t *testing.T) {
+		cache := gocurl.NewResponseCache()
+		cache.MaxBytes = int64(len("response 10")) + 1
+		client := gocurl.NewClient().SetCache(cache)
+		for _, path := range []string{"/x", "/y"} {
+			_, _, err := client.Curl(ctx, "-s", server.URL+path)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 1, cache.Len())
+	})
+
+	t.Run("Expired entries are dropped first", func(t *testing.T) {
+		cache := gocurl.NewResponseCache()
+		cache.MaxEntries = 2
+		client := gocurl.NewClient().SetCache(cache)
+		get := func(path string) string {
+			_, body, err := client.Curl(ctx, "-s", server.URL+path)
+			require.NoError(t, err)
+			return body
+		}
+
+		a := get("/a")
+		get("/expiring")
+		time.Sleep(1100 * time.Millisecond)
+		get("/c")
+		assert.Equal(t, 2, cache.Len())
+		assert.Equal(t, a, get("/a"))
+	})
+}
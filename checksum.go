@@ -0,0 +1,186 @@
+package gocurl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrChecksumMismatch is matched (via errors.Is) by the errors returned for
+// files whose checksum differs from the expected one.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ChecksumError reports a file whose checksum differs from the expected one.
+type ChecksumError struct {
+	Path      string
+	Algorithm ChecksumFormat
+	Expected  string
+	Actual    string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s: %s is %s, expected %s", e.Path, e.Algorithm, e.Actual, e.Expected)
+}
+
+// Is makes errors.Is(err, ErrChecksumMismatch) match checksum errors.
+func (e *ChecksumError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
+
+// ChecksumFormat is the hash algorithm of a checksum manifest.
+type ChecksumFormat string
+
+const (
+	// ChecksumAuto detects the algorithm of every checksum from its length
+	ChecksumAuto ChecksumFormat = ""
+	// ChecksumSHA256 is the format of SHA256SUMS files and sha256sum
+	ChecksumSHA256 ChecksumFormat = "sha256"
+	// ChecksumSHA384 is the format of SHA384SUMS files and sha384sum
+	ChecksumSHA384 ChecksumFormat = "sha384"
+	// ChecksumSHA512 is the format of SHA512SUMS files and sha512sum
+	ChecksumSHA512 ChecksumFormat = "sha512"
+)
+
+// checksumHashes are the hashes of the checksum formats.
+var checksumHashes = map[ChecksumFormat]func() hash.Hash{
+	ChecksumSHA256: sha256.New,
+	ChecksumSHA384: sha512.New384,
+	ChecksumSHA512: sha512.New,
+}
+
+// VerifyFromManifest checks the file at path, typically just downloaded,
+// against its entry in the checksum manifest at manifestURL, such as the
+// SHA256SUMS file published next to release artifacts. The entry is the
+// one named like the base name of path. Manifests can be in the format of
+// sha256sum and its siblings, including their binary mode "*name" entries,
+// or the BSD "SHA256 (name) = digest" format of shasum --tag.
+//
+// A file whose checksum differs returns a *ChecksumError. manifestURL may
+// also be a curl command, for manifests needing authentication.
+func VerifyFromManifest(ctx context.Context, path, manifestURL string, format ChecksumFormat) error {
+	resp, manifest, err := CurlBytes(ctx, manifestURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch checksum manifest: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to fetch checksum manifest: %s", resp.Status)
+	}
+	if manifest, err = loadSpooledBytes(resp, manifest); err != nil {
+		return fmt.Errorf("failed to fetch checksum manifest: %v", err)
+	}
+	return verifyManifest(path, manifest, format)
+}
+
+// VerifyChecksum checks the file at path against expected, the hex digest
+// of the format's algorithm, returning a *ChecksumError when it differs.
+// ChecksumAuto detects the algorithm from the length of expected.
+func VerifyChecksum(path string, format ChecksumFormat, expected string) error {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	if format == ChecksumAuto {
+		format = checksumFormatOf(expected)
+	}
+	newHash, ok := checksumHashes[format]
+	if !ok {
+		return fmt.Errorf("unsupported checksum %q", expected)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to verify checksum: %v", err)
+	}
+	defer file.Close()
+	h := newHash()
+	if _, err := io.Copy(h, file); err != nil {
+		return fmt.Errorf("failed to verify checksum: %v", err)
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != expected {
+		return &ChecksumError{Path: path, Algorithm: format, Expected: expected, Actual: actual}
+	}
+	return nil
+}
+
+// verifyManifest checks the file at path against its entry in manifest.
+func verifyManifest(path string, manifest []byte, format ChecksumFormat) error {
+	name := filepath.Base(path)
+	scanner := bufio.NewScanner(bytes.NewReader(manifest))
+	for scanner.Scan() {
+		algorithm, digest, file, ok := parseChecksumLine(scanner.Text())
+		if !ok || entryBase(file) != name {
+			continue
+		}
+		switch {
+		case algorithm == ChecksumAuto:
+			algorithm = format
+		case format != ChecksumAuto && algorithm != format:
+			return fmt.Errorf("checksum manifest has a %s checksum for %s, expected %s", algorithm, name, format)
+		}
+		return VerifyChecksum(path, algorithm, digest)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read checksum manifest: %v", err)
+	}
+	return fmt.Errorf("checksum manifest has no entry for %s", name)
+}
+
+// parseChecksumLine parses a "digest  name" line of sha256sum or a
+// "SHA256 (name) = digest" line of shasum --tag. algorithm is only set by
+// the latter.
+func parseChecksumLine(line string) (algorithm ChecksumFormat, digest, name string, ok bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", "", false
+	}
+
+	// BSD format, as written by shasum --tag
+	if open := strings.Index(line, " ("); open > 0 {
+		if end := strings.LastIndex(line, ") = "); end > open {
+			algorithm = ChecksumFormat(strings.ToLower(strings.ReplaceAll(line[:open], "-", "")))
+			return algorithm, line[end+4:], line[open+2 : end], true
+		}
+	}
+
+	// Names with a backslash or newline are escaped, and the line marked
+	// with a leading backslash
+	escaped := strings.HasPrefix(line, "\\")
+	line = strings.TrimPrefix(line, "\\")
+	digest, name, found := strings.Cut(line, " ")
+	if !found {
+		return "", "", "", false
+	}
+	// Text mode entries are separated by two spaces, binary ones by " *"
+	name = strings.TrimPrefix(strings.TrimPrefix(name, " "), "*")
+	if escaped {
+		name = strings.NewReplacer(`\\`, `\`, `\n`, "\n").Replace(name)
+	}
+	return ChecksumAuto, digest, name, true
+}
+
+// checksumFormatOf returns the format of a hex digest from its length.
+func checksumFormatOf(digest string) ChecksumFormat {
+	switch len(digest) {
+	case sha256.Size * 2:
+		return ChecksumSHA256
+	case sha512.Size384 * 2:
+		return ChecksumSHA384
+	case sha512.Size * 2:
+		return ChecksumSHA512
+	}
+	return ChecksumAuto
+}
+
+// entryBase returns the base name of a manifest entry, whose paths are
+// separated by backslashes when written on Windows.
+func entryBase(name string) string {
+	return path.Base(strings.ReplaceAll(name, `\`, "/"))
+}
@@ -0,0 +1,67 @@
+package gocurl_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyFromManifest(t *testing.T) {
+	dir := t.TempDir()
+	artifact := filepath.Join(dir, "tool_linux_amd64.tar.gz")
+	require.NoError(t, os.WriteFile(artifact, []byte("artifact"), 0644))
+	sum256 := sha256.Sum256([]byte("artifact"))
+	sum512 := sha512.Sum512([]byte("artifact"))
+	digest256, digest512 := hex.EncodeToString(sum256[:]), hex.EncodeToString(sum512[:])
+
+	manifests := map[string]string{
+		"/SHA256SUMS": "0000000000000000000000000000000000000000000000000000000000000000  tool_darwin_arm64.tar.gz\n" +
+			digest256 + "  tool_linux_amd64.tar.gz\n",
+		"/binary":   digest256 + " *dist/tool_linux_amd64.tar.gz\n",
+		"/bsd":      fmt.Sprintf("SHA512 (tool_linux_amd64.tar.gz) = %s\n", digest512),
+		"/tampered": "1111111111111111111111111111111111111111111111111111111111111111  tool_linux_amd64.tar.gz\n",
+		"/other":    digest256 + "  tool_windows_amd64.zip\n",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		manifest, ok := manifests[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, manifest)
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	assert.NoError(t, gocurl.VerifyFromManifest(ctx, artifact, server.URL+"/SHA256SUMS", gocurl.ChecksumSHA256))
+	assert.NoError(t, gocurl.VerifyFromManifest(ctx, artifact, server.URL+"/binary", gocurl.ChecksumAuto))
+	assert.NoError(t, gocurl.VerifyFromManifest(ctx, artifact, server.URL+"/bsd", gocurl.ChecksumAuto))
+
+	err := gocurl.VerifyFromManifest(ctx, artifact, server.URL+"/tampered", gocurl.ChecksumSHA256)
+	assert.True(t, errors.Is(err, gocurl.ErrChecksumMismatch))
+	var checksumErr *gocurl.ChecksumError
+	require.True(t, errors.As(err, &checksumErr))
+	assert.Equal(t, digest256, checksumErr.Actual)
+
+	assert.ErrorContains(t, gocurl.VerifyFromManifest(ctx, artifact, server.URL+"/bsd", gocurl.ChecksumSHA256), "sha512 checksum")
+	assert.ErrorContains(t, gocurl.VerifyFromManifest(ctx, artifact, server.URL+"/other", gocurl.ChecksumSHA256), "no entry")
+	assert.ErrorContains(t, gocurl.VerifyFromManifest(ctx, artifact, server.URL+"/missing", gocurl.ChecksumSHA256), "404")
+
+	t.Run("VerifyChecksum", func(t *testing.T) {
+		assert.NoError(t, gocurl.VerifyChecksum(artifact, gocurl.ChecksumAuto, digest512))
+		assert.True(t, errors.Is(gocurl.VerifyChecksum(artifact, gocurl.ChecksumSHA512, digest256), gocurl.ErrChecksumMismatch))
+		assert.Error(t, gocurl.VerifyChecksum(artifact, gocurl.ChecksumAuto, "abc"))
+	})
+}
@@ -0,0 +1,357 @@
+package gocurl
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/maniartech/gocurl/options"
+	"github.com/maniartech/gocurl/record"
+)
+
+// Client executes requests like Process while keeping state that is shared
+// across calls, such as pooled connections, per-host circuit breakers and
+// rate limiters. The zero value is ready to use.
+type Client struct {
+	breaker     *CircuitBreaker
+	limiter     *RateLimiter
+	adaptLimit  bool
+	concurrency *ConcurrencyLimiter
+	retryBudget *RetryBudget
+	accounting  *Accounting
+	dns         *DNSCache
+	compression *BodyCompression
+	tlsSessions tls.ClientSessionCache
+	altSvc      options.AltSvcStore
+
+	mu          sync.Mutex
+	serializer  options.Serializer
+	serializers map[string]options.Serializer
+	transports  map[transportKey]http.RoundTripper
+	clients     map[clientKey]*http.Client
+	lastCommand string
+	filters     []ResponseFilter
+	routes      []Route
+	coalescing  bool
+	cache       *ResponseCache
+	inflight    map[string]*coalescedCall
+	offline     bool
+	recordings  *record.Stub
+}
+
+// NewClient creates a new Client.
+func NewClient() *Client {
+	return &Client{}
+}
+
+// SetCircuitBreaker enables per-host circuit breaking for the client.
+func (c *Client) SetCircuitBreaker(breaker *CircuitBreaker) *Client {
+	c.breaker = breaker
+	return c
+}
+
+// SetRateLimit limits the client to requestsPerSecond per host, allowing
+// bursts of up to burst requests. Requests over the limit wait (respecting
+// their context) rather than fail.
+func (c *Client) SetRateLimit(requestsPerSecond float64, burst int) *Client {
+	c.limiter = NewRateLimiter(requestsPerSecond, burst)
+	c.limiter.AdaptToHeaders = c.adaptLimit
+	return c
+}
+
+// SetRateLimitHeaders makes the rate limiter adapt to X-RateLimit-Remaining
+// and X-RateLimit-Reset response headers. The setting also applies to rate
+// limiters installed later.
+func (c *Client) SetRateLimitHeaders(enabled bool) *Client {
+	c.adaptLimit = enabled
+	if c.limiter != nil {
+		c.limiter.AdaptToHeaders = enabled
+	}
+	return c
+}
+
+// SetRateLimiter installs a custom configured RateLimiter, e.g. one keyed
+// per endpoint instead of per host.
+func (c *Client) SetRateLimiter(limiter *RateLimiter) *Client {
+	if limiter != nil && c.adaptLimit {
+		limiter.AdaptToHeaders = true
+	}
+	c.limiter = limiter
+	return c
+}
+
+// Curl parses the curl command and executes it through the client.
+func (c *Client) Curl(ctx context.Context, command ...string) (*http.Response, string, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, "", err
+	}
+	return c.Process(ctx, opts)
+}
+
+// Process executes the request described by opts through the client.
+func (c *Client) Process(ctx context.Context, opts *options.RequestOptions) (*http.Response, string, error) {
+	return c.process(ctx, nil, opts)
+}
+
+// LastCommand returns the curl command equivalent to the last request the
+// client started, as formatted by RequestOptions.ToCurlCommand, or an empty
+// string before the first request. It is recorded whether or not the
+// request succeeded, so failures can be reproduced outside of Go.
+func (c *Client) LastCommand() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastCommand
+}
+
+// process runs opts through the client's layers. A nil httpClient builds one
+// from opts, as Process does.
+func (c *Client) process(ctx context.Context, httpClient *http.Client, opts *options.RequestOptions) (*http.Response, string, error) {
+	opts = withDefaults(ctx, c.route(opts))
+	if c.dns != nil {
+		ctx = WithDNSCache(ctx, c.dns)
+	}
+	if c.tlsSessions != nil && opts.TLSSessionCache == nil {
+		opts = opts.Clone()
+		opts.TLSSessionCache = c.tlsSessions
+	}
+	if c.altSvc != nil && opts.AltSvcStore == nil {
+		opts = opts.Clone()
+		opts.AltSvcStore = c.altSvc
+	}
+	command := opts.ToCurlCommand()
+	c.mu.Lock()
+	c.lastCommand = command
+	c.mu.Unlock()
+
+	if c.compression != nil {
+		var err error
+		if opts, err = c.compression.compress(opts); err != nil {
+			return nil, "", fmt.Errorf("failed to compress request body: %v", err)
+		}
+	}
+
+	if c.cache != nil && coalescable(opts) {
+		return c.cached(ctx, opts, func(ctx context.Context, opts *options.RequestOptions) (*http.Response, string, error) {
+			return c.fetch(ctx, httpClient, opts)
+		})
+	}
+	return c.fetch(ctx, httpClient, opts)
+}
+
+// fetch sends opts over the network, sharing the call of an identical
+// request in flight when the client coalesces them.
+func (c *Client) fetch(ctx context.Context, httpClient *http.Client, opts *options.RequestOptions) (*http.Response, string, error) {
+	if c.coalescing && coalescable(opts) {
+		return c.coalesce(ctx, opts, func() (*http.Response, string, error) {
+			return c.dispatch(ctx, httpClient, opts)
+		})
+	}
+	return c.dispatch(ctx, httpClient, opts)
+}
+
+// dispatch sends opts through the client's circuit breaker, rate limiter,
+// concurrency limit, retry budget, accounting and response filters.
+func (c *Client) dispatch(ctx context.Context, httpClient *http.Client, opts *options.RequestOptions) (*http.Response, string, error) {
+	var limitKey string
+	if c.limiter != nil {
+		limitKey = c.limiter.Key(opts.URL)
+		if err := c.limiter.Wait(ctx, limitKey); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if c.concurrency != nil {
+		if err := c.concurrency.Acquire(ctx); err != nil {
+			return nil, "", err
+		}
+		defer c.concurrency.Release()
+	}
+
+	// The breaker admits the request last, as a probe it lets through must
+	// be recorded and nothing may fail it before it is sent
+	var host string
+	if c.breaker != nil {
+		host = requestHost(opts.URL)
+		if err := c.breaker.Allow(host); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if c.retryBudget != nil {
+		c.retryBudget.Request()
+		ctx = context.WithValue(ctx, retryBudgetKey, c.retryBudget)
+	}
+
+	// Accounting needs the result of the request, which is shared with
+	// the caller's own
+	var result, outer *Result
+	if c.accounting != nil {
+		outer, _ = ctx.Value(resultKey).(*Result)
+		result = &Result{}
+		ctx = WithResult(ctx, result)
+	}
+
+	resp, body, err := c.execute(ctx, httpClient, opts)
+
+	if c.accounting != nil {
+		c.accounting.Record(requestHost(opts.URL), result, err != nil)
+		if outer != nil {
+			*outer = *result
+		}
+	}
+
+	if c.breaker != nil {
+		c.breaker.Record(host, err == nil && resp.StatusCode < 500)
+	}
+
+	if c.limiter != nil && err == nil {
+		c.limiter.Observe(limitKey, resp.Header)
+	}
+
+	if resp != nil {
+		var filterErr error
+		if body, filterErr = c.filter(resp, body); filterErr != nil && err == nil {
+			err = filterErr
+		}
+	}
+	return resp, body, err
+}
+
+// execute validates and sends opts, building an HTTP client on the client's
+// shared transports when httpClient is nil. Offline clients always send
+// through the offline transport.
+func (c *Client) execute(ctx context.Context, httpClient *http.Client, opts *options.RequestOptions) (*http.Response, string, error) {
+	if err := ValidateOptions(opts); err != nil {
+		return nil, "", err
+	}
+	if c.isOffline() {
+		// Even the HTTP clients of sessions must not reach the network
+		httpClient = c.offlineClient(opts)
+	} else if httpClient == nil {
+		var err error
+		if httpClient, err = c.httpClient(opts); err != nil {
+			return nil, "", err
+		}
+	}
+	return processWithClient(ctx, httpClient, opts)
+}
+
+// httpClient returns an HTTP client for opts on the pooled transport. Clients
+// without a cookie jar are cached as well, as they only differ by timeout
+// and redirect policy.
+func (c *Client) httpClient(opts *options.RequestOptions) (*http.Client, error) {
+	if opts.CookieJar != nil {
+		transport, err := c.transport(opts)
+		if err != nil {
+			return nil, err
+		}
+		return newHTTPClient(transport, opts), nil
+	}
+
+	key := clientKey{
+		transport:       newTransportKey(opts),
+		timeout:         opts.Timeout,
+		followRedirects: opts.FollowRedirects,
+		maxRedirects:    opts.MaxRedirects,
+	}
+	c.mu.Lock()
+	httpClient, ok := c.clients[key]
+	c.mu.Unlock()
+	if ok {
+		return httpClient, nil
+	}
+
+	transport, err := c.transport(opts)
+	if err != nil {
+		return nil, err
+	}
+	// The redirect policy must not capture opts, which the caller may reuse
+	redirects := &options.RequestOptions{FollowRedirects: opts.FollowRedirects, MaxRedirects: opts.MaxRedirects, Timeout: opts.Timeout}
+	httpClient = newHTTPClient(transport, redirects)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clients == nil {
+		c.clients = map[clientKey]*http.Client{}
+	}
+	c.clients[key] = httpClient
+	return httpClient, nil
+}
+
+// transport returns the pooled transport for the connection settings of
+// opts, creating it on first use.
+func (c *Client) transport(opts *options.RequestOptions) (http.RoundTripper, error) {
+	key := newTransportKey(opts)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if transport, ok := c.transports[key]; ok {
+		return transport, nil
+	}
+
+	httpClient, err := CreateHTTPClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	if c.transports == nil {
+		c.transports = map[transportKey]http.RoundTripper{}
+	}
+	c.transports[key] = httpClient.Transport
+	return httpClient.Transport, nil
+}
+
+// transportKey identifies the options that CreateHTTPClient bakes into a
+// transport; requests with equal keys can share connections.
+type transportKey struct {
+	tlsConfig                  *tls.Config
+	insecure                   bool
+	certFile, keyFile, caFile  string
+	proxy, proxyPAC            string
+	compress, http2, http2Only bool
+	rawHeaders                 bool
+	sessionCache               tls.ClientSessionCache
+	noSessionResumption        bool
+	addressRules               string
+}
+
+func newTransportKey(opts *options.RequestOptions) transportKey {
+	return transportKey{
+		tlsConfig:  opts.TLSConfig,
+		insecure:   opts.Insecure,
+		certFile:   opts.CertFile,
+		keyFile:    opts.KeyFile,
+		caFile:     opts.CAFile,
+		proxy:      opts.Proxy,
+		proxyPAC:   opts.ProxyPAC,
+		compress:   opts.Compress,
+		http2:      opts.HTTP2,
+		http2Only:  opts.HTTP2Only,
+		rawHeaders: opts.RawHeaders,
+
+		sessionCache:        opts.TLSSessionCache,
+		noSessionResumption: opts.NoTLSSessionResumption,
+		addressRules:        addressRules(opts.Policy),
+	}
+}
+
+// clientKey identifies the settings of a cached http.Client.
+type clientKey struct {
+	transport       transportKey
+	timeout         time.Duration
+	followRedirects bool
+	maxRedirects    int
+}
+
+// requestHost returns the host (with port, if any) targeted by rawURL.
+func requestHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
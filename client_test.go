@@ -0,0 +1,26 @@
+package gocurl_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientLastCommand(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer server.Close()
+
+	client := gocurl.NewClient()
+	assert.Empty(t, client.LastCommand())
+
+	_, _, err := client.Curl(context.Background(), "curl", "-f", "-H", "X-Test: 1", "-d", "a=1", server.URL+"/items?x=y")
+	require.Error(t, err)
+	assert.Equal(t, "curl '"+server.URL+"/items?x=y' -H 'X-Test: 1' --data-raw a=1 -f", client.LastCommand())
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/redact"
+	"github.com/maniartech/gocurl/tokenizer"
+)
+
+// collectionDir is the project-local directory holding saved requests. Each
+// request is a file containing its command line, with variables left
+// unexpanded so the directory can be committed next to the code using it.
+const collectionDir = ".gocurl"
+
+// collectionExt is the extension of saved request files.
+const collectionExt = ".curl"
+
+var requestNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+(/[A-Za-z0-9_.-]+)*$`)
+
+// findCollection returns the .gocurl directory of the current directory or
+// its closest parent. When there is none, it returns the one the current
+// directory would have.
+func findCollection() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for dir := wd; ; {
+		path := filepath.Join(dir, collectionDir)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return filepath.Join(wd, collectionDir), nil
+		}
+		dir = parent
+	}
+}
+
+// savedRequestPath returns the file of the saved request called name.
+func savedRequestPath(name string) (string, error) {
+	if !requestNamePattern.MatchString(name) || strings.Contains(name, "..") {
+		return "", fmt.Errorf("invalid request name %q", name)
+	}
+	dir, err := findCollection()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.FromSlash(name)+collectionExt), nil
+}
+
+// loadSavedRequest returns the arguments of the saved request called name.
+// Lines may be continued with a trailing backslash and lines starting with #
+// are comments.
+func loadSavedRequest(name string) ([]string, error) {
+	path, err := savedRequestPath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no saved request %q", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\\\n", " "), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	args, err := tokenizer.Split(strings.Join(lines, " "))
+	if err != nil {
+		return nil, fmt.Errorf("invalid saved request %q: %v", name, err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("saved request %q is empty", name)
+	}
+	return args, nil
+}
+
+// savedRequests lists the names of the saved requests.
+func savedRequests() ([]string, error) {
+	dir, err := findCollection()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if os.IsNotExist(err) && path == dir {
+			return filepath.SkipDir
+		}
+		if err != nil || entry.IsDir() || filepath.Ext(path) != collectionExt {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, strings.TrimSuffix(filepath.ToSlash(rel), collectionExt))
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+// runSave saves a request under a name:
+//
+//	gocurl save <name> [curl arguments]
+//
+// Without curl arguments, the last command of the history is saved. Quote
+// variables, as in '$API_URL/users', to keep them out of the saved command.
+func runSave(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: gocurl save <name> [curl arguments]")
+		return 2
+	}
+	path, err := savedRequestPath(args[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl save: %v\n", err)
+		return 2
+	}
+
+	command := args[1:]
+	if len(command) == 0 {
+		entries, err := loadHistory()
+		if err != nil {
+			fmt.Fprintf(stderr, "gocurl save: %v\n", err)
+			return 1
+		}
+		if len(entries) == 0 {
+			fmt.Fprintln(stderr, "gocurl save: the history is empty")
+			return 2
+		}
+		last := entries[len(entries)-1]
+		if last.Masked {
+			fmt.Fprintln(stderr, "gocurl save: the last command contains masked secrets; pass the command with variables instead")
+			return 2
+		}
+		command = last.Args
+	}
+	if command[0] != "curl" {
+		command = append([]string{"curl"}, command...)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fmt.Fprintf(stderr, "gocurl save: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(path, []byte(quoteArgs(command)+"\n"), 0644); err != nil {
+		fmt.Fprintf(stderr, "gocurl save: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "saved %s to %s\n", args[0], path)
+	return 0
+}
+
+// runSaved runs a saved request, or lists them when no name is given:
+//
+//	gocurl run [--env name] <name> [KEY=VALUE...]
+func runSaved(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	env := flags.String("env", "", "environment profile to run the request in")
+	file := flags.String("f", "", "YAML or JSON file defining the requests")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *file != "" {
+		return runRequestFile(ctx, *file, *env, flags.Args(), stdout, stderr)
+	}
+
+	if flags.NArg() == 0 {
+		names, err := savedRequests()
+		if err != nil {
+			fmt.Fprintf(stderr, "gocurl run: %v\n", err)
+			return 1
+		}
+		for _, name := range names {
+			fmt.Fprintln(stdout, name)
+		}
+		return 0
+	}
+
+	command, err := loadSavedRequest(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl run: %v\n", err)
+		return 2
+	}
+	vars, err := parseVarArgs(flags.Args()[1:])
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl run: %v\n", err)
+		return 2
+	}
+	return curl(ctx, command, *env, vars, stdout, stderr)
+}
+
+// parseVarArgs parses KEY=VALUE arguments into variables.
+func parseVarArgs(args []string) (gocurl.Variables, error) {
+	vars := gocurl.Variables{}
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("expected KEY=VALUE, got %q", arg)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// runRequestFile runs the request called args[0] of the request file at
+// path, with the variables of the other arguments and of the named
+// environment profile, if any. Without arguments it lists the requests.
+// Failed assertions are printed to stderr and exit with 1.
+func runRequestFile(ctx context.Context, path, env string, args []string, stdout, stderr io.Writer) int {
+	file, err := gocurl.LoadRequests(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl run: %v\n", err)
+		return 2
+	}
+	if len(args) == 0 {
+		for _, name := range file.Names() {
+			fmt.Fprintln(stdout, name)
+		}
+		return 0
+	}
+
+	vars, err := parseVarArgs(args[1:])
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl run: %v\n", err)
+		return 2
+	}
+	if env != "" {
+		profile, err := loadEnv(env)
+		if err != nil {
+			fmt.Fprintf(stderr, "gocurl run: %v\n", err)
+			return 2
+		}
+		for key, value := range profile.Variables {
+			if _, ok := vars[key]; !ok {
+				vars[key] = value
+			}
+		}
+		markSecrets(profile, vars)
+	}
+
+	_, body, err := file.RunRequest(ctx, args[0], vars)
+	fmt.Fprint(stdout, body)
+	var assertErr *gocurl.AssertionError
+	if errors.As(err, &assertErr) {
+		for _, failure := range assertErr.Failures {
+			fmt.Fprintf(stderr, "gocurl run: %s: %s\n", args[0], failure)
+		}
+		return 1
+	}
+	if err != nil {
+		code := gocurl.ExitCode(err)
+		fmt.Fprintf(stderr, "gocurl run: (%d) %s\n", code, redact.Default.String(err.Error()))
+		return code
+	}
+	return 0
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavedRequests(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	project := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(project))
+	defer os.Chdir(wd)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.URL.Path, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"save", "users/create", "-X", "POST", "-H", "Authorization: Bearer $TOKEN", "$API_URL/users"}, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+
+	saved, err := os.ReadFile(filepath.Join(project, ".gocurl", "users", "create.curl"))
+	require.NoError(t, err)
+	assert.Equal(t, "curl -X POST -H 'Authorization: Bearer $TOKEN' '$API_URL/users'\n", string(saved))
+
+	t.Run("run", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), []string{"run", "users/create", "API_URL=" + server.URL, "TOKEN=abc"}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Equal(t, "POST /users Bearer abc", stdout.String())
+
+		// The history keeps the variables
+		entries, err := loadHistory()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"curl", "-X", "POST", "-H", "Authorization: Bearer $TOKEN", "$API_URL/users"}, entries[len(entries)-1].Args)
+	})
+
+	t.Run("run from a subdirectory", func(t *testing.T) {
+		sub := filepath.Join(project, "internal", "api")
+		require.NoError(t, os.MkdirAll(sub, 0755))
+		require.NoError(t, os.Chdir(sub))
+		defer os.Chdir(project)
+
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), []string{"run", "users/create", "API_URL=" + server.URL}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+	})
+
+	t.Run("save the last command", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		require.Equal(t, 0, run(context.Background(), []string{server.URL + "/health"}, &stdout, &stderr))
+		require.Equal(t, 0, run(context.Background(), []string{"save", "health"}, &stdout, &stderr), stderr.String())
+
+		stdout.Reset()
+		require.Equal(t, 0, run(context.Background(), []string{"run", "health"}, &stdout, &stderr), stderr.String())
+		assert.Equal(t, "GET /health ", stdout.String())
+	})
+
+	t.Run("list", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		require.Equal(t, 0, run(context.Background(), []string{"run"}, &stdout, &stderr))
+		assert.Equal(t, "health\nusers/create\n", stdout.String())
+	})
+
+	t.Run("comments and continuations", func(t *testing.T) {
+		content := "# Lists the users\ncurl \\\n  -H 'Accept: application/json' \\\n  '$API_URL/users'\n"
+		require.NoError(t, os.WriteFile(filepath.Join(project, ".gocurl", "users", "list.curl"), []byte(content), 0644))
+
+		args, err := loadSavedRequest("users/list")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"curl", "-H", "Accept: application/json", "$API_URL/users"}, args)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, run(context.Background(), []string{"save", "../escape", "https://example.com"}, &stdout, &stderr))
+		assert.Equal(t, 2, run(context.Background(), []string{"run", "missing"}, &stdout, &stderr))
+		assert.Contains(t, stderr.String(), `no saved request "missing"`)
+		assert.Equal(t, 2, run(context.Background(), []string{"run", "health", "NOVALUE"}, &stdout, &stderr))
+	})
+}
+
+func TestRunRequestFile(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if name := r.URL.Query().Get("name"); name != "ada" {
+			fmt.Fprint(w, `{"name": "bob"}`)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"name": "ada"}`)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "requests.yaml")
+	content := "requests:\n  create-user:\n    method: POST\n    url: ${base}/users?name=${name}\n    assert:\n      status: 201\n      json:\n        name: ${name}\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run(context.Background(), []string{"run", "-f", path}, &stdout, &stderr), stderr.String())
+	assert.Equal(t, "create-user\n", stdout.String())
+
+	stdout.Reset()
+	code := run(context.Background(), []string{"run", "-f", path, "create-user", "base=" + server.URL, "name=ada"}, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, `{"name": "ada"}`, stdout.String())
+
+	stdout.Reset()
+	stderr.Reset()
+	code = run(context.Background(), []string{"run", "-f", path, "create-user", "base=" + server.URL, "name=eve"}, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "gocurl run: create-user: status: expected 201, got 200\ngocurl run: create-user: json name: expected eve, got \"bob\"\n", stderr.String())
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/maniartech/gocurl"
+)
+
+// completionShells maps shell names to their completion script generators.
+var completionShells = map[string]func(w io.Writer, c completionData){
+	"bash":       bashCompletion,
+	"fish":       fishCompletion,
+	"powershell": powershellCompletion,
+	"zsh":        zshCompletion,
+}
+
+// completionData is what completion scripts offer: subcommands, flags and
+// the flags taking a file name. Saved request names and environment profiles
+// are completed dynamically by calling `gocurl run` and `gocurl env list`.
+type completionData struct {
+	commands  []string
+	flags     []gocurl.CurlFlag
+	fileFlags []string
+}
+
+func newCompletionData() completionData {
+	var c completionData
+	for name := range commands() {
+		c.commands = append(c.commands, name)
+	}
+	sort.Strings(c.commands)
+
+	c.flags = append(c.flags,
+		gocurl.CurlFlag{Long: "--env", Arg: "name"},
+		gocurl.CurlFlag{Long: "--pretty"},
+		gocurl.CurlFlag{Long: "--no-pager"},
+		gocurl.CurlFlag{Long: "--offline"})
+	for _, f := range gocurl.CurlFlags() {
+		if f.Support == gocurl.FlagUnsupported {
+			continue
+		}
+		c.flags = append(c.flags, f)
+		if strings.Contains(f.Arg, "file") || f.Arg == "certificate" || f.Arg == "key" {
+			c.fileFlags = append(c.fileFlags, f.Names()...)
+		}
+	}
+	return c
+}
+
+// flagNames returns every spelling of every flag.
+func (c completionData) flagNames() []string {
+	var names []string
+	for _, f := range c.flags {
+		names = append(names, f.Names()...)
+	}
+	return names
+}
+
+// runCompletion prints the completion script for a shell:
+//
+//	gocurl completion bash|zsh|fish|powershell
+func runCompletion(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 || completionShells[args[0]] == nil {
+		fmt.Fprintln(stderr, "usage: gocurl completion bash|zsh|fish|powershell")
+		return 2
+	}
+	completionShells[args[0]](stdout, newCompletionData())
+	return 0
+}
+
+func bashCompletion(w io.Writer, c completionData) {
+	fmt.Fprintf(w, `# bash completion for gocurl; load it with: source <(gocurl completion bash)
+_gocurl() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+    case "$prev" in
+        --env)
+            COMPREPLY=($(compgen -W "$(gocurl env list 2>/dev/null)" -- "$cur"))
+            return ;;
+        run)
+            COMPREPLY=($(compgen -W "$(gocurl run 2>/dev/null)" -- "$cur"))
+            return ;;
+        %s)
+            COMPREPLY=($(compgen -f -- "$cur"))
+            return ;;
+    esac
+    if [[ "$cur" == -* ]]; then
+        COMPREPLY=($(compgen -W "%s" -- "$cur"))
+    elif [[ $COMP_CWORD -eq 1 ]]; then
+        COMPREPLY=($(compgen -W "%s" -- "$cur"))
+    fi
+}
+complete -o default -F _gocurl gocurl
+`, strings.Join(c.fileFlags, "|"), strings.Join(c.flagNames(), " "), strings.Join(c.commands, " "))
+}
+
+func zshCompletion(w io.Writer, c completionData) {
+	fmt.Fprintf(w, `#compdef gocurl
+# zsh completion for gocurl; load it with: source <(gocurl completion zsh)
+_gocurl() {
+    local -a subcommands flags
+    subcommands=(%s)
+    flags=(%s)
+    case ${words[CURRENT-1]} in
+        --env)
+            compadd -- ${(f)"$(gocurl env list 2>/dev/null)"}
+            return ;;
+        run)
+            compadd -- ${(f)"$(gocurl run 2>/dev/null)"}
+            return ;;
+        %s)
+            _files
+            return ;;
+    esac
+    if [[ $PREFIX == -* ]]; then
+        compadd -- $flags
+    elif (( CURRENT == 2 )); then
+        compadd -- $subcommands
+    else
+        _files
+    fi
+}
+if [[ "${funcstack[1]}" == "_gocurl" ]]; then
+    _gocurl "$@"
+else
+    compdef _gocurl gocurl
+fi
+`, strings.Join(c.commands, " "), strings.Join(c.flagNames(), " "), strings.Join(c.fileFlags, "|"))
+}
+
+func fishCompletion(w io.Writer, c completionData) {
+	fmt.Fprintln(w, "# fish completion for gocurl; load it with: gocurl completion fish | source")
+	fmt.Fprintln(w, "complete -c gocurl -f")
+	fmt.Fprintf(w, "complete -c gocurl -n __fish_use_subcommand -a '%s'\n", strings.Join(c.commands, " "))
+	fmt.Fprintln(w, "complete -c gocurl -n '__fish_seen_subcommand_from run' -x -a '(gocurl run 2>/dev/null)'")
+
+	fileFlags := map[string]bool{}
+	for _, name := range c.fileFlags {
+		fileFlags[name] = true
+	}
+	for _, f := range c.flags {
+		line := "complete -c gocurl"
+		if f.Short != "" {
+			line += " -s " + strings.TrimPrefix(f.Short, "-")
+		}
+		for _, name := range append([]string{f.Long}, f.Aliases...) {
+			line += " -l " + strings.TrimPrefix(name, "--")
+		}
+		switch {
+		case f.Long == "--env":
+			line += " -x -a '(gocurl env list 2>/dev/null)'"
+		case fileFlags[f.Long]:
+			line += " -r -F"
+		case f.Arg != "":
+			line += " -x"
+		}
+		if f.Note != "" {
+			line += " -d " + fishQuote(f.Note)
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func powershellCompletion(w io.Writer, c completionData) {
+	fmt.Fprintf(w, `# PowerShell completion for gocurl; load it with:
+#     gocurl completion powershell | Out-String | Invoke-Expression
+Register-ArgumentCompleter -Native -CommandName gocurl -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements |
+        Where-Object { $_.Extent.StartOffset -lt $cursorPosition } |
+        ForEach-Object { $_.ToString() })
+    if ($wordToComplete) { $words = @($words | Select-Object -SkipLast 1) }
+
+    $candidates = switch ($words[-1]) {
+        '--env' { @(gocurl env list 2>$null) }
+        'run' { @(gocurl run 2>$null) }
+        default {
+            if ($wordToComplete -like '-*') { @(%s) }
+            elseif ($words.Count -eq 1) { @(%s) }
+            else { @() }
+        }
+    }
+    $candidates | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`, powershellList(c.flagNames()), powershellList(c.commands))
+}
+
+func powershellList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = "'" + strings.ReplaceAll(item, "'", "''") + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		t.Run(shell, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			require.Equal(t, 0, run(context.Background(), []string{"completion", shell}, &stdout, &stderr), stderr.String())
+
+			script := stdout.String()
+			assert.Contains(t, script, "header")
+			assert.Contains(t, script, "upload-file")
+			assert.Contains(t, script, "env")
+			assert.Contains(t, script, "gocurl run 2>")
+			assert.Contains(t, script, "gocurl env list 2>")
+			assert.NotContains(t, script, "oauth2-bearer", "unsupported flags are not offered")
+		})
+	}
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, run(context.Background(), []string{"completion", "tcsh"}, &stdout, &stderr))
+}
+
+func TestBashCompletion(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not installed")
+	}
+
+	var script, stderr bytes.Buffer
+	require.Equal(t, 0, run(context.Background(), []string{"completion", "bash"}, &script, &stderr))
+
+	// A stand-in gocurl answers the dynamic completions
+	dir := t.TempDir()
+	fake := "#!/bin/sh\ncase \"$1\" in\n  run) echo users/list; echo users/create ;;\n  env) echo prod; echo staging ;;\nesac\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gocurl"), []byte(fake), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "completion.bash"), script.Bytes(), 0644))
+
+	complete := func(words ...string) string {
+		line := `source "$1"; COMP_WORDS=(` + strings.Join(words, " ") + `); COMP_CWORD=$((${#COMP_WORDS[@]}-1)); _gocurl; echo "${COMPREPLY[*]}"`
+		cmd := exec.Command(bash, "-c", line, "bash", filepath.Join(dir, "completion.bash"))
+		cmd.Env = append(os.Environ(), "PATH="+dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+
+	assert.Equal(t, "--header", complete("gocurl", "--hea"))
+	assert.Equal(t, "bench", complete("gocurl", "ben"))
+	assert.Equal(t, "users/list users/create", complete("gocurl", "run", "users/"))
+	assert.Equal(t, "staging", complete("gocurl", "--env", "st"))
+}
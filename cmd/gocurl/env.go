@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/redact"
+	"gopkg.in/yaml.v3"
+)
+
+// envProfile is a named environment selected with --env. Its variables are
+// expanded in the command and its headers are sent unless the command sets
+// them itself. The values of variables listed in Secrets, or named like
+// secrets (TOKEN, API_KEY...), are masked in the output.
+type envProfile struct {
+	Variables map[string]string `yaml:"variables,omitempty"`
+	Headers   map[string]string `yaml:"headers,omitempty"`
+	Secrets   []string          `yaml:"secrets,omitempty"`
+}
+
+// markSecrets registers the secret variables of the profile, with their
+// values in vars, for redaction.
+func markSecrets(profile *envProfile, vars gocurl.Variables) {
+	vars.MarkSecret(profile.Secrets...)
+	for name := range profile.Variables {
+		if redact.Default.IsSecretName(name) {
+			vars.MarkSecret(name)
+		}
+	}
+}
+
+// envProfiles maps profile names to profiles, as stored in envs.yaml.
+type envProfiles map[string]*envProfile
+
+// configDir returns the gocurl configuration directory,
+// $XDG_CONFIG_HOME/gocurl or ~/.config/gocurl.
+func configDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gocurl"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "gocurl"), nil
+}
+
+func envsPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "envs.yaml"), nil
+}
+
+// loadEnvs reads the environment profiles. A missing file has no profiles.
+func loadEnvs() (envProfiles, error) {
+	path, err := envsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return envProfiles{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	envs := envProfiles{}
+	if err := yaml.Unmarshal(data, &envs); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", path, err)
+	}
+	return envs, nil
+}
+
+// save writes the profiles, readable only by the user as they usually hold
+// credentials.
+func (envs envProfiles) save() error {
+	path, err := envsPath()
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(envs)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// loadEnv returns the profile called name.
+func loadEnv(name string) (*envProfile, error) {
+	envs, err := loadEnvs()
+	if err != nil {
+		return nil, err
+	}
+	profile, ok := envs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown environment %q", name)
+	}
+	return profile, nil
+}
+
+// splitEnvFlag extracts a leading --env name or --env=name from args.
+func splitEnvFlag(args []string) (name string, rest []string, ok bool) {
+	switch {
+	case args[0] == "--env" && len(args) > 1:
+		return args[1], args[2:], true
+	case strings.HasPrefix(args[0], "--env="):
+		return strings.TrimPrefix(args[0], "--env="), args[1:], true
+	}
+	return "", args, false
+}
+
+// runEnv manages the environment profiles:
+//
+//	gocurl env list
+//	gocurl env set <name> [-H 'Header: value'] [KEY=VALUE...]
+func runEnv(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: gocurl env list | gocurl env set <name> [-H 'Header: value'] [KEY=VALUE...]")
+		return 2
+	}
+
+	switch args[0] {
+	case "list":
+		envs, err := loadEnvs()
+		if err != nil {
+			fmt.Fprintf(stderr, "gocurl env: %v\n", err)
+			return 1
+		}
+		names := make([]string, 0, len(envs))
+		for name := range envs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintln(stdout, name)
+		}
+		return 0
+	case "set":
+		return runEnvSet(args[1:], stdout, stderr)
+	}
+
+	fmt.Fprintf(stderr, "gocurl env: unknown command %q\n", args[0])
+	return 2
+}
+
+// runEnvSet creates the profile if needed and sets the given variables and
+// headers. An empty value removes the variable or header.
+func runEnvSet(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: gocurl env set <name> [-H 'Header: value'] [KEY=VALUE...]")
+		return 2
+	}
+
+	envs, err := loadEnvs()
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl env: %v\n", err)
+		return 1
+	}
+	name := args[0]
+	profile := envs[name]
+	if profile == nil {
+		profile = &envProfile{}
+		envs[name] = profile
+	}
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-H" || arg == "--header" {
+			i++
+			if i >= len(args) {
+				fmt.Fprintf(stderr, "gocurl env: expected header after %s\n", arg)
+				return 2
+			}
+			key, value, ok := strings.Cut(args[i], ":")
+			if !ok || strings.TrimSpace(key) == "" {
+				fmt.Fprintf(stderr, "gocurl env: invalid header %q\n", args[i])
+				return 2
+			}
+			profile.Headers = setOrDelete(profile.Headers, strings.TrimSpace(key), strings.TrimSpace(value))
+			continue
+		}
+
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			fmt.Fprintf(stderr, "gocurl env: expected KEY=VALUE, got %q\n", arg)
+			return 2
+		}
+		profile.Variables = setOrDelete(profile.Variables, key, value)
+	}
+
+	if err := envs.save(); err != nil {
+		fmt.Fprintf(stderr, "gocurl env: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func setOrDelete(m map[string]string, key, value string) map[string]string {
+	if value == "" {
+		delete(m, key)
+		return m
+	}
+	if m == nil {
+		m = map[string]string{}
+	}
+	m[key] = value
+	return m
+}
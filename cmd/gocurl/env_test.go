@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvProfiles(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s %s", r.URL.Path, r.Header.Get("X-Tenant"), r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"env", "set", "staging", "API_URL=" + server.URL, "TOKEN=secret", "-H", "X-Tenant: acme", "-H", "Authorization: Bearer $TOKEN"}, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	code = run(context.Background(), []string{"env", "set", "prod", "API_URL=https://api.example.com"}, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+
+	info, err := os.Stat(filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "gocurl", "envs.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	t.Run("list", func(t *testing.T) {
+		var stdout bytes.Buffer
+		assert.Equal(t, 0, run(context.Background(), []string{"env", "list"}, &stdout, &stderr))
+		assert.Equal(t, "prod\nstaging\n", stdout.String())
+	})
+
+	t.Run("select", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), []string{"--env", "staging", "$API_URL/users"}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Equal(t, "/users acme Bearer secret", stdout.String())
+	})
+
+	t.Run("command headers win", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), []string{"--env=staging", "-H", "X-Tenant: other", "${API_URL}/users"}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Equal(t, "/users other Bearer secret", stdout.String())
+	})
+
+	t.Run("secrets are masked", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), []string{"--env", "staging", "-H", "Broken $TOKEN", "$API_URL/users"}, &stdout, &stderr)
+		assert.Equal(t, 2, code)
+		assert.Equal(t, "gocurl: invalid header format: Broken ****\n", stderr.String())
+	})
+
+	t.Run("remove a variable", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		require.Equal(t, 0, run(context.Background(), []string{"env", "set", "staging", "TOKEN="}, &stdout, &stderr))
+		profile, err := loadEnv("staging")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"API_URL": server.URL}, profile.Variables)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, run(context.Background(), []string{"--env", "missing", "https://example.com"}, &stdout, &stderr))
+		assert.Contains(t, stderr.String(), `unknown environment "missing"`)
+		assert.Equal(t, 2, run(context.Background(), []string{"env", "set", "prod", "NOVALUE"}, &stdout, &stderr))
+		assert.Equal(t, 2, run(context.Background(), []string{"env", "rename"}, &stdout, &stderr))
+	})
+}
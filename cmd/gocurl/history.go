@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maniartech/gocurl/redact"
+	"github.com/maniartech/gocurl/tokenizer"
+)
+
+// historyEntry is an executed command as recorded in the history.
+type historyEntry struct {
+	ID     int       `json:"id"`
+	Time   time.Time `json:"time"`
+	Env    string    `json:"env,omitempty"`
+	Args   []string  `json:"args"`
+	Status int       `json:"status,omitempty"`
+	Masked bool      `json:"masked,omitempty"`
+}
+
+// command returns the arguments that run the entry again.
+func (e historyEntry) command() []string {
+	if e.Env == "" {
+		return e.Args
+	}
+	return append([]string{"--env", e.Env}, e.Args...)
+}
+
+// stateDir returns the directory gocurl keeps its state in,
+// $XDG_STATE_HOME/gocurl or ~/.local/state/gocurl.
+func stateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "gocurl"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "gocurl"), nil
+}
+
+// The history is kept in two files at most: once the current one reaches
+// historyFileSize it replaces the previous one, history.jsonl.1, and a new
+// one is started.
+const (
+	historyFileSize = 1 << 20
+	// maxHistoryLine is the longest entry recorded or read; longer lines,
+	// like invalid ones, are skipped when reading
+	maxHistoryLine = 64 << 10
+)
+
+func historyPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.jsonl"), nil
+}
+
+// loadHistory reads the recorded commands, oldest first. Invalid entries,
+// such as a line cut short by a crash, are skipped.
+func loadHistory() ([]historyEntry, error) {
+	path, err := historyPath()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []historyEntry
+	for _, file := range []string{path + ".1", path} {
+		err := readHistory(file, func(line []byte) {
+			var entry historyEntry
+			if json.Unmarshal(line, &entry) == nil {
+				entries = append(entries, entry)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// readHistory calls fn with each line of the history file at path that is
+// at most maxHistoryLine long. A missing file has no lines.
+func readHistory(path string, fn func(line []byte)) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var line []byte
+	tooLong := false
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if !tooLong {
+			line = append(line, chunk...)
+			tooLong = len(line) > maxHistoryLine+1
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if !tooLong {
+			if line = bytes.TrimSuffix(line, []byte("\n")); len(line) > 0 {
+				fn(line)
+			}
+		}
+		line, tooLong = line[:0], false
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// lastHistoryID returns the ID of the last valid entry of the history file
+// at path, or 0 when it has none. Only the end of the file is decoded unless
+// no valid entry is found there.
+func lastHistoryID(path string) (int, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	var entry struct {
+		ID int `json:"id"`
+	}
+	offset := max(0, info.Size()-2*maxHistoryLine)
+	tail := make([]byte, info.Size()-offset)
+	if _, err := file.ReadAt(tail, offset); err != nil {
+		return 0, err
+	}
+	lines := bytes.Split(tail, []byte("\n"))
+	if offset > 0 {
+		// The first line is the end of a longer one
+		lines = lines[1:]
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		if len(lines[i]) <= maxHistoryLine && json.Unmarshal(lines[i], &entry) == nil && entry.ID > 0 {
+			return entry.ID, nil
+		}
+	}
+	if offset == 0 {
+		return 0, nil
+	}
+
+	id := 0
+	err = readHistory(path, func(line []byte) {
+		if json.Unmarshal(line, &entry) == nil && entry.ID > 0 {
+			id = entry.ID
+		}
+	})
+	return id, err
+}
+
+// recordHistory appends the command to the history with its secrets masked.
+// Setting GOCURL_HISTORY=off disables the history.
+func recordHistory(env string, args []string, resp *http.Response) error {
+	if os.Getenv("GOCURL_HISTORY") == "off" {
+		return nil
+	}
+
+	path, err := historyPath()
+	if err != nil {
+		return err
+	}
+	last, err := lastHistoryID(path)
+	if err == nil && last == 0 {
+		last, err = lastHistoryID(path + ".1")
+	}
+	if err != nil {
+		return err
+	}
+	entry := historyEntry{ID: last + 1, Time: time.Now().UTC(), Env: env}
+	entry.Args, entry.Masked = maskArgs(args)
+	if resp != nil {
+		entry.Status = resp.StatusCode
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if len(line) > maxHistoryLine {
+		return fmt.Errorf("command of %d bytes is too long to record", len(line))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil && info.Size()+int64(len(line)) >= historyFileSize {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return err
+		}
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	// Start a new line after an entry cut short
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			line = append([]byte{'\n'}, line...)
+		}
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// maskArgs returns a copy of args with credentials masked by the default
+// redactor: sensitive headers, passwords, cookies, sensitive query
+// parameters and registered secrets. Values referring to variables, such as
+// "Bearer $TOKEN", are kept as they hold no secret themselves.
+func maskArgs(args []string) ([]string, bool) {
+	return redact.Default.Args(args)
+}
+
+// quoteArgs formats args as a POSIX shell command line.
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && strings.IndexFunc(arg, func(r rune) bool {
+			return !(r == '-' || r == '_' || r == '.' || r == '/' || r == ':' || r == '=' || r == '@' || r == ',' ||
+				'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+		}) < 0 {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// runHistory lists the most recent commands.
+func runHistory(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	limit := flags.Int("n", 20, "number of commands to list (0 lists all)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	entries, err := loadHistory()
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl history: %v\n", err)
+		return 1
+	}
+	if *limit > 0 && len(entries) > *limit {
+		entries = entries[len(entries)-*limit:]
+	}
+	for _, entry := range entries {
+		status := "-"
+		if entry.Status != 0 {
+			status = strconv.Itoa(entry.Status)
+		}
+		fmt.Fprintf(stdout, "%4d  %s  %3s  %s\n", entry.ID, entry.Time.Local().Format("2006-01-02 15:04:05"), status, quoteArgs(entry.command()))
+	}
+	return 0
+}
+
+// runRerun executes a recorded command again.
+func runRerun(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("rerun", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	edit := flags.Bool("edit", false, "edit the command in $EDITOR before running it")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: gocurl rerun [--edit] <id>")
+		return 2
+	}
+	id, err := strconv.Atoi(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl rerun: invalid id %q\n", flags.Arg(0))
+		return 2
+	}
+
+	entries, err := loadHistory()
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl rerun: %v\n", err)
+		return 1
+	}
+	for _, entry := range entries {
+		if entry.ID == id {
+			return rerunEntry(ctx, entry, *edit, stdout, stderr)
+		}
+	}
+	fmt.Fprintf(stderr, "gocurl rerun: no command with id %d\n", id)
+	return 2
+}
+
+// runLast executes the most recent command again.
+func runLast(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("last", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	edit := flags.Bool("edit", false, "edit the command in $EDITOR before running it")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	entries, err := loadHistory()
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl last: %v\n", err)
+		return 1
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(stderr, "gocurl last: the history is empty")
+		return 2
+	}
+	return rerunEntry(ctx, entries[len(entries)-1], *edit, stdout, stderr)
+}
+
+// rerunEntry runs entry, optionally letting the user edit it first. Entries
+// with masked secrets must be edited, as the secrets were not recorded.
+func rerunEntry(ctx context.Context, entry historyEntry, edit bool, stdout, stderr io.Writer) int {
+	args := entry.command()
+	if edit {
+		var err error
+		if args, err = editCommand(args); err != nil {
+			fmt.Fprintf(stderr, "gocurl: %v\n", err)
+			return 1
+		}
+		if len(args) == 0 {
+			fmt.Fprintln(stderr, "gocurl: empty command, nothing to run")
+			return 2
+		}
+	} else if entry.Masked {
+		fmt.Fprintf(stderr, "gocurl: command %d contains masked secrets; rerun it with --edit or use variables\n", entry.ID)
+		return 2
+	}
+	return run(ctx, args, stdout, stderr)
+}
+
+// editCommand opens args as a command line in $VISUAL or $EDITOR and returns
+// the edited arguments.
+func editCommand(args []string) ([]string, error) {
+	file, err := os.CreateTemp("", "gocurl-*.sh")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(quoteArgs(args) + "\n")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", file.Name())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor failed: %v", err)
+	}
+
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return nil, err
+	}
+	line := strings.TrimSpace(strings.ReplaceAll(string(edited), "\\\n", " "))
+	return tokenizer.Split(line)
+}
@@ -0,0 +1,330 @@
+// Command gocurl executes curl commands with gocurl and offers helper
+// subcommands for working with them.
+//
+// Usage:
+//
+//	gocurl [--env name] [--pretty[=auto|always|never]] [--no-pager] [--offline[=recordings]] [curl arguments]
+//	gocurl env list | set <name> [-H 'Header: value'] [KEY=VALUE...]
+//	gocurl history [-n 20]
+//	gocurl rerun [--edit] <id>
+//	gocurl last [--edit]
+//	gocurl save <name> [curl arguments]
+//	gocurl run [--env name] [-f requests.yaml] [<name> [KEY=VALUE...]]
+//	gocurl supported [-json] [full|partial|unsupported]
+//	gocurl completion bash|zsh|fish|powershell
+//	gocurl diff <command A> <command B>
+//	gocurl bench [-n requests] [-c concurrency] [-d duration] [curl arguments]
+//	gocurl monitor [--interval 30s] [--expect-status 200] [curl arguments]
+//	gocurl proxy [--port 8888] [--mitm] [--save file] [--verbose] [--commands] | --convert file
+//	gocurl serve --from <recordings> [--port 8080]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/redact"
+)
+
+// command is a gocurl subcommand. It returns the process exit code.
+type command func(ctx context.Context, args []string, stdout, stderr io.Writer) int
+
+// commands returns the subcommands by name. It is a function rather than a
+// variable as rerun, last and completion refer back to the subcommands.
+func commands() map[string]command {
+	return map[string]command{
+		"bench":      runBench,
+		"completion": runCompletion,
+		"diff":       runDiff,
+		"env":        runEnv,
+		"history":    runHistory,
+		"last":       runLast,
+		"monitor":    runMonitor,
+		"proxy":      runProxy,
+		"rerun":      runRerun,
+		"run":        runSaved,
+		"save":       runSave,
+		"serve":      runServe,
+		"supported":  runSupported,
+	}
+}
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: gocurl [curl arguments] | gocurl <command> [arguments]")
+		return 2
+	}
+
+	if cmd, ok := commands()[args[0]]; ok {
+		return cmd(ctx, args[1:], stdout, stderr)
+	}
+	if name, rest, ok := splitEnvFlag(args); ok {
+		if len(rest) == 0 {
+			fmt.Fprintln(stderr, "usage: gocurl --env <name> [curl arguments]")
+			return 2
+		}
+		return curl(ctx, rest, name, nil, stdout, stderr)
+	}
+	return runCurl(ctx, args, stdout, stderr)
+}
+
+// runCurl executes the arguments as a curl command.
+func runCurl(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	return curl(ctx, args, "", nil, stdout, stderr)
+}
+
+// curl executes the arguments as a curl command within the named environment
+// profile, if any, and records it in the history. vars take precedence over
+// the variables of the profile.
+func curl(ctx context.Context, args []string, env string, vars gocurl.Variables, stdout, stderr io.Writer) int {
+	if args[0] != "curl" {
+		args = append([]string{"curl"}, args...)
+	}
+	// Requests separated by --next run in turn, exiting like the last one
+	if requests := gocurl.SplitNext(args); len(requests) > 1 {
+		code := 0
+		for _, request := range requests {
+			code = curl(ctx, request, env, vars, stdout, stderr)
+		}
+		return code
+	}
+	output, curlArgs, err := splitOutputFlags(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl: %v\n", err)
+		return 2
+	}
+	offline, recordings, curlArgs := splitOfflineFlag(curlArgs)
+	var client *gocurl.Client
+	if offline {
+		if client, err = offlineClient(recordings); err != nil {
+			fmt.Fprintf(stderr, "gocurl: %v\n", err)
+			return 2
+		}
+	}
+	var profile *envProfile
+	if env != "" {
+		if profile, err = loadEnv(env); err != nil {
+			fmt.Fprintf(stderr, "gocurl: %v\n", err)
+			return 2
+		}
+	}
+	if profile != nil {
+		merged := gocurl.Variables{}
+		for key, value := range profile.Variables {
+			merged[key] = value
+		}
+		for key, value := range vars {
+			merged[key] = value
+		}
+		vars = merged
+		markSecrets(profile, vars)
+	}
+	opts, err := gocurl.ArgsToOptionsWithVars(curlArgs, vars)
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl: %s\n", redact.Default.String(err.Error()))
+		return 2
+	}
+	if profile != nil && len(profile.Headers) > 0 {
+		if opts.Headers == nil {
+			opts.Headers = http.Header{}
+		}
+		for key, value := range profile.Headers {
+			if opts.Headers.Get(key) != "" {
+				continue
+			}
+			expanded, err := vars.Resolve(value)
+			if err != nil {
+				fmt.Fprintf(stderr, "gocurl: %s\n", redact.Default.String(err.Error()))
+				return 2
+			}
+			opts.SetHeader(key, expanded)
+		}
+	}
+
+	silent := opts.Silent || opts.OutputFile != ""
+	opts.Silent = true
+	var resp *http.Response
+	var body string
+	if client != nil {
+		resp, body, err = client.Process(ctx, opts)
+	} else {
+		resp, body, err = gocurl.Process(ctx, opts)
+	}
+	if recordErr := recordHistory(env, args, resp); recordErr != nil {
+		fmt.Fprintf(stderr, "gocurl: failed to record history: %v\n", recordErr)
+	}
+	if err == nil || opts.FailWithBody && resp != nil {
+		var out string
+		if !silent {
+			out = formatBody(stdout, body, output)
+		}
+		if opts.WriteOut != "" {
+			out += gocurl.FormatWriteOut(opts.WriteOut, resp, body)
+		}
+		if writeErr := writeOutput(stdout, out, output); writeErr != nil {
+			fmt.Fprintf(stderr, "gocurl: %v\n", writeErr)
+			return 23
+		}
+	}
+	if err != nil {
+		code := gocurl.ExitCode(err)
+		fmt.Fprintf(stderr, "gocurl: (%d) %s\n", code, redact.Default.String(err.Error()))
+		return code
+	}
+	return 0
+}
+
+// runDiff prints the structural differences between two curl commands and
+// exits with 1 when they differ, like diff(1).
+func runDiff(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) != 2 {
+		fmt.Fprintln(stderr, "usage: gocurl diff <command A> <command B>")
+		return 2
+	}
+
+	diff, err := gocurl.DiffCommands(args[0], args[1])
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl diff: %v\n", err)
+		return 2
+	}
+	if diff.Equal() {
+		return 0
+	}
+	fmt.Fprint(stdout, diff.String())
+	return 1
+}
+
+// runBench load tests the request described by the curl arguments.
+func runBench(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	requests := flags.Int("n", 200, "number of requests to send")
+	concurrency := flags.Int("c", 10, "number of concurrent requests")
+	duration := flags.Duration("d", 0, "send requests for this long instead of a fixed count")
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: gocurl bench [-n requests] [-c concurrency] [-d duration] [curl arguments]")
+		return 2
+	}
+
+	opts, err := gocurl.ArgsToOptions(flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl bench: %v\n", err)
+		return 2
+	}
+
+	config := gocurl.BenchConfig{Requests: *requests, Concurrency: *concurrency, Duration: *duration}
+	if *duration > 0 {
+		config.Requests = 0
+	}
+	result, err := gocurl.Bench(ctx, opts, config)
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl bench: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(result)
+	} else {
+		fmt.Fprint(stdout, result.String())
+	}
+	if result.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runMonitor polls the endpoint and prints every check and state change.
+func runMonitor(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("monitor", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	interval := flags.Duration("interval", 30*time.Second, "time between checks")
+	expect := flags.String("expect-status", "", "comma separated healthy status codes (default: any below 400)")
+	threshold := flags.Int("threshold", 1, "consecutive failures before the endpoint is down")
+	webhook := flags.String("webhook", "", "URL receiving state changes as JSON")
+	count := flags.Int("count", 0, "stop after this many checks (default: run forever)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: gocurl monitor [--interval 30s] [--expect-status 200] [curl arguments]")
+		return 2
+	}
+
+	config := gocurl.MonitorConfig{
+		Interval:         *interval,
+		FailureThreshold: *threshold,
+		WebhookURL:       *webhook,
+	}
+	if *expect != "" {
+		for _, field := range strings.Split(*expect, ",") {
+			code, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				fmt.Fprintf(stderr, "gocurl monitor: invalid status code %q\n", field)
+				return 2
+			}
+			config.ExpectStatus = append(config.ExpectStatus, code)
+		}
+	}
+
+	opts, err := gocurl.ArgsToOptions(flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl monitor: %v\n", err)
+		return 2
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	checks := 0
+	config.OnCheck = func(check gocurl.MonitorCheck) {
+		status := "OK  "
+		if !check.Healthy {
+			status = "FAIL"
+		}
+		detail := strconv.Itoa(check.StatusCode)
+		if check.Error != "" {
+			detail = check.Error
+		}
+		fmt.Fprintf(stdout, "%s %s %v %s\n", check.Time.Format(time.RFC3339), status, check.Latency.Round(time.Millisecond), detail)
+
+		checks++
+		if *count > 0 && checks >= *count {
+			cancel()
+		}
+	}
+	config.OnStateChange = func(event gocurl.MonitorEvent) {
+		fmt.Fprintf(stdout, "%s state %s -> %s\n", event.Check.Time.Format(time.RFC3339), event.From, event.To)
+	}
+
+	monitor, err := gocurl.NewMonitor(opts, config)
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl monitor: %v\n", err)
+		return 2
+	}
+	monitor.Run(ctx)
+
+	stats := monitor.Stats()
+	fmt.Fprintf(stdout, "%d checks, %.2f%% uptime, mean latency %v\n", stats.Checks, stats.Uptime*100, stats.MeanLatency.Round(time.Millisecond))
+	if stats.State == gocurl.MonitorDown {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	// Keep the history of the commands run by the tests out of the user's
+	dir, err := os.MkdirTemp("", "gocurl-state")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_STATE_HOME", dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Method, r.URL.Path)
+	}))
+	defer server.Close()
+
+	t.Run("Executes curl arguments", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), []string{"-X", "DELETE", server.URL + "/items/1"}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Equal(t, "DELETE /items/1", stdout.String())
+	})
+
+	t.Run("JSON shorthand", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), []string{"--json", `{"name":"x"}`, server.URL + "/items"}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Equal(t, "POST /items", stdout.String())
+	})
+
+	t.Run("Next", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), []string{"-X", "PUT", server.URL + "/a", "--next", server.URL + "/b"}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Equal(t, "PUT /aGET /b", stdout.String())
+	})
+
+	t.Run("Supported", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), []string{"supported", "unsupported"}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Contains(t, stdout.String(), "-I, --head")
+		assert.NotContains(t, stdout.String(), "--header")
+
+		assert.Equal(t, 2, run(context.Background(), []string{"supported", "sometimes"}, &stdout, &stderr))
+	})
+
+	t.Run("Write-out", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), []string{"-s", "-w", `%{http_code} %{attempt}\n`, server.URL}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Equal(t, "200 1\n", stdout.String())
+	})
+
+	t.Run("Exit codes", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "gone", http.StatusGone)
+		}))
+		defer failing.Close()
+
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 0, run(context.Background(), []string{failing.URL}, &stdout, &stderr))
+		assert.Equal(t, "gone\n", stdout.String())
+
+		stdout.Reset()
+		assert.Equal(t, 22, run(context.Background(), []string{"-f", failing.URL}, &stdout, &stderr))
+		assert.Empty(t, stdout.String())
+		assert.Contains(t, stderr.String(), "gocurl: (22) The requested URL returned error: 410")
+
+		assert.Equal(t, 22, run(context.Background(), []string{"--fail-with-body", failing.URL}, &stdout, &stderr))
+		assert.Equal(t, "gone\n", stdout.String())
+	})
+
+	t.Run("Diff", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), []string{"diff", "curl -X PUT https://a.example.com", "curl https://a.example.com"}, &stdout, &stderr)
+		assert.Equal(t, 1, code)
+		assert.Equal(t, "method: \"PUT\" != \"GET\"\n", stdout.String())
+
+		stdout.Reset()
+		code = run(context.Background(), []string{"diff", "curl https://a.example.com", "curl https://a.example.com"}, &stdout, &stderr)
+		assert.Equal(t, 0, code)
+		assert.Empty(t, stdout.String())
+	})
+
+	t.Run("Bench", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), []string{"bench", "-n", "20", "-c", "4", server.URL}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Contains(t, stdout.String(), "Requests:    20 (20 succeeded, 0 failed)")
+	})
+
+	t.Run("Monitor", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), []string{"monitor", "--interval", "1ms", "--count", "2", "--expect-status", "200", server.URL}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Contains(t, stdout.String(), "state unknown -> up")
+		assert.Contains(t, stdout.String(), "2 checks, 100.00% uptime")
+
+		code = run(context.Background(), []string{"monitor", "--count", "1", "--expect-status", "204", server.URL}, &stdout, &stderr)
+		assert.Equal(t, 1, code)
+	})
+
+	t.Run("Usage errors", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, run(context.Background(), nil, &stdout, &stderr))
+		assert.Equal(t, 2, run(context.Background(), []string{"diff", "only one"}, &stdout, &stderr))
+		assert.Equal(t, 2, run(context.Background(), []string{"bench", "-n", "5"}, &stdout, &stderr))
+	})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// defaultPager pages output the way git does: less exits at once when the
+// output fits on the screen and keeps colors.
+const defaultPager = "less -FRX"
+
+// outputConfig tells how the body of a response is printed.
+type outputConfig struct {
+	// pretty is auto, always or never. With auto, JSON bodies are indented
+	// only when printed to a terminal, so redirected output stays raw.
+	pretty string
+	// pager disables the pager when false.
+	pager bool
+}
+
+// splitOutputFlags extracts the gocurl output flags --pretty[=auto|always|never]
+// and --no-pager from args.
+func splitOutputFlags(args []string) (outputConfig, []string, error) {
+	config := outputConfig{pretty: "auto", pager: true}
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch {
+		case arg == "--no-pager":
+			config.pager = false
+		case arg == "--pretty":
+			config.pretty = "always"
+		case strings.HasPrefix(arg, "--pretty="):
+			config.pretty = strings.TrimPrefix(arg, "--pretty=")
+			if config.pretty != "auto" && config.pretty != "always" && config.pretty != "never" {
+				return config, nil, fmt.Errorf("invalid --pretty value %q, expected auto, always or never", config.pretty)
+			}
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return config, rest, nil
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// formatBody indents JSON bodies when config asks for pretty output on w.
+// Other bodies are returned as is.
+func formatBody(w io.Writer, body string, config outputConfig) string {
+	if config.pretty == "never" || config.pretty == "auto" && !isTerminal(w) {
+		return body
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(body), "", "  "); err != nil {
+		return body
+	}
+	if !strings.HasSuffix(body, "\n") {
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// pagerCommand returns the pager to show output on w with, or "" when the
+// output is printed directly: the pager is disabled, w is not a terminal or
+// the output fits on the screen. The pager is $GOCURL_PAGER, $PAGER or less.
+func pagerCommand(w io.Writer, output string, config outputConfig) string {
+	if !config.pager || !isTerminal(w) || strings.Count(output, "\n") < screenLines() {
+		return ""
+	}
+	for _, name := range []string{"GOCURL_PAGER", "PAGER"} {
+		if pager, ok := os.LookupEnv(name); ok {
+			return pager
+		}
+	}
+	return defaultPager
+}
+
+// screenLines returns the height of the terminal as told by $LINES, or 24.
+func screenLines() int {
+	if lines, err := strconv.Atoi(os.Getenv("LINES")); err == nil && lines > 0 {
+		return lines
+	}
+	return 24
+}
+
+// writeOutput prints output to w, through the pager when there is one.
+func writeOutput(w io.Writer, output string, config outputConfig) error {
+	pager := pagerCommand(w, output, config)
+	if pager == "" {
+		_, err := io.WriteString(w, output)
+		return err
+	}
+	return runPager(pager, w, output)
+}
+
+// runPager pipes output through the pager shell command.
+func runPager(pager string, w io.Writer, output string) error {
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdin = strings.NewReader(output)
+	cmd.Stdout, cmd.Stderr = w, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pager failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitOutputFlags(t *testing.T) {
+	config, rest, err := splitOutputFlags([]string{"curl", "--no-pager", "-s", "--pretty=never", "https://example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, outputConfig{pretty: "never"}, config)
+	assert.Equal(t, []string{"curl", "-s", "https://example.com"}, rest)
+
+	config, _, err = splitOutputFlags([]string{"curl", "--pretty", "https://example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, outputConfig{pretty: "always", pager: true}, config)
+
+	_, _, err = splitOutputFlags([]string{"curl", "--pretty=sometimes"})
+	assert.Error(t, err)
+}
+
+func TestPrettyOutput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":1,"tags":["a"]}`)
+	}))
+	defer server.Close()
+
+	t.Run("Raw when redirected", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 0, run(context.Background(), []string{server.URL}, &stdout, &stderr), stderr.String())
+		assert.Equal(t, `{"id":1,"tags":["a"]}`, stdout.String())
+	})
+
+	t.Run("Always", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 0, run(context.Background(), []string{"--pretty=always", server.URL}, &stdout, &stderr), stderr.String())
+		assert.Equal(t, "{\n  \"id\": 1,\n  \"tags\": [\n    \"a\"\n  ]\n}\n", stdout.String())
+	})
+
+	t.Run("Not JSON", func(t *testing.T) {
+		assert.Equal(t, "plain", formatBody(nil, "plain", outputConfig{pretty: "always"}))
+	})
+}
+
+func TestRunPager(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, runPager("tr a-z A-Z", &out, "paged\n"))
+	assert.Equal(t, "PAGED\n", out.String())
+
+	// Output that is not a terminal is never paged
+	assert.Empty(t, pagerCommand(&out, "many\nlines\n", outputConfig{pager: true}))
+}
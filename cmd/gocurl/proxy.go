@@ -0,0 +1,501 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/record"
+	"github.com/maniartech/gocurl/redact"
+)
+
+// recordingProxy is an HTTP proxy printing and capturing the exchanges
+// passing through it. Bodies are buffered, so streamed responses are only
+// delivered once complete.
+type recordingProxy struct {
+	// transport forwards the requests
+	transport http.RoundTripper
+	// ca signs the certificates of intercepted TLS connections. CONNECT
+	// tunnels are passed through uninspected when nil.
+	ca *proxyCA
+	// verbose prints the headers and bodies of the exchanges
+	verbose bool
+	// commands prints the gocurl command of each request
+	commands bool
+	// redactor masks secrets in the output and captures when set
+	redactor *redact.Redactor
+	// save receives the captures as JSON lines when set
+	save io.Writer
+
+	mu     sync.Mutex // serializes output
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (p *recordingProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.connect(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "gocurl proxy: not a proxy request", http.StatusBadRequest)
+		return
+	}
+
+	resp := p.forward(r)
+	defer resp.Body.Close()
+	gocurl.RemoveHopHeaders(resp.Header)
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// forward sends r upstream, reports the exchange and returns the response
+// to relay, a 502 Bad Gateway when the upstream request failed.
+func (p *recordingProxy) forward(r *http.Request) *http.Response {
+	reqBody, err := io.ReadAll(r.Body)
+	if err != nil {
+		return errorResponse(r, fmt.Errorf("failed to read request body: %v", err))
+	}
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	out.Body = io.NopCloser(bytes.NewReader(reqBody))
+	out.ContentLength = int64(len(reqBody))
+	out.Header.Del("Content-Length")
+	gocurl.RemoveHopHeaders(out.Header)
+
+	start := time.Now()
+	resp, err := p.transport.RoundTrip(out)
+	var respBody []byte
+	if err == nil {
+		respBody, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	if err != nil {
+		p.report(record.NewExchange(out, reqBody, nil, nil, time.Since(start)), err)
+		return errorResponse(r, err)
+	}
+	p.report(record.NewExchange(out, reqBody, resp, respBody, time.Since(start)), nil)
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	resp.TransferEncoding = nil
+	if r.Method != http.MethodHead && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotModified {
+		resp.ContentLength = int64(len(respBody))
+		resp.Header.Set("Content-Length", strconv.Itoa(len(respBody)))
+	}
+	return resp
+}
+
+// connect handles CONNECT requests, intercepting the TLS connection when
+// the proxy has a CA and tunneling it otherwise.
+func (p *recordingProxy) connect(w http.ResponseWriter, r *http.Request) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "gocurl proxy: CONNECT is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var upstream net.Conn
+	if p.ca == nil {
+		var err error
+		upstream, err = net.DialTimeout("tcp", r.Host, 30*time.Second)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			p.printf("%s CONNECT %s failed: %v\n", time.Now().Format(time.TimeOnly), r.Host, err)
+			return
+		}
+	}
+
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		if upstream != nil {
+			upstream.Close()
+		}
+		return
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, "HTTP/1.1 200 Connection Established\r\n\r\n"); err != nil {
+		return
+	}
+
+	if upstream != nil {
+		defer upstream.Close()
+		p.printf("%s CONNECT %s (tunneled, use --mitm to inspect)\n", time.Now().Format(time.TimeOnly), r.Host)
+		go io.Copy(upstream, conn)
+		io.Copy(conn, upstream)
+		return
+	}
+	p.intercept(conn, r.Host)
+}
+
+// intercept terminates the TLS connection of a CONNECT tunnel to host with
+// a certificate signed by the proxy CA and forwards the requests sent on it.
+func (p *recordingProxy) intercept(conn net.Conn, host string) {
+	tlsConn := tls.Server(conn, &tls.Config{
+		NextProtos: []string{"http/1.1"},
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			name := hello.ServerName
+			if name == "" {
+				name, _, _ = net.SplitHostPort(host)
+			}
+			return p.ca.certificate(name)
+		},
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		p.printf("%s CONNECT %s TLS handshake failed: %v\n", time.Now().Format(time.TimeOnly), host, err)
+		return
+	}
+
+	reader := bufio.NewReader(tlsConn)
+	for {
+		req, err := http.ReadRequest(reader)
+		if err != nil {
+			return
+		}
+		req.URL.Scheme = "https"
+		req.URL.Host = host
+		if strings.HasSuffix(host, ":443") {
+			req.URL.Host = strings.TrimSuffix(host, ":443")
+		}
+		resp := p.forward(req)
+		gocurl.RemoveHopHeaders(resp.Header)
+		err = resp.Write(tlsConn)
+		resp.Body.Close()
+		if err != nil || req.Close {
+			return
+		}
+	}
+}
+
+// report prints the exchange and saves it. err is the upstream error, if
+// any.
+func (p *recordingProxy) report(ex record.Exchange, err error) {
+	if p.redactor != nil {
+		ex = ex.Redact(p.redactor)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result := ""
+	if err != nil {
+		result = "error: " + err.Error()
+	} else {
+		result = fmt.Sprint(ex.Response.StatusCode)
+	}
+	fmt.Fprintf(p.stdout, "%s %s %s -> %s (%v)\n", ex.StartedAt.Format(time.TimeOnly), ex.Request.Method, ex.Request.URL, result, ex.Duration.Round(time.Millisecond))
+
+	if p.verbose {
+		p.printMessage("> ", ex.Request.Header, ex.Request.Body)
+		if ex.Response != nil {
+			fmt.Fprintf(p.stdout, "< %s %d\n", ex.Response.Proto, ex.Response.StatusCode)
+			p.printMessage("< ", ex.Response.Header, ex.Response.Body)
+		}
+	}
+	if p.commands {
+		if command, err := exchangeCommand(&ex); err == nil {
+			fmt.Fprintf(p.stdout, "  %s\n", command)
+		}
+	}
+	if p.save != nil {
+		if err := json.NewEncoder(p.save).Encode(ex); err != nil {
+			fmt.Fprintf(p.stderr, "gocurl proxy: failed to save exchange: %v\n", err)
+		}
+	}
+}
+
+// printMessage prints the sorted headers and the body of a message, JSON
+// bodies being indented.
+func (p *recordingProxy) printMessage(prefix string, header http.Header, body string) {
+	var lines bytes.Buffer
+	header.Write(&lines)
+	for _, line := range strings.Split(strings.TrimSpace(lines.String()), "\r\n") {
+		if line != "" {
+			fmt.Fprintf(p.stdout, "%s%s\n", prefix, line)
+		}
+	}
+	if body != "" {
+		body = formatBody(p.stdout, body, outputConfig{pretty: "always"})
+		fmt.Fprintf(p.stdout, "%s\n", strings.TrimRight(body, "\n"))
+	}
+	fmt.Fprintln(p.stdout)
+}
+
+func (p *recordingProxy) printf(format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.stdout, format, args...)
+}
+
+// exchangeCommand returns the gocurl command sending the request of ex.
+func exchangeCommand(ex *record.Exchange) (string, error) {
+	opts, err := gocurl.ReplayRequestOptions(ex)
+	if err != nil {
+		return "", err
+	}
+	return "gocurl " + strings.TrimPrefix(opts.ToCurlCommand(), "curl "), nil
+}
+
+func errorResponse(r *http.Request, err error) *http.Response {
+	body := "gocurl proxy: " + err.Error() + "\n"
+	return &http.Response{
+		StatusCode:    http.StatusBadGateway,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       r,
+	}
+}
+
+// proxyCA is the certificate authority signing the certificates of
+// intercepted hosts.
+type proxyCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+
+	mu    sync.Mutex
+	certs map[string]*tls.Certificate
+}
+
+// loadProxyCA loads the CA stored in dir, generating it on first use. It
+// returns the path of the certificate clients must trust.
+func loadProxyCA(dir string) (*proxyCA, string, error) {
+	certPath := filepath.Join(dir, "proxy-ca.pem")
+	keyPath := filepath.Join(dir, "proxy-ca-key.pem")
+
+	certPEM, err := os.ReadFile(certPath)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := generateProxyCA(certPath, keyPath); err != nil {
+			return nil, "", err
+		}
+		certPEM, err = os.ReadFile(certPath)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read proxy CA: %v", err)
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read proxy CA key: %v", err)
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid proxy CA: %v", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid proxy CA: %v", err)
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, "", fmt.Errorf("invalid proxy CA: unsupported key type %T", pair.PrivateKey)
+	}
+	return &proxyCA{cert: cert, key: key, certs: map[string]*tls.Certificate{}}, certPath, nil
+}
+
+func generateProxyCA(certPath, keyPath string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate proxy CA: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{CommonName: "gocurl proxy CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("failed to generate proxy CA: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to generate proxy CA: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certPath), 0700); err != nil {
+		return fmt.Errorf("failed to save proxy CA: %v", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return fmt.Errorf("failed to save proxy CA key: %v", err)
+	}
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return fmt.Errorf("failed to save proxy CA: %v", err)
+	}
+	return nil
+}
+
+// certificate returns the certificate of host, signing it on first use.
+func (ca *proxyCA) certificate(host string) (*tls.Certificate, error) {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	if cert, ok := ca.certs[host]; ok {
+		return cert, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, err
+	}
+	cert := &tls.Certificate{Certificate: [][]byte{der, ca.cert.Raw}, PrivateKey: key}
+	ca.certs[host] = cert
+	return cert, nil
+}
+
+func randomSerial() *big.Int {
+	serial, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	return serial
+}
+
+// runProxy runs a local proxy printing the traffic of the clients using
+// it, optionally intercepting their TLS connections.
+func runProxy(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("proxy", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	port := flags.Int("port", 8888, "port to listen on")
+	listen := flags.String("listen", "127.0.0.1", "address to listen on")
+	mitm := flags.Bool("mitm", false, "intercept TLS connections with a generated CA")
+	save := flags.String("save", "", "append the captured exchanges to this file as JSON lines")
+	verbose := flags.Bool("verbose", false, "print headers and bodies")
+	commands := flags.Bool("commands", false, "print the gocurl command of each request")
+	mask := flags.Bool("redact", false, "mask credentials in the output and captures")
+	insecure := flags.Bool("insecure", false, "do not verify upstream certificates")
+	convert := flags.String("convert", "", "print the gocurl commands of a capture file and exit")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		fmt.Fprintln(stderr, "usage: gocurl proxy [--port 8888] [--mitm] [--save file] [--verbose] [--commands] | --convert file")
+		return 2
+	}
+
+	if *convert != "" {
+		return convertCaptures(*convert, *mask, stdout, stderr)
+	}
+
+	p := &recordingProxy{
+		transport: &http.Transport{
+			Proxy:              nil,
+			DisableCompression: true,
+			TLSClientConfig:    &tls.Config{InsecureSkipVerify: *insecure},
+		},
+		verbose:  *verbose,
+		commands: *commands,
+		stdout:   stdout,
+		stderr:   stderr,
+	}
+	if *mask {
+		p.redactor = redact.Default
+	}
+	if *save != "" {
+		file, err := os.OpenFile(*save, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			fmt.Fprintf(stderr, "gocurl proxy: %v\n", err)
+			return 1
+		}
+		defer file.Close()
+		p.save = file
+	}
+
+	var caPath string
+	if *mitm {
+		dir, err := configDir()
+		if err == nil {
+			p.ca, caPath, err = loadProxyCA(dir)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "gocurl proxy: %v\n", err)
+			return 1
+		}
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(*listen, fmt.Sprint(*port)))
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl proxy: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "gocurl proxy listening on http://%s\n", listener.Addr())
+	if caPath != "" {
+		fmt.Fprintf(stdout, "intercepting TLS, clients must trust %s\n", caPath)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	server := &http.Server{Handler: p}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(stderr, "gocurl proxy: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// convertCaptures prints the gocurl commands of the exchanges saved in
+// path.
+func convertCaptures(path string, mask bool, stdout, stderr io.Writer) int {
+	exchanges, err := record.Load(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl proxy: %v\n", err)
+		return 1
+	}
+	for _, ex := range exchanges {
+		if mask {
+			ex = ex.Redact(redact.Default)
+		}
+		command, err := exchangeCommand(&ex)
+		if err != nil {
+			fmt.Fprintf(stderr, "gocurl proxy: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, command)
+	}
+	return 0
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maniartech/gocurl/record"
+)
+
+func TestRecordingProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"method":%q,"body":%q}`, r.Method, body)
+	}))
+	defer upstream.Close()
+
+	var stdout, saved bytes.Buffer
+	p := &recordingProxy{
+		transport: http.DefaultTransport.(*http.Transport).Clone(),
+		verbose:   true,
+		commands:  true,
+		save:      &saved,
+		stdout:    &stdout,
+		stderr:    io.Discard,
+	}
+	p.transport.(*http.Transport).Proxy = nil
+	proxy := httptest.NewServer(p)
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	resp, err := client.Post(upstream.URL+"/items", "application/json", strings.NewReader(`{"name":"x"}`))
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"method":"POST","body":"{\"name\":\"x\"}"}`, string(body))
+
+	output := stdout.String()
+	assert.Contains(t, output, "POST "+upstream.URL+"/items -> 200")
+	assert.Contains(t, output, "> Content-Type: application/json")
+	assert.Contains(t, output, "  \"method\": \"POST\"")
+	assert.Contains(t, output, "  gocurl "+upstream.URL+"/items")
+	assert.Contains(t, output, `--data-raw '{"name":"x"}'`)
+
+	var ex record.Exchange
+	require.NoError(t, json.NewDecoder(&saved).Decode(&ex))
+	assert.Equal(t, upstream.URL+"/items", ex.Request.URL)
+	assert.Equal(t, `{"name":"x"}`, ex.Request.Body)
+	assert.Equal(t, 200, ex.Response.StatusCode)
+
+	t.Run("Not a proxy request", func(t *testing.T) {
+		resp, err := http.Get(proxy.URL + "/items")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Upstream failure", func(t *testing.T) {
+		stdout.Reset()
+		resp, err := client.Get("http://127.0.0.1:1/down")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+		assert.Contains(t, stdout.String(), "GET http://127.0.0.1:1/down -> error:")
+	})
+}
+
+func TestRecordingProxyMITM(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.URL.Path, r.Header.Get("Authorization"))
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	ca, caPath, err := loadProxyCA(dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "proxy-ca.pem"), caPath)
+
+	var stdout bytes.Buffer
+	p := &recordingProxy{
+		transport: upstream.Client().Transport,
+		ca:        ca,
+		stdout:    &stdout,
+		stderr:    io.Discard,
+	}
+	proxy := httptest.NewServer(p)
+	defer proxy.Close()
+
+	caPEM, err := os.ReadFile(caPath)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(caPEM))
+	proxyURL, _ := url.Parse(proxy.URL)
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyURL(proxyURL),
+		TLSClientConfig: &tls.Config{RootCAs: roots},
+	}}
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", upstream.URL+"/secure", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "GET /secure Bearer token", string(body))
+	}
+	assert.Equal(t, 2, strings.Count(stdout.String(), "GET "+upstream.URL+"/secure -> 200"))
+
+	t.Run("Reuses the stored CA", func(t *testing.T) {
+		again, _, err := loadProxyCA(dir)
+		require.NoError(t, err)
+		assert.Equal(t, ca.cert.Raw, again.cert.Raw)
+	})
+}
+
+func TestProxyConvert(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "captures.jsonl")
+	recorder := record.NewRecorder()
+	req := httptest.NewRequest("PUT", "https://api.example.com/items/1", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	recorder.Record(req, []byte(`{"done":true}`), nil, nil, 0)
+	require.NoError(t, recorder.Save(path))
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"proxy", "--convert", path, "--redact"}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "gocurl -X PUT")
+	assert.Contains(t, stdout.String(), "https://api.example.com/items/1")
+	assert.NotContains(t, stdout.String(), "secret")
+
+	assert.Equal(t, 2, run(context.Background(), []string{"proxy", "extra"}, &stdout, &stderr))
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/record"
+)
+
+// runServe serves recorded exchanges as a local stub of the API they were
+// recorded from.
+func runServe(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	from := flags.String("from", "", "directory or file of recorded exchanges")
+	port := flags.Int("port", 8080, "port to listen on")
+	listen := flags.String("listen", "127.0.0.1", "address to listen on")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *from == "" || flags.NArg() != 0 {
+		fmt.Fprintln(stderr, "usage: gocurl serve --from <recordings> [--port 8080] [--listen 127.0.0.1]")
+		return 2
+	}
+
+	stub, err := loadStub(*from, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl serve: %v\n", err)
+		return 1
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(*listen, fmt.Sprint(*port)))
+	if err != nil {
+		fmt.Fprintf(stderr, "gocurl serve: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "serving %d recorded exchanges on http://%s\n", stub.Len(), listener.Addr())
+	for _, route := range stub.Routes() {
+		fmt.Fprintf(stdout, "  %s\n", route)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	server := &http.Server{Handler: stub}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(stderr, "gocurl serve: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// loadStub loads the recordings of from into a stub logging the requests
+// it answers to stdout.
+func loadStub(from string, stdout io.Writer) (*record.Stub, error) {
+	exchanges, err := record.LoadDir(from)
+	if err != nil {
+		return nil, err
+	}
+	stub := record.NewStub(exchanges)
+	if stub.Len() == 0 {
+		return nil, fmt.Errorf("no recorded responses in %s", from)
+	}
+
+	var mu sync.Mutex
+	stub.OnRequest = func(r *http.Request, ex *record.Exchange) {
+		result := "404 (no recording)"
+		if ex != nil {
+			result = fmt.Sprint(ex.Response.StatusCode)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(stdout, "%s %s %s -> %s\n", time.Now().Format(time.TimeOnly), r.Method, r.URL.RequestURI(), result)
+	}
+	return stub, nil
+}
+
+// splitOfflineFlag extracts --offline and --offline=<recordings> from args.
+// Offline requests are answered from the recordings only, never the network.
+func splitOfflineFlag(args []string) (offline bool, recordings string, rest []string) {
+	rest = make([]string, 0, len(args))
+	for _, arg := range args {
+		switch {
+		case arg == "--offline":
+			offline = true
+		case strings.HasPrefix(arg, "--offline="):
+			offline = true
+			recordings = strings.TrimPrefix(arg, "--offline=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return offline, recordings, rest
+}
+
+// offlineClient returns a client answering requests from the recordings of
+// from, or refusing them all when from is empty.
+func offlineClient(from string) (*gocurl.Client, error) {
+	client := gocurl.NewClient().SetOfflineMode(true)
+	if from == "" {
+		return client, nil
+	}
+	exchanges, err := record.LoadDir(from)
+	if err != nil {
+		return nil, err
+	}
+	return client.SetOfflineRecordings(exchanges), nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maniartech/gocurl/record"
+)
+
+func TestServe(t *testing.T) {
+	dir := t.TempDir()
+	recorder := record.NewRecorder()
+	req := httptest.NewRequest("GET", "https://api.example.com/users/1", nil)
+	resp := &http.Response{StatusCode: 200, Header: http.Header{"Content-Type": {"application/json"}}}
+	recorder.Record(req, nil, resp, []byte(`{"id":1}`), 0)
+	require.NoError(t, recorder.Save(filepath.Join(dir, "users.jsonl")))
+
+	var stdout bytes.Buffer
+	stub, err := loadStub(dir, &stdout)
+	require.NoError(t, err)
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	got, err := http.Get(server.URL + "/users/1")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(got.Body)
+	got.Body.Close()
+	assert.Equal(t, 200, got.StatusCode)
+	assert.Equal(t, `{"id":1}`, string(body))
+	assert.Contains(t, stdout.String(), "GET /users/1 -> 200")
+
+	got, err = http.Get(server.URL + "/users/2")
+	require.NoError(t, err)
+	got.Body.Close()
+	assert.Equal(t, 404, got.StatusCode)
+	assert.Contains(t, stdout.String(), "GET /users/2 -> 404 (no recording)")
+
+	t.Run("Offline", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), []string{"--offline=" + dir, "https://api.example.com/users/1"}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Equal(t, `{"id":1}`, stdout.String())
+
+		stdout.Reset()
+		code = run(context.Background(), []string{"--offline", server.URL + "/users/1"}, &stdout, &stderr)
+		assert.Equal(t, 7, code)
+		assert.Contains(t, stderr.String(), "offline")
+	})
+
+	t.Run("Usage", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, run(context.Background(), []string{"serve"}, &stdout, &stderr))
+		assert.Equal(t, 1, run(context.Background(), []string{"serve", "--from", t.TempDir()}, &stdout, &stderr))
+		assert.Contains(t, stderr.String(), "no recorded responses")
+	})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/maniartech/gocurl"
+)
+
+// runSupported prints the curl flags gocurl knows with their support status:
+//
+//	gocurl supported [-json] [full|partial|unsupported]
+func runSupported(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("supported", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "print the flags as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 1 {
+		fmt.Fprintln(stderr, "usage: gocurl supported [-json] [full|partial|unsupported]")
+		return 2
+	}
+
+	var curlFlags []gocurl.CurlFlag
+	for _, f := range gocurl.CurlFlags() {
+		if flags.NArg() == 0 || f.Support.String() == flags.Arg(0) {
+			curlFlags = append(curlFlags, f)
+		}
+	}
+	if len(curlFlags) == 0 {
+		fmt.Fprintf(stderr, "gocurl supported: unknown support status %q\n", flags.Arg(0))
+		return 2
+	}
+
+	if *asJSON {
+		type flagJSON struct {
+			gocurl.CurlFlag
+			Support string `json:"support"`
+		}
+		out := make([]flagJSON, len(curlFlags))
+		for i, f := range curlFlags {
+			out[i] = flagJSON{f, f.Support.String()}
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(out)
+		return 0
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FLAG\tSUPPORT\tNOTE")
+	for _, f := range curlFlags {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", f, f.Support, f.Note)
+	}
+	w.Flush()
+	return 0
+}
@@ -0,0 +1,140 @@
+package gocurl
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// SetCoalescing makes identical concurrent GET and HEAD requests of the
+// client share a single network call, whose response is handed to each of
+// them, cutting duplicate traffic in fan-out services. Requests are
+// identical when their normalized URLs, headers, credentials and cookies
+// are; requests authenticated by a signer, interceptors, middleware or a
+// client certificate are never coalesced. The requests joining one in
+// flight wait for it within their own context but share its outcome,
+// including its failure should the context of the first one be canceled;
+// they are not counted by the client's rate limiter, circuit breaker or
+// accounting, and their Result is not filled.
+func (c *Client) SetCoalescing(enabled bool) *Client {
+	c.coalescing = enabled
+	return c
+}
+
+// coalescedCall is a request in flight other identical ones wait for.
+type coalescedCall struct {
+	done chan struct{}
+	resp *http.Response
+	body string
+	err  error
+}
+
+// coalesce runs send for the first of the identical requests of opts in
+// flight and hands its response to the others.
+func (c *Client) coalesce(ctx context.Context, opts *options.RequestOptions, send func() (*http.Response, string, error)) (*http.Response, string, error) {
+	key := coalesceKey(opts)
+	c.mu.Lock()
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+			return copyResponse(call.resp, call.body), call.body, call.err
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		}
+	}
+	call := &coalescedCall{done: make(chan struct{})}
+	if c.inflight == nil {
+		c.inflight = map[string]*coalescedCall{}
+	}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	call.resp, call.body, call.err = send()
+	c.mu.Lock()
+	delete(c.inflight, key)
+	c.mu.Unlock()
+	close(call.done)
+	return call.resp, call.body, call.err
+}
+
+// coalescable reports whether the request of opts can share the response of
+// an identical one: it reads without a body or side effects, and does not
+// authenticate in ways its key does not capture, through a signer,
+// interceptors, middleware or a client certificate.
+func coalescable(opts *options.RequestOptions) bool {
+	method := strings.ToUpper(opts.Method)
+	if method != "" && method != "GET" && method != "HEAD" {
+		return false
+	}
+	if opts.Signer != nil || len(opts.Interceptors) > 0 || len(opts.Middleware) > 0 ||
+		opts.CertFile != "" || opts.KeyFile != "" || opts.TLSConfig != nil {
+		return false
+	}
+	return opts.Body == "" && opts.BodyReader == nil && len(opts.Form) == 0 && opts.FileUpload == nil &&
+		opts.UploadFile == "" && opts.OutputFile == "" && opts.ResponseTee == nil
+}
+
+// coalesceKey identifies the request of opts among concurrent ones.
+func coalesceKey(opts *options.RequestOptions) string {
+	var b strings.Builder
+	method := strings.ToUpper(opts.Method)
+	if method == "" {
+		method = "GET"
+	}
+	b.WriteString(method + " " + normalizeURL(opts) + "\n")
+
+	keys := make([]string, 0, len(opts.Headers))
+	for key := range opts.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.WriteString(http.CanonicalHeaderKey(key) + ": " + strings.Join(opts.Headers[key], ", ") + "\n")
+	}
+	if opts.BasicAuth != nil {
+		b.WriteString("basic: " + opts.BasicAuth.Username + ":" + opts.BasicAuth.Password + "\n")
+	}
+	b.WriteString("bearer: " + opts.BearerToken + "\nagent: " + opts.UserAgent + "\nreferer: " + opts.Referer + "\n")
+	// Proxies may answer differently, or on behalf of another identity
+	b.WriteString("proxy: " + opts.Proxy + "\npac: " + opts.ProxyPAC + "\n")
+	for _, cookie := range opts.Cookies {
+		b.WriteString("cookie: " + cookie.String() + "\n")
+	}
+	return b.String()
+}
+
+// normalizeURL returns the URL of opts with its query parameters merged and
+// sorted and its scheme and host in lower case.
+func normalizeURL(opts *options.RequestOptions) string {
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return opts.URL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	query := u.Query()
+	for key, values := range opts.QueryParams {
+		query[key] = append(query[key], values...)
+	}
+	u.RawQuery = query.Encode()
+	u.Fragment = ""
+	return u.String()
+}
+
+// copyResponse returns a copy of resp reading body, for a request sharing
+// its response.
+func copyResponse(resp *http.Response, body string) *http.Response {
+	if resp == nil {
+		return nil
+	}
+	copied := *resp
+	copied.Header = resp.Header.Clone()
+	copied.Body = io.NopCloser(strings.NewReader(body))
+	return &copied
+}
@@ -0,0 +1,109 @@
+package gocurl_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/middlewares"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCoalescing(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		w.Header().Set("X-Hit", fmt.Sprint(n))
+		io.WriteString(w, "body "+r.URL.RawQuery)
+	}))
+	defer server.Close()
+	client := gocurl.NewClient().SetCoalescing(true)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	bodies := make(chan string, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// The same query in another order is the same request
+			url := server.URL + "/slow?a=1&b=2"
+			if i%2 == 1 {
+				url = server.URL + "/slow?b=2&a=1"
+			}
+			resp, body, err := client.Curl(ctx, "-s", url)
+			require.NoError(t, err)
+			read, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, body, string(read))
+			assert.Equal(t, "1", resp.Header.Get("X-Hit"))
+			bodies <- body
+		}(i)
+	}
+	// Let the requests join the first
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(bodies)
+	for body := range bodies {
+		assert.Contains(t, body, "body ")
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&hits))
+
+	// Requests that differ, or are not concurrent, are not coalesced
+	_, _, err := client.Curl(ctx, "-s", "-H", "X-Other: 1", server.URL+"/fast")
+	require.NoError(t, err)
+	_, _, err = client.Curl(ctx, "-s", server.URL+"/fast")
+	require.NoError(t, err)
+	_, _, err = client.Curl(ctx, "-s", "-d", "x", server.URL+"/fast")
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, atomic.LoadInt32(&hits))
+}
+
+func TestClientCoalescingInterceptors(t *testing.T) {
+	var hits int32
+	arrived := make(chan struct{}, 2)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		arrived <- struct{}{}
+		<-release
+		io.WriteString(w, "data for "+r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+	client := gocurl.NewClient().SetCoalescing(true)
+
+	// Requests authenticating through interceptors never share a response
+	var wg sync.WaitGroup
+	for _, tenant := range []string{"tenant-A", "tenant-B"} {
+		wg.Add(1)
+		go func(tenant string) {
+			defer wg.Done()
+			opts := &options.RequestOptions{URL: server.URL, Silent: true}
+			opts.Interceptors = []middlewares.Interceptor{func(req *http.Request, next middlewares.RoundTripFunc) (*http.Response, error) {
+				req.Header.Set("Authorization", tenant)
+				return next(req)
+			}}
+			_, body, err := client.Process(context.Background(), opts)
+			require.NoError(t, err)
+			assert.Equal(t, "data for "+tenant, body)
+		}(tenant)
+	}
+	<-arrived
+	<-arrived
+	close(release)
+	wg.Wait()
+	assert.EqualValues(t, 2, atomic.LoadInt32(&hits))
+}
@@ -0,0 +1,102 @@
+package gocurl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CompareOptions controls which parts of two responses CompareResponses
+// takes into account.
+type CompareOptions struct {
+	// IgnoreHeaders lists response headers that are expected to differ
+	IgnoreHeaders []string
+
+	// IgnorePaths lists JSON body paths, such as "$.meta.requestId", that are
+	// expected to differ
+	IgnorePaths []string
+}
+
+// DefaultIgnoredHeaders are the volatile headers ignored when no
+// CompareOptions are given.
+var DefaultIgnoredHeaders = []string{"Date", "Content-Length", "Age", "Expires", "Set-Cookie", "X-Request-Id"}
+
+// CompareResponses runs command once with each variable set, e.g. to send the
+// same request to staging and production, and reports the differences
+// between the responses: status, headers and body. JSON bodies are compared
+// semantically, ignoring key and array order. A nil compare ignores
+// DefaultIgnoredHeaders.
+func CompareResponses(ctx context.Context, command string, envA, envB Variables, compare *CompareOptions) (*Diff, error) {
+	if compare == nil {
+		compare = &CompareOptions{IgnoreHeaders: DefaultIgnoredHeaders}
+	}
+
+	respA, bodyA, err := curlWithVars(ctx, envA, command)
+	if err != nil {
+		return nil, fmt.Errorf("first request failed: %v", err)
+	}
+	respB, bodyB, err := curlWithVars(ctx, envB, command)
+	if err != nil {
+		return nil, fmt.Errorf("second request failed: %v", err)
+	}
+
+	return DiffResponses(respA, bodyA, respB, bodyB, compare), nil
+}
+
+// DiffResponses reports the differences between two responses and their
+// bodies as described by CompareResponses.
+func DiffResponses(respA *http.Response, bodyA string, respB *http.Response, bodyB string, compare *CompareOptions) *Diff {
+	if compare == nil {
+		compare = &CompareOptions{}
+	}
+	d := &Diff{}
+
+	d.add("status", "", fmt.Sprint(respA.StatusCode), fmt.Sprint(respB.StatusCode))
+
+	headersA, headersB := url.Values(respA.Header.Clone()), url.Values(respB.Header.Clone())
+	for _, name := range compare.IgnoreHeaders {
+		name = http.CanonicalHeaderKey(name)
+		delete(headersA, name)
+		delete(headersB, name)
+	}
+	diffValues(d, "header", headersA, headersB)
+
+	var ja, jb interface{}
+	if json.Unmarshal([]byte(bodyA), &ja) == nil && json.Unmarshal([]byte(bodyB), &jb) == nil {
+		bodyDiff := &Diff{}
+		diffJSON(bodyDiff, "body", "$", ja, jb, true)
+		for _, diff := range bodyDiff.Differences {
+			if !ignoredPath(diff.Key, compare.IgnorePaths) {
+				d.Differences = append(d.Differences, diff)
+			}
+		}
+	} else {
+		d.add("body", "", bodyA, bodyB)
+	}
+
+	return d
+}
+
+// ignoredPath reports whether path equals or lies below one of ignored.
+func ignoredPath(path string, ignored []string) bool {
+	for _, prefix := range ignored {
+		if path == prefix || strings.HasPrefix(path, prefix+".") || strings.HasPrefix(path, prefix+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// curlWithVars executes command silently after expanding vars.
+func curlWithVars(ctx context.Context, vars Variables, command ...string) (*http.Response, string, error) {
+	opts, err := parseCommandWithVars(vars, command...)
+	if err != nil {
+		return nil, "", err
+	}
+	opts.Silent = true
+
+	return Process(ctx, opts)
+}
@@ -0,0 +1,56 @@
+package gocurl_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareResponses(t *testing.T) {
+	newServer := func(env, body string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Env", env)
+			w.Header().Set("X-Request-Id", env+"-1")
+			fmt.Fprint(w, body)
+		}))
+	}
+	staging := newServer("staging", `{"items":[1,2,3],"meta":{"version":"2","id":"a"}}`)
+	defer staging.Close()
+	production := newServer("production", `{"meta":{"id":"b","version":"1"},"items":[3,2,1]}`)
+	defer production.Close()
+
+	command := "curl -H 'Accept: application/json' $BASE_URL/items"
+	envA := gocurl.Variables{"BASE_URL": staging.URL}
+	envB := gocurl.Variables{"BASE_URL": production.URL}
+
+	t.Run("Default options", func(t *testing.T) {
+		diff, err := gocurl.CompareResponses(context.Background(), command, envA, envB, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []gocurl.Difference{
+			{Field: "header", Key: "X-Env", A: "staging", B: "production"},
+			{Field: "body", Key: "$.meta.id", A: `"a"`, B: `"b"`},
+			{Field: "body", Key: "$.meta.version", A: `"2"`, B: `"1"`},
+		}, diff.Differences)
+	})
+
+	t.Run("Ignore lists", func(t *testing.T) {
+		diff, err := gocurl.CompareResponses(context.Background(), command, envA, envB, &gocurl.CompareOptions{
+			IgnoreHeaders: append([]string{"x-env"}, gocurl.DefaultIgnoredHeaders...),
+			IgnorePaths:   []string{"$.meta"},
+		})
+		require.NoError(t, err)
+		assert.True(t, diff.Equal(), diff.String())
+	})
+
+	t.Run("Request failure", func(t *testing.T) {
+		_, err := gocurl.CompareResponses(context.Background(), command, envA, gocurl.Variables{"BASE_URL": "http://127.0.0.1:1"}, nil)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,356 @@
+// Package compat checks that gocurl sends the same requests as curl.
+//
+// It runs curl commands through both the system curl, when installed, and
+// gocurl against a local echo server, and reports how the requests they sent
+// differ. The corpus of real-world commands gocurl is tested with is
+// exported, and users can run their own commands the same way, e.g. as
+// regression tests:
+//
+//	commands, err := compat.LoadCorpus("testdata/commands.txt")
+//	...
+//	results, err := compat.Run(ctx, commands, nil)
+//	for _, r := range results {
+//		if r.Unexpected() {
+//			t.Errorf("%s:\n%s", r.Command, strings.Join(r.Diffs, "\n"))
+//		}
+//	}
+package compat
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/tokenizer"
+)
+
+// Placeholder is replaced in commands by the URL of the echo server.
+const Placeholder = "{{url}}"
+
+//go:embed corpus.txt
+var corpus string
+
+// Command is a command of a corpus.
+type Command struct {
+	Line string
+
+	// Known describes a known difference with curl, the command is then
+	// expected to differ
+	Known string
+}
+
+// Corpus returns the commands gocurl is tested with.
+func Corpus() []Command {
+	commands, _ := ParseCorpus(strings.NewReader(corpus))
+	return commands
+}
+
+// ParseCorpus reads a corpus of commands: one per line, continued on the next
+// line when it ends with a backslash. Blank lines and lines starting with #
+// are ignored, except for "# known: reason" comments, which set the Known
+// difference of the command that follows.
+func ParseCorpus(r io.Reader) ([]Command, error) {
+	var commands []Command
+	var current strings.Builder
+	var known string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if current.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "#")) {
+			if reason, ok := strings.CutPrefix(trimmed, "# known:"); ok {
+				known = strings.TrimSpace(reason)
+			}
+			continue
+		}
+		current.WriteString(line)
+		if strings.HasSuffix(line, `\`) {
+			current.WriteString("\n")
+			continue
+		}
+		commands = append(commands, Command{Line: current.String(), Known: known})
+		current.Reset()
+		known = ""
+	}
+	if current.Len() > 0 {
+		commands = append(commands, Command{Line: current.String(), Known: known})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %v", err)
+	}
+	return commands, nil
+}
+
+// LoadCorpus reads the corpus file at path, as ParseCorpus does.
+func LoadCorpus(path string) ([]Command, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open corpus: %v", err)
+	}
+	defer file.Close()
+	return ParseCorpus(file)
+}
+
+// Request is a request as received by the echo server.
+type Request struct {
+	Method string
+	URI    string
+	Header http.Header
+	Body   string
+}
+
+// Options controls how requests are compared.
+type Options struct {
+	// CurlPath is the curl binary, found in PATH when empty
+	CurlPath string
+
+	// SkipCurl runs commands through gocurl only
+	SkipCurl bool
+
+	// IgnoreHeaders lists request headers that are expected to differ
+	IgnoreHeaders []string
+
+	// Defaults maps headers to the value curl or Go send by default. Such a
+	// header sent by only one of them with its default value is not a
+	// difference.
+	Defaults map[string]string
+}
+
+// DefaultOptions are the options used when Run is given none.
+var DefaultOptions = Options{
+	IgnoreHeaders: []string{"User-Agent"},
+	Defaults: map[string]string{
+		"Accept":          "*/*",
+		"Accept-Encoding": "gzip",
+	},
+}
+
+// Result is the outcome of running one command.
+type Result struct {
+	// Command is the command run, with Placeholder replaced
+	Command string
+	Known   string
+
+	// Curl and Gocurl are the requests each sent, several when following
+	// redirects
+	Curl   []Request
+	Gocurl []Request
+
+	// CurlErr and GocurlErr are the errors each failed with
+	CurlErr   error
+	GocurlErr error
+
+	// Diffs describes each difference between the requests, empty when they
+	// are the same
+	Diffs []string
+
+	// Skipped is why the command was not compared, e.g. when curl is not
+	// installed
+	Skipped string
+}
+
+// Unexpected reports whether the result is not the expected one: a command
+// differing from curl, or one with a known difference that no longer
+// differs.
+func (r *Result) Unexpected() bool {
+	return r.Skipped == "" && (len(r.Diffs) > 0) != (r.Known != "")
+}
+
+// Run runs each command through curl and gocurl against a local echo server
+// and compares the requests they sent. Commands refer to the server with
+// Placeholder. A nil opts uses DefaultOptions.
+//
+// When curl is not installed, commands are only run through gocurl and their
+// results are marked as skipped.
+func Run(ctx context.Context, commands []Command, opts *Options) ([]Result, error) {
+	if opts == nil {
+		opts = &DefaultOptions
+	}
+	curlPath := opts.CurlPath
+	if curlPath == "" && !opts.SkipCurl {
+		curlPath, _ = exec.LookPath("curl")
+	}
+
+	server := newEchoServer()
+	defer server.Close()
+
+	var results []Result
+	for _, c := range commands {
+		command := strings.ReplaceAll(c.Line, Placeholder, server.URL)
+		result := Result{Command: command, Known: c.Known}
+
+		server.reset()
+		_, _, result.GocurlErr = gocurl.Curl(ctx, command)
+		result.Gocurl = server.reset()
+
+		if opts.SkipCurl {
+			result.Skipped = "curl is skipped"
+			results = append(results, result)
+			continue
+		}
+		if curlPath == "" {
+			result.Skipped = "curl is not installed"
+			results = append(results, result)
+			continue
+		}
+		args, err := tokenizer.Split(command)
+		if err != nil {
+			return nil, fmt.Errorf("invalid command %q: %v", command, err)
+		}
+		if len(args) > 0 && args[0] == "curl" {
+			args = args[1:]
+		}
+		cmd := exec.CommandContext(ctx, curlPath, append([]string{"--silent", "--show-error"}, args...)...)
+		cmd.Stdout = io.Discard
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			result.CurlErr = fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		result.Curl = server.reset()
+
+		result.Diffs = Compare(result.Curl, result.Gocurl, opts)
+		if (result.CurlErr == nil) != (result.GocurlErr == nil) {
+			result.Diffs = append(result.Diffs, fmt.Sprintf("error: curl %v, gocurl %v", result.CurlErr, result.GocurlErr))
+		}
+		results = append(results, result)
+	}
+	return results, ctx.Err()
+}
+
+// Compare describes the differences between the requests sent by curl and
+// by gocurl.
+func Compare(curl, gocurl []Request, opts *Options) []string {
+	if opts == nil {
+		opts = &DefaultOptions
+	}
+	var diffs []string
+	if len(curl) != len(gocurl) {
+		return []string{fmt.Sprintf("requests: curl sent %d, gocurl %d", len(curl), len(gocurl))}
+	}
+	for i := range curl {
+		prefix := ""
+		if len(curl) > 1 {
+			prefix = fmt.Sprintf("request %d: ", i+1)
+		}
+		a, b := normalize(curl[i]), normalize(gocurl[i])
+		if a.Method != b.Method {
+			diffs = append(diffs, fmt.Sprintf("%smethod: curl %s, gocurl %s", prefix, a.Method, b.Method))
+		}
+		if a.URI != b.URI {
+			diffs = append(diffs, fmt.Sprintf("%sURI: curl %q, gocurl %q", prefix, a.URI, b.URI))
+		}
+		for _, diff := range compareHeaders(a.Header, b.Header, opts) {
+			diffs = append(diffs, prefix+diff)
+		}
+		if a.Body != b.Body {
+			diffs = append(diffs, fmt.Sprintf("%sbody: curl %q, gocurl %q", prefix, a.Body, b.Body))
+		}
+	}
+	return diffs
+}
+
+func compareHeaders(a, b http.Header, opts *Options) []string {
+	ignored := map[string]bool{}
+	for _, name := range opts.IgnoreHeaders {
+		ignored[http.CanonicalHeaderKey(name)] = true
+	}
+	names := map[string]bool{}
+	for name := range a {
+		names[name] = true
+	}
+	for name := range b {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var diffs []string
+	for _, name := range sorted {
+		if ignored[name] {
+			continue
+		}
+		va, vb := strings.Join(a.Values(name), ", "), strings.Join(b.Values(name), ", ")
+		if va == vb {
+			continue
+		}
+		if def, ok := opts.Defaults[name]; ok && (va == "" && vb == def || vb == "" && va == def) {
+			continue
+		}
+		diffs = append(diffs, fmt.Sprintf("header %s: curl %q, gocurl %q", name, va, vb))
+	}
+	return diffs
+}
+
+// normalize replaces the random boundary of multipart requests, so they can
+// be compared.
+func normalize(r Request) Request {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return r
+	}
+	boundary := params["boundary"]
+	r.Header = r.Header.Clone()
+	r.Header.Set("Content-Type", strings.ReplaceAll(r.Header.Get("Content-Type"), boundary, "BOUNDARY"))
+	r.Body = strings.ReplaceAll(r.Body, boundary, "BOUNDARY")
+	if r.Header.Get("Content-Length") != "" {
+		r.Header.Set("Content-Length", fmt.Sprint(len(r.Body)))
+	}
+	return r
+}
+
+// echoServer captures the requests it receives and answers them with an
+// empty body.
+type echoServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []Request
+}
+
+func newEchoServer() *echoServer {
+	s := &echoServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		header := r.Header.Clone()
+		// net/http moves these out of the headers
+		if r.ContentLength > 0 {
+			header.Set("Content-Length", fmt.Sprint(r.ContentLength))
+		}
+		if len(r.TransferEncoding) > 0 {
+			header.Set("Transfer-Encoding", strings.Join(r.TransferEncoding, ", "))
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{Method: r.Method, URI: r.RequestURI, Header: header, Body: string(body)})
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	return s
+}
+
+// reset returns the requests received since the last reset.
+func (s *echoServer) reset() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := s.requests
+	s.requests = nil
+	return requests
+}
@@ -0,0 +1,113 @@
+package compat_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl/compat"
+	"github.com/maniartech/gocurl/tokenizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorpus(t *testing.T) {
+	results, err := compat.Run(context.Background(), compat.Corpus(), nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	for _, r := range results {
+		if r.Skipped != "" {
+			t.Skipf("%s: %s", r.Command, r.Skipped)
+		}
+		if r.Unexpected() {
+			if r.Known != "" {
+				t.Errorf("%s: known difference %q no longer differs, remove it from the corpus", r.Command, r.Known)
+			} else {
+				t.Errorf("%s:\n\t%s", r.Command, strings.Join(r.Diffs, "\n\t"))
+			}
+		}
+	}
+}
+
+func TestParseCorpus(t *testing.T) {
+	commands, err := compat.ParseCorpus(strings.NewReader(`
+# comment
+curl {{url}}/a
+
+# known: not yet
+curl {{url}}/b \
+  -H 'X: 1'
+curl {{url}}/c`))
+	require.NoError(t, err)
+	assert.Equal(t, []compat.Command{
+		{Line: "curl {{url}}/a"},
+		{Line: "curl {{url}}/b \\\n  -H 'X: 1'", Known: "not yet"},
+		{Line: "curl {{url}}/c"},
+	}, commands)
+}
+
+func TestCompare(t *testing.T) {
+	curl := []compat.Request{{
+		Method: "POST",
+		URI:    "/upload",
+		Header: http.Header{
+			"Accept":       {"*/*"},
+			"User-Agent":   {"curl/8.0"},
+			"Content-Type": {"multipart/form-data; boundary=------abc"},
+		},
+		Body: "--------abc\r\nx\r\n--------abc--\r\n",
+	}}
+	gocurl := []compat.Request{{
+		Method: "POST",
+		URI:    "/upload",
+		Header: http.Header{
+			"Accept-Encoding": {"gzip"},
+			"User-Agent":      {"gocurl"},
+			"Content-Type":    {"multipart/form-data; boundary=0123456789"},
+		},
+		Body: "--0123456789\r\nx\r\n--0123456789--\r\n",
+	}}
+	assert.Empty(t, compat.Compare(curl, gocurl, nil))
+
+	gocurl[0].Method = "PUT"
+	gocurl[0].Header.Set("X-Extra", "1")
+	assert.Equal(t, []string{
+		`method: curl POST, gocurl PUT`,
+		`header X-Extra: curl "", gocurl "1"`,
+	}, compat.Compare(curl, gocurl, nil))
+	assert.Len(t, compat.Compare(curl, nil, nil), 1)
+}
+
+// fuzzFlags are the flags fuzzed commands may use: none of them touches
+// files.
+var fuzzFlags = map[string]bool{
+	"-X": true, "-H": true, "-d": true, "--data": true, "--data-raw": true, "-u": true,
+	"-A": true, "-e": true, "-b": true, "-F": true, "--compressed": true,
+}
+
+// FuzzGocurl checks that gocurl handles arbitrary commands derived from the
+// corpus without panicking or following redirects it was not asked to.
+func FuzzGocurl(f *testing.F) {
+	for _, c := range compat.Corpus() {
+		f.Add(c.Line)
+	}
+	f.Fuzz(func(t *testing.T, command string) {
+		args, err := tokenizer.Split(command)
+		if err != nil || len(args) < 2 || args[0] != "curl" || !strings.Contains(command, compat.Placeholder) || strings.Contains(command, "@") {
+			return
+		}
+		for _, arg := range args[1:] {
+			if strings.HasPrefix(arg, "-") && !fuzzFlags[arg] {
+				return
+			}
+		}
+		results, err := compat.Run(context.Background(), []compat.Command{{Line: command}}, &compat.Options{SkipCurl: true})
+		if err != nil {
+			return
+		}
+		if len(results[0].Gocurl) > 1 {
+			t.Errorf("%s: sent %d requests without following redirects", command, len(results[0].Gocurl))
+		}
+	})
+}
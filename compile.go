@@ -0,0 +1,164 @@
+package gocurl
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/maniartech/gocurl/options"
+	"github.com/maniartech/gocurl/tokenizer"
+)
+
+// CompiledCommand is a curl command parsed once for repeated execution.
+// Variables are expanded on every use, so the same compiled command can be
+// run with different values.
+type CompiledCommand struct {
+	args []string
+
+	// static holds the converted options of commands without variables
+	static *options.RequestOptions
+}
+
+// Tokenize splits a curl command string into arguments as Curl does: like a
+// POSIX shell, including the $'...' quoting of browsers' "Copy as cURL",
+// with variables left unexpanded. Commands in the cmd.exe syntax of "Copy as
+// cURL (cmd)" are detected and split following its rules.
+func Tokenize(command string) ([]string, error) {
+	if tokenizer.IsWindowsCommand(command) {
+		return tokenizer.SplitWindows(command)
+	}
+	return tokenizer.Split(command)
+}
+
+// Compile parses a curl command string for repeated execution.
+func Compile(command string) (*CompiledCommand, error) {
+	args, err := Tokenize(command)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := &CompiledCommand{args: args}
+	if !strings.Contains(command, "$") && !readsInput(args) {
+		if compiled.static, err = argsToOptions(args, nil); err != nil {
+			return nil, err
+		}
+	}
+	return compiled, nil
+}
+
+// readsInput reports whether args read stdin, with -T - or @-, or files
+// as they are converted, with @file or <file arguments. Such commands are
+// converted on every use, as stdin is consumed by each and files may have
+// changed since.
+func readsInput(args []string) bool {
+	for _, arg := range args {
+		if arg == "-" || strings.HasPrefix(arg, "@") || strings.HasPrefix(arg, "<") {
+			return true
+		}
+	}
+	return false
+}
+
+// Options returns fresh request options for the command with variables
+// expanded from vars, falling back to the environment.
+func (c *CompiledCommand) Options(vars Variables) (*options.RequestOptions, error) {
+	if c.static != nil {
+		return c.static.Clone(), nil
+	}
+	return argsToOptions(c.args, vars)
+}
+
+// Curl executes the command with variables expanded from vars.
+func (c *CompiledCommand) Curl(ctx context.Context, vars Variables) (*http.Response, string, error) {
+	opts, err := c.Options(vars)
+	if err != nil {
+		return nil, "", err
+	}
+	return Process(ctx, opts)
+}
+
+// DefaultCommandCacheSize is the number of parsed command strings kept by
+// Curl and the other helpers accepting a command string.
+const DefaultCommandCacheSize = 256
+
+var commandCache = newCommandLRU(DefaultCommandCacheSize)
+
+// SetCommandCacheSize changes the number of parsed command strings kept in
+// the cache. A size of zero disables caching.
+func SetCommandCacheSize(size int) {
+	commandCache.resize(size)
+}
+
+// compileCached returns the compiled form of command, parsing it only if it
+// is not in the cache.
+func compileCached(command string) (*CompiledCommand, error) {
+	if compiled, ok := commandCache.get(command); ok {
+		return compiled, nil
+	}
+	compiled, err := Compile(command)
+	if err != nil {
+		return nil, err
+	}
+	commandCache.add(command, compiled)
+	return compiled, nil
+}
+
+// commandLRU is a size bounded least recently used cache of compiled commands.
+type commandLRU struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type commandEntry struct {
+	command  string
+	compiled *CompiledCommand
+}
+
+func newCommandLRU(size int) *commandLRU {
+	return &commandLRU{size: size, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+func (l *commandLRU) get(command string) (*CompiledCommand, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if elem, ok := l.entries[command]; ok {
+		l.order.MoveToFront(elem)
+		return elem.Value.(*commandEntry).compiled, true
+	}
+	return nil, false
+}
+
+func (l *commandLRU) add(command string, compiled *CompiledCommand) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.size <= 0 {
+		return
+	}
+	if elem, ok := l.entries[command]; ok {
+		elem.Value.(*commandEntry).compiled = compiled
+		l.order.MoveToFront(elem)
+		return
+	}
+	l.entries[command] = l.order.PushFront(&commandEntry{command, compiled})
+	l.evict()
+}
+
+func (l *commandLRU) resize(size int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.size = size
+	l.evict()
+}
+
+// evict drops the least recently used entries beyond the size limit.
+func (l *commandLRU) evict() {
+	for l.order.Len() > l.size && l.order.Len() > 0 {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*commandEntry).command)
+	}
+}
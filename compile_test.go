@@ -0,0 +1,141 @@
+package gocurl_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path + " " + r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	t.Run("Variables are expanded on each use", func(t *testing.T) {
+		compiled, err := gocurl.Compile(`curl -s -H "Authorization: Bearer $TOKEN" ` + server.URL + `/users/${USER_ID}`)
+		require.NoError(t, err)
+
+		_, body, err := compiled.Curl(context.Background(), gocurl.Variables{"TOKEN": "a", "USER_ID": "1"})
+		require.NoError(t, err)
+		assert.Equal(t, "/users/1 Bearer a", body)
+
+		_, body, err = compiled.Curl(context.Background(), gocurl.Variables{"TOKEN": "b", "USER_ID": "2"})
+		require.NoError(t, err)
+		assert.Equal(t, "/users/2 Bearer b", body)
+	})
+
+	t.Run("Static commands return independent options", func(t *testing.T) {
+		compiled, err := gocurl.Compile(`curl -H 'Accept: application/json' https://api.example.com/items?page=1`)
+		require.NoError(t, err)
+
+		first, err := compiled.Options(nil)
+		require.NoError(t, err)
+		first.Headers.Set("Accept", "text/plain")
+		first.QueryParams.Set("page", "2")
+
+		second, err := compiled.Options(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "application/json", second.Headers.Get("Accept"))
+		assert.Equal(t, "1", second.QueryParams.Get("page"))
+	})
+
+	t.Run("Files are read on each use", func(t *testing.T) {
+		echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(w, r.Body)
+		}))
+		defer echo.Close()
+		path := filepath.Join(t.TempDir(), "body.txt")
+
+		compiled, err := gocurl.Compile("curl -s --data-binary @" + path + " " + echo.URL)
+		require.NoError(t, err)
+		for _, data := range []string{"first", "second"} {
+			require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+			_, body, err := compiled.Curl(context.Background(), nil)
+			require.NoError(t, err)
+			assert.Equal(t, data, body)
+		}
+	})
+
+	t.Run("Cached commands read JSON files on each call", func(t *testing.T) {
+		echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(w, r.Body)
+		}))
+		defer echo.Close()
+		path := filepath.Join(t.TempDir(), "body.json")
+
+		command := "curl -s --json @" + path + " " + echo.URL
+		for _, data := range []string{`{"n":1}`, `{"n":2}`} {
+			require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+			_, body, err := gocurl.Curl(context.Background(), command)
+			require.NoError(t, err)
+			assert.Equal(t, data, body)
+		}
+	})
+
+	t.Run("Invalid commands", func(t *testing.T) {
+		_, err := gocurl.Compile(`curl 'https://api.example.com`)
+		assert.Error(t, err)
+		_, err = gocurl.Compile(`curl --no-such-flag https://api.example.com`)
+		assert.Error(t, err)
+	})
+
+	t.Run("Command cache", func(t *testing.T) {
+		defer gocurl.SetCommandCacheSize(gocurl.DefaultCommandCacheSize)
+		command := "curl -s " + server.URL + "/cached"
+
+		for _, size := range []int{0, 1} {
+			gocurl.SetCommandCacheSize(size)
+			for i := 0; i < 2; i++ {
+				_, body, err := gocurl.Curl(context.Background(), command)
+				require.NoError(t, err)
+				assert.Equal(t, "/cached ", body)
+			}
+		}
+	})
+}
+
+func BenchmarkCompiledCommand(b *testing.B) {
+	server := newBenchmarkServer(b)
+	compiled, err := gocurl.Compile("curl -s -H 'Accept: application/json' " + server.URL + "/items/$ID")
+	if err != nil {
+		b.Fatal(err)
+	}
+	vars := gocurl.Variables{"ID": "1"}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := compiled.Curl(context.Background(), vars); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	args, err := gocurl.Tokenize("curl -H 'Accept: */*' \\\n  --data-raw $'{\"a\":\"b\\nc\"}' $API_URL")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"curl", "-H", "Accept: */*", "--data-raw", "{\"a\":\"b\nc\"}", "$API_URL"}, args)
+
+	_, err = gocurl.Tokenize(`curl "unterminated`)
+	assert.Error(t, err)
+
+	t.Run("Copy as cURL (cmd)", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			w.Write([]byte(r.Header.Get("X-Name") + " " + string(body)))
+		}))
+		defer server.Close()
+
+		_, body, err := gocurl.CurlString(context.Background(), "curl ^\""+server.URL+"^\" ^\r\n  -H ^\"X-Name: a^&b^\" ^\r\n  --data-raw ^\"^{^\\^\"k^\\^\":1^}^\"")
+		require.NoError(t, err)
+		assert.Equal(t, `a&b {"k":1}`, body)
+	})
+}
@@ -0,0 +1,71 @@
+package gocurl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// ErrUnexpectedContentType is matched by the *ContentTypeError CurlJSON
+// returns when a response fails its JSONContentType check.
+var ErrUnexpectedContentType = errors.New("unexpected content type")
+
+// contentTypePrefixSize is the number of bytes of the body a
+// ContentTypeError carries.
+const contentTypePrefixSize = 512
+
+// ContentTypeError is returned by CurlJSON when the Content-Type of a
+// response fails the JSONContentType check of the request, and by CurlWith
+// when it is not of the serializer's media type. It carries the
+// start of the body, which usually tells what answered instead of the API.
+type ContentTypeError struct {
+	ContentType string
+	// Prefix holds up to the first 512 bytes of the body
+	Prefix []byte
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("%v %q for JSON response starting with %q", ErrUnexpectedContentType, e.ContentType, e.Prefix)
+}
+
+// Unwrap returns ErrUnexpectedContentType.
+func (e *ContentTypeError) Unwrap() error {
+	return ErrUnexpectedContentType
+}
+
+// checkJSONContentType returns a *ContentTypeError when the Content-Type of
+// resp fails check. body reads the start of the response body.
+func checkJSONContentType(check options.JSONContentType, resp *http.Response, body io.Reader) error {
+	if check == options.JSONContentTypeAny {
+		return nil
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if isJSON(contentType) {
+		return nil
+	}
+	prefix, _ := io.ReadAll(io.LimitReader(body, contentTypePrefixSize))
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch check {
+	case options.JSONContentTypeText:
+		if mediaType == "text/plain" {
+			return nil
+		}
+	case options.JSONContentTypeSniff:
+		if trimmed := bytes.TrimSpace(prefix); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+			return nil
+		}
+	}
+	return &ContentTypeError{ContentType: contentType, Prefix: prefix}
+}
+
+// newContentTypeError returns the *ContentTypeError of a response of
+// contentType whose body reads body.
+func newContentTypeError(contentType string, body io.Reader) error {
+	prefix, _ := io.ReadAll(io.LimitReader(body, contentTypePrefixSize))
+	return &ContentTypeError{ContentType: contentType, Prefix: prefix}
+}
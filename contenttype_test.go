@@ -0,0 +1,76 @@
+package gocurl_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurlJSONContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.Write([]byte(`{"ok": true}`))
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte(`{"ok": true}`))
+		case "/octet":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte(` {"ok": true}`))
+		case "/html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body>502 Bad Gateway</body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		check    options.JSONContentType
+		accepted []string
+	}{
+		{options.JSONContentTypeAny, []string{"/json", "/text", "/octet"}},
+		{options.JSONContentTypeStrict, []string{"/json"}},
+		{options.JSONContentTypeText, []string{"/json", "/text"}},
+		{options.JSONContentTypeSniff, []string{"/json", "/text", "/octet"}},
+	}
+	for _, tt := range tests {
+		ctx := gocurl.WithDefaults(context.Background(), options.NewRequestOptionsBuilder().SetJSONContentType(tt.check).Build())
+		for _, path := range []string{"/json", "/text", "/octet", "/html"} {
+			var v struct{ OK bool }
+			_, err := gocurl.CurlJSON(ctx, &v, server.URL+path)
+			if contains(tt.accepted, path) {
+				require.NoError(t, err, "%q %s", tt.check, path)
+				assert.True(t, v.OK)
+				continue
+			}
+			require.Error(t, err, "%q %s", tt.check, path)
+			if tt.check == options.JSONContentTypeAny {
+				continue
+			}
+			assert.True(t, errors.Is(err, gocurl.ErrUnexpectedContentType))
+			var ctErr *gocurl.ContentTypeError
+			require.True(t, errors.As(err, &ctErr))
+			if path == "/html" {
+				assert.Equal(t, "text/html", ctErr.ContentType)
+				assert.Contains(t, string(ctErr.Prefix), "502 Bad Gateway")
+			}
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package gocurl
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+
+	"github.com/maniartech/gocurl/middlewares"
+	"github.com/maniartech/gocurl/options"
+	"github.com/maniartech/gocurl/redact"
+)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	tenantKey
+	principalKey
+	attemptKey
+	requestStateKey
+	connectTimeoutKey
+	headerOrderKey
+	resultKey
+	defaultsKey
+	dnsCacheKey
+	altSvcKey
+	paginationKey
+	retryBudgetKey
+	policyKey
+)
+
+// WithRequestID returns a context carrying the request ID. gocurl passes it
+// to interceptors and loggers and exposes it as %{request_id} in write-out
+// formats. RequestOptions.RequestID takes precedence over the context.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, if any.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithTenant returns a context carrying the tenant the request is made for,
+// exposed as %{tenant} in write-out formats.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+// TenantFromContext returns the tenant carried by ctx, if any.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey).(string)
+	return tenant
+}
+
+// WithPrincipal returns a context carrying the principal (user or service)
+// the request is made on behalf of, recorded in audit events.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// PrincipalFromContext returns the principal carried by ctx, if any.
+func PrincipalFromContext(ctx context.Context) string {
+	principal, _ := ctx.Value(principalKey).(string)
+	return principal
+}
+
+// AttemptFromContext returns the attempt number, starting at 1, of the
+// request whose context is ctx. Retries of any kind count as new attempts.
+// It returns 0 outside of a request.
+func AttemptFromContext(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey).(int)
+	return attempt
+}
+
+// ContextAttrs returns the request ID, tenant, principal and attempt number
+// carried by ctx as log attributes, omitting those that are unset.
+func ContextAttrs(ctx context.Context) []slog.Attr {
+	var attrs []slog.Attr
+	if id := RequestIDFromContext(ctx); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	if tenant := TenantFromContext(ctx); tenant != "" {
+		attrs = append(attrs, slog.String("tenant", tenant))
+	}
+	if principal := PrincipalFromContext(ctx); principal != "" {
+		attrs = append(attrs, slog.String("principal", principal))
+	}
+	if attempt := AttemptFromContext(ctx); attempt > 0 {
+		attrs = append(attrs, slog.Int("attempt", attempt))
+	}
+	return attrs
+}
+
+// requestState tracks a request across its attempts.
+type requestState struct {
+	attempts int32
+	start    time.Time
+	total    time.Duration
+
+	// result, when set, receives the outcome of the request, timed with
+	// timings
+	result  *Result
+	timings timingTrace
+}
+
+// withRequestState prepares ctx for executing opts: it adds the request ID
+// set in opts and a fresh requestState, filling the Result set with
+// WithResult.
+func withRequestState(ctx context.Context, opts *options.RequestOptions) (context.Context, *requestState) {
+	if opts.RequestID != "" {
+		ctx = WithRequestID(ctx, opts.RequestID)
+	}
+	state := &requestState{start: time.Now()}
+	state.result, _ = ctx.Value(resultKey).(*Result)
+	return context.WithValue(ctx, requestStateKey, state), state
+}
+
+func requestStateFromContext(ctx context.Context) *requestState {
+	state, _ := ctx.Value(requestStateKey).(*requestState)
+	return state
+}
+
+// send makes a single attempt at req through the interceptors of opts, logs
+// it and audits it. fallback is the attempt number used when req was not prepared by
+// withRequestState, as for direct calls to ExecuteRequestWithRetries.
+func send(client *http.Client, req *http.Request, opts *options.RequestOptions, fallback int) (*http.Response, error) {
+	ctx := req.Context()
+	attempt := fallback
+	if state := requestStateFromContext(ctx); state != nil {
+		attempt = int(atomic.AddInt32(&state.attempts, 1))
+	}
+	ctx = context.WithValue(ctx, attemptKey, attempt)
+	var cancel context.CancelFunc
+	if opts.AttemptTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.AttemptTimeout)
+	}
+	if state := requestStateFromContext(ctx); state != nil && state.result != nil {
+		ctx = httptrace.WithClientTrace(ctx, state.timings.trace())
+	}
+	req = req.WithContext(ctx)
+
+	start := time.Now()
+	var finish func(*http.Response, error) *http.Response
+	if opts.Auditor != nil {
+		req, finish = auditAttempt(req, opts, start)
+	}
+	resp, err := middlewares.Chain(client.Do, opts.Interceptors...)(req)
+	if finish != nil {
+		resp = finish(resp, err)
+	}
+	if cancel != nil {
+		// The attempt lasts until its response is read
+		if err != nil {
+			cancel()
+		} else {
+			resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+		}
+	}
+
+	if opts.Logger != nil {
+		attrs := append([]slog.Attr{
+			slog.String("method", req.Method),
+			slog.String("url", redact.Default.URL(req.URL.String())),
+			slog.Duration("duration", time.Since(start)),
+		}, ContextAttrs(req.Context())...)
+		if err != nil {
+			opts.Logger.LogAttrs(req.Context(), slog.LevelError, "gocurl request failed", append(attrs, slog.String("error", redact.Default.String(err.Error())))...)
+		} else {
+			opts.Logger.LogAttrs(req.Context(), slog.LevelInfo, "gocurl request", append(attrs, slog.Int("status", resp.StatusCode))...)
+		}
+	}
+	return resp, err
+}
+
+// cancelBody cancels the context of the attempt it was received by when it
+// is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,95 @@
+package gocurl_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/middlewares"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextPropagation(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	type seen struct {
+		requestID, tenant string
+		attempt           int
+	}
+	var attempts []seen
+	interceptor := func(req *http.Request, next middlewares.RoundTripFunc) (*http.Response, error) {
+		ctx := req.Context()
+		attempts = append(attempts, seen{gocurl.RequestIDFromContext(ctx), gocurl.TenantFromContext(ctx), gocurl.AttemptFromContext(ctx)})
+		return next(req)
+	}
+
+	var logs bytes.Buffer
+	opts := options.NewRequestOptionsBuilder().
+		SetURL(server.URL).
+		SetSilent(true).
+		SetRetryConfig(&options.RetryConfig{MaxRetries: 1, RetryDelay: time.Millisecond, RetryOnHTTP: []int{503}}).
+		AddInterceptor(interceptor).
+		SetLogger(slog.New(slog.NewTextHandler(&logs, nil))).
+		Build()
+
+	ctx := gocurl.WithTenant(gocurl.WithRequestID(context.Background(), "req-1"), "acme")
+	resp, body, err := gocurl.Process(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", body)
+
+	assert.Equal(t, []seen{{"req-1", "acme", 1}, {"req-1", "acme", 2}}, attempts)
+	assert.Equal(t, 2, gocurl.AttemptFromContext(resp.Request.Context()))
+
+	assert.Contains(t, logs.String(), "request_id=req-1 tenant=acme attempt=1 status=503")
+	assert.Contains(t, logs.String(), "request_id=req-1 tenant=acme attempt=2 status=200")
+
+	t.Run("logs are redacted", func(t *testing.T) {
+		logs.Reset()
+		opts := opts.Clone()
+		opts.QueryParams = url.Values{"access_token": {"t0ps3cret"}}
+
+		_, _, err := gocurl.Process(ctx, opts)
+		require.NoError(t, err)
+		assert.NotContains(t, logs.String(), "t0ps3cret")
+		assert.Contains(t, logs.String(), "access_token=****")
+	})
+
+	t.Run("options take precedence", func(t *testing.T) {
+		attempts = nil
+		atomic.StoreInt32(&calls, 1)
+		opts := opts.Clone()
+		opts.RequestID = "req-2"
+
+		_, _, err := gocurl.Process(ctx, opts)
+		require.NoError(t, err)
+		assert.Equal(t, []seen{{"req-2", "acme", 1}}, attempts)
+	})
+}
+
+func TestContextAttrs(t *testing.T) {
+	assert.Empty(t, gocurl.ContextAttrs(context.Background()))
+
+	ctx := gocurl.WithRequestID(context.Background(), "req-1")
+	assert.Equal(t, []slog.Attr{slog.String("request_id", "req-1")}, gocurl.ContextAttrs(ctx))
+
+	ctx = gocurl.WithPrincipal(ctx, "alice")
+	assert.Equal(t, "alice", gocurl.PrincipalFromContext(ctx))
+	assert.Equal(t, []slog.Attr{slog.String("request_id", "req-1"), slog.String("principal", "alice")}, gocurl.ContextAttrs(ctx))
+}
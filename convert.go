@@ -4,10 +4,12 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -17,28 +19,49 @@ import (
 )
 
 func ArgsToOptions(args []string) (*options.RequestOptions, error) {
+	return argsToOptions(args, nil)
+}
+
+// ArgsToOptionsWithVars is ArgsToOptions expanding the $VAR and ${VAR}
+// references of args from vars before falling back to the environment.
+func ArgsToOptionsWithVars(args []string, vars Variables) (*options.RequestOptions, error) {
+	return argsToOptions(args, vars)
+}
+
+// argsToOptions converts args into options, expanding variables from vars
+// before falling back to the environment.
+func argsToOptions(args []string, vars Variables) (*options.RequestOptions, error) {
 	tokens := []tokenizer.Token{}
 	for _, arg := range args {
 		tokens = append(tokens, tokenizer.Token{Type: tokenizer.TokenValue, Value: arg})
 	}
-	return convertTokensToRequestOptions(tokens)
+	return convertTokensToRequestOptions(tokens, vars)
 }
 
 // ConvertTokensToRequestOptions converts the tokenized cURL command into options.RequestOptions.
-func convertTokensToRequestOptions(tokens []tokenizer.Token) (*options.RequestOptions, error) {
-	o := options.NewRequestOptions("https://api.example.com/data")
+func convertTokensToRequestOptions(tokens []tokenizer.Token, vars Variables) (*options.RequestOptions, error) {
+	o := options.NewRequestOptions("")
 
 	// Default method is GET
 	o.Method = "GET"
 
 	// Initialize slices for accumulating multiple headers and data fields
 	dataFields := []string{}
+	jsonFields := []string{}
+	// stdinField is the index of the data field read from stdin with @-
+	stdinField, stdinBinary := -1, false
 	formFields := url.Values{}
+	// headerNames keeps the -H names in order and casing for --raw-headers
+	headerNames := []string{}
 
 	// Expand environment variables in tokens
 	expandedTokens := []string{}
 	for _, token := range tokens {
-		expandedTokens = append(expandedTokens, expandVariables(token.Value))
+		expanded, err := vars.Resolve(token.Value)
+		if err != nil {
+			return nil, err
+		}
+		expandedTokens = append(expandedTokens, expanded)
 	}
 	// tokens = expandedTokens
 	tokenLen := len(expandedTokens)
@@ -51,30 +74,75 @@ func convertTokensToRequestOptions(tokens []tokenizer.Token) (*options.RequestOp
 			i++
 			continue
 		}
+		if isNext(token) {
+			return nil, fmt.Errorf("%s starts another request; use CurlAll to execute several requests", token)
+		}
 
-		// Handle flags
+		// Handle flags, splitting combined short flags such as -sL first
 		if strings.HasPrefix(token, "-") {
+			if flags, ok := expandShortFlags(token); ok {
+				expandedTokens = append(expandedTokens[:i], append(flags, expandedTokens[i+1:]...)...)
+				tokenLen = len(expandedTokens)
+				token = expandedTokens[i]
+			}
 			switch token {
 			case "-X", "--request":
 				i++
 				if i >= tokenLen {
 					return nil, fmt.Errorf("expected method after %s", token)
 				}
+				token = expandedTokens[i]
 				o.Method = token
 			case "-d", "--data", "--data-raw", "--data-binary":
+				flag := token
 				i++
 				if i >= tokenLen {
 					return nil, fmt.Errorf("expected data after %s", token)
 				}
+				token = expandedTokens[i]
+				// Like cURL, @file reads the data from a file, or from stdin
+				// for @-, stripping newlines unless it is --data-binary
+				switch {
+				case flag == "--data-raw" || !strings.HasPrefix(token, "@"):
+				case token == "@-":
+					stdinField, stdinBinary = len(dataFields), flag == "--data-binary"
+				default:
+					data, err := os.ReadFile(token[1:])
+					if err != nil {
+						return nil, fmt.Errorf("failed to read data: %v", err)
+					}
+					if flag != "--data-binary" {
+						data = stripNewlines(data)
+					}
+					token = string(data)
+				}
 				dataFields = append(dataFields, token)
 				if o.Method == "GET" {
 					o.Method = "POST" // cURL defaults to POST when data is provided
 				}
+			case "--json":
+				i++
+				if i >= tokenLen {
+					return nil, fmt.Errorf("expected data after %s", token)
+				}
+				token = expandedTokens[i]
+				if strings.HasPrefix(token, "@") {
+					data, err := os.ReadFile(token[1:])
+					if err != nil {
+						return nil, fmt.Errorf("failed to read JSON data: %v", err)
+					}
+					token = string(data)
+				}
+				jsonFields = append(jsonFields, token)
+				if o.Method == "GET" {
+					o.Method = "POST"
+				}
 			case "-H", "--header":
 				i++
 				if i >= tokenLen {
 					return nil, fmt.Errorf("expected header after %s", token)
 				}
+				token = expandedTokens[i]
 				headerLine := token
 				idx := strings.Index(headerLine, ":")
 				if idx <= 0 {
@@ -82,12 +150,19 @@ func convertTokensToRequestOptions(tokens []tokenizer.Token) (*options.RequestOp
 				}
 				key := strings.TrimSpace(headerLine[:idx])
 				value := strings.TrimSpace(headerLine[idx+1:])
+				if o.Headers == nil {
+					o.Headers = http.Header{}
+				}
 				o.Headers.Add(key, value)
+				headerNames = append(headerNames, key)
+			case "--raw-headers":
+				o.RawHeaders = true
 			case "-F", "--form":
 				i++
 				if i >= tokenLen {
 					return nil, fmt.Errorf("expected form data after %s", token)
 				}
+				token = expandedTokens[i]
 				formData := token
 				idx := strings.Index(formData, "=")
 				if idx <= 0 {
@@ -119,6 +194,7 @@ func convertTokensToRequestOptions(tokens []tokenizer.Token) (*options.RequestOp
 				if i >= tokenLen {
 					return nil, fmt.Errorf("expected credentials after %s", token)
 				}
+				token = expandedTokens[i]
 				creds := token
 				parts := strings.SplitN(creds, ":", 2)
 				if len(parts) != 2 {
@@ -130,6 +206,7 @@ func convertTokensToRequestOptions(tokens []tokenizer.Token) (*options.RequestOp
 				if i >= tokenLen {
 					return nil, fmt.Errorf("expected cookie data after %s", token)
 				}
+				token = expandedTokens[i]
 				cookieData := token
 				if strings.Contains(cookieData, "=") {
 					// Inline cookies
@@ -148,14 +225,55 @@ func convertTokensToRequestOptions(tokens []tokenizer.Token) (*options.RequestOp
 				if i >= tokenLen {
 					return nil, fmt.Errorf("expected cookie jar file after %s", token)
 				}
+				token = expandedTokens[i]
 				// For simplicity, we won't implement cookie jar file writing here
 				// You can set o.CookieJar or handle it as needed
+			case "-T", "--upload-file":
+				i++
+				if i >= tokenLen {
+					return nil, fmt.Errorf("expected file after %s", token)
+				}
+				token = expandedTokens[i]
+				if token == "-" {
+					o.BodyReader = os.Stdin
+				} else {
+					o.UploadFile = token
+				}
+				if o.Method == "GET" {
+					o.Method = "PUT" // cURL uploads with PUT
+				}
 			case "-o", "--output":
 				i++
 				if i >= tokenLen {
 					return nil, fmt.Errorf("expected output file after %s", token)
 				}
+				token = expandedTokens[i]
 				o.OutputFile = token
+			case "--output-dir":
+				i++
+				if i >= tokenLen {
+					return nil, fmt.Errorf("expected directory after %s", token)
+				}
+				token = expandedTokens[i]
+				o.OutputDir = token
+			case "--create-dirs":
+				o.CreateDirs = true
+			case "--alt-svc":
+				i++
+				if i >= tokenLen {
+					return nil, fmt.Errorf("expected file after %s", token)
+				}
+				o.AltSvc = expandedTokens[i]
+			case "--etag-save", "--etag-compare":
+				i++
+				if i >= tokenLen {
+					return nil, fmt.Errorf("expected file after %s", token)
+				}
+				if token == "--etag-save" {
+					o.ETagSave = expandedTokens[i]
+				} else {
+					o.ETagCompare = expandedTokens[i]
+				}
 			case "--compressed":
 				o.Compress = true
 			case "-A", "--user-agent":
@@ -163,30 +281,35 @@ func convertTokensToRequestOptions(tokens []tokenizer.Token) (*options.RequestOp
 				if i >= tokenLen {
 					return nil, fmt.Errorf("expected user-agent after %s", token)
 				}
+				token = expandedTokens[i]
 				o.UserAgent = token
 			case "-e", "--referer":
 				i++
 				if i >= tokenLen {
 					return nil, fmt.Errorf("expected referer after %s", token)
 				}
+				token = expandedTokens[i]
 				o.Referer = token
 			case "--cert":
 				i++
 				if i >= tokenLen {
 					return nil, fmt.Errorf("expected certificate file after %s", token)
 				}
+				token = expandedTokens[i]
 				o.CertFile = token
 			case "--key":
 				i++
 				if i >= tokenLen {
 					return nil, fmt.Errorf("expected key file after %s", token)
 				}
+				token = expandedTokens[i]
 				o.KeyFile = token
 			case "--cacert":
 				i++
 				if i >= tokenLen {
 					return nil, fmt.Errorf("expected CA certificate file after %s", token)
 				}
+				token = expandedTokens[i]
 				o.CAFile = token
 			case "--http2":
 				o.HTTP2 = true
@@ -197,19 +320,40 @@ func convertTokensToRequestOptions(tokens []tokenizer.Token) (*options.RequestOp
 				if i >= tokenLen {
 					return nil, fmt.Errorf("expected proxy after %s", token)
 				}
+				token = expandedTokens[i]
 				o.Proxy = token
-			case "--max-time":
+			case "--proxy-pac":
+				i++
+				if i >= tokenLen {
+					return nil, fmt.Errorf("expected PAC file URL or path after %s", token)
+				}
+				o.ProxyPAC = expandedTokens[i]
+			case "-m", "--max-time":
 				i++
 				if i >= tokenLen {
 					return nil, fmt.Errorf("expected time after %s", token)
 				}
+				token = expandedTokens[i]
 				timeout, err := time.ParseDuration(token + "s")
 				if err != nil {
 					return nil, err
 				}
 				o.Timeout = timeout
+			case "--connect-timeout":
+				i++
+				if i >= tokenLen {
+					return nil, fmt.Errorf("expected time after %s", token)
+				}
+				token = expandedTokens[i]
+				timeout, err := time.ParseDuration(token + "s")
+				if err != nil {
+					return nil, err
+				}
+				o.ConnectTimeout = timeout
 			case "-k", "--insecure":
 				o.Insecure = true
+			case "--no-sessionid":
+				o.NoTLSSessionResumption = true
 			case "-L", "--location":
 				o.FollowRedirects = true
 			case "--max-redirs":
@@ -217,17 +361,36 @@ func convertTokensToRequestOptions(tokens []tokenizer.Token) (*options.RequestOp
 				if i >= tokenLen {
 					return nil, fmt.Errorf("expected number after %s", token)
 				}
+				token = expandedTokens[i]
 				maxRedirs, err := parseInt(token)
 				if err != nil {
 					return nil, fmt.Errorf("invalid max redirects: %v", err)
 				}
 				o.MaxRedirects = maxRedirs
+			case "-w", "--write-out":
+				i++
+				if i >= tokenLen {
+					return nil, fmt.Errorf("expected format after %s", token)
+				}
+				token = expandedTokens[i]
+				if strings.HasPrefix(token, "@") {
+					format, err := os.ReadFile(token[1:])
+					if err != nil {
+						return nil, fmt.Errorf("failed to read write-out format: %v", err)
+					}
+					token = string(format)
+				}
+				o.WriteOut = token
+			case "-f", "--fail":
+				o.Fail = true
+			case "--fail-with-body":
+				o.FailWithBody = true
 			case "-v", "--verbose":
 				o.Verbose = true
 			case "-s", "--silent":
 				o.Silent = true
 			default:
-				return nil, fmt.Errorf("unknown flag: %s", token)
+				return nil, unknownFlagError(token)
 			}
 			i++
 		} else {
@@ -237,7 +400,10 @@ func convertTokensToRequestOptions(tokens []tokenizer.Token) (*options.RequestOp
 				i++
 			} else {
 				// Handle unexpected tokens
-				return nil, fmt.Errorf("unexpected token: %s", token)
+				if o.URL == "" {
+					return nil, fmt.Errorf("unexpected token: %s (URLs must start with http:// or https://)", token)
+				}
+				return nil, fmt.Errorf("unexpected token: %s (only one URL is supported, already got %s)", token, o.URL)
 			}
 		}
 	}
@@ -255,19 +421,67 @@ func convertTokensToRequestOptions(tokens []tokenizer.Token) (*options.RequestOp
 	o.QueryParams = parsedURL.Query()
 	o.URL = parsedURL.Scheme + "://" + parsedURL.Host + parsedURL.Path
 
+	// Like cURL, upload to the file's name when the URL ends with a slash
+	if o.UploadFile != "" && (parsedURL.Path == "" || strings.HasSuffix(parsedURL.Path, "/")) {
+		if parsedURL.Path == "" {
+			o.URL += "/"
+		}
+		o.URL += url.PathEscape(filepath.Base(o.UploadFile))
+	}
+
+	// Stream data read from stdin unless it must be joined with other fields
+	var stdin io.Reader = os.Stdin
+	if stdinField >= 0 && !stdinBinary {
+		stdin = &newlineStripper{r: stdin}
+	}
+	switch {
+	case stdinField >= 0 && len(dataFields) == 1:
+		o.BodyReader = stdin
+		dataFields = nil
+	case stdinField >= 0:
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read data from stdin: %v", err)
+		}
+		dataFields[stdinField] = string(data)
+	}
+
 	// Combine data fields if any
 	if len(dataFields) > 0 {
 		o.Body = strings.Join(dataFields, "&")
 	}
 
+	// Like cURL, --json data is concatenated and sent as JSON unless the
+	// headers were set explicitly
+	if len(jsonFields) > 0 {
+		o.Body += strings.Join(jsonFields, "")
+		if o.Headers == nil {
+			o.Headers = http.Header{}
+		}
+		if o.Headers.Get("Content-Type") == "" {
+			o.Headers.Set("Content-Type", "application/json")
+		}
+		if o.Headers.Get("Accept") == "" {
+			o.Headers.Set("Accept", "application/json")
+		}
+	}
+
+	if o.RawHeaders {
+		o.HeaderOrder = headerNames
+	}
+
 	// Set form data if any
 	if len(formFields) > 0 {
 		o.Form = formFields
 	}
 
+	if o.Headers == nil && (o.Compress || o.UserAgent != "" || o.Referer != "") {
+		o.Headers = http.Header{}
+	}
+
 	// Handle Compression
 	if o.Compress {
-		o.Headers.Set("Accept-Encoding", "deflate, gzip")
+		o.Headers.Set("Accept-Encoding", AcceptEncoding())
 	}
 
 	// Set User-Agent
@@ -292,11 +506,6 @@ func convertTokensToRequestOptions(tokens []tokenizer.Token) (*options.RequestOp
 	return o, nil
 }
 
-// Helper function to expand environment variables within a string
-func expandVariables(s string) string {
-	return os.ExpandEnv(s)
-}
-
 // Helper function to parse cookies from a string
 func parseCookies(cookieStr string) []*http.Cookie {
 	cookies := []*http.Cookie{}
@@ -376,3 +376,59 @@ func normalizeHeaders(headers http.Header) {
 		}
 	}
 }
+
+func TestJSONFlag(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/body.json"
+	assert.NoError(t, os.WriteFile(path, []byte(`{"b":2}`), 0644))
+
+	tests := []struct {
+		name     string
+		args     []string
+		body     string
+		method   string
+		headers  http.Header
+		hasError bool
+	}{
+		{
+			name:    "Sets method and headers",
+			args:    []string{"curl", "--json", `{"a":1}`, "https://example.com"},
+			body:    `{"a":1}`,
+			method:  "POST",
+			headers: http.Header{"Content-Type": {"application/json"}, "Accept": {"application/json"}},
+		},
+		{
+			name:    "Reads data from a file and concatenates",
+			args:    []string{"curl", "--json", "[", "--json", "@" + path, "--json", "]", "https://example.com"},
+			body:    `[{"b":2}]`,
+			method:  "POST",
+			headers: http.Header{"Content-Type": {"application/json"}, "Accept": {"application/json"}},
+		},
+		{
+			name:    "Explicit headers and method win",
+			args:    []string{"curl", "-X", "PUT", "-H", "Accept: text/plain", "--json", "{}", "https://example.com"},
+			body:    "{}",
+			method:  "PUT",
+			headers: http.Header{"Content-Type": {"application/json"}, "Accept": {"text/plain"}},
+		},
+		{
+			name:     "Missing data",
+			args:     []string{"curl", "https://example.com", "--json"},
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := gocurl.ArgsToOptions(tt.args)
+			if tt.hasError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.body, opts.Body)
+			assert.Equal(t, tt.method, opts.Method)
+			assert.Equal(t, tt.headers, opts.Headers)
+		})
+	}
+}
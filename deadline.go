@@ -0,0 +1,74 @@
+package gocurl
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// withTimeouts derives the deadlines of opts from the curl --max-time and
+// --connect-timeout flags, so that callers passing only a command string and
+// context.Background() still get bounded requests.
+//
+// The precedence rules are:
+//   - When ctx has no deadline, --max-time (opts.Timeout) becomes one that
+//     covers the whole request: every retry, the waits between them and
+//     reading the body.
+//   - When ctx already has a deadline, it governs the request as a whole and
+//     --max-time only limits each attempt, as http.Client.Timeout does.
+//   - --connect-timeout (opts.ConnectTimeout) always limits establishing each
+//     connection, and never extends a deadline set by ctx.
+func withTimeouts(ctx context.Context, opts *options.RequestOptions) (context.Context, context.CancelFunc) {
+	if opts.ConnectTimeout > 0 {
+		ctx = context.WithValue(ctx, connectTimeoutKey, opts.ConnectTimeout)
+	}
+	if _, ok := ctx.Deadline(); ok || opts.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, opts.Timeout)
+}
+
+var dialer net.Dialer
+
+// dialContext connects to addr within the connect timeout carried by the
+// request context, if any, resolving it through its DNS cache, or to an
+// alternative service of it. The address connected to must be allowed by
+// the policy of the request.
+func dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	ctx, cancel := withConnectTimeout(ctx)
+	defer cancel()
+	d := policyDialer(ctx, addr)
+	return dialAltSvc(ctx, network, addr, func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialResolved(ctx, network, addr, d.DialContext)
+	})
+}
+
+// dialTLSContext is dialContext for HTTP/2-only transports, which do their own
+// TLS handshake. The handshake counts towards the connect timeout.
+func dialTLSContext(ctx context.Context, network, addr string, config *tls.Config) (net.Conn, error) {
+	ctx, cancel := withConnectTimeout(ctx)
+	defer cancel()
+	if config == nil {
+		config = &tls.Config{}
+	}
+	if config.ServerName == "" {
+		// Verify the certificate of the host, not of the address it
+		// resolves to
+		config = config.Clone()
+		config.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+	d := tls.Dialer{NetDialer: policyDialer(ctx, addr), Config: config}
+	return dialAltSvc(ctx, network, addr, func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialResolved(ctx, network, addr, d.DialContext)
+	})
+}
+
+func withConnectTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout, ok := ctx.Value(connectTimeoutKey).(time.Duration); ok {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {}
+}
@@ -0,0 +1,114 @@
+package gocurl_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeoutFlags(t *testing.T) {
+	t.Run("parsing", func(t *testing.T) {
+		opts, err := gocurl.ArgsToOptions([]string{"curl", "--max-time", "2.5", "--connect-timeout", "0.5", "https://example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, 2500*time.Millisecond, opts.Timeout)
+		assert.Equal(t, 500*time.Millisecond, opts.ConnectTimeout)
+	})
+
+	// Every attempt is quick, but retrying takes longer than --max-time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(40 * time.Millisecond)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	command := func() []string {
+		return []string{"curl", "--max-time", "0.1", server.URL}
+	}
+
+	t.Run("max-time bounds all attempts without a context deadline", func(t *testing.T) {
+		opts, err := gocurl.ArgsToOptions(command())
+		require.NoError(t, err)
+		opts.Silent = true
+		opts.RetryConfig = retryOn503(5)
+
+		start := time.Now()
+		_, _, err = gocurl.Process(context.Background(), opts)
+		require.Error(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("a context deadline takes precedence", func(t *testing.T) {
+		opts, err := gocurl.ArgsToOptions(command())
+		require.NoError(t, err)
+		opts.Silent = true
+		opts.RetryConfig = retryOn503(3)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		resp, _, err := gocurl.Process(ctx, opts)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	})
+
+	t.Run("max-time still limits each attempt", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, _, err := gocurl.CurlString(ctx, "curl", "--max-time", "0.01", server.URL)
+		assert.Error(t, err)
+	})
+}
+
+func retryOn503(retries int) *options.RetryConfig {
+	return &options.RetryConfig{MaxRetries: retries, RetryDelay: 10 * time.Millisecond, RetryOnHTTP: []int{http.StatusServiceUnavailable}}
+}
+
+func TestAttemptTimeout(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			// The first attempt hangs
+			select {
+			case <-r.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	opts := options.NewRequestOptionsBuilder().
+		SetURL(server.URL).
+		SetAttemptTimeout(50 * time.Millisecond).
+		SetRetryConfig(&options.RetryConfig{MaxRetries: 2}).
+		Build()
+	opts.Silent = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, body, err := gocurl.Process(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", body)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&hits))
+
+	t.Run("The context bounds all attempts", func(t *testing.T) {
+		atomic.StoreInt32(&hits, 0)
+		opts := opts.Clone()
+		opts.AttemptTimeout = time.Second
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, _, err := gocurl.Process(ctx, opts)
+		require.Error(t, err)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.EqualValues(t, 1, atomic.LoadInt32(&hits))
+	})
+}
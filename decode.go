@@ -0,0 +1,151 @@
+package gocurl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/maniartech/gocurl/options"
+	"gopkg.in/yaml.v3"
+)
+
+// Decoder decodes a response body into v.
+type Decoder func(r io.Reader, v interface{}) error
+
+var (
+	decodersMu sync.RWMutex
+	// decoders maps media types, and structured syntax suffixes such as
+	// "+json", to the decoder of their bodies
+	decoders = map[string]Decoder{
+		"application/json":   decodeJSON,
+		"text/json":          decodeJSON,
+		"+json":              decodeJSON,
+		"application/xml":    decodeXML,
+		"text/xml":           decodeXML,
+		"+xml":               decodeXML,
+		"application/yaml":   decodeYAML,
+		"application/x-yaml": decodeYAML,
+		"text/yaml":          decodeYAML,
+		"text/x-yaml":        decodeYAML,
+		"+yaml":              decodeYAML,
+	}
+)
+
+// defaultAccept is the Accept header CurlDecode sends when the command sets
+// none.
+var defaultAccept = options.AcceptHeader("application/json", "application/xml", "application/yaml")
+
+func decodeJSON(r io.Reader, v interface{}) error { return json.NewDecoder(r).Decode(v) }
+func decodeXML(r io.Reader, v interface{}) error  { return xml.NewDecoder(r).Decode(v) }
+func decodeYAML(r io.Reader, v interface{}) error { return yaml.NewDecoder(r).Decode(v) }
+
+// RegisterDecoder sets the decoder CurlDecode uses for the bodies of
+// mediaType, such as "application/msgpack". A suffix such as "+cbor" applies
+// to every media type ending with it that has no decoder of its own.
+func RegisterDecoder(mediaType string, decoder Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[strings.ToLower(mediaType)] = decoder
+}
+
+// decoderFor returns the decoder of the Content-Type contentType. Bodies
+// without a Content-Type are decoded as JSON.
+func decoderFor(contentType string) (Decoder, error) {
+	if contentType == "" {
+		return decodeJSON, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Type %q: %v", contentType, err)
+	}
+
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	if decoder, ok := decoders[mediaType]; ok {
+		return decoder, nil
+	}
+	if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+		if decoder, ok := decoders[mediaType[i:]]; ok {
+			return decoder, nil
+		}
+	}
+	return nil, fmt.Errorf("no decoder for Content-Type %q", mediaType)
+}
+
+// CurlDecode executes the curl command and decodes the response body into v
+// with the decoder matching its Content-Type: JSON, XML, YAML or one set
+// with RegisterDecoder. Unless the command sets one, the request prefers
+// JSON, then XML and YAML in its Accept header.
+func CurlDecode(ctx context.Context, v interface{}, command ...string) (*http.Response, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, err
+	}
+	opts.Silent = true
+	if opts.Headers == nil {
+		opts.Headers = http.Header{}
+	}
+	if opts.Headers.Get("Accept") == "" {
+		opts.Headers.Set("Accept", defaultAccept)
+	}
+
+	buf := bodyBufferPool.Get().(*bytes.Buffer)
+	defer putBodyBuffer(buf)
+	buf.Reset()
+
+	resp, err := processInto(ctx, opts, buf)
+	if err != nil {
+		return resp, err
+	}
+	decoder, err := decoderFor(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return resp, err
+	}
+	defaults := withDefaults(ctx, opts)
+
+	// Spooled bodies are decoded straight from disk
+	if spool, ok := resp.Body.(*spooledBody); ok {
+		if err := preDecode(defaults, resp, spool.open); err != nil {
+			return resp, err
+		}
+		if err := decoder(spool.open(), v); err != nil {
+			return resp, fmt.Errorf("failed to decode response: %v", err)
+		}
+		return resp, nil
+	}
+
+	body := append([]byte(nil), buf.Bytes()...)
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err := preDecode(defaults, resp, func() io.Reader { return bytes.NewReader(body) }); err != nil {
+		return resp, err
+	}
+	if err := decoder(bytes.NewReader(body), v); err != nil {
+		return resp, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return resp, nil
+}
+
+// preDecode runs the PreDecodeHook of opts, if any, on resp before its body
+// is decoded. The hook reads the body from open, or none when open is nil,
+// and resp.Body is restored afterwards.
+func preDecode(opts *options.RequestOptions, resp *http.Response, open func() io.Reader) error {
+	if opts.PreDecodeHook == nil {
+		return nil
+	}
+	body := resp.Body
+	resp.Body = http.NoBody
+	if open != nil {
+		resp.Body = ioutil.NopCloser(open())
+	}
+	err := opts.PreDecodeHook(resp)
+	resp.Body = body
+	return err
+}
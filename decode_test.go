@@ -0,0 +1,130 @@
+package gocurl_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type decodedUser struct {
+	Name  string `json:"name" xml:"name" yaml:"name"`
+	Admin bool   `json:"admin" xml:"admin" yaml:"admin"`
+}
+
+func TestCurlDecode(t *testing.T) {
+	var accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/vnd.api+json; charset=utf-8")
+			io.WriteString(w, `{"name":"ada","admin":true}`)
+		case "/xml":
+			w.Header().Set("Content-Type", "text/xml")
+			io.WriteString(w, `<user><name>ada</name><admin>true</admin></user>`)
+		case "/yaml":
+			w.Header().Set("Content-Type", "application/yaml")
+			io.WriteString(w, "name: ada\nadmin: true\n")
+		case "/csv":
+			w.Header().Set("Content-Type", "text/csv")
+			io.WriteString(w, "ada,true\n")
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	for _, path := range []string{"/json", "/xml", "/yaml"} {
+		t.Run(path, func(t *testing.T) {
+			var user decodedUser
+			resp, err := gocurl.CurlDecode(ctx, &user, server.URL+path)
+			require.NoError(t, err)
+			assert.Equal(t, decodedUser{Name: "ada", Admin: true}, user)
+
+			body, _ := io.ReadAll(resp.Body)
+			assert.Contains(t, string(body), "ada")
+		})
+	}
+	assert.Equal(t, "application/json, application/xml;q=0.9, application/yaml;q=0.8", accept)
+
+	t.Run("Explicit Accept", func(t *testing.T) {
+		var user decodedUser
+		_, err := gocurl.CurlDecode(ctx, &user, "-H", "Accept: application/yaml", server.URL+"/yaml")
+		require.NoError(t, err)
+		assert.Equal(t, "application/yaml", accept)
+	})
+
+	t.Run("Unknown Content-Type", func(t *testing.T) {
+		var user decodedUser
+		_, err := gocurl.CurlDecode(ctx, &user, server.URL+"/csv")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `no decoder for Content-Type "text/csv"`)
+	})
+
+	t.Run("Registered decoder", func(t *testing.T) {
+		gocurl.RegisterDecoder("text/csv", func(r io.Reader, v interface{}) error {
+			data, err := io.ReadAll(r)
+			fields := strings.Split(strings.TrimSpace(string(data)), ",")
+			*v.(*decodedUser) = decodedUser{Name: fields[0], Admin: fields[1] == "true"}
+			return err
+		})
+		var user decodedUser
+		_, err := gocurl.CurlDecode(ctx, &user, server.URL+"/csv")
+		require.NoError(t, err)
+		assert.Equal(t, decodedUser{Name: "ada", Admin: true}, user)
+	})
+}
+
+func TestPreDecodeHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/signed" {
+			w.Header().Set("X-Signature", "ok")
+		}
+		io.WriteString(w, `{"name":"ada","admin":true}`)
+	}))
+	defer server.Close()
+
+	errUnsigned := errors.New("unsigned response")
+	var seen string
+	hook := func(resp *http.Response) error {
+		body, _ := io.ReadAll(resp.Body)
+		seen = string(body)
+		if resp.Header.Get("X-Signature") != "ok" {
+			return errUnsigned
+		}
+		return nil
+	}
+	ctx := gocurl.WithDefaults(context.Background(), options.NewRequestOptionsBuilder().SetPreDecodeHook(hook).Build())
+
+	var user decodedUser
+	_, err := gocurl.CurlJSON(ctx, &user, server.URL+"/signed")
+	require.NoError(t, err)
+	assert.Equal(t, decodedUser{Name: "ada", Admin: true}, user)
+	assert.Equal(t, `{"name":"ada","admin":true}`, seen)
+
+	for name, decode := range map[string]func(v interface{}) (*http.Response, error){
+		"CurlJSON":        func(v interface{}) (*http.Response, error) { return gocurl.CurlJSON(ctx, v, server.URL) },
+		"CurlDecode":      func(v interface{}) (*http.Response, error) { return gocurl.CurlDecode(ctx, v, server.URL) },
+		"Client.CurlJSON": func(v interface{}) (*http.Response, error) { return gocurl.NewClient().CurlJSON(ctx, v, server.URL) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			var user decodedUser
+			resp, err := decode(&user)
+			assert.ErrorIs(t, err, errUnsigned)
+			assert.Empty(t, user.Name)
+
+			// The body is left for the caller
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, `{"name":"ada","admin":true}`, string(body))
+		})
+	}
+}
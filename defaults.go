@@ -0,0 +1,69 @@
+package gocurl
+
+import (
+	"context"
+	"sync"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+var (
+	defaultsMu sync.RWMutex
+	// globalDefaults are the process-wide defaults set with SetDefaults
+	globalDefaults *options.RequestOptions
+)
+
+// SetDefaults sets process-wide defaults for every request gocurl executes,
+// e.g. organization-wide headers, timeouts or retry policy, without passing
+// a Client or Session around. Requests inherit the values they do not set
+// themselves, as from the defaults of a Session; their precedence is the
+// request, then its Session, then scoped defaults set with WithDefaults,
+// then these. A nil opts removes the defaults. It is safe to call
+// concurrently with requests, which use the defaults current when they
+// start.
+func SetDefaults(opts *options.RequestOptions) {
+	if opts != nil {
+		opts = opts.Clone()
+	}
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
+	globalDefaults = opts
+}
+
+// Defaults returns a copy of the process-wide defaults, or nil when none are
+// set.
+func Defaults() *options.RequestOptions {
+	defaultsMu.RLock()
+	defer defaultsMu.RUnlock()
+	if globalDefaults == nil {
+		return nil
+	}
+	return globalDefaults.Clone()
+}
+
+// WithDefaults returns a context whose requests inherit the values of opts
+// they do not set, overriding the process-wide defaults of SetDefaults for
+// a scope such as a subsystem or an incoming request. Scopes nest: opts
+// inherits the defaults of ctx in turn.
+func WithDefaults(ctx context.Context, opts *options.RequestOptions) context.Context {
+	scoped := opts.Clone()
+	if parent, ok := ctx.Value(defaultsKey).(*options.RequestOptions); ok {
+		scoped = applyDefaults(scoped, parent)
+	}
+	return context.WithValue(ctx, defaultsKey, scoped)
+}
+
+// withDefaults returns opts completed with the scoped defaults of ctx and
+// the process-wide ones, or opts itself when there are none.
+func withDefaults(ctx context.Context, opts *options.RequestOptions) *options.RequestOptions {
+	if scoped, ok := ctx.Value(defaultsKey).(*options.RequestOptions); ok {
+		opts = applyDefaults(opts, scoped)
+	}
+	defaultsMu.RLock()
+	global := globalDefaults
+	defaultsMu.RUnlock()
+	if global != nil {
+		opts = applyDefaults(opts, global)
+	}
+	return opts
+}
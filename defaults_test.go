@@ -0,0 +1,78 @@
+package gocurl_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("X-Org")+"|"+r.Header.Get("X-Team"))
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	gocurl.SetDefaults(options.NewRequestOptionsBuilder().
+		AddHeader("X-Org", "acme").
+		AddHeader("X-Team", "platform").
+		Build())
+	defer gocurl.SetDefaults(nil)
+	assert.Equal(t, []string{"acme"}, gocurl.Defaults().Headers["X-Org"])
+
+	_, body, err := gocurl.Curl(ctx, "-s", server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "acme|platform", body)
+
+	_, body, err = gocurl.Curl(ctx, "-s", "-H", "X-Org: other", server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "other|platform", body, "the request overrides the defaults")
+
+	scoped := gocurl.WithDefaults(ctx, options.NewRequestOptionsBuilder().AddHeader("X-Team", "billing").Build())
+	_, body, err = gocurl.Curl(scoped, "-s", server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "acme|billing", body, "the scope overrides the global defaults")
+
+	nested := gocurl.WithDefaults(scoped, options.NewRequestOptionsBuilder().AddHeader("X-Org", "sub").Build())
+	_, body, err = gocurl.NewClient().Curl(nested, "-s", server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "sub|billing", body, "scopes nest")
+
+	gocurl.SetDefaults(nil)
+	assert.Nil(t, gocurl.Defaults())
+	_, body, err = gocurl.Curl(ctx, "-s", server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "|", body)
+}
+
+func TestDefaultsConcurrent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("X-Org"))
+	}))
+	defer server.Close()
+	defer gocurl.SetDefaults(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			gocurl.SetDefaults(options.NewRequestOptionsBuilder().AddHeader("X-Org", "acme").Build())
+		}()
+		go func() {
+			defer wg.Done()
+			_, body, err := gocurl.Curl(context.Background(), "-s", server.URL)
+			assert.NoError(t, err)
+			assert.Contains(t, []string{"", "acme"}, body)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,304 @@
+package gocurl
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// Difference is a single structural difference between two requests. A and B
+// hold the value on each side. OnlyIn is "a" or "b" when the value is present
+// on that side only.
+type Difference struct {
+	Field  string `json:"field"`
+	Key    string `json:"key,omitempty"`
+	A      string `json:"a"`
+	B      string `json:"b"`
+	OnlyIn string `json:"only_in,omitempty"`
+}
+
+// String formats d as "field key: a != b".
+func (d Difference) String() string {
+	name := d.Field
+	if d.Key != "" {
+		name += " " + d.Key
+	}
+	a, b := fmt.Sprintf("%q", d.A), fmt.Sprintf("%q", d.B)
+	switch d.OnlyIn {
+	case "a":
+		b = "(missing)"
+	case "b":
+		a = "(missing)"
+	}
+	return fmt.Sprintf("%s: %s != %s", name, a, b)
+}
+
+// missingValue marks a value that is absent on one side of a comparison.
+const missingValue = "\x00missing"
+
+// Diff is the list of differences between two requests or responses.
+type Diff struct {
+	Differences []Difference `json:"differences"`
+}
+
+// Equal reports whether no differences were found.
+func (d *Diff) Equal() bool {
+	return len(d.Differences) == 0
+}
+
+// String returns one difference per line.
+func (d *Diff) String() string {
+	var sb strings.Builder
+	for _, diff := range d.Differences {
+		sb.WriteString(diff.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func (d *Diff) add(field, key, a, b string) {
+	if a == b {
+		return
+	}
+	diff := Difference{Field: field, Key: key, A: a, B: b}
+	if a == missingValue {
+		diff.A, diff.OnlyIn = "", "b"
+	} else if b == missingValue {
+		diff.B, diff.OnlyIn = "", "a"
+	}
+	d.Differences = append(d.Differences, diff)
+}
+
+// DiffCommands parses two curl commands and reports how the requests they
+// describe differ in method, URL, query parameters, headers, cookies,
+// authentication, form fields, body and transfer options.
+func DiffCommands(cmdA, cmdB string) (*Diff, error) {
+	a, err := parseCommand(cmdA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse first command: %v", err)
+	}
+	b, err := parseCommand(cmdB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse second command: %v", err)
+	}
+	return DiffOptions(a, b), nil
+}
+
+// DiffOptions reports the structural differences between two requests.
+func DiffOptions(a, b *options.RequestOptions) *Diff {
+	d := &Diff{}
+
+	d.add("method", "", methodOf(a), methodOf(b))
+	d.add("url", "", a.URL, b.URL)
+	diffValues(d, "query", a.QueryParams, b.QueryParams)
+	diffValues(d, "header", url.Values(a.Headers), url.Values(b.Headers))
+	diffValues(d, "cookie", cookieValues(a.Cookies), cookieValues(b.Cookies))
+	d.add("basic auth", "", basicAuthOf(a), basicAuthOf(b))
+	d.add("bearer token", "", a.BearerToken, b.BearerToken)
+	diffValues(d, "form", a.Form, b.Form)
+	diffBodies(d, a.Body, b.Body)
+
+	d.add("option", "follow redirects", fmt.Sprint(a.FollowRedirects), fmt.Sprint(b.FollowRedirects))
+	d.add("option", "insecure", fmt.Sprint(a.Insecure), fmt.Sprint(b.Insecure))
+	d.add("option", "compressed", fmt.Sprint(a.Compress), fmt.Sprint(b.Compress))
+	d.add("option", "http2", fmt.Sprint(a.HTTP2 || a.HTTP2Only), fmt.Sprint(b.HTTP2 || b.HTTP2Only))
+	d.add("option", "proxy", a.Proxy, b.Proxy)
+	d.add("option", "timeout", a.Timeout.String(), b.Timeout.String())
+
+	return d
+}
+
+func methodOf(opts *options.RequestOptions) string {
+	if opts.Method == "" {
+		return "GET"
+	}
+	return strings.ToUpper(opts.Method)
+}
+
+func basicAuthOf(opts *options.RequestOptions) string {
+	if opts.BasicAuth == nil {
+		return ""
+	}
+	return opts.BasicAuth.Username + ":" + opts.BasicAuth.Password
+}
+
+func cookieValues(cookies []*http.Cookie) url.Values {
+	values := url.Values{}
+	for _, cookie := range cookies {
+		values.Add(cookie.Name, cookie.Value)
+	}
+	return values
+}
+
+// diffValues compares multi-valued maps key by key; header keys are compared
+// case-insensitively.
+func diffValues(d *Diff, field string, a, b url.Values) {
+	normalize := func(values url.Values) map[string]string {
+		m := map[string]string{}
+		for key, vals := range values {
+			if field == "header" {
+				key = http.CanonicalHeaderKey(key)
+			}
+			m[key] = strings.Join(vals, ", ")
+		}
+		return m
+	}
+	ma, mb := normalize(a), normalize(b)
+
+	for _, key := range sortedKeys(ma, mb) {
+		va, ok := ma[key]
+		if !ok {
+			va = missingValue
+		}
+		vb, ok := mb[key]
+		if !ok {
+			vb = missingValue
+		}
+		d.add(field, key, va, vb)
+	}
+}
+
+func sortedKeys(maps ...map[string]string) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, m := range maps {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// diffBodies compares JSON bodies field by field and other bodies verbatim.
+func diffBodies(d *Diff, a, b string) {
+	var ja, jb interface{}
+	if json.Unmarshal([]byte(a), &ja) == nil && json.Unmarshal([]byte(b), &jb) == nil {
+		diffJSON(d, "body", "$", ja, jb, false)
+		return
+	}
+	d.add("body", "", a, b)
+}
+
+// diffJSON compares two decoded JSON documents and records a difference for
+// every path whose value differs. With unordered set, arrays are compared as
+// multisets.
+func diffJSON(d *Diff, field, path string, a, b interface{}, unordered bool) {
+	switch va := a.(type) {
+	case map[string]interface{}:
+		vb, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := map[string]string{}
+		for key := range va {
+			keys[key] = ""
+		}
+		for key := range vb {
+			keys[key] = ""
+		}
+		for _, key := range sortedKeys(keys) {
+			ea, okA := va[key]
+			eb, okB := vb[key]
+			child := path + "." + key
+			switch {
+			case !okA:
+				d.add(field, child, missingValue, encodeJSON(eb))
+			case !okB:
+				d.add(field, child, encodeJSON(ea), missingValue)
+			default:
+				diffJSON(d, field, child, ea, eb, unordered)
+			}
+		}
+		return
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		if unordered {
+			if !sameMultiset(va, vb) {
+				d.add(field, path, encodeJSON(va), encodeJSON(vb))
+			}
+			return
+		}
+		for i := 0; i < len(va) || i < len(vb); i++ {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(va):
+				d.add(field, child, missingValue, encodeJSON(vb[i]))
+			case i >= len(vb):
+				d.add(field, child, encodeJSON(va[i]), missingValue)
+			default:
+				diffJSON(d, field, child, va[i], vb[i], unordered)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		d.add(field, path, encodeJSON(a), encodeJSON(b))
+	}
+}
+
+// sameMultiset reports whether a and b hold the same elements in any order.
+func sameMultiset(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := map[string]int{}
+	for _, v := range a {
+		counts[canonicalJSON(v)]++
+	}
+	for _, v := range b {
+		key := canonicalJSON(v)
+		if counts[key] == 0 {
+			return false
+		}
+		counts[key]--
+	}
+	return true
+}
+
+// canonicalJSON encodes v with arrays sorted so that equal multisets encode
+// identically. Object keys are already sorted by encoding/json.
+func canonicalJSON(v interface{}) string {
+	if arr, ok := v.([]interface{}); ok {
+		elems := make([]string, len(arr))
+		for i, e := range arr {
+			elems[i] = canonicalJSON(e)
+		}
+		sort.Strings(elems)
+		return "[" + strings.Join(elems, ",") + "]"
+	}
+	if obj, ok := v.(map[string]interface{}); ok {
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = encodeJSON(key) + ":" + canonicalJSON(obj[key])
+		}
+		return "{" + strings.Join(parts, ",") + "}"
+	}
+	return encodeJSON(v)
+}
+
+func encodeJSON(v interface{}) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,52 @@
+package gocurl_test
+
+import (
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffCommands(t *testing.T) {
+	t.Run("Identical requests", func(t *testing.T) {
+		diff, err := gocurl.DiffCommands(
+			`curl -H 'accept: application/json' 'https://api.example.com/items?a=1&b=2'`,
+			`curl https://api.example.com/items?b=2\&a=1 -H "Accept: application/json"`,
+		)
+		require.NoError(t, err)
+		assert.True(t, diff.Equal(), diff.String())
+	})
+
+	t.Run("Structural differences", func(t *testing.T) {
+		diff, err := gocurl.DiffCommands(
+			`curl -X PUT -H 'X-Trace: 1' -d '{"user":{"id":1,"tags":["a"]}}' 'https://api.example.com/items?page=1'`,
+			`curl -X POST -H 'Accept: */*' -d '{"user":{"id":2,"tags":["a","b"]}}' 'https://api.example.com/items?page=2'`,
+		)
+		require.NoError(t, err)
+
+		assert.Equal(t, []gocurl.Difference{
+			{Field: "method", A: "PUT", B: "POST"},
+			{Field: "query", Key: "page", A: "1", B: "2"},
+			{Field: "header", Key: "Accept", B: "*/*", OnlyIn: "b"},
+			{Field: "header", Key: "X-Trace", A: "1", OnlyIn: "a"},
+			{Field: "body", Key: "$.user.id", A: "1", B: "2"},
+			{Field: "body", Key: "$.user.tags[1]", B: `"b"`, OnlyIn: "b"},
+		}, diff.Differences)
+		assert.Contains(t, diff.String(), `header X-Trace: "1" != (missing)`)
+	})
+
+	t.Run("Non JSON bodies are compared verbatim", func(t *testing.T) {
+		diff, err := gocurl.DiffCommands(
+			`curl -d 'a=1' https://api.example.com`,
+			`curl -d 'a=2' https://api.example.com`,
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []gocurl.Difference{{Field: "body", A: "a=1", B: "a=2"}}, diff.Differences)
+	})
+
+	t.Run("Invalid command", func(t *testing.T) {
+		_, err := gocurl.DiffCommands(`curl 'https://api.example.com`, `curl https://api.example.com`)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,348 @@
+package gocurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNSLookupFunc resolves host to its addresses and returns how long they
+// may be cached.
+type DNSLookupFunc func(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error)
+
+// DNSCache caches the addresses hosts resolve to, so high-QPS clients do not
+// query the resolver for every new connection. Entries live for the TTL of
+// their records, clamped to [MinTTL, MaxTTL], and hosts that do not exist
+// are remembered for NegativeTTL. It is safe for concurrent use.
+type DNSCache struct {
+	// Lookup resolves hosts. It defaults to the system resolver, which does
+	// not expose record TTLs: its answers are cached for DefaultTTL. Use
+	// DNSServerLookup to honor the TTLs of a DNS server.
+	Lookup DNSLookupFunc
+	// DefaultTTL is the TTL of answers without one, 1 minute by default
+	DefaultTTL time.Duration
+	// MinTTL and MaxTTL clamp the TTLs of answers; zero leaves them unbounded
+	MinTTL, MaxTTL time.Duration
+	// NegativeTTL is how long a host that does not exist is remembered, 5
+	// seconds by default; a negative value disables negative caching
+	NegativeTTL time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]*dnsEntry
+	overrides map[string][]net.IPAddr
+	now       func() time.Time
+}
+
+type dnsEntry struct {
+	ready   chan struct{}
+	addrs   []net.IPAddr
+	err     error
+	expires time.Time
+}
+
+// NewDNSCache creates an empty DNSCache using the system resolver.
+func NewDNSCache() *DNSCache {
+	return &DNSCache{}
+}
+
+// WithDNSCache returns a context making the connections of the requests
+// executed with it resolve their hosts through cache.
+func WithDNSCache(ctx context.Context, cache *DNSCache) context.Context {
+	return context.WithValue(ctx, dnsCacheKey, cache)
+}
+
+// SetDNSCache resolves the hosts the client connects to through cache.
+func (c *Client) SetDNSCache(cache *DNSCache) *Client {
+	c.dns = cache
+	return c
+}
+
+// Override makes host resolve to addrs, like curl --resolve, until it is
+// flushed. Without addrs, the override of host is removed.
+func (c *DNSCache) Override(host string, addrs ...string) error {
+	ips := make([]net.IPAddr, 0, len(addrs))
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return fmt.Errorf("invalid address %q for %s", addr, host)
+		}
+		ips = append(ips, net.IPAddr{IP: ip})
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(ips) == 0 {
+		delete(c.overrides, host)
+		return nil
+	}
+	if c.overrides == nil {
+		c.overrides = map[string][]net.IPAddr{}
+	}
+	c.overrides[host] = ips
+	return nil
+}
+
+// Flush forgets what is cached for hosts, overrides included, or for every
+// host when none is given.
+func (c *DNSCache) Flush(hosts ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(hosts) == 0 {
+		c.entries, c.overrides = nil, nil
+		return
+	}
+	for _, host := range hosts {
+		delete(c.entries, host)
+		delete(c.overrides, host)
+	}
+}
+
+// LookupIPAddr returns the addresses of host, from the cache when they have
+// not expired. Concurrent lookups of the same host share a single query.
+func (c *DNSCache) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IPAddr{{IP: ip}}, nil
+	}
+
+	c.mu.Lock()
+	if addrs, ok := c.overrides[host]; ok {
+		c.mu.Unlock()
+		return addrs, nil
+	}
+	now := c.clock()
+	entry, ok := c.entries[host]
+	if ok {
+		select {
+		case <-entry.ready:
+			if now.Before(entry.expires) {
+				c.mu.Unlock()
+				return entry.addrs, entry.err
+			}
+			ok = false
+		default:
+		}
+	}
+	if !ok {
+		entry = &dnsEntry{ready: make(chan struct{})}
+		if c.entries == nil {
+			c.entries = map[string]*dnsEntry{}
+		}
+		c.entries[host] = entry
+		c.mu.Unlock()
+		c.resolve(ctx, host, entry)
+		return entry.addrs, entry.err
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-entry.ready:
+		return entry.addrs, entry.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// resolve looks host up into entry and releases those waiting for it.
+func (c *DNSCache) resolve(ctx context.Context, host string, entry *dnsEntry) {
+	lookup := c.Lookup
+	if lookup == nil {
+		lookup = c.systemLookup
+	}
+	addrs, ttl, err := lookup(ctx, host)
+
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil:
+		ttl = c.clamp(ttl)
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound && c.NegativeTTL >= 0:
+		ttl = c.NegativeTTL
+		if ttl == 0 {
+			ttl = 5 * time.Second
+		}
+	default:
+		// Transient failures are not cached
+		ttl = 0
+	}
+
+	c.mu.Lock()
+	entry.addrs, entry.err = addrs, err
+	entry.expires = c.clock().Add(ttl)
+	if ttl == 0 && c.entries[host] == entry {
+		delete(c.entries, host)
+	}
+	c.mu.Unlock()
+	close(entry.ready)
+}
+
+func (c *DNSCache) clamp(ttl time.Duration) time.Duration {
+	if ttl < c.MinTTL {
+		ttl = c.MinTTL
+	}
+	if c.MaxTTL > 0 && ttl > c.MaxTTL {
+		ttl = c.MaxTTL
+	}
+	return ttl
+}
+
+func (c *DNSCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+func (c *DNSCache) systemLookup(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	ttl := c.DefaultTTL
+	if ttl == 0 {
+		ttl = time.Minute
+	}
+	return addrs, ttl, err
+}
+
+// DNSServerLookup returns a DNSLookupFunc querying the A and AAAA records of
+// hosts from the DNS server at addr (host:port) over UDP, with the TTLs of
+// the records. Unlike the system resolver it ignores /etc/hosts and search
+// domains, so hosts must be fully qualified.
+func DNSServerLookup(addr string) DNSLookupFunc {
+	return func(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+		var addrs []net.IPAddr
+		var ttl time.Duration
+		found := false
+		for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+			answers, answerTTL, err := queryDNS(ctx, addr, host, qtype)
+			if err != nil {
+				return nil, 0, err
+			}
+			if answers != nil {
+				found = true
+			}
+			if len(answers) > 0 && (len(addrs) == 0 || answerTTL < ttl) {
+				ttl = answerTTL
+			}
+			addrs = append(addrs, answers...)
+		}
+		if !found || len(addrs) == 0 {
+			return nil, 0, &net.DNSError{Err: "no such host", Name: host, Server: addr, IsNotFound: true}
+		}
+		return addrs, ttl, nil
+	}
+}
+
+// queryDNS queries the records of type qtype of host from server. It
+// returns a nil slice when the host does not exist and an empty one when it
+// has no such records.
+func queryDNS(ctx context.Context, server, host string, qtype dnsmessage.Type) ([]net.IPAddr, time.Duration, error) {
+	name, err := dnsmessage.NewName(dnsName(host))
+	if err != nil {
+		return nil, 0, &net.DNSError{Err: err.Error(), Name: host, Server: server}
+	}
+	id := uint16(time.Now().UnixNano())
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packet, err := query.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+	if _, err := conn.Write(packet); err != nil {
+		return nil, 0, err
+	}
+
+	buf := make([]byte, 1232)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, 0, &net.DNSError{Err: err.Error(), Name: host, Server: server, IsTimeout: isTimeout(err)}
+		}
+		var reply dnsmessage.Message
+		if err := reply.Unpack(buf[:n]); err != nil || reply.ID != id || !reply.Response {
+			continue
+		}
+		switch reply.RCode {
+		case dnsmessage.RCodeSuccess:
+		case dnsmessage.RCodeNameError:
+			return nil, 0, nil
+		default:
+			return nil, 0, &net.DNSError{Err: "server failure: " + reply.RCode.String(), Name: host, Server: server, IsTemporary: true}
+		}
+
+		addrs := []net.IPAddr{}
+		var ttl time.Duration
+		for _, answer := range reply.Answers {
+			var ip net.IP
+			switch body := answer.Body.(type) {
+			case *dnsmessage.AResource:
+				ip = net.IP(body.A[:])
+			case *dnsmessage.AAAAResource:
+				ip = net.IP(body.AAAA[:])
+			default:
+				continue
+			}
+			answerTTL := time.Duration(answer.Header.TTL) * time.Second
+			if len(addrs) == 0 || answerTTL < ttl {
+				ttl = answerTTL
+			}
+			addrs = append(addrs, net.IPAddr{IP: ip})
+		}
+		return addrs, ttl, nil
+	}
+}
+
+func dnsName(host string) string {
+	if len(host) > 0 && host[len(host)-1] == '.' {
+		return host
+	}
+	return host + "."
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// dialResolved dials addr with dial, resolving its host through the DNS
+// cache carried by ctx, if any, and trying its addresses in turn.
+func dialResolved(ctx context.Context, network, addr string, dial func(ctx context.Context, network, addr string) (net.Conn, error)) (net.Conn, error) {
+	cache, _ := ctx.Value(dnsCacheKey).(*DNSCache)
+	if cache == nil {
+		return dial(ctx, network, addr)
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return dial(ctx, network, addr)
+	}
+	addrs, err := cache.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	for _, ip := range addrs {
+		var conn net.Conn
+		conn, err = dial(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	if err == nil {
+		err = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+	return nil, err
+}
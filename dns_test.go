@@ -0,0 +1,151 @@
+package gocurl_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestDNSCache(t *testing.T) {
+	ctx := context.Background()
+	var lookups int32
+	cache := gocurl.NewDNSCache()
+	cache.MinTTL = 20 * time.Millisecond
+	cache.MaxTTL = time.Hour
+	cache.Lookup = func(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+		atomic.AddInt32(&lookups, 1)
+		if host == "missing.test" {
+			return nil, 0, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		if host == "flaky.test" {
+			return nil, 0, errors.New("timeout")
+		}
+		return []net.IPAddr{{IP: net.IPv4(10, 0, 0, 1)}}, time.Millisecond, nil
+	}
+
+	addrs, err := cache.LookupIPAddr(ctx, "api.test")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", addrs[0].IP.String())
+	_, err = cache.LookupIPAddr(ctx, "api.test")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&lookups), "cached for MinTTL")
+
+	time.Sleep(30 * time.Millisecond)
+	_, err = cache.LookupIPAddr(ctx, "api.test")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&lookups), "expired")
+
+	for i := 0; i < 2; i++ {
+		_, err = cache.LookupIPAddr(ctx, "missing.test")
+		require.Error(t, err)
+	}
+	assert.EqualValues(t, 3, atomic.LoadInt32(&lookups), "negative answers are cached")
+
+	for i := 0; i < 2; i++ {
+		_, err = cache.LookupIPAddr(ctx, "flaky.test")
+		require.Error(t, err)
+	}
+	assert.EqualValues(t, 5, atomic.LoadInt32(&lookups), "transient failures are not cached")
+
+	cache.Flush("missing.test")
+	_, err = cache.LookupIPAddr(ctx, "missing.test")
+	require.Error(t, err)
+	assert.EqualValues(t, 6, atomic.LoadInt32(&lookups))
+
+	require.NoError(t, cache.Override("api.test", "10.0.0.2"))
+	addrs, err = cache.LookupIPAddr(ctx, "api.test")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", addrs[0].IP.String())
+	assert.Error(t, cache.Override("api.test", "nope"))
+	cache.Flush()
+	addrs, err = cache.LookupIPAddr(ctx, "api.test")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", addrs[0].IP.String())
+}
+
+func TestClientDNSCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Host)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+
+	cache := gocurl.NewDNSCache()
+	require.NoError(t, cache.Override("api.test", "127.0.0.1"))
+	client := gocurl.NewClient().SetDNSCache(cache)
+
+	_, body, err := client.Curl(context.Background(), "-s", "http://api.test:"+port+"/")
+	require.NoError(t, err)
+	assert.Equal(t, "api.test:"+port, body)
+
+	_, body, err = gocurl.Curl(gocurl.WithDNSCache(context.Background(), cache), "-s", "http://api.test:"+port+"/")
+	require.NoError(t, err)
+	assert.Equal(t, "api.test:"+port, body)
+}
+
+func TestDNSServerLookup(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	go serveDNS(conn)
+
+	lookup := gocurl.DNSServerLookup(conn.LocalAddr().String())
+	addrs, ttl, err := lookup(context.Background(), "api.test")
+	require.NoError(t, err)
+	require.Len(t, addrs, 2)
+	assert.Equal(t, "10.0.0.1", addrs[0].IP.String())
+	assert.Equal(t, "fd00::1", addrs[1].IP.String())
+	assert.Equal(t, 30*time.Second, ttl, "the shortest TTL")
+
+	_, _, err = lookup(context.Background(), "missing.test")
+	var dnsErr *net.DNSError
+	require.ErrorAs(t, err, &dnsErr)
+	assert.True(t, dnsErr.IsNotFound)
+}
+
+// serveDNS answers api.test with an A record with a TTL of 60s and an AAAA
+// record with a TTL of 30s, and any other name with NXDOMAIN.
+func serveDNS(conn net.PacketConn) {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var query dnsmessage.Message
+		if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+			continue
+		}
+		q := query.Questions[0]
+		reply := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.ID, Response: true},
+			Questions: query.Questions,
+		}
+		header := dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class}
+		switch {
+		case q.Name.String() != "api.test.":
+			reply.RCode = dnsmessage.RCodeNameError
+		case q.Type == dnsmessage.TypeA:
+			header.TTL = 60
+			reply.Answers = append(reply.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}}})
+		case q.Type == dnsmessage.TypeAAAA:
+			header.TTL = 30
+			ip := [16]byte{0: 0xfd, 15: 1}
+			reply.Answers = append(reply.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.AAAAResource{AAAA: ip}})
+		}
+		packet, _ := reply.Pack()
+		conn.WriteTo(packet, addr)
+	}
+}
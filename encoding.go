@@ -0,0 +1,148 @@
+package gocurl
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Codec compresses and decompresses the bodies of a content encoding.
+type Codec interface {
+	// NewReader returns a reader decoding r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+	// NewWriter returns a writer encoding to w. Closing it flushes the
+	// encoded data without closing w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+var encodings = struct {
+	sync.RWMutex
+	names  []string
+	codecs map[string]Codec
+}{codecs: map[string]Codec{}}
+
+func init() {
+	RegisterEncoding("deflate", deflateCodec{})
+	RegisterEncoding("gzip", gzipCodec{})
+}
+
+// RegisterEncoding makes codec handle the content encoding name, as used in
+// Accept-Encoding and Content-Encoding headers. Registered encodings are
+// offered by --compressed requests, in the order they were registered, and
+// decoded from their responses; BodyCompression compresses request bodies
+// with them. deflate and gzip are built in, brotli ("br") and zstd are
+// registered by importing the github.com/maniartech/gocurl/encoding/brotli
+// and github.com/maniartech/gocurl/encoding/zstd modules. Registering a
+// name again replaces its codec.
+func RegisterEncoding(name string, codec Codec) {
+	name = strings.ToLower(name)
+	encodings.Lock()
+	defer encodings.Unlock()
+	if _, ok := encodings.codecs[name]; !ok {
+		encodings.names = append(encodings.names, name)
+	}
+	encodings.codecs[name] = codec
+}
+
+// Encodings returns the names of the registered encodings, in the order
+// they were registered.
+func Encodings() []string {
+	encodings.RLock()
+	defer encodings.RUnlock()
+	return append([]string(nil), encodings.names...)
+}
+
+// AcceptEncoding returns the Accept-Encoding header of --compressed
+// requests, listing the registered encodings.
+func AcceptEncoding() string {
+	return strings.Join(Encodings(), ", ")
+}
+
+// codecFor returns the codec of the content encoding name, if registered.
+func codecFor(name string) (Codec, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "x-gzip" {
+		name = "gzip"
+	}
+	encodings.RLock()
+	defer encodings.RUnlock()
+	codec, ok := encodings.codecs[name]
+	return codec, ok
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error)  { return gzip.NewReader(r) }
+func (gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil }
+
+// deflateCodec writes zlib wrapped deflate streams, as HTTP specifies, and
+// reads raw ones too, as some servers send them.
+type deflateCodec struct{}
+
+func (deflateCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == nil && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 && header[0]&0x0f == 8 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+func (deflateCodec) NewWriter(w io.Writer) (io.WriteCloser, error) { return zlib.NewWriter(w), nil }
+
+// decodingTransport decodes the responses of --compressed requests, which
+// net/http leaves encoded as they set Accept-Encoding themselves, like curl
+// does.
+type decodingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *decodingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || req.Method == http.MethodHead || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return resp, err
+	}
+	encoding := resp.Header.Get("Content-Encoding")
+	if encoding == "" || resp.ContentLength == 0 {
+		return resp, nil
+	}
+	codec, ok := codecFor(encoding)
+	if !ok {
+		return resp, nil
+	}
+	body, err := codec.NewReader(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to decode %s response: %v", encoding, err)
+	}
+	resp.Body = &decodedBody{ReadCloser: body, raw: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport.
+func (t *decodingTransport) CloseIdleConnections() {
+	if closer, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// decodedBody closes both the decoder and the encoded body it reads.
+type decodedBody struct {
+	io.ReadCloser
+	raw io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	b.ReadCloser.Close()
+	return b.raw.Close()
+}
@@ -0,0 +1,44 @@
+// Package brotli registers the brotli ("br") content encoding with gocurl,
+// so --compressed requests offer and decode it and BodyCompression can
+// compress request bodies with it. It is a module of its own to keep the
+// brotli implementation out of gocurl's dependencies; import it for its
+// side effect:
+//
+//	import _ "github.com/maniartech/gocurl/encoding/brotli"
+package brotli
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+	"github.com/maniartech/gocurl"
+)
+
+// Name is the content encoding of brotli.
+const Name = "br"
+
+func init() {
+	gocurl.RegisterEncoding(Name, Codec{})
+}
+
+// Codec is the gocurl.Codec of brotli. Its zero value writes with the
+// default compression level.
+type Codec struct {
+	// Level is the compression level, from 0 to 11; zero means the
+	// default level
+	Level int
+}
+
+// NewReader returns a reader decoding the brotli stream r.
+func (Codec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(brotli.NewReader(r)), nil
+}
+
+// NewWriter returns a writer compressing to w with brotli.
+func (c Codec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	level := c.Level
+	if level == 0 {
+		level = brotli.DefaultCompression
+	}
+	return brotli.NewWriterLevel(w, level), nil
+}
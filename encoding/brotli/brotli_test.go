@@ -0,0 +1,47 @@
+package brotli_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/encoding/brotli"
+)
+
+func TestCodec(t *testing.T) {
+	if !strings.Contains(gocurl.AcceptEncoding(), brotli.Name) {
+		t.Fatalf("%s is not registered: %s", brotli.Name, gocurl.AcceptEncoding())
+	}
+
+	text := strings.Repeat("gocurl ", 100)
+	var buf bytes.Buffer
+	w, err := brotli.Codec{}.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, text)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() >= len(text) {
+		t.Errorf("compressed %d bytes into %d", len(text), buf.Len())
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", brotli.Name)
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+	_, body, err := gocurl.Curl(context.Background(), "-s", "--compressed", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != text {
+		t.Errorf("body = %q, want %q", body, text)
+	}
+}
@@ -0,0 +1,16 @@
+module github.com/maniartech/gocurl/encoding/brotli
+
+go 1.22.3
+
+require (
+	github.com/andybalholm/brotli v1.2.5
+	github.com/maniartech/gocurl v0.0.0
+)
+
+require (
+	golang.org/x/net v0.30.0 // indirect
+	golang.org/x/text v0.19.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/maniartech/gocurl => ../..
@@ -0,0 +1,50 @@
+// Package zstd registers the zstd content encoding with gocurl, so
+// --compressed requests offer and decode it and BodyCompression can
+// compress request bodies with it. It is a module of its own to keep the
+// zstd implementation out of gocurl's dependencies; import it for its side
+// effect:
+//
+//	import _ "github.com/maniartech/gocurl/encoding/zstd"
+package zstd
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/maniartech/gocurl"
+)
+
+// Name is the content encoding of zstd.
+const Name = "zstd"
+
+func init() {
+	gocurl.RegisterEncoding(Name, Codec{})
+}
+
+// Codec is the gocurl.Codec of zstd. Its zero value writes with the
+// default compression level.
+type Codec struct {
+	// Level is the compression level; zero means the default level
+	Level zstd.EncoderLevel
+}
+
+// NewReader returns a reader decoding the zstd stream r. Closing it
+// releases the decoder.
+func (Codec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	// HTTP bodies are decoded as they stream in, without the decoder's
+	// read-ahead goroutines
+	decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return decoder.IOReadCloser(), nil
+}
+
+// NewWriter returns a writer compressing to w with zstd.
+func (c Codec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	var opts []zstd.EOption
+	if c.Level != 0 {
+		opts = append(opts, zstd.WithEncoderLevel(c.Level))
+	}
+	return zstd.NewWriter(w, opts...)
+}
@@ -0,0 +1,91 @@
+package gocurl_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upperCodec is a toy encoding writing bodies upper case and reading them
+// lower case.
+type upperCodec struct{}
+
+func (upperCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	data, err := io.ReadAll(r)
+	return io.NopCloser(strings.NewReader(strings.ToLower(string(data)))), err
+}
+
+func (upperCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return &upperWriter{w: w}, nil
+}
+
+type upperWriter struct {
+	w io.Writer
+}
+
+func (u *upperWriter) Write(p []byte) (int, error) { return u.w.Write(bytes.ToUpper(p)) }
+func (u *upperWriter) Close() error                { return nil }
+
+func TestEncodings(t *testing.T) {
+	gocurl.RegisterEncoding("X-Upper", upperCodec{})
+	assert.Equal(t, []string{"deflate", "gzip", "x-upper"}, gocurl.Encodings())
+	assert.Equal(t, "deflate, gzip, x-upper", gocurl.AcceptEncoding())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/echo" {
+			data, _ := io.ReadAll(r.Body)
+			io.WriteString(w, r.Header.Get("Content-Encoding")+"|"+string(data))
+			return
+		}
+		encoding := r.URL.Query().Get("encoding")
+		w.Header().Set("X-Accept-Encoding", r.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Encoding", encoding)
+		var body io.WriteCloser
+		switch encoding {
+		case "gzip":
+			body = gzip.NewWriter(w)
+		case "deflate":
+			body = zlib.NewWriter(w)
+		default:
+			body, _ = upperCodec{}.NewWriter(w)
+		}
+		io.WriteString(body, "hello")
+		body.Close()
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	for _, encoding := range []string{"gzip", "deflate", "x-upper"} {
+		resp, body, err := gocurl.Curl(ctx, "-s", "--compressed", server.URL+"/?encoding="+encoding)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", body, encoding)
+		assert.Empty(t, resp.Header.Get("Content-Encoding"))
+		assert.Equal(t, "deflate, gzip, x-upper", resp.Header.Get("X-Accept-Encoding"))
+	}
+
+	_, body, err := gocurl.Curl(ctx, "-s", server.URL+"/?encoding=x-upper")
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", body, "without --compressed bodies are left encoded")
+
+	client := gocurl.NewClient().AddResponseFilter(gocurl.DecompressFilter)
+	_, body, err = client.Curl(ctx, "-s", server.URL+"/?encoding=x-upper")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", body)
+
+	opts := options.NewRequestOptionsBuilder().SetURL(server.URL + "/echo").SetMethod("POST").JSON(strings.Repeat("a", 20)).Build()
+	client = gocurl.NewClient().SetBodyCompression(&gocurl.BodyCompression{Threshold: 10, Hosts: []string{"127.0.0.1"}, Encoding: "x-upper"})
+	_, body, err = client.Process(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, `x-upper|"`+strings.Repeat("A", 20)+`"`, body)
+}
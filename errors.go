@@ -0,0 +1,187 @@
+package gocurl
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/url"
+	"syscall"
+
+	"github.com/maniartech/gocurl/redact"
+)
+
+// ErrorKind classifies why a request failed, following curl's exit codes.
+type ErrorKind int
+
+const (
+	KindUnknown ErrorKind = iota
+	KindUnsupportedProtocol
+	KindMalformedURL
+	KindResolveProxy
+	KindResolveHost
+	KindConnect
+	KindHTTP
+	KindWrite
+	KindRead
+	KindTimeout
+	KindTLS
+	KindTooManyRedirects
+	KindEmptyReply
+	KindReceive
+	KindCertificate
+)
+
+var errorKinds = map[ErrorKind]struct {
+	name     string
+	exitCode int
+}{
+	KindUnknown:             {"unknown", 1},
+	KindUnsupportedProtocol: {"unsupported protocol", 1},
+	KindMalformedURL:        {"malformed URL", 3},
+	KindResolveProxy:        {"could not resolve proxy", 5},
+	KindResolveHost:         {"could not resolve host", 6},
+	KindConnect:             {"failed to connect", 7},
+	KindHTTP:                {"HTTP error", 22},
+	KindWrite:               {"write error", 23},
+	KindRead:                {"read error", 26},
+	KindTimeout:             {"timeout", 28},
+	KindTLS:                 {"TLS connect error", 35},
+	KindTooManyRedirects:    {"too many redirects", 47},
+	KindEmptyReply:          {"empty reply from server", 52},
+	KindReceive:             {"failure receiving data", 56},
+	KindCertificate:         {"peer certificate cannot be authenticated", 60},
+}
+
+func (k ErrorKind) String() string {
+	if kind, ok := errorKinds[k]; ok {
+		return kind.name
+	}
+	return fmt.Sprintf("ErrorKind(%d)", int(k))
+}
+
+// ExitCode returns the exit code curl uses for failures of this kind.
+func (k ErrorKind) ExitCode() int {
+	if kind, ok := errorKinds[k]; ok {
+		return kind.exitCode
+	}
+	return 1
+}
+
+// Error is returned by Process and the Curl helpers when a request fails. It
+// wraps the underlying error, so errors.Is and errors.As keep working, and
+// tells what kind of failure it was. Its message is masked by
+// redact.Default, while URL is the unmasked request URL.
+type Error struct {
+	Kind ErrorKind
+	URL  string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return redact.Default.String(e.Err.Error())
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// HTTPError reports a response with an error status when failing on HTTP
+// errors is enabled, as with curl --fail or an error model. It is wrapped in
+// an *Error of kind KindHTTP.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	// Model is the body decoded into a new value of the error model type,
+	// or nil when there is no error model or the body did not decode
+	Model interface{}
+}
+
+func (e *HTTPError) Error() string {
+	if err, ok := e.Model.(error); ok {
+		return fmt.Sprintf("The requested URL returned error: %d: %v", e.StatusCode, err)
+	}
+	return fmt.Sprintf("The requested URL returned error: %d", e.StatusCode)
+}
+
+// Unwrap returns the Model when it implements error, so errors.As finds
+// it.
+func (e *HTTPError) Unwrap() error {
+	err, _ := e.Model.(error)
+	return err
+}
+
+// ExitCode returns the curl exit code matching err: 0 for nil, the code of
+// its kind for an *Error and of the classified error otherwise.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return errorKind(err).ExitCode()
+}
+
+// errorKind returns the kind of err, classifying errors that are not an
+// *Error by their type.
+func errorKind(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return classifyError(err)
+}
+
+// wrapError wraps err in an *Error for rawURL unless it already is one.
+func wrapError(err error, rawURL string) error {
+	var e *Error
+	if err == nil || errors.As(err, &e) {
+		return err
+	}
+	return &Error{Kind: classifyError(err), URL: rawURL, Err: err}
+}
+
+// classifyError determines the kind of a transport or I/O error from the
+// types in its chain.
+func classifyError(err error) ErrorKind {
+	var (
+		netErr     net.Error
+		dnsErr     *net.DNSError
+		opErr      *net.OpError
+		pathErr    *fs.PathError
+		headerErr  tls.RecordHeaderError
+		alertErr   tls.AlertError
+		verifyErr  *tls.CertificateVerificationError
+		unknownCA  x509.UnknownAuthorityError
+		hostErr    x509.HostnameError
+		invalidErr x509.CertificateInvalidError
+		urlErr     *url.Error
+	)
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return KindTimeout
+	case errors.As(err, &dnsErr):
+		if errors.As(err, &opErr) && opErr.Op == "proxyconnect" {
+			return KindResolveProxy
+		}
+		return KindResolveHost
+	case errors.As(err, &verifyErr), errors.As(err, &unknownCA), errors.As(err, &hostErr), errors.As(err, &invalidErr):
+		return KindCertificate
+	case errors.As(err, &headerErr), errors.As(err, &alertErr):
+		return KindTLS
+	case errors.Is(err, ErrOffline), errors.Is(err, syscall.ECONNREFUSED), errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "proxyconnect"):
+		return KindConnect
+	case errors.Is(err, syscall.ECONNRESET):
+		return KindReceive
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return KindEmptyReply
+	case errors.As(err, &pathErr):
+		return KindRead
+	case errors.As(err, &urlErr) && urlErr.Op == "parse":
+		return KindMalformedURL
+	}
+	return KindUnknown
+}
@@ -0,0 +1,174 @@
+package gocurl_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.Error(w, "not here", http.StatusNotFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/hangup":
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}
+	}))
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedURL := "http://" + listener.Addr().String()
+	listener.Close()
+
+	tests := []struct {
+		name string
+		args []string
+		kind gocurl.ErrorKind
+		code int
+	}{
+		{"Could not resolve host", []string{"http://gocurl.invalid"}, gocurl.KindResolveHost, 6},
+		{"Connection refused", []string{closedURL}, gocurl.KindConnect, 7},
+		{"HTTP error with --fail", []string{"-f", server.URL + "/missing"}, gocurl.KindHTTP, 22},
+		{"Timeout", []string{"-m", "0.05", server.URL + "/slow"}, gocurl.KindTimeout, 28},
+		{"Too many redirects", []string{"-L", "--max-redirs", "3", server.URL + "/loop"}, gocurl.KindTooManyRedirects, 47},
+		{"Empty reply", []string{server.URL + "/hangup"}, gocurl.KindEmptyReply, 52},
+		{"Untrusted certificate", []string{tlsServer.URL}, gocurl.KindCertificate, 60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := gocurl.CurlString(context.Background(), append([]string{"curl"}, tt.args...)...)
+			require.Error(t, err)
+
+			var e *gocurl.Error
+			require.True(t, errors.As(err, &e), "%T: %v", err, err)
+			assert.Equal(t, tt.kind, e.Kind, err.Error())
+			assert.Equal(t, tt.code, gocurl.ExitCode(err))
+		})
+	}
+
+	t.Run("Unsupported protocol", func(t *testing.T) {
+		_, _, err := gocurl.Process(context.Background(), options.NewRequestOptions("ftp://example.com/file"))
+		var e *gocurl.Error
+		require.True(t, errors.As(err, &e))
+		assert.Equal(t, gocurl.KindUnsupportedProtocol, e.Kind)
+		assert.Equal(t, 1, gocurl.ExitCode(err))
+	})
+
+	t.Run("HTTPError", func(t *testing.T) {
+		_, _, err := gocurl.CurlString(context.Background(), "curl", "--fail", server.URL+"/missing")
+		var httpErr *gocurl.HTTPError
+		require.True(t, errors.As(err, &httpErr))
+		assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+		assert.Equal(t, "The requested URL returned error: 404", err.Error())
+	})
+
+	t.Run("HTTP errors succeed without --fail", func(t *testing.T) {
+		resp, _, err := gocurl.CurlString(context.Background(), "curl", server.URL+"/missing")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, 0, gocurl.ExitCode(err))
+	})
+
+	t.Run("Messages are redacted", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := listener.Addr().String()
+		listener.Close()
+
+		rawURL := "http://" + addr + "/files?id=1&token=t0ps3cret"
+		_, _, err = gocurl.CurlString(context.Background(), "curl", rawURL)
+		var e *gocurl.Error
+		require.True(t, errors.As(err, &e))
+		assert.NotContains(t, err.Error(), "t0ps3cret")
+		assert.Contains(t, err.Error(), "id=1&token=****")
+		assert.Equal(t, rawURL, e.URL)
+	})
+
+	t.Run("--fail-with-body returns the body", func(t *testing.T) {
+		_, body, err := gocurl.CurlString(context.Background(), "curl", "--fail-with-body", server.URL+"/missing")
+		assert.Equal(t, 22, gocurl.ExitCode(err))
+		assert.Equal(t, "not here\n", body)
+	})
+}
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func TestErrorModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/invalid":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"code":"invalid_name","message":"name is required"}`))
+		case "/html":
+			http.Error(w, "<h1>Bad Gateway</h1>", http.StatusBadGateway)
+		default:
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer server.Close()
+
+	process := func(path string) (*http.Response, string, error) {
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(server.URL + path).
+			SetErrorModel(&apiError{}).
+			Build()
+		opts.Silent = true
+		return gocurl.Process(context.Background(), opts)
+	}
+
+	t.Run("Decodes error responses", func(t *testing.T) {
+		resp, body, err := process("/invalid")
+		require.Error(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+		assert.Contains(t, body, "invalid_name")
+
+		var apiErr *apiError
+		require.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, "name is required", apiErr.Message)
+		var httpErr *gocurl.HTTPError
+		require.True(t, errors.As(err, &httpErr))
+		assert.Equal(t, http.StatusUnprocessableEntity, httpErr.StatusCode)
+		assert.Equal(t, "The requested URL returned error: 422: invalid_name: name is required", err.Error())
+		assert.Equal(t, 22, gocurl.ExitCode(err))
+	})
+
+	t.Run("Undecodable bodies", func(t *testing.T) {
+		_, _, err := process("/html")
+		var httpErr *gocurl.HTTPError
+		require.True(t, errors.As(err, &httpErr))
+		assert.Nil(t, httpErr.Model)
+		var apiErr *apiError
+		assert.False(t, errors.As(err, &apiErr))
+	})
+
+	t.Run("Successful responses", func(t *testing.T) {
+		_, body, err := process("/")
+		require.NoError(t, err)
+		assert.Equal(t, `{"ok":true}`, body)
+	})
+}
@@ -0,0 +1,59 @@
+package gocurl
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// setIfNoneMatch makes req conditional on the ETag of opts.ETagCompare, or
+// else the one opts.ETagStore holds for its URL, unless it sets
+// If-None-Match itself. Like curl, a missing compare file sends no header.
+func setIfNoneMatch(req *http.Request, opts *options.RequestOptions) error {
+	if req.Header.Get("If-None-Match") != "" {
+		return nil
+	}
+	var etag string
+	if opts.ETagCompare != "" {
+		data, err := os.ReadFile(opts.ETagCompare)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read ETag: %v", err)
+		}
+		etag = strings.TrimSpace(string(data))
+	} else if opts.ETagStore != nil {
+		var err error
+		if etag, err = opts.ETagStore.ETag(req.URL.String()); err != nil {
+			return fmt.Errorf("failed to read ETag: %v", err)
+		}
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	return nil
+}
+
+// saveETag saves the ETag of a successful response to req to
+// opts.ETagSave and opts.ETagStore. A 304 response keeps the saved ETag.
+func saveETag(req *http.Request, resp *http.Response, opts *options.RequestOptions) error {
+	if opts.ETagSave == "" && opts.ETagStore == nil {
+		return nil
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" || resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil
+	}
+	if opts.ETagSave != "" {
+		if err := os.WriteFile(opts.ETagSave, []byte(etag+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to save ETag: %v", err)
+		}
+	}
+	if opts.ETagStore != nil {
+		if err := opts.ETagStore.SetETag(req.URL.String(), etag); err != nil {
+			return fmt.Errorf("failed to save ETag: %v", err)
+		}
+	}
+	return nil
+}
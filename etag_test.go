@@ -0,0 +1,95 @@
+package gocurl_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newETagServer(etag string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("content"))
+	}))
+}
+
+func TestETagSaveCompare(t *testing.T) {
+	server := newETagServer(`"v1"`)
+	defer server.Close()
+	file := filepath.Join(t.TempDir(), "etag")
+	ctx := context.Background()
+
+	// A missing compare file sends no If-None-Match
+	resp, body, err := gocurl.Curl(ctx, "--etag-compare", file, "--etag-save", file, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "content", body)
+	saved, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "\"v1\"\n", string(saved))
+
+	resp, body, err = gocurl.Curl(ctx, "--etag-compare", file, "--etag-save", file, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	assert.Empty(t, body)
+	saved, err = os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "\"v1\"\n", string(saved))
+}
+
+func TestETagSaveSpooled(t *testing.T) {
+	server := newETagServer(`"v1"`)
+	defer server.Close()
+	file := filepath.Join(t.TempDir(), "etag")
+
+	opts := &options.RequestOptions{
+		URL:            server.URL,
+		Silent:         true,
+		ETagSave:       file,
+		SpoolThreshold: 1,
+		SpoolDir:       t.TempDir(),
+	}
+	resp, _, err := gocurl.Process(context.Background(), opts)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	saved, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "\"v1\"\n", string(saved))
+}
+
+func TestETagStore(t *testing.T) {
+	server := newETagServer(`"v2"`)
+	defer server.Close()
+	store := options.NewMemoryETagStore()
+	ctx := context.Background()
+
+	opts := options.NewRequestOptionsBuilder().SetURL(server.URL).SetETagStore(store).Build()
+	resp, _, err := gocurl.Process(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	etag, err := store.ETag(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, `"v2"`, etag)
+
+	resp, _, err = gocurl.Process(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	// An explicit If-None-Match wins over the store
+	opts.Headers = http.Header{"If-None-Match": {`"other"`}}
+	resp, _, err = gocurl.Process(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,298 @@
+package gocurl
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var extractLimits = struct {
+	sync.RWMutex
+	maxBytes int64
+	maxFiles int
+}{maxBytes: 1 << 30, maxFiles: 10000}
+
+// SetExtractLimits bounds what CurlDownloadExtract writes: maxBytes of
+// extracted content and maxFiles entries per archive. Archives going over
+// either limit fail the download. The defaults are 1 GiB and 10000 entries;
+// zero or less keeps the current value.
+func SetExtractLimits(maxBytes int64, maxFiles int) {
+	extractLimits.Lock()
+	defer extractLimits.Unlock()
+	if maxBytes > 0 {
+		extractLimits.maxBytes = maxBytes
+	}
+	if maxFiles > 0 {
+		extractLimits.maxFiles = maxFiles
+	}
+}
+
+// errExtractLimit is returned when an archive exceeds the extract limits.
+var errExtractLimit = errors.New("archive exceeds the extract limits")
+
+// CurlDownloadExtract executes the curl command and extracts the tar,
+// tar.gz or zip archive it downloads into destDir, like piping curl into
+// tar. The format is detected from the content. Tar archives are extracted
+// as they stream in without being stored; zip archives, whose directory is
+// at their end, are held in memory within the size limit.
+//
+// Entries are only written within destDir: absolute paths, paths escaping
+// it and links pointing outside of it fail the extraction, as do archives
+// over the limits set by SetExtractLimits. Entries extracted before a
+// failure are left in place.
+func CurlDownloadExtract(ctx context.Context, destDir string, command ...string) (*http.Response, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create destination directory: %v", err)
+	}
+
+	extractLimits.RLock()
+	x := &extractor{dir: destDir, bytesLeft: extractLimits.maxBytes, filesLeft: extractLimits.maxFiles, links: map[string]bool{}}
+	extractLimits.RUnlock()
+
+	return executeStream(ctx, opts, func(resp *http.Response) error {
+		if err := x.extract(resp.Body); err != nil {
+			return fmt.Errorf("failed to extract archive: %v", err)
+		}
+		return nil
+	})
+}
+
+// extractor writes archive entries into dir within its limits.
+type extractor struct {
+	dir       string
+	bytesLeft int64
+	filesLeft int
+	// links are the symbolic links created so far, which entries must not
+	// be written through
+	links map[string]bool
+}
+
+// extract detects the format of the archive r reads and extracts it.
+func (x *extractor) extract(r io.Reader) error {
+	br := bufio.NewReaderSize(r, 512)
+	magic, _ := br.Peek(262)
+	switch {
+	case bytes.HasPrefix(magic, []byte("\x1f\x8b")):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		return x.tar(gz)
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")), bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		return x.zip(br)
+	case len(magic) == 262 && bytes.HasPrefix(magic[257:], []byte("ustar")):
+		return x.tar(br)
+	}
+	return fmt.Errorf("unsupported archive format")
+}
+
+func (x *extractor) tar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = x.mkdir(hdr.Name)
+		case tar.TypeReg:
+			err = x.file(hdr.Name, hdr.FileInfo().Mode(), tr)
+		case tar.TypeSymlink:
+			err = x.symlink(hdr.Name, hdr.Linkname)
+		case tar.TypeLink:
+			err = x.hardlink(hdr.Name, hdr.Linkname)
+		case tar.TypeXGlobalHeader:
+			continue
+		default:
+			err = fmt.Errorf("%s: unsupported entry type %q", hdr.Name, hdr.Typeflag)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (x *extractor) zip(r io.Reader) error {
+	data, err := io.ReadAll(io.LimitReader(r, x.bytesLeft+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > x.bytesLeft {
+		return errExtractLimit
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			err = x.mkdir(f.Name)
+		case mode&os.ModeSymlink != 0:
+			var target []byte
+			if target, err = readZipFile(f, 4096); err == nil {
+				err = x.symlink(f.Name, string(target))
+			}
+		case mode.IsRegular():
+			var rc io.ReadCloser
+			if rc, err = f.Open(); err == nil {
+				err = x.file(f.Name, mode, rc)
+				rc.Close()
+			}
+		default:
+			err = fmt.Errorf("%s: unsupported entry type", f.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readZipFile(f *zip.File, max int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(io.LimitReader(rc, max))
+}
+
+// path returns where the entry name is extracted, failing for names that
+// are not local to the destination or go through an extracted link.
+func (x *extractor) path(name string) (string, error) {
+	x.filesLeft--
+	if x.filesLeft < 0 {
+		return "", errExtractLimit
+	}
+	clean := filepath.Clean(filepath.FromSlash(strings.TrimSuffix(name, "/")))
+	if !filepath.IsLocal(clean) {
+		return "", fmt.Errorf("%s: path outside of the destination", name)
+	}
+	if x.throughLink(clean) {
+		return "", fmt.Errorf("%s: path through a link", name)
+	}
+	return filepath.Join(x.dir, clean), nil
+}
+
+// throughLink reports whether a parent directory of the local path clean is
+// an extracted link.
+func (x *extractor) throughLink(clean string) bool {
+	for dir := filepath.Dir(clean); dir != "."; dir = filepath.Dir(dir) {
+		if x.links[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+func (x *extractor) mkdir(name string) error {
+	path, err := x.path(name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(path, 0755)
+}
+
+func (x *extractor) file(name string, mode os.FileMode, r io.Reader) error {
+	path, err := x.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Replace rather than write through existing files or links
+	os.Remove(path)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(file, io.LimitReader(r, x.bytesLeft+1))
+	x.bytesLeft -= n
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && x.bytesLeft < 0 {
+		err = errExtractLimit
+	}
+	return err
+}
+
+// linkTarget checks that the target of a link at name stays within the
+// destination. Targets may not go through extracted links either, as their
+// ".." elements would then resolve from the link target.
+func (x *extractor) linkTarget(name, target string) error {
+	target = filepath.FromSlash(target)
+	if filepath.IsAbs(target) || !filepath.IsLocal(filepath.Join(filepath.Dir(filepath.FromSlash(name)), target)) {
+		return fmt.Errorf("%s: link to %s outside of the destination", name, target)
+	}
+	prefix := filepath.Dir(filepath.Clean(filepath.FromSlash(name)))
+	elems := strings.Split(target, string(filepath.Separator))
+	for i, elem := range elems {
+		prefix = filepath.Join(prefix, elem)
+		if i < len(elems)-1 && x.links[prefix] {
+			return fmt.Errorf("%s: link to %s through a link", name, target)
+		}
+	}
+	return nil
+}
+
+func (x *extractor) symlink(name, target string) error {
+	if err := x.linkTarget(name, target); err != nil {
+		return err
+	}
+	path, err := x.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	os.Remove(path)
+	if err := os.Symlink(filepath.FromSlash(target), path); err != nil {
+		return err
+	}
+	x.links[filepath.Clean(filepath.FromSlash(name))] = true
+	return nil
+}
+
+// hardlink links name to target, another entry of the archive.
+func (x *extractor) hardlink(name, target string) error {
+	clean := filepath.Clean(filepath.FromSlash(target))
+	if !filepath.IsLocal(clean) || x.throughLink(clean) {
+		return fmt.Errorf("%s: link to %s outside of the destination", name, target)
+	}
+	path, err := x.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	os.Remove(path)
+	return os.Link(filepath.Join(x.dir, clean), path)
+}
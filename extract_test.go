@@ -0,0 +1,137 @@
+package gocurl_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tarEntry struct {
+	name, body, link string
+	typeflag         byte
+}
+
+func tarGz(t *testing.T, entries ...tarEntry) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.link, Mode: 0755, Size: int64(len(e.body))}
+		if e.typeflag == 0 {
+			hdr.Typeflag, hdr.Mode = tar.TypeReg, 0644
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		tw.Write([]byte(e.body))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestCurlDownloadExtract(t *testing.T) {
+	archives := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		archive, ok := archives[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(archive)
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	archives["/app.tar.gz"] = tarGz(t,
+		tarEntry{name: "app/", typeflag: tar.TypeDir},
+		tarEntry{name: "app/bin/tool", body: "#!/bin/sh\n"},
+		tarEntry{name: "app/lib/libx.so.1", body: "lib"},
+		tarEntry{name: "app/lib/libx.so", link: "libx.so.1", typeflag: tar.TypeSymlink},
+	)
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	w, _ := zw.Create("docs/readme.txt")
+	w.Write([]byte("hello"))
+	require.NoError(t, zw.Close())
+	archives["/docs.zip"] = zipped.Bytes()
+
+	t.Run("tar.gz", func(t *testing.T) {
+		dir := t.TempDir()
+		resp, err := gocurl.CurlDownloadExtract(ctx, dir, server.URL+"/app.tar.gz")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		data, err := os.ReadFile(filepath.Join(dir, "app/bin/tool"))
+		require.NoError(t, err)
+		assert.Equal(t, "#!/bin/sh\n", string(data))
+		data, err = os.ReadFile(filepath.Join(dir, "app/lib/libx.so"))
+		require.NoError(t, err)
+		assert.Equal(t, "lib", string(data))
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := gocurl.CurlDownloadExtract(ctx, dir, server.URL+"/docs.zip")
+		require.NoError(t, err)
+		data, err := os.ReadFile(filepath.Join(dir, "docs/readme.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(data))
+	})
+
+	t.Run("Unsafe entries", func(t *testing.T) {
+		for name, entries := range map[string][]tarEntry{
+			"traversal": {{name: "../evil", body: "x"}},
+			"absolute":  {{name: "/tmp/evil", body: "x"}},
+			"symlink":   {{name: "out", link: "../../etc", typeflag: tar.TypeSymlink}},
+			"hardlink":  {{name: "passwd", link: "../../etc/passwd", typeflag: tar.TypeLink}},
+			"through a link": {
+				{name: "d", link: ".", typeflag: tar.TypeSymlink},
+				{name: "up", link: "d/..", typeflag: tar.TypeSymlink},
+			},
+			"write through a link": {
+				{name: "d", link: "sub", typeflag: tar.TypeSymlink},
+				{name: "d/file", body: "x"},
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				archives["/unsafe.tar.gz"] = tarGz(t, entries...)
+				parent := t.TempDir()
+				_, err := gocurl.CurlDownloadExtract(ctx, filepath.Join(parent, "dest"), server.URL+"/unsafe.tar.gz")
+				require.Error(t, err)
+				assert.NoFileExists(t, filepath.Join(parent, "evil"))
+			})
+		}
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		gocurl.SetExtractLimits(4, 0)
+		defer gocurl.SetExtractLimits(1<<30, 10000)
+		_, err := gocurl.CurlDownloadExtract(ctx, t.TempDir(), server.URL+"/app.tar.gz")
+		assert.ErrorContains(t, err, "exceeds the extract limits")
+
+		gocurl.SetExtractLimits(1<<30, 2)
+		_, err = gocurl.CurlDownloadExtract(ctx, t.TempDir(), server.URL+"/app.tar.gz")
+		assert.ErrorContains(t, err, "exceeds the extract limits")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := gocurl.CurlDownloadExtract(ctx, t.TempDir(), server.URL+"/missing.zip")
+		var httpErr *gocurl.HTTPError
+		assert.True(t, errors.As(err, &httpErr))
+
+		archives["/plain"] = []byte("not an archive")
+		_, err = gocurl.CurlDownloadExtract(ctx, t.TempDir(), server.URL+"/plain")
+		assert.ErrorContains(t, err, "unsupported archive format")
+	})
+}
@@ -0,0 +1,38 @@
+package gocurl
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// fastClient pools connections for CurlFast.
+var fastClient = &Client{}
+
+// CurlFast is a low overhead variant of Curl for hot paths. When command is a
+// plain http(s) URL it skips tokenization and variable expansion and sends a
+// GET request directly; anything else is parsed like Curl. Requests reuse
+// pooled connections and never print the response body.
+func CurlFast(ctx context.Context, command string) (*http.Response, string, error) {
+	if isPlainURL(command) {
+		opts := &options.RequestOptions{Method: "GET", URL: command, Silent: true}
+		return fastClient.Process(ctx, opts)
+	}
+
+	opts, err := parseCommand(command)
+	if err != nil {
+		return nil, "", err
+	}
+	opts.Silent = true
+	return fastClient.Process(ctx, opts)
+}
+
+// isPlainURL reports whether s is an http(s) URL needing no shell parsing.
+func isPlainURL(s string) bool {
+	if !strings.HasPrefix(s, "https://") && !strings.HasPrefix(s, "http://") {
+		return false
+	}
+	return !strings.ContainsAny(s, " \t\r\n$'\"\\`")
+}
@@ -0,0 +1,90 @@
+package gocurl_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurlFast(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + r.URL.RequestURI() + " " + r.Header.Get("X-Test")))
+	}))
+	defer server.Close()
+
+	t.Run("Plain URL", func(t *testing.T) {
+		resp, body, err := gocurl.CurlFast(context.Background(), server.URL+"/items?b=2&a=1")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "GET /items?b=2&a=1 ", body)
+	})
+
+	t.Run("Falls back to the full parser", func(t *testing.T) {
+		t.Setenv("GOCURL_FAST_TEST", "yes")
+		_, body, err := gocurl.CurlFast(context.Background(), "curl -X PUT -H 'X-Test: $GOCURL_FAST_TEST' "+server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "PUT / yes", body)
+	})
+}
+
+func newBenchmarkServer(b *testing.B) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	b.Cleanup(server.Close)
+	return server
+}
+
+func BenchmarkCurl(b *testing.B) {
+	server := newBenchmarkServer(b)
+	command := "curl -s -H 'Accept: application/json' " + server.URL + "/items"
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := gocurl.Curl(context.Background(), command); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCurlFast(b *testing.B) {
+	server := newBenchmarkServer(b)
+	url := server.URL + "/items"
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := gocurl.CurlFast(context.Background(), url); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkClientProcess(b *testing.B) {
+	server := newBenchmarkServer(b)
+	client := gocurl.NewClient()
+	opts := options.NewRequestOptionsBuilder().SetURL(server.URL + "/items").SetSilent(true).Build()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := client.Process(context.Background(), opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkArgsToOptions(b *testing.B) {
+	args := []string{"curl", "-X", "POST", "-H", "Accept: application/json", "-d", `{"a":1}`, "https://api.example.com/items?page=2"}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := gocurl.ArgsToOptions(args); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
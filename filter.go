@@ -0,0 +1,172 @@
+package gocurl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// ResponseFilter transforms the body of a response before a Client returns
+// it. Filters may update the headers of resp to describe the new body, as
+// DecompressFilter removes Content-Encoding.
+type ResponseFilter func(resp *http.Response, body []byte) ([]byte, error)
+
+// AddResponseFilter appends filters to the chain the client runs response
+// bodies through, in order. A typical chain is DecompressFilter,
+// CharsetFilter, PrettyJSONFilter followed by custom filters.
+func (c *Client) AddResponseFilter(filters ...ResponseFilter) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.filters = append(c.filters, filters...)
+	return c
+}
+
+// CurlString executes the curl command through the client and returns the
+// filtered response body without printing it.
+func (c *Client) CurlString(ctx context.Context, command ...string) (*http.Response, string, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, "", err
+	}
+	opts.Silent = true
+	return c.Process(ctx, opts)
+}
+
+// CurlBytes executes the curl command through the client and returns the
+// filtered response body without printing it.
+func (c *Client) CurlBytes(ctx context.Context, command ...string) (*http.Response, []byte, error) {
+	resp, body, err := c.CurlString(ctx, command...)
+	return resp, []byte(body), err
+}
+
+// filter runs body through the client's filters and resets resp.Body to the
+// result.
+func (c *Client) filter(resp *http.Response, body string) (string, error) {
+	c.mu.Lock()
+	filters := c.filters
+	c.mu.Unlock()
+	if len(filters) == 0 || resp == nil {
+		return body, nil
+	}
+
+	filtered := []byte(body)
+	for _, f := range filters {
+		var err error
+		if filtered, err = f(resp, filtered); err != nil {
+			return body, fmt.Errorf("response filter failed: %v", err)
+		}
+	}
+	if resp.Body != nil {
+		resp.Body.Close()
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(filtered))
+	resp.ContentLength = int64(len(filtered))
+	if resp.Header.Get("Content-Length") != "" {
+		resp.Header.Set("Content-Length", strconv.Itoa(len(filtered)))
+	}
+	return string(filtered), nil
+}
+
+// DecompressFilter decodes bodies of the encodings registered with
+// RegisterEncoding, such as gzip and deflate, which net/http leaves encoded
+// when Accept-Encoding is set explicitly, and removes their Content-Encoding
+// header.
+func DecompressFilter(resp *http.Response, body []byte) ([]byte, error) {
+	codec, ok := codecFor(resp.Header.Get("Content-Encoding"))
+	if !ok {
+		return body, nil
+	}
+	r, err := codec.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress body: %v", err)
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress body: %v", err)
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Uncompressed = true
+	return decoded, nil
+}
+
+// CharsetFilter converts text bodies to UTF-8 from the charset of their
+// Content-Type, and updates the header accordingly.
+func CharsetFilter(resp *http.Response, body []byte) ([]byte, error) {
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || params["charset"] == "" || strings.EqualFold(params["charset"], "utf-8") {
+		return body, nil
+	}
+	r, err := charset.NewReaderLabel(params["charset"], bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode charset: %v", err)
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode charset: %v", err)
+	}
+	params["charset"] = "utf-8"
+	resp.Header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+	return decoded, nil
+}
+
+// PrettyJSONFilter indents JSON bodies by two spaces. Bodies that are not
+// valid JSON are left unchanged.
+func PrettyJSONFilter(resp *http.Response, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, body, "", "  "); err != nil {
+		return body, nil
+	}
+	return buf.Bytes(), nil
+}
+
+// jsonLinesTypes are the media types of JSON lines bodies.
+var jsonLinesTypes = map[string]bool{
+	"application/x-ndjson":     true,
+	"application/ndjson":       true,
+	"application/jsonl":        true,
+	"application/x-jsonlines":  true,
+	"application/json-lines":   true,
+	"application/jsonlines":    true,
+	"application/stream+json":  true,
+	"application/x-json-lines": true,
+}
+
+// JSONLinesFilter converts JSON lines (NDJSON) bodies into a JSON array and
+// sets their Content-Type to application/json. Blank lines are skipped and
+// other bodies are left unchanged.
+func JSONLinesFilter(resp *http.Response, body []byte) ([]byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !jsonLinesTypes[mediaType] {
+		return body, nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	n := 0
+	for i, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			return nil, fmt.Errorf("invalid JSON on line %d", i+1)
+		}
+		if n > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(line)
+		n++
+	}
+	buf.WriteByte(']')
+	resp.Header.Set("Content-Type", "application/json")
+	return buf.Bytes(), nil
+}
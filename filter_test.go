@@ -0,0 +1,86 @@
+package gocurl_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseFilters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gzip":
+			w.Header().Set("Content-Type", "application/json; charset=iso-8859-1")
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write([]byte("{\"name\":\"Caf\xe9\"}"))
+			gz.Close()
+		case "/lines":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			io.WriteString(w, "{\"id\":1}\n\n{\"id\":2}\n")
+		case "/broken":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			io.WriteString(w, "{\"id\":1}\nnot json\n")
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	t.Run("Pipeline", func(t *testing.T) {
+		var seen string
+		client := gocurl.NewClient().AddResponseFilter(
+			gocurl.DecompressFilter,
+			gocurl.CharsetFilter,
+			gocurl.PrettyJSONFilter,
+			func(resp *http.Response, body []byte) ([]byte, error) {
+				seen = resp.Header.Get("Content-Type")
+				return bytes.ToUpper(body), nil
+			},
+		)
+		resp, body, err := client.CurlString(ctx, "--compressed", server.URL+"/gzip")
+		require.NoError(t, err)
+		assert.Equal(t, "{\n  \"NAME\": \"CAFÉ\"\n}", body)
+		assert.Equal(t, "application/json; charset=utf-8", seen)
+		assert.Empty(t, resp.Header.Get("Content-Encoding"))
+
+		read, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, body, string(read))
+	})
+
+	t.Run("JSON lines", func(t *testing.T) {
+		client := gocurl.NewClient().AddResponseFilter(gocurl.JSONLinesFilter)
+		resp, body, err := client.CurlBytes(ctx, server.URL+"/lines")
+		require.NoError(t, err)
+		assert.Equal(t, `[{"id":1},{"id":2}]`, string(body))
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+		_, body, err = client.CurlBytes(ctx, server.URL+"/gzip")
+		require.NoError(t, err)
+		assert.NotContains(t, string(body), "[")
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		client := gocurl.NewClient().AddResponseFilter(gocurl.JSONLinesFilter)
+		_, _, err := client.CurlString(ctx, server.URL+"/broken")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid JSON on line 2")
+
+		client = gocurl.NewClient().AddResponseFilter(func(*http.Response, []byte) ([]byte, error) {
+			return nil, errors.New("boom")
+		})
+		_, body, err := client.CurlString(ctx, server.URL+"/lines")
+		require.Error(t, err)
+		assert.True(t, strings.HasPrefix(body, `{"id":1}`))
+	})
+}
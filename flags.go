@@ -0,0 +1,208 @@
+package gocurl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FlagSupport tells how far gocurl supports a curl flag.
+type FlagSupport int
+
+const (
+	// FlagFull flags behave as they do in curl.
+	FlagFull FlagSupport = iota
+	// FlagPartial flags are accepted but differ from curl, as described by
+	// their note.
+	FlagPartial
+	// FlagUnsupported flags are known curl flags that gocurl rejects.
+	FlagUnsupported
+)
+
+func (s FlagSupport) String() string {
+	switch s {
+	case FlagFull:
+		return "full"
+	case FlagPartial:
+		return "partial"
+	case FlagUnsupported:
+		return "unsupported"
+	}
+	return fmt.Sprintf("FlagSupport(%d)", int(s))
+}
+
+// CurlFlag describes a curl flag and its support by the command parser.
+type CurlFlag struct {
+	Short   string      `json:"short,omitempty"`
+	Long    string      `json:"long"`
+	Aliases []string    `json:"aliases,omitempty"`
+	Arg     string      `json:"arg,omitempty"`
+	Support FlagSupport `json:"support"`
+	Note    string      `json:"note,omitempty"`
+}
+
+// Names returns every spelling of the flag.
+func (f CurlFlag) Names() []string {
+	var names []string
+	if f.Short != "" {
+		names = append(names, f.Short)
+	}
+	return append(append(names, f.Long), f.Aliases...)
+}
+
+// String formats the flag as in curl's help, e.g. "-H, --header <header>".
+func (f CurlFlag) String() string {
+	s := strings.Join(f.Names(), ", ")
+	if f.Arg != "" {
+		s += " <" + f.Arg + ">"
+	}
+	return s
+}
+
+// curlFlags is the metadata of the parser: every flag it handles and the
+// common curl flags it rejects. Keep it in sync with
+// convertTokensToRequestOptions.
+var curlFlags = []CurlFlag{
+	{Short: "-A", Long: "--user-agent", Arg: "name", Support: FlagFull},
+	{Long: "--alt-svc", Arg: "file", Support: FlagPartial, Note: "HTTP/3 alternatives are saved but not used"},
+	{Short: "-b", Long: "--cookie", Arg: "data|filename", Support: FlagFull},
+	{Long: "--cacert", Arg: "file", Support: FlagFull},
+	{Long: "--cert", Arg: "certificate", Support: FlagPartial, Note: "PEM files only, without a password"},
+	{Long: "--compressed", Support: FlagFull},
+	{Long: "--connect-timeout", Arg: "seconds", Support: FlagFull},
+	{Long: "--create-dirs", Support: FlagFull},
+	{Short: "-c", Long: "--cookie-jar", Arg: "filename", Support: FlagPartial, Note: "accepted but cookies are not written"},
+	{Short: "-d", Long: "--data", Aliases: []string{"--data-raw", "--data-binary"}, Arg: "data", Support: FlagFull},
+	{Long: "--etag-compare", Arg: "file", Support: FlagFull},
+	{Long: "--etag-save", Arg: "file", Support: FlagFull},
+	{Short: "-e", Long: "--referer", Arg: "URL", Support: FlagFull},
+	{Short: "-f", Long: "--fail", Support: FlagFull},
+	{Long: "--fail-with-body", Support: FlagFull},
+	{Short: "-F", Long: "--form", Arg: "name=content", Support: FlagPartial, Note: "one file per request"},
+	{Short: "-H", Long: "--header", Arg: "header", Support: FlagFull},
+	{Long: "--http2", Support: FlagFull},
+	{Long: "--http2-only", Support: FlagPartial, Note: "gocurl name for --http2-prior-knowledge over TLS"},
+	{Long: "--json", Arg: "data", Support: FlagFull},
+	{Short: "-k", Long: "--insecure", Support: FlagFull},
+	{Long: "--key", Arg: "key", Support: FlagFull},
+	{Short: "-L", Long: "--location", Support: FlagFull},
+	{Short: "-m", Long: "--max-time", Arg: "seconds", Support: FlagFull},
+	{Long: "--max-redirs", Arg: "num", Support: FlagFull},
+	{Short: "-:", Long: "--next", Support: FlagPartial, Note: "executed by CurlAll and the CLI; Curl and Process take a single request"},
+	{Long: "--no-sessionid", Support: FlagFull},
+	{Short: "-o", Long: "--output", Arg: "file", Support: FlagFull},
+	{Long: "--output-dir", Arg: "dir", Support: FlagFull},
+	{Short: "-x", Long: "--proxy", Arg: "[protocol://]host[:port]", Support: FlagFull},
+	{Long: "--proxy-pac", Arg: "URL|file", Support: FlagPartial, Note: "gocurl extension choosing the proxy with a proxy auto-config file"},
+	{Long: "--raw-headers", Support: FlagPartial, Note: "gocurl extension sending -H headers in the order and casing given, over HTTP/1.1"},
+	{Short: "-X", Long: "--request", Arg: "method", Support: FlagFull},
+	{Short: "-s", Long: "--silent", Support: FlagFull},
+	{Short: "-T", Long: "--upload-file", Arg: "file", Support: FlagFull},
+	{Short: "-u", Long: "--user", Arg: "user:password", Support: FlagPartial, Note: "the password cannot be prompted for"},
+	{Short: "-v", Long: "--verbose", Support: FlagPartial, Note: "accepted but prints nothing extra"},
+	{Short: "-w", Long: "--write-out", Arg: "format", Support: FlagPartial, Note: "a subset of the variables, see FormatWriteOut"},
+
+	{Short: "-C", Long: "--continue-at", Arg: "offset", Support: FlagUnsupported},
+	{Long: "--data-urlencode", Arg: "data", Support: FlagUnsupported},
+	{Long: "--digest", Support: FlagUnsupported},
+	{Short: "-D", Long: "--dump-header", Arg: "filename", Support: FlagUnsupported},
+	{Short: "-G", Long: "--get", Support: FlagUnsupported},
+	{Short: "-I", Long: "--head", Support: FlagUnsupported, Note: "use -X HEAD"},
+	{Long: "--http1.1", Support: FlagUnsupported},
+	{Short: "-i", Long: "--include", Support: FlagUnsupported},
+	{Long: "--interface", Arg: "name", Support: FlagUnsupported},
+	{Long: "--limit-rate", Arg: "speed", Support: FlagUnsupported},
+	{Short: "-n", Long: "--netrc", Support: FlagUnsupported},
+	{Long: "--ntlm", Support: FlagUnsupported},
+	{Long: "--oauth2-bearer", Arg: "token", Support: FlagUnsupported, Note: "use -H 'Authorization: Bearer <token>'"},
+	{Long: "--proxy-user", Arg: "user:password", Support: FlagUnsupported},
+	{Short: "-r", Long: "--range", Arg: "range", Support: FlagUnsupported},
+	{Short: "-O", Long: "--remote-name", Support: FlagUnsupported},
+	{Long: "--resolve", Arg: "host:port:addr", Support: FlagUnsupported},
+	{Long: "--retry", Arg: "num", Support: FlagUnsupported, Note: "set RequestOptions.RetryConfig"},
+	{Short: "-S", Long: "--show-error", Support: FlagUnsupported},
+	{Long: "--socks5", Arg: "host[:port]", Support: FlagUnsupported},
+	{Long: "--url", Arg: "url", Support: FlagUnsupported},
+	{Short: "-K", Long: "--config", Arg: "file", Support: FlagUnsupported},
+}
+
+// CurlFlags returns the curl flags known to gocurl with their support status.
+func CurlFlags() []CurlFlag {
+	return append([]CurlFlag(nil), curlFlags...)
+}
+
+// lookupFlag returns the flag spelled name.
+func lookupFlag(name string) (CurlFlag, bool) {
+	for _, flag := range curlFlags {
+		for _, n := range flag.Names() {
+			if n == name {
+				return flag, true
+			}
+		}
+	}
+	return CurlFlag{}, false
+}
+
+// expandShortFlags splits combined short flags such as -sL into -s -L, as
+// curl does, when every letter is a supported flag taking no argument.
+func expandShortFlags(token string) ([]string, bool) {
+	if len(token) < 3 || token[0] != '-' || token[1] == '-' {
+		return nil, false
+	}
+	var flags []string
+	for _, c := range token[1:] {
+		flag, ok := lookupFlag("-" + string(c))
+		if !ok || flag.Arg != "" || flag.Support == FlagUnsupported {
+			return nil, false
+		}
+		flags = append(flags, flag.Short)
+	}
+	return flags, true
+}
+
+// unknownFlagError explains why token was rejected, suggesting the closest
+// flag gocurl supports.
+func unknownFlagError(token string) error {
+	if flag, ok := lookupFlag(token); ok && flag.Support == FlagUnsupported {
+		if flag.Note != "" {
+			return fmt.Errorf("curl flag %s is not supported by gocurl (%s); run 'gocurl supported' for the full list", token, flag.Note)
+		}
+		return fmt.Errorf("curl flag %s is not supported by gocurl; run 'gocurl supported' for the full list", token)
+	}
+
+	best, bestDistance := "", 3
+	for _, flag := range curlFlags {
+		if flag.Support == FlagUnsupported {
+			continue
+		}
+		for _, name := range flag.Names() {
+			if d := editDistance(token, name); d < bestDistance {
+				best, bestDistance = name, d
+			}
+		}
+	}
+	if best != "" {
+		return fmt.Errorf("unknown flag: %s (did you mean %s?)", token, best)
+	}
+	return fmt.Errorf("unknown flag: %s; run 'gocurl supported' for the flags gocurl understands", token)
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,78 @@
+package gocurl_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurlFlagsMatchParser(t *testing.T) {
+	cookies := filepath.Join(t.TempDir(), "cookies.txt")
+	require.NoError(t, os.WriteFile(cookies, nil, 0644))
+
+	// Values the parser accepts for flags taking an argument
+	values := map[string]string{
+		"--header":          "Accept: */*",
+		"--form":            "a=b",
+		"--user":            "user:password",
+		"--cookie":          "a=b",
+		"--max-time":        "1",
+		"--connect-timeout": "1",
+		"--max-redirs":      "1",
+		"--json":            "{}",
+	}
+
+	for _, flag := range gocurl.CurlFlags() {
+		for _, name := range flag.Names() {
+			t.Run(name, func(t *testing.T) {
+				args := []string{"curl", name}
+				if flag.Arg != "" {
+					value, ok := values[flag.Long]
+					if !ok {
+						value = "value"
+					}
+					args = append(args, value)
+				}
+				_, err := gocurl.ArgsToOptions(append(args, "https://example.com"))
+
+				switch flag.Support {
+				case gocurl.FlagUnsupported:
+					require.Error(t, err)
+					assert.Contains(t, err.Error(), "not supported by gocurl")
+				default:
+					if err != nil {
+						assert.NotContains(t, err.Error(), "flag")
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestFlagErrors(t *testing.T) {
+	_, err := gocurl.ArgsToOptions([]string{"curl", "--heaer", "Accept: */*", "https://example.com"})
+	assert.EqualError(t, err, "unknown flag: --heaer (did you mean --header?)")
+
+	_, err = gocurl.ArgsToOptions([]string{"curl", "--oauth2-bearer", "abc", "https://example.com"})
+	assert.EqualError(t, err, "curl flag --oauth2-bearer is not supported by gocurl (use -H 'Authorization: Bearer <token>'); run 'gocurl supported' for the full list")
+
+	_, err = gocurl.ArgsToOptions([]string{"curl", "example.com"})
+	assert.EqualError(t, err, "unexpected token: example.com (URLs must start with http:// or https://)")
+}
+
+func TestCombinedShortFlags(t *testing.T) {
+	opts, err := gocurl.ArgsToOptions([]string{"curl", "-sLk", "-m", "3", "https://example.com"})
+	require.NoError(t, err)
+	assert.True(t, opts.Silent)
+	assert.True(t, opts.FollowRedirects)
+	assert.True(t, opts.Insecure)
+	assert.Equal(t, 3*time.Second, opts.Timeout)
+
+	_, err = gocurl.ArgsToOptions([]string{"curl", "-sI", "https://example.com"})
+	assert.Error(t, err)
+}
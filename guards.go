@@ -0,0 +1,214 @@
+package gocurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RetryBudget caps the share of a Client's requests that may be retries, so
+// that a struggling API is not buried under a retry storm: when the budget
+// is spent, failed attempts are returned as they are instead of retried.
+//
+// Requests and retries are counted over a sliding Window. A retry is allowed
+// while the retries of the window stay below Ratio times its requests, plus
+// MinRetries so that clients sending few requests can still retry.
+type RetryBudget struct {
+	Ratio      float64       // Retries allowed per request, e.g. 0.1 for 10%
+	MinRetries int           // Retries allowed per window regardless of Ratio
+	Window     time.Duration // Period requests and retries are counted over, 10s if zero
+
+	mu      sync.Mutex
+	buckets [10]budgetBucket
+	now     func() time.Time
+}
+
+type budgetBucket struct {
+	start             time.Time
+	requests, retries int
+}
+
+// defaultBudgetWindow is the Window of RetryBudgets that set none.
+const defaultBudgetWindow = 10 * time.Second
+
+// NewRetryBudget creates a RetryBudget allowing ratio retries per request
+// over a 10 second window, with at least 10 retries per window.
+func NewRetryBudget(ratio float64) *RetryBudget {
+	return &RetryBudget{
+		Ratio:      ratio,
+		MinRetries: 10,
+		Window:     defaultBudgetWindow,
+	}
+}
+
+// SetRetryBudget limits the retries of the client's requests to budget.
+func (c *Client) SetRetryBudget(budget *RetryBudget) *Client {
+	c.retryBudget = budget
+	return c
+}
+
+// Request counts a request against the budget.
+func (b *RetryBudget) Request() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bucket(b.clock()).requests++
+}
+
+// AllowRetry reports whether the budget allows another retry, counting it
+// if so.
+func (b *RetryBudget) AllowRetry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock()
+	requests, retries := b.totals(now)
+	if float64(retries) >= b.Ratio*float64(requests)+float64(b.MinRetries) {
+		return false
+	}
+	b.bucket(now).retries++
+	return true
+}
+
+// bucket returns the bucket counting the events at now, resetting it when
+// it last counted an earlier period.
+func (b *RetryBudget) bucket(now time.Time) *budgetBucket {
+	width := b.window() / time.Duration(len(b.buckets))
+	if width <= 0 {
+		width = time.Second
+	}
+	start := now.Truncate(width)
+	bucket := &b.buckets[int(start.UnixNano()/int64(width))%len(b.buckets)]
+	if !bucket.start.Equal(start) {
+		*bucket = budgetBucket{start: start}
+	}
+	return bucket
+}
+
+// totals returns the requests and retries counted within the window
+// ending at now.
+func (b *RetryBudget) totals(now time.Time) (requests, retries int) {
+	since := now.Add(-b.window())
+	for _, bucket := range b.buckets {
+		if bucket.start.After(since) {
+			requests += bucket.requests
+			retries += bucket.retries
+		}
+	}
+	return requests, retries
+}
+
+func (b *RetryBudget) window() time.Duration {
+	if b.Window > 0 {
+		return b.Window
+	}
+	return defaultBudgetWindow
+}
+
+func (b *RetryBudget) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+// allowRetry reports whether the retry budget carried by ctx, if any,
+// allows another attempt.
+func allowRetry(ctx context.Context) bool {
+	budget, _ := ctx.Value(retryBudgetKey).(*RetryBudget)
+	return budget == nil || budget.AllowRetry()
+}
+
+// ErrConcurrencyLimit is matched (via errors.Is) by the errors returned for
+// requests rejected by a ConcurrencyLimiter.
+var ErrConcurrencyLimit = errors.New("concurrency limit reached")
+
+// ConcurrencyLimiter caps the number of requests a Client has in flight.
+// Requests over the limit wait in a queue for a slot; they fail with an
+// error matching ErrConcurrencyLimit when the queue is full or they waited
+// longer than the queue timeout.
+type ConcurrencyLimiter struct {
+	slots        chan struct{}
+	queueSize    int
+	queueTimeout time.Duration
+
+	mu     sync.Mutex
+	queued int
+}
+
+// NewConcurrencyLimiter creates a ConcurrencyLimiter allowing max requests
+// in flight and queueing up to queueSize more for at most queueTimeout each.
+// A queueTimeout of zero lets queued requests wait as long as their context
+// allows.
+func NewConcurrencyLimiter(max, queueSize int, queueTimeout time.Duration) *ConcurrencyLimiter {
+	if max < 1 {
+		max = 1
+	}
+	return &ConcurrencyLimiter{
+		slots:        make(chan struct{}, max),
+		queueSize:    queueSize,
+		queueTimeout: queueTimeout,
+	}
+}
+
+// SetConcurrencyLimit limits the client to max requests in flight, queueing
+// up to queueSize more for at most queueTimeout each.
+func (c *Client) SetConcurrencyLimit(max, queueSize int, queueTimeout time.Duration) *Client {
+	c.concurrency = NewConcurrencyLimiter(max, queueSize, queueTimeout)
+	return c
+}
+
+// Acquire takes a slot for a request, waiting in the queue if there is none
+// free. Each successful Acquire must be followed by a Release.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	l.mu.Lock()
+	if l.queued >= l.queueSize {
+		l.mu.Unlock()
+		return fmt.Errorf("%w: %d requests in flight and %d queued", ErrConcurrencyLimit, cap(l.slots), l.queueSize)
+	}
+	l.queued++
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		l.queued--
+		l.mu.Unlock()
+	}()
+
+	var timeout <-chan time.Time
+	if l.queueTimeout > 0 {
+		timer := time.NewTimer(l.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-timeout:
+		return fmt.Errorf("%w: no slot freed within %v", ErrConcurrencyLimit, l.queueTimeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees the slot taken by Acquire.
+func (l *ConcurrencyLimiter) Release() {
+	<-l.slots
+}
+
+// InFlight returns the number of requests holding a slot.
+func (l *ConcurrencyLimiter) InFlight() int {
+	return len(l.slots)
+}
+
+// Queued returns the number of requests waiting for a slot.
+func (l *ConcurrencyLimiter) Queued() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.queued
+}
@@ -0,0 +1,140 @@
+package gocurl_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryBudget(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	budget := gocurl.NewRetryBudget(0.5)
+	budget.MinRetries = 0
+	client := gocurl.NewClient().SetRetryBudget(budget)
+	ctx := context.Background()
+
+	get := func() {
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(server.URL).
+			SetRetryConfig(&options.RetryConfig{MaxRetries: 3, RetryOnHTTP: []int{http.StatusServiceUnavailable}}).
+			Build()
+		opts.Silent = true
+		resp, _, err := client.Process(ctx, opts)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	// Half a retry is allowed per request
+	get()
+	assert.EqualValues(t, 2, hits)
+	get()
+	assert.EqualValues(t, 3, hits)
+	get()
+	assert.EqualValues(t, 5, hits)
+	get()
+	assert.EqualValues(t, 6, hits)
+
+	t.Run("Allowance", func(t *testing.T) {
+		budget := gocurl.NewRetryBudget(0.1)
+		budget.MinRetries = 0
+		for i := 0; i < 20; i++ {
+			budget.Request()
+		}
+		assert.True(t, budget.AllowRetry())
+		assert.True(t, budget.AllowRetry())
+		assert.False(t, budget.AllowRetry())
+	})
+
+	t.Run("Zero value", func(t *testing.T) {
+		budget := &gocurl.RetryBudget{Ratio: 0.5}
+		budget.Request()
+		budget.Request()
+		assert.True(t, budget.AllowRetry())
+		assert.False(t, budget.AllowRetry())
+	})
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	release := make(chan struct{})
+	var running, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	limiter := gocurl.NewConcurrencyLimiter(2, 1, 50*time.Millisecond)
+	client := gocurl.NewClient()
+	client.SetConcurrencyLimit(2, 1, time.Second)
+	ctx := context.Background()
+
+	t.Run("Limiter", func(t *testing.T) {
+		require.NoError(t, limiter.Acquire(ctx))
+		require.NoError(t, limiter.Acquire(ctx))
+		assert.Equal(t, 2, limiter.InFlight())
+
+		// The queue times out, then is full while a request waits
+		err := limiter.Acquire(ctx)
+		assert.True(t, errors.Is(err, gocurl.ErrConcurrencyLimit))
+
+		queued := make(chan error)
+		go func() { queued <- limiter.Acquire(ctx) }()
+		require.Eventually(t, func() bool { return limiter.Queued() == 1 }, time.Second, time.Millisecond)
+		err = limiter.Acquire(ctx)
+		assert.True(t, errors.Is(err, gocurl.ErrConcurrencyLimit))
+		limiter.Release()
+		require.NoError(t, <-queued)
+		limiter.Release()
+		limiter.Release()
+		assert.Equal(t, 0, limiter.InFlight())
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := client.Curl(ctx, "-s", server.URL)
+			errs <- err
+		}()
+	}
+	// Two requests run, one waits and one is rejected
+	err := <-errs
+	assert.True(t, errors.Is(err, gocurl.ErrConcurrencyLimit), "%v", err)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&running) == 2 }, time.Second, time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		release <- struct{}{}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.EqualValues(t, 2, peak)
+}
@@ -0,0 +1,87 @@
+package gocurl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// defaultHeaderPoll is the interval WaitForHeader polls at when none is given.
+const defaultHeaderPoll = time.Second
+
+// GetHeader fetches url with a GET request and returns its name response
+// header, or an empty string if the response has none. The header is read
+// whatever the status of the response.
+func GetHeader(ctx context.Context, url, name string) (string, error) {
+	opts := headerOptions(url)
+	if err := ValidateOptions(opts); err != nil {
+		return "", err
+	}
+	resp, _, err := Process(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	return resp.Header.Get(name), nil
+}
+
+// WaitForHeader polls url every poll (1s when zero) until its header
+// response header satisfies predicate, and returns the matching value. It is
+// meant for scripts waiting for a deployment marker such as X-App-Version.
+// Failed requests are retried, as servers may be unavailable while they are
+// deployed; when ctx is done the last value or error is reported.
+func WaitForHeader(ctx context.Context, url, header string, predicate func(value string) bool, poll time.Duration) (string, error) {
+	if poll <= 0 {
+		poll = defaultHeaderPoll
+	}
+	opts := headerOptions(url)
+	if err := ValidateOptions(opts); err != nil {
+		return "", err
+	}
+	client, err := CreateHTTPClient(opts)
+	if err != nil {
+		return "", err
+	}
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	var last string
+	var lastErr error
+	for {
+		resp, _, err := processWithClient(ctx, client, opts)
+		switch {
+		case err == nil:
+			last, lastErr = resp.Header.Get(header), nil
+			if predicate(last) {
+				return last, nil
+			}
+		case ctx.Err() == nil:
+			lastErr = err
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return "", fmt.Errorf("header %s not matched: %w (last error: %v)", header, ctx.Err(), lastErr)
+			}
+			return last, fmt.Errorf("header %s not matched: %w (last value %q)", header, ctx.Err(), last)
+		case <-ticker.C:
+		}
+	}
+}
+
+// HeaderEquals returns a WaitForHeader predicate matching value exactly.
+func HeaderEquals(value string) func(string) bool {
+	return func(v string) bool { return v == value }
+}
+
+// headerOptions returns the options of the requests made by GetHeader and
+// WaitForHeader.
+func headerOptions(url string) *options.RequestOptions {
+	opts := options.NewRequestOptions(url)
+	opts.Method = "GET"
+	opts.Silent = true
+	return opts
+}
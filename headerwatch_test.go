@@ -0,0 +1,63 @@
+package gocurl_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderWatch(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		if n == 2 {
+			// Mid-deployment failure
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Header().Set("X-App-Version", "v"+strconv.Itoa(int(n)))
+	}))
+	defer server.Close()
+
+	t.Run("GetHeader", func(t *testing.T) {
+		atomic.StoreInt32(&hits, 0)
+		value, err := gocurl.GetHeader(context.Background(), server.URL, "x-app-version")
+		require.NoError(t, err)
+		assert.Equal(t, "v1", value)
+
+		value, err = gocurl.GetHeader(context.Background(), server.URL, "X-Missing")
+		require.NoError(t, err)
+		assert.Empty(t, value)
+	})
+
+	t.Run("WaitForHeader", func(t *testing.T) {
+		atomic.StoreInt32(&hits, 0)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		value, err := gocurl.WaitForHeader(ctx, server.URL, "X-App-Version", gocurl.HeaderEquals("v3"), 10*time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, "v3", value)
+		assert.EqualValues(t, 3, atomic.LoadInt32(&hits))
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		value, err := gocurl.WaitForHeader(ctx, server.URL, "X-App-Version", gocurl.HeaderEquals("never"), 10*time.Millisecond)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+		assert.NotEmpty(t, value)
+	})
+
+	t.Run("Invalid URL", func(t *testing.T) {
+		_, err := gocurl.GetHeader(context.Background(), "ftp://example.com", "X-App-Version")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,67 @@
+package gocurl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// FromHTTPRequest converts a net/http request into request options, so
+// requests built for the standard library can be sent through Process or a
+// Client and gain their retries, tracing and caching. The body of req is
+// read into the options, unless its length is unknown, in which case it is
+// streamed from req.Body. The context of req is not carried over.
+func FromHTTPRequest(req *http.Request) (*options.RequestOptions, error) {
+	if req.URL == nil {
+		return nil, fmt.Errorf("request has no URL")
+	}
+	opts := options.NewRequestOptions(req.URL.String())
+	opts.Method = req.Method
+	if opts.Method == "" {
+		opts.Method = http.MethodGet
+	}
+	opts.Headers = req.Header.Clone()
+	if opts.Headers == nil {
+		opts.Headers = http.Header{}
+	}
+	if req.Host != "" && req.Host != req.URL.Host {
+		opts.Headers.Set("Host", req.Host)
+	}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return opts, nil
+	}
+	if req.GetBody == nil && req.ContentLength <= 0 {
+		opts.BodyReader = req.Body
+		return opts, nil
+	}
+	body := req.Body
+	if req.GetBody != nil {
+		// Leave req.Body unread so req can still be sent itself
+		var err error
+		if body, err = req.GetBody(); err != nil {
+			return nil, fmt.Errorf("failed to read request body: %v", err)
+		}
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %v", err)
+	}
+	opts.Body = string(data)
+	return opts, nil
+}
+
+// ToHTTPRequest builds the net/http request Process would send for opts,
+// with the global defaults, middleware and signer applied, so requests
+// described with gocurl can be sent by code using the standard library.
+func ToHTTPRequest(ctx context.Context, opts *options.RequestOptions) (*http.Request, error) {
+	opts = withDefaults(ctx, opts)
+	if err := ValidateOptions(opts); err != nil {
+		return nil, err
+	}
+	return prepareRequest(ctx, opts)
+}
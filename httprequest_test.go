@@ -0,0 +1,86 @@
+package gocurl_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromHTTPRequest(t *testing.T) {
+	var got *http.Request
+	var gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		got, gotBody = r, string(data)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest("PUT", server.URL+"/items/1?v=2", strings.NewReader(`{"name":"a"}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Host = "api.example.com"
+
+	opts, err := gocurl.FromHTTPRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, "PUT", opts.Method)
+	assert.Equal(t, `{"name":"a"}`, opts.Body)
+
+	// req keeps its body
+	data, _ := io.ReadAll(req.Body)
+	assert.Equal(t, `{"name":"a"}`, string(data))
+
+	opts.Silent = true
+	_, body, err := gocurl.Process(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", body)
+	assert.Equal(t, "/items/1?v=2", got.URL.RequestURI())
+	assert.Equal(t, "api.example.com", got.Host)
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.Equal(t, `{"name":"a"}`, gotBody)
+
+	t.Run("Streamed body", func(t *testing.T) {
+		req, err := http.NewRequest("POST", server.URL, io.NopCloser(strings.NewReader("stream")))
+		require.NoError(t, err)
+		opts, err := gocurl.FromHTTPRequest(req)
+		require.NoError(t, err)
+		assert.Empty(t, opts.Body)
+		require.NotNil(t, opts.BodyReader)
+		data, _ := io.ReadAll(opts.BodyReader)
+		assert.Equal(t, "stream", string(data))
+	})
+}
+
+func TestToHTTPRequest(t *testing.T) {
+	opts := options.NewRequestOptionsBuilder().
+		SetURL("https://api.example.com/items").
+		SetMethod("POST").
+		SetBody(`{"name":"a"}`).
+		AddQueryParam("page", "2").
+		SetBearerToken("secret").
+		Build()
+
+	req, err := gocurl.ToHTTPRequest(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, "POST", req.Method)
+	assert.Equal(t, "https://api.example.com/items?page=2", req.URL.String())
+	assert.Equal(t, "Bearer secret", req.Header.Get("Authorization"))
+	data, _ := io.ReadAll(req.Body)
+	assert.Equal(t, `{"name":"a"}`, string(data))
+
+	roundTripped, err := gocurl.FromHTTPRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, req.URL.String(), roundTripped.URL)
+	assert.Equal(t, `{"name":"a"}`, roundTripped.Body)
+
+	_, err = gocurl.ToHTTPRequest(context.Background(), options.NewRequestOptions(""))
+	assert.Error(t, err)
+}
@@ -0,0 +1,69 @@
+package gocurl_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	t.Run("Auto key is reused across retries", func(t *testing.T) {
+		var keys, bodies []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			keys = append(keys, r.Header.Get("Idempotency-Key"))
+			bodies = append(bodies, string(body))
+			if len(keys) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		opts := options.NewRequestOptionsBuilder().
+			POST(server.URL, "amount=100", http.Header{}).
+			SetIdempotencyKey(options.IdempotencyKeyAuto).
+			SetRetryConfig(&options.RetryConfig{MaxRetries: 3, RetryDelay: time.Millisecond, RetryOnHTTP: []int{503}}).
+			SetSilent(true).
+			Build()
+
+		resp, _, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Len(t, keys, 3)
+		assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), keys[0])
+		assert.Equal(t, keys[0], keys[1])
+		assert.Equal(t, keys[0], keys[2])
+		assert.Equal(t, []string{"amount=100", "amount=100", "amount=100"}, bodies)
+	})
+
+	t.Run("Explicit key", func(t *testing.T) {
+		opts := &options.RequestOptions{
+			URL:            "https://example.com",
+			IdempotencyKey: "order-42",
+		}
+		req, err := gocurl.CreateRequest(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, "order-42", req.Header.Get("Idempotency-Key"))
+	})
+
+	t.Run("Auto keys differ per request", func(t *testing.T) {
+		a, err := gocurl.NewIdempotencyKey()
+		require.NoError(t, err)
+		b, err := gocurl.NewIdempotencyKey()
+		require.NoError(t, err)
+		assert.NotEqual(t, a, b)
+	})
+}
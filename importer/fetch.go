@@ -0,0 +1,312 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// fetchMaxRedirects is the number of redirects fetch follows.
+const fetchMaxRedirects = 20
+
+var fetchCall = regexp.MustCompile(`\bfetch\s*\(`)
+
+// FromFetch converts a fetch() call, as copied with "Copy as fetch" or "Copy
+// as fetch (Node.js)", into request options. Redirects are followed unless
+// redirect is "manual" or "error", as fetch does, credentials "omit" drops
+// the cookies of the request and referrerPolicy "no-referrer" its referrer.
+func FromFetch(snippet string) (*options.RequestOptions, error) {
+	loc := fetchCall.FindStringIndex(snippet)
+	if loc == nil {
+		return nil, fmt.Errorf("no fetch() call found")
+	}
+	p := &jsParser{s: snippet, i: loc[1]}
+	args, err := p.args()
+	if err != nil {
+		return nil, fmt.Errorf("invalid fetch() call: %v", err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("fetch() needs a URL")
+	}
+	rawURL, err := jsString(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid fetch() URL: %v", err)
+	}
+
+	opts := options.NewRequestOptions(rawURL)
+	opts.Method = "GET"
+	opts.FollowRedirects = true
+	opts.MaxRedirects = fetchMaxRedirects
+	if len(args) < 2 {
+		return opts, nil
+	}
+	init, ok := args[1].(*jsObject)
+	if !ok {
+		return nil, fmt.Errorf("fetch() options must be an object")
+	}
+
+	noReferrer, omitCredentials := false, false
+	for _, key := range init.keys {
+		value := init.values[key]
+		if _, ok := value.(jsUndefined); ok || value == nil {
+			continue
+		}
+		switch key {
+		case "method":
+			method, err := jsString(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid method: %v", err)
+			}
+			opts.Method = strings.ToUpper(method)
+		case "headers":
+			if err := setHeaders(opts, value); err != nil {
+				return nil, err
+			}
+		case "body":
+			body, err := jsString(value)
+			if err != nil {
+				return nil, fmt.Errorf("unsupported body: %v", err)
+			}
+			opts.Body = body
+		case "referrer":
+			if opts.Referer, err = jsString(value); err != nil {
+				return nil, fmt.Errorf("invalid referrer: %v", err)
+			}
+		case "redirect":
+			if mode, _ := jsString(value); mode == "manual" || mode == "error" {
+				opts.FollowRedirects = false
+				opts.MaxRedirects = 0
+			}
+		case "referrerPolicy":
+			policy, _ := jsString(value)
+			noReferrer = policy == "no-referrer"
+		case "credentials":
+			mode, _ := jsString(value)
+			omitCredentials = mode == "omit"
+		}
+	}
+	if noReferrer {
+		opts.Referer = ""
+	}
+	if omitCredentials && opts.Headers != nil {
+		opts.Headers.Del("Cookie")
+	}
+	return opts, nil
+}
+
+var axiosCall = regexp.MustCompile(`\baxios(?:\.(get|delete|head|options|post|put|patch|request))?\s*\(`)
+
+// axiosMaxRedirects is the number of redirects axios follows in Node.js.
+const axiosMaxRedirects = 21
+
+// FromAxios converts an axios(config), axios.request(config) or
+// axios.<method>(url[, data][, config]) call into request options. Object
+// data is sent as JSON, params are added to the URL, auth becomes basic
+// authentication and timeout is read in milliseconds.
+func FromAxios(snippet string) (*options.RequestOptions, error) {
+	match := axiosCall.FindStringSubmatchIndex(snippet)
+	if match == nil {
+		return nil, fmt.Errorf("no axios call found")
+	}
+	p := &jsParser{s: snippet, i: match[1]}
+	args, err := p.args()
+	if err != nil {
+		return nil, fmt.Errorf("invalid axios call: %v", err)
+	}
+
+	// Gather the config of every call form
+	config := &jsObject{values: map[string]interface{}{}}
+	merge := func(arg interface{}) error {
+		obj, ok := arg.(*jsObject)
+		if !ok {
+			return fmt.Errorf("axios config must be an object")
+		}
+		for _, key := range obj.keys {
+			if _, ok := config.values[key]; !ok {
+				config.keys = append(config.keys, key)
+			}
+			config.values[key] = obj.values[key]
+		}
+		return nil
+	}
+	set := func(key string, value interface{}) {
+		merge(&jsObject{keys: []string{key}, values: map[string]interface{}{key: value}})
+	}
+
+	method := ""
+	if match[2] >= 0 {
+		method = snippet[match[2]:match[3]]
+	}
+	switch {
+	case method == "" || method == "request":
+		// axios(config) or axios(url[, config])
+		if len(args) > 0 {
+			if _, ok := args[0].(string); ok {
+				set("url", args[0])
+				args = args[1:]
+			}
+		}
+		for _, arg := range args {
+			if err := merge(arg); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		if len(args) == 0 {
+			return nil, fmt.Errorf("axios.%s needs a URL", method)
+		}
+		set("url", args[0])
+		rest := args[1:]
+		if method == "post" || method == "put" || method == "patch" {
+			if len(rest) > 0 {
+				set("data", rest[0])
+				rest = rest[1:]
+			}
+		}
+		for _, arg := range rest {
+			if err := merge(arg); err != nil {
+				return nil, err
+			}
+		}
+		set("method", method)
+	}
+	return axiosOptions(config)
+}
+
+// axiosOptions converts an axios request config.
+func axiosOptions(config *jsObject) (*options.RequestOptions, error) {
+	opts := options.NewRequestOptions("")
+	opts.Method = "GET"
+	opts.FollowRedirects = true
+	opts.MaxRedirects = axiosMaxRedirects
+
+	var baseURL, path string
+	var params *jsObject
+	for _, key := range config.keys {
+		value := config.values[key]
+		if _, ok := value.(jsUndefined); ok || value == nil {
+			continue
+		}
+		var err error
+		switch key {
+		case "url":
+			path, err = jsString(value)
+		case "baseURL":
+			baseURL, err = jsString(value)
+		case "method":
+			var method string
+			method, err = jsString(value)
+			opts.Method = strings.ToUpper(method)
+		case "headers":
+			err = setHeaders(opts, value)
+		case "params":
+			var ok bool
+			if params, ok = value.(*jsObject); !ok {
+				err = fmt.Errorf("params must be an object")
+			}
+		case "data":
+			if body, ok := value.(string); ok {
+				opts.Body = body
+				break
+			}
+			var body []byte
+			if body, err = json.Marshal(value); err == nil {
+				opts.Body = string(body)
+				if opts.Headers == nil || opts.Headers.Get("Content-Type") == "" {
+					setHeader(opts, "Content-Type", "application/json")
+				}
+			}
+		case "auth":
+			auth, ok := value.(*jsObject)
+			if !ok {
+				return nil, fmt.Errorf("auth must be an object")
+			}
+			opts.BasicAuth = &options.BasicAuth{}
+			if username, ok := auth.get("username"); ok {
+				opts.BasicAuth.Username, err = jsString(username)
+			}
+			if password, ok := auth.get("password"); ok && err == nil {
+				opts.BasicAuth.Password, err = jsString(password)
+			}
+		case "timeout":
+			var ms float64
+			if ms, err = jsNumber(value); err == nil && ms > 0 {
+				opts.Timeout = time.Duration(ms * float64(time.Millisecond))
+			}
+		case "maxRedirects":
+			var max float64
+			if max, err = jsNumber(value); err == nil {
+				opts.FollowRedirects = max > 0
+				opts.MaxRedirects = int(max)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid axios %s: %v", key, err)
+		}
+	}
+
+	if path == "" {
+		return nil, fmt.Errorf("axios call has no url")
+	}
+	opts.URL = path
+	if baseURL != "" && !strings.Contains(path, "://") {
+		opts.URL = strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(path, "/")
+	}
+	if params != nil {
+		query, err := formEncode(params)
+		if err != nil {
+			return nil, fmt.Errorf("invalid axios params: %v", err)
+		}
+		if query != "" {
+			separator := "?"
+			if strings.Contains(opts.URL, "?") {
+				separator = "&"
+			}
+			opts.URL += separator + query
+		}
+	}
+	if _, err := url.Parse(opts.URL); err != nil {
+		return nil, fmt.Errorf("invalid axios url: %v", err)
+	}
+	return opts, nil
+}
+
+// setHeaders adds the headers of a headers object.
+func setHeaders(opts *options.RequestOptions, value interface{}) error {
+	headers, ok := value.(*jsObject)
+	if !ok {
+		return fmt.Errorf("headers must be an object")
+	}
+	for _, name := range headers.keys {
+		if _, ok := headers.values[name].(jsUndefined); ok {
+			continue
+		}
+		header, err := jsString(headers.values[name])
+		if err != nil {
+			return fmt.Errorf("invalid header %s: %v", name, err)
+		}
+		setHeader(opts, name, header)
+	}
+	return nil
+}
+
+func setHeader(opts *options.RequestOptions, name, value string) {
+	if opts.Headers == nil {
+		opts.Headers = http.Header{}
+	}
+	opts.Headers.Add(name, value)
+}
+
+func jsNumber(v interface{}) (float64, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("expected a number, got %T", v)
+	}
+	return n.Float64()
+}
@@ -0,0 +1,139 @@
+package importer_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl/importer"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromFetch(t *testing.T) {
+	t.Run("Copy as fetch", func(t *testing.T) {
+		snippet := `fetch("https://api.example.com/items", {
+  "headers": {
+    "accept": "application/json",
+    "content-type": "application/json",
+    "cookie": "sid=abc",
+  },
+  "referrer": "https://example.com/",
+  "referrerPolicy": "strict-origin-when-cross-origin",
+  "body": "{\"name\":\"gocurl\",\"emoji\":\"\ud83d\ude00\"}",
+  "method": "POST",
+  "mode": "cors",
+  "credentials": "include"
+});`
+		opts, err := importer.FromFetch(snippet)
+		require.NoError(t, err)
+		assert.Equal(t, "POST", opts.Method)
+		assert.Equal(t, "https://api.example.com/items", opts.URL)
+		assert.Equal(t, http.Header{
+			"Accept":       {"application/json"},
+			"Content-Type": {"application/json"},
+			"Cookie":       {"sid=abc"},
+		}, opts.Headers)
+		assert.Equal(t, `{"name":"gocurl","emoji":"😀"}`, opts.Body)
+		assert.Equal(t, "https://example.com/", opts.Referer)
+		assert.True(t, opts.FollowRedirects)
+		assert.Equal(t, 20, opts.MaxRedirects)
+	})
+
+	t.Run("Credentials, redirects and JSON.stringify", func(t *testing.T) {
+		snippet := `await fetch('https://api.example.com/items', {
+  // Firefox writes comments too
+  credentials: 'omit',
+  headers: { Cookie: 'sid=abc', 'X-Id': 42 },
+  referrer: 'https://example.com/',
+  referrerPolicy: 'no-referrer',
+  redirect: 'manual',
+  method: 'put',
+  body: JSON.stringify({ b: [1, true, null, undefined], a: 'xé', skipped: undefined }),
+})`
+		opts, err := importer.FromFetch(snippet)
+		require.NoError(t, err)
+		assert.Equal(t, "PUT", opts.Method)
+		assert.Equal(t, http.Header{"X-Id": {"42"}}, opts.Headers)
+		assert.Equal(t, `{"b":[1,true,null,null],"a":"xé"}`, opts.Body)
+		assert.Empty(t, opts.Referer)
+		assert.False(t, opts.FollowRedirects)
+	})
+
+	t.Run("URL only", func(t *testing.T) {
+		opts, err := importer.FromFetch("fetch(`https://example.com/?q=1`)")
+		require.NoError(t, err)
+		assert.Equal(t, "GET", opts.Method)
+		assert.Equal(t, "https://example.com/?q=1", opts.URL)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, snippet := range []string{
+			`console.log("no request")`,
+			`fetch()`,
+			`fetch(url)`,
+			"fetch(`https://example.com/${id}`)",
+			`fetch("https://example.com", { "body": "unterminated })`,
+			`fetch("https://example.com", { "body": new Blob(["x"]) })`,
+		} {
+			_, err := importer.FromFetch(snippet)
+			assert.Error(t, err, snippet)
+		}
+	})
+}
+
+func TestFromAxios(t *testing.T) {
+	t.Run("Config object", func(t *testing.T) {
+		snippet := `axios({
+  method: 'post',
+  baseURL: 'https://api.example.com/v1/',
+  url: '/users',
+  params: { page: 2, q: 'a b' },
+  data: { firstName: 'Fred', tags: ['x'] },
+  headers: { 'X-Trace': 'abc' },
+  auth: { username: 'janedoe', password: 's00pers3cret' },
+  timeout: 1500,
+  maxRedirects: 0,
+});`
+		opts, err := importer.FromAxios(snippet)
+		require.NoError(t, err)
+		assert.Equal(t, "POST", opts.Method)
+		assert.Equal(t, "https://api.example.com/v1/users?page=2&q=a+b", opts.URL)
+		assert.Equal(t, `{"firstName":"Fred","tags":["x"]}`, opts.Body)
+		assert.Equal(t, http.Header{"X-Trace": {"abc"}, "Content-Type": {"application/json"}}, opts.Headers)
+		assert.Equal(t, &options.BasicAuth{Username: "janedoe", Password: "s00pers3cret"}, opts.BasicAuth)
+		assert.Equal(t, 1500*time.Millisecond, opts.Timeout)
+		assert.False(t, opts.FollowRedirects)
+	})
+
+	t.Run("Method shorthands", func(t *testing.T) {
+		opts, err := importer.FromAxios(`const res = await axios.put("https://example.com/items/1", "raw=1", { headers: { "Content-Type": "text/plain" } })`)
+		require.NoError(t, err)
+		assert.Equal(t, "PUT", opts.Method)
+		assert.Equal(t, "raw=1", opts.Body)
+		assert.Equal(t, "text/plain", opts.Headers.Get("Content-Type"))
+		assert.True(t, opts.FollowRedirects)
+
+		opts, err = importer.FromAxios(`axios.get('https://example.com/items?a=1', { params: { b: 2 } })`)
+		require.NoError(t, err)
+		assert.Equal(t, "GET", opts.Method)
+		assert.Equal(t, "https://example.com/items?a=1&b=2", opts.URL)
+
+		opts, err = importer.FromAxios(`axios('https://example.com/items', { method: 'delete' })`)
+		require.NoError(t, err)
+		assert.Equal(t, "DELETE", opts.Method)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, snippet := range []string{
+			`fetch("https://example.com")`,
+			`axios.get()`,
+			`axios({ method: 'get' })`,
+			`axios.get('https://example.com', 'not a config')`,
+		} {
+			_, err := importer.FromAxios(snippet)
+			assert.Error(t, err, snippet)
+		}
+	})
+}
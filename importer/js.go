@@ -0,0 +1,398 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// jsObject is a JavaScript object literal, keeping the order of its keys so
+// bodies are encoded as they were written.
+type jsObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *jsObject) get(key string) (interface{}, bool) {
+	value, ok := o.values[key]
+	return value, ok
+}
+
+func (o *jsObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, key := range o.keys {
+		if _, ok := o.values[key].(jsUndefined); ok {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		value, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsUndefined is the value of undefined, which JSON.stringify leaves out of
+// objects and writes as null in arrays.
+type jsUndefined struct{}
+
+func (jsUndefined) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+// jsParser reads the JavaScript literals used by fetch and axios snippets:
+// strings, template strings without substitutions, numbers, booleans, null,
+// objects, arrays, JSON.stringify(...) and new URLSearchParams(...).
+type jsParser struct {
+	s string
+	i int
+}
+
+func (p *jsParser) done() bool {
+	return p.i >= len(p.s)
+}
+
+func (p *jsParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.s[p.i]
+}
+
+// skipSpace skips whitespace and comments.
+func (p *jsParser) skipSpace() {
+	for !p.done() {
+		switch {
+		case strings.IndexByte(" \t\r\n", p.s[p.i]) >= 0:
+			p.i++
+		case strings.HasPrefix(p.s[p.i:], "//"):
+			for !p.done() && p.s[p.i] != '\n' {
+				p.i++
+			}
+		case strings.HasPrefix(p.s[p.i:], "/*"):
+			end := strings.Index(p.s[p.i+2:], "*/")
+			if end < 0 {
+				p.i = len(p.s)
+			} else {
+				p.i += end + 4
+			}
+		default:
+			return
+		}
+	}
+}
+
+// expect skips spaces and the character c.
+func (p *jsParser) expect(c byte) error {
+	p.skipSpace()
+	if p.peek() != c {
+		if p.done() {
+			return fmt.Errorf("expected %q, got end of snippet", c)
+		}
+		return fmt.Errorf("expected %q at offset %d, got %q", c, p.i, p.s[p.i])
+	}
+	p.i++
+	return nil
+}
+
+// args reads the arguments of a call, after its opening parenthesis.
+func (p *jsParser) args() ([]interface{}, error) {
+	var args []interface{}
+	for {
+		p.skipSpace()
+		if p.peek() == ')' {
+			p.i++
+			return args, nil
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+		p.skipSpace()
+		if p.peek() == ',' {
+			p.i++
+		} else if err := p.expect(')'); err != nil {
+			return nil, err
+		} else {
+			return args, nil
+		}
+	}
+}
+
+// value reads a literal.
+func (p *jsParser) value() (interface{}, error) {
+	p.skipSpace()
+	switch c := p.peek(); {
+	case c == '"' || c == '\'' || c == '`':
+		return p.str()
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '-' || c == '.' || c >= '0' && c <= '9':
+		return p.number()
+	case c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		return p.expression()
+	case p.done():
+		return nil, fmt.Errorf("unexpected end of snippet")
+	default:
+		return nil, fmt.Errorf("unexpected %q at offset %d", c, p.i)
+	}
+}
+
+func (p *jsParser) identifier() string {
+	start := p.i
+	for !p.done() {
+		c := p.s[p.i]
+		if !(c == '_' || c == '$' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			break
+		}
+		p.i++
+	}
+	return p.s[start:p.i]
+}
+
+// expression reads a keyword or one of the calls snippets build bodies with.
+func (p *jsParser) expression() (interface{}, error) {
+	start := p.i
+	name := p.identifier()
+	switch name {
+	case "true", "false":
+		return name == "true", nil
+	case "null":
+		return nil, nil
+	case "undefined":
+		return jsUndefined{}, nil
+	case "new":
+		p.skipSpace()
+		name = "new " + p.identifier()
+	}
+
+	if err := p.expect('('); err != nil {
+		return nil, fmt.Errorf("unsupported expression %q", name)
+	}
+	args, err := p.args()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case name == "JSON.stringify" && len(args) > 0:
+		body, err := json.Marshal(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return string(body), nil
+	case name == "new URLSearchParams" && len(args) == 1:
+		return formEncode(args[0])
+	}
+	return nil, fmt.Errorf("unsupported expression %q", p.s[start:p.i])
+}
+
+// formEncode encodes the argument of new URLSearchParams.
+func formEncode(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimPrefix(v, "?"), nil
+	case *jsObject:
+		var parts []string
+		for _, key := range v.keys {
+			value, err := jsString(v.values[key])
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+		return strings.Join(parts, "&"), nil
+	}
+	return "", fmt.Errorf("unsupported URLSearchParams argument")
+}
+
+// jsString converts a scalar to the string JavaScript would.
+func jsString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case nil:
+		return "null", nil
+	}
+	return "", fmt.Errorf("expected a string, got %T", v)
+}
+
+func (p *jsParser) object() (*jsObject, error) {
+	obj := &jsObject{values: map[string]interface{}{}}
+	p.i++
+	for {
+		p.skipSpace()
+		if p.peek() == '}' {
+			p.i++
+			return obj, nil
+		}
+
+		var key string
+		switch c := p.peek(); {
+		case c == '"' || c == '\'':
+			var err error
+			if key, err = p.str(); err != nil {
+				return nil, err
+			}
+		default:
+			if key = p.identifier(); key == "" {
+				return nil, fmt.Errorf("unexpected %q at offset %d", c, p.i)
+			}
+		}
+		if err := p.expect(':'); err != nil {
+			return nil, err
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := obj.values[key]; !ok {
+			obj.keys = append(obj.keys, key)
+		}
+		obj.values[key] = value
+
+		p.skipSpace()
+		if p.peek() == ',' {
+			p.i++
+		} else if err := p.expect('}'); err != nil {
+			return nil, err
+		} else {
+			return obj, nil
+		}
+	}
+}
+
+func (p *jsParser) array() ([]interface{}, error) {
+	array := []interface{}{}
+	p.i++
+	for {
+		p.skipSpace()
+		if p.peek() == ']' {
+			p.i++
+			return array, nil
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+		p.skipSpace()
+		if p.peek() == ',' {
+			p.i++
+		} else if err := p.expect(']'); err != nil {
+			return nil, err
+		} else {
+			return array, nil
+		}
+	}
+}
+
+func (p *jsParser) number() (json.Number, error) {
+	start := p.i
+	for !p.done() && strings.IndexByte("+-.0123456789eE", p.s[p.i]) >= 0 {
+		p.i++
+	}
+	text := p.s[start:p.i]
+	if _, err := strconv.ParseFloat(text, 64); err != nil {
+		return "", fmt.Errorf("invalid number %q", text)
+	}
+	return json.Number(text), nil
+}
+
+// jsEscapes are the single character escapes of string literals.
+var jsEscapes = map[byte]string{'b': "\b", 'f': "\f", 'n': "\n", 'r': "\r", 't': "\t", 'v': "\v", '0': "\x00"}
+
+// str reads a quoted or template string.
+func (p *jsParser) str() (string, error) {
+	quote := p.s[p.i]
+	var b strings.Builder
+	for p.i++; !p.done(); p.i++ {
+		c := p.s[p.i]
+		switch {
+		case c == quote:
+			p.i++
+			return b.String(), nil
+		case quote == '`' && strings.HasPrefix(p.s[p.i:], "${"):
+			return "", fmt.Errorf("template substitutions are not supported")
+		case c == '\n' && quote != '`':
+			return "", fmt.Errorf("unterminated string")
+		case c == '\\' && p.i+1 < len(p.s):
+			p.i++
+			escape := p.s[p.i]
+			switch {
+			case jsEscapes[escape] != "":
+				b.WriteString(jsEscapes[escape])
+			case escape == '\n':
+				// Line continuation
+			case escape == 'x' && p.i+2 < len(p.s):
+				code, err := strconv.ParseUint(p.s[p.i+1:p.i+3], 16, 8)
+				if err != nil {
+					return "", fmt.Errorf("invalid escape \\x%s", p.s[p.i+1:p.i+3])
+				}
+				b.WriteRune(rune(code))
+				p.i += 2
+			case escape == 'u':
+				r, n, err := unicodeEscape(p.s[p.i+1:])
+				if err != nil {
+					return "", err
+				}
+				b.WriteRune(r)
+				p.i += n
+			default:
+				b.WriteByte(escape)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+// unicodeEscape decodes the \uXXXX or \u{X...} escape s starts with, after
+// the u, joining surrogate pairs. It returns the rune and the bytes read.
+func unicodeEscape(s string) (rune, int, error) {
+	if strings.HasPrefix(s, "{") {
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return 0, 0, fmt.Errorf("invalid escape \\u%s", s)
+		}
+		code, err := strconv.ParseUint(s[1:end], 16, 32)
+		if err != nil || code > utf8.MaxRune {
+			return 0, 0, fmt.Errorf("invalid escape \\u%s", s[:end+1])
+		}
+		return rune(code), end + 1, nil
+	}
+
+	if len(s) < 4 {
+		return 0, 0, fmt.Errorf("invalid escape \\u%s", s)
+	}
+	code, err := strconv.ParseUint(s[:4], 16, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid escape \\u%s", s[:4])
+	}
+	if code >= 0xd800 && code < 0xdc00 && len(s) >= 10 && s[4:6] == `\u` {
+		if low, err := strconv.ParseUint(s[6:10], 16, 16); err == nil && low >= 0xdc00 && low < 0xe000 {
+			return rune((code-0xd800)<<10 + (low - 0xdc00) + 0x10000), 10, nil
+		}
+	}
+	return rune(code), 4, nil
+}
@@ -0,0 +1,423 @@
+// Package importer converts request snippets copied from other tools, such
+// as the "Copy as PowerShell" command of browsers, into gocurl request
+// options.
+package importer
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// psValue is a value in a PowerShell snippet.
+type psValue struct {
+	// text is the decoded string, the bare word or the variable name
+	text string
+	// hash holds the entries of a @{...} hashtable in order
+	hash [][2]string
+	// strings holds the string literals of a (...) expression
+	strings []string
+	isHash  bool
+	isParen bool
+}
+
+// psSwitches are the parameters of Invoke-WebRequest and Invoke-RestMethod
+// that take no value.
+var psSwitches = map[string]bool{
+	"usebasicparsing": true, "skipcertificatecheck": true, "disablekeepalive": true,
+	"usedefaultcredentials": true, "skipheadervalidation": true, "skiphttperrorcheck": true,
+	"allowunencryptedauthentication": true, "passthru": true, "resume": true,
+	"proxyusedefaultcredentials": true, "allowinsecureredirect": true, "noproxy": true,
+}
+
+// psPseudoHeaders are HTTP/2 pseudo-headers browsers write without their
+// colon in the -Headers hashtable.
+var psPseudoHeaders = map[string]bool{"authority": true, "method": true, "path": true, "scheme": true}
+
+// FromPowerShell converts an Invoke-WebRequest or Invoke-RestMethod snippet,
+// as copied with "Copy as PowerShell", into request options. The user agent
+// and cookies set on a WebRequestSession variable are applied too.
+func FromPowerShell(snippet string) (*options.RequestOptions, error) {
+	p := &psParser{s: snippet}
+	var opts *options.RequestOptions
+	var userAgent string
+	var cookies []*http.Cookie
+
+	for {
+		p.skipSpace(true)
+		if p.done() {
+			break
+		}
+		word, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.ToLower(word.text)
+		switch {
+		case strings.HasSuffix(name, ".useragent") && strings.HasPrefix(name, "$"):
+			p.skipSpace(false)
+			if p.peek() == '=' {
+				p.i++
+				p.skipSpace(false)
+				value, err := p.value()
+				if err != nil {
+					return nil, err
+				}
+				userAgent = value.text
+			}
+		case strings.HasSuffix(name, ".cookies.add") && strings.HasPrefix(name, "$"):
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			if len(value.strings) < 2 {
+				return nil, fmt.Errorf("unsupported cookie in PowerShell snippet")
+			}
+			cookies = append(cookies, &http.Cookie{Name: value.strings[0], Value: value.strings[1]})
+		case name == "invoke-webrequest" || name == "invoke-restmethod" || name == "iwr" || name == "irm":
+			if opts != nil {
+				return nil, fmt.Errorf("PowerShell snippet contains several requests")
+			}
+			if opts, err = p.invoke(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := p.skipStatement(); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts == nil {
+		return nil, fmt.Errorf("no Invoke-WebRequest or Invoke-RestMethod command found")
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = userAgent
+	}
+	opts.Cookies = append(cookies, opts.Cookies...)
+	return opts, nil
+}
+
+// invoke parses the parameters of an Invoke-WebRequest command.
+func (p *psParser) invoke() (*options.RequestOptions, error) {
+	opts := options.NewRequestOptions("")
+	opts.Method = "GET"
+
+	for {
+		p.skipSpace(false)
+		if p.done() || p.atStatementEnd() {
+			break
+		}
+		arg, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(arg.text, "-") || arg.isHash || arg.isParen {
+			// The URI is the first positional parameter
+			if opts.URL != "" {
+				return nil, fmt.Errorf("unexpected argument %q", arg.text)
+			}
+			opts.URL = arg.text
+			continue
+		}
+
+		param := strings.ToLower(strings.TrimPrefix(arg.text, "-"))
+		if psSwitches[param] {
+			if param == "skipcertificatecheck" {
+				opts.Insecure = true
+			}
+			continue
+		}
+		p.skipSpace(false)
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if err := applyPowerShellParam(opts, param, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.URL == "" {
+		return nil, fmt.Errorf("no -Uri in PowerShell snippet")
+	}
+	return opts, nil
+}
+
+// applyPowerShellParam sets the option matching the Invoke-WebRequest
+// parameter. Parameters that do not affect the request are ignored.
+func applyPowerShellParam(opts *options.RequestOptions, param string, value psValue) error {
+	switch param {
+	case "uri":
+		opts.URL = value.text
+	case "method", "custommethod":
+		opts.Method = strings.ToUpper(value.text)
+	case "headers":
+		if !value.isHash {
+			return fmt.Errorf("-Headers must be a hashtable")
+		}
+		for _, entry := range value.hash {
+			if strings.HasPrefix(entry[0], ":") || psPseudoHeaders[strings.ToLower(entry[0])] {
+				continue
+			}
+			if opts.Headers == nil {
+				opts.Headers = http.Header{}
+			}
+			opts.Headers.Add(entry[0], entry[1])
+		}
+	case "body":
+		if value.isHash {
+			return fmt.Errorf("hashtable bodies are not supported")
+		}
+		opts.Body = value.text
+		if value.isParen && len(value.strings) > 0 {
+			// ([System.Text.Encoding]::UTF8.GetBytes("..."))
+			opts.Body = value.strings[0]
+		}
+	case "contenttype":
+		if opts.Headers == nil {
+			opts.Headers = http.Header{}
+		}
+		opts.Headers.Set("Content-Type", value.text)
+	case "useragent":
+		opts.UserAgent = value.text
+	case "infile":
+		opts.UploadFile = value.text
+	case "outfile":
+		opts.OutputFile = value.text
+	case "proxy":
+		opts.Proxy = value.text
+	case "timeoutsec":
+		seconds, err := strconv.Atoi(value.text)
+		if err != nil {
+			return fmt.Errorf("invalid -TimeoutSec %q", value.text)
+		}
+		opts.Timeout = time.Duration(seconds) * time.Second
+	case "maximumredirection":
+		max, err := strconv.Atoi(value.text)
+		if err != nil {
+			return fmt.Errorf("invalid -MaximumRedirection %q", value.text)
+		}
+		opts.FollowRedirects = max > 0
+		opts.MaxRedirects = max
+	}
+	return nil
+}
+
+// psParser reads the subset of PowerShell used by request snippets.
+type psParser struct {
+	s string
+	i int
+}
+
+func (p *psParser) done() bool {
+	return p.i >= len(p.s)
+}
+
+func (p *psParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.s[p.i]
+}
+
+// skipSpace skips blanks, comments and backtick line continuations, and
+// statement separators too when statements is set.
+func (p *psParser) skipSpace(statements bool) {
+	for !p.done() {
+		switch c := p.s[p.i]; {
+		case c == ' ' || c == '\t':
+			p.i++
+		case c == '`' && strings.HasPrefix(p.s[p.i+1:], "\n"):
+			p.i += 2
+		case c == '`' && strings.HasPrefix(p.s[p.i+1:], "\r\n"):
+			p.i += 3
+		case c == '#':
+			for !p.done() && p.s[p.i] != '\n' {
+				p.i++
+			}
+		case statements && (c == '\n' || c == '\r' || c == ';'):
+			p.i++
+		default:
+			return
+		}
+	}
+}
+
+func (p *psParser) atStatementEnd() bool {
+	c := p.peek()
+	return c == '\n' || c == '\r' || c == ';'
+}
+
+// skipStatement skips the values up to the end of the statement.
+func (p *psParser) skipStatement() error {
+	for {
+		p.skipSpace(false)
+		if p.done() || p.atStatementEnd() {
+			return nil
+		}
+		if p.peek() == '=' {
+			p.i++
+			continue
+		}
+		if _, err := p.value(); err != nil {
+			return err
+		}
+	}
+}
+
+// value reads a string, hashtable, parenthesized expression or bare word.
+func (p *psParser) value() (psValue, error) {
+	switch {
+	case p.peek() == '"':
+		text, err := p.doubleQuoted()
+		return psValue{text: text}, err
+	case p.peek() == '\'':
+		text, err := p.singleQuoted()
+		return psValue{text: text}, err
+	case strings.HasPrefix(p.s[p.i:], "@{"):
+		return p.hashtable()
+	case p.peek() == '(':
+		return p.paren()
+	}
+	return p.word(false)
+}
+
+// word reads a bare word. Words naming a variable, and hashtable keys when
+// key is set, end before an =.
+func (p *psParser) word(key bool) (psValue, error) {
+	start := p.i
+	for !p.done() {
+		c := p.s[p.i]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ';' || c == '}' || c == ')' || c == '(' ||
+			c == '=' && (key || p.s[start] == '$') {
+			break
+		}
+		p.i++
+	}
+	if p.i == start {
+		return psValue{}, fmt.Errorf("unexpected %q in PowerShell snippet", p.s[p.i])
+	}
+	return psValue{text: p.s[start:p.i]}, nil
+}
+
+// psEscapes are the backtick escapes of double-quoted strings.
+var psEscapes = map[byte]string{'0': "\x00", 'a': "\a", 'b': "\b", 'e': "\x1b", 'f': "\f", 'n': "\n", 'r': "\r", 't': "\t", 'v': "\v"}
+
+// doubleQuoted reads a "..." string. Backticks escape the next character and
+// "" is a quote. Variables are not expanded.
+func (p *psParser) doubleQuoted() (string, error) {
+	var b strings.Builder
+	for p.i++; !p.done(); p.i++ {
+		c := p.s[p.i]
+		switch {
+		case c == '"' && strings.HasPrefix(p.s[p.i+1:], `"`):
+			b.WriteByte('"')
+			p.i++
+		case c == '"':
+			p.i++
+			return b.String(), nil
+		case c == '`' && p.i+1 < len(p.s):
+			p.i++
+			if escape, ok := psEscapes[p.s[p.i]]; ok {
+				b.WriteString(escape)
+			} else {
+				b.WriteByte(p.s[p.i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unmatched \" quote in PowerShell snippet")
+}
+
+// singleQuoted reads a '...' string, where a doubled ' is a quote.
+func (p *psParser) singleQuoted() (string, error) {
+	var b strings.Builder
+	for p.i++; !p.done(); p.i++ {
+		c := p.s[p.i]
+		if c != '\'' {
+			b.WriteByte(c)
+			continue
+		}
+		if !strings.HasPrefix(p.s[p.i+1:], "'") {
+			p.i++
+			return b.String(), nil
+		}
+		b.WriteByte('\'')
+		p.i++
+	}
+	return "", fmt.Errorf("unmatched ' quote in PowerShell snippet")
+}
+
+// hashtable reads a @{ key = value; ... } hashtable.
+func (p *psParser) hashtable() (psValue, error) {
+	value := psValue{isHash: true}
+	p.i += 2
+	for {
+		p.skipSpace(true)
+		if p.peek() == '}' {
+			p.i++
+			return value, nil
+		}
+		if p.done() {
+			return value, fmt.Errorf("unmatched { in PowerShell snippet")
+		}
+		var key psValue
+		var err error
+		if c := p.peek(); c == '"' || c == '\'' {
+			key, err = p.value()
+		} else {
+			key, err = p.word(true)
+		}
+		if err != nil {
+			return value, err
+		}
+		p.skipSpace(false)
+		if p.peek() != '=' {
+			return value, fmt.Errorf("expected = after hashtable key %q", key.text)
+		}
+		p.i++
+		p.skipSpace(false)
+		entry, err := p.value()
+		if err != nil {
+			return value, err
+		}
+		value.hash = append(value.hash, [2]string{key.text, entry.text})
+	}
+}
+
+// paren reads a (...) expression, keeping its string literals.
+func (p *psParser) paren() (psValue, error) {
+	value := psValue{isParen: true}
+	start := p.i
+	p.i++
+	for depth := 1; depth > 0; {
+		p.skipSpace(true)
+		switch {
+		case p.done():
+			return value, fmt.Errorf("unmatched ( in PowerShell snippet")
+		case p.peek() == '(':
+			depth++
+			p.i++
+		case p.peek() == ')':
+			depth--
+			p.i++
+		case p.peek() == '"' || p.peek() == '\'':
+			str, err := p.value()
+			if err != nil {
+				return value, err
+			}
+			value.strings = append(value.strings, str.text)
+		default:
+			p.i++
+		}
+	}
+	value.text = p.s[start:p.i]
+	return value, nil
+}
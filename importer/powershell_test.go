@@ -0,0 +1,76 @@
+package importer_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl/importer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromPowerShell(t *testing.T) {
+	t.Run("Copy as PowerShell", func(t *testing.T) {
+		snippet := "$session = New-Object Microsoft.PowerShell.Commands.WebRequestSession\r\n" +
+			"$session.UserAgent = \"Mozilla/5.0 (Windows NT 10.0; Win64; x64)\"\r\n" +
+			"$session.Cookies.Add((New-Object System.Net.Cookie(\"sid\", \"abc\", \"/\", \"api.example.com\")))\r\n" +
+			"Invoke-WebRequest -UseBasicParsing -Uri \"https://api.example.com/items?page=2\" `\r\n" +
+			"-Method \"POST\" `\r\n" +
+			"-WebSession $session `\r\n" +
+			"-Headers @{\r\n" +
+			"\"authority\"=\"api.example.com\"\r\n" +
+			"  \"method\"=\"POST\"\r\n" +
+			"  \"accept\"=\"application/json\"\r\n" +
+			"  \"x-note\"=\"say `\"hi`\" ''ok''\"\r\n" +
+			"} `\r\n" +
+			"-ContentType \"application/json\" `\r\n" +
+			"-Body \"{`\"name`\":`\"gocurl`\",`\"cost`\":`\"`$5`\"}\""
+
+		opts, err := importer.FromPowerShell(snippet)
+		require.NoError(t, err)
+		assert.Equal(t, "POST", opts.Method)
+		assert.Equal(t, "https://api.example.com/items?page=2", opts.URL)
+		assert.Equal(t, http.Header{
+			"Accept":       {"application/json"},
+			"X-Note":       {`say "hi" ''ok''`},
+			"Content-Type": {"application/json"},
+		}, opts.Headers)
+		assert.Equal(t, `{"name":"gocurl","cost":"$5"}`, opts.Body)
+		assert.Equal(t, "Mozilla/5.0 (Windows NT 10.0; Win64; x64)", opts.UserAgent)
+		require.Len(t, opts.Cookies, 1)
+		assert.Equal(t, "sid", opts.Cookies[0].Name)
+		assert.Equal(t, "abc", opts.Cookies[0].Value)
+	})
+
+	t.Run("Invoke-RestMethod with inline hashtable and byte body", func(t *testing.T) {
+		snippet := `irm https://example.com/upload?a=1 -Method put -Headers @{ Authorization = 'Bearer t''k'; "X-Id"=42 } ` +
+			`-Body ([System.Text.Encoding]::UTF8.GetBytes("caf` + "é" + `")) -TimeoutSec 30 -MaximumRedirection 3 -SkipCertificateCheck -OutFile out.json`
+
+		opts, err := importer.FromPowerShell(snippet)
+		require.NoError(t, err)
+		assert.Equal(t, "PUT", opts.Method)
+		assert.Equal(t, "https://example.com/upload?a=1", opts.URL)
+		assert.Equal(t, "Bearer t'k", opts.Headers.Get("Authorization"))
+		assert.Equal(t, "42", opts.Headers.Get("X-Id"))
+		assert.Equal(t, "café", opts.Body)
+		assert.Equal(t, 30*time.Second, opts.Timeout)
+		assert.True(t, opts.FollowRedirects)
+		assert.Equal(t, 3, opts.MaxRedirects)
+		assert.True(t, opts.Insecure)
+		assert.Equal(t, "out.json", opts.OutputFile)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, snippet := range []string{
+			`Write-Host "no request"`,
+			`Invoke-WebRequest -Method GET`,
+			`Invoke-WebRequest -Uri "https://example.com`,
+			`Invoke-WebRequest -Uri https://example.com -Headers @{ "a"="b"`,
+			"iwr https://a.example.com\niwr https://b.example.com",
+		} {
+			_, err := importer.FromPowerShell(snippet)
+			assert.Error(t, err, snippet)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package gocurl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// CurlJSONChan executes the curl command and streams the elements of the
+// top-level JSON array it responds with into the returned channel as they
+// are decoded, so exports far larger than memory can be processed as a
+// pipeline. Only the element being decoded is held.
+//
+// The element channel is closed once the array ends or the request fails;
+// the error channel then receives the error, if any, and is closed. The
+// consumer must drain the element channel or cancel ctx, which stops the
+// download.
+func CurlJSONChan[T any](ctx context.Context, command ...string) (<-chan T, <-chan error) {
+	items := make(chan T)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(items)
+		opts, err := parseCommand(command...)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defaults := withDefaults(ctx, opts)
+		if _, err := executeStream(ctx, opts, func(resp *http.Response) error {
+			// The body is streamed, so the hook only sees the headers
+			if err := preDecode(defaults, resp, nil); err != nil {
+				return err
+			}
+			return decodeJSONArray(ctx, json.NewDecoder(resp.Body), items)
+		}); err != nil {
+			errs <- err
+		}
+	}()
+	return items, errs
+}
+
+// decodeJSONArray sends the elements of the JSON array read by dec to items
+// until the array ends or ctx is done.
+func decodeJSONArray[T any](ctx context.Context, dec *json.Decoder, items chan<- T) error {
+	token, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("failed to decode JSON array: %v", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("failed to decode JSON array: response is not an array but starts with %v", token)
+	}
+	for i := 0; dec.More(); i++ {
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			return fmt.Errorf("failed to decode JSON array element %d: %v", i, err)
+		}
+		select {
+		case items <- item:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("failed to decode JSON array: %v", err)
+	}
+	return nil
+}
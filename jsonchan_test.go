@@ -0,0 +1,69 @@
+package gocurl_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type exportRow struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestCurlJSONChan(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/export":
+			io.WriteString(w, "[")
+			for i := 1; i <= 1000; i++ {
+				if i > 1 {
+					io.WriteString(w, ",")
+				}
+				fmt.Fprintf(w, `{"id":%d,"name":"row %d"}`, i, i)
+			}
+			io.WriteString(w, "]")
+		case "/object":
+			io.WriteString(w, `{"id":1}`)
+		case "/truncated":
+			io.WriteString(w, `[{"id":1},{"id":`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	items, errs := gocurl.CurlJSONChan[exportRow](ctx, server.URL+"/export")
+	count := 0
+	for row := range items {
+		count++
+		assert.Equal(t, count, row.ID)
+	}
+	require.NoError(t, <-errs)
+	assert.Equal(t, 1000, count)
+
+	for _, path := range []string{"/object", "/truncated", "/missing"} {
+		items, errs := gocurl.CurlJSONChan[exportRow](ctx, server.URL+path)
+		for range items {
+		}
+		assert.Error(t, <-errs, path)
+	}
+
+	t.Run("Cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		items, errs := gocurl.CurlJSONChan[exportRow](ctx, server.URL+"/export")
+		<-items
+		cancel()
+		for range items {
+		}
+		assert.ErrorIs(t, <-errs, context.Canceled)
+	})
+}
@@ -0,0 +1,89 @@
+package gocurl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// JSONMarshalFunc encodes v as JSON, like json.Marshal.
+type JSONMarshalFunc func(v interface{}) ([]byte, error)
+
+// JSONUnmarshalFunc decodes the JSON data into v, like json.Unmarshal.
+type JSONUnmarshalFunc func(data []byte, v interface{}) error
+
+// SetJSONEngine makes the JSON helpers of the client encode and decode
+// bodies with marshal and unmarshal instead of encoding/json, so hot paths
+// can use a faster engine such as sonic or go-json, which cache the
+// decoders they compile for each type:
+//
+//	client.SetJSONEngine(sonic.Marshal, sonic.Unmarshal)
+//
+// A nil function keeps encoding/json for that direction. It registers the
+// serializer NewJSONSerializer returns.
+func (c *Client) SetJSONEngine(marshal JSONMarshalFunc, unmarshal JSONUnmarshalFunc) *Client {
+	return c.RegisterSerializer(NewJSONSerializer(marshal, unmarshal))
+}
+
+// CurlJSON executes the curl command through the client and decodes the
+// JSON response body into v with the client's JSON engine. Like CurlJSON,
+// it checks the Content-Type of the response first when the defaults in
+// effect have a JSONContentType.
+func (c *Client) CurlJSON(ctx context.Context, v interface{}, command ...string) (*http.Response, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, err
+	}
+	opts.Silent = true
+	resp, body, err := c.Process(ctx, opts)
+	if err != nil {
+		return resp, err
+	}
+	return resp, c.decodeJSON(ctx, opts, resp, body, v)
+}
+
+// decodeJSON checks the response to opts and decodes its JSON body into v
+// with the client's JSON engine.
+func (c *Client) decodeJSON(ctx context.Context, opts *options.RequestOptions, resp *http.Response, body string, v interface{}) error {
+	body, err := loadSpooled(resp, body)
+	if err != nil {
+		return err
+	}
+	defaults := withDefaults(ctx, opts)
+	if err := checkJSONContentType(defaults.JSONContentType, resp, strings.NewReader(body)); err != nil {
+		return err
+	}
+	if err := preDecode(defaults, resp, func() io.Reader { return strings.NewReader(body) }); err != nil {
+		return err
+	}
+	if err := c.serializerFor("application/json").Unmarshal([]byte(body), v); err != nil {
+		return fmt.Errorf("failed to decode JSON response: %v", err)
+	}
+	return nil
+}
+
+// SendJSON executes the curl command through the client with in encoded by
+// the client's JSON engine as its body, like curl --json, and decodes the
+// JSON response body into out unless it is nil. The command's method
+// defaults to POST.
+func (c *Client) SendJSON(ctx context.Context, in, out interface{}, command ...string) (*http.Response, error) {
+	s := c.serializerFor("application/json")
+	resp, body, err := c.send(ctx, s, in, command)
+	if err != nil || out == nil {
+		return resp, err
+	}
+	if body, err = loadSpooled(resp, body); err != nil {
+		return resp, err
+	}
+	if err := preDecode(withDefaults(ctx, &options.RequestOptions{}), resp, func() io.Reader { return strings.NewReader(body) }); err != nil {
+		return resp, err
+	}
+	if err := s.Unmarshal([]byte(body), out); err != nil {
+		return resp, fmt.Errorf("failed to decode JSON response: %v", err)
+	}
+	return resp, nil
+}
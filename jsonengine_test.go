@@ -0,0 +1,108 @@
+package gocurl_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientJSONEngine(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"method": "` + r.Method + `", "type": "` + r.Header.Get("Content-Type") + `", "echo": ` + string(body) + `}`))
+	}))
+	defer server.Close()
+
+	var marshals, unmarshals int
+	client := gocurl.NewClient().SetJSONEngine(
+		func(v interface{}) ([]byte, error) {
+			marshals++
+			return json.Marshal(v)
+		},
+		func(data []byte, v interface{}) error {
+			unmarshals++
+			return json.Unmarshal(data, v)
+		},
+	)
+	ctx := context.Background()
+
+	var out struct {
+		Method string
+		Type   string
+		Echo   map[string]int
+	}
+	_, err := client.SendJSON(ctx, map[string]int{"n": 1}, &out, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "POST", out.Method)
+	assert.Equal(t, "application/json", out.Type)
+	assert.Equal(t, map[string]int{"n": 1}, out.Echo)
+
+	_, err = client.SendJSON(ctx, []int{}, nil, "-X", "PUT", server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, 2, marshals)
+	assert.Equal(t, 1, unmarshals)
+
+	// The default engine is encoding/json
+	_, err = gocurl.NewClient().SendJSON(ctx, map[string]int{"n": 2}, &out, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"n": 2}, out.Echo)
+	assert.Equal(t, 1, unmarshals)
+}
+
+func TestClientCurlJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	var v struct{ OK bool }
+	_, err := gocurl.NewClient().CurlJSON(context.Background(), &v, server.URL)
+	require.NoError(t, err)
+	assert.True(t, v.OK)
+
+	var items []int
+	_, err = gocurl.NewClient().CurlJSON(context.Background(), &items, server.URL)
+	assert.ErrorContains(t, err, "failed to decode JSON response")
+}
+
+type benchmarkItem struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// BenchmarkClientCurlJSON measures the client's JSON helper with the
+// default engine; run it with one set with SetJSONEngine to compare.
+func BenchmarkClientCurlJSON(b *testing.B) {
+	benchmarkClientCurlJSON(b, gocurl.NewClient())
+}
+
+// BenchmarkClientCurlJSONEngine measures the overhead of a custom engine,
+// here a plain wrapper of encoding/json.
+func BenchmarkClientCurlJSONEngine(b *testing.B) {
+	benchmarkClientCurlJSON(b, gocurl.NewClient().SetJSONEngine(json.Marshal, json.Unmarshal))
+}
+
+func benchmarkClientCurlJSON(b *testing.B, client *gocurl.Client) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":1,"name":"one"},{"id":2,"name":"two"},{"id":3,"name":"three"}]`))
+	}))
+	b.Cleanup(server.Close)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var items []benchmarkItem
+		if _, err := client.CurlJSON(ctx, &items, server.URL); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,475 @@
+// Package jsonschema validates JSON documents against JSON Schema.
+//
+// It implements the validation keywords shared by drafts 4 through 2020-12
+// that matter for API contracts: type, enum, const, the numeric, string,
+// array and object constraints, allOf/anyOf/oneOf/not and local $ref
+// references into definitions or $defs. Unknown keywords are ignored.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Violation describes a single place where a document breaks its schema.
+type Violation struct {
+	// Path is the location of the offending value, e.g. "$.items[2].id"
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// ValidationError lists every violation found in a document.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.String()
+	}
+	return fmt.Sprintf("schema validation failed: %s", strings.Join(parts, "; "))
+}
+
+// Schema is a compiled JSON Schema.
+type Schema struct {
+	root     interface{}
+	patterns map[string]*regexp.Regexp
+}
+
+// Compile parses a JSON Schema document.
+func Compile(schema []byte) (*Schema, error) {
+	var root interface{}
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	s := &Schema{root: root, patterns: map[string]*regexp.Regexp{}}
+	if err := s.compilePatterns(root); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// compilePatterns validates every "pattern" keyword up front.
+func (s *Schema) compilePatterns(node interface{}) error {
+	switch node := node.(type) {
+	case map[string]interface{}:
+		for key, value := range node {
+			if pattern, ok := value.(string); ok && key == "pattern" {
+				if err := s.compilePattern(pattern); err != nil {
+					return err
+				}
+				continue
+			}
+			if props, ok := value.(map[string]interface{}); ok && key == "patternProperties" {
+				for pattern := range props {
+					if err := s.compilePattern(pattern); err != nil {
+						return err
+					}
+				}
+			}
+			if err := s.compilePatterns(value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, value := range node {
+			if err := s.compilePatterns(value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) compilePattern(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid schema pattern %q: %v", pattern, err)
+	}
+	s.patterns[pattern] = re
+	return nil
+}
+
+// Validate checks the JSON document data and returns a *ValidationError
+// listing all violations, or nil if data is valid.
+func (s *Schema) Validate(data []byte) error {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return &ValidationError{Violations: []Violation{{Path: "$", Message: fmt.Sprintf("invalid JSON: %v", err)}}}
+	}
+	return s.ValidateValue(doc)
+}
+
+// ValidateValue checks an already decoded document, as produced by
+// encoding/json into an interface{}.
+func (s *Schema) ValidateValue(doc interface{}) error {
+	violations := s.validate(s.root, doc, "$")
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: violations}
+}
+
+func (s *Schema) validate(node interface{}, v interface{}, path string) []Violation {
+	switch node := node.(type) {
+	case bool:
+		if !node {
+			return []Violation{{Path: path, Message: "no value is allowed"}}
+		}
+		return nil
+	case map[string]interface{}:
+		return s.validateObjectSchema(node, v, path)
+	}
+	return nil
+}
+
+func (s *Schema) validateObjectSchema(schema map[string]interface{}, v interface{}, path string) []Violation {
+	var violations []Violation
+	fail := func(format string, args ...interface{}) {
+		violations = append(violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		target, err := s.resolve(ref)
+		if err != nil {
+			fail("%v", err)
+			return violations
+		}
+		violations = append(violations, s.validate(target, v, path)...)
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(t, v) {
+		fail("expected %s, got %s", typeNames(t), typeOf(v))
+		return violations
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range enum {
+			if equalJSON(candidate, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value %s is not one of %s", encode(v), encode(enum))
+		}
+	}
+	if c, ok := schema["const"]; ok && !equalJSON(c, v) {
+		fail("value %s must be %s", encode(v), encode(c))
+	}
+
+	switch v := v.(type) {
+	case float64:
+		violations = append(violations, s.validateNumber(schema, v, path)...)
+	case string:
+		violations = append(violations, s.validateString(schema, v, path)...)
+	case []interface{}:
+		violations = append(violations, s.validateArray(schema, v, path)...)
+	case map[string]interface{}:
+		violations = append(violations, s.validateObject(schema, v, path)...)
+	}
+
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			violations = append(violations, s.validate(sub, v, path)...)
+		}
+	}
+	if any, ok := schema["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range any {
+			if len(s.validate(sub, v, path)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("value does not match any schema of anyOf")
+		}
+	}
+	if one, ok := schema["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, sub := range one {
+			if len(s.validate(sub, v, path)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			fail("value matches %d schemas of oneOf, expected exactly 1", matches)
+		}
+	}
+	if not, ok := schema["not"]; ok && len(s.validate(not, v, path)) == 0 {
+		fail("value must not match the schema of not")
+	}
+
+	return violations
+}
+
+func (s *Schema) validateNumber(schema map[string]interface{}, v float64, path string) []Violation {
+	var violations []Violation
+	fail := func(format string, args ...interface{}) {
+		violations = append(violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if min, ok := schema["minimum"].(float64); ok && v < min {
+		fail("value %v is less than minimum %v", v, min)
+	}
+	if max, ok := schema["maximum"].(float64); ok && v > max {
+		fail("value %v is greater than maximum %v", v, max)
+	}
+	switch min := schema["exclusiveMinimum"].(type) {
+	case float64:
+		if v <= min {
+			fail("value %v must be greater than %v", v, min)
+		}
+	case bool:
+		if m, ok := schema["minimum"].(float64); min && ok && v == m {
+			fail("value %v must be greater than %v", v, m)
+		}
+	}
+	switch max := schema["exclusiveMaximum"].(type) {
+	case float64:
+		if v >= max {
+			fail("value %v must be less than %v", v, max)
+		}
+	case bool:
+		if m, ok := schema["maximum"].(float64); max && ok && v == m {
+			fail("value %v must be less than %v", v, m)
+		}
+	}
+	if multiple, ok := schema["multipleOf"].(float64); ok && multiple > 0 {
+		if q := v / multiple; math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("value %v is not a multiple of %v", v, multiple)
+		}
+	}
+	return violations
+}
+
+func (s *Schema) validateString(schema map[string]interface{}, v string, path string) []Violation {
+	var violations []Violation
+	fail := func(format string, args ...interface{}) {
+		violations = append(violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(v)
+	if min, ok := schema["minLength"].(float64); ok && length < int(min) {
+		fail("length %d is shorter than minLength %v", length, min)
+	}
+	if max, ok := schema["maxLength"].(float64); ok && length > int(max) {
+		fail("length %d is longer than maxLength %v", length, max)
+	}
+	if pattern, ok := schema["pattern"].(string); ok && !s.patterns[pattern].MatchString(v) {
+		fail("value %q does not match pattern %q", v, pattern)
+	}
+	return violations
+}
+
+func (s *Schema) validateArray(schema map[string]interface{}, v []interface{}, path string) []Violation {
+	var violations []Violation
+	fail := func(format string, args ...interface{}) {
+		violations = append(violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if min, ok := schema["minItems"].(float64); ok && len(v) < int(min) {
+		fail("array has %d items, fewer than minItems %v", len(v), min)
+	}
+	if max, ok := schema["maxItems"].(float64); ok && len(v) > int(max) {
+		fail("array has %d items, more than maxItems %v", len(v), max)
+	}
+	if unique, ok := schema["uniqueItems"].(bool); ok && unique {
+		for i := range v {
+			for j := i + 1; j < len(v); j++ {
+				if equalJSON(v[i], v[j]) {
+					fail("items %d and %d are equal but uniqueItems is set", i, j)
+				}
+			}
+		}
+	}
+
+	// prefixItems (2020-12) or the array form of items (earlier drafts)
+	// validate by position; the remaining items use items or additionalItems.
+	prefix, _ := schema["prefixItems"].([]interface{})
+	rest, hasRest := schema["items"]
+	if tuple, ok := rest.([]interface{}); ok {
+		prefix = tuple
+		rest, hasRest = schema["additionalItems"]
+	}
+	for i, item := range v {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		if i < len(prefix) {
+			violations = append(violations, s.validate(prefix[i], item, itemPath)...)
+		} else if hasRest {
+			violations = append(violations, s.validate(rest, item, itemPath)...)
+		}
+	}
+
+	if contains, ok := schema["contains"]; ok {
+		found := false
+		for i, item := range v {
+			if len(s.validate(contains, item, fmt.Sprintf("%s[%d]", path, i))) == 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("no item matches the schema of contains")
+		}
+	}
+	return violations
+}
+
+func (s *Schema) validateObject(schema map[string]interface{}, v map[string]interface{}, path string) []Violation {
+	var violations []Violation
+	fail := func(format string, args ...interface{}) {
+		violations = append(violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, present := v[name]; !present {
+					fail("missing required property %q", name)
+				}
+			}
+		}
+	}
+	if min, ok := schema["minProperties"].(float64); ok && len(v) < int(min) {
+		fail("object has %d properties, fewer than minProperties %v", len(v), min)
+	}
+	if max, ok := schema["maxProperties"].(float64); ok && len(v) > int(max) {
+		fail("object has %d properties, more than maxProperties %v", len(v), max)
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	patternProperties, _ := schema["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := schema["additionalProperties"]
+
+	keys := make([]string, 0, len(v))
+	for key := range v {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := v[key]
+		childPath := path + "." + key
+		matched := false
+
+		if sub, ok := properties[key]; ok {
+			matched = true
+			violations = append(violations, s.validate(sub, value, childPath)...)
+		}
+		for pattern, sub := range patternProperties {
+			if s.patterns[pattern].MatchString(key) {
+				matched = true
+				violations = append(violations, s.validate(sub, value, childPath)...)
+			}
+		}
+		if !matched && hasAdditional {
+			if allowed, ok := additional.(bool); ok && !allowed {
+				fail("additional property %q is not allowed", key)
+			} else {
+				violations = append(violations, s.validate(additional, value, childPath)...)
+			}
+		}
+	}
+	return violations
+}
+
+// resolve looks up a local reference such as "#/definitions/User".
+func (s *Schema) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref %q: only local references are supported", ref)
+	}
+	node := s.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if node, ok = obj[part]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	return node, nil
+}
+
+func matchesType(t interface{}, v interface{}) bool {
+	switch t := t.(type) {
+	case string:
+		return matchesTypeName(t, v)
+	case []interface{}:
+		for _, name := range t {
+			if name, ok := name.(string); ok && matchesTypeName(name, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesTypeName(name string, v interface{}) bool {
+	switch name {
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	}
+	return typeOf(v) == name
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func typeNames(t interface{}) string {
+	if list, ok := t.([]interface{}); ok {
+		names := make([]string, len(list))
+		for i, name := range list {
+			names[i] = fmt.Sprint(name)
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func equalJSON(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func encode(v interface{}) string {
+	encoded, _ := json.Marshal(v)
+	return string(encoded)
+}
@@ -0,0 +1,74 @@
+package jsonschema_test
+
+import (
+	"testing"
+
+	"github.com/maniartech/gocurl/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const userSchema = `{
+	"type": "object",
+	"required": ["id", "email", "roles"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "integer", "minimum": 1},
+		"email": {"type": "string", "pattern": "^[^@]+@[^@]+$"},
+		"name": {"type": ["string", "null"], "maxLength": 5},
+		"roles": {"type": "array", "minItems": 1, "uniqueItems": true, "items": {"enum": ["admin", "user"]}},
+		"address": {"$ref": "#/$defs/address"}
+	},
+	"$defs": {
+		"address": {
+			"type": "object",
+			"required": ["city"],
+			"properties": {"city": {"type": "string", "minLength": 1}}
+		}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	schema, err := jsonschema.Compile([]byte(userSchema))
+	require.NoError(t, err)
+
+	t.Run("Valid document", func(t *testing.T) {
+		err := schema.Validate([]byte(`{"id":1,"email":"a@b.c","name":null,"roles":["admin"],"address":{"city":"Pune"}}`))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Violations are reported with paths", func(t *testing.T) {
+		err := schema.Validate([]byte(`{"id":1.5,"email":"nope","name":"toolong","roles":["root","root"],"address":{},"extra":true}`))
+		require.Error(t, err)
+
+		verr, ok := err.(*jsonschema.ValidationError)
+		require.True(t, ok)
+		assert.ElementsMatch(t, []jsonschema.Violation{
+			{Path: "$", Message: `additional property "extra" is not allowed`},
+			{Path: "$.id", Message: "expected integer, got number"},
+			{Path: "$.email", Message: `value "nope" does not match pattern "^[^@]+@[^@]+$"`},
+			{Path: "$.name", Message: "length 7 is longer than maxLength 5"},
+			{Path: "$.roles", Message: "items 0 and 1 are equal but uniqueItems is set"},
+			{Path: "$.roles[0]", Message: `value "root" is not one of ["admin","user"]`},
+			{Path: "$.roles[1]", Message: `value "root" is not one of ["admin","user"]`},
+			{Path: "$.address", Message: `missing required property "city"`},
+		}, verr.Violations)
+	})
+
+	t.Run("Combinators", func(t *testing.T) {
+		schema, err := jsonschema.Compile([]byte(`{"oneOf":[{"type":"string"},{"type":"number","exclusiveMinimum":0}],"not":{"const":"x"}}`))
+		require.NoError(t, err)
+		assert.NoError(t, schema.Validate([]byte(`"a"`)))
+		assert.NoError(t, schema.Validate([]byte(`3`)))
+		assert.Error(t, schema.Validate([]byte(`0`)))
+		assert.Error(t, schema.Validate([]byte(`"x"`)))
+		assert.Error(t, schema.Validate([]byte(`not json`)))
+	})
+
+	t.Run("Invalid schema", func(t *testing.T) {
+		_, err := jsonschema.Compile([]byte(`{"pattern":"("}`))
+		assert.Error(t, err)
+		_, err = jsonschema.Compile([]byte(`{`))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,23 @@
+package middlewares
+
+import "net/http"
+
+// RoundTripFunc sends a request and returns its response.
+type RoundTripFunc func(*http.Request) (*http.Response, error)
+
+// Interceptor wraps every attempt at sending a request, including retries.
+// It may inspect or replace the request before calling next and the
+// response after. The request context carries the values gocurl propagates,
+// such as the request ID, tenant and attempt number.
+type Interceptor func(req *http.Request, next RoundTripFunc) (*http.Response, error)
+
+// Chain returns a RoundTripFunc running interceptors in order around send.
+func Chain(send RoundTripFunc, interceptors ...Interceptor) RoundTripFunc {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], send
+		send = func(req *http.Request) (*http.Response, error) {
+			return interceptor(req, next)
+		}
+	}
+	return send
+}
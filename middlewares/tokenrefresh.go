@@ -0,0 +1,155 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultRefreshTimeout bounds a refresh when TokenRefresher.RefreshTimeout
+// is not set.
+const defaultRefreshTimeout = 30 * time.Second
+
+// RefreshFunc obtains a new access token, e.g. from an OAuth2 refresh
+// token grant.
+type RefreshFunc func(ctx context.Context) (string, error)
+
+// TokenRefresher authenticates requests with a bearer token and refreshes
+// it when a server answers 401 Unauthorized, then retries the request with
+// the new token. Concurrent requests failing with the same token share a
+// single refresh. Share one TokenRefresher among the requests using the
+// token:
+//
+//	refresher := middlewares.NewTokenRefresher(token, refresh)
+//	opts := options.NewRequestOptionsBuilder().AddInterceptor(refresher.Interceptor())
+type TokenRefresher struct {
+	refresh RefreshFunc
+
+	// MaxRefreshes is the number of refreshes a request tries while it
+	// keeps being answered 401, after which its last response is
+	// returned; 1 by default
+	MaxRefreshes int
+
+	// RefreshTimeout bounds each refresh, which is shared by the requests
+	// waiting for it and so is not cancelled with the request that started
+	// it; 30s by default
+	RefreshTimeout time.Duration
+
+	mu       sync.Mutex
+	token    string
+	inflight *refreshCall
+}
+
+type refreshCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// NewTokenRefresher creates a TokenRefresher starting with token, which may
+// be empty to fetch one on the first 401.
+func NewTokenRefresher(token string, refresh RefreshFunc) *TokenRefresher {
+	return &TokenRefresher{refresh: refresh, token: token}
+}
+
+// Token returns the current token.
+func (t *TokenRefresher) Token() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.token
+}
+
+// Interceptor returns the interceptor authenticating requests with the
+// token. Requests whose body cannot be sent again are not retried.
+func (t *TokenRefresher) Interceptor() Interceptor {
+	return func(req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		token := t.Token()
+		resp, err := next(withBearer(req, token))
+		maxRefreshes := t.MaxRefreshes
+		if maxRefreshes <= 0 {
+			maxRefreshes = 1
+		}
+		for i := 0; i < maxRefreshes && err == nil && resp.StatusCode == http.StatusUnauthorized; i++ {
+			if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+				return resp, nil
+			}
+			if token, err = t.refreshed(req.Context(), token); err != nil {
+				resp.Body.Close()
+				return nil, fmt.Errorf("failed to refresh token: %v", err)
+			}
+			retry := req.Clone(req.Context())
+			if req.GetBody != nil {
+				if retry.Body, err = req.GetBody(); err != nil {
+					resp.Body.Close()
+					return nil, err
+				}
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			resp, err = next(withBearer(retry, token))
+		}
+		return resp, err
+	}
+}
+
+// refreshed returns the token replacing stale, refreshing it unless another
+// request already did or is doing so. Requests stop waiting when their ctx
+// is done, while the refresh carries on for the others.
+func (t *TokenRefresher) refreshed(ctx context.Context, stale string) (string, error) {
+	t.mu.Lock()
+	if t.token != stale {
+		token := t.token
+		t.mu.Unlock()
+		return token, nil
+	}
+	call := t.inflight
+	leader := call == nil
+	if leader {
+		call = &refreshCall{done: make(chan struct{})}
+		t.inflight = call
+	}
+	t.mu.Unlock()
+
+	if leader {
+		go t.run(context.WithoutCancel(ctx), call)
+	}
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// run refreshes the token for call, the refresh the requests waiting on it
+// share.
+func (t *TokenRefresher) run(ctx context.Context, call *refreshCall) {
+	timeout := t.RefreshTimeout
+	if timeout <= 0 {
+		timeout = defaultRefreshTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	call.token, call.err = t.refresh(ctx)
+	t.mu.Lock()
+	t.inflight = nil
+	if call.err == nil {
+		t.token = call.token
+	}
+	t.mu.Unlock()
+	close(call.done)
+}
+
+// withBearer sets the Authorization header of req to token, unless token is
+// empty.
+func withBearer(req *http.Request, token string) *http.Request {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
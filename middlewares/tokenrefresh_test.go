@@ -0,0 +1,159 @@
+package middlewares_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/middlewares"
+	"github.com/maniartech/gocurl/options"
+)
+
+func TestTokenRefresher(t *testing.T) {
+	var valid atomic.Value
+	valid.Store("fresh")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+valid.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	var refreshes int32
+	started, release := make(chan struct{}), make(chan struct{})
+	refresher := middlewares.NewTokenRefresher("stale", func(ctx context.Context) (string, error) {
+		if atomic.AddInt32(&refreshes, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return "fresh", nil
+	})
+	opts := options.NewRequestOptionsBuilder().
+		SetURL(server.URL).
+		SetMethod("POST").
+		SetBody("payload").
+		AddInterceptor(refresher.Interceptor()).
+		Build()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, body, err := gocurl.Process(context.Background(), opts)
+			if err == nil && (resp.StatusCode != http.StatusOK || body != "payload") {
+				err = errors.New(resp.Status + " " + body)
+			}
+			errs <- err
+		}()
+	}
+	<-started
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if n := atomic.LoadInt32(&refreshes); n != 1 {
+		t.Errorf("refreshed %d times, want once", n)
+	}
+	if refresher.Token() != "fresh" {
+		t.Errorf("Token() = %q", refresher.Token())
+	}
+}
+
+func TestTokenRefresherCancelledLeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	refresher := middlewares.NewTokenRefresher("stale", func(ctx context.Context) (string, error) {
+		close(started)
+		select {
+		case <-release:
+			return "fresh", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	})
+	opts := options.NewRequestOptionsBuilder().SetURL(server.URL).AddInterceptor(refresher.Interceptor()).Build()
+
+	// The request starting the refresh gives up, the one waiting for it
+	// still gets the new token
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, _, err := gocurl.Process(ctx, opts)
+		leader <- err
+	}()
+	<-started
+	waiter := make(chan error, 1)
+	go func() {
+		resp, _, err := gocurl.Process(context.Background(), opts)
+		if err == nil && resp.StatusCode != http.StatusOK {
+			err = errors.New(resp.Status)
+		}
+		waiter <- err
+	}()
+	cancel()
+	if err := <-leader; err == nil {
+		t.Error("cancelled request succeeded")
+	}
+	close(release)
+	if err := <-waiter; err != nil {
+		t.Errorf("waiting request: %v", err)
+	}
+	if refresher.Token() != "fresh" {
+		t.Errorf("Token() = %q", refresher.Token())
+	}
+}
+
+func TestTokenRefresherGivesUp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	var refreshes int32
+	refresher := middlewares.NewTokenRefresher("", func(ctx context.Context) (string, error) {
+		return "token" + string(rune('0'+atomic.AddInt32(&refreshes, 1))), nil
+	})
+	refresher.MaxRefreshes = 3
+	opts := options.NewRequestOptionsBuilder().SetURL(server.URL).AddInterceptor(refresher.Interceptor()).Build()
+
+	resp, _, err := gocurl.Process(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", resp.StatusCode)
+	}
+	if refreshes != 3 {
+		t.Errorf("refreshed %d times, want 3", refreshes)
+	}
+
+	failing := middlewares.NewTokenRefresher("", func(ctx context.Context) (string, error) {
+		return "", errors.New("invalid_grant")
+	})
+	opts = options.NewRequestOptionsBuilder().SetURL(server.URL).AddInterceptor(failing.Interceptor()).Build()
+	if _, _, err := gocurl.Process(context.Background(), opts); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("err = %v, want the refresh error", err)
+	}
+}
@@ -0,0 +1,147 @@
+package gocurl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// mirrorState is the validator of a mirrored file, stored next to it.
+type mirrorState struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// mirrorStatePath returns the sidecar file holding the state of the mirror
+// at path.
+func mirrorStatePath(path string) string {
+	return path + ".mirror.json"
+}
+
+// CurlMirror executes the curl command to keep the file at path in sync
+// with the remote one, downloading it only when it changed. It reports
+// whether the file was updated.
+//
+// The ETag and Last-Modified of the last download are stored in a sidecar
+// file, path with a .mirror.json suffix, and sent back as If-None-Match and
+// If-Modified-Since so the server answers 304 Not Modified when nothing
+// changed. A changed file is downloaded to a temporary file next to path
+// and renamed over it, so readers never see a partial file. The file is
+// downloaded again when it or its sidecar is missing, or when the URL
+// changed.
+func CurlMirror(ctx context.Context, path string, command ...string) (*http.Response, bool, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	state, err := loadMirrorState(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if state != nil && state.URL == opts.URL {
+		if opts.Headers == nil {
+			opts.Headers = http.Header{}
+		}
+		if state.ETag != "" && opts.Headers.Get("If-None-Match") == "" {
+			opts.Headers.Set("If-None-Match", state.ETag)
+		}
+		if state.LastModified != "" && opts.Headers.Get("If-Modified-Since") == "" {
+			opts.Headers.Set("If-Modified-Since", state.LastModified)
+		}
+	}
+
+	updated := false
+	resp, err := executeStream(ctx, opts, func(resp *http.Response) error {
+		if resp.StatusCode == http.StatusNotModified {
+			return nil
+		}
+		if err := replaceFile(path, resp.Body); err != nil {
+			return err
+		}
+		updated = true
+		return saveMirrorState(path, &mirrorState{
+			URL:          opts.URL,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		})
+	})
+	return resp, updated, err
+}
+
+// loadMirrorState returns the state of the mirror at path, or nil when the
+// file or its state is missing.
+func loadMirrorState(path string) (*mirrorState, error) {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	data, err := os.ReadFile(mirrorStatePath(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mirror state: %v", err)
+	}
+	var state mirrorState
+	if err := json.Unmarshal(data, &state); err != nil {
+		// A corrupt state only costs a full download
+		return nil, nil
+	}
+	return &state, nil
+}
+
+func saveMirrorState(path string, state *mirrorState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to save mirror state: %v", err)
+	}
+	if err := replaceFile(mirrorStatePath(path), bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to save mirror state: %v", err)
+	}
+	return nil
+}
+
+// replaceFile atomically replaces the file at path with the content of r,
+// keeping the permissions of the existing file.
+func replaceFile(path string, r io.Reader) error {
+	mode := fs.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), mode)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package gocurl_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurlMirror(t *testing.T) {
+	content, etag := "v1", `"1"`
+	var conditional []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conditional = append(conditional, r.Header.Get("If-None-Match"))
+		if r.URL.Path == "/broken" {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Mon, 05 Oct 2026 10:00:00 GMT")
+		io.WriteString(w, content)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	_, updated, err := gocurl.CurlMirror(ctx, path, server.URL+"/config")
+	require.NoError(t, err)
+	assert.True(t, updated)
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, "v1", string(data))
+	assert.FileExists(t, path+".mirror.json")
+
+	resp, updated, err := gocurl.CurlMirror(ctx, path, server.URL+"/config")
+	require.NoError(t, err)
+	assert.False(t, updated)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	assert.Equal(t, []string{"", `"1"`}, conditional)
+
+	content, etag = "v2", `"2"`
+	_, updated, err = gocurl.CurlMirror(ctx, path, server.URL+"/config")
+	require.NoError(t, err)
+	assert.True(t, updated)
+	data, _ = os.ReadFile(path)
+	assert.Equal(t, "v2", string(data))
+
+	t.Run("Missing file", func(t *testing.T) {
+		require.NoError(t, os.Remove(path))
+		_, updated, err := gocurl.CurlMirror(ctx, path, server.URL+"/config")
+		require.NoError(t, err)
+		assert.True(t, updated)
+		assert.Empty(t, conditional[len(conditional)-1])
+	})
+
+	t.Run("Failures keep the file", func(t *testing.T) {
+		_, updated, err := gocurl.CurlMirror(ctx, path, server.URL+"/broken")
+		require.Error(t, err)
+		assert.False(t, updated)
+		data, _ := os.ReadFile(path)
+		assert.Equal(t, "v2", string(data))
+
+		entries, _ := os.ReadDir(filepath.Dir(path))
+		assert.Len(t, entries, 2)
+	})
+}
@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package gocurl
+
+import "errors"
+
+// mmapFile is not supported on this platform; uploads fall back to reads.
+func mmapFile(path string) ([]byte, func() error, error) {
+	return nil, nil, errors.New("memory-mapped uploads are not supported on this platform")
+}
@@ -0,0 +1,278 @@
+package gocurl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// MonitorState is the health of a monitored endpoint.
+type MonitorState int
+
+const (
+	// MonitorUnknown is the state before the first check completes.
+	MonitorUnknown MonitorState = iota
+	// MonitorUp means the endpoint answers as expected.
+	MonitorUp
+	// MonitorDown means FailureThreshold consecutive checks failed.
+	MonitorDown
+)
+
+func (s MonitorState) String() string {
+	switch s {
+	case MonitorUnknown:
+		return "unknown"
+	case MonitorUp:
+		return "up"
+	case MonitorDown:
+		return "down"
+	}
+	return fmt.Sprintf("MonitorState(%d)", int(s))
+}
+
+// MarshalJSON encodes the state by name.
+func (s MonitorState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// MonitorConfig controls how a Monitor polls its endpoint.
+type MonitorConfig struct {
+	// Interval between checks (default 30s)
+	Interval time.Duration
+
+	// ExpectStatus lists the status codes counted as healthy; when empty any
+	// status below 400 is healthy
+	ExpectStatus []int
+
+	// FailureThreshold is the number of consecutive failed checks after which
+	// the endpoint is considered down (default 1)
+	FailureThreshold int
+
+	// HistorySize is the number of checks kept in History (default 1000)
+	HistorySize int
+
+	// OnCheck is called after every check
+	OnCheck func(check MonitorCheck)
+
+	// OnStateChange is called whenever the state changes
+	OnStateChange func(event MonitorEvent)
+
+	// WebhookURL receives every MonitorEvent as a JSON POST
+	WebhookURL string
+}
+
+// MonitorCheck is the outcome of a single probe.
+type MonitorCheck struct {
+	Time       time.Time     `json:"time"`
+	Latency    time.Duration `json:"latency"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Healthy    bool          `json:"healthy"`
+}
+
+// MonitorEvent describes a state change.
+type MonitorEvent struct {
+	URL   string       `json:"url"`
+	From  MonitorState `json:"from"`
+	To    MonitorState `json:"to"`
+	Check MonitorCheck `json:"check"`
+}
+
+// MonitorStats summarizes the checks performed so far.
+type MonitorStats struct {
+	State       MonitorState  `json:"state"`
+	Checks      int           `json:"checks"`
+	Healthy     int           `json:"healthy"`
+	Uptime      float64       `json:"uptime"` // fraction of healthy checks
+	MeanLatency time.Duration `json:"mean_latency"`
+	Since       time.Time     `json:"since"` // time of the last state change
+}
+
+// Monitor polls an endpoint, keeps its uptime and latency history and
+// reports state changes through callbacks and webhooks.
+type Monitor struct {
+	opts   *options.RequestOptions
+	config MonitorConfig
+	client *http.Client
+
+	mu           sync.Mutex
+	state        MonitorState
+	since        time.Time
+	failures     int
+	checks       int
+	healthy      int
+	totalLatency time.Duration
+	history      []MonitorCheck
+}
+
+// NewMonitor returns a Monitor for the request described by opts.
+func NewMonitor(opts *options.RequestOptions, config MonitorConfig) (*Monitor, error) {
+	opts = opts.Clone()
+	opts.Silent = true
+	opts.OutputFile = ""
+	if err := ValidateOptions(opts); err != nil {
+		return nil, err
+	}
+	client, err := CreateHTTPClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Interval <= 0 {
+		config.Interval = 30 * time.Second
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 1
+	}
+	if config.HistorySize <= 0 {
+		config.HistorySize = 1000
+	}
+
+	return &Monitor{opts: opts, config: config, client: client, since: time.Now()}, nil
+}
+
+// Run checks the endpoint immediately and then every Interval until ctx is
+// done.
+func (m *Monitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check probes the endpoint once and updates the state.
+func (m *Monitor) Check(ctx context.Context) MonitorCheck {
+	check := MonitorCheck{Time: time.Now()}
+	resp, _, err := processWithClient(ctx, m.client, m.opts)
+	check.Latency = time.Since(check.Time)
+
+	if err != nil {
+		check.Error = err.Error()
+	} else {
+		check.StatusCode = resp.StatusCode
+		check.Healthy = m.expected(resp.StatusCode)
+		if !check.Healthy {
+			check.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+		}
+	}
+
+	if ctx.Err() != nil && !check.Healthy {
+		// The monitor is shutting down; this is not a real failure
+		return check
+	}
+
+	event := m.record(check)
+	if m.config.OnCheck != nil {
+		m.config.OnCheck(check)
+	}
+	if event != nil {
+		m.notify(ctx, *event)
+	}
+	return check
+}
+
+func (m *Monitor) expected(status int) bool {
+	if len(m.config.ExpectStatus) == 0 {
+		return status < 400
+	}
+	for _, code := range m.config.ExpectStatus {
+		if status == code {
+			return true
+		}
+	}
+	return false
+}
+
+// record stores check and returns the resulting state change, if any.
+func (m *Monitor) record(check MonitorCheck) *MonitorEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.checks++
+	m.totalLatency += check.Latency
+	m.history = append(m.history, check)
+	if len(m.history) > m.config.HistorySize {
+		m.history = m.history[len(m.history)-m.config.HistorySize:]
+	}
+
+	next := m.state
+	if check.Healthy {
+		m.healthy++
+		m.failures = 0
+		next = MonitorUp
+	} else {
+		m.failures++
+		if m.failures >= m.config.FailureThreshold {
+			next = MonitorDown
+		}
+	}
+
+	if next == m.state {
+		return nil
+	}
+	event := &MonitorEvent{URL: m.opts.URL, From: m.state, To: next, Check: check}
+	m.state = next
+	m.since = check.Time
+	return event
+}
+
+func (m *Monitor) notify(ctx context.Context, event MonitorEvent) {
+	if m.config.OnStateChange != nil {
+		m.config.OnStateChange(event)
+	}
+	if m.config.WebhookURL == "" {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	webhook := options.NewRequestOptions(m.config.WebhookURL)
+	webhook.Method = "POST"
+	webhook.Body = string(payload)
+	webhook.Headers = http.Header{"Content-Type": {"application/json"}}
+	webhook.Silent = true
+	// Delivery failures must not stop the monitor
+	Process(ctx, webhook)
+}
+
+// State returns the current state.
+func (m *Monitor) State() MonitorState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state
+}
+
+// History returns the most recent checks, oldest first.
+func (m *Monitor) History() []MonitorCheck {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MonitorCheck(nil), m.history...)
+}
+
+// Stats summarizes all checks performed so far.
+func (m *Monitor) Stats() MonitorStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := MonitorStats{State: m.state, Checks: m.checks, Healthy: m.healthy, Since: m.since}
+	if m.checks > 0 {
+		stats.Uptime = float64(m.healthy) / float64(m.checks)
+		stats.MeanLatency = m.totalLatency / time.Duration(m.checks)
+	}
+	return stats
+}
@@ -0,0 +1,91 @@
+package gocurl_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonitor(t *testing.T) {
+	statuses := []int{200, 500, 500, 500, 204}
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		status := statuses[calls%len(statuses)]
+		calls++
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	var webhookEvents []map[string]interface{}
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&event)
+		mu.Lock()
+		webhookEvents = append(webhookEvents, event)
+		mu.Unlock()
+	}))
+	defer webhook.Close()
+
+	var events []gocurl.MonitorEvent
+	monitor, err := gocurl.NewMonitor(options.NewRequestOptions(server.URL), gocurl.MonitorConfig{
+		ExpectStatus:     []int{200, 204},
+		FailureThreshold: 2,
+		HistorySize:      3,
+		WebhookURL:       webhook.URL,
+		OnStateChange: func(event gocurl.MonitorEvent) {
+			events = append(events, event)
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, gocurl.MonitorUnknown, monitor.State())
+
+	var states []gocurl.MonitorState
+	for range statuses {
+		monitor.Check(context.Background())
+		states = append(states, monitor.State())
+	}
+
+	assert.Equal(t, []gocurl.MonitorState{
+		gocurl.MonitorUp, gocurl.MonitorUp, gocurl.MonitorDown, gocurl.MonitorDown, gocurl.MonitorUp,
+	}, states)
+
+	require.Len(t, events, 3)
+	assert.Equal(t, gocurl.MonitorDown, events[1].To)
+	assert.Equal(t, 500, events[1].Check.StatusCode)
+	assert.Equal(t, "unexpected status 500", events[1].Check.Error)
+
+	require.Len(t, webhookEvents, 3)
+	assert.Equal(t, "up", webhookEvents[1]["from"])
+	assert.Equal(t, "down", webhookEvents[1]["to"])
+
+	history := monitor.History()
+	require.Len(t, history, 3)
+	assert.Equal(t, 204, history[2].StatusCode)
+
+	stats := monitor.Stats()
+	assert.Equal(t, 5, stats.Checks)
+	assert.Equal(t, 2, stats.Healthy)
+	assert.InDelta(t, 0.4, stats.Uptime, 0.001)
+
+	t.Run("Run polls until cancelled", func(t *testing.T) {
+		monitor, err := gocurl.NewMonitor(options.NewRequestOptions(server.URL), gocurl.MonitorConfig{Interval: 5 * time.Millisecond})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 40*time.Millisecond)
+		defer cancel()
+		require.NoError(t, monitor.Run(ctx))
+		assert.Greater(t, monitor.Stats().Checks, 2)
+	})
+}
@@ -0,0 +1,111 @@
+package gocurl
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"sort"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// multipartBody is a multipart/form-data body that streams its file part
+// from disk instead of buffering it. Form fields come first, followed by the
+// file; head and tail hold everything around the file content.
+type multipartBody struct {
+	head, tail  []byte
+	path        string
+	size        int64 // -1 when the file size is unknown
+	mmap        bool
+	contentType string
+}
+
+// switchWriter forwards writes to w, which can be swapped between writes.
+type switchWriter struct {
+	w io.Writer
+}
+
+func (s *switchWriter) Write(p []byte) (int, error) {
+	return s.w.Write(p)
+}
+
+func newMultipartBody(upload *options.FileUpload, form url.Values, mmap bool) (*multipartBody, error) {
+	info, err := os.Stat(upload.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file for upload: %v", err)
+	}
+
+	var head, tail bytes.Buffer
+	out := &switchWriter{w: &head}
+	w := multipart.NewWriter(out)
+
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range form[key] {
+			if err := w.WriteField(key, value); err != nil {
+				return nil, fmt.Errorf("failed to write form field: %v", err)
+			}
+		}
+	}
+
+	if _, err := w.CreateFormFile(upload.FieldName, upload.FileName); err != nil {
+		return nil, fmt.Errorf("failed to create form file: %v", err)
+	}
+
+	// The file content goes between head and tail
+	out.w = &tail
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close multipart writer: %v", err)
+	}
+
+	size := int64(-1)
+	if info.Mode().IsRegular() {
+		size = info.Size()
+	}
+
+	return &multipartBody{
+		head:        head.Bytes(),
+		tail:        tail.Bytes(),
+		path:        upload.FilePath,
+		size:        size,
+		mmap:        mmap,
+		contentType: w.FormDataContentType(),
+	}, nil
+}
+
+// contentLength returns the exact body length, or -1 if it is unknown.
+func (m *multipartBody) contentLength() int64 {
+	if m.size < 0 {
+		return -1
+	}
+	return int64(len(m.head)) + m.size + int64(len(m.tail))
+}
+
+// open returns a fresh reader over the whole body. It can be called again
+// to resend the body, e.g. on retries.
+func (m *multipartBody) open() (io.ReadCloser, error) {
+	file, err := openUploadFile(m.path, m.mmap && m.size > 0)
+	if err != nil {
+		return nil, err
+	}
+	return &multipartReader{
+		Reader: io.MultiReader(bytes.NewReader(m.head), file, bytes.NewReader(m.tail)),
+		file:   file,
+	}, nil
+}
+
+type multipartReader struct {
+	io.Reader
+	file io.Closer
+}
+
+func (r *multipartReader) Close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,99 @@
+package gocurl_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamingMultipartUpload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.txt")
+	require.NoError(t, os.WriteFile(path, []byte("file content"), 0644))
+
+	t.Run("Fields, file and content length", func(t *testing.T) {
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			require.NoError(t, r.ParseMultipartForm(1<<20))
+			assert.Greater(t, r.ContentLength, int64(0))
+			assert.Equal(t, "monthly", r.FormValue("kind"))
+
+			file, header, err := r.FormFile("upload")
+			require.NoError(t, err)
+			content, _ := ioutil.ReadAll(file)
+			assert.Equal(t, "report.txt", header.Filename)
+			assert.Equal(t, "file content", string(content))
+
+			if attempts == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer server.Close()
+
+		opts := options.NewRequestOptionsBuilder().
+			SetMethod("POST").
+			SetURL(server.URL).
+			SetForm(url.Values{"kind": {"monthly"}}).
+			SetFileUpload(&options.FileUpload{FieldName: "upload", FileName: "report.txt", FilePath: path}).
+			SetRetryConfig(&options.RetryConfig{MaxRetries: 1, RetryDelay: time.Millisecond, RetryOnHTTP: []int{503}}).
+			SetSilent(true).
+			Build()
+
+		resp, _, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, attempts, "the body must be resent on retry")
+	})
+
+	t.Run("Large files are not buffered", func(t *testing.T) {
+		const size = 64 << 20
+		large := filepath.Join(dir, "large.bin")
+		file, err := os.Create(large)
+		require.NoError(t, err)
+		require.NoError(t, file.Truncate(size))
+		file.Close()
+
+		var received int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received, _ = io.Copy(ioutil.Discard, r.Body)
+		}))
+		defer server.Close()
+
+		opts := options.NewRequestOptionsBuilder().
+			SetMethod("POST").
+			SetURL(server.URL).
+			SetFileUpload(&options.FileUpload{FieldName: "upload", FileName: "large.bin", FilePath: large}).
+			SetSilent(true).
+			Build()
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, _, err = gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+		runtime.ReadMemStats(&after)
+
+		assert.Greater(t, received, int64(size))
+		assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(size/4))
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		opts := options.NewRequestOptions("http://127.0.0.1:1")
+		opts.FileUpload = &options.FileUpload{FieldName: "upload", FilePath: filepath.Join(dir, "missing")}
+		_, err := gocurl.CreateRequest(context.Background(), opts)
+		assert.Error(t, err)
+	})
+}
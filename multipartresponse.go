@@ -0,0 +1,127 @@
+package gocurl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// MultipartResponse iterates over the parts of a multipart response, such as
+// the multipart/mixed answers of batch endpoints or the multipart/related
+// ones of DICOMweb. Parts are streamed: each one must be read before moving
+// to the next.
+//
+//	parts, err := gocurl.ParseMultipartResponse(resp)
+//	if err != nil { ... }
+//	defer parts.Close()
+//	for parts.Next() {
+//		part := parts.Part()
+//		...
+//	}
+//	if err := parts.Err(); err != nil { ... }
+type MultipartResponse struct {
+	// MediaType is the media type of the response, e.g. multipart/mixed
+	MediaType string
+	// Params are the parameters of its Content-Type, such as the type of
+	// multipart/related responses
+	Params map[string]string
+
+	body   io.ReadCloser
+	reader *multipart.Reader
+	part   *ResponsePart
+	err    error
+}
+
+// ResponsePart is a part of a multipart response.
+type ResponsePart struct {
+	Header textproto.MIMEHeader
+	// Body reads the content of the part. Quoted-printable content is
+	// decoded.
+	Body io.Reader
+}
+
+// ParseMultipartResponse returns an iterator over the parts of resp, which
+// must have a multipart Content-Type. Closing it closes resp.Body.
+func ParseMultipartResponse(resp *http.Response) (*MultipartResponse, error) {
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Type: %v", err)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, fmt.Errorf("response is not multipart: %s", mediaType)
+	}
+	if params["boundary"] == "" {
+		return nil, fmt.Errorf("multipart response has no boundary")
+	}
+	return &MultipartResponse{
+		MediaType: mediaType,
+		Params:    params,
+		body:      resp.Body,
+		reader:    multipart.NewReader(resp.Body, params["boundary"]),
+	}, nil
+}
+
+// Next advances to the next part. It returns false when there are no more
+// parts or reading failed, which Err tells.
+func (m *MultipartResponse) Next() bool {
+	if m.err != nil {
+		return false
+	}
+	part, err := m.reader.NextPart()
+	if err != nil {
+		if err != io.EOF {
+			m.err = fmt.Errorf("failed to read multipart response: %v", err)
+		}
+		m.part = nil
+		return false
+	}
+	m.part = &ResponsePart{Header: part.Header, Body: part}
+	return true
+}
+
+// Part returns the current part.
+func (m *MultipartResponse) Part() *ResponsePart {
+	return m.part
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (m *MultipartResponse) Err() error {
+	return m.err
+}
+
+// Close closes the response body.
+func (m *MultipartResponse) Close() error {
+	return m.body.Close()
+}
+
+// ContentType returns the Content-Type of the part.
+func (p *ResponsePart) ContentType() string {
+	return p.Header.Get("Content-Type")
+}
+
+// ContentID returns the Content-ID of the part without its angle brackets,
+// as used by batch responses to match their requests.
+func (p *ResponsePart) ContentID() string {
+	return strings.TrimSuffix(strings.TrimPrefix(p.Header.Get("Content-Id"), "<"), ">")
+}
+
+// Bytes reads the whole content of the part.
+func (p *ResponsePart) Bytes() ([]byte, error) {
+	return io.ReadAll(p.Body)
+}
+
+// HTTPResponse parses a part holding an HTTP response, as the
+// application/http parts of batch responses do. req is the request it
+// answers and may be nil.
+func (p *ResponsePart) HTTPResponse(req *http.Request) (*http.Response, error) {
+	resp, err := http.ReadResponse(bufio.NewReader(p.Body), req)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTP response part: %v", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,90 @@
+package gocurl_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const batchResponse = "--batch_1\r\n" +
+	"Content-Type: application/http\r\n" +
+	"Content-ID: <response-item1>\r\n" +
+	"\r\n" +
+	"HTTP/1.1 200 OK\r\n" +
+	"Content-Type: application/json\r\n" +
+	"Content-Length: 11\r\n" +
+	"\r\n" +
+	"{\"id\":\"1\"}\n" +
+	"\r\n--batch_1\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"caf=C3=A9\r\n" +
+	"--batch_1--\r\n"
+
+func TestParseMultipartResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plain" {
+			io.WriteString(w, "plain")
+			return
+		}
+		w.Header().Set("Content-Type", `multipart/mixed; boundary=batch_1`)
+		io.WriteString(w, batchResponse)
+	}))
+	defer server.Close()
+
+	resp, _, err := gocurl.CurlString(context.Background(), server.URL+"/batch")
+	require.NoError(t, err)
+
+	parts, err := gocurl.ParseMultipartResponse(resp)
+	require.NoError(t, err)
+	defer parts.Close()
+	assert.Equal(t, "multipart/mixed", parts.MediaType)
+
+	require.True(t, parts.Next())
+	part := parts.Part()
+	assert.Equal(t, "application/http", part.ContentType())
+	assert.Equal(t, "response-item1", part.ContentID())
+	inner, err := part.HTTPResponse(nil)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(inner.Body)
+	assert.Equal(t, http.StatusOK, inner.StatusCode)
+	assert.Equal(t, "{\"id\":\"1\"}\n", string(body))
+
+	require.True(t, parts.Next())
+	text, err := parts.Part().Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, "café", string(text))
+
+	assert.False(t, parts.Next())
+	assert.NoError(t, parts.Err())
+
+	t.Run("Not multipart", func(t *testing.T) {
+		resp, _, err := gocurl.CurlString(context.Background(), server.URL+"/plain")
+		require.NoError(t, err)
+		_, err = gocurl.ParseMultipartResponse(resp)
+		assert.ErrorContains(t, err, "not multipart")
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		resp := &http.Response{
+			Header: http.Header{"Content-Type": {"multipart/related; boundary=b; type=\"application/dicom\""}},
+			Body:   io.NopCloser(strings.NewReader("--b\r\nContent-Type: application/dicom\r\n\r\ndata")),
+		}
+		parts, err := gocurl.ParseMultipartResponse(resp)
+		require.NoError(t, err)
+		assert.Equal(t, "application/dicom", parts.Params["type"])
+		require.True(t, parts.Next())
+		_, err = parts.Part().Bytes()
+		assert.Error(t, err)
+		assert.False(t, parts.Next())
+		assert.Error(t, parts.Err())
+	})
+}
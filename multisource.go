@@ -0,0 +1,365 @@
+package gocurl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultSegmentSize is the size of the segments of a MultiSourceDownload
+// that sets none.
+const DefaultSegmentSize = 8 << 20
+
+// MultiSourceDownload downloads a large artifact from several mirrors at
+// once, torrent style: the artifact is split into segments fetched with
+// Range requests from the mirrors in turn, so the download is as fast as
+// the mirrors together and survives some of them failing. A segment that
+// fails, or does not match its checksum, is fetched again from another
+// mirror, and mirrors failing repeatedly are dropped.
+type MultiSourceDownload struct {
+	// Mirrors are the URLs of the artifact, or curl commands fetching it
+	// when they need authentication
+	Mirrors []string
+
+	// SegmentSize is the size of the segments, DefaultSegmentSize if zero
+	SegmentSize int64
+	// Concurrency is the number of segments fetched at once, twice the
+	// number of mirrors if zero
+	Concurrency int
+
+	// SegmentChecksums are the hex SHA-256 digests of the segments, in
+	// order, when the publisher has a manifest of them for SegmentSize
+	SegmentChecksums []string
+	// Checksum is the hex digest of the whole artifact, checked as
+	// VerifyChecksum does once it is downloaded
+	Checksum string
+}
+
+// maxMirrorFailures is the number of failures after which a mirror is no
+// longer used.
+const maxMirrorFailures = 3
+
+// Download downloads the artifact to path. It is written to a temporary
+// file next to path and renamed over it once complete and verified. Mirrors
+// that do not support Range requests, or disagree on the size of the
+// artifact, are not used; when none do, the artifact is downloaded from
+// the first mirror available.
+func (d *MultiSourceDownload) Download(ctx context.Context, path string) error {
+	if len(d.Mirrors) == 0 {
+		return fmt.Errorf("no mirrors to download from")
+	}
+	size, mirrors, err := d.probe(ctx)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".part"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %v", err)
+	}
+	defer os.Remove(tmp)
+
+	if len(mirrors) == 0 {
+		err = d.fetchWhole(ctx, file)
+	} else {
+		err = d.fetchSegments(ctx, file, size, mirrors)
+	}
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write file: %v", closeErr)
+	}
+	if err != nil {
+		return err
+	}
+	if d.Checksum != "" {
+		if err := VerifyChecksum(tmp, ChecksumAuto, d.Checksum); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write file: %v", err)
+	}
+	return nil
+}
+
+// probe returns the size of the artifact and the mirrors serving it in
+// ranges, asking each for its first byte.
+func (d *MultiSourceDownload) probe(ctx context.Context) (int64, []string, error) {
+	sizes := make([]int64, len(d.Mirrors))
+	var wg sync.WaitGroup
+	for i, mirror := range d.Mirrors {
+		wg.Add(1)
+		go func(i int, mirror string) {
+			defer wg.Done()
+			sizes[i] = -1
+			d.fetch(ctx, mirror, "bytes=0-0", func(resp *http.Response) error {
+				if resp.StatusCode == http.StatusPartialContent {
+					sizes[i] = contentRangeSize(resp.Header.Get("Content-Range"))
+				}
+				return nil
+			})
+		}(i, mirror)
+	}
+	wg.Wait()
+
+	// The size served by most mirrors is the artifact's
+	counts := map[int64]int{}
+	var size int64 = -1
+	for _, s := range sizes {
+		if s <= 0 {
+			continue
+		}
+		counts[s]++
+		if size < 0 || counts[s] > counts[size] {
+			size = s
+		}
+	}
+	var mirrors []string
+	for i, s := range sizes {
+		if s == size && size > 0 {
+			mirrors = append(mirrors, d.Mirrors[i])
+		}
+	}
+	if len(d.SegmentChecksums) > 0 && len(mirrors) > 0 {
+		if want := (size + d.segmentSize() - 1) / d.segmentSize(); int64(len(d.SegmentChecksums)) != want {
+			return 0, nil, fmt.Errorf("%d segment checksums for %d segments of %d bytes", len(d.SegmentChecksums), want, d.segmentSize())
+		}
+	}
+	return size, mirrors, ctx.Err()
+}
+
+// segmentJob is a segment to fetch and the mirrors that failed it.
+type segmentJob struct {
+	index  int
+	failed map[string]bool
+}
+
+// fetchSegments downloads the segments of an artifact of size bytes from
+// mirrors into file.
+func (d *MultiSourceDownload) fetchSegments(ctx context.Context, file *os.File, size int64, mirrors []string) error {
+	if err := file.Truncate(size); err != nil {
+		return fmt.Errorf("failed to write file: %v", err)
+	}
+	segmentSize := d.segmentSize()
+	segments := int((size + segmentSize - 1) / segmentSize)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	jobs := make(chan segmentJob, segments)
+	for i := 0; i < segments; i++ {
+		jobs <- segmentJob{index: i, failed: map[string]bool{}}
+	}
+
+	var (
+		mu       sync.Mutex
+		failures = map[string]int{}
+		next     int
+		left     = segments
+		firstErr error
+	)
+	// pick returns the next healthy mirror that did not fail job
+	pick := func(job segmentJob) string {
+		mu.Lock()
+		defer mu.Unlock()
+		for range mirrors {
+			mirror := mirrors[next%len(mirrors)]
+			next++
+			if failures[mirror] < maxMirrorFailures && !job.failed[mirror] {
+				return mirror
+			}
+		}
+		return ""
+	}
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		cancel()
+	}
+
+	concurrency := d.Concurrency
+	if concurrency <= 0 {
+		concurrency = 2 * len(mirrors)
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var job segmentJob
+				select {
+				case job = <-jobs:
+				case <-ctx.Done():
+					return
+				}
+				mirror := pick(job)
+				if mirror == "" {
+					fail(fmt.Errorf("no mirror could serve segment %d", job.index))
+					return
+				}
+
+				start := int64(job.index) * segmentSize
+				end := min(start+segmentSize, size) - 1
+				err := d.fetchSegment(ctx, file, mirror, job.index, start, end)
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					mu.Lock()
+					failures[mirror]++
+					mu.Unlock()
+					job.failed[mirror] = true
+					jobs <- job
+					continue
+				}
+
+				mu.Lock()
+				left--
+				done := left == 0
+				mu.Unlock()
+				if done {
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if left > 0 {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// fetchSegment downloads the bytes start to end of the artifact, the
+// segment index, from mirror into file, hashing them as they are written.
+func (d *MultiSourceDownload) fetchSegment(ctx context.Context, file *os.File, mirror string, index int, start, end int64) error {
+	return d.fetch(ctx, mirror, fmt.Sprintf("bytes=%d-%d", start, end), func(resp *http.Response) error {
+		if resp.StatusCode != http.StatusPartialContent {
+			return fmt.Errorf("mirror %s answered %s to a range request", mirror, resp.Status)
+		}
+		// The bytes sent must be those asked for, not another range of the
+		// same length
+		if first, last, ok := contentRange(resp.Header.Get("Content-Range")); !ok || first != start || last != end {
+			return fmt.Errorf("mirror %s sent range %q for bytes %d-%d", mirror, resp.Header.Get("Content-Range"), start, end)
+		}
+		length := end - start + 1
+		hash := sha256.New()
+		n, err := io.Copy(io.NewOffsetWriter(file, start), io.TeeReader(io.LimitReader(resp.Body, length), hash))
+		if err != nil {
+			return fmt.Errorf("segment %d from mirror %s: %v", index, mirror, err)
+		}
+		// A longer body must not spill over the next segment
+		if extra, _ := io.CopyN(io.Discard, resp.Body, 1); n != length || extra > 0 {
+			return fmt.Errorf("mirror %s sent %d bytes for a segment of %d", mirror, n+extra, length)
+		}
+		if index < len(d.SegmentChecksums) && !strings.EqualFold(hex.EncodeToString(hash.Sum(nil)), d.SegmentChecksums[index]) {
+			return fmt.Errorf("segment %d from mirror %s: %w", index, mirror, ErrChecksumMismatch)
+		}
+		return nil
+	})
+}
+
+// fetchWhole downloads the artifact from the first mirror available,
+// starting file over for each mirror.
+func (d *MultiSourceDownload) fetchWhole(ctx context.Context, file *os.File) error {
+	var errs []error
+	for _, mirror := range d.Mirrors {
+		if err := file.Truncate(0); err != nil {
+			return fmt.Errorf("failed to write file: %v", err)
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to write file: %v", err)
+		}
+		err := d.fetch(ctx, mirror, "", func(resp *http.Response) error {
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				return fmt.Errorf("mirror %s answered %s", mirror, resp.Status)
+			}
+			if _, err := io.Copy(file, resp.Body); err != nil {
+				return fmt.Errorf("mirror %s: %v", mirror, err)
+			}
+			return nil
+		})
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return fmt.Errorf("no mirror could serve the download: %w", errors.Join(errs...))
+}
+
+// fetch requests rng, a Range header value, of the artifact from mirror and
+// passes the response to handle with its body unread, as executeStream does.
+func (d *MultiSourceDownload) fetch(ctx context.Context, mirror, rng string, handle func(resp *http.Response) error) error {
+	opts, err := parseCommand(mirror)
+	if err != nil {
+		return err
+	}
+	opts.Silent = true
+	if rng != "" {
+		if opts.Headers == nil {
+			opts.Headers = http.Header{}
+		}
+		opts.Headers.Set("Range", rng)
+	}
+	_, err = executeStream(ctx, opts, handle)
+	return err
+}
+
+func (d *MultiSourceDownload) segmentSize() int64 {
+	if d.SegmentSize > 0 {
+		return d.SegmentSize
+	}
+	return DefaultSegmentSize
+}
+
+// contentRange returns the first and last byte positions of a Content-Range
+// header value.
+func contentRange(value string) (first, last int64, ok bool) {
+	rng, _, found := strings.Cut(strings.TrimPrefix(value, "bytes "), "/")
+	if !found || !strings.HasPrefix(value, "bytes ") {
+		return 0, 0, false
+	}
+	from, to, found := strings.Cut(rng, "-")
+	if !found {
+		return 0, 0, false
+	}
+	first, err := strconv.ParseInt(from, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	last, err = strconv.ParseInt(to, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return first, last, true
+}
+
+// contentRangeSize returns the complete length of a Content-Range header
+// value, or -1 when it is unknown.
+func contentRangeSize(value string) int64 {
+	_, total, ok := strings.Cut(value, "/")
+	if !ok || !strings.HasPrefix(value, "bytes ") {
+		return -1
+	}
+	size, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return size
+}
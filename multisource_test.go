@@ -0,0 +1,134 @@
+package gocurl_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiSourceDownload(t *testing.T) {
+	artifact := []byte(strings.Repeat("0123456789abcdef", 100)) // 1600 bytes
+	var segmentSums []string
+	for i := 0; i < len(artifact); i += 256 {
+		sum := sha256.Sum256(artifact[i:min(i+256, len(artifact))])
+		segmentSums = append(segmentSums, hex.EncodeToString(sum[:]))
+	}
+	whole := sha256.Sum256(artifact)
+
+	var mu sync.Mutex
+	served := map[string]int{}
+	mirror := func(name string, content []byte) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Range") != "bytes=0-0" {
+				mu.Lock()
+				served[name]++
+				mu.Unlock()
+			}
+			http.ServeContent(w, r, "artifact.bin", time.Time{}, bytes.NewReader(content))
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	corrupt := bytes.ToUpper(artifact)
+
+	a := mirror("a", artifact)
+	b := mirror("b", artifact)
+	bad := mirror("bad", corrupt)
+
+	path := filepath.Join(t.TempDir(), "artifact.bin")
+	download := &gocurl.MultiSourceDownload{
+		Mirrors:          []string{a.URL, b.URL, bad.URL},
+		SegmentSize:      256,
+		SegmentChecksums: segmentSums,
+		Checksum:         hex.EncodeToString(whole[:]),
+	}
+	require.NoError(t, download.Download(context.Background(), path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, artifact, data)
+	assert.NoFileExists(t, path+".part")
+	assert.Positive(t, served["a"])
+	assert.Positive(t, served["b"])
+	assert.LessOrEqual(t, served["bad"], 3, "failing mirror should be dropped")
+
+	t.Run("Mirror of another size", func(t *testing.T) {
+		short := mirror("short", artifact[:1000])
+		served["short"] = 0
+		download := &gocurl.MultiSourceDownload{Mirrors: []string{a.URL, short.URL, b.URL}, SegmentSize: 256}
+		require.NoError(t, download.Download(context.Background(), path))
+		data, _ := os.ReadFile(path)
+		assert.Equal(t, artifact, data)
+		assert.Zero(t, served["short"])
+	})
+
+	t.Run("Mirror sending another range", func(t *testing.T) {
+		// The mirror answers with the bytes following those asked for
+		shifted := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var start, end int
+			fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+			if start > 0 && end+1 < len(artifact) {
+				start, end = start+1, end+1
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(artifact)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(artifact[start : end+1])
+		}))
+		defer shifted.Close()
+		other := filepath.Join(t.TempDir(), "artifact.bin")
+		download := &gocurl.MultiSourceDownload{Mirrors: []string{shifted.URL, a.URL}, SegmentSize: 256}
+		require.NoError(t, download.Download(context.Background(), other))
+		data, _ := os.ReadFile(other)
+		assert.Equal(t, artifact, data)
+	})
+
+	t.Run("No range support", func(t *testing.T) {
+		plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(artifact)
+		}))
+		defer plain.Close()
+		download := &gocurl.MultiSourceDownload{Mirrors: []string{plain.URL}, Checksum: hex.EncodeToString(whole[:])}
+		require.NoError(t, download.Download(context.Background(), path))
+		data, _ := os.ReadFile(path)
+		assert.Equal(t, artifact, data)
+	})
+
+	t.Run("Every mirror corrupt", func(t *testing.T) {
+		other := filepath.Join(t.TempDir(), "artifact.bin")
+		download := &gocurl.MultiSourceDownload{
+			Mirrors:          []string{bad.URL},
+			SegmentSize:      256,
+			SegmentChecksums: segmentSums,
+		}
+		err := download.Download(context.Background(), other)
+		require.Error(t, err)
+		assert.NoFileExists(t, other)
+		assert.NoFileExists(t, other+".part")
+	})
+
+	t.Run("Whole checksum mismatch", func(t *testing.T) {
+		other := filepath.Join(t.TempDir(), "artifact.bin")
+		download := &gocurl.MultiSourceDownload{Mirrors: []string{bad.URL}, SegmentSize: 256, Checksum: hex.EncodeToString(whole[:])}
+		err := download.Download(context.Background(), other)
+		assert.ErrorIs(t, err, gocurl.ErrChecksumMismatch)
+		assert.NoFileExists(t, other)
+	})
+
+	t.Run("Wrong number of segment checksums", func(t *testing.T) {
+		download := &gocurl.MultiSourceDownload{Mirrors: []string{a.URL}, SegmentSize: 512, SegmentChecksums: segmentSums}
+		assert.Error(t, download.Download(context.Background(), path))
+	})
+}
@@ -0,0 +1,95 @@
+package gocurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// isNext reports whether arg is curl's -:/--next, which separates the
+// requests of a command.
+func isNext(arg string) bool {
+	return arg == "-:" || arg == "--next"
+}
+
+// SplitNext splits the arguments of a curl command at each -: or --next
+// into the arguments of its requests, each with its own options as curl
+// gives them. A leading "curl" stays with the first request. Empty
+// requests, such as one after a trailing --next, are dropped.
+func SplitNext(args []string) [][]string {
+	var requests [][]string
+	start := 0
+	for i := 0; i <= len(args); i++ {
+		if i < len(args) && !isNext(args[i]) {
+			continue
+		}
+		if request := args[start:i]; len(request) > 0 && !(len(request) == 1 && request[0] == "curl") {
+			requests = append(requests, request)
+		}
+		start = i + 1
+	}
+	return requests
+}
+
+// ParseCommands parses a curl command that may carry several requests
+// separated by --next into their options. Like Curl, it accepts the command
+// as a single string or as separate arguments.
+func ParseCommands(command ...string) ([]*options.RequestOptions, error) {
+	args := command
+	if len(command) == 1 {
+		var err error
+		if args, err = Tokenize(command[0]); err != nil {
+			return nil, err
+		}
+	}
+	requests := SplitNext(args)
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no URL provided")
+	}
+	all := make([]*options.RequestOptions, len(requests))
+	for i, request := range requests {
+		opts, err := argsToOptions(request, nil)
+		if err != nil {
+			if len(requests) > 1 {
+				err = fmt.Errorf("request %d: %w", i+1, err)
+			}
+			return nil, err
+		}
+		all[i] = opts
+	}
+	return all, nil
+}
+
+// CurlAll executes a curl command carrying several requests separated by
+// --next, in order, and returns the Result of each, body included. Like
+// curl, it carries on after a request fails: the error joins the errors of
+// the failed requests, and their results hold what is known of them.
+func CurlAll(ctx context.Context, command ...string) ([]*Result, error) {
+	return curlAll(ctx, Execute, command)
+}
+
+// CurlAll executes a curl command carrying several requests separated by
+// --next through the client, like the package level CurlAll.
+func (c *Client) CurlAll(ctx context.Context, command ...string) ([]*Result, error) {
+	return curlAll(ctx, c.Execute, command)
+}
+
+func curlAll(ctx context.Context, execute func(context.Context, *options.RequestOptions) (*Result, error), command []string) ([]*Result, error) {
+	all, err := ParseCommands(command...)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*Result, len(all))
+	var errs []error
+	for i, opts := range all {
+		opts.Silent = true
+		result, err := execute(ctx, opts)
+		results[i] = result
+		if err != nil {
+			errs = append(errs, fmt.Errorf("request %d: %w", i+1, err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
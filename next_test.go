@@ -0,0 +1,54 @@
+package gocurl_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitNext(t *testing.T) {
+	assert.Equal(t, [][]string{
+		{"curl", "-X", "POST", "https://a"},
+		{"-H", "X: 1", "https://b"},
+	}, gocurl.SplitNext([]string{"curl", "-X", "POST", "https://a", "--next", "-H", "X: 1", "https://b", "-:"}))
+	assert.Equal(t, [][]string{{"curl", "https://a"}}, gocurl.SplitNext([]string{"curl", "https://a"}))
+	assert.Empty(t, gocurl.SplitNext([]string{"curl", "--next"}))
+}
+
+func TestCurlAll(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		io.WriteString(w, r.Method+" "+r.URL.Path+" "+r.Header.Get("X-Token")+" "+string(data))
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	// Options such as -H and -d only apply to their own request
+	results, err := gocurl.CurlAll(ctx, "curl -H 'X-Token: t' -d login "+server.URL+"/login --next "+server.URL+"/me")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "POST /login t login", results[0].Body)
+	assert.Equal(t, "GET /me  ", results[1].Body)
+	assert.Equal(t, http.StatusOK, results[1].Response.StatusCode)
+
+	results, err = gocurl.NewClient().CurlAll(ctx, "-f", server.URL+"/missing", "--next", server.URL+"/after")
+	require.Len(t, results, 2)
+	assert.ErrorContains(t, err, "request 1: ")
+	assert.Equal(t, "GET /after  ", results[1].Body, "later requests still run")
+
+	_, err = gocurl.ParseCommands("curl " + server.URL + " --next --bogus")
+	assert.ErrorContains(t, err, "request 2: unknown flag: --bogus")
+
+	_, _, err = gocurl.Curl(ctx, "curl "+server.URL+" --next "+server.URL)
+	assert.ErrorContains(t, err, "use CurlAll")
+}
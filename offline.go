@@ -0,0 +1,83 @@
+package gocurl
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/maniartech/gocurl/options"
+	"github.com/maniartech/gocurl/record"
+)
+
+// ErrOffline is matched by the errors of requests an offline Client refuses
+// to send over the network.
+var ErrOffline = errors.New("offline")
+
+// OfflineError reports a request an offline Client has neither a cached
+// response nor a recording for.
+type OfflineError struct {
+	Method string
+	URL    string
+}
+
+func (e *OfflineError) Error() string {
+	return fmt.Sprintf("offline: no cached or recorded response for %s %s", e.Method, e.URL)
+}
+
+// Is makes errors.Is(err, ErrOffline) match offline errors.
+func (e *OfflineError) Is(target error) bool {
+	return target == ErrOffline
+}
+
+// SetOfflineMode makes the client answer requests only from its response
+// cache and the recordings set with SetOfflineRecordings, for deterministic
+// CI runs and demos. Cached responses are served however stale, and requests
+// with neither fail with an *OfflineError without touching the network.
+func (c *Client) SetOfflineMode(enabled bool) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offline = enabled
+	return c
+}
+
+// SetOfflineRecordings sets the recorded exchanges an offline client answers
+// requests with, matched as a record.Stub matches them.
+func (c *Client) SetOfflineRecordings(exchanges []record.Exchange) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recordings = record.NewStub(exchanges)
+	return c
+}
+
+// isOffline reports whether the client is in offline mode.
+func (c *Client) isOffline() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.offline
+}
+
+// offlineTransport answers requests from recordings, refusing the others.
+type offlineTransport struct {
+	recordings *record.Stub
+}
+
+func (t *offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.recordings != nil {
+		resp, err := t.recordings.RoundTrip(req)
+		if !errors.Is(err, record.ErrNoRecording) {
+			return resp, err
+		}
+	} else if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, &OfflineError{Method: req.Method, URL: req.URL.String()}
+}
+
+// offlineClient returns an HTTP client for opts that never leaves the
+// process.
+func (c *Client) offlineClient(opts *options.RequestOptions) *http.Client {
+	c.mu.Lock()
+	recordings := c.recordings
+	c.mu.Unlock()
+	return newHTTPClient(&offlineTransport{recordings: recordings}, opts)
+}
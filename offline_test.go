@@ -0,0 +1,63 @@
+package gocurl_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/record"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineMode(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "no-cache")
+		fmt.Fprint(w, "cached")
+	}))
+	defer server.Close()
+	client := gocurl.NewClient().SetCache(gocurl.NewResponseCache())
+	ctx := context.Background()
+
+	_, body, err := client.Curl(ctx, "-s", server.URL+"/cached")
+	require.NoError(t, err)
+	assert.Equal(t, "cached", body)
+
+	client.SetOfflineMode(true).SetOfflineRecordings([]record.Exchange{{
+		Request:  record.Request{Method: "GET", URL: "https://api.example.com/recorded"},
+		Response: &record.Response{StatusCode: 200, Header: http.Header{"Content-Type": {"text/plain"}}, Body: "recorded"},
+	}})
+
+	// Stale responses are served without revalidation
+	resp, body, err := client.Curl(ctx, "-s", server.URL+"/cached")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "cached", body)
+
+	resp, body, err = client.Curl(ctx, "-s", server.URL+"/recorded")
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "recorded", body)
+
+	_, _, err = client.Curl(ctx, "-s", "-X", "POST", server.URL+"/cached")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, gocurl.ErrOffline))
+	var offlineErr *gocurl.OfflineError
+	require.True(t, errors.As(err, &offlineErr))
+	assert.Equal(t, "POST", offlineErr.Method)
+	assert.Equal(t, 7, gocurl.ExitCode(err))
+	assert.EqualValues(t, 1, hits)
+
+	client.SetOfflineMode(false)
+	_, body, err = client.Curl(ctx, "-s", server.URL+"/recorded")
+	require.NoError(t, err)
+	assert.Equal(t, "cached", body)
+	assert.EqualValues(t, 2, hits)
+}
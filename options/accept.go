@@ -0,0 +1,37 @@
+package options
+
+import (
+	"strconv"
+	"strings"
+)
+
+// AcceptHeader formats an Accept header listing types in order of
+// preference. Types without a q-value get one decreasing from 1 by 0.1 with
+// their position, down to 0.1. Types with one keep it.
+func AcceptHeader(types ...string) string {
+	parts := make([]string, 0, len(types))
+	for i, t := range types {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if i > 0 && !hasQValue(t) {
+			q := 1 - 0.1*float64(i)
+			if q < 0.1 {
+				q = 0.1
+			}
+			t += ";q=" + strconv.FormatFloat(q, 'f', 1, 64)
+		}
+		parts = append(parts, t)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func hasQValue(mediaRange string) bool {
+	for _, param := range strings.Split(mediaRange, ";")[1:] {
+		if name, _, _ := strings.Cut(strings.TrimSpace(param), "="); strings.EqualFold(name, "q") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package options
+
+import (
+	"sync"
+	"time"
+)
+
+// AltService is an alternative service an origin advertised in an Alt-Svc
+// header: another endpoint serving it, possibly over another protocol.
+type AltService struct {
+	// Protocol is the ALPN of the service: "h1", "h2" or "h3"
+	Protocol string    `json:"protocol"`
+	Host     string    `json:"host"`
+	Port     int       `json:"port"`
+	Expires  time.Time `json:"expires"`
+	// Persist keeps the service when the network changes
+	Persist bool `json:"persist,omitempty"`
+}
+
+// AltSvcStore keeps the alternative services of origins, so that later
+// connections to an origin use them. Sharing a store across requests, e.g.
+// through Client.SetAltSvcStore, shares what they learned.
+type AltSvcStore interface {
+	// AltServices returns the alternative services of origin, a
+	// "host:port".
+	AltServices(origin string) ([]AltService, error)
+	// SetAltServices replaces the alternative services of origin, removing
+	// them when services is empty.
+	SetAltServices(origin string, services []AltService) error
+}
+
+// MemoryAltSvcStore is an AltSvcStore held in memory. It is safe for
+// concurrent use.
+type MemoryAltSvcStore struct {
+	mu       sync.Mutex
+	services map[string][]AltService
+}
+
+// NewMemoryAltSvcStore creates an empty MemoryAltSvcStore.
+func NewMemoryAltSvcStore() *MemoryAltSvcStore {
+	return &MemoryAltSvcStore{services: map[string][]AltService{}}
+}
+
+// AltServices returns the alternative services of origin.
+func (s *MemoryAltSvcStore) AltServices(origin string) ([]AltService, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AltService(nil), s.services[origin]...), nil
+}
+
+// SetAltServices replaces the alternative services of origin.
+func (s *MemoryAltSvcStore) SetAltServices(origin string, services []AltService) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(services) == 0 {
+		delete(s.services, origin)
+	} else {
+		s.services[origin] = append([]AltService(nil), services...)
+	}
+	return nil
+}
+
+// SetAltSvc sets the file alternative services are read from and saved to.
+func (b *RequestOptionsBuilder) SetAltSvc(path string) *RequestOptionsBuilder {
+	b.options.AltSvc = path
+	return b
+}
+
+// SetAltSvcStore sets the store alternative services are read from and
+// saved to.
+func (b *RequestOptionsBuilder) SetAltSvcStore(store AltSvcStore) *RequestOptionsBuilder {
+	b.options.AltSvcStore = store
+	return b
+}
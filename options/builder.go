@@ -2,9 +2,14 @@ package options
 
 import (
 	"crypto/tls"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"time"
+
+	"github.com/maniartech/gocurl/audit"
+	"github.com/maniartech/gocurl/middlewares"
 )
 
 // RequestOptionsBuilder is a builder for RequestOptions.
@@ -41,6 +46,15 @@ func (b *RequestOptionsBuilder) AddHeader(key, value string) *RequestOptionsBuil
 	return b
 }
 
+// Accept sets the Accept header to types in order of preference. Types
+// without a q-value get one decreasing from 1 by 0.1 with their position,
+// down to 0.1, so Accept("application/json", "application/xml") sends
+// "application/json, application/xml;q=0.9".
+func (b *RequestOptionsBuilder) Accept(types ...string) *RequestOptionsBuilder {
+	b.options.Headers.Set("Accept", AcceptHeader(types...))
+	return b
+}
+
 // SetHeaders sets multiple headers for the request.
 func (b *RequestOptionsBuilder) SetHeaders(headers http.Header) *RequestOptionsBuilder {
 	b.options.Headers = headers
@@ -59,6 +73,19 @@ func (b *RequestOptionsBuilder) SetForm(form url.Values) *RequestOptionsBuilder
 	return b
 }
 
+// Form adds v, a struct with url tags, a map or url.Values, to the
+// form sent as an application/x-www-form-urlencoded body. See EncodeForm for
+// how values are encoded.
+func (b *RequestOptionsBuilder) Form(v interface{}) *RequestOptionsBuilder {
+	if b.options.Form == nil {
+		b.options.Form = url.Values{}
+	}
+	for key, values := range EncodeForm(v) {
+		b.options.Form[key] = append(b.options.Form[key], values...)
+	}
+	return b
+}
+
 // SetQueryParams sets the query parameters for the request.
 func (b *RequestOptionsBuilder) SetQueryParams(queryParams url.Values) *RequestOptionsBuilder {
 	b.options.QueryParams = queryParams
@@ -116,12 +143,31 @@ func (b *RequestOptionsBuilder) SetTLSConfig(tlsConfig *tls.Config) *RequestOpti
 	return b
 }
 
+// SetTLSSessionCache sets the cache of the TLS sessions new connections
+// resume.
+func (b *RequestOptionsBuilder) SetTLSSessionCache(cache tls.ClientSessionCache) *RequestOptionsBuilder {
+	b.options.TLSSessionCache = cache
+	return b
+}
+
+// SetNoTLSSessionResumption sets whether to disable resuming TLS sessions.
+func (b *RequestOptionsBuilder) SetNoTLSSessionResumption(disable bool) *RequestOptionsBuilder {
+	b.options.NoTLSSessionResumption = disable
+	return b
+}
+
 // SetProxy sets the proxy URL.
 func (b *RequestOptionsBuilder) SetProxy(proxy string) *RequestOptionsBuilder {
 	b.options.Proxy = proxy
 	return b
 }
 
+// SetProxyPAC sets the URL or path of the proxy auto-config file.
+func (b *RequestOptionsBuilder) SetProxyPAC(location string) *RequestOptionsBuilder {
+	b.options.ProxyPAC = location
+	return b
+}
+
 // SetTimeout sets the request timeout.
 func (b *RequestOptionsBuilder) SetTimeout(timeout time.Duration) *RequestOptionsBuilder {
 	b.options.Timeout = timeout
@@ -134,6 +180,14 @@ func (b *RequestOptionsBuilder) SetConnectTimeout(connectTimeout time.Duration)
 	return b
 }
 
+// SetAttemptTimeout limits each attempt of the request to timeout, so a
+// slow attempt is abandoned and retried while the deadline of the context
+// still bounds the whole operation.
+func (b *RequestOptionsBuilder) SetAttemptTimeout(timeout time.Duration) *RequestOptionsBuilder {
+	b.options.AttemptTimeout = timeout
+	return b
+}
+
 // SetFollowRedirects sets whether to follow redirects.
 func (b *RequestOptionsBuilder) SetFollowRedirects(follow bool) *RequestOptionsBuilder {
 	b.options.FollowRedirects = follow
@@ -164,6 +218,14 @@ func (b *RequestOptionsBuilder) SetHTTP2Only(http2Only bool) *RequestOptionsBuil
 	return b
 }
 
+// SetRawHeaders sends the headers as given, in the order and casing of the
+// names in order followed by the others. See RequestOptions.RawHeaders.
+func (b *RequestOptionsBuilder) SetRawHeaders(order ...string) *RequestOptionsBuilder {
+	b.options.RawHeaders = true
+	b.options.HeaderOrder = order
+	return b
+}
+
 // SetCookie adds a cookie to the request.
 func (b *RequestOptionsBuilder) SetCookie(cookie *http.Cookie) *RequestOptionsBuilder {
 	b.options.Cookies = append(b.options.Cookies, cookie)
@@ -188,18 +250,160 @@ func (b *RequestOptionsBuilder) SetFileUpload(fileUpload *FileUpload) *RequestOp
 	return b
 }
 
+// SetUploadFile sets a file sent as the raw request body, like curl -T.
+func (b *RequestOptionsBuilder) SetUploadFile(path string) *RequestOptionsBuilder {
+	b.options.UploadFile = path
+	return b
+}
+
+// SetMmapUploads memory-maps uploaded files where supported instead of
+// reading them, which reduces copies for very large files.
+func (b *RequestOptionsBuilder) SetMmapUploads(enabled bool) *RequestOptionsBuilder {
+	b.options.MmapUploads = enabled
+	return b
+}
+
 // SetRetryConfig sets the retry configuration.
 func (b *RequestOptionsBuilder) SetRetryConfig(retryConfig *RetryConfig) *RequestOptionsBuilder {
 	b.options.RetryConfig = retryConfig
 	return b
 }
 
+// SetRetryAfterPolicy sets the policy used to retry rate limited responses.
+func (b *RequestOptionsBuilder) SetRetryAfterPolicy(policy *RetryAfterPolicy) *RequestOptionsBuilder {
+	b.options.RetryAfter = policy
+	return b
+}
+
+// SetPolicy restricts the requests the options may send.
+func (b *RequestOptionsBuilder) SetPolicy(policy *Policy) *RequestOptionsBuilder {
+	b.options.Policy = policy
+	return b
+}
+
+// SetIdempotencyKey sets the Idempotency-Key sent with the request and all of
+// its retries. Pass IdempotencyKeyAuto to generate a UUID per request.
+func (b *RequestOptionsBuilder) SetIdempotencyKey(key string) *RequestOptionsBuilder {
+	b.options.IdempotencyKey = key
+	return b
+}
+
+// SetSigner sets the signer invoked on the final request before it is sent.
+func (b *RequestOptionsBuilder) SetSigner(signer Signer) *RequestOptionsBuilder {
+	b.options.Signer = signer
+	return b
+}
+
+// SetBodyDigest sends a digest of the request body computed with algo, as
+// Content-MD5 or Digest depending on the algorithm. Bodies that can be read
+// twice are hashed before the request is sent, and before it is signed so
+// that signers can cover the digest; streamed bodies are hashed while they
+// are sent and the digest follows them as a trailer.
+func (b *RequestOptionsBuilder) SetBodyDigest(algo BodyDigest) *RequestOptionsBuilder {
+	b.options.BodyDigest = algo
+	return b
+}
+
+// SetBodyReader streams r as the request body. The body is sent chunked
+// and cannot be resent, so requests with it are not retried.
+func (b *RequestOptionsBuilder) SetBodyReader(r io.Reader) *RequestOptionsBuilder {
+	b.options.BodyReader = r
+	return b
+}
+
+// SetResponseTee copies the raw response body to w while it is read. Use
+// io.MultiWriter to send the body to several sinks.
+func (b *RequestOptionsBuilder) SetResponseTee(w io.Writer) *RequestOptionsBuilder {
+	b.options.ResponseTee = w
+	return b
+}
+
+// SetResponseSchema sets a JSON Schema that the response body is validated
+// against. Violations are returned as a *jsonschema.ValidationError.
+func (b *RequestOptionsBuilder) SetResponseSchema(schemaJSON string) *RequestOptionsBuilder {
+	b.options.ResponseSchema = schemaJSON
+	return b
+}
+
+// SetSpoolThreshold spools response bodies larger than threshold bytes to a
+// temporary file in dir (the system default when empty) instead of memory.
+// The response body then reads lazily from the file, which is removed when
+// the body is closed, and the body string or bytes returned is empty.
+func (b *RequestOptionsBuilder) SetSpoolThreshold(threshold int64, dir string) *RequestOptionsBuilder {
+	b.options.SpoolThreshold = threshold
+	b.options.SpoolDir = dir
+	return b
+}
+
+// AddInterceptor adds an interceptor wrapping every attempt at sending the
+// request.
+func (b *RequestOptionsBuilder) AddInterceptor(interceptor middlewares.Interceptor) *RequestOptionsBuilder {
+	b.options.Interceptors = append(b.options.Interceptors, interceptor)
+	return b
+}
+
+// SetLogger logs every attempt at sending the request, along with the
+// request ID, tenant and attempt number carried by its context.
+func (b *RequestOptionsBuilder) SetLogger(logger *slog.Logger) *RequestOptionsBuilder {
+	b.options.Logger = logger
+	return b
+}
+
+// SetFail makes HTTP error statuses fail the request with a
+// *gocurl.HTTPError, like curl --fail.
+func (b *RequestOptionsBuilder) SetFail(fail bool) *RequestOptionsBuilder {
+	b.options.Fail = fail
+	return b
+}
+
+// SetErrorModel decodes the JSON body of error responses into a new value
+// of model's type, which must be a pointer, and fails the request with it.
+// The value is the Model of the returned *gocurl.HTTPError, and is found
+// by errors.As when it implements error:
+//
+//	var apiErr *MyAPIError
+//	if errors.As(err, &apiErr) { ... }
+func (b *RequestOptionsBuilder) SetErrorModel(model interface{}) *RequestOptionsBuilder {
+	b.options.ErrorModel = model
+	return b
+}
+
+// SetWriteOut sets the format printed after the response, like curl -w.
+func (b *RequestOptionsBuilder) SetWriteOut(format string) *RequestOptionsBuilder {
+	b.options.WriteOut = format
+	return b
+}
+
+// SetRecorder sets the recorder capturing each request and its response.
+func (b *RequestOptionsBuilder) SetRecorder(recorder Recorder) *RequestOptionsBuilder {
+	b.options.Recorder = recorder
+	return b
+}
+
+// SetAuditor sets the sink receiving an audit event for each request attempt.
+func (b *RequestOptionsBuilder) SetAuditor(auditor audit.Sink) *RequestOptionsBuilder {
+	b.options.Auditor = auditor
+	return b
+}
+
 // SetOutputFile sets the output file for the response.
 func (b *RequestOptionsBuilder) SetOutputFile(outputFile string) *RequestOptionsBuilder {
 	b.options.OutputFile = outputFile
 	return b
 }
 
+// SetOutputDir sets the directory a relative output file is written in.
+func (b *RequestOptionsBuilder) SetOutputDir(dir string) *RequestOptionsBuilder {
+	b.options.OutputDir = dir
+	return b
+}
+
+// SetCreateDirs creates the missing directories of the output file.
+func (b *RequestOptionsBuilder) SetCreateDirs(createDirs bool) *RequestOptionsBuilder {
+	b.options.CreateDirs = createDirs
+	return b
+}
+
 // SetSilent sets whether the request should be silent.
 func (b *RequestOptionsBuilder) SetSilent(silent bool) *RequestOptionsBuilder {
 	b.options.Silent = silent
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -264,3 +265,22 @@ func TestSetHeaders(t *testing.T) {
 		t.Errorf("expected Accept header to be application/json, got %s", requestOptions.Headers.Get("Accept"))
 	}
 }
+
+func TestAccept(t *testing.T) {
+	requestOptions := options.NewRequestOptionsBuilder().
+		Accept("application/json", "application/xml", "text/*;q=0.2").
+		Build()
+
+	want := "application/json, application/xml;q=0.9, text/*;q=0.2"
+	if got := requestOptions.Headers.Get("Accept"); got != want {
+		t.Errorf("expected Accept header to be %q, got %q", want, got)
+	}
+
+	types := make([]string, 12)
+	for i := range types {
+		types[i] = "a/" + string(rune('a'+i))
+	}
+	if got := options.AcceptHeader(types...); !strings.HasSuffix(got, "a/k;q=0.1, a/l;q=0.1") {
+		t.Errorf("expected q-values to stop at 0.1, got %q", got)
+	}
+}
@@ -0,0 +1,220 @@
+package options
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maniartech/gocurl/tokenizer"
+)
+
+// ToCurlCommand formats the request as an equivalent curl command, quoted
+// for a POSIX shell, so it can be shared and replayed outside of Go. Options
+// curl has no flag for, such as TLSConfig, Middleware or Signer, are left
+// out, and a BodyReader is written as -T - to be piped on stdin.
+func (ro *RequestOptions) ToCurlCommand() string {
+	return tokenizer.Join(ro.curlArgs())
+}
+
+// curlArgs returns the arguments of ToCurlCommand.
+func (ro *RequestOptions) curlArgs() []string {
+	args := []string{"curl"}
+	add := func(flag string, values ...string) {
+		args = append(args, flag)
+		args = append(args, values...)
+	}
+
+	// curl picks the method from the body flags, -X is only needed to
+	// override it
+	impliedMethod := "GET"
+	switch {
+	case ro.BodyReader != nil || ro.Body == "" && len(ro.Form) == 0 && ro.FileUpload == nil && ro.UploadFile != "":
+		impliedMethod = "PUT"
+	case ro.Body != "" || len(ro.Form) > 0 || ro.FileUpload != nil:
+		impliedMethod = "POST"
+	}
+	if method := strings.ToUpper(ro.Method); method != "" && method != impliedMethod {
+		add("-X", method)
+	}
+
+	rawURL := ro.URL
+	if len(ro.QueryParams) > 0 {
+		separator := "?"
+		if strings.Contains(rawURL, "?") {
+			separator = "&"
+		}
+		rawURL += separator + ro.QueryParams.Encode()
+	}
+	args = append(args, rawURL)
+
+	// Headers the options below produce are written with their own flag
+	skip := map[string]string{}
+	if ro.UserAgent != "" {
+		skip["User-Agent"] = ro.UserAgent
+	}
+	if ro.Referer != "" {
+		skip["Referer"] = ro.Referer
+	}
+	addHeader := func(name, value string) {
+		// --compressed sets Accept-Encoding to the encodings gocurl supports
+		if ro.Compress && http.CanonicalHeaderKey(name) == "Accept-Encoding" {
+			return
+		}
+		if generated, ok := skip[http.CanonicalHeaderKey(name)]; !ok || value != generated {
+			add("-H", name+": "+value)
+		}
+	}
+	// curl sends headers as given, so HeaderOrder is kept as is
+	sent := map[string]int{}
+	for _, name := range ro.HeaderOrder {
+		key := http.CanonicalHeaderKey(name)
+		if values := ro.Headers.Values(key); sent[key] < len(values) {
+			addHeader(name, values[sent[key]])
+			sent[key]++
+		}
+	}
+	names := make([]string, 0, len(ro.Headers))
+	for name := range ro.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := ro.Headers[name]
+		for _, value := range values[min(sent[name], len(values)):] {
+			addHeader(name, value)
+		}
+	}
+	if ro.BearerToken != "" {
+		add("-H", "Authorization: Bearer "+ro.BearerToken)
+	}
+	if ro.IdempotencyKey != "" && ro.IdempotencyKey != IdempotencyKeyAuto {
+		add("-H", "Idempotency-Key: "+ro.IdempotencyKey)
+	}
+	if ro.UserAgent != "" {
+		add("-A", ro.UserAgent)
+	}
+	if ro.Referer != "" {
+		add("-e", ro.Referer)
+	}
+	if ro.BasicAuth != nil {
+		add("-u", ro.BasicAuth.Username+":"+ro.BasicAuth.Password)
+	}
+	if len(ro.Cookies) > 0 {
+		cookies := make([]string, len(ro.Cookies))
+		for i, cookie := range ro.Cookies {
+			cookies[i] = cookie.Name + "=" + cookie.Value
+		}
+		add("-b", strings.Join(cookies, "; "))
+	}
+
+	// Body, in the order CreateRequest picks it
+	switch {
+	case ro.BodyReader != nil:
+		add("-T", "-")
+	case ro.Body != "":
+		add("--data-raw", ro.Body)
+	case len(ro.Form) > 0 && ro.FileUpload == nil:
+		add("--data-raw", ro.Form.Encode())
+	case ro.FileUpload != nil:
+		keys := make([]string, 0, len(ro.Form))
+		for key := range ro.Form {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			for _, value := range ro.Form[key] {
+				add("-F", key+"="+value)
+			}
+		}
+		add("-F", ro.FileUpload.FieldName+"=@"+ro.FileUpload.FilePath)
+	case ro.UploadFile != "":
+		add("-T", ro.UploadFile)
+	}
+
+	if ro.Compress {
+		add("--compressed")
+	}
+	if ro.HTTP2 {
+		add("--http2")
+	}
+	if ro.HTTP2Only {
+		add("--http2-only")
+	}
+	if ro.RawHeaders {
+		add("--raw-headers")
+	}
+	if ro.Insecure {
+		add("-k")
+	}
+	if ro.NoTLSSessionResumption {
+		add("--no-sessionid")
+	}
+	if ro.CertFile != "" {
+		add("--cert", ro.CertFile)
+	}
+	if ro.KeyFile != "" {
+		add("--key", ro.KeyFile)
+	}
+	if ro.CAFile != "" {
+		add("--cacert", ro.CAFile)
+	}
+	if ro.Proxy != "" {
+		add("-x", ro.Proxy)
+	}
+	if ro.ProxyPAC != "" {
+		add("--proxy-pac", ro.ProxyPAC)
+	}
+	if ro.ConnectTimeout > 0 {
+		add("--connect-timeout", seconds(ro.ConnectTimeout))
+	}
+	if ro.Timeout > 0 {
+		add("-m", seconds(ro.Timeout))
+	}
+	if ro.FollowRedirects {
+		add("-L")
+	}
+	if ro.MaxRedirects > 0 {
+		add("--max-redirs", strconv.Itoa(ro.MaxRedirects))
+	}
+	if ro.Fail {
+		add("-f")
+	}
+	if ro.FailWithBody {
+		add("--fail-with-body")
+	}
+	if ro.OutputDir != "" {
+		add("--output-dir", ro.OutputDir)
+	}
+	if ro.CreateDirs {
+		add("--create-dirs")
+	}
+	if ro.AltSvc != "" {
+		add("--alt-svc", ro.AltSvc)
+	}
+	if ro.ETagSave != "" {
+		add("--etag-save", ro.ETagSave)
+	}
+	if ro.ETagCompare != "" {
+		add("--etag-compare", ro.ETagCompare)
+	}
+	if ro.OutputFile != "" {
+		add("-o", ro.OutputFile)
+	}
+	if ro.WriteOut != "" {
+		add("-w", ro.WriteOut)
+	}
+	if ro.Silent {
+		add("-s")
+	}
+	if ro.Verbose {
+		add("-v")
+	}
+	return args
+}
+
+// seconds formats d as the decimal seconds curl's timeout flags take.
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
@@ -0,0 +1,135 @@
+package options_test
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/maniartech/gocurl/tokenizer"
+)
+
+func TestToCurlCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     *options.RequestOptions
+		expected string
+	}{
+		{
+			name:     "GET",
+			opts:     options.NewRequestOptionsBuilder().SetURL("https://example.com/items").Build(),
+			expected: "curl https://example.com/items",
+		},
+		{
+			name: "JSON POST",
+			opts: options.NewRequestOptionsBuilder().
+				POST("https://example.com/items", `{"name":"it's"}`, http.Header{"Content-Type": {"application/json"}}).
+				AddQueryParam("dry run", "1").
+				SetBearerToken("t0ken").
+				Build(),
+			expected: `curl 'https://example.com/items?dry+run=1' -H 'Content-Type: application/json' -H 'Authorization: Bearer t0ken' --data-raw '{"name":"it'\''s"}'`,
+		},
+		{
+			name: "Method overrides",
+			opts: options.NewRequestOptionsBuilder().
+				SetMethod("delete").
+				SetURL("https://example.com/items/1").
+				SetBasicAuth("user", "p@ss word").
+				Build(),
+			expected: `curl -X DELETE https://example.com/items/1 -u 'user:p@ss word'`,
+		},
+		{
+			name: "Form and file upload",
+			opts: options.NewRequestOptionsBuilder().
+				SetMethod("POST").
+				SetURL("https://example.com/upload").
+				SetForm(url.Values{"b": {"2"}, "a": {"1"}}).
+				SetFileUpload(&options.FileUpload{FieldName: "file", FileName: "x.txt", FilePath: "/tmp/x.txt"}).
+				Build(),
+			expected: "curl https://example.com/upload -F a=1 -F b=2 -F file=@/tmp/x.txt",
+		},
+		{
+			name: "Connection and output options",
+			opts: options.NewRequestOptionsBuilder().
+				SetURL("https://example.com").
+				SetUserAgent("gocurl/1.0").
+				SetReferer("https://example.com/start").
+				SetCookie(&http.Cookie{Name: "sid", Value: "abc"}).
+				SetCookie(&http.Cookie{Name: "theme", Value: "dark"}).
+				SetCompress(true).
+				SetInsecure(true).
+				SetNoTLSSessionResumption(true).
+				SetProxy("http://proxy:3128").
+				SetTimeout(1500 * time.Millisecond).
+				SetConnectTimeout(2 * time.Second).
+				SetFollowRedirects(true).
+				SetMaxRedirects(5).
+				SetFail(true).
+				SetOutputFile("out.json").
+				SetWriteOut("%{http_code}\n").
+				SetSilent(true).
+				Build(),
+			expected: "curl https://example.com -A gocurl/1.0 -e https://example.com/start -b 'sid=abc; theme=dark' --compressed -k --no-sessionid -x http://proxy:3128 --connect-timeout 2 -m 1.5 -L --max-redirs 5 -f -o out.json -w '%{http_code}\n' -s",
+		},
+		{
+			name: "Streamed body",
+			opts: options.NewRequestOptionsBuilder().
+				SetURL("https://example.com/logs").
+				SetMethod("POST").
+				SetBodyReader(os.Stdin).
+				Build(),
+			expected: "curl -X POST https://example.com/logs -T -",
+		},
+		{
+			name: "Header order",
+			opts: options.NewRequestOptionsBuilder().
+				SetURL("https://example.com").
+				SetHeaders(http.Header{"X-B": {"1", "2"}, "X-A": {"3"}, "Accept": {"*/*"}}).
+				SetRawHeaders("x-b", "X-A", "x-B").
+				Build(),
+			expected: "curl https://example.com -H 'x-b: 1' -H 'X-A: 3' -H 'x-B: 2' -H 'Accept: */*' --raw-headers",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.ToCurlCommand(); got != tt.expected {
+				t.Errorf("ToCurlCommand() =\n%s\nwant\n%s", got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("Round trip", func(t *testing.T) {
+		original := options.NewRequestOptionsBuilder().
+			SetMethod("PATCH").
+			SetURL("https://example.com/items/1").
+			AddQueryParam("q", "a&b").
+			AddHeader("x-trace", `"quoted" value`).
+			SetRawHeaders("x-trace").
+			SetBody("name=gocurl\nline two").
+			SetUserAgent("agent 'x'").
+			SetBasicAuth("user", "pass:word").
+			SetFollowRedirects(true).
+			SetProxyPAC("https://example.com/proxy.pac").
+			Build()
+
+		args, err := tokenizer.Split(original.ToCurlCommand())
+		if err != nil {
+			t.Fatalf("Split() error = %v", err)
+		}
+		parsed, err := gocurl.ArgsToOptions(args)
+		if err != nil {
+			t.Fatalf("ArgsToOptions() error = %v", err)
+		}
+		if parsed.Method != "PATCH" || parsed.URL != original.URL || parsed.Body != original.Body ||
+			parsed.QueryParams.Get("q") != "a&b" || parsed.Headers.Get("X-Trace") != `"quoted" value` ||
+			parsed.UserAgent != original.UserAgent || *parsed.BasicAuth != *original.BasicAuth || !parsed.FollowRedirects ||
+			!parsed.RawHeaders || len(parsed.HeaderOrder) != 1 || parsed.HeaderOrder[0] != "x-trace" ||
+			parsed.ProxyPAC != original.ProxyPAC {
+			t.Errorf("round trip of %s gave %+v", original.ToCurlCommand(), parsed)
+		}
+	})
+}
@@ -0,0 +1,16 @@
+package options
+
+// BodyDigest is the algorithm of the digest of the request body sent with
+// a request, for APIs checking the integrity of what they receive.
+type BodyDigest string
+
+const (
+	// BodyDigestMD5 sends the base64 MD5 of the body as Content-MD5, as
+	// object storage APIs expect
+	BodyDigestMD5 BodyDigest = "md5"
+	// BodyDigestSHA256 sends "Digest: sha-256=<base64>", as banking APIs
+	// such as those of PSD2 expect
+	BodyDigestSHA256 BodyDigest = "sha-256"
+	// BodyDigestSHA512 sends "Digest: sha-512=<base64>"
+	BodyDigestSHA512 BodyDigest = "sha-512"
+)
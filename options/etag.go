@@ -0,0 +1,59 @@
+package options
+
+import "sync"
+
+// ETagStore keeps the ETags of responses by key, the URL of their request,
+// so that later requests for the same resource are conditional and answered
+// with 304 Not Modified when it did not change.
+type ETagStore interface {
+	// ETag returns the ETag stored for key, or "" when there is none.
+	ETag(key string) (string, error)
+	// SetETag stores etag for key.
+	SetETag(key, etag string) error
+}
+
+// MemoryETagStore is an ETagStore held in memory. It is safe for concurrent
+// use.
+type MemoryETagStore struct {
+	mu    sync.Mutex
+	etags map[string]string
+}
+
+// NewMemoryETagStore creates an empty MemoryETagStore.
+func NewMemoryETagStore() *MemoryETagStore {
+	return &MemoryETagStore{etags: map[string]string{}}
+}
+
+// ETag returns the ETag stored for key.
+func (s *MemoryETagStore) ETag(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.etags[key], nil
+}
+
+// SetETag stores etag for key.
+func (s *MemoryETagStore) SetETag(key, etag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.etags[key] = etag
+	return nil
+}
+
+// SetETagStore makes the request conditional on the ETag store holds for its
+// URL, and stores the ETag of its response.
+func (b *RequestOptionsBuilder) SetETagStore(store ETagStore) *RequestOptionsBuilder {
+	b.options.ETagStore = store
+	return b
+}
+
+// SetETagSave sets the file the ETag of the response is saved to.
+func (b *RequestOptionsBuilder) SetETagSave(path string) *RequestOptionsBuilder {
+	b.options.ETagSave = path
+	return b
+}
+
+// SetETagCompare sets the file of the ETag the request is conditional on.
+func (b *RequestOptionsBuilder) SetETagCompare(path string) *RequestOptionsBuilder {
+	b.options.ETagCompare = path
+	return b
+}
@@ -0,0 +1,110 @@
+package options
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EncodeForm encodes v, a struct, a map or url.Values, as form values.
+//
+// Struct fields are named by their url tag, as in github.com/google/go-querystring:
+// `url:"name"`, `url:"name,omitempty"` to skip zero values or `url:"-"` to
+// skip the field, and by the field name without a tag. Embedded structs are
+// flattened. Slices and arrays repeat the key once per element, while nested
+// structs and maps use bracketed keys, so Stripe style parameters such as
+// metadata[order_id] are written as a map field named metadata. time.Time is
+// encoded in RFC 3339, nil pointers are skipped and other values are
+// formatted with fmt.Sprint.
+func EncodeForm(v interface{}) url.Values {
+	values := url.Values{}
+	if form, ok := v.(url.Values); ok {
+		for key, vs := range form {
+			values[key] = append([]string(nil), vs...)
+		}
+		return values
+	}
+	encodeFormValue(values, "", reflect.ValueOf(v))
+	return values
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func encodeFormValue(values url.Values, key string, v reflect.Value) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return
+	}
+
+	switch {
+	case v.Type() == timeType:
+		values.Add(key, v.Interface().(time.Time).Format(time.RFC3339))
+	case v.Kind() == reflect.Struct:
+		encodeFormStruct(values, key, v)
+	case v.Kind() == reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, k := range keys {
+			encodeFormValue(values, formKey(key, fmt.Sprint(k.Interface())), v.MapIndex(k))
+		}
+	case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8:
+		for i := 0; i < v.Len(); i++ {
+			encodeFormValue(values, key, v.Index(i))
+		}
+	case v.Kind() == reflect.Slice:
+		values.Add(key, string(v.Bytes()))
+	default:
+		values.Add(key, fmt.Sprint(v.Interface()))
+	}
+}
+
+func encodeFormStruct(values url.Values, prefix string, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		// Embedded structs of unexported types still promote their fields
+		if !field.IsExported() && (!field.Anonymous || indirectType(field.Type).Kind() != reflect.Struct) {
+			continue
+		}
+		tag := field.Tag.Get("url")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		value := v.Field(i)
+		if opts == "omitempty" && value.IsZero() {
+			continue
+		}
+		if field.Anonymous && name == "" && reflect.Indirect(value).Kind() == reflect.Struct {
+			encodeFormValue(values, prefix, value)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		encodeFormValue(values, formKey(prefix, name), value)
+	}
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// formKey returns the key of name nested in prefix.
+func formKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "[" + name + "]"
+}
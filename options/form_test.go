@@ -0,0 +1,80 @@
+package options_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+type formAddress struct {
+	City    string `url:"city"`
+	Country string `url:"country,omitempty"`
+}
+
+type formPaging struct {
+	Limit int `url:"limit,omitempty"`
+}
+
+type chargeForm struct {
+	formPaging
+	Amount      int               `url:"amount"`
+	Currency    string            `url:"currency"`
+	Description string            `url:"description,omitempty"`
+	Capture     *bool             `url:"capture"`
+	Expand      []string          `url:"expand[]"`
+	Metadata    map[string]string `url:"metadata"`
+	Address     *formAddress      `url:"address"`
+	Created     time.Time         `url:"created"`
+	Secret      string            `url:"-"`
+	Note        string
+	internal    string
+}
+
+func TestBuilderForm(t *testing.T) {
+	capture := false
+	charge := chargeForm{
+		formPaging: formPaging{Limit: 3},
+		Amount:     2000,
+		Currency:   "usd",
+		Capture:    &capture,
+		Expand:     []string{"customer", "invoice"},
+		Metadata:   map[string]string{"order_id": "6735", "b": "2"},
+		Address:    &formAddress{City: "Pune"},
+		Created:    time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC),
+		Secret:     "sk",
+		Note:       "n",
+		internal:   "i",
+	}
+
+	opts := options.NewRequestOptionsBuilder().
+		Form(charge).
+		Form(map[string]interface{}{"source": "tok_visa"}).
+		Build()
+
+	want := url.Values{
+		"limit":              {"3"},
+		"amount":             {"2000"},
+		"currency":           {"usd"},
+		"capture":            {"false"},
+		"expand[]":           {"customer", "invoice"},
+		"metadata[b]":        {"2"},
+		"metadata[order_id]": {"6735"},
+		"address[city]":      {"Pune"},
+		"created":            {"2026-10-15T08:00:00Z"},
+		"Note":               {"n"},
+		"source":             {"tok_visa"},
+	}
+	if got := opts.Form.Encode(); got != want.Encode() {
+		t.Errorf("expected form %q, got %q", want.Encode(), got)
+	}
+
+	values := options.EncodeForm(url.Values{"a": {"1", "2"}})
+	if values.Encode() != "a=1&a=2" {
+		t.Errorf("expected url.Values to be kept, got %q", values.Encode())
+	}
+	if len(options.EncodeForm(nil)) != 0 {
+		t.Error("expected no values for nil")
+	}
+}
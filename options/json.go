@@ -0,0 +1,67 @@
+package options
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// JSON sets the request body to v encoded as JSON, with a Content-Type of
+// application/json unless one is set. Should v not encode, the error is
+// returned when the request is sent.
+func (b *RequestOptionsBuilder) JSON(v interface{}) *RequestOptionsBuilder {
+	data, err := json.Marshal(v)
+	if err != nil {
+		b.options.Body = ""
+		b.options.BodyReader = &errReader{err: fmt.Errorf("failed to encode JSON body: %v", err)}
+	} else {
+		b.options.Body = string(data)
+		b.options.BodyReader = nil
+	}
+	if b.options.Headers.Get("Content-Type") == "" {
+		b.options.Headers.Set("Content-Type", "application/json")
+	}
+	return b
+}
+
+// JSONContentType is how CurlJSON checks the Content-Type of a response
+// before decoding it, catching HTML error pages of proxies and gateways
+// served in place of API responses.
+type JSONContentType string
+
+const (
+	// JSONContentTypeAny decodes any response, the default
+	JSONContentTypeAny JSONContentType = ""
+	// JSONContentTypeStrict requires application/json or a +json type
+	JSONContentTypeStrict JSONContentType = "strict"
+	// JSONContentTypeText also accepts text/plain, as some APIs send
+	JSONContentTypeText JSONContentType = "text"
+	// JSONContentTypeSniff also accepts responses of other types whose body
+	// starts like a JSON object or array
+	JSONContentTypeSniff JSONContentType = "sniff"
+)
+
+// SetJSONContentType sets how CurlJSON checks the Content-Type of the
+// response.
+func (b *RequestOptionsBuilder) SetJSONContentType(check JSONContentType) *RequestOptionsBuilder {
+	b.options.JSONContentType = check
+	return b
+}
+
+// SetPreDecodeHook sets a check run on responses before their body is
+// decoded by CurlJSON, CurlDecode and the other decoding helpers, such as
+// limiting their Content-Length, requiring headers or verifying a
+// signature. An error from hook is returned instead of decoding the body.
+func (b *RequestOptionsBuilder) SetPreDecodeHook(hook func(*http.Response) error) *RequestOptionsBuilder {
+	b.options.PreDecodeHook = hook
+	return b
+}
+
+// errReader fails every read with err.
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
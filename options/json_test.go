@@ -0,0 +1,71 @@
+package options_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+func TestJSON(t *testing.T) {
+	opts := options.NewRequestOptionsBuilder().
+		JSON(map[string]interface{}{"name": "gocurl", "stars": 1}).
+		Build()
+	if opts.Body != `{"name":"gocurl","stars":1}` {
+		t.Errorf("Body = %q", opts.Body)
+	}
+	if got := opts.Headers.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	opts = options.NewRequestOptionsBuilder().
+		AddHeader("Content-Type", "application/vnd.api+json").
+		JSON([]int{1}).
+		Build()
+	if got := opts.Headers.Get("Content-Type"); got != "application/vnd.api+json" {
+		t.Errorf("Content-Type = %q, want the one set", got)
+	}
+
+	opts = options.NewRequestOptionsBuilder().JSON(func() {}).Build()
+	if opts.BodyReader == nil {
+		t.Fatal("an unsupported value should fail when read")
+	}
+	if _, err := io.ReadAll(opts.BodyReader); err == nil || !strings.Contains(err.Error(), "failed to encode JSON body") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// upperSerializer encodes strings in upper case.
+type upperSerializer struct{}
+
+func (upperSerializer) Marshal(v interface{}) ([]byte, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a string", v)
+	}
+	return []byte(strings.ToUpper(s)), nil
+}
+
+func (upperSerializer) Unmarshal(data []byte, v interface{}) error {
+	*v.(*string) = strings.ToLower(string(data))
+	return nil
+}
+
+func (upperSerializer) ContentType() string { return "text/x-upper" }
+
+func TestEncode(t *testing.T) {
+	opts := options.NewRequestOptionsBuilder().Encode(upperSerializer{}, "body").Build()
+	if opts.Body != "BODY" {
+		t.Errorf("Body = %q", opts.Body)
+	}
+	if got := opts.Headers.Get("Content-Type"); got != "text/x-upper" {
+		t.Errorf("Content-Type = %q, want text/x-upper", got)
+	}
+
+	opts = options.NewRequestOptionsBuilder().Encode(upperSerializer{}, 1).Build()
+	if _, err := io.ReadAll(opts.BodyReader); err == nil || !strings.Contains(err.Error(), "failed to encode text/x-upper body") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package options
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// JSONLinesIterator produces the values of a JSON Lines (NDJSON) request
+// body. Next returns io.EOF once there are no more values.
+type JSONLinesIterator interface {
+	Next() (interface{}, error)
+}
+
+// JSONLinesFunc adapts an ordinary function to the JSONLinesIterator
+// interface.
+type JSONLinesFunc func() (interface{}, error)
+
+// Next calls f().
+func (f JSONLinesFunc) Next() (interface{}, error) {
+	return f()
+}
+
+// jsonLinesReader encodes the values of an iterator as JSON Lines while it
+// is read, one value at a time.
+type jsonLinesReader struct {
+	values JSONLinesIterator
+	buf    bytes.Buffer
+	enc    *json.Encoder
+	err    error
+}
+
+// NewJSONLinesReader returns a reader of the values produced by values, each
+// encoded as JSON on its own line. Values are only requested as the reader
+// is drained, so a slow consumer such as a congested upload holds back the
+// producer rather than letting the body pile up in memory. An error of the
+// iterator, other than io.EOF, is returned by Read.
+func NewJSONLinesReader(values JSONLinesIterator) io.Reader {
+	r := &jsonLinesReader{values: values}
+	r.enc = json.NewEncoder(&r.buf)
+	return r
+}
+
+func (r *jsonLinesReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		v, err := r.values.Next()
+		if err != nil {
+			r.err = err
+			continue
+		}
+		// Encode appends the newline terminating the line
+		if err := r.enc.Encode(v); err != nil {
+			r.err = fmt.Errorf("failed to encode JSON line: %v", err)
+		}
+	}
+	return r.buf.Read(p)
+}
+
+// SetBodyJSONLines streams the values produced by values as a JSON Lines
+// request body with the application/x-ndjson content type, for bulk APIs
+// such as Elasticsearch _bulk. Like SetBodyReader, the body is sent chunked
+// and the request is not retried.
+func (b *RequestOptionsBuilder) SetBodyJSONLines(values JSONLinesIterator) *RequestOptionsBuilder {
+	b.options.BodyReader = NewJSONLinesReader(values)
+	if b.options.Headers.Get("Content-Type") == "" {
+		b.options.Headers.Set("Content-Type", "application/x-ndjson")
+	}
+	return b
+}
@@ -0,0 +1,74 @@
+package options_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+func TestSetBodyJSONLines(t *testing.T) {
+	docs := []interface{}{
+		map[string]interface{}{"index": map[string]string{"_id": "1"}},
+		map[string]string{"title": "gocurl"},
+	}
+	produced := 0
+	opts := options.NewRequestOptionsBuilder().
+		SetBodyJSONLines(options.JSONLinesFunc(func() (interface{}, error) {
+			if produced == len(docs) {
+				return nil, io.EOF
+			}
+			produced++
+			return docs[produced-1], nil
+		})).
+		Build()
+
+	if got := opts.Headers.Get("Content-Type"); got != "application/x-ndjson" {
+		t.Errorf("expected the NDJSON content type, got %q", got)
+	}
+	// Values are only produced as the body is read
+	if produced != 0 {
+		t.Errorf("expected no value to be produced before reading, got %d", produced)
+	}
+	buf := make([]byte, 4)
+	if _, err := opts.BodyReader.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	if produced != 1 {
+		t.Errorf("expected one value to be produced, got %d", produced)
+	}
+
+	rest, err := io.ReadAll(opts.BodyReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\"index\":{\"_id\":\"1\"}}\n{\"title\":\"gocurl\"}\n"
+	if got := string(buf) + string(rest); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestJSONLinesReaderErrors(t *testing.T) {
+	failure := errors.New("source failed")
+	calls := 0
+	r := options.NewJSONLinesReader(options.JSONLinesFunc(func() (interface{}, error) {
+		calls++
+		if calls == 1 {
+			return 1, nil
+		}
+		return nil, failure
+	}))
+	data, err := io.ReadAll(r)
+	if !errors.Is(err, failure) || string(data) != "1\n" {
+		t.Errorf("expected the source error after the first line, got %q, %v", data, err)
+	}
+
+	r = options.NewJSONLinesReader(options.JSONLinesFunc(func() (interface{}, error) {
+		return func() {}, nil
+	}))
+	if _, err := io.ReadAll(r); err == nil || !strings.Contains(err.Error(), "failed to encode") {
+		t.Errorf("expected an encoding error, got %v", err)
+	}
+}
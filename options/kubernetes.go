@@ -0,0 +1,62 @@
+package options
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/maniartech/gocurl/middlewares"
+)
+
+// serviceAccountDir is where Kubernetes mounts the service account of a pod.
+var serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// KubernetesAPIServer returns the base URL of the Kubernetes API server as
+// seen from inside a pod, from the KUBERNETES_SERVICE_HOST and
+// KUBERNETES_SERVICE_PORT variables, or https://kubernetes.default.svc when
+// they are not set.
+func KubernetesAPIServer() string {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" {
+		return "https://kubernetes.default.svc"
+	}
+	if port == "" {
+		port = "443"
+	}
+	return "https://" + net.JoinHostPort(host, port)
+}
+
+// SetKubernetesInCluster configures the request to call the Kubernetes API
+// from inside a pod: the API server certificate is verified against the
+// cluster CA and requests authenticate with the service account token,
+// both read from the service account mounted in the pod.
+//
+// The token is read again for every request, as the kubelet rotates it. A
+// URL that is only a path, such as /api/v1/namespaces, is resolved against
+// KubernetesAPIServer, so set it before calling SetKubernetesInCluster.
+func (b *RequestOptionsBuilder) SetKubernetesInCluster() *RequestOptionsBuilder {
+	b.options.CAFile = filepath.Join(serviceAccountDir, "ca.crt")
+	if b.options.URL == "" || strings.HasPrefix(b.options.URL, "/") {
+		b.options.URL = KubernetesAPIServer() + b.options.URL
+	}
+	b.options.Interceptors = append(b.options.Interceptors, kubernetesToken(filepath.Join(serviceAccountDir, "token")))
+	return b
+}
+
+// kubernetesToken authenticates requests with the service account token at
+// path, unless they already carry an Authorization header.
+func kubernetesToken(path string) middlewares.Interceptor {
+	return func(req *http.Request, next middlewares.RoundTripFunc) (*http.Response, error) {
+		if req.Header.Get("Authorization") == "" {
+			token, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the service account token: %v", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+		}
+		return next(req)
+	}
+}
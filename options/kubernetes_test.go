@@ -0,0 +1,69 @@
+package options
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetKubernetesInCluster(t *testing.T) {
+	dir := t.TempDir()
+	defer func(old string) { serviceAccountDir = old }(serviceAccountDir)
+	serviceAccountDir = dir
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("first\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KUBERNETES_SERVICE_HOST", "fd00::1")
+	t.Setenv("KUBERNETES_SERVICE_PORT", "6443")
+
+	opts := NewRequestOptionsBuilder().SetURL("/api/v1/namespaces").SetKubernetesInCluster().Build()
+	if opts.URL != "https://[fd00::1]:6443/api/v1/namespaces" {
+		t.Errorf("unexpected URL %q", opts.URL)
+	}
+	if opts.CAFile != filepath.Join(dir, "ca.crt") {
+		t.Errorf("unexpected CA file %q", opts.CAFile)
+	}
+	if len(opts.Interceptors) != 1 {
+		t.Fatalf("expected one interceptor, got %d", len(opts.Interceptors))
+	}
+
+	var auth string
+	next := func(req *http.Request) (*http.Response, error) {
+		auth = req.Header.Get("Authorization")
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}
+	send := func(header http.Header) {
+		req, _ := http.NewRequest(http.MethodGet, opts.URL, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if _, err := opts.Interceptors[0](req, next); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	send(nil)
+	if auth != "Bearer first" {
+		t.Errorf("unexpected Authorization %q", auth)
+	}
+	// Rotated tokens are picked up
+	os.WriteFile(filepath.Join(dir, "token"), []byte("second"), 0600)
+	send(nil)
+	if auth != "Bearer second" {
+		t.Errorf("unexpected Authorization %q", auth)
+	}
+	send(http.Header{"Authorization": {"Bearer mine"}})
+	if auth != "Bearer mine" {
+		t.Errorf("expected an explicit Authorization to be kept, got %q", auth)
+	}
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	opts = NewRequestOptionsBuilder().SetURL("https://example.com/").SetKubernetesInCluster().Build()
+	if opts.URL != "https://example.com/" {
+		t.Errorf("expected an absolute URL to be kept, got %q", opts.URL)
+	}
+	if KubernetesAPIServer() != "https://kubernetes.default.svc" {
+		t.Errorf("unexpected default API server %q", KubernetesAPIServer())
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"time"
 
+	"github.com/maniartech/gocurl/audit"
 	"github.com/maniartech/gocurl/middlewares"
 )
 
@@ -32,12 +35,32 @@ type RequestOptions struct {
 	Insecure  bool        `json:"insecure,omitempty"`
 	TLSConfig *tls.Config `json:"-"` // Not exported to JSON
 
+	// TLSSessionCache caches TLS sessions so that new connections resume
+	// them instead of doing a full handshake
+	TLSSessionCache tls.ClientSessionCache `json:"-"`
+	// NoTLSSessionResumption disables resuming TLS sessions, like curl
+	// --no-sessionid
+	NoTLSSessionResumption bool `json:"no_tls_session_resumption,omitempty"`
+
 	// Proxy settings
 	Proxy string `json:"proxy,omitempty"`
 
+	// ProxyPAC is the URL or path of a proxy auto-config file choosing the
+	// proxy of each request. Proxy takes precedence over it.
+	ProxyPAC string `json:"proxy_pac,omitempty"`
+
+	// AltSvc is the file alternative services advertised by Alt-Svc headers
+	// are saved to and used from, like curl --alt-svc. AltSvcStore keeps
+	// them elsewhere and takes precedence over it
+	AltSvc      string      `json:"alt_svc,omitempty"`
+	AltSvcStore AltSvcStore `json:"-"`
+
 	// Timeout settings
 	Timeout        time.Duration `json:"timeout,omitempty"`
 	ConnectTimeout time.Duration `json:"connect_timeout,omitempty"`
+	// AttemptTimeout limits each attempt of a retried request, including
+	// reading its response, while Timeout and the context bound them all
+	AttemptTimeout time.Duration `json:"attempt_timeout,omitempty"`
 
 	// Redirect behavior
 	FollowRedirects bool `json:"follow_redirects,omitempty"`
@@ -50,6 +73,14 @@ type RequestOptions struct {
 	HTTP2     bool `json:"http2,omitempty"`
 	HTTP2Only bool `json:"http2_only,omitempty"`
 
+	// RawHeaders sends the headers over HTTP/1.1 exactly as given, without
+	// canonicalizing their names: first those named in HeaderOrder, in that
+	// order and casing, then the others sorted. It is meant for debugging
+	// servers sensitive to header order, and connects directly, without
+	// proxies or connection reuse.
+	RawHeaders  bool     `json:"raw_headers,omitempty"`
+	HeaderOrder []string `json:"header_order,omitempty"`
+
 	// Cookie handling
 	Cookies   []*http.Cookie `json:"cookies,omitempty"`
 	CookieJar http.CookieJar `json:"-"` // Not exported to JSON
@@ -61,23 +92,107 @@ type RequestOptions struct {
 	// File upload
 	FileUpload *FileUpload `json:"file_upload,omitempty"`
 
+	// UploadFile is sent as the raw request body, like curl -T
+	UploadFile string `json:"upload_file,omitempty"`
+
+	// BodyReader is streamed as the request body, like curl -T - with stdin.
+	// Its length is unknown, so it is sent chunked over HTTP/1.1, and as it
+	// can only be read once the request cannot be retried or signed. It is
+	// not closed.
+	BodyReader io.Reader `json:"-"`
+
+	// MmapUploads memory-maps uploaded files on 64-bit Unix platforms
+	// instead of reading them, saving a copy per byte sent
+	MmapUploads bool `json:"mmap_uploads,omitempty"`
+
 	// Retry configuration
 	RetryConfig *RetryConfig `json:"retry_config,omitempty"`
 
+	// RetryAfter retries rate limited responses after the delay the server asks for
+	RetryAfter *RetryAfterPolicy `json:"retry_after,omitempty"`
+
+	// Policy restricts the hosts, schemes, methods, body size and redirects
+	// of the request
+	Policy *Policy `json:"policy,omitempty"`
+
+	// IdempotencyKey is sent as the Idempotency-Key header and reused across
+	// retries. Use IdempotencyKeyAuto to generate a fresh UUID per request.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+
 	// Output options
 	OutputFile string `json:"output_file,omitempty"`
 	Silent     bool   `json:"silent,omitempty"`
 	Verbose    bool   `json:"verbose,omitempty"`
 
+	// OutputDir is the directory a relative OutputFile is written in and
+	// CreateDirs creates its missing directories, like curl --output-dir and
+	// --create-dirs
+	OutputDir  string `json:"output_dir,omitempty"`
+	CreateDirs bool   `json:"create_dirs,omitempty"`
+
+	// ETagSave is the file the ETag of a successful response is saved to,
+	// and ETagCompare the file of the ETag sent as If-None-Match, like curl
+	// --etag-save and --etag-compare
+	ETagSave    string `json:"etag_save,omitempty"`
+	ETagCompare string `json:"etag_compare,omitempty"`
+
+	// ETagStore does the same by URL for many resources: requests are
+	// conditional on the ETag it holds and it stores those of responses
+	ETagStore ETagStore `json:"-"`
+
+	// ResponseTee receives a raw copy of the response body as it is read
+	ResponseTee io.Writer `json:"-"`
+
+	// Fail turns HTTP error statuses (400 and above) into errors, like curl
+	// --fail. FailWithBody does the same but still outputs the body.
+	Fail         bool `json:"fail,omitempty"`
+	FailWithBody bool `json:"fail_with_body,omitempty"`
+
+	// ErrorModel is a pointer to the struct error responses (400 and above)
+	// are decoded into from JSON. They then fail the request with a fresh
+	// value of its type, as the Model of a *gocurl.HTTPError
+	ErrorModel interface{} `json:"-"`
+
+	// WriteOut is printed after the response, like curl -w
+	WriteOut string `json:"write_out,omitempty"`
+
+	// ResponseSchema is a JSON Schema the response body must satisfy
+	ResponseSchema string `json:"response_schema,omitempty"`
+
+	// JSONContentType is how CurlJSON checks the Content-Type of the
+	// response before decoding it
+	JSONContentType JSONContentType `json:"json_content_type,omitempty"`
+
+	// PreDecodeHook checks the response before CurlJSON, CurlDecode and the
+	// other decoding helpers decode its body, which is skipped when it
+	// returns an error
+	PreDecodeHook func(*http.Response) error `json:"-"`
+
+	// SpoolThreshold is the body size in bytes above which the response body
+	// is written to a temporary file in SpoolDir instead of memory, and only
+	// read from resp.Body
+	SpoolThreshold int64  `json:"spool_threshold,omitempty"`
+	SpoolDir       string `json:"spool_dir,omitempty"`
+
 	// Advanced options
 	Context           context.Context              `json:"-"` // Not exported to JSON
 	RequestID         string                       `json:"request_id,omitempty"`
 	Middleware        []middlewares.MiddlewareFunc `json:"-"`
+	Interceptors      []middlewares.Interceptor    `json:"-"`
+	Logger            *slog.Logger                 `json:"-"`
+	Signer            Signer                       `json:"-"`
+	BodyDigest        BodyDigest                   `json:"body_digest,omitempty"`
+	Recorder          Recorder                     `json:"-"`
+	Auditor           audit.Sink                   `json:"-"`
 	ResponseBodyLimit int64                        `json:"response_body_limit,omitempty"`
 	ResponseDecoder   ResponseDecoder              `json:"-"`
 	Metrics           *RequestMetrics              `json:"metrics,omitempty"`
 }
 
+// IdempotencyKeyAuto asks gocurl to generate a random UUID as the
+// Idempotency-Key of each request.
+const IdempotencyKeyAuto = "auto"
+
 // NewRequestOptions creates a new RequestOptions with default values aligned to cURL's defaults.
 func NewRequestOptions(url string) *RequestOptions {
 	return &RequestOptions{
@@ -121,6 +236,30 @@ type RetryConfig struct {
 	RetryOnHTTP []int         `json:"retry_on_http"`
 }
 
+// RetryAfterPolicy retries responses such as 429 Too Many Requests and 503
+// Service Unavailable after the delay announced by the server through the
+// Retry-After, RateLimit-* or X-RateLimit-* headers. It is applied on top of
+// RetryConfig, which retries with a fixed delay.
+type RetryAfterPolicy struct {
+	MaxRetries  int           `json:"max_retries"`
+	MaxWait     time.Duration `json:"max_wait,omitempty"`     // Longer waits are not retried; 0 means no limit
+	DefaultWait time.Duration `json:"default_wait,omitempty"` // Used when the response carries no delay hint; 0 disables the retry
+	StatusCodes []int         `json:"status_codes,omitempty"` // Defaults to 429 and 503
+
+	// OnWait, if set, is called before each wait.
+	OnWait func(RetryAfterWait) `json:"-"`
+}
+
+// RetryAfterWait describes a wait performed by a RetryAfterPolicy.
+type RetryAfterWait struct {
+	Attempt    int           // 1 for the first retry
+	StatusCode int           // Status of the response that triggered the wait
+	Wait       time.Duration // How long the request is delayed
+	Source     string        // Header the delay was taken from, empty for DefaultWait
+	Remaining  int           // Remaining quota reported by the server, -1 if unknown
+	Reset      time.Time     // When the quota resets, zero if unknown
+}
+
 // ResponseDecoder is a function type for custom response decoding.
 type ResponseDecoder func(*http.Response) (interface{}, error)
 
@@ -149,6 +288,14 @@ func (ro *RequestOptions) Clone() *RequestOptions {
 		clone.QueryParams[k] = append([]string(nil), v...)
 	}
 
+	if ro.HeaderOrder != nil {
+		clone.HeaderOrder = append([]string(nil), ro.HeaderOrder...)
+	}
+
+	if ro.Cookies != nil {
+		clone.Cookies = append([]*http.Cookie(nil), ro.Cookies...)
+	}
+
 	// Deep copy other pointer fields as needed
 	if ro.BasicAuth != nil {
 		clonedBasicAuth := *ro.BasicAuth
@@ -165,14 +312,25 @@ func (ro *RequestOptions) Clone() *RequestOptions {
 		clone.RetryConfig = &clonedRetryConfig
 	}
 
+	if ro.RetryAfter != nil {
+		clonedRetryAfter := *ro.RetryAfter
+		clone.RetryAfter = &clonedRetryAfter
+	}
+
+	if ro.Policy != nil {
+		clonedPolicy := *ro.Policy
+		clone.Policy = &clonedPolicy
+	}
+
 	if ro.Metrics != nil {
 		clonedMetrics := *ro.Metrics
 		clone.Metrics = &clonedMetrics
 	}
 
 	// Note: We're not deep copying the Context, TLSConfig, CookieJar,
-	// Middleware, or ResponseDecoder as these are typically shared or
-	// would require more complex deep copying logic.
+	// Middleware, Interceptors, Logger, Signer, Recorder, Auditor, BodyReader,
+	// ResponseTee, ResponseDecoder, ErrorModel or PreDecodeHook as these are
+	// typically shared or would require more complex deep copying logic.
 
 	return &clone
 }
@@ -0,0 +1,29 @@
+package options
+
+// Policy restricts the requests that may be sent, so that platforms running
+// curl commands provided by users can sandbox them. It is checked before
+// each request and redirect is sent. Empty lists allow everything and deny
+// lists take precedence over allow lists.
+//
+// Hosts are matched against the host name of the URL as written:
+// "example.com" matches that host only and "*.example.com" its subdomains.
+// CIDR blocks such as "10.0.0.0/8" match IP addresses, including those host
+// names resolve to, which are checked as connections are made. Proxies must
+// be allowed like hosts, and as they resolve the hosts requested through
+// them, host names not allowed by name are rejected behind a proxy when
+// the hosts have CIDR blocks.
+type Policy struct {
+	AllowedHosts   []string `json:"allowed_hosts,omitempty"`
+	DeniedHosts    []string `json:"denied_hosts,omitempty"`
+	AllowedSchemes []string `json:"allowed_schemes,omitempty"`
+	AllowedMethods []string `json:"allowed_methods,omitempty"`
+	DeniedMethods  []string `json:"denied_methods,omitempty"`
+
+	// MaxBodySize is the largest request body allowed, in bytes; 0 means
+	// no limit
+	MaxBodySize int64 `json:"max_body_size,omitempty"`
+
+	// MaxRedirects caps the redirects followed whatever the request asks
+	// for; 0 means no cap and a negative value forbids redirects
+	MaxRedirects int `json:"max_redirects,omitempty"`
+}
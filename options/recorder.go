@@ -0,0 +1,15 @@
+package options
+
+import (
+	"net/http"
+	"time"
+)
+
+// Recorder captures every request/response exchange performed with the
+// options it is set on, e.g. to replay it later.
+//
+// reqBody and respBody hold the complete bodies; resp is nil when the request
+// failed before a response was received.
+type Recorder interface {
+	Record(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte, duration time.Duration)
+}
@@ -0,0 +1,32 @@
+package options
+
+import "fmt"
+
+// Serializer encodes and decodes bodies of one media type, such as JSON,
+// msgpack, CBOR or protobuf.
+type Serializer interface {
+	// Marshal encodes v.
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal decodes data into v.
+	Unmarshal(data []byte, v interface{}) error
+	// ContentType returns the Content-Type of the encoded bodies.
+	ContentType() string
+}
+
+// Encode sets the request body to v encoded by s, with s's Content-Type
+// unless one is set, generalizing JSON to other formats. Should v not
+// encode, the error is returned when the request is sent.
+func (b *RequestOptionsBuilder) Encode(s Serializer, v interface{}) *RequestOptionsBuilder {
+	data, err := s.Marshal(v)
+	if err != nil {
+		b.options.Body = ""
+		b.options.BodyReader = &errReader{err: fmt.Errorf("failed to encode %s body: %v", s.ContentType(), err)}
+	} else {
+		b.options.Body = string(data)
+		b.options.BodyReader = nil
+	}
+	if b.options.Headers.Get("Content-Type") == "" {
+		b.options.Headers.Set("Content-Type", s.ContentType())
+	}
+	return b
+}
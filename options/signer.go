@@ -0,0 +1,22 @@
+package options
+
+import "net/http"
+
+// Signer signs a fully built request right before it is sent.
+//
+// It is invoked after headers, authentication and middleware have been
+// applied, so implementations see exactly what goes on the wire. bodyHash is
+// the lowercase hex-encoded SHA-256 digest of the request body (the digest of
+// the empty string when there is no body), which most HMAC schemes include in
+// their canonical request.
+type Signer interface {
+	Sign(req *http.Request, bodyHash string) error
+}
+
+// SignerFunc adapts an ordinary function to the Signer interface.
+type SignerFunc func(req *http.Request, bodyHash string) error
+
+// Sign calls f(req, bodyHash).
+func (f SignerFunc) Sign(req *http.Request, bodyHash string) error {
+	return f(req, bodyHash)
+}
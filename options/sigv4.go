@@ -0,0 +1,154 @@
+package options
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sigV4TimeFormat is the format of X-Amz-Date.
+const sigV4TimeFormat = "20060102T150405Z"
+
+// SigV4 is a Signer implementing AWS Signature Version 4, as used by S3 and
+// S3-compatible object stores as well as the other AWS APIs.
+//
+// The signed headers are Host, Content-Type, Content-MD5, Range and the
+// X-Amz-* headers. X-Amz-Date is set to the current time unless the request
+// has one, and for the s3 service X-Amz-Content-Sha256 is set to the hash of
+// the body.
+type SigV4 struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Temporary credentials only
+	Region          string // e.g. us-east-1
+	Service         string // e.g. s3
+}
+
+// Sign implements Signer.
+func (s *SigV4) Sign(req *http.Request, bodyHash string) error {
+	if s.AccessKeyID == "" || s.SecretAccessKey == "" {
+		return fmt.Errorf("sigv4: missing credentials")
+	}
+	if req.Header.Get("X-Amz-Date") == "" {
+		req.Header.Set("X-Amz-Date", time.Now().UTC().Format(sigV4TimeFormat))
+	}
+	date, err := time.Parse(sigV4TimeFormat, req.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return fmt.Errorf("sigv4: invalid X-Amz-Date: %v", err)
+	}
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+	if s.Service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", bodyHash)
+	}
+
+	// Canonical headers, sorted by name
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		switch {
+		case name == "content-type", name == "content-md5", name == "range", strings.HasPrefix(name, "x-amz-"):
+			trimmed := make([]string, len(values))
+			for i, value := range values {
+				trimmed[i] = strings.Join(strings.Fields(value), " ")
+			}
+			headers[name] = strings.Join(trimmed, ",")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		sigV4Escape(path, false),
+		sigV4Query(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		bodyHash,
+	}, "\n")
+
+	day := date.Format("20060102")
+	scope := day + "/" + s.Region + "/" + s.Service + "/aws4_request"
+	digest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + date.Format(sigV4TimeFormat) + "\n" + scope + "\n" + hex.EncodeToString(digest[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), day)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// sigV4Query returns the canonical query string of query, sorted by key
+// and value.
+func sigV4Query(query url.Values) string {
+	escaped := make(map[string][]string, len(query))
+	keys := make([]string, 0, len(query))
+	for key, values := range query {
+		key = sigV4Escape(key, true)
+		keys = append(keys, key)
+		for _, value := range values {
+			escaped[key] = append(escaped[key], sigV4Escape(value, true))
+		}
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, key := range keys {
+		values := escaped[key]
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, key+"="+value)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// sigV4Escape percent-encodes every byte of s but the unreserved
+// characters, and slashes unless escapeSlash is set.
+func sigV4Escape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
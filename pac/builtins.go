@@ -0,0 +1,194 @@
+package pac
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// builtins returns the PAC functions defined by Netscape, bound to s.
+func (s *Script) builtins() map[string]value {
+	str := func(args []value, i int) string { return toString(arg(args, i)) }
+	return map[string]value{
+		"isPlainHostName": builtin(func(args []value) (value, error) {
+			return !strings.Contains(str(args, 0), "."), nil
+		}),
+		"dnsDomainIs": builtin(func(args []value) (value, error) {
+			return strings.HasSuffix(strings.ToLower(str(args, 0)), strings.ToLower(str(args, 1))), nil
+		}),
+		"localHostOrDomainIs": builtin(func(args []value) (value, error) {
+			host, hostdom := strings.ToLower(str(args, 0)), strings.ToLower(str(args, 1))
+			return host == hostdom || !strings.Contains(host, ".") && strings.HasPrefix(hostdom, host+"."), nil
+		}),
+		"isResolvable": builtin(func(args []value) (value, error) {
+			return s.resolve(str(args, 0)) != "", nil
+		}),
+		"dnsResolve": builtin(func(args []value) (value, error) {
+			if ip := s.resolve(str(args, 0)); ip != "" {
+				return ip, nil
+			}
+			return nil, nil
+		}),
+		"isInNet": builtin(func(args []value) (value, error) {
+			ip := net.ParseIP(s.resolve(str(args, 0))).To4()
+			pattern, mask := net.ParseIP(str(args, 1)).To4(), net.ParseIP(str(args, 2)).To4()
+			if ip == nil || pattern == nil || mask == nil {
+				return false, nil
+			}
+			return ip.Mask(net.IPMask(mask)).Equal(pattern.Mask(net.IPMask(mask))), nil
+		}),
+		"myIpAddress": builtin(func(args []value) (value, error) {
+			return myIPAddress(), nil
+		}),
+		"dnsDomainLevels": builtin(func(args []value) (value, error) {
+			return float64(strings.Count(str(args, 0), ".")), nil
+		}),
+		"convert_addr": builtin(func(args []value) (value, error) {
+			ip := net.ParseIP(str(args, 0)).To4()
+			if ip == nil {
+				return float64(0), nil
+			}
+			return float64(uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3])), nil
+		}),
+		"shExpMatch": builtin(func(args []value) (value, error) {
+			return shExpMatch(str(args, 0), str(args, 1)), nil
+		}),
+		"weekdayRange": builtin(func(args []value) (value, error) {
+			return s.weekdayRange(args)
+		}),
+		"timeRange": builtin(func(args []value) (value, error) {
+			return s.timeRange(args)
+		}),
+		"dateRange": builtin(func(args []value) (value, error) {
+			return nil, fmt.Errorf("dateRange is not supported")
+		}),
+		"alert": builtin(func(args []value) (value, error) {
+			return undefined, nil
+		}),
+	}
+}
+
+// resolve returns the first IPv4 address of host, or an empty string.
+func (s *Script) resolve(host string) string {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	lookup := s.LookupHost
+	if lookup == nil {
+		lookup = net.LookupHost
+	}
+	addrs, err := lookup(host)
+	if err != nil {
+		return ""
+	}
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr); ip != nil && ip.To4() != nil {
+			return ip.String()
+		}
+	}
+	return ""
+}
+
+// myIPAddress returns the address of the interface used to reach the
+// internet, or 127.0.0.1. No packet is sent.
+func myIPAddress() string {
+	conn, err := net.Dial("udp", "198.51.100.1:80")
+	if err != nil {
+		return "127.0.0.1"
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String()
+}
+
+// shExpMatch matches s against a shell expression where * matches any
+// sequence of characters, slashes included, and ? a single one.
+func shExpMatch(s, pattern string) bool {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, c := range pattern {
+		switch c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile("(?s)" + b.String())
+	return err == nil && re.MatchString(s)
+}
+
+// now returns the time the date functions compare with, in UTC when the
+// last argument is "GMT".
+func (s *Script) now(args []value) ([]value, time.Time) {
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	t := now()
+	if len(args) > 0 && args[len(args)-1] == "GMT" {
+		return args[:len(args)-1], t.UTC()
+	}
+	return args, t
+}
+
+var weekdays = map[string]int{"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6}
+
+func (s *Script) weekdayRange(args []value) (value, error) {
+	args, t := s.now(args)
+	if len(args) == 0 || len(args) > 2 {
+		return nil, fmt.Errorf("weekdayRange takes one or two weekdays")
+	}
+	var days []int
+	for _, a := range args {
+		day, ok := weekdays[toString(a)]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q", toString(a))
+		}
+		days = append(days, day)
+	}
+	today := int(t.Weekday())
+	if len(days) == 1 {
+		return today == days[0], nil
+	}
+	if days[0] <= days[1] {
+		return today >= days[0] && today <= days[1], nil
+	}
+	return today >= days[0] || today <= days[1], nil
+}
+
+// timeRange supports the hour, hour range, minute range and second range
+// forms. The end of hour and minute ranges is exclusive.
+func (s *Script) timeRange(args []value) (value, error) {
+	args, t := s.now(args)
+	n := make([]int, len(args))
+	for i, a := range args {
+		n[i] = int(toNumber(a))
+	}
+	secs := func(h, m, sec int) int { return h*3600 + m*60 + sec }
+	now := secs(t.Hour(), t.Minute(), t.Second())
+	var from, to int
+	switch len(n) {
+	case 1:
+		return t.Hour() == n[0], nil
+	case 2:
+		if n[0] == n[1] {
+			return t.Hour() == n[0], nil
+		}
+		from, to = secs(n[0], 0, 0), secs(n[1], 0, 0)-1
+	case 4:
+		from, to = secs(n[0], n[1], 0), secs(n[2], n[3], 0)-1
+	case 6:
+		from, to = secs(n[0], n[1], n[2]), secs(n[3], n[4], n[5])
+	default:
+		return nil, fmt.Errorf("timeRange takes 1, 2, 4 or 6 numbers")
+	}
+	if from <= to {
+		return now >= from && now <= to, nil
+	}
+	return now >= from || now <= to, nil
+}
@@ -0,0 +1,706 @@
+package pac
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// value is a JavaScript value: nil (null), undefinedType, bool, float64,
+// string, []value, *closure or builtin.
+type value interface{}
+
+type undefinedType struct{}
+
+var undefined = undefinedType{}
+
+// builtin is a function implemented in Go.
+type builtin func(args []value) (value, error)
+
+// closure is a script function with the scope it was defined in.
+type closure struct {
+	fn    *funcLit
+	scope *scope
+}
+
+const (
+	// maxDepth limits the nesting of function calls.
+	maxDepth = 100
+	// maxSteps limits the loop iterations of an evaluation.
+	maxSteps = 1000000
+)
+
+var errTooManySteps = errors.New("script runs too long")
+
+type scope struct {
+	vars   map[string]value
+	parent *scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{vars: map[string]value{}, parent: parent}
+}
+
+func (s *scope) lookup(name string) (value, bool) {
+	for ; s != nil; s = s.parent {
+		if v, ok := s.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// set assigns an existing variable, or a global as sloppy mode does.
+func (s *scope) set(name string, v value) {
+	for cur := s; ; cur = cur.parent {
+		if _, ok := cur.vars[name]; ok || cur.parent == nil {
+			cur.vars[name] = v
+			return
+		}
+	}
+}
+
+// interp evaluates statements; it is not safe for concurrent use.
+type interp struct {
+	depth int
+	steps int
+}
+
+type control int
+
+const (
+	ctrlNone control = iota
+	ctrlReturn
+	ctrlBreak
+	ctrlContinue
+)
+
+// hoist declares the functions of stmts in sc, as JavaScript does before
+// running them.
+func hoist(stmts []stmt, sc *scope) {
+	for _, s := range stmts {
+		if decl, ok := s.(*funcDecl); ok {
+			sc.vars[decl.fn.name] = &closure{fn: decl.fn, scope: sc}
+		}
+	}
+}
+
+func (in *interp) execAll(stmts []stmt, sc *scope) (control, value, error) {
+	for _, s := range stmts {
+		ctrl, v, err := in.exec(s, sc)
+		if err != nil || ctrl != ctrlNone {
+			return ctrl, v, err
+		}
+	}
+	return ctrlNone, nil, nil
+}
+
+func (in *interp) exec(s stmt, sc *scope) (control, value, error) {
+	switch s := s.(type) {
+	case *blockStmt:
+		hoist(s.stmts, sc)
+		return in.execAll(s.stmts, sc)
+	case *funcDecl:
+		sc.vars[s.fn.name] = &closure{fn: s.fn, scope: sc}
+	case *varStmt:
+		for i, name := range s.names {
+			var v value = undefined
+			if s.inits[i] != nil {
+				var err error
+				if v, err = in.eval(s.inits[i], sc); err != nil {
+					return ctrlNone, nil, err
+				}
+			} else if existing, ok := sc.vars[name]; ok {
+				v = existing
+			}
+			sc.vars[name] = v
+		}
+	case *exprStmt:
+		_, err := in.eval(s.x, sc)
+		return ctrlNone, nil, err
+	case *ifStmt:
+		cond, err := in.eval(s.cond, sc)
+		if err != nil {
+			return ctrlNone, nil, err
+		}
+		if truthy(cond) {
+			return in.exec(s.then, sc)
+		}
+		if s.els != nil {
+			return in.exec(s.els, sc)
+		}
+	case *forStmt:
+		return in.loop(s, sc)
+	case *returnStmt:
+		if s.value == nil {
+			return ctrlReturn, undefined, nil
+		}
+		v, err := in.eval(s.value, sc)
+		return ctrlReturn, v, err
+	case *breakStmt:
+		return ctrlBreak, nil, nil
+	case *contStmt:
+		return ctrlContinue, nil, nil
+	default:
+		return ctrlNone, nil, fmt.Errorf("unsupported statement %T", s)
+	}
+	return ctrlNone, nil, nil
+}
+
+func (in *interp) loop(s *forStmt, sc *scope) (control, value, error) {
+	if s.init != nil {
+		if _, _, err := in.exec(s.init, sc); err != nil {
+			return ctrlNone, nil, err
+		}
+	}
+	for {
+		if in.steps++; in.steps > maxSteps {
+			return ctrlNone, nil, errTooManySteps
+		}
+		if s.cond != nil {
+			cond, err := in.eval(s.cond, sc)
+			if err != nil {
+				return ctrlNone, nil, err
+			}
+			if !truthy(cond) {
+				return ctrlNone, nil, nil
+			}
+		}
+		ctrl, v, err := in.exec(s.body, sc)
+		if err != nil || ctrl == ctrlReturn {
+			return ctrl, v, err
+		}
+		if ctrl == ctrlBreak {
+			return ctrlNone, nil, nil
+		}
+		if s.update != nil {
+			if _, err := in.eval(s.update, sc); err != nil {
+				return ctrlNone, nil, err
+			}
+		}
+	}
+}
+
+func (in *interp) eval(x expr, sc *scope) (value, error) {
+	switch x := x.(type) {
+	case *literal:
+		return x.value, nil
+	case *ident:
+		v, ok := sc.lookup(x.name)
+		if !ok {
+			return nil, fmt.Errorf("%s is not defined", x.name)
+		}
+		return v, nil
+	case *arrayLit:
+		array := make([]value, len(x.elems))
+		for i, elem := range x.elems {
+			v, err := in.eval(elem, sc)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = v
+		}
+		return array, nil
+	case *funcLit:
+		return &closure{fn: x, scope: sc}, nil
+	case *unary:
+		if id, ok := x.x.(*ident); ok && x.op == "typeof" {
+			if _, defined := sc.lookup(id.name); !defined {
+				return "undefined", nil
+			}
+		}
+		v, err := in.eval(x.x, sc)
+		if err != nil {
+			return nil, err
+		}
+		switch x.op {
+		case "!":
+			return !truthy(v), nil
+		case "-":
+			return -toNumber(v), nil
+		case "+":
+			return toNumber(v), nil
+		default:
+			return typeOf(v), nil
+		}
+	case *binary:
+		return in.binary(x, sc)
+	case *condExpr:
+		cond, err := in.eval(x.cond, sc)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(cond) {
+			return in.eval(x.a, sc)
+		}
+		return in.eval(x.b, sc)
+	case *assign:
+		v, err := in.eval(x.value, sc)
+		if err != nil {
+			return nil, err
+		}
+		if x.op != "=" {
+			old, ok := sc.lookup(x.name)
+			if !ok {
+				return nil, fmt.Errorf("%s is not defined", x.name)
+			}
+			v = arithmetic(x.op[:1], old, v)
+		}
+		sc.set(x.name, v)
+		return v, nil
+	case *update:
+		old, ok := sc.lookup(x.name)
+		if !ok {
+			return nil, fmt.Errorf("%s is not defined", x.name)
+		}
+		n := toNumber(old)
+		updated := n + 1
+		if x.op == "--" {
+			updated = n - 1
+		}
+		sc.set(x.name, updated)
+		if x.prefix {
+			return updated, nil
+		}
+		return n, nil
+	case *member:
+		v, err := in.eval(x.x, sc)
+		if err != nil {
+			return nil, err
+		}
+		return property(v, x.name)
+	case *index:
+		v, err := in.eval(x.x, sc)
+		if err != nil {
+			return nil, err
+		}
+		i, err := in.eval(x.index, sc)
+		if err != nil {
+			return nil, err
+		}
+		if n, ok := i.(float64); ok {
+			switch v := v.(type) {
+			case []value:
+				if n >= 0 && int(n) < len(v) && n == math.Trunc(n) {
+					return v[int(n)], nil
+				}
+				return undefined, nil
+			case string:
+				if n >= 0 && int(n) < len(v) && n == math.Trunc(n) {
+					return v[int(n) : int(n)+1], nil
+				}
+				return undefined, nil
+			}
+		}
+		return property(v, toString(i))
+	case *call:
+		fn, err := in.eval(x.callee, sc)
+		if err != nil {
+			return nil, err
+		}
+		args := make([]value, len(x.args))
+		for i, arg := range x.args {
+			if args[i], err = in.eval(arg, sc); err != nil {
+				return nil, err
+			}
+		}
+		return in.call(fn, args, x.callee)
+	}
+	return nil, fmt.Errorf("unsupported expression %T", x)
+}
+
+func (in *interp) binary(x *binary, sc *scope) (value, error) {
+	l, err := in.eval(x.l, sc)
+	if err != nil {
+		return nil, err
+	}
+	switch x.op {
+	case "&&":
+		if !truthy(l) {
+			return l, nil
+		}
+		return in.eval(x.r, sc)
+	case "||":
+		if truthy(l) {
+			return l, nil
+		}
+		return in.eval(x.r, sc)
+	}
+	r, err := in.eval(x.r, sc)
+	if err != nil {
+		return nil, err
+	}
+	switch x.op {
+	case ",":
+		return r, nil
+	case "===":
+		return strictEquals(l, r), nil
+	case "!==":
+		return !strictEquals(l, r), nil
+	case "==":
+		return looseEquals(l, r), nil
+	case "!=":
+		return !looseEquals(l, r), nil
+	case "<", ">", "<=", ">=":
+		return compare(x.op, l, r), nil
+	}
+	return arithmetic(x.op, l, r), nil
+}
+
+func (in *interp) call(fn value, args []value, callee expr) (value, error) {
+	switch fn := fn.(type) {
+	case builtin:
+		return fn(args)
+	case *closure:
+		if in.depth >= maxDepth {
+			return nil, fmt.Errorf("maximum call depth exceeded")
+		}
+		in.depth++
+		defer func() { in.depth-- }()
+
+		sc := newScope(fn.scope)
+		for i, param := range fn.fn.params {
+			if i < len(args) {
+				sc.vars[param] = args[i]
+			} else {
+				sc.vars[param] = undefined
+			}
+		}
+		sc.vars["arguments"] = args
+		hoist(fn.fn.body, sc)
+		ctrl, v, err := in.execAll(fn.fn.body, sc)
+		if err != nil {
+			return nil, err
+		}
+		if ctrl != ctrlReturn {
+			return undefined, nil
+		}
+		return v, nil
+	}
+	name := "expression"
+	switch c := callee.(type) {
+	case *ident:
+		name = c.name
+	case *member:
+		name = c.name
+	}
+	return nil, fmt.Errorf("%s is not a function", name)
+}
+
+func arithmetic(op string, l, r value) value {
+	if op == "+" {
+		_, ls := l.(string)
+		_, rs := r.(string)
+		if ls || rs {
+			return toString(l) + toString(r)
+		}
+	}
+	a, b := toNumber(l), toNumber(r)
+	switch op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	case "/":
+		return a / b
+	}
+	return math.Mod(a, b)
+}
+
+func compare(op string, l, r value) bool {
+	ls, lok := l.(string)
+	rs, rok := r.(string)
+	if lok && rok {
+		switch op {
+		case "<":
+			return ls < rs
+		case ">":
+			return ls > rs
+		case "<=":
+			return ls <= rs
+		}
+		return ls >= rs
+	}
+	a, b := toNumber(l), toNumber(r)
+	switch op {
+	case "<":
+		return a < b
+	case ">":
+		return a > b
+	case "<=":
+		return a <= b
+	}
+	return a >= b
+}
+
+func strictEquals(l, r value) bool {
+	switch l := l.(type) {
+	case nil, undefinedType, bool, float64, string:
+		return l == r
+	case []value:
+		rv, ok := r.([]value)
+		return ok && len(l) > 0 && len(rv) > 0 && &l[0] == &rv[0]
+	case builtin:
+		return false
+	}
+	return l == r
+}
+
+func looseEquals(l, r value) bool {
+	nullish := func(v value) bool { return v == nil || v == undefined }
+	if nullish(l) || nullish(r) {
+		return nullish(l) && nullish(r)
+	}
+	switch l.(type) {
+	case float64, bool:
+		if _, ok := r.(string); ok {
+			return toNumber(l) == toNumber(r)
+		}
+		if _, ok := r.(bool); ok {
+			return toNumber(l) == toNumber(r)
+		}
+	case string:
+		switch r.(type) {
+		case float64, bool:
+			return toNumber(l) == toNumber(r)
+		}
+	}
+	return strictEquals(l, r)
+}
+
+func truthy(v value) bool {
+	switch v := v.(type) {
+	case nil, undefinedType:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	}
+	return true
+}
+
+func toNumber(v value) float64 {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case float64:
+		return v
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "" {
+			return 0
+		}
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+	}
+	return math.NaN()
+}
+
+func toString(v value) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case undefinedType:
+		return "undefined"
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "NaN"
+		case math.IsInf(v, 1):
+			return "Infinity"
+		case math.IsInf(v, -1):
+			return "-Infinity"
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	case []value:
+		parts := make([]string, len(v))
+		for i, elem := range v {
+			if elem != nil && elem != undefined {
+				parts[i] = toString(elem)
+			}
+		}
+		return strings.Join(parts, ",")
+	}
+	return "function"
+}
+
+func typeOf(v value) string {
+	switch v.(type) {
+	case nil, []value:
+		return "object"
+	case undefinedType:
+		return "undefined"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	}
+	return "function"
+}
+
+// property returns the property name of v, including the string and array
+// methods PAC files commonly use.
+func property(v value, name string) (value, error) {
+	switch v := v.(type) {
+	case nil, undefinedType:
+		return nil, fmt.Errorf("cannot read property %s of %s", name, toString(v))
+	case string:
+		if name == "length" {
+			return float64(len(v)), nil
+		}
+		if method, ok := stringMethods[name]; ok {
+			return builtin(func(args []value) (value, error) { return method(v, args), nil }), nil
+		}
+	case []value:
+		switch name {
+		case "length":
+			return float64(len(v)), nil
+		case "indexOf":
+			return builtin(func(args []value) (value, error) {
+				for i, elem := range v {
+					if len(args) > 0 && strictEquals(elem, args[0]) {
+						return float64(i), nil
+					}
+				}
+				return float64(-1), nil
+			}), nil
+		case "join":
+			return builtin(func(args []value) (value, error) {
+				sep := ","
+				if len(args) > 0 && args[0] != undefined {
+					sep = toString(args[0])
+				}
+				parts := make([]string, len(v))
+				for i, elem := range v {
+					parts[i] = toString(elem)
+				}
+				return strings.Join(parts, sep), nil
+			}), nil
+		}
+	}
+	return undefined, nil
+}
+
+// arg returns the i-th argument, or undefined.
+func arg(args []value, i int) value {
+	if i < len(args) {
+		return args[i]
+	}
+	return undefined
+}
+
+// intArg returns the i-th argument as an integer, or def when it is missing.
+func intArg(args []value, i, def int) int {
+	v := arg(args, i)
+	if v == undefined {
+		return def
+	}
+	n := toNumber(v)
+	if math.IsNaN(n) {
+		return 0
+	}
+	return int(n)
+}
+
+// clamp limits i to [0, n].
+func clamp(i, n int) int {
+	return max(0, min(i, n))
+}
+
+var stringMethods = map[string]func(s string, args []value) value{
+	"toLowerCase": func(s string, args []value) value { return strings.ToLower(s) },
+	"toUpperCase": func(s string, args []value) value { return strings.ToUpper(s) },
+	"trim":        func(s string, args []value) value { return strings.TrimSpace(s) },
+	"indexOf": func(s string, args []value) value {
+		from := clamp(intArg(args, 1, 0), len(s))
+		i := strings.Index(s[from:], toString(arg(args, 0)))
+		if i < 0 {
+			return float64(-1)
+		}
+		return float64(from + i)
+	},
+	"lastIndexOf": func(s string, args []value) value {
+		return float64(strings.LastIndex(s, toString(arg(args, 0))))
+	},
+	"includes": func(s string, args []value) value {
+		return strings.Contains(s, toString(arg(args, 0)))
+	},
+	"startsWith": func(s string, args []value) value {
+		return strings.HasPrefix(s, toString(arg(args, 0)))
+	},
+	"endsWith": func(s string, args []value) value {
+		return strings.HasSuffix(s, toString(arg(args, 0)))
+	},
+	"charAt": func(s string, args []value) value {
+		i := intArg(args, 0, 0)
+		if i < 0 || i >= len(s) {
+			return ""
+		}
+		return s[i : i+1]
+	},
+	"substring": func(s string, args []value) value {
+		start, end := clamp(intArg(args, 0, 0), len(s)), clamp(intArg(args, 1, len(s)), len(s))
+		if start > end {
+			start, end = end, start
+		}
+		return s[start:end]
+	},
+	"substr": func(s string, args []value) value {
+		start := intArg(args, 0, 0)
+		if start < 0 {
+			start += len(s)
+		}
+		start = clamp(start, len(s))
+		end := clamp(start+intArg(args, 1, len(s)), len(s))
+		if end < start {
+			return ""
+		}
+		return s[start:end]
+	},
+	"slice": func(s string, args []value) value {
+		start, end := intArg(args, 0, 0), intArg(args, 1, len(s))
+		if start < 0 {
+			start += len(s)
+		}
+		if end < 0 {
+			end += len(s)
+		}
+		start, end = clamp(start, len(s)), clamp(end, len(s))
+		if start > end {
+			return ""
+		}
+		return s[start:end]
+	},
+	"split": func(s string, args []value) value {
+		var parts []string
+		if sep := arg(args, 0); sep == undefined {
+			parts = []string{s}
+		} else {
+			parts = strings.Split(s, toString(sep))
+		}
+		array := make([]value, len(parts))
+		for i, part := range parts {
+			array[i] = part
+		}
+		return array
+	},
+}
@@ -0,0 +1,62 @@
+package pac_test
+
+import (
+	"testing"
+
+	"github.com/maniartech/gocurl/pac"
+)
+
+func TestExpressions(t *testing.T) {
+	tests := []struct {
+		expr, expected string
+	}{
+		{`1 + 2 * 3`, "7"},
+		{`(1 + 2) * 3 % 4`, "1"},
+		{`"a" + 1 + 2`, "a12"},
+		{`1 + 2 + "a"`, "3a"},
+		{`10 / 4`, "2.5"},
+		{`-"3" + +"4"`, "1"},
+		{`"1" == 1`, "true"},
+		{`"1" === 1`, "false"},
+		{`null == undefined`, "true"},
+		{`null === undefined`, "false"},
+		{`"b" > "a" && 2 >= 2`, "true"},
+		{`0 || "" || "fallback"`, "fallback"},
+		{`"x" && 0`, "0"},
+		{`!"" ? "empty" : "not empty"`, "empty"},
+		{`typeof missing + "," + typeof 1 + "," + typeof "s" + "," + typeof isPlainHostName`, "undefined,number,string,function"},
+		{`"Example.COM".toLowerCase()`, "example.com"},
+		{`"proxy.example.com".split(".").length`, "3"},
+		{`"proxy.example.com".split(".")[1]`, "example"},
+		{`"abcdef".substring(4, 1)`, "bcd"},
+		{`"abcdef".substr(-3, 2)`, "de"},
+		{`"abcdef".slice(1, -1)`, "bcde"},
+		{`"abcdef".charAt(2) + "abcdef"[3]`, "cd"},
+		{`"a.b.c".indexOf(".", 2) + "," + "a.b.c".lastIndexOf(".")`, "3,3"},
+		{`["x", "y"].indexOf("y")`, "1"},
+		{`[1, "a", null].join("-")`, "1-a-null"},
+		{`(function (a, b) { return a + b; })(2, 3)`, "5"},
+		{`(function () { var n = 0; for (var i = 0; i < 10; i++) { if (i % 2) continue; if (i > 6) break; n += i; } return n; })()`, "12"},
+		{`(function () { var i = 3, s = ""; while (i--) s += i; return s; })()`, "210"},
+		{`dnsDomainLevels("www.example.com") + convert_addr("10.0.0.1")`, "167772163"},
+		{`localHostOrDomainIs("www", "www.example.com") && !localHostOrDomainIs("www.other.com", "www.example.com")`, "true"},
+		{`shExpMatch("http://a/b/c", "http://*/c") && shExpMatch("x.y", "x?y") && !shExpMatch("xy", "x.y")`, "true"},
+		{`isInNet("192.168.1.20", "192.168.0.0", "255.255.0.0") && !isInNet("192.169.1.20", "192.168.0.0", "255.255.0.0")`, "true"},
+		{`dnsResolve("127.0.0.1") + isResolvable("10.0.0.1")`, "127.0.0.1true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			script, err := pac.Parse("function FindProxyForURL(url, host) { return " + tt.expr + "; }")
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got, err := script.FindProxyForURL("http://example.com/", "example.com")
+			if err != nil {
+				t.Fatalf("FindProxyForURL() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package pac
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string  // identifier, punctuator or decoded string
+	num  float64 // value of numbers
+	pos  int
+}
+
+// punctuators are the operators of the supported JavaScript subset, longest
+// first so that they are matched greedily.
+var punctuators = []string{
+	"===", "!==",
+	"==", "!=", "<=", ">=", "&&", "||", "+=", "-=", "++", "--",
+	"(", ")", "{", "}", "[", "]", ";", ",", ".", "?", ":", "!",
+	"<", ">", "+", "-", "*", "/", "%", "=",
+}
+
+// lex splits src into tokens.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == '\v':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment at offset %d", i)
+			}
+			i += end + 4
+		case c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] == '$' || src[i] >= 'a' && src[i] <= 'z' || src[i] >= 'A' && src[i] <= 'Z' || src[i] >= '0' && src[i] <= '9') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start})
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			start := i
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.' || src[i] == 'x' || src[i] == 'X' ||
+				src[i] >= 'a' && src[i] <= 'f' || src[i] >= 'A' && src[i] <= 'F') {
+				i++
+			}
+			text := src[start:i]
+			var n float64
+			var err error
+			if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+				var u uint64
+				u, err = strconv.ParseUint(text[2:], 16, 64)
+				n = float64(u)
+			} else {
+				n, err = strconv.ParseFloat(text, 64)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at offset %d", text, start)
+			}
+			tokens = append(tokens, token{kind: tokNumber, num: n, text: text, pos: start})
+		case c == '"' || c == '\'':
+			s, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%v at offset %d", err, i)
+			}
+			tokens = append(tokens, token{kind: tokString, text: s, pos: i})
+			i += n
+		default:
+			matched := false
+			for _, p := range punctuators {
+				if strings.HasPrefix(src[i:], p) {
+					tokens = append(tokens, token{kind: tokPunct, text: p, pos: i})
+					i += len(p)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected %q at offset %d", c, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// stringEscapes are the single character escapes of string literals.
+var stringEscapes = map[byte]string{'b': "\b", 'f': "\f", 'n': "\n", 'r': "\r", 't': "\t", 'v': "\v", '0': "\x00"}
+
+// lexString decodes the string literal s starts with and returns it with
+// the number of bytes read.
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\n':
+			return "", 0, fmt.Errorf("unterminated string")
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch e := s[i]; {
+			case stringEscapes[e] != "":
+				b.WriteString(stringEscapes[e])
+			case e == 'x' && i+2 < len(s):
+				code, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid escape \\x%s", s[i+1:i+3])
+				}
+				b.WriteRune(rune(code))
+				i += 2
+			case e == 'u' && i+4 < len(s):
+				code, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid escape \\u%s", s[i+1:i+5])
+				}
+				b.WriteRune(rune(code))
+				i += 4
+			case e == '\n':
+				// Line continuation
+			default:
+				b.WriteByte(e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
@@ -0,0 +1,144 @@
+// Package pac evaluates proxy auto-config (PAC) files to choose the proxy of
+// each request, as browsers do in many corporate networks.
+//
+// PAC files are JavaScript. They are run by a small embedded interpreter
+// supporting the subset they are written in: functions, var, if/else, for
+// and while loops, the usual operators, string and array methods, and the
+// PAC functions (isPlainHostName, dnsDomainIs, localHostOrDomainIs,
+// isResolvable, isInNet, dnsResolve, myIpAddress, dnsDomainLevels,
+// shExpMatch, weekdayRange, timeRange). Regular expressions, objects and
+// dateRange are not supported and fail when the script is parsed or run.
+package pac
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Script is a parsed PAC file. It is safe for concurrent use, evaluations
+// being serialized.
+type Script struct {
+	// LookupHost resolves host names for dnsResolve, isResolvable and
+	// isInNet (default net.LookupHost)
+	LookupHost func(host string) ([]string, error)
+
+	// Now is the time weekdayRange and timeRange compare with (default
+	// time.Now)
+	Now func() time.Time
+
+	mu      sync.Mutex
+	globals *scope
+}
+
+// Parse parses and initializes the PAC file src, which must define
+// FindProxyForURL.
+func Parse(src string) (*Script, error) {
+	stmts, err := parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PAC file: %v", err)
+	}
+	s := &Script{}
+	builtins := newScope(nil)
+	for name, fn := range s.builtins() {
+		builtins.vars[name] = fn
+	}
+	s.globals = newScope(builtins)
+
+	hoist(stmts, s.globals)
+	if _, _, err := (&interp{}).execAll(stmts, s.globals); err != nil {
+		return nil, fmt.Errorf("invalid PAC file: %v", err)
+	}
+	if _, ok := s.globals.vars["FindProxyForURL"].(*closure); !ok {
+		return nil, fmt.Errorf("invalid PAC file: FindProxyForURL is not defined")
+	}
+	return s, nil
+}
+
+// FindProxyForURL calls the FindProxyForURL function of the script and
+// returns its result, such as "PROXY proxy:8080; DIRECT".
+func (s *Script) FindProxyForURL(rawURL, host string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	in := &interp{}
+	result, err := in.call(s.globals.vars["FindProxyForURL"], []value{rawURL, host}, nil)
+	if err != nil {
+		return "", fmt.Errorf("FindProxyForURL failed: %v", err)
+	}
+	if result == nil || result == undefined {
+		return "", nil
+	}
+	return toString(result), nil
+}
+
+// Proxy returns the proxy for req, or nil to connect directly, so it can be
+// used as http.Transport.Proxy. It is the first entry of the script result
+// net/http supports: PROXY, HTTP, HTTPS, SOCKS and SOCKS5 proxies (SOCKS
+// being taken as SOCKS5) or DIRECT. As in browsers, only the scheme and
+// host of https URLs are passed to the script.
+func (s *Script) Proxy(req *http.Request) (*url.URL, error) {
+	u := *req.URL
+	if u.Scheme == "https" {
+		u = url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}
+	}
+	result, err := s.FindProxyForURL(u.String(), req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range ParseResult(result) {
+		if p.Type == "DIRECT" {
+			return nil, nil
+		}
+		if proxyURL := p.URL(); proxyURL != nil {
+			return proxyURL, nil
+		}
+	}
+	return nil, fmt.Errorf("no supported proxy in PAC result %q", result)
+}
+
+// Entry is an entry of a FindProxyForURL result.
+type Entry struct {
+	// Type is DIRECT, PROXY, HTTP, HTTPS, SOCKS, SOCKS4 or SOCKS5
+	Type string
+	// Host is the host:port of the proxy
+	Host string
+}
+
+// URL returns the proxy URL of the entry, or nil for DIRECT and SOCKS4
+// entries, which net/http does not support.
+func (e Entry) URL() *url.URL {
+	switch e.Type {
+	case "PROXY", "HTTP":
+		return &url.URL{Scheme: "http", Host: e.Host}
+	case "HTTPS":
+		return &url.URL{Scheme: "https", Host: e.Host}
+	case "SOCKS", "SOCKS5":
+		return &url.URL{Scheme: "socks5", Host: e.Host}
+	}
+	return nil
+}
+
+// ParseResult splits a FindProxyForURL result into its entries. An empty
+// result means DIRECT.
+func ParseResult(result string) []Entry {
+	var entries []Entry
+	for _, part := range strings.Split(result, ";") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		entry := Entry{Type: strings.ToUpper(fields[0])}
+		if len(fields) > 1 {
+			entry.Host = fields[1]
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		entries = []Entry{{Type: "DIRECT"}}
+	}
+	return entries
+}
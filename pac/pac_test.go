@@ -0,0 +1,190 @@
+package pac_test
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl/pac"
+)
+
+const corporatePAC = `
+// Typical corporate PAC file
+var bypass = ["localhost", ".internal.example.com", ".corp"];
+
+function isBypassed(host) {
+	for (var i = 0; i < bypass.length; i++) {
+		if (host === bypass[i] || dnsDomainIs(host, bypass[i])) {
+			return true;
+		}
+	}
+	return false;
+}
+
+function FindProxyForURL(url, host) {
+	host = host.toLowerCase();
+	if (isPlainHostName(host) && host != "printer" || isBypassed(host)) {
+		return "DIRECT";
+	}
+	if (isInNet(dnsResolve(host), "10.0.0.0", "255.0.0.0")) {
+		return "DIRECT";
+	}
+	if (shExpMatch(url, "http://downloads.*/*.iso")) {
+		return "PROXY bulk.example.com:3128";
+	}
+	if (url.substring(0, 6) == "https:") {
+		return "PROXY secure.example.com:8443; DIRECT";
+	}
+	return "PROXY proxy.example.com:8080; SOCKS socks.example.com:1080";
+}
+`
+
+func TestFindProxyForURL(t *testing.T) {
+	script, err := pac.Parse(corporatePAC)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	script.LookupHost = func(host string) ([]string, error) {
+		if host == "build.example.com" {
+			return []string{"::1", "10.1.2.3"}, nil
+		}
+		return []string{"203.0.113.7"}, nil
+	}
+
+	tests := []struct {
+		url, host, expected string
+	}{
+		{"http://intranet/", "intranet", "DIRECT"},
+		{"http://printer/", "printer", "PROXY proxy.example.com:8080; SOCKS socks.example.com:1080"},
+		{"http://LOCALHOST:8080/", "LOCALHOST", "DIRECT"},
+		{"http://wiki.internal.example.com/", "wiki.internal.example.com", "DIRECT"},
+		{"http://git.corp/", "git.corp", "DIRECT"},
+		{"http://build.example.com/", "build.example.com", "DIRECT"},
+		{"http://downloads.example.com/linux/distro.iso", "downloads.example.com", "PROXY bulk.example.com:3128"},
+		{"https://example.com/", "example.com", "PROXY secure.example.com:8443; DIRECT"},
+		{"http://example.com/", "example.com", "PROXY proxy.example.com:8080; SOCKS socks.example.com:1080"},
+	}
+	for _, tt := range tests {
+		got, err := script.FindProxyForURL(tt.url, tt.host)
+		if err != nil {
+			t.Errorf("FindProxyForURL(%q) error = %v", tt.url, err)
+		} else if got != tt.expected {
+			t.Errorf("FindProxyForURL(%q) = %q, want %q", tt.url, got, tt.expected)
+		}
+	}
+}
+
+func TestProxy(t *testing.T) {
+	script, err := pac.Parse(`
+		function FindProxyForURL(url, host) {
+			if (url.indexOf("/secret") >= 0) return "PROXY leak.example.com:1";
+			if (host == "socks.test") return "SOCKS4 old.example.com:1080; SOCKS5 new.example.com:1080";
+			if (host == "direct.test") return "";
+			return "HTTPS secure.example.com:443";
+		}`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		url, expected string
+	}{
+		{"https://example.com/secret?token=1", "https://secure.example.com:443"},
+		{"http://example.com/secret", "http://leak.example.com:1"},
+		{"http://socks.test/", "socks5://new.example.com:1080"},
+		{"http://direct.test/", ""},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.url, nil)
+		proxyURL, err := script.Proxy(req)
+		if err != nil {
+			t.Errorf("Proxy(%s) error = %v", tt.url, err)
+			continue
+		}
+		got := ""
+		if proxyURL != nil {
+			got = proxyURL.String()
+		}
+		if got != tt.expected {
+			t.Errorf("Proxy(%s) = %q, want %q", tt.url, got, tt.expected)
+		}
+	}
+}
+
+func TestParseResult(t *testing.T) {
+	got := pac.ParseResult("PROXY a:1;  socks5 b:2 ;DIRECT;")
+	expected := []pac.Entry{{"PROXY", "a:1"}, {"SOCKS5", "b:2"}, {"DIRECT", ""}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("ParseResult() = %v, want %v", got, expected)
+	}
+	if got := pac.ParseResult(" "); !reflect.DeepEqual(got, []pac.Entry{{Type: "DIRECT"}}) {
+		t.Errorf("ParseResult of an empty result = %v, want DIRECT", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name, src, expected string
+	}{
+		{"No FindProxyForURL", `function f() { return "DIRECT"; }`, "FindProxyForURL is not defined"},
+		{"Syntax", `function FindProxyForURL(url, host) { return "DIRECT" `, "end of script"},
+		{"Regular expression", `function FindProxyForURL(url, host) { return /x/.test(host); }`, "unsupported syntax"},
+		{"Unterminated string", `var x = "abc`, "unterminated string"},
+		{"Top level failure", `var x = missing(); function FindProxyForURL() {}`, "missing is not defined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pac.Parse(tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		name, body, expected string
+	}{
+		{"Undefined function", `return nope(host);`, "nope is not defined"},
+		{"Infinite loop", `while (true) {}`, "too long"},
+		{"Infinite recursion", `return FindProxyForURL(url, host);`, "maximum call depth"},
+		{"dateRange", `return dateRange("JAN", "MAR");`, "dateRange is not supported"},
+		{"Property of null", `return dnsResolve("").length;`, "cannot read property length of null"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := pac.Parse("function FindProxyForURL(url, host) {" + tt.body + "}")
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			script.LookupHost = func(string) ([]string, error) { return nil, nil }
+			_, err = script.FindProxyForURL("http://example.com/", "example.com")
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("FindProxyForURL() error = %v, want %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDateFunctions(t *testing.T) {
+	script, err := pac.Parse(`
+		function FindProxyForURL(url, host) {
+			return [weekdayRange("MON", "FRI"), weekdayRange("SAT"), weekdayRange("FRI", "MON"),
+				timeRange(9, 17), timeRange(14), timeRange(22, 6), timeRange(14, 29, 14, 31, "GMT")].join(" ");
+		}`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	// A Wednesday
+	script.Now = func() time.Time { return time.Date(2024, 5, 1, 14, 30, 0, 0, time.UTC) }
+	got, err := script.FindProxyForURL("http://example.com/", "example.com")
+	if err != nil {
+		t.Fatalf("FindProxyForURL() error = %v", err)
+	}
+	if expected := "true false false true true false true"; got != expected {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+}
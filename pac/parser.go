@@ -0,0 +1,554 @@
+package pac
+
+import "fmt"
+
+// The AST of the supported JavaScript subset: function declarations and
+// expressions, var/let/const, if/else, for, while, break, continue, return
+// and the usual operators, which covers the PAC files found in practice.
+
+type stmt interface{}
+
+type (
+	varStmt struct {
+		names []string
+		inits []expr
+	}
+	ifStmt struct {
+		cond      expr
+		then, els stmt
+	}
+	forStmt struct {
+		init   stmt
+		cond   expr
+		update expr
+		body   stmt
+	}
+	returnStmt struct{ value expr }
+	exprStmt   struct{ x expr }
+	blockStmt  struct{ stmts []stmt }
+	funcDecl   struct{ fn *funcLit }
+	breakStmt  struct{}
+	contStmt   struct{}
+)
+
+type expr interface{}
+
+type (
+	literal  struct{ value value }
+	ident    struct{ name string }
+	arrayLit struct{ elems []expr }
+	funcLit  struct {
+		name   string
+		params []string
+		body   []stmt
+	}
+	unary struct {
+		op string
+		x  expr
+	}
+	binary struct {
+		op   string
+		l, r expr
+	}
+	condExpr struct{ cond, a, b expr }
+	assign   struct {
+		name  string
+		op    string
+		value expr
+	}
+	update struct {
+		name   string
+		op     string
+		prefix bool
+	}
+	call struct {
+		callee expr
+		args   []expr
+	}
+	member struct {
+		x    expr
+		name string
+	}
+	index struct{ x, index expr }
+)
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+// parse parses a whole script.
+func parse(src string) ([]stmt, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	var stmts []stmt
+	for p.peek().kind != tokEOF {
+		s, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, s)
+	}
+	return stmts, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// is reports whether the next token is the punctuator or keyword text.
+func (p *parser) is(text string) bool {
+	t := p.peek()
+	return (t.kind == tokPunct || t.kind == tokIdent) && t.text == text
+}
+
+// accept consumes the next token if it is text.
+func (p *parser) accept(text string) bool {
+	if p.is(text) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.unexpected(fmt.Sprintf("expected %q", text))
+	}
+	return nil
+}
+
+func (p *parser) unexpected(context string) error {
+	t := p.peek()
+	if t.kind == tokEOF {
+		return fmt.Errorf("%s, got end of script", context)
+	}
+	text := t.text
+	if t.kind == tokString {
+		text = fmt.Sprintf("%q", t.text)
+	}
+	return fmt.Errorf("%s, got %s at offset %d", context, text, t.pos)
+}
+
+func (p *parser) identifier() (string, error) {
+	t := p.peek()
+	if t.kind != tokIdent {
+		return "", p.unexpected("expected an identifier")
+	}
+	p.i++
+	return t.text, nil
+}
+
+// semicolon consumes an optional statement terminator.
+func (p *parser) semicolon() {
+	p.accept(";")
+}
+
+func (p *parser) statement() (stmt, error) {
+	switch {
+	case p.accept(";"):
+		return &blockStmt{}, nil
+	case p.is("{"):
+		return p.block()
+	case p.is("function"):
+		p.next()
+		fn, err := p.function(true)
+		if err != nil {
+			return nil, err
+		}
+		return &funcDecl{fn: fn}, nil
+	case p.is("var") || p.is("let") || p.is("const"):
+		p.next()
+		s, err := p.varDecl()
+		p.semicolon()
+		return s, err
+	case p.accept("if"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		cond, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		s := &ifStmt{cond: cond}
+		if s.then, err = p.statement(); err != nil {
+			return nil, err
+		}
+		if p.accept("else") {
+			if s.els, err = p.statement(); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	case p.accept("for"):
+		return p.forStatement()
+	case p.accept("while"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		cond, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		body, err := p.statement()
+		return &forStmt{cond: cond, body: body}, err
+	case p.accept("return"):
+		s := &returnStmt{}
+		if !p.is(";") && !p.is("}") && p.peek().kind != tokEOF {
+			var err error
+			if s.value, err = p.expression(); err != nil {
+				return nil, err
+			}
+		}
+		p.semicolon()
+		return s, nil
+	case p.accept("break"):
+		p.semicolon()
+		return &breakStmt{}, nil
+	case p.accept("continue"):
+		p.semicolon()
+		return &contStmt{}, nil
+	}
+	x, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	p.semicolon()
+	return &exprStmt{x: x}, nil
+}
+
+func (p *parser) block() (*blockStmt, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	b := &blockStmt{}
+	for !p.accept("}") {
+		if p.peek().kind == tokEOF {
+			return nil, p.unexpected(`expected "}"`)
+		}
+		s, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		b.stmts = append(b.stmts, s)
+	}
+	return b, nil
+}
+
+func (p *parser) varDecl() (*varStmt, error) {
+	s := &varStmt{}
+	for {
+		name, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+		var init expr
+		if p.accept("=") {
+			if init, err = p.assignment(); err != nil {
+				return nil, err
+			}
+		}
+		s.names = append(s.names, name)
+		s.inits = append(s.inits, init)
+		if !p.accept(",") {
+			return s, nil
+		}
+	}
+}
+
+func (p *parser) forStatement() (stmt, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	s := &forStmt{}
+	var err error
+	switch {
+	case p.is(";"):
+	case p.is("var") || p.is("let") || p.is("const"):
+		p.next()
+		s.init, err = p.varDecl()
+	default:
+		var x expr
+		x, err = p.expression()
+		s.init = &exprStmt{x: x}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(";"); err != nil {
+		return nil, p.unexpected("only for (init; condition; update) loops are supported")
+	}
+	if !p.is(";") {
+		if s.cond, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	if !p.is(")") {
+		if s.update, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	s.body, err = p.statement()
+	return s, err
+}
+
+// function parses a function after its keyword.
+func (p *parser) function(named bool) (*funcLit, error) {
+	fn := &funcLit{}
+	if named || p.peek().kind == tokIdent {
+		var err error
+		if fn.name, err = p.identifier(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	for !p.accept(")") {
+		name, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+		fn.params = append(fn.params, name)
+		if !p.is(")") {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	fn.body = body.stmts
+	return fn, nil
+}
+
+func (p *parser) expression() (expr, error) {
+	x, err := p.assignment()
+	for err == nil && p.accept(",") {
+		// The comma operator evaluates to its last operand
+		var r expr
+		r, err = p.assignment()
+		x = &binary{op: ",", l: x, r: r}
+	}
+	return x, err
+}
+
+func (p *parser) assignment() (expr, error) {
+	x, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"=", "+=", "-="} {
+		if p.is(op) {
+			target, ok := x.(*ident)
+			if !ok {
+				return nil, p.unexpected("only variables can be assigned")
+			}
+			p.next()
+			value, err := p.assignment()
+			if err != nil {
+				return nil, err
+			}
+			return &assign{name: target.name, op: op, value: value}, nil
+		}
+	}
+	return x, nil
+}
+
+func (p *parser) conditional() (expr, error) {
+	cond, err := p.binary(0)
+	if err != nil || !p.accept("?") {
+		return cond, err
+	}
+	a, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	b, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	return &condExpr{cond: cond, a: a, b: b}, nil
+}
+
+// precedence lists the binary operators from the loosest to the tightest.
+var precedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "===", "!=="},
+	{"<", ">", "<=", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) binary(level int) (expr, error) {
+	if level == len(precedence) {
+		return p.unary()
+	}
+	x, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, candidate := range precedence[level] {
+			if p.peek().kind == tokPunct && p.peek().text == candidate {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return x, nil
+		}
+		p.next()
+		r, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = &binary{op: op, l: x, r: r}
+	}
+}
+
+func (p *parser) unary() (expr, error) {
+	t := p.peek()
+	if t.kind == tokPunct && (t.text == "!" || t.text == "-" || t.text == "+") || t.kind == tokIdent && t.text == "typeof" {
+		p.next()
+		x, err := p.unary()
+		return &unary{op: t.text, x: x}, err
+	}
+	if t.kind == tokPunct && (t.text == "++" || t.text == "--") {
+		p.next()
+		name, err := p.identifier()
+		return &update{name: name, op: t.text, prefix: true}, err
+	}
+	return p.postfix()
+}
+
+func (p *parser) postfix() (expr, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("("):
+			c := &call{callee: x}
+			for !p.accept(")") {
+				arg, err := p.assignment()
+				if err != nil {
+					return nil, err
+				}
+				c.args = append(c.args, arg)
+				if !p.is(")") {
+					if err := p.expect(","); err != nil {
+						return nil, err
+					}
+				}
+			}
+			x = c
+		case p.accept("."):
+			name, err := p.identifier()
+			if err != nil {
+				return nil, err
+			}
+			x = &member{x: x, name: name}
+		case p.accept("["):
+			i, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = &index{x: x, index: i}
+		case p.is("++") || p.is("--"):
+			target, ok := x.(*ident)
+			if !ok {
+				return nil, p.unexpected("only variables can be incremented")
+			}
+			x = &update{name: target.name, op: p.next().text}
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *parser) primary() (expr, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokNumber:
+		p.next()
+		return &literal{value: t.num}, nil
+	case tokString:
+		p.next()
+		return &literal{value: t.text}, nil
+	case tokIdent:
+		p.next()
+		switch t.text {
+		case "true", "false":
+			return &literal{value: t.text == "true"}, nil
+		case "null":
+			return &literal{value: nil}, nil
+		case "undefined":
+			return &literal{value: undefined}, nil
+		case "function":
+			return p.function(false)
+		}
+		return &ident{name: t.text}, nil
+	case tokPunct:
+		switch t.text {
+		case "(":
+			p.next()
+			x, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		case "[":
+			p.next()
+			a := &arrayLit{}
+			for !p.accept("]") {
+				elem, err := p.assignment()
+				if err != nil {
+					return nil, err
+				}
+				a.elems = append(a.elems, elem)
+				if !p.is("]") {
+					if err := p.expect(","); err != nil {
+						return nil, err
+					}
+				}
+			}
+			return a, nil
+		}
+	}
+	return nil, p.unexpected("unsupported syntax")
+}
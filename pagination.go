@@ -0,0 +1,213 @@
+package gocurl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// Paginator finds the page following a response of a paginated API.
+type Paginator interface {
+	// Next returns the URL of the page after resp, whose body is body,
+	// or "" when resp is the last page. Relative URLs are resolved
+	// against the URL of resp.
+	Next(resp *http.Response, body []byte) (string, error)
+}
+
+// PaginatorFunc adapts a function to a Paginator.
+type PaginatorFunc func(resp *http.Response, body []byte) (string, error)
+
+// Next calls f.
+func (f PaginatorFunc) Next(resp *http.Response, body []byte) (string, error) {
+	return f(resp, body)
+}
+
+// LinkPaginator follows the rel="next" link of the Link header, as the
+// GitHub and GitLab APIs send.
+var LinkPaginator Paginator = PaginatorFunc(func(resp *http.Response, body []byte) (string, error) {
+	return linkNext(resp.Header.Values("Link")), nil
+})
+
+// JSONFieldPaginator follows the URL at the dot separated path of the body,
+// such as "next" or "links.next". A missing or null field ends the pages.
+func JSONFieldPaginator(path string) Paginator {
+	return PaginatorFunc(func(resp *http.Response, body []byte) (string, error) {
+		var doc interface{}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return "", fmt.Errorf("failed to decode JSON response: %v", err)
+		}
+		next, _ := jsonPathValue(doc, path)
+		s, _ := next.(string)
+		return s, nil
+	})
+}
+
+// Pagination configures how CurlJSONAll pages through an API.
+type Pagination struct {
+	// Next finds the following pages, LinkPaginator by default
+	Next Paginator
+	// Items is the dot separated path of the array of items in the body of
+	// a page, such as "data"; empty when the body is the array itself
+	Items string
+	// MaxPages and MaxItems stop fetching once reached; zero means no limit
+	MaxPages int
+	MaxItems int
+}
+
+// WithPagination returns a context making CurlJSONAll page through the API
+// as p configures.
+func WithPagination(ctx context.Context, p *Pagination) context.Context {
+	return context.WithValue(ctx, paginationKey, p)
+}
+
+// CurlJSONAll executes the curl command and the requests for the pages that
+// follow its response, appending the items of each JSON page to the slice
+// dest points to, until the last page or a limit of the pagination set with
+// WithPagination. By default pages are followed through rel="next" Link
+// headers and are arrays of items. The response of the last page fetched is
+// returned. Pages on another origin than the page linking to them are
+// fetched without the credentials of the command: its basic auth, bearer
+// token, signer, cookies and Authorization and Cookie headers.
+func CurlJSONAll(ctx context.Context, dest interface{}, command ...string) (*http.Response, error) {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("CurlJSONAll needs a pointer to a slice, not %T", dest)
+	}
+	slice = slice.Elem()
+
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, err
+	}
+	opts.Silent = true
+
+	p, _ := ctx.Value(paginationKey).(*Pagination)
+	if p == nil {
+		p = &Pagination{}
+	}
+	paginator := p.Next
+	if paginator == nil {
+		paginator = LinkPaginator
+	}
+
+	defaults := withDefaults(ctx, opts)
+	seen := map[string]bool{}
+	items := 0
+	for pages := 1; ; pages++ {
+		resp, body, err := Process(ctx, opts)
+		if err != nil {
+			return resp, err
+		}
+		if body, err = loadSpooled(resp, body); err != nil {
+			return resp, err
+		}
+		if err := preDecode(defaults, resp, func() io.Reader { return strings.NewReader(body) }); err != nil {
+			return resp, err
+		}
+
+		page := reflect.New(slice.Type())
+		if err := decodePage([]byte(body), p.Items, page.Interface()); err != nil {
+			return resp, err
+		}
+		page = page.Elem()
+		if p.MaxItems > 0 && items+page.Len() > p.MaxItems {
+			page = page.Slice(0, p.MaxItems-items)
+		}
+		slice.Set(reflect.AppendSlice(slice, page))
+		items += page.Len()
+
+		if (p.MaxPages > 0 && pages >= p.MaxPages) || (p.MaxItems > 0 && items >= p.MaxItems) {
+			return resp, nil
+		}
+		next, err := paginator.Next(resp, []byte(body))
+		if err != nil || next == "" {
+			return resp, err
+		}
+		nextURL, err := resp.Request.URL.Parse(next)
+		if err != nil {
+			return resp, fmt.Errorf("invalid next page URL %q: %v", next, err)
+		}
+		seen[resp.Request.URL.String()] = true
+		if seen[nextURL.String()] {
+			return resp, fmt.Errorf("pagination loops back to %s", nextURL)
+		}
+
+		// The next page URL carries the query of the command
+		opts = opts.Clone()
+		opts.URL = nextURL.String()
+		opts.QueryParams = url.Values{}
+		if !sameOrigin(resp.Request.URL, nextURL) {
+			dropCredentials(opts)
+		}
+	}
+}
+
+// sameOrigin reports whether a and b have the same scheme, host and port.
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(a.Host, b.Host)
+}
+
+// dropCredentials removes the credentials of opts before its request is sent
+// to another origin, as net/http does when following redirects.
+func dropCredentials(opts *options.RequestOptions) {
+	opts.BasicAuth = nil
+	opts.BearerToken = ""
+	opts.Signer = nil
+	opts.Cookies = nil
+	for key := range opts.Headers {
+		switch strings.ToLower(key) {
+		case "authorization", "www-authenticate", "cookie", "cookie2":
+			delete(opts.Headers, key)
+		}
+	}
+}
+
+// decodePage decodes the items of a page, at path in body, into v.
+func decodePage(body []byte, path string, v interface{}) error {
+	if path != "" {
+		var doc interface{}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return fmt.Errorf("failed to decode JSON response: %v", err)
+		}
+		items, ok := jsonPathValue(doc, path)
+		if !ok {
+			return fmt.Errorf("page has no %s items", path)
+		}
+		body, _ = json.Marshal(items)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to decode JSON response: %v", err)
+	}
+	return nil
+}
+
+// linkNext returns the target of the rel="next" link of Link header values.
+func linkNext(values []string) string {
+	for _, value := range values {
+		for _, link := range strings.Split(value, ",") {
+			params := strings.Split(link, ";")
+			target := strings.TrimSpace(params[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range params[1:] {
+				key, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(key, "rel") {
+					for _, rel := range strings.Fields(strings.Trim(val, `"`)) {
+						if strings.EqualFold(rel, "next") {
+							return target[1 : len(target)-1]
+						}
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,110 @@
+package gocurl_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurlJSONAllLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token", r.Header.Get("X-Token"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		if page < 3 {
+			w.Header().Set("Link", fmt.Sprintf(`</items?page=%d>; rel="next", </items?page=3>; rel="last"`, page+1))
+		}
+		fmt.Fprintf(w, `[{"id": %d}, {"id": %d}]`, page*2-1, page*2)
+	}))
+	defer server.Close()
+
+	type item struct{ ID int }
+	var items []item
+	resp, err := gocurl.CurlJSONAll(context.Background(), &items, "-H", "X-Token: token", server.URL+"/items")
+	require.NoError(t, err)
+	assert.Equal(t, "/items?page=3", resp.Request.URL.RequestURI())
+	assert.Equal(t, []item{{1}, {2}, {3}, {4}, {5}, {6}}, items)
+
+	// Limits stop fetching
+	items = nil
+	ctx := gocurl.WithPagination(context.Background(), &gocurl.Pagination{MaxItems: 3})
+	_, err = gocurl.CurlJSONAll(ctx, &items, "-H", "X-Token: token", server.URL+"/items")
+	require.NoError(t, err)
+	assert.Equal(t, []item{{1}, {2}, {3}}, items)
+
+	items = nil
+	ctx = gocurl.WithPagination(context.Background(), &gocurl.Pagination{MaxPages: 1})
+	_, err = gocurl.CurlJSONAll(ctx, &items, "-H", "X-Token: token", server.URL+"/items")
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	_, err = gocurl.CurlJSONAll(ctx, items, server.URL)
+	assert.Error(t, err)
+}
+
+func TestCurlJSONAllCrossOrigin(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		assert.Empty(t, r.Header.Get("Cookie"))
+		assert.Equal(t, "token", r.Header.Get("X-Token"))
+		fmt.Fprint(w, `[2]`)
+	}))
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "session=1", r.Header.Get("Cookie"))
+		w.Header().Set("Link", `<`+other.URL+`/items?page=2>; rel="next"`)
+		fmt.Fprint(w, `[1]`)
+	}))
+	defer server.Close()
+
+	var items []int
+	_, err := gocurl.CurlJSONAll(context.Background(), &items, "-u", "user:secret", "-H", "Cookie: session=1", "-H", "X-Token: token", server.URL+"/items")
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, items)
+}
+
+func TestCurlJSONAllJSONField(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			fmt.Fprintf(w, `{"data": ["a", "b"], "links": {"next": "%s/?cursor=2"}}`, server.URL)
+		case "2":
+			fmt.Fprint(w, `{"data": ["c"], "links": {"next": null}}`)
+		}
+	}))
+	defer server.Close()
+
+	var items []string
+	ctx := gocurl.WithPagination(context.Background(), &gocurl.Pagination{
+		Next:  gocurl.JSONFieldPaginator("links.next"),
+		Items: "data",
+	})
+	_, err := gocurl.CurlJSONAll(ctx, &items, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, items)
+}
+
+func TestCurlJSONAllLoop(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", `<`+r.URL.Path+`>; rel="next"`)
+		fmt.Fprint(w, `[1]`)
+	}))
+	defer server.Close()
+
+	var items []int
+	_, err := gocurl.CurlJSONAll(context.Background(), &items, server.URL+"/same")
+	assert.ErrorContains(t, err, "loops")
+	assert.Equal(t, []int{1}, items)
+}
@@ -0,0 +1,238 @@
+package gocurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// ErrPolicyViolation is matched with errors.Is by the errors of requests
+// rejected by their options.Policy.
+var ErrPolicyViolation = errors.New("policy violation")
+
+// PolicyError reports the rule of an options.Policy a request broke, such
+// as "host" or "method", and the offending value.
+type PolicyError struct {
+	Rule  string
+	Value string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("policy violation: %s %q is not allowed", e.Rule, e.Value)
+}
+
+// Is makes errors.Is(err, ErrPolicyViolation) match policy errors.
+func (e *PolicyError) Is(target error) bool {
+	return target == ErrPolicyViolation
+}
+
+// checkPolicy returns a *PolicyError when req breaks policy. Bodies of
+// unknown length are limited as they are sent.
+func checkPolicy(req *http.Request, policy *options.Policy) error {
+	if policy == nil {
+		return nil
+	}
+	if len(policy.AllowedSchemes) > 0 && !containsFold(policy.AllowedSchemes, req.URL.Scheme) {
+		return &PolicyError{Rule: "scheme", Value: req.URL.Scheme}
+	}
+	host := req.URL.Hostname()
+	if !allowsHost(policy, host) {
+		return &PolicyError{Rule: "host", Value: host}
+	}
+	if containsFold(policy.DeniedMethods, req.Method) || len(policy.AllowedMethods) > 0 && !containsFold(policy.AllowedMethods, req.Method) {
+		return &PolicyError{Rule: "method", Value: req.Method}
+	}
+
+	if policy.MaxBodySize > 0 && req.Body != nil && req.Body != http.NoBody {
+		if req.ContentLength > policy.MaxBodySize {
+			return &PolicyError{Rule: "body size", Value: fmt.Sprint(req.ContentLength)}
+		}
+		if req.ContentLength < 0 {
+			req.Body = &policyLimitedBody{ReadCloser: req.Body, limit: policy.MaxBodySize}
+		}
+	}
+	return nil
+}
+
+// withPolicy attaches policy to the context of req, where redirects of the
+// request find it. Cached http.Clients are shared by requests with different
+// policies, so their redirect checks cannot capture one.
+func withPolicy(req *http.Request, policy *options.Policy) *http.Request {
+	if policy == nil {
+		return req
+	}
+	return req.WithContext(context.WithValue(req.Context(), policyKey, policy))
+}
+
+// checkRedirectPolicy returns a *PolicyError when following the redirect to
+// req, after the requests of via, breaks the policy of the original request.
+func checkRedirectPolicy(req *http.Request, via []*http.Request) error {
+	policy, _ := req.Context().Value(policyKey).(*options.Policy)
+	if policy == nil {
+		return nil
+	}
+	if policy.MaxRedirects < 0 || policy.MaxRedirects > 0 && len(via) > policy.MaxRedirects {
+		return &PolicyError{Rule: "redirect", Value: req.URL.String()}
+	}
+	return checkPolicy(req, policy)
+}
+
+// allowsHost reports whether the host rules of policy let requests reach
+// host. Host names are only matched against CIDR blocks once resolved, by
+// checkAddress, so an allowed block lets them through here.
+func allowsHost(policy *options.Policy, host string) bool {
+	if matchHosts(policy.DeniedHosts, host) {
+		return false
+	}
+	if len(policy.AllowedHosts) == 0 || matchHosts(policy.AllowedHosts, host) {
+		return true
+	}
+	return net.ParseIP(host) == nil && hasCIDR(policy.AllowedHosts)
+}
+
+// checkAddress returns a *PolicyError when connecting to ip, an address of
+// host, breaks the CIDR rules of policy: ip is in a denied block, or host
+// was only allowed pending its address and ip is in no allowed block.
+func checkAddress(policy *options.Policy, host string, ip net.IP) error {
+	denied := matchCIDRs(policy.DeniedHosts, ip)
+	if !denied && hasCIDR(policy.AllowedHosts) && !matchHosts(policy.AllowedHosts, host) {
+		denied = !matchCIDRs(policy.AllowedHosts, ip)
+	}
+	if denied {
+		return &PolicyError{Rule: "address", Value: ip.String()}
+	}
+	return nil
+}
+
+// policyDialer returns the dialer connecting to addr for the request of ctx,
+// which checks the addresses it connects to, those of the host or of its
+// proxy, against the request's policy.
+func policyDialer(ctx context.Context, addr string) *net.Dialer {
+	policy, _ := ctx.Value(policyKey).(*options.Policy)
+	if policy == nil || !hasCIDR(policy.AllowedHosts) && !hasCIDR(policy.DeniedHosts) {
+		return &dialer
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	d := dialer
+	d.ControlContext = func(ctx context.Context, network, address string, c syscall.RawConn) error {
+		ip, _, err := net.SplitHostPort(address)
+		if err != nil {
+			ip = address
+		}
+		return checkAddress(policy, host, net.ParseIP(ip))
+	}
+	return &d
+}
+
+// policyProxy returns proxy checking the proxies it chooses against the
+// policy of the request, whose host cannot be matched against CIDR blocks
+// then, as the proxy resolves it.
+func policyProxy(proxy func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		proxyURL, err := proxy(req)
+		policy, _ := req.Context().Value(policyKey).(*options.Policy)
+		if err != nil || proxyURL == nil || policy == nil {
+			return proxyURL, err
+		}
+		if !allowsHost(policy, proxyURL.Hostname()) {
+			return nil, &PolicyError{Rule: "proxy", Value: proxyURL.Host}
+		}
+		host := req.URL.Hostname()
+		if net.ParseIP(host) == nil && (hasCIDR(policy.DeniedHosts) || hasCIDR(policy.AllowedHosts) && !matchHosts(policy.AllowedHosts, host)) {
+			return nil, &PolicyError{Rule: "host", Value: host}
+		}
+		return proxyURL, nil
+	}
+}
+
+// addressRules returns the host rules of policy when it has CIDR blocks,
+// which are checked as connections are made: only requests of the same
+// rules may share them.
+func addressRules(policy *options.Policy) string {
+	if policy == nil || !hasCIDR(policy.AllowedHosts) && !hasCIDR(policy.DeniedHosts) {
+		return ""
+	}
+	return strings.Join(policy.AllowedHosts, ",") + ";" + strings.Join(policy.DeniedHosts, ",")
+}
+
+// matchHosts reports whether host matches one of patterns: a host name, a
+// *.domain wildcard matching its subdomains or a CIDR block.
+func matchHosts(patterns []string, host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	ip := net.ParseIP(host)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if _, block, err := net.ParseCIDR(pattern); err == nil {
+			if ip != nil && block.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok && strings.HasPrefix(suffix, ".") {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCIDRs reports whether ip is in one of the CIDR blocks of patterns.
+func matchCIDRs(patterns []string, ip net.IP) bool {
+	for _, pattern := range patterns {
+		if _, block, err := net.ParseCIDR(pattern); err == nil && ip != nil && block.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCIDR reports whether patterns has a CIDR block.
+func hasCIDR(patterns []string) bool {
+	for _, pattern := range patterns {
+		if _, _, err := net.ParseCIDR(pattern); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// policyLimitedBody fails a streamed body once it exceeds the maximum body
+// size of the policy.
+type policyLimitedBody struct {
+	io.ReadCloser
+	limit, read int64
+}
+
+func (b *policyLimitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		return 0, &PolicyError{Rule: "body size", Value: fmt.Sprintf("more than %d", b.limit)}
+	}
+	return n, err
+}
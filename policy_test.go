@@ -0,0 +1,165 @@
+package gocurl_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/away":
+			http.Redirect(w, r, "http://blocked.example.com/", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			io.Copy(io.Discard, r.Body)
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	process := func(policy *options.Policy, configure func(*options.RequestOptions)) error {
+		opts := options.NewRequestOptions(server.URL + "/")
+		opts.Silent = true
+		opts.Policy = policy
+		if configure != nil {
+			configure(opts)
+		}
+		_, _, err := gocurl.Process(context.Background(), opts)
+		return err
+	}
+	violation := func(t *testing.T, err error, rule, value string) {
+		t.Helper()
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, gocurl.ErrPolicyViolation), err.Error())
+		var policyErr *gocurl.PolicyError
+		require.True(t, errors.As(err, &policyErr), err.Error())
+		assert.Equal(t, rule, policyErr.Rule)
+		if value != "" {
+			assert.Equal(t, value, policyErr.Value)
+		}
+	}
+
+	t.Run("Hosts", func(t *testing.T) {
+		assert.NoError(t, process(&options.Policy{AllowedHosts: []string{"127.0.0.0/8"}}, nil))
+		assert.NoError(t, process(&options.Policy{AllowedHosts: []string{"example.com", "127.0.0.1"}}, nil))
+		violation(t, process(&options.Policy{AllowedHosts: []string{"*.example.com"}}, nil), "host", "127.0.0.1")
+		violation(t, process(&options.Policy{DeniedHosts: []string{"10.0.0.0/8", "127.0.0.1"}}, nil), "host", "127.0.0.1")
+		violation(t, process(&options.Policy{AllowedHosts: []string{"127.0.0.1"}, DeniedHosts: []string{"127.0.0.0/8"}}, nil), "host", "127.0.0.1")
+	})
+
+	t.Run("Resolved addresses", func(t *testing.T) {
+		local := func(o *options.RequestOptions) {
+			o.URL = strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/"
+		}
+		before := hits.Load()
+		violation(t, process(&options.Policy{DeniedHosts: []string{"127.0.0.0/8"}}, local), "address", "127.0.0.1")
+		violation(t, process(&options.Policy{AllowedHosts: []string{"10.0.0.0/8"}}, local), "address", "127.0.0.1")
+		assert.Equal(t, before, hits.Load())
+		assert.NoError(t, process(&options.Policy{AllowedHosts: []string{"127.0.0.0/8"}}, local))
+		assert.NoError(t, process(&options.Policy{AllowedHosts: []string{"localhost", "10.0.0.0/8"}}, local))
+
+		// Connections of the client are not shared with requests of other
+		// rules, which did not check their addresses
+		client := gocurl.NewClient()
+		opts := options.NewRequestOptions(strings.Replace(server.URL, "127.0.0.1", "localhost", 1))
+		opts.Silent = true
+		_, _, err := client.Process(context.Background(), opts)
+		require.NoError(t, err)
+		opts.Policy = &options.Policy{DeniedHosts: []string{"127.0.0.0/8"}}
+		_, _, err = client.Process(context.Background(), opts)
+		violation(t, err, "address", "127.0.0.1")
+	})
+
+	t.Run("Proxies", func(t *testing.T) {
+		proxied := func(o *options.RequestOptions) {
+			o.URL = "http://api.example.com/"
+			o.Proxy = server.URL
+		}
+		assert.NoError(t, process(&options.Policy{AllowedHosts: []string{"api.example.com", "127.0.0.1"}}, proxied))
+		violation(t, process(&options.Policy{AllowedHosts: []string{"api.example.com"}}, proxied), "proxy", strings.TrimPrefix(server.URL, "http://"))
+		violation(t, process(&options.Policy{DeniedHosts: []string{"127.0.0.0/8"}}, proxied), "proxy", "")
+		// The proxy resolves the host, which cannot be matched against CIDR
+		// blocks then
+		violation(t, process(&options.Policy{DeniedHosts: []string{"10.0.0.0/8"}}, proxied), "host", "api.example.com")
+	})
+
+	t.Run("Schemes and methods", func(t *testing.T) {
+		violation(t, process(&options.Policy{AllowedSchemes: []string{"https"}}, nil), "scheme", "http")
+		assert.NoError(t, process(&options.Policy{AllowedSchemes: []string{"HTTP"}, AllowedMethods: []string{"get", "head"}}, nil))
+		violation(t, process(&options.Policy{AllowedMethods: []string{"GET"}}, func(o *options.RequestOptions) { o.Method = "DELETE" }), "method", "DELETE")
+		violation(t, process(&options.Policy{DeniedMethods: []string{"delete"}}, func(o *options.RequestOptions) { o.Method = "DELETE" }), "method", "DELETE")
+	})
+
+	t.Run("Body size", func(t *testing.T) {
+		policy := &options.Policy{MaxBodySize: 4}
+		assert.NoError(t, process(policy, func(o *options.RequestOptions) { o.Body = "1234" }))
+		violation(t, process(policy, func(o *options.RequestOptions) { o.Body = "12345" }), "body size", "5")
+
+		before := hits.Load()
+		err := process(policy, func(o *options.RequestOptions) {
+			o.Method = "POST"
+			o.BodyReader = strings.NewReader(strings.Repeat("x", 1<<16))
+		})
+		violation(t, err, "body size", "")
+		assert.LessOrEqual(t, hits.Load()-before, int32(1))
+	})
+
+	t.Run("Redirects", func(t *testing.T) {
+		follow := func(o *options.RequestOptions) {
+			o.URL = server.URL + "/away"
+			o.FollowRedirects = true
+			o.MaxRedirects = 10
+		}
+		violation(t, process(&options.Policy{AllowedHosts: []string{"127.0.0.1"}}, follow), "host", "blocked.example.com")
+		violation(t, process(&options.Policy{MaxRedirects: -1}, follow), "redirect", "")
+
+		loop := func(o *options.RequestOptions) {
+			follow(o)
+			o.URL = server.URL + "/loop"
+		}
+		before := hits.Load()
+		violation(t, process(&options.Policy{MaxRedirects: 2}, loop), "redirect", "")
+		assert.Equal(t, int32(3), hits.Load()-before)
+	})
+
+	t.Run("Sessions and clients", func(t *testing.T) {
+		session, err := gocurl.NewSession(options.NewRequestOptionsBuilder().
+			SetPolicy(&options.Policy{AllowedHosts: []string{"api.example.com"}}).
+			Build())
+		require.NoError(t, err)
+		_, _, err = session.Curl(context.Background(), "curl", server.URL)
+		violation(t, err, "host", "127.0.0.1")
+
+		client := gocurl.NewClient()
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(server.URL + "/away").
+			SetFollowRedirects(true).
+			SetMaxRedirects(5).
+			SetSilent(true).
+			SetPolicy(&options.Policy{DeniedHosts: []string{"*.example.com"}}).
+			Build()
+		_, _, err = client.Process(context.Background(), opts)
+		violation(t, err, "host", "blocked.example.com")
+
+		// The client's cached http.Client checks redirects against the
+		// policy of each request
+		opts.Policy = &options.Policy{MaxRedirects: -1}
+		_, _, err = client.Process(context.Background(), opts)
+		violation(t, err, "redirect", "")
+	})
+}
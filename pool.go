@@ -0,0 +1,368 @@
+package gocurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// ErrNoHealthyTarget is returned by a ClientPool when every target is down.
+var ErrNoHealthyTarget = errors.New("no healthy target available")
+
+// BalanceStrategy selects how a ClientPool spreads requests over its targets.
+type BalanceStrategy int
+
+const (
+	// RoundRobin cycles through the healthy targets in order.
+	RoundRobin BalanceStrategy = iota
+	// Weighted distributes requests proportionally to the target weights.
+	Weighted
+	// LeastLatency sends requests to the target with the lowest observed latency.
+	LeastLatency
+)
+
+// Target is a backend of a ClientPool: a base URL (scheme, host and an
+// optional path prefix) and its weight for the Weighted strategy.
+type Target struct {
+	URL    string
+	Weight int
+}
+
+// TargetStatus reports the health and observed latency of a pool target.
+type TargetStatus struct {
+	URL     string
+	Healthy bool
+	Latency time.Duration
+}
+
+// ClientPool balances requests across a set of equivalent targets. Requests
+// are written against any base URL; the pool swaps the scheme and host for
+// those of the selected target. When a target fails at the transport level it
+// is marked unhealthy and the request fails over to the next target. Other
+// errors, such as HTTP error statuses or policy violations, are returned
+// without failing over.
+//
+// Unhealthy targets are tried again once the cooldown has passed, unless
+// health checks are running, in which case a successful check brings them
+// back.
+type ClientPool struct {
+	strategy BalanceStrategy
+	client   *Client
+
+	mu       sync.Mutex
+	targets  []*poolTarget
+	next     int
+	cooldown time.Duration
+	checking bool
+}
+
+type poolTarget struct {
+	base    *url.URL
+	weight  int
+	current int // Smooth weighted round-robin state
+	healthy bool
+	downAt  time.Time
+	latency time.Duration
+}
+
+// latencyDecay is the weight given to the newest sample of the latency EWMA.
+const latencyDecay = 0.3
+
+// DefaultPoolCooldown is how long a ClientPool leaves a failed target out
+// before trying it again.
+const DefaultPoolCooldown = 30 * time.Second
+
+// NewClientPool creates a pool balancing across targets with strategy.
+func NewClientPool(strategy BalanceStrategy, targets ...Target) (*ClientPool, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("at least one target is required")
+	}
+
+	p := &ClientPool{strategy: strategy, client: NewClient(), cooldown: DefaultPoolCooldown}
+	for _, t := range targets {
+		base, err := url.Parse(t.URL)
+		if err != nil || base.Scheme == "" || base.Host == "" {
+			return nil, fmt.Errorf("invalid target URL: %s", t.URL)
+		}
+		weight := t.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		p.targets = append(p.targets, &poolTarget{base: base, weight: weight, healthy: true})
+	}
+	return p, nil
+}
+
+// SetClient sets the Client used to execute requests, so pooled requests
+// share its circuit breakers and rate limits.
+func (p *ClientPool) SetClient(client *Client) *ClientPool {
+	p.client = client
+	return p
+}
+
+// SetCooldown sets how long failed targets are left out before they are
+// tried again when no health checks are running. Zero leaves them out until
+// a health check succeeds.
+func (p *ClientPool) SetCooldown(cooldown time.Duration) *ClientPool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cooldown = cooldown
+	return p
+}
+
+// Curl parses the curl command and executes it against a pool target.
+func (p *ClientPool) Curl(ctx context.Context, command ...string) (*http.Response, string, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, "", err
+	}
+	return p.Process(ctx, opts)
+}
+
+// CurlString executes the curl command against a pool target and returns
+// the response body without printing it.
+func (p *ClientPool) CurlString(ctx context.Context, command ...string) (*http.Response, string, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, "", err
+	}
+	opts.Silent = true
+	return p.Process(ctx, opts)
+}
+
+// CurlBytes executes the curl command against a pool target and returns the
+// response body without printing it.
+func (p *ClientPool) CurlBytes(ctx context.Context, command ...string) (*http.Response, []byte, error) {
+	resp, body, err := p.CurlString(ctx, command...)
+	return resp, []byte(body), err
+}
+
+// CurlJSON executes the curl command against a pool target and decodes the
+// JSON response body into v as the pool's Client does.
+func (p *ClientPool) CurlJSON(ctx context.Context, v interface{}, command ...string) (*http.Response, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, err
+	}
+	opts.Silent = true
+	resp, body, err := p.Process(ctx, opts)
+	if err != nil {
+		return resp, err
+	}
+	return resp, p.client.decodeJSON(ctx, opts, resp, body, v)
+}
+
+// CurlDecode executes the curl command against a pool target and decodes
+// the response body into v as the pool's Client does.
+func (p *ClientPool) CurlDecode(ctx context.Context, v interface{}, command ...string) (*http.Response, error) {
+	opts, err := p.client.decodeRequest(command)
+	if err != nil {
+		return nil, err
+	}
+	resp, body, err := p.Process(ctx, opts)
+	if err != nil {
+		return resp, err
+	}
+	return resp, p.client.decode(ctx, opts, resp, body, v)
+}
+
+// Process executes the request against a pool target, failing over to the
+// other targets on transport errors.
+func (p *ClientPool) Process(ctx context.Context, opts *options.RequestOptions) (*http.Response, string, error) {
+	reqURL, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid URL: %v", err)
+	}
+
+	tried := make(map[*poolTarget]bool)
+	var lastErr error
+	for len(tried) < len(p.targets) {
+		target := p.pick(tried)
+		if target == nil {
+			break
+		}
+		tried[target] = true
+
+		attempt := opts.Clone()
+		attempt.URL = target.resolve(reqURL)
+
+		start := time.Now()
+		resp, body, err := p.client.Process(ctx, attempt)
+		if err == nil {
+			p.observe(target, time.Since(start), true)
+			return resp, body, nil
+		}
+
+		lastErr = err
+		if ctx.Err() != nil || !failoverError(err) {
+			return resp, body, err
+		}
+		p.observe(target, 0, false)
+	}
+
+	if lastErr == nil {
+		lastErr = ErrNoHealthyTarget
+	}
+	return nil, "", lastErr
+}
+
+// failoverError reports whether err is a transport failure of a target,
+// which the other targets may not share.
+func failoverError(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrOffline) {
+		return false
+	}
+	switch errorKind(err) {
+	case KindResolveHost, KindConnect, KindTimeout, KindTLS, KindEmptyReply, KindReceive:
+		return true
+	}
+	return false
+}
+
+// StartHealthChecks probes path on every target each interval until ctx is
+// done. Targets answering with a status below 400 are healthy.
+func (p *ClientPool) StartHealthChecks(ctx context.Context, path string, interval time.Duration) {
+	p.mu.Lock()
+	p.checking = true
+	p.mu.Unlock()
+
+	p.checkHealth(ctx, path)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				p.mu.Lock()
+				p.checking = false
+				p.mu.Unlock()
+				return
+			case <-ticker.C:
+				p.checkHealth(ctx, path)
+			}
+		}
+	}()
+}
+
+// Targets returns the current status of every target.
+func (p *ClientPool) Targets() []TargetStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	statuses := make([]TargetStatus, len(p.targets))
+	for i, t := range p.targets {
+		statuses[i] = TargetStatus{URL: t.base.String(), Healthy: t.healthy, Latency: t.latency}
+	}
+	return statuses
+}
+
+func (p *ClientPool) checkHealth(ctx context.Context, path string) {
+	p.mu.Lock()
+	targets := append([]*poolTarget(nil), p.targets...)
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t *poolTarget) {
+			defer wg.Done()
+			probe, _ := url.Parse(path)
+			opts := options.NewRequestOptions(t.resolve(probe))
+			opts.Silent = true
+
+			start := time.Now()
+			resp, _, err := Process(ctx, opts)
+			if ctx.Err() != nil {
+				return
+			}
+			p.observe(t, time.Since(start), err == nil && resp.StatusCode < 400)
+		}(t)
+	}
+	wg.Wait()
+}
+
+// pick selects the next target, skipping already tried ones and unhealthy
+// ones still in their cooldown.
+func (p *ClientPool) pick(tried map[*poolTarget]bool) *poolTarget {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	retry := !p.checking && p.cooldown > 0
+
+	var candidates []*poolTarget
+	for _, t := range p.targets {
+		if !tried[t] && (t.healthy || retry && now.Sub(t.downAt) >= p.cooldown) {
+			candidates = append(candidates, t)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	switch p.strategy {
+	case Weighted:
+		total := 0
+		var best *poolTarget
+		for _, t := range candidates {
+			t.current += t.weight
+			total += t.weight
+			if best == nil || t.current > best.current {
+				best = t
+			}
+		}
+		best.current -= total
+		return best
+
+	case LeastLatency:
+		best := candidates[0]
+		for _, t := range candidates[1:] {
+			if t.latency < best.latency {
+				best = t
+			}
+		}
+		return best
+
+	default:
+		t := candidates[p.next%len(candidates)]
+		p.next++
+		return t
+	}
+}
+
+func (p *ClientPool) observe(t *poolTarget, latency time.Duration, healthy bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t.healthy = healthy
+	if !healthy {
+		t.downAt = time.Now()
+		return
+	}
+	if t.latency == 0 {
+		t.latency = latency
+	} else {
+		t.latency = time.Duration(latencyDecay*float64(latency) + (1-latencyDecay)*float64(t.latency))
+	}
+}
+
+// resolve rewrites u to point at the target, prefixing the target's path.
+func (t *poolTarget) resolve(u *url.URL) string {
+	out := *u
+	out.Scheme = t.base.Scheme
+	out.Host = t.base.Host
+	out.User = t.base.User
+	if prefix := strings.TrimSuffix(t.base.Path, "/"); prefix != "" {
+		out.Path = prefix + "/" + strings.TrimPrefix(u.Path, "/")
+		out.RawPath = ""
+	}
+	return out.String()
+}
@@ -0,0 +1,168 @@
+package gocurl_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func namedServer(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			return
+		}
+		fmt.Fprintf(w, "%s %s", name, r.URL.Path)
+	}))
+}
+
+func TestClientPool(t *testing.T) {
+	a, b := namedServer("a"), namedServer("b")
+	defer a.Close()
+	defer b.Close()
+
+	run := func(pool *gocurl.ClientPool, n int) map[string]int {
+		counts := map[string]int{}
+		for i := 0; i < n; i++ {
+			_, body, err := pool.Process(context.Background(), &options.RequestOptions{URL: "http://api/users", Silent: true})
+			require.NoError(t, err)
+			counts[body]++
+		}
+		return counts
+	}
+
+	t.Run("Round robin", func(t *testing.T) {
+		pool, err := gocurl.NewClientPool(gocurl.RoundRobin, gocurl.Target{URL: a.URL}, gocurl.Target{URL: b.URL})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"a /users": 2, "b /users": 2}, run(pool, 4))
+	})
+
+	t.Run("Weighted", func(t *testing.T) {
+		pool, err := gocurl.NewClientPool(gocurl.Weighted, gocurl.Target{URL: a.URL, Weight: 3}, gocurl.Target{URL: b.URL, Weight: 1})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"a /users": 6, "b /users": 2}, run(pool, 8))
+	})
+
+	t.Run("Target path prefix", func(t *testing.T) {
+		pool, err := gocurl.NewClientPool(gocurl.RoundRobin, gocurl.Target{URL: a.URL + "/v2"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"a /v2/users": 1}, run(pool, 1))
+	})
+
+	t.Run("Failover and health checks", func(t *testing.T) {
+		down := namedServer("down")
+		down.Close()
+
+		pool, err := gocurl.NewClientPool(gocurl.RoundRobin, gocurl.Target{URL: down.URL}, gocurl.Target{URL: a.URL})
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]int{"a /users": 3}, run(pool, 3))
+		assert.False(t, pool.Targets()[0].Healthy)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		pool.StartHealthChecks(ctx, "/health", time.Hour)
+		statuses := pool.Targets()
+		assert.False(t, statuses[0].Healthy)
+		assert.True(t, statuses[1].Healthy)
+		assert.NotZero(t, statuses[1].Latency)
+	})
+
+	t.Run("HTTP errors do not fail over", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer failing.Close()
+
+		pool, err := gocurl.NewClientPool(gocurl.RoundRobin, gocurl.Target{URL: failing.URL}, gocurl.Target{URL: a.URL})
+		require.NoError(t, err)
+
+		resp, _, err := pool.Process(context.Background(), &options.RequestOptions{URL: "http://api/users", Silent: true, Fail: true})
+		require.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.True(t, pool.Targets()[0].Healthy)
+	})
+
+	t.Run("Cooldown", func(t *testing.T) {
+		var broken atomic.Bool
+		broken.Store(true)
+		flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if broken.Load() {
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			fmt.Fprintf(w, "flaky %s", r.URL.Path)
+		}))
+		defer flaky.Close()
+
+		pool, err := gocurl.NewClientPool(gocurl.RoundRobin, gocurl.Target{URL: flaky.URL}, gocurl.Target{URL: a.URL})
+		require.NoError(t, err)
+		pool.SetCooldown(50 * time.Millisecond)
+
+		assert.Equal(t, map[string]int{"a /users": 2}, run(pool, 2))
+		assert.False(t, pool.Targets()[0].Healthy)
+
+		broken.Store(false)
+		time.Sleep(60 * time.Millisecond)
+		assert.Equal(t, map[string]int{"a /users": 1, "flaky /users": 1}, run(pool, 2))
+		assert.True(t, pool.Targets()[0].Healthy)
+	})
+
+	t.Run("Body helpers", func(t *testing.T) {
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"path":%q}`, r.URL.Path)
+		}))
+		defer api.Close()
+
+		pool, err := gocurl.NewClientPool(gocurl.RoundRobin, gocurl.Target{URL: api.URL})
+		require.NoError(t, err)
+
+		var result struct {
+			Path string `json:"path"`
+		}
+		_, err = pool.CurlJSON(context.Background(), &result, "curl", "http://api/json")
+		require.NoError(t, err)
+		assert.Equal(t, "/json", result.Path)
+
+		result.Path = ""
+		_, err = pool.CurlDecode(context.Background(), &result, "curl", "http://api/decode")
+		require.NoError(t, err)
+		assert.Equal(t, "/decode", result.Path)
+
+		_, body, err := pool.CurlString(context.Background(), "curl", "http://api/string")
+		require.NoError(t, err)
+		assert.Equal(t, `{"path":"/string"}`, body)
+
+		_, data, err := pool.CurlBytes(context.Background(), "curl", "http://api/bytes")
+		require.NoError(t, err)
+		assert.Equal(t, `{"path":"/bytes"}`, string(data))
+	})
+
+	t.Run("All targets down", func(t *testing.T) {
+		down := namedServer("down")
+		down.Close()
+
+		pool, err := gocurl.NewClientPool(gocurl.LeastLatency, gocurl.Target{URL: down.URL})
+		require.NoError(t, err)
+
+		_, _, err = pool.Process(context.Background(), &options.RequestOptions{URL: "http://api/", Silent: true})
+		assert.Error(t, err)
+		_, _, err = pool.Process(context.Background(), &options.RequestOptions{URL: "http://api/", Silent: true})
+		assert.ErrorIs(t, err, gocurl.ErrNoHealthyTarget)
+	})
+
+	t.Run("Invalid target", func(t *testing.T) {
+		_, err := gocurl.NewClientPool(gocurl.RoundRobin, gocurl.Target{URL: "not a url"})
+		assert.Error(t, err)
+	})
+}
@@ -3,43 +3,114 @@ package gocurl
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
+	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/maniartech/gocurl/middlewares"
 	"github.com/maniartech/gocurl/options"
-	"github.com/maniartech/gocurl/tokenizer"
 	"golang.org/x/net/http2"
 )
 
-func Curl(ctx context.Context, command string) (*http.Response, string, error) {
-	tokenizer := tokenizer.NewTokenizer()
-
-	err := tokenizer.Tokenize(command)
+// Curl executes a curl command. The command is either a single string, which
+// is tokenized like a shell would, or a list of already separated arguments.
+func Curl(ctx context.Context, command ...string) (*http.Response, string, error) {
+	opts, err := parseCommand(command...)
 	if err != nil {
 		return nil, "", err
 	}
 
-	tokens := tokenizer.GetTokens()
+	return Process(ctx, opts)
+}
 
-	opts, err := convertTokensToRequestOptions(tokens)
+// CurlJSON executes the curl command and decodes the JSON response body into v.
+// When the defaults in effect, set with SetDefaults or WithDefaults, have a
+// JSONContentType, the Content-Type of the response is checked first and a
+// *ContentTypeError returned when it fails.
+func CurlJSON(ctx context.Context, v interface{}, command ...string) (*http.Response, error) {
+	opts, err := parseCommand(command...)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
+	opts.Silent = true
 
-	return Process(ctx, opts)
+	buf := bodyBufferPool.Get().(*bytes.Buffer)
+	defer putBodyBuffer(buf)
+	buf.Reset()
+
+	resp, err := processInto(ctx, opts, buf)
+	if err != nil {
+		return resp, err
+	}
+	defaults := withDefaults(ctx, opts)
+	check := defaults.JSONContentType
+
+	// Spooled bodies are decoded straight from disk
+	if spool, ok := resp.Body.(*spooledBody); ok {
+		if err := checkJSONContentType(check, resp, spool.open()); err != nil {
+			return resp, err
+		}
+		if err := preDecode(defaults, resp, spool.open); err != nil {
+			return resp, err
+		}
+		if err := json.NewDecoder(spool.open()).Decode(v); err != nil {
+			return resp, fmt.Errorf("failed to decode JSON response: %v", err)
+		}
+		return resp, nil
+	}
+
+	body := append([]byte(nil), buf.Bytes()...)
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err := checkJSONContentType(check, resp, bytes.NewReader(body)); err != nil {
+		return resp, err
+	}
+	if err := preDecode(defaults, resp, func() io.Reader { return bytes.NewReader(body) }); err != nil {
+		return resp, err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return resp, fmt.Errorf("failed to decode JSON response: %v", err)
+	}
+	return resp, nil
+}
+
+// parseCommand converts a curl command into options. A single argument is
+// tokenized as a command string; several are treated as separate arguments.
+func parseCommand(command ...string) (*options.RequestOptions, error) {
+	return parseCommandWithVars(nil, command...)
+}
+
+// parseCommandWithVars is parseCommand expanding variables from vars first.
+func parseCommandWithVars(vars Variables, command ...string) (*options.RequestOptions, error) {
+	if len(command) != 1 {
+		return argsToOptions(command, vars)
+	}
+
+	compiled, err := compileCached(command[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return compiled.Options(vars)
 }
 
-// Process executes the curl command based on the provided options.RequestOptions
-// Process executes the curl command based on the provided options.RequestOptions
+// Process executes the curl command based on the provided options.RequestOptions.
+// It returns the response together with its body as a string; use Execute
+// for the details of how the request was executed. A body spooled to disk
+// under RequestOptions.SpoolThreshold is not read into memory: the string is
+// empty and resp.Body streams the body from its file.
 func Process(ctx context.Context, opts *options.RequestOptions) (*http.Response, string, error) {
+	opts = withDefaults(ctx, opts)
+
 	// Validate options
 	if err := ValidateOptions(opts); err != nil {
 		return nil, "", err
@@ -51,57 +122,275 @@ func Process(ctx context.Context, opts *options.RequestOptions) (*http.Response,
 		return nil, "", err
 	}
 
-	// Create request
-	req, err := CreateRequest(ctx, opts)
-	if err != nil {
+	return processWithClient(ctx, client, opts)
+}
+
+// bodyBufferPool recycles the buffers response bodies are read into.
+var bodyBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// putBodyBuffer returns buf to the pool unless it grew too large to keep.
+func putBodyBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= 1<<20 {
+		bodyBufferPool.Put(buf)
+	}
+}
+
+// processWithClient executes opts using an already configured HTTP client,
+// which lets callers such as Session share a transport across requests.
+func processWithClient(ctx context.Context, client *http.Client, opts *options.RequestOptions) (*http.Response, string, error) {
+	buf := bodyBufferPool.Get().(*bytes.Buffer)
+	defer putBodyBuffer(buf)
+	buf.Reset()
+
+	resp, err := executeInto(ctx, client, opts, buf)
+	if resp == nil {
 		return nil, "", err
 	}
 
-	// Apply middleware
-	req, err = ApplyMiddleware(req, opts.Middleware)
+	// Recreate the response body for further use. A spooled body is left on
+	// disk: the returned string is empty and resp.Body reads it from the file.
+	var bodyString string
+	spool, spooled := resp.Body.(*spooledBody)
+	if !spooled {
+		bodyString = buf.String()
+		resp.Body = ioutil.NopCloser(strings.NewReader(bodyString))
+	}
+	output := func() error {
+		if spooled {
+			return spool.output(opts)
+		}
+		return HandleOutput(bodyString, opts)
+	}
 	if err != nil {
-		return nil, "", err
+		if opts.FailWithBody && errorKind(err) == KindHTTP {
+			if outErr := output(); outErr != nil {
+				return resp, bodyString, outErr
+			}
+		}
+		return resp, bodyString, err
 	}
 
-	// Execute request with retries
-	resp, err := ExecuteRequestWithRetries(client, req, opts)
+	// Handle output
+	err = output()
 	if err != nil {
+		if spooled {
+			spool.Close()
+		}
 		return nil, "", err
 	}
+	if opts.WriteOut != "" && !opts.Silent {
+		fmt.Fprint(os.Stdout, FormatWriteOut(opts.WriteOut, resp, bodyString))
+	}
+
+	return resp, bodyString, nil
+}
+
+// executeInto builds and sends the request described by opts and appends the
+// response body to buf. The returned response has its body consumed and
+// closed, unless the body exceeded opts.SpoolThreshold: it is then left out of
+// buf and resp.Body is a *spooledBody reading it from disk. When the body
+// fails validation both the response and the error are returned.
+func executeInto(ctx context.Context, client *http.Client, opts *options.RequestOptions, buf *bytes.Buffer) (*http.Response, error) {
+	ctx, cancel := withTimeouts(ctx, opts)
+	defer cancel()
+	ctx, state := withRequestState(ctx, opts)
+
+	req, err := prepareRequest(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Capture the request body before it is consumed by sending it
+	var reqBody []byte
+	if opts.Recorder != nil {
+		if reqBody, err = readRequestBody(req); err != nil {
+			return nil, err
+		}
+	}
 
-	// Read the response body
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	// Execute request with retries
+	start := time.Now()
+	resp, err := ExecuteRequestWithRetryAfter(client, req, opts)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to read response body: %v", err)
+		if opts.Recorder != nil {
+			opts.Recorder.Record(req, reqBody, nil, nil, time.Since(start))
+		}
+		state.finish(nil, req.ContentLength, 0)
+		return nil, wrapError(err, req.URL.String())
 	}
+
+	// Read the response body, copying it to the tee if one is set
+	var bodyReader io.Reader = resp.Body
+	if opts.ResponseTee != nil {
+		bodyReader = io.TeeReader(resp.Body, opts.ResponseTee)
+	}
+	offset := buf.Len()
+	threshold, dir := spoolSettings(opts)
+	spool, err := readBody(bodyReader, buf, threshold, dir)
 	resp.Body.Close()
-	bodyString := string(bodyBytes)
+	state.total = time.Since(state.start)
+	if err != nil {
+		state.finish(nil, req.ContentLength, 0)
+		return nil, readBodyError(err, req.URL.String())
+	}
+	if err := recordAltSvc(req, resp, opts); err != nil {
+		return resp, err
+	}
+	if spool != nil {
+		state.finish(resp, req.ContentLength, spool.size)
+		return resp, checkSpooledBody(req, reqBody, resp, spool, opts, start)
+	}
+	body := buf.Bytes()[offset:]
+	state.finish(resp, req.ContentLength, int64(len(body)))
 
-	// Handle output
-	err = HandleOutput(bodyString, opts)
+	if opts.Recorder != nil {
+		opts.Recorder.Record(req, reqBody, resp, append([]byte(nil), body...), time.Since(start))
+	}
+
+	if err := failOnHTTPError(resp, bytes.NewReader(body), opts); err != nil {
+		return resp, err
+	}
+	if err := saveETag(req, resp, opts); err != nil {
+		return resp, err
+	}
+
+	// Check the body against the response contract
+	if opts.ResponseSchema != "" {
+		if err := validateJSON(body, opts.ResponseSchema); err != nil {
+			return resp, err
+		}
+	}
+
+	return resp, nil
+}
+
+// prepareRequest creates the request described by opts, runs it through the
+// middleware, signs it and checks it against the policy.
+func prepareRequest(ctx context.Context, opts *options.RequestOptions) (*http.Request, error) {
+	// Create request
+	req, err := CreateRequest(ctx, opts)
 	if err != nil {
-		return nil, "", err
+		return nil, wrapError(err, opts.URL)
 	}
 
-	// Recreate the response body for further use
-	resp.Body = ioutil.NopCloser(strings.NewReader(bodyString))
+	// Apply middleware
+	req, err = ApplyMiddleware(req, opts.Middleware)
+	if err != nil {
+		return nil, err
+	}
 
-	return resp, bodyString, nil
+	// Send the digest of the body, covered by the signature
+	if opts.BodyDigest != "" {
+		if err := setBodyDigest(req, opts.BodyDigest); err != nil {
+			return nil, err
+		}
+	}
+
+	// Sign the fully built request
+	if opts.Signer != nil {
+		if err := SignRequest(req, opts.Signer); err != nil {
+			return nil, err
+		}
+	}
+
+	// Enforce the policy on the request as it will be sent
+	if err := checkPolicy(req, opts.Policy); err != nil {
+		return nil, err
+	}
+	return withPolicy(req, opts.Policy), nil
+}
+
+// checkSpooledBody records and validates a body that was spooled to disk and
+// installs it as the body of resp.
+func checkSpooledBody(req *http.Request, reqBody []byte, resp *http.Response, spool *spooledBody, opts *options.RequestOptions, start time.Time) error {
+	resp.Body = spool
+
+	if opts.Recorder != nil {
+		body, err := ioutil.ReadAll(spool.open())
+		if err != nil {
+			return fmt.Errorf("failed to read spooled response body: %v", err)
+		}
+		opts.Recorder.Record(req, reqBody, resp, body, time.Since(start))
+	}
+
+	if err := failOnHTTPError(resp, spool.open(), opts); err != nil {
+		return err
+	}
+	if err := saveETag(req, resp, opts); err != nil {
+		return err
+	}
+	if opts.ResponseSchema != "" {
+		return validateJSONReader(spool.open(), opts.ResponseSchema)
+	}
+	return nil
 }
 
 func ValidateOptions(opts *options.RequestOptions) error {
 	if opts.URL == "" {
 		return fmt.Errorf("URL is required")
 	}
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return &Error{Kind: KindMalformedURL, URL: opts.URL, Err: fmt.Errorf("invalid URL: %v", err)}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return &Error{Kind: KindUnsupportedProtocol, URL: opts.URL, Err: fmt.Errorf("unsupported protocol %q in URL %s", u.Scheme, opts.URL)}
+	}
+	if opts.RawHeaders && (opts.HTTP2Only || opts.Proxy != "") {
+		return fmt.Errorf("raw headers are sent over HTTP/1.1 without a proxy")
+	}
 	// Add more validation as needed
 	return nil
 }
 
+// failOnHTTPError returns an *Error wrapping an *HTTPError when opts asks to
+// fail on HTTP errors and resp has an error status, like curl --fail. With an
+// ErrorModel, body is decoded into the Model of the *HTTPError.
+func failOnHTTPError(resp *http.Response, body io.Reader, opts *options.RequestOptions) error {
+	if (!opts.Fail && !opts.FailWithBody && opts.ErrorModel == nil) || resp.StatusCode < 400 {
+		return nil
+	}
+	httpErr := &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	if opts.ErrorModel != nil {
+		httpErr.Model = decodeErrorModel(opts.ErrorModel, body)
+	}
+	return &Error{Kind: KindHTTP, URL: resp.Request.URL.String(), Err: httpErr}
+}
+
+// decodeErrorModel decodes body into a new value of the type model points
+// to. It returns nil when model is not a pointer or body does not decode.
+func decodeErrorModel(model interface{}, body io.Reader) interface{} {
+	t := reflect.TypeOf(model)
+	if t.Kind() != reflect.Ptr {
+		return nil
+	}
+	v := reflect.New(t.Elem()).Interface()
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		return nil
+	}
+	return v
+}
+
+// readBodyError wraps a failure reading the response body.
+func readBodyError(err error, rawURL string) error {
+	e := &Error{Kind: classifyError(err), URL: rawURL, Err: fmt.Errorf("failed to read response body: %w", err)}
+	if e.Kind == KindUnknown || e.Kind == KindEmptyReply {
+		e.Kind = KindReceive
+	}
+	return e
+}
+
 func CreateHTTPClient(opts *options.RequestOptions) (*http.Client, error) {
+	tlsConfig := sessionTLSConfig(opts)
 	transport := &http.Transport{
-		TLSClientConfig:    opts.TLSConfig,
+		TLSClientConfig:    tlsConfig,
 		DisableCompression: !opts.Compress,
 		Proxy:              http.ProxyFromEnvironment,
+		DialContext:        dialContext,
 	}
 
 	if opts.Proxy != "" {
@@ -110,20 +399,24 @@ func CreateHTTPClient(opts *options.RequestOptions) (*http.Client, error) {
 			return nil, fmt.Errorf("invalid proxy URL: %v", err)
 		}
 		transport.Proxy = http.ProxyURL(proxyURL)
+	} else if opts.ProxyPAC != "" {
+		script, err := loadPAC(opts.ProxyPAC)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = script.Proxy
 	}
+	transport.Proxy = policyProxy(transport.Proxy)
 
-	client := &http.Client{
-		Transport: transport,
-		Timeout:   opts.Timeout,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if !opts.FollowRedirects {
-				return http.ErrUseLastResponse
-			}
-			if len(via) >= opts.MaxRedirects {
-				return fmt.Errorf("stopped after %d redirects", opts.MaxRedirects)
-			}
-			return nil
-		},
+	client := newHTTPClient(transport, opts)
+
+	// Raw headers need their own HTTP/1.1 writer
+	if opts.RawHeaders {
+		client.Transport = &rawTransport{tlsConfig: tlsConfig}
+		if opts.Compress {
+			client.Transport = &decodingTransport{next: client.Transport}
+		}
+		return client, nil
 	}
 
 	// Add HTTP/2 support based on the options
@@ -132,6 +425,7 @@ func CreateHTTPClient(opts *options.RequestOptions) (*http.Client, error) {
 		if opts.HTTP2Only {
 			http2Transport := &http2.Transport{
 				TLSClientConfig: transport.TLSClientConfig,
+				DialTLSContext:  dialTLSContext,
 			}
 			client.Transport = http2Transport
 		} else {
@@ -142,11 +436,39 @@ func CreateHTTPClient(opts *options.RequestOptions) (*http.Client, error) {
 		}
 	}
 
+	// Responses to the encodings offered by --compressed are decoded
+	if opts.Compress {
+		client.Transport = &decodingTransport{next: client.Transport}
+	}
+
+	return client, nil
+}
+
+// newHTTPClient returns an http.Client sending requests through transport
+// with the timeout, redirect policy and cookie jar of opts.
+func newHTTPClient(transport http.RoundTripper, opts *options.RequestOptions) *http.Client {
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !opts.FollowRedirects {
+				return http.ErrUseLastResponse
+			}
+			if err := checkRedirectPolicy(req, via); err != nil {
+				return err
+			}
+			if len(via) >= opts.MaxRedirects {
+				return &Error{Kind: KindTooManyRedirects, URL: req.URL.String(), Err: fmt.Errorf("stopped after %d redirects", opts.MaxRedirects)}
+			}
+			return nil
+		},
+	}
+
 	if opts.CookieJar != nil {
 		client.Jar = opts.CookieJar
 	}
 
-	return client, nil
+	return client
 }
 
 func CreateRequest(ctx context.Context, opts *options.RequestOptions) (*http.Request, error) {
@@ -166,57 +488,54 @@ func CreateRequest(ctx context.Context, opts *options.RequestOptions) (*http.Req
 
 	var body io.Reader
 	var contentType string
+	var upload streamedBody
 
-	if opts.Body != "" {
+	if opts.BodyReader != nil {
+		// Streamed as it is read, with an unknown length. Hiding the
+		// reader's type keeps net/http from buffering or closing it.
+		body = io.NopCloser(opts.BodyReader)
+	} else if opts.Body != "" {
 		body = strings.NewReader(opts.Body)
 	} else if len(opts.Form) > 0 && opts.FileUpload == nil {
 		// URL-encoded form data
 		body = strings.NewReader(opts.Form.Encode())
 		contentType = "application/x-www-form-urlencoded"
 	} else if opts.FileUpload != nil {
-		// Multipart form data
-		var b bytes.Buffer
-		w := multipart.NewWriter(&b)
-
-		// Add file
-		file, err := os.Open(opts.FileUpload.FilePath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open file for upload: %v", err)
-		}
-		defer file.Close()
-
-		part, err := w.CreateFormFile(opts.FileUpload.FieldName, opts.FileUpload.FileName)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create form file: %v", err)
-		}
-		_, err = io.Copy(part, file)
+		// Multipart form data, streamed from the file
+		multipartBody, err := newMultipartBody(opts.FileUpload, opts.Form, opts.MmapUploads)
 		if err != nil {
-			return nil, fmt.Errorf("failed to copy file content: %v", err)
-		}
-
-		// Add other form fields
-		for key, values := range opts.Form {
-			for _, value := range values {
-				err := w.WriteField(key, value)
-				if err != nil {
-					return nil, fmt.Errorf("failed to write form field: %v", err)
-				}
-			}
+			return nil, err
 		}
-
-		err = w.Close()
+		upload = multipartBody
+		contentType = multipartBody.contentType
+	} else if opts.UploadFile != "" {
+		// Raw file body, streamed from disk
+		uploadBody, err := newUploadBody(opts.UploadFile, opts.MmapUploads)
 		if err != nil {
-			return nil, fmt.Errorf("failed to close multipart writer: %v", err)
+			return nil, err
 		}
-
-		body = &b
-		contentType = w.FormDataContentType()
+		upload = uploadBody
 	}
 
+	if opts.RawHeaders {
+		ctx = context.WithValue(ctx, headerOrderKey, opts.HeaderOrder)
+	}
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(withAltSvc(ctx, req.URL, opts))
+
+	if opts.BodyReader != nil {
+		req.ContentLength = -1
+	}
+	if upload != nil {
+		if req.Body, err = upload.open(); err != nil {
+			return nil, err
+		}
+		req.GetBody = upload.open
+		req.ContentLength = upload.contentLength()
+	}
 
 	// Set headers
 	for key, values := range opts.Headers {
@@ -225,11 +544,21 @@ func CreateRequest(ctx context.Context, opts *options.RequestOptions) (*http.Req
 		}
 	}
 
+	// A Host header overrides the host sent, as with curl
+	if host := req.Header.Get("Host"); host != "" {
+		req.Host = host
+	}
+
 	// Set content type if not already set
 	if contentType != "" && req.Header.Get("Content-Type") == "" {
 		req.Header.Set("Content-Type", contentType)
 	}
 
+	// Set cookies
+	for _, cookie := range opts.Cookies {
+		req.AddCookie(cookie)
+	}
+
 	// Set basic auth
 	if opts.BasicAuth != nil {
 		req.SetBasicAuth(opts.BasicAuth.Username, opts.BasicAuth.Password)
@@ -250,6 +579,28 @@ func CreateRequest(ctx context.Context, opts *options.RequestOptions) (*http.Req
 		req.Header.Set("Referer", opts.Referer)
 	}
 
+	// Offer the registered encodings
+	if opts.Compress && req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", AcceptEncoding())
+	}
+
+	// Set idempotency key; it is generated once so retries reuse it
+	if opts.IdempotencyKey != "" && req.Header.Get(IdempotencyKeyHeader) == "" {
+		key := opts.IdempotencyKey
+		if key == options.IdempotencyKeyAuto {
+			key, err = NewIdempotencyKey()
+			if err != nil {
+				return nil, err
+			}
+		}
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+
+	// Make the request conditional on a saved ETag
+	if err := setIfNoneMatch(req, opts); err != nil {
+		return nil, err
+	}
+
 	return req, nil
 }
 
@@ -264,29 +615,130 @@ func ApplyMiddleware(req *http.Request, middleware []middlewares.MiddlewareFunc)
 	return req, nil
 }
 
+// SignRequest hashes the request body and hands the request to signer. The
+// body is read through req.GetBody so the request can still be sent afterwards.
+func SignRequest(req *http.Request, signer options.Signer) error {
+	hash := sha256.New()
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return fmt.Errorf("signer error: request body cannot be re-read for hashing")
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return fmt.Errorf("signer error: %v", err)
+		}
+		_, err = io.Copy(hash, body)
+		body.Close()
+		if err != nil {
+			return fmt.Errorf("signer error: failed to hash body: %v", err)
+		}
+	}
+
+	if err := signer.Sign(req, hex.EncodeToString(hash.Sum(nil))); err != nil {
+		return fmt.Errorf("signer error: %v", err)
+	}
+	return nil
+}
+
 func ExecuteRequestWithRetries(client *http.Client, req *http.Request, opts *options.RequestOptions) (*http.Response, error) {
-	var resp *http.Response
-	var err error
+	return executeWithRetries(client, req, opts, nil)
+}
 
+// executeWithRetries sends req, retrying failures and the statuses of
+// opts.RetryConfig after its RetryDelay and, when policy is set, the rate
+// limited responses it covers after the delay the server asks for. Both are
+// retried by this one loop, so their retries add up rather than multiply.
+func executeWithRetries(client *http.Client, req *http.Request, opts *options.RequestOptions, policy *options.RetryAfterPolicy) (*http.Response, error) {
 	retries := 0
 	if opts.RetryConfig != nil {
 		retries = opts.RetryConfig.MaxRetries
 	}
 
-	for i := 0; i <= retries; i++ {
-		resp, err = client.Do(req)
-		if err == nil {
-			if opts.RetryConfig == nil || !shouldRetry(resp.StatusCode, opts.RetryConfig.RetryOnHTTP) {
-				break
+	retried, waited := 0, 0
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			// Rewind the body so retried requests send the same payload
+			if err := rewindBody(req); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := send(client, req, opts, attempt)
+		if err != nil && req.Context().Err() != nil {
+			// The deadline of the whole operation has passed
+			return resp, err
+		}
+
+		var wait *options.RetryAfterWait
+		if err == nil && policy != nil && waited < policy.MaxRetries && retryAfterStatus(resp.StatusCode, policy.StatusCodes) {
+			info := retryAfterInfo(resp, policy, waited+1)
+			if policy.MaxWait > 0 && info.Wait > policy.MaxWait {
+				return resp, nil
 			}
+			if info.Wait > 0 || info.Source != "" {
+				wait = &info
+			}
+		}
+
+		var delay time.Duration
+		switch {
+		case wait != nil:
+			waited++
+			delay = wait.Wait
+		case retried < retries && (err != nil || shouldRetry(resp.StatusCode, opts.RetryConfig.RetryOnHTTP)):
+			retried++
+			delay = opts.RetryConfig.RetryDelay
+		default:
+			return resp, err
+		}
+		if !allowRetry(req.Context()) {
+			return resp, err
+		}
+
+		if wait != nil && policy.OnWait != nil {
+			policy.OnWait(*wait)
+		}
+		if err == nil {
+			resp.Body.Close()
 		}
 
-		if i < retries {
-			time.Sleep(opts.RetryConfig.RetryDelay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
 		}
 	}
+}
+
+// readRequestBody returns a copy of the request body read through req.GetBody.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody == nil {
+		return nil, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %v", err)
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
 
-	return resp, err
+// rewindBody resets req.Body from req.GetBody before the request is resent.
+func rewindBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	if req.GetBody == nil {
+		return fmt.Errorf("failed to rewind request body: a streamed body cannot be resent")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return fmt.Errorf("failed to rewind request body: %v", err)
+	}
+	req.Body = body
+	return nil
 }
 
 func shouldRetry(statusCode int, retryOnHTTP []int) bool {
@@ -300,14 +752,17 @@ func shouldRetry(statusCode int, retryOnHTTP []int) bool {
 
 func HandleOutput(body string, opts *options.RequestOptions) error {
 	if opts.OutputFile != "" {
-		err := ioutil.WriteFile(opts.OutputFile, []byte(body), 0644)
+		path, err := outputPath(opts)
+		if err == nil {
+			err = ioutil.WriteFile(path, []byte(body), 0644)
+		}
 		if err != nil {
-			return fmt.Errorf("failed to write response to file: %v", err)
+			return &Error{Kind: KindWrite, URL: opts.URL, Err: fmt.Errorf("failed to write response to file: %v", err)}
 		}
 	} else if !opts.Silent {
 		_, err := fmt.Fprint(os.Stdout, body)
 		if err != nil {
-			return fmt.Errorf("failed to write response to stdout: %v", err)
+			return &Error{Kind: KindWrite, URL: opts.URL, Err: fmt.Errorf("failed to write response to stdout: %v", err)}
 		}
 	}
 
@@ -1,9 +1,11 @@
 package gocurl_test
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -285,3 +287,37 @@ func TestEdgeCases(t *testing.T) {
 		assert.Contains(t, err.Error(), "unexpected EOF")
 	})
 }
+
+func TestResponseTee(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name":"gocurl","stars":42}`)
+	}))
+	defer server.Close()
+
+	t.Run("Tee receives the raw body", func(t *testing.T) {
+		var first, second bytes.Buffer
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(server.URL).
+			SetSilent(true).
+			SetResponseTee(io.MultiWriter(&first, &second)).
+			Build()
+
+		_, body, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, body, first.String())
+		assert.Equal(t, body, second.String())
+	})
+
+	t.Run("CurlJSON decodes the body", func(t *testing.T) {
+		var result struct {
+			Name  string `json:"name"`
+			Stars int    `json:"stars"`
+		}
+		resp, err := gocurl.CurlJSON(context.Background(), &result, "curl", server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "gocurl", result.Name)
+		assert.Equal(t, 42, result.Stars)
+	})
+}
@@ -0,0 +1,99 @@
+package gocurl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maniartech/gocurl/tokenizer"
+)
+
+// VariableProvider resolves the argument of ${namespace:argument} variable
+// references, such as the path of ${file:path}.
+type VariableProvider func(arg string) (string, error)
+
+var (
+	providersMu       sync.RWMutex
+	variableProviders = map[string]VariableProvider{
+		"env":  EnvVariableProvider,
+		"file": FileVariableProvider,
+	}
+)
+
+// RegisterVariableProvider makes ${namespace:argument} references resolve
+// through provider, replacing the provider of the namespace if any. A nil
+// provider removes the namespace. The env and file namespaces are
+// registered by default; cmd, which runs programs, must be enabled
+// explicitly:
+//
+//	gocurl.RegisterVariableProvider("cmd", gocurl.CommandVariableProvider)
+func RegisterVariableProvider(namespace string, provider VariableProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if provider == nil {
+		delete(variableProviders, namespace)
+		return
+	}
+	variableProviders[namespace] = provider
+}
+
+func variableProvider(namespace string) VariableProvider {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	return variableProviders[namespace]
+}
+
+// EnvVariableProvider resolves ${env:NAME} to the environment variable
+// NAME, ignoring the Variables of the command.
+func EnvVariableProvider(name string) (string, error) {
+	return os.Getenv(name), nil
+}
+
+// FileVariableProvider resolves ${file:path} to the contents of the file,
+// without trailing newlines, as with tokens mounted from Kubernetes secrets.
+func FileVariableProvider(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// commandTimeout bounds the programs run by CommandVariableProvider.
+const commandTimeout = 10 * time.Second
+
+// CommandVariableProvider resolves ${cmd:program args...} to the output of
+// the program, without trailing newlines. The command line is split like a
+// shell would but run without one, so pipes, redirections and variables are
+// not available. The program is killed after 10 seconds.
+//
+// It runs arbitrary programs named by commands, so it is not registered by
+// default and must only be enabled for trusted commands.
+func CommandVariableProvider(command string) (string, error) {
+	args, err := tokenizer.Split(command)
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "", fmt.Errorf("empty command")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%v: %s", err, message)
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(output), "\r\n"), nil
+}
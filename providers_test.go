@@ -0,0 +1,77 @@
+package gocurl_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariableProviders(t *testing.T) {
+	t.Run("File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "token")
+		require.NoError(t, os.WriteFile(path, []byte("s3cret\n"), 0600))
+
+		opts, err := gocurl.ArgsToOptions([]string{"curl", "-H", "Authorization: Bearer ${file:" + path + "}", "https://example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer s3cret", opts.Headers.Get("Authorization"))
+
+		got, err := gocurl.Variables{}.Resolve("${file:" + path + "|base64}")
+		require.NoError(t, err)
+		assert.Equal(t, "czNjcmV0", got)
+
+		_, err = gocurl.Variables{}.Resolve("${file:" + path + ".missing}")
+		assert.ErrorContains(t, err, "variable ${file:"+path+".missing}: open")
+	})
+
+	t.Run("Environment", func(t *testing.T) {
+		t.Setenv("GOCURL_TEST_TOKEN", "from-env")
+		vars := gocurl.Variables{"GOCURL_TEST_TOKEN": "from-vars"}
+		assert.Equal(t, "from-env from-vars", vars.Expand("${env:GOCURL_TEST_TOKEN} $GOCURL_TEST_TOKEN"))
+		assert.Equal(t, "", vars.Expand("${env:GOCURL_TEST_UNSET}"))
+	})
+
+	t.Run("Namespaces do not shadow defaults", func(t *testing.T) {
+		vars := gocurl.Variables{}
+		assert.Equal(t, "fallback", vars.Expand("${file:-fallback}"))
+	})
+
+	t.Run("Commands are opt-in", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("needs echo")
+		}
+		_, err := gocurl.Variables{}.Resolve("${cmd:echo hi}")
+		assert.EqualError(t, err, "bad substitution ${cmd:echo hi}")
+
+		gocurl.RegisterVariableProvider("cmd", gocurl.CommandVariableProvider)
+		defer gocurl.RegisterVariableProvider("cmd", nil)
+		got, err := gocurl.Variables{}.Resolve("${cmd:echo 'a  b'}")
+		require.NoError(t, err)
+		assert.Equal(t, "a  b", got)
+
+		_, err = gocurl.Variables{}.Resolve("${cmd:sh -c 'echo denied >&2; exit 3'}")
+		assert.EqualError(t, err, "variable ${cmd:sh -c 'echo denied >&2; exit 3'}: exit status 3: denied")
+	})
+
+	t.Run("Custom provider", func(t *testing.T) {
+		gocurl.RegisterVariableProvider("vault", func(path string) (string, error) {
+			if !strings.HasPrefix(path, "secret/") {
+				return "", fmt.Errorf("no secret at %s", path)
+			}
+			return "token-for-" + strings.TrimPrefix(path, "secret/"), nil
+		})
+		defer gocurl.RegisterVariableProvider("vault", nil)
+
+		got, err := gocurl.Variables{}.Resolve("${vault:secret/api|upper}")
+		require.NoError(t, err)
+		assert.Equal(t, "TOKEN-FOR-API", got)
+		_, err = gocurl.Variables{}.Resolve("${vault:other}")
+		assert.EqualError(t, err, "variable ${vault:other}: no secret at other")
+	})
+}
@@ -0,0 +1,163 @@
+package gocurl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/maniartech/gocurl/redact"
+)
+
+// hopHeaders are the hop-by-hop headers a proxy does not forward.
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// Proxy is an http.Handler forwarding the requests it receives to the
+// upstream of a curl command, as a thin API gateway or sidecar. The command
+// supplies the upstream URL and everything added to the forwarded requests,
+// such as authentication and timeouts, while the Client adds its retries
+// and policies:
+//
+//	proxy, err := gocurl.ProxyHandler(`curl -H "Authorization: Bearer ${API_TOKEN}" --max-time 10 https://api.example.com/v1`)
+//	http.Handle("/api/", http.StripPrefix("/api", proxy))
+//
+// The path and query of a received request are appended to those of the
+// command's URL, and its method, headers and body are forwarded, the
+// command's headers taking precedence. Variables of the command are
+// expanded for every request, so rotated secrets are picked up.
+//
+// Responses are buffered before they are written. Upstream failures are
+// answered with 502 Bad Gateway, timeouts with 504 Gateway Timeout and
+// policy violations with 403 Forbidden.
+type Proxy struct {
+	// Client sends the requests, Process when nil
+	Client *Client
+	// Vars are the variables of the command, falling back to the
+	// environment
+	Vars Variables
+
+	command *CompiledCommand
+}
+
+// ProxyHandler returns a Proxy forwarding requests to the upstream of the
+// curl command.
+func ProxyHandler(command string) (*Proxy, error) {
+	compiled, err := Compile(command)
+	if err != nil {
+		return nil, err
+	}
+	return &Proxy{command: compiled}, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	opts, err := p.command.Options(p.Vars)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid proxy command: %v", err), http.StatusInternalServerError)
+		return
+	}
+	incoming, err := FromHTTPRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	target, err := url.Parse(opts.URL)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid upstream URL: %v", err), http.StatusInternalServerError)
+		return
+	}
+	opts.URL = upstreamURL(target, r.URL).String()
+	opts.Method = r.Method
+	opts.Silent = true
+	opts.OutputFile = ""
+
+	header := incoming.Headers
+	header.Del("Host")
+	RemoveHopHeaders(header)
+	if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		if prior := header.Values("X-Forwarded-For"); len(prior) > 0 {
+			ip = strings.Join(prior, ", ") + ", " + ip
+		}
+		header.Set("X-Forwarded-For", ip)
+	}
+	for key, values := range opts.Headers {
+		header[key] = values
+	}
+	opts.Headers = header
+	if incoming.Body != "" || incoming.BodyReader != nil {
+		opts.Body = incoming.Body
+		opts.BodyReader = incoming.BodyReader
+		opts.Form = nil
+		opts.FileUpload = nil
+		opts.UploadFile = ""
+	}
+
+	var resp *http.Response
+	var body string
+	if p.Client != nil {
+		resp, body, err = p.Client.Process(r.Context(), opts)
+	} else {
+		resp, body, err = Process(r.Context(), opts)
+	}
+	if err != nil {
+		http.Error(w, "gocurl proxy: "+redact.Default.String(err.Error()), proxyErrorStatus(err))
+		return
+	}
+
+	RemoveHopHeaders(resp.Header)
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	if r.Method != http.MethodHead {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.WriteString(w, body)
+}
+
+// upstreamURL appends the path and query of the received URL u to target.
+func upstreamURL(target, u *url.URL) *url.URL {
+	joined := *target
+	joined.Path = strings.TrimSuffix(target.Path, "/") + "/" + strings.TrimPrefix(u.Path, "/")
+	joined.RawPath = ""
+	switch {
+	case target.RawQuery == "":
+		joined.RawQuery = u.RawQuery
+	case u.RawQuery != "":
+		joined.RawQuery = target.RawQuery + "&" + u.RawQuery
+	}
+	return &joined
+}
+
+// proxyErrorStatus returns the status a Proxy answers err with.
+func proxyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrPolicyViolation):
+		return http.StatusForbidden
+	case errorKind(err) == KindTimeout:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
+
+// RemoveHopHeaders deletes the hop-by-hop headers of header, including
+// those listed by its Connection header, as proxies do before forwarding
+// requests and responses.
+func RemoveHopHeaders(header http.Header) {
+	for _, name := range header.Values("Connection") {
+		for _, field := range strings.Split(name, ",") {
+			header.Del(strings.TrimSpace(field))
+		}
+	}
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+}
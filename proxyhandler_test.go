@@ -0,0 +1,66 @@
+package gocurl_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyHandler(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Upstream", "yes")
+		w.Header().Set("Connection", "X-Private")
+		w.Header().Set("X-Private", "hop")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, strings.Join([]string{
+			r.Method, r.URL.RequestURI(), r.Header.Get("Authorization"), r.Header.Get("X-Client"),
+			r.Header.Get("X-Forwarded-For"), string(body),
+		}, "|"))
+	}))
+	defer upstream.Close()
+
+	proxy, err := gocurl.ProxyHandler(`curl -H "Authorization: Bearer ${TOKEN}" "` + upstream.URL + `/v1?key=k"`)
+	require.NoError(t, err)
+	proxy.Vars = gocurl.Variables{"TOKEN": "secret"}
+	server := httptest.NewServer(proxy)
+	defer server.Close()
+
+	req, err := http.NewRequest("POST", server.URL+"/items?page=2", strings.NewReader("data"))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer client")
+	req.Header.Set("X-Client", "c")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "POST|/v1/items?page=2&key=k|Bearer secret|c|127.0.0.1|data", string(body))
+	assert.Equal(t, "yes", resp.Header.Get("X-Upstream"))
+	assert.Empty(t, resp.Header.Get("X-Private"))
+
+	t.Run("Errors", func(t *testing.T) {
+		down, err := gocurl.ProxyHandler("curl http://127.0.0.1:1")
+		require.NoError(t, err)
+		rec := httptest.NewRecorder()
+		down.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusBadGateway, rec.Code)
+
+		denied, err := gocurl.ProxyHandler("curl " + upstream.URL)
+		require.NoError(t, err)
+		denied.Client = gocurl.NewClient().AddRoute(gocurl.Route{
+			Options: &options.RequestOptions{Policy: &options.Policy{DeniedMethods: []string{"DELETE"}}},
+		})
+		rec = httptest.NewRecorder()
+		denied.ServeHTTP(rec, httptest.NewRequest("DELETE", "/items/1", nil))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
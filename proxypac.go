@@ -0,0 +1,62 @@
+package gocurl
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maniartech/gocurl/pac"
+)
+
+// pacFetchTimeout bounds the download of remote PAC files.
+const pacFetchTimeout = 30 * time.Second
+
+// pacScripts caches the PAC files by location, as they are loaded once per
+// process.
+var pacScripts sync.Map
+
+// loadPAC returns the proxy auto-config script at location, a URL or a file
+// path, loading it on first use. Failures are not cached.
+func loadPAC(location string) (*pac.Script, error) {
+	if script, ok := pacScripts.Load(location); ok {
+		return script.(*pac.Script), nil
+	}
+
+	var src []byte
+	var err error
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		src, err = fetchPAC(location)
+	} else {
+		src, err = os.ReadFile(strings.TrimPrefix(location, "file://"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load PAC file: %v", err)
+	}
+	script, err := pac.Parse(string(src))
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := pacScripts.LoadOrStore(location, script)
+	return actual.(*pac.Script), nil
+}
+
+// fetchPAC downloads a PAC file, directly as proxies are not known yet.
+func fetchPAC(location string) ([]byte, error) {
+	client := &http.Client{
+		Timeout:   pacFetchTimeout,
+		Transport: &http.Transport{DialContext: dialContext},
+	}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
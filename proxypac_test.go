@@ -0,0 +1,85 @@
+package gocurl_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyPAC(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("direct"))
+	}))
+	defer target.Close()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A proxy receives the absolute URL of the target
+		fmt.Fprintf(w, "proxied %s", r.URL.Path)
+	}))
+	defer proxy.Close()
+
+	proxyHost := proxy.Listener.Addr().String()
+	script := fmt.Sprintf(`function FindProxyForURL(url, host) {
+		if (shExpMatch(url, "*/via-proxy*")) return "PROXY %s";
+		return "DIRECT";
+	}`, proxyHost)
+
+	t.Run("File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "proxy.pac")
+		require.NoError(t, os.WriteFile(path, []byte(script), 0o600))
+
+		_, body, err := gocurl.Curl(context.Background(), "curl", "--proxy-pac", path, target.URL+"/via-proxy")
+		require.NoError(t, err)
+		assert.Equal(t, "proxied /via-proxy", body)
+
+		_, body, err = gocurl.Curl(context.Background(), "curl", "--proxy-pac", path, target.URL+"/other")
+		require.NoError(t, err)
+		assert.Equal(t, "direct", body)
+	})
+
+	t.Run("URL", func(t *testing.T) {
+		pacServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/x-ns-proxy-autoconfig")
+			w.Write([]byte(script))
+		}))
+		defer pacServer.Close()
+
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(target.URL + "/via-proxy").
+			SetProxyPAC(pacServer.URL + "/proxy.pac").
+			Build()
+		_, body, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, "proxied /via-proxy", body)
+	})
+
+	t.Run("Proxy takes precedence", func(t *testing.T) {
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(target.URL + "/other").
+			SetProxy(proxy.URL).
+			SetProxyPAC("/nonexistent.pac").
+			Build()
+		_, body, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, "proxied /other", body)
+	})
+
+	t.Run("Invalid file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "broken.pac")
+		require.NoError(t, os.WriteFile(path, []byte("function FindProxyForURL(url, host) {"), 0o600))
+		_, _, err := gocurl.Curl(context.Background(), "curl", "--proxy-pac", path, target.URL)
+		assert.ErrorContains(t, err, "invalid PAC file")
+
+		_, _, err = gocurl.Curl(context.Background(), "curl", "--proxy-pac", (&url.URL{Scheme: "file", Path: "/nonexistent.pac"}).String(), target.URL)
+		assert.ErrorContains(t, err, "failed to load PAC file")
+	})
+}
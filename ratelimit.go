@@ -0,0 +1,185 @@
+package gocurl
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token-bucket rate limiter with one bucket per key (by
+// default the request host). Requests wait for a token instead of failing.
+type RateLimiter struct {
+	Rate  float64 // Tokens added per second
+	Burst int     // Bucket capacity
+
+	// AdaptToHeaders makes the limiter honour X-RateLimit-Remaining and
+	// X-RateLimit-Reset response headers (as sent by GitHub and many others):
+	// the remaining quota is spread evenly until the reset time, and requests
+	// are held back entirely once it is exhausted.
+	AdaptToHeaders bool
+
+	// KeyFunc maps a request URL to its bucket. Defaults to the URL host; use
+	// it to limit per endpoint instead.
+	KeyFunc func(u *url.URL) string
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens       float64
+	last         time.Time
+	rate         float64 // Adapted rate, 0 uses the limiter rate
+	blockedUntil time.Time
+}
+
+// NewRateLimiter creates a RateLimiter allowing requestsPerSecond with bursts
+// of up to burst requests per key.
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		Rate:    requestsPerSecond,
+		Burst:   burst,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Key returns the bucket key for rawURL.
+func (l *RateLimiter) Key(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	if l.KeyFunc != nil {
+		return l.KeyFunc(u)
+	}
+	return u.Host
+}
+
+// Wait blocks until a request for key may proceed or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context, key string) error {
+	delay := l.reserve(key)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel(key)
+		return ctx.Err()
+	}
+}
+
+// Observe adapts the bucket for key to the rate limit headers of a response.
+// It does nothing unless AdaptToHeaders is set.
+func (l *RateLimiter) Observe(key string, header http.Header) {
+	if !l.AdaptToHeaders || header == nil {
+		return
+	}
+
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(key)
+	now := l.now()
+	resetAt := time.Unix(reset, 0)
+	window := resetAt.Sub(now)
+	if window <= 0 {
+		b.rate = 0
+		b.blockedUntil = time.Time{}
+		return
+	}
+
+	if remaining <= 0 {
+		b.blockedUntil = resetAt
+		b.tokens = 0
+		return
+	}
+
+	b.blockedUntil = time.Time{}
+	adapted := float64(remaining) / window.Seconds()
+	if adapted < l.Rate {
+		b.rate = adapted
+	} else {
+		b.rate = 0
+	}
+}
+
+// reserve takes a token for key and returns how long the caller must wait
+// before using it.
+func (l *RateLimiter) reserve(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(key)
+	now := l.now()
+	rate := l.rateOf(b)
+
+	if rate > 0 {
+		b.tokens += now.Sub(b.last).Seconds() * rate
+		if b.tokens > float64(l.Burst) {
+			b.tokens = float64(l.Burst)
+		}
+	}
+	b.last = now
+	b.tokens--
+
+	var delay time.Duration
+	if b.tokens < 0 && rate > 0 {
+		delay = time.Duration(-b.tokens / rate * float64(time.Second))
+	}
+	if wait := b.blockedUntil.Sub(now); wait > delay {
+		delay = wait
+	}
+	return delay
+}
+
+// cancel returns a token reserved by a Wait that was abandoned.
+func (l *RateLimiter) cancel(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bucket(key).tokens++
+}
+
+func (l *RateLimiter) rateOf(b *bucket) float64 {
+	if b.rate > 0 {
+		return b.rate
+	}
+	return l.Rate
+}
+
+func (l *RateLimiter) bucket(key string) *bucket {
+	if l.buckets == nil {
+		l.buckets = make(map[string]*bucket)
+	}
+	if l.now == nil {
+		l.now = time.Now
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), last: l.now()}
+		l.buckets[key] = b
+	}
+	return b
+}
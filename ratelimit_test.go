@@ -0,0 +1,72 @@
+package gocurl_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("Client waits for tokens", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		client := gocurl.NewClient().SetRateLimit(20, 1)
+		opts := &options.RequestOptions{URL: server.URL, Silent: true}
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			_, _, err := client.Process(context.Background(), opts)
+			require.NoError(t, err)
+		}
+		assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	})
+
+	t.Run("Buckets are per host", func(t *testing.T) {
+		limiter := gocurl.NewRateLimiter(1, 1)
+		require.NoError(t, limiter.Wait(context.Background(), "a.example.com"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		require.NoError(t, limiter.Wait(ctx, "b.example.com"))
+		assert.ErrorIs(t, limiter.Wait(ctx, "a.example.com"), context.DeadlineExceeded)
+	})
+
+	t.Run("Exhausted quota from headers blocks until reset", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(5*time.Second).Unix(), 10))
+		}))
+		defer server.Close()
+
+		client := gocurl.NewClient().SetRateLimitHeaders(true).SetRateLimit(100, 10)
+		opts := &options.RequestOptions{URL: server.URL, Silent: true}
+
+		_, _, err := client.Process(context.Background(), opts)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, _, err = client.Process(ctx, opts)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("Header adaptation applies to later limiters", func(t *testing.T) {
+		limiter := gocurl.NewRateLimiter(100, 10)
+		gocurl.NewClient().SetRateLimitHeaders(true).SetRateLimiter(limiter)
+		assert.True(t, limiter.AdaptToHeaders)
+	})
+
+	t.Run("Custom keys", func(t *testing.T) {
+		limiter := gocurl.NewRateLimiter(1, 1)
+		assert.Equal(t, "api.example.com", limiter.Key("https://api.example.com/users?page=2"))
+	})
+}
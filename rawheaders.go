@@ -0,0 +1,180 @@
+package gocurl
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"sort"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// rawTransport sends requests over HTTP/1.1 with their headers written as
+// given instead of canonicalized and sorted as net/http does, for
+// RequestOptions.RawHeaders. The order is taken from the request context,
+// where CreateRequest puts HeaderOrder. Each request uses a new connection.
+type rawTransport struct {
+	tlsConfig *tls.Config
+}
+
+func (t *rawTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	conn, err := t.dial(req)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	stop := context.AfterFunc(req.Context(), func() { conn.Close() })
+
+	order, _ := req.Context().Value(headerOrderKey).([]string)
+	w := bufio.NewWriter(conn)
+	err = writeRawRequest(w, req, order)
+	if err == nil {
+		err = w.Flush()
+	}
+	var resp *http.Response
+	if err == nil {
+		resp, err = http.ReadResponse(bufio.NewReader(conn), req)
+	}
+	if err != nil {
+		stop()
+		conn.Close()
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+	resp.Body = &rawBody{ReadCloser: resp.Body, conn: conn, stop: stop}
+	return resp, nil
+}
+
+// dial connects to the host of req, negotiating HTTP/1.1 over TLS.
+func (t *rawTransport) dial(req *http.Request) (net.Conn, error) {
+	addr := req.URL.Host
+	if req.URL.Port() == "" {
+		port := "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(req.URL.Hostname(), port)
+	}
+	if req.URL.Scheme != "https" {
+		return dialContext(req.Context(), "tcp", addr)
+	}
+
+	config := &tls.Config{}
+	if t.tlsConfig != nil {
+		config = t.tlsConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = req.URL.Hostname()
+	}
+	config.NextProtos = []string{"http/1.1"}
+	return dialTLSContext(req.Context(), "tcp", addr, config)
+}
+
+// writeRawRequest writes req with the headers named in order first, in that
+// order and casing, then the others sorted. Host comes first unless it is
+// named in order. Framing headers are added unless set explicitly.
+func writeRawRequest(w *bufio.Writer, req *http.Request, order []string) error {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	header := req.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	chunked := hasBody && req.ContentLength < 0
+	if header.Get("Host") == "" {
+		host := req.Host
+		if host == "" {
+			host = req.URL.Host
+		}
+		header.Set("Host", host)
+		order = append([]string{"Host"}, order...)
+	}
+	if hasBody && !chunked && header.Get("Content-Length") == "" {
+		header.Set("Content-Length", fmt.Sprint(req.ContentLength))
+	}
+	if chunked && header.Get("Transfer-Encoding") == "" {
+		header.Set("Transfer-Encoding", "chunked")
+	}
+	if header.Get("Connection") == "" {
+		header.Set("Connection", "close")
+	}
+
+	fmt.Fprintf(w, "%s %s HTTP/1.1\r\n", req.Method, req.URL.RequestURI())
+	writeHeader := func(name, value string) error {
+		if !httpguts.ValidHeaderFieldName(name) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return fmt.Errorf("invalid value for header %s", name)
+		}
+		_, err := fmt.Fprintf(w, "%s: %s\r\n", name, value)
+		return err
+	}
+	for _, name := range order {
+		key := http.CanonicalHeaderKey(name)
+		if values := header[key]; len(values) > 0 {
+			if err := writeHeader(name, values[0]); err != nil {
+				return err
+			}
+			header[key] = values[1:]
+		}
+	}
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			if err := writeHeader(name, value); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := w.WriteString("\r\n"); err != nil {
+		return err
+	}
+
+	if !hasBody {
+		return nil
+	}
+	if !chunked {
+		_, err := io.Copy(w, req.Body)
+		return err
+	}
+	cw := httputil.NewChunkedWriter(w)
+	if _, err := io.Copy(cw, req.Body); err != nil {
+		return err
+	}
+	if err := cw.Close(); err != nil {
+		return err
+	}
+	_, err := w.WriteString("\r\n")
+	return err
+}
+
+// rawBody closes the connection of a raw response with its body.
+type rawBody struct {
+	io.ReadCloser
+	conn net.Conn
+	stop func() bool
+}
+
+func (b *rawBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.stop()
+	b.conn.Close()
+	return err
+}
@@ -0,0 +1,124 @@
+package gocurl_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rawServer answers each connection with "ok" and sends the request it
+// read, head and body, on the returned channel.
+func rawServer(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	requests := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			var b strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				b.WriteString(line)
+				if err != nil || line == "\r\n" {
+					break
+				}
+			}
+			head := b.String()
+			if strings.Contains(head, "Transfer-Encoding: chunked") {
+				for {
+					line, err := r.ReadString('\n')
+					b.WriteString(line)
+					if err != nil || line == "0\r\n" {
+						line, _ = r.ReadString('\n')
+						b.WriteString(line)
+						break
+					}
+				}
+			} else if strings.Contains(head, "Content-Length: ") {
+				body := make([]byte, 4)
+				n, _ := io.ReadFull(r, body)
+				b.Write(body[:n])
+			}
+			conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"))
+			conn.Close()
+			requests <- b.String()
+		}
+	}()
+	return "http://" + listener.Addr().String(), requests
+}
+
+func TestRawHeaders(t *testing.T) {
+	baseURL, requests := rawServer(t)
+
+	t.Run("Command line order", func(t *testing.T) {
+		_, body, err := gocurl.Curl(context.Background(), "curl", "--raw-headers",
+			"-H", "x-zeta: 1", "-H", "ACCEPT: */*", "-H", "X-Alpha: 2", "-H", "x-zeta: 3", baseURL+"/path?q=1")
+		require.NoError(t, err)
+		assert.Equal(t, "ok", body)
+
+		host := strings.TrimPrefix(baseURL, "http://")
+		assert.Equal(t, "GET /path?q=1 HTTP/1.1\r\n"+
+			"Host: "+host+"\r\n"+
+			"x-zeta: 1\r\n"+
+			"ACCEPT: */*\r\n"+
+			"X-Alpha: 2\r\n"+
+			"x-zeta: 3\r\n"+
+			"Connection: close\r\n"+
+			"\r\n", <-requests)
+	})
+
+	t.Run("Body", func(t *testing.T) {
+		opts := options.NewRequestOptionsBuilder().
+			SetMethod("POST").
+			SetURL(baseURL).
+			SetBody("ping").
+			AddHeader("Content-Type", "text/plain").
+			SetRawHeaders("content-type").
+			Build()
+		_, _, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+		request := <-requests
+		assert.Contains(t, request, "\r\ncontent-type: text/plain\r\nConnection: close\r\nContent-Length: 4\r\n\r\nping")
+	})
+
+	t.Run("Streamed body", func(t *testing.T) {
+		opts := options.NewRequestOptionsBuilder().
+			SetMethod("PUT").
+			SetURL(baseURL).
+			SetBodyReader(strings.NewReader("ping")).
+			SetRawHeaders().
+			Build()
+		_, _, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+		assert.True(t, strings.HasSuffix(<-requests, "Transfer-Encoding: chunked\r\n\r\n4\r\nping\r\n0\r\n\r\n"))
+	})
+
+	t.Run("Invalid header", func(t *testing.T) {
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(baseURL).
+			AddHeader("X-Bad", "a\r\nInjected: 1").
+			SetRawHeaders().
+			Build()
+		_, _, err := gocurl.Process(context.Background(), opts)
+		assert.ErrorContains(t, err, "invalid value for header X-Bad")
+	})
+
+	t.Run("HTTP/2 only", func(t *testing.T) {
+		opts := options.NewRequestOptionsBuilder().SetURL(baseURL).SetHTTP2Only(true).SetRawHeaders().Build()
+		assert.Error(t, gocurl.ValidateOptions(opts))
+	})
+}
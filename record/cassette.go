@@ -0,0 +1,404 @@
+package record
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maniartech/gocurl/middlewares"
+	"github.com/maniartech/gocurl/redact"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInteractionNotFound is returned, wrapped, when a cassette has no
+// interaction matching a request it cannot record.
+var ErrInteractionNotFound = errors.New("requested interaction not found")
+
+// Cassette is a set of recorded interactions stored as YAML, in the format
+// of github.com/dnaeon/go-vcr, so existing go-vcr fixtures can be replayed
+// and gocurl recordings used by go-vcr.
+type Cassette struct {
+	// Name is the path of the cassette without its .yaml extension
+	Name         string         `yaml:"-"`
+	Version      int            `yaml:"version"`
+	Interactions []*Interaction `yaml:"interactions"`
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	ID       int              `yaml:"id"`
+	Request  CassetteRequest  `yaml:"request"`
+	Response CassetteResponse `yaml:"response"`
+	replayed bool
+}
+
+// CassetteRequest is the recorded form of a request.
+type CassetteRequest struct {
+	Proto   string      `yaml:"proto,omitempty"`
+	Body    string      `yaml:"body"`
+	Form    url.Values  `yaml:"form,omitempty"`
+	Headers http.Header `yaml:"headers"`
+	URL     string      `yaml:"url"`
+	Method  string      `yaml:"method"`
+}
+
+// CassetteResponse is the recorded form of a response.
+type CassetteResponse struct {
+	Proto    string      `yaml:"proto,omitempty"`
+	Body     string      `yaml:"body"`
+	Headers  http.Header `yaml:"headers"`
+	Status   string      `yaml:"status"`
+	Code     int         `yaml:"code"`
+	Duration string      `yaml:"duration"`
+}
+
+// cassettePath returns the file of the cassette name.
+func cassettePath(name string) string {
+	return name + ".yaml"
+}
+
+// LoadCassette reads the cassette name, stored in name.yaml.
+func LoadCassette(name string) (*Cassette, error) {
+	data, err := os.ReadFile(cassettePath(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cassette: %v", err)
+	}
+	c := &Cassette{Name: name}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %v", cassettePath(name), err)
+	}
+	if c.Version != 1 && c.Version != 2 {
+		return nil, fmt.Errorf("unsupported cassette version %d in %s", c.Version, cassettePath(name))
+	}
+	return c, nil
+}
+
+// Save writes the cassette to its file, creating its directory.
+func (c *Cassette) Save() error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %v", err)
+	}
+	path := cassettePath(c.Name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to save cassette: %v", err)
+	}
+	if err := os.WriteFile(path, append([]byte("---\n"), data...), 0644); err != nil {
+		return fmt.Errorf("failed to save cassette: %v", err)
+	}
+	return nil
+}
+
+// Matcher reports whether the recorded request i answers req, whose body is
+// body.
+type Matcher func(req *http.Request, body []byte, i *CassetteRequest) bool
+
+// DefaultMatcher matches requests by method and URL, like go-vcr.
+func DefaultMatcher(req *http.Request, body []byte, i *CassetteRequest) bool {
+	return req.Method == i.Method && req.URL.String() == i.URL
+}
+
+// BodyMatcher matches requests by method, URL and body.
+func BodyMatcher(req *http.Request, body []byte, i *CassetteRequest) bool {
+	return DefaultMatcher(req, body, i) && string(body) == i.Body
+}
+
+// Mode is how a CassetteRecorder uses its cassette.
+type Mode int
+
+const (
+	// ModeRecordOnce replays the cassette when it exists and records a
+	// new one otherwise
+	ModeRecordOnce Mode = iota
+	// ModeReplayOnly replays the cassette, failing requests it has no
+	// interaction for
+	ModeReplayOnly
+	// ModeRecordOnly records every request, replacing the cassette
+	ModeRecordOnly
+	// ModeReplayWithNewEpisodes replays the interactions of the cassette
+	// and records the requests it has none for
+	ModeReplayWithNewEpisodes
+	// ModePassthrough sends requests without recording nor replaying
+	ModePassthrough
+)
+
+// CassetteRecorder records and replays requests with a cassette. Add its
+// Interceptor to the requests and call Stop once done to save what it
+// recorded. It is safe for concurrent use.
+//
+// Each interaction is replayed once, in order, unless SetReplayable is set;
+// with the default matcher, the same request made twice is thus answered by
+// the two responses recorded for it.
+type CassetteRecorder struct {
+	mu         sync.Mutex
+	cassette   *Cassette
+	mode       Mode
+	matcher    Matcher
+	redactor   *redact.Redactor
+	latency    bool
+	replayable bool
+	recorded   bool
+}
+
+// NewCassetteRecorder returns a recorder of the cassette name, stored in
+// name.yaml, used according to mode.
+func NewCassetteRecorder(name string, mode Mode) (*CassetteRecorder, error) {
+	r := &CassetteRecorder{mode: mode, matcher: DefaultMatcher}
+	_, err := os.Stat(cassettePath(name))
+	exists := err == nil
+
+	switch {
+	case mode == ModeRecordOnce && exists:
+		r.mode = ModeReplayOnly
+	case mode == ModeRecordOnce:
+		r.mode = ModeRecordOnly
+	case mode == ModeReplayOnly && !exists:
+		return nil, fmt.Errorf("failed to open cassette: %s does not exist", cassettePath(name))
+	}
+
+	cassette := &Cassette{Name: name, Version: 2}
+	if exists && r.mode != ModeRecordOnly && r.mode != ModePassthrough {
+		if cassette, err = LoadCassette(name); err != nil {
+			return nil, err
+		}
+	}
+	r.cassette = cassette
+	return r, nil
+}
+
+// SetMatcher sets how requests are matched with interactions. It defaults
+// to DefaultMatcher.
+func (r *CassetteRecorder) SetMatcher(matcher Matcher) *CassetteRecorder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.matcher = matcher
+	return r
+}
+
+// SetRedactor masks the secrets of the interactions it records, so the
+// cassette can be committed. Matchers then see the masked values.
+func (r *CassetteRecorder) SetRedactor(redactor *redact.Redactor) *CassetteRecorder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.redactor = redactor
+	return r
+}
+
+// SetLatency makes replayed responses take as long as the recorded ones.
+func (r *CassetteRecorder) SetLatency(enabled bool) *CassetteRecorder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.latency = enabled
+	return r
+}
+
+// SetReplayable lets interactions be replayed any number of times.
+func (r *CassetteRecorder) SetReplayable(enabled bool) *CassetteRecorder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.replayable = enabled
+	return r
+}
+
+// Cassette returns the cassette of the recorder.
+func (r *CassetteRecorder) Cassette() *Cassette {
+	return r.cassette
+}
+
+// Stop saves the cassette when new interactions were recorded.
+func (r *CassetteRecorder) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.recorded {
+		return nil
+	}
+	return r.cassette.Save()
+}
+
+// Interceptor returns the interceptor recording and replaying requests.
+func (r *CassetteRecorder) Interceptor() middlewares.Interceptor {
+	return r.roundTrip
+}
+
+func (r *CassetteRecorder) roundTrip(req *http.Request, next middlewares.RoundTripFunc) (*http.Response, error) {
+	if r.mode == ModePassthrough {
+		return next(req)
+	}
+	body, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode != ModeRecordOnly {
+		if i := r.match(req, body); i != nil {
+			return r.replay(req, i)
+		}
+		if r.mode == ModeReplayOnly {
+			return nil, fmt.Errorf("cassette %s: %s %s: %w", r.cassette.Name, req.Method, req.URL, ErrInteractionNotFound)
+		}
+	}
+
+	start := time.Now()
+	resp, err := next(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	r.record(req, body, resp, respBody, time.Since(start))
+	return resp, nil
+}
+
+// match returns the first interaction left to replay matching req.
+func (r *CassetteRecorder) match(req *http.Request, body []byte) *Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, i := range r.cassette.Interactions {
+		if (r.replayable || !i.replayed) && r.matcher(req, body, &i.Request) {
+			i.replayed = true
+			return i
+		}
+	}
+	return nil
+}
+
+// replay answers req with the response of i.
+func (r *CassetteRecorder) replay(req *http.Request, i *Interaction) (*http.Response, error) {
+	r.mu.Lock()
+	latency := r.latency
+	r.mu.Unlock()
+	if latency {
+		if d, err := time.ParseDuration(i.Response.Duration); err == nil && d > 0 {
+			if err := sleep(req.Context(), d); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	proto := i.Response.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	major, minor, _ := http.ParseHTTPVersion(proto)
+	status := i.Response.Status
+	if status == "" {
+		status = strconv.Itoa(i.Response.Code) + " " + http.StatusText(i.Response.Code)
+	}
+	return &http.Response{
+		Status:        status,
+		StatusCode:    i.Response.Code,
+		Proto:         proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        i.Response.Headers.Clone(),
+		Body:          io.NopCloser(strings.NewReader(i.Response.Body)),
+		ContentLength: int64(len(i.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// record adds the exchange to the cassette.
+func (r *CassetteRecorder) record(req *http.Request, body []byte, resp *http.Response, respBody []byte, duration time.Duration) {
+	i := &Interaction{
+		Request: CassetteRequest{
+			Proto:   req.Proto,
+			Body:    string(body),
+			Form:    requestForm(req, body),
+			Headers: req.Header.Clone(),
+			URL:     req.URL.String(),
+			Method:  req.Method,
+		},
+		Response: CassetteResponse{
+			Proto:    resp.Proto,
+			Body:     string(respBody),
+			Headers:  resp.Header.Clone(),
+			Status:   resp.Status,
+			Code:     resp.StatusCode,
+			Duration: duration.String(),
+		},
+		replayed: true,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.redactor != nil {
+		i.Request.URL = r.redactor.URL(i.Request.URL)
+		i.Request.Headers = r.redactor.Header(i.Request.Headers)
+		i.Request.Body = r.redactor.String(i.Request.Body)
+		i.Response.Headers = r.redactor.Header(i.Response.Headers)
+		i.Response.Body = r.redactor.String(i.Response.Body)
+		for key, values := range i.Request.Form {
+			for j := range values {
+				values[j] = r.redactor.String(values[j])
+			}
+			if r.redactor.IsSecretName(key) {
+				i.Request.Form[key] = []string{redact.Mask}
+			}
+		}
+	}
+	i.ID = len(r.cassette.Interactions)
+	r.cassette.Interactions = append(r.cassette.Interactions, i)
+	r.recorded = true
+}
+
+// requestBody reads the body of req, leaving it readable again.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+// requestForm returns the form values of a urlencoded request body.
+func requestForm(req *http.Request, body []byte) url.Values {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType != "application/x-www-form-urlencoded" {
+		return nil
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil
+	}
+	return form
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
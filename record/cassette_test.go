@@ -0,0 +1,169 @@
+package record_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl/middlewares"
+	"github.com/maniartech/gocurl/record"
+	"github.com/maniartech/gocurl/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// through sends req through the interceptor of r to the real client.
+func through(t *testing.T, r *record.CassetteRecorder, method, url, body string) (*http.Response, string, error) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	send := middlewares.Chain(http.DefaultClient.Do, r.Interceptor())
+	resp, err := send(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(data), nil
+}
+
+func TestCassetteRecorder(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Hit", fmt.Sprint(n))
+		fmt.Fprintf(w, "hit %d %s", n, body)
+	}))
+	defer server.Close()
+	name := filepath.Join(t.TempDir(), "fixtures", "api")
+
+	recorder, err := record.NewCassetteRecorder(name, record.ModeRecordOnce)
+	require.NoError(t, err)
+	redactor := redact.New()
+	redactor.AddSecret("s3cr3t")
+	recorder.SetRedactor(redactor)
+	_, body, err := through(t, recorder, "GET", server.URL+"/a", "")
+	require.NoError(t, err)
+	assert.Equal(t, "hit 1 ", body)
+	_, body, err = through(t, recorder, "POST", server.URL+"/b", "token=s3cr3t&name=ada")
+	require.NoError(t, err)
+	assert.Equal(t, "hit 2 token=s3cr3t&name=ada", body)
+	require.NoError(t, recorder.Stop())
+
+	data, err := os.ReadFile(name + ".yaml")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "---\nversion: 2\ninteractions:\n"))
+	assert.NotContains(t, string(data), "s3cr3t")
+
+	t.Run("Replay", func(t *testing.T) {
+		replayer, err := record.NewCassetteRecorder(name, record.ModeRecordOnce)
+		require.NoError(t, err)
+		resp, body, err := through(t, replayer, "GET", server.URL+"/a", "")
+		require.NoError(t, err)
+		assert.Equal(t, "hit 1 ", body)
+		assert.Equal(t, "1", resp.Header.Get("X-Hit"))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// Interactions are replayed once
+		_, _, err = through(t, replayer, "GET", server.URL+"/a", "")
+		assert.True(t, errors.Is(err, record.ErrInteractionNotFound))
+		assert.EqualValues(t, 2, atomic.LoadInt32(&hits))
+
+		replayer, err = record.NewCassetteRecorder(name, record.ModeReplayOnly)
+		require.NoError(t, err)
+		replayer.SetReplayable(true)
+		for i := 0; i < 2; i++ {
+			_, body, err = through(t, replayer, "GET", server.URL+"/a", "")
+			require.NoError(t, err)
+			assert.Equal(t, "hit 1 ", body)
+		}
+		assert.Equal(t, "****", replayer.Cassette().Interactions[1].Request.Form.Get("token"))
+	})
+
+	t.Run("New episodes", func(t *testing.T) {
+		recorder, err := record.NewCassetteRecorder(name, record.ModeReplayWithNewEpisodes)
+		require.NoError(t, err)
+		recorder.SetMatcher(record.BodyMatcher)
+		_, body, err := through(t, recorder, "POST", server.URL+"/b", "name=grace")
+		require.NoError(t, err)
+		assert.Equal(t, "hit 3 name=grace", body)
+		require.NoError(t, recorder.Stop())
+
+		cassette, err := record.LoadCassette(name)
+		require.NoError(t, err)
+		require.Len(t, cassette.Interactions, 3)
+		assert.Equal(t, 2, cassette.Interactions[2].ID)
+	})
+
+	t.Run("Latency", func(t *testing.T) {
+		replayer, err := record.NewCassetteRecorder(name, record.ModeReplayOnly)
+		require.NoError(t, err)
+		replayer.SetLatency(true)
+		replayer.Cassette().Interactions[0].Response.Duration = "50ms"
+		start := time.Now()
+		_, _, err = through(t, replayer, "GET", server.URL+"/a", "")
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+		replayer.Cassette().Interactions[0].Response.Duration = "1h"
+		replayer.SetReplayable(true)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/a", nil)
+		_, err = replayer.Interceptor()(req, http.DefaultClient.Do)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("Missing cassette", func(t *testing.T) {
+		_, err := record.NewCassetteRecorder(filepath.Join(t.TempDir(), "none"), record.ModeReplayOnly)
+		assert.Error(t, err)
+	})
+}
+
+func TestLoadCassetteGoVCR(t *testing.T) {
+	// A cassette written by go-vcr v1
+	name := filepath.Join(t.TempDir(), "govcr")
+	fixture := `---
+version: 1
+interactions:
+- request:
+    body: ""
+    form: {}
+    headers:
+      Accept:
+      - application/json
+    url: https://api.example.com/users/1
+    method: GET
+  response:
+    body: '{"id":1}'
+    headers:
+      Content-Type:
+      - application/json
+    status: 200 OK
+    code: 200
+    duration: 120.5ms
+`
+	require.NoError(t, os.WriteFile(name+".yaml", []byte(fixture), 0644))
+
+	recorder, err := record.NewCassetteRecorder(name, record.ModeReplayOnly)
+	require.NoError(t, err)
+	resp, body, err := through(t, recorder, "GET", "https://api.example.com/users/1", "")
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":1}`, body)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "200 OK", resp.Status)
+}
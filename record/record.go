@@ -0,0 +1,178 @@
+// Package record captures executed requests and their responses so they can
+// be inspected, saved and replayed later.
+package record
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/maniartech/gocurl/redact"
+)
+
+// Exchange is a captured request together with the response it received.
+type Exchange struct {
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Request   Request       `json:"request"`
+	Response  *Response     `json:"response,omitempty"`
+}
+
+// Request is the captured form of an outgoing request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is the captured form of a received response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Proto      string      `json:"proto,omitempty"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// NewExchange captures req and resp. resp may be nil.
+func NewExchange(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte, duration time.Duration) Exchange {
+	ex := Exchange{
+		StartedAt: time.Now().Add(-duration),
+		Duration:  duration,
+		Request: Request{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: req.Header.Clone(),
+			Body:   string(reqBody),
+		},
+	}
+	if resp != nil {
+		ex.Response = &Response{
+			StatusCode: resp.StatusCode,
+			Proto:      resp.Proto,
+			Header:     resp.Header.Clone(),
+			Body:       string(respBody),
+		}
+	}
+	return ex
+}
+
+// Redact returns a copy of the exchange with the secrets of its URL,
+// headers and bodies masked by redactor.
+func (ex Exchange) Redact(redactor *redact.Redactor) Exchange {
+	ex.Request.URL = redactor.URL(ex.Request.URL)
+	ex.Request.Header = redactor.Header(ex.Request.Header)
+	ex.Request.Body = redactor.String(ex.Request.Body)
+	if ex.Response != nil {
+		resp := *ex.Response
+		resp.Header = redactor.Header(resp.Header)
+		resp.Body = redactor.String(resp.Body)
+		ex.Response = &resp
+	}
+	return ex
+}
+
+// Recorder collects exchanges in memory. It implements options.Recorder and
+// is safe for concurrent use.
+type Recorder struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+	redactor  *redact.Redactor
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// SetRedactor masks the secrets of exchanges as they are captured, so they
+// can be saved and shared safely. Replaying them then needs credentials
+// to be set again, e.g. with gocurl.ReplayHeader.
+func (r *Recorder) SetRedactor(redactor *redact.Redactor) *Recorder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.redactor = redactor
+	return r
+}
+
+// Record captures a single exchange.
+func (r *Recorder) Record(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte, duration time.Duration) {
+	ex := NewExchange(req, reqBody, resp, respBody, duration)
+
+	r.mu.Lock()
+	redactor := r.redactor
+	r.mu.Unlock()
+	if redactor != nil {
+		ex = ex.Redact(redactor)
+	}
+
+	r.mu.Lock()
+	r.exchanges = append(r.exchanges, ex)
+	r.mu.Unlock()
+}
+
+// Exchanges returns the captured exchanges in the order they completed.
+func (r *Recorder) Exchanges() []Exchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Exchange(nil), r.exchanges...)
+}
+
+// Last returns the most recent exchange, or nil if none was captured.
+func (r *Recorder) Last() *Exchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.exchanges) == 0 {
+		return nil
+	}
+	ex := r.exchanges[len(r.exchanges)-1]
+	return &ex
+}
+
+// Save writes the captured exchanges to path as JSON lines.
+func (r *Recorder) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create recording file: %v", err)
+	}
+	defer file.Close()
+
+	enc := json.NewEncoder(file)
+	for _, ex := range r.Exchanges() {
+		if err := enc.Encode(ex); err != nil {
+			return fmt.Errorf("failed to write exchange: %v", err)
+		}
+	}
+	return nil
+}
+
+// Load reads exchanges previously written by Recorder.Save.
+func Load(path string) ([]Exchange, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording file: %v", err)
+	}
+	defer file.Close()
+
+	var exchanges []Exchange
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var ex Exchange
+		if err := json.Unmarshal(scanner.Bytes(), &ex); err != nil {
+			return nil, fmt.Errorf("failed to parse exchange: %v", err)
+		}
+		exchanges = append(exchanges, ex)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording file: %v", err)
+	}
+	return exchanges, nil
+}
@@ -0,0 +1,72 @@
+package record_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl/record"
+	"github.com/maniartech/gocurl/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	recorder := record.NewRecorder()
+	assert.Nil(t, recorder.Last())
+
+	req, _ := http.NewRequest("POST", "https://api.example.com/items?x=1", strings.NewReader(`{"a":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp := &http.Response{
+		StatusCode: http.StatusCreated,
+		Proto:      "HTTP/1.1",
+		Header:     http.Header{"X-Id": {"42"}},
+		Body:       io.NopCloser(&bytes.Buffer{}),
+	}
+	recorder.Record(req, []byte(`{"a":1}`), resp, []byte("created"), 15*time.Millisecond)
+	recorder.Record(req, nil, nil, nil, time.Millisecond)
+
+	exchanges := recorder.Exchanges()
+	require.Len(t, exchanges, 2)
+	assert.Equal(t, "POST", exchanges[0].Request.Method)
+	assert.Equal(t, "https://api.example.com/items?x=1", exchanges[0].Request.URL)
+	assert.Equal(t, `{"a":1}`, exchanges[0].Request.Body)
+	assert.Equal(t, http.StatusCreated, exchanges[0].Response.StatusCode)
+	assert.Equal(t, "created", exchanges[0].Response.Body)
+	assert.Nil(t, recorder.Last().Response)
+
+	t.Run("Save and load", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "recording.jsonl")
+		require.NoError(t, recorder.Save(path))
+
+		loaded, err := record.Load(path)
+		require.NoError(t, err)
+		require.Len(t, loaded, 2)
+		assert.Equal(t, exchanges[0].Request, loaded[0].Request)
+		assert.Equal(t, exchanges[0].Response, loaded[0].Response)
+		assert.Equal(t, 15*time.Millisecond, loaded[0].Duration)
+	})
+
+	t.Run("Redacted", func(t *testing.T) {
+		redactor := redact.New()
+		redactor.AddSecret("hunter2")
+		recorder := record.NewRecorder().SetRedactor(redactor)
+
+		req, _ := http.NewRequest("POST", "https://api.example.com/login?api_key=k1", nil)
+		req.Header.Set("Authorization", "Bearer abc")
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Set-Cookie": {"sid=xyz"}}}
+		recorder.Record(req, []byte(`{"password":"hunter2"}`), resp, []byte("welcome"), time.Millisecond)
+
+		ex := recorder.Last()
+		assert.Equal(t, "https://api.example.com/login?api_key=****", ex.Request.URL)
+		assert.Equal(t, http.Header{"Authorization": {"****"}}, ex.Request.Header)
+		assert.Equal(t, `{"password":"****"}`, ex.Request.Body)
+		assert.Equal(t, http.Header{"Set-Cookie": {"****"}}, ex.Response.Header)
+		assert.Equal(t, "welcome", ex.Response.Body)
+		assert.Equal(t, "Bearer abc", req.Header.Get("Authorization"), "the request is not modified")
+	})
+}
@@ -0,0 +1,239 @@
+package record
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LoadDir reads the exchanges of the recording files (*.jsonl and *.json)
+// of dir, in file name order. dir may also be a single recording file.
+func LoadDir(dir string) ([]Exchange, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recordings: %v", err)
+	}
+	if !info.IsDir() {
+		return Load(dir)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recordings: %v", err)
+	}
+	var exchanges []Exchange
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || ext != ".jsonl" && ext != ".json" {
+			continue
+		}
+		loaded, err := Load(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", entry.Name(), err)
+		}
+		exchanges = append(exchanges, loaded...)
+	}
+	return exchanges, nil
+}
+
+// Stub is an http.Handler answering requests with recorded responses, so
+// code can be developed against an API offline. It is safe for concurrent
+// use.
+//
+// A request is answered by the exchanges with the same method and path,
+// whatever their host, preferring those with the same query and then the
+// same body. When several exchanges match equally, they are served in order
+// and the last one is repeated. Requests without a match are answered with
+// 404 Not Found.
+type Stub struct {
+	// OnRequest, when set, is called for every request with the exchange
+	// answering it, nil when there is none.
+	OnRequest func(r *http.Request, ex *Exchange)
+
+	mu        sync.Mutex
+	exchanges []Exchange
+	served    []int
+}
+
+// NewStub returns a Stub serving the responses of exchanges. Exchanges
+// without a response are ignored.
+func NewStub(exchanges []Exchange) *Stub {
+	s := &Stub{}
+	for _, ex := range exchanges {
+		if ex.Response != nil {
+			s.exchanges = append(s.exchanges, ex)
+		}
+	}
+	s.served = make([]int, len(s.exchanges))
+	return s
+}
+
+// Len returns the number of exchanges the stub serves.
+func (s *Stub) Len() int {
+	return len(s.exchanges)
+}
+
+func (s *Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	ex := s.match(r, body)
+	if s.OnRequest != nil {
+		s.OnRequest(r, ex)
+	}
+	if ex == nil {
+		http.Error(w, fmt.Sprintf("no recorded response for %s %s", r.Method, r.URL.RequestURI()), http.StatusNotFound)
+		return
+	}
+
+	resp := ex.Response
+	for name, values := range responseHeader(resp) {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.WriteString(w, resp.Body)
+}
+
+// ErrNoRecording is matched by the errors of the requests RoundTrip has no
+// recorded response for.
+var ErrNoRecording = errors.New("no recorded response")
+
+// RoundTrip answers req with its recorded response, matched like the
+// requests ServeHTTP answers, making the stub an http.RoundTripper that
+// serves clients without a server. Requests without a recorded response
+// fail with an error matching ErrNoRecording.
+func (s *Stub) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+	}
+	ex := s.match(req, body)
+	if s.OnRequest != nil {
+		s.OnRequest(req, ex)
+	}
+	if ex == nil {
+		return nil, fmt.Errorf("%w for %s %s", ErrNoRecording, req.Method, req.URL)
+	}
+	resp := ex.Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+		StatusCode:    resp.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        responseHeader(resp),
+		Body:          io.NopCloser(strings.NewReader(resp.Body)),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}, nil
+}
+
+// responseHeader returns the header of the recorded response resp as it is
+// served.
+func responseHeader(resp *Response) http.Header {
+	header := http.Header{}
+	for name, values := range resp.Header {
+		switch http.CanonicalHeaderKey(name) {
+		case "Content-Length", "Transfer-Encoding", "Connection":
+			continue
+		case "Content-Encoding":
+			// Recordings usually hold decoded bodies
+			if !isGzip(values, resp.Body) {
+				continue
+			}
+		}
+		header[name] = append([]string(nil), values...)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(resp.Body)))
+	return header
+}
+
+// match returns the exchange answering r, marking it as served.
+func (s *Stub) match(r *http.Request, body []byte) *Exchange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := r.URL.Query().Encode()
+	best, bestScore := []int(nil), -1
+	for i, ex := range s.exchanges {
+		u, err := url.Parse(ex.Request.URL)
+		if err != nil || !strings.EqualFold(ex.Request.Method, r.Method) || cleanPath(u.Path) != cleanPath(r.URL.Path) {
+			continue
+		}
+		score := 0
+		if u.Query().Encode() == query {
+			score += 2
+		}
+		if bytes.Equal(bytes.TrimSpace([]byte(ex.Request.Body)), bytes.TrimSpace(body)) {
+			score++
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore = []int{i}, score
+		case score == bestScore:
+			best = append(best, i)
+		}
+	}
+	if len(best) == 0 {
+		return nil
+	}
+
+	chosen := best[len(best)-1]
+	for _, i := range best {
+		if s.served[i] == 0 {
+			chosen = i
+			break
+		}
+	}
+	s.served[chosen]++
+	ex := s.exchanges[chosen]
+	return &ex
+}
+
+// Reset serves the matching exchanges from the first one again.
+func (s *Stub) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.served = make([]int, len(s.exchanges))
+}
+
+// Routes returns the method and path of the served exchanges, sorted and
+// without duplicates.
+func (s *Stub) Routes() []string {
+	seen := map[string]bool{}
+	var routes []string
+	for _, ex := range s.exchanges {
+		path := ex.Request.URL
+		if u, err := url.Parse(path); err == nil {
+			path = cleanPath(u.Path)
+		}
+		route := strings.ToUpper(ex.Request.Method) + " " + path
+		if !seen[route] {
+			seen[route] = true
+			routes = append(routes, route)
+		}
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+func cleanPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// isGzip reports whether a body recorded with the Content-Encoding values
+// is still gzip encoded.
+func isGzip(encoding []string, body string) bool {
+	return len(encoding) == 1 && strings.EqualFold(encoding[0], "gzip") && strings.HasPrefix(body, "\x1f\x8b")
+}
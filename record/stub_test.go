@@ -0,0 +1,151 @@
+package record_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl/record"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exchange(method, url, reqBody string, status int, respBody string) record.Exchange {
+	return record.Exchange{
+		Request:  record.Request{Method: method, URL: url, Body: reqBody},
+		Response: &record.Response{StatusCode: status, Header: http.Header{"Content-Type": {"text/plain"}}, Body: respBody},
+	}
+}
+
+func serve(t *testing.T, stub http.Handler, method, target, body string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	stub.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec.Code, rec.Body.String()
+}
+
+func TestStub(t *testing.T) {
+	stub := record.NewStub([]record.Exchange{
+		exchange("GET", "https://api.example.com/items", "", 200, "all"),
+		exchange("GET", "https://api.example.com/items?page=2", "", 200, "page 2"),
+		exchange("POST", "https://api.example.com/items", `{"name":"a"}`, 201, "created a"),
+		exchange("POST", "https://api.example.com/items", `{"name":"b"}`, 201, "created b"),
+		exchange("GET", "https://api.example.com/jobs/1", "", 202, "pending"),
+		exchange("GET", "https://api.example.com/jobs/1", "", 200, "done"),
+		{Request: record.Request{Method: "GET", URL: "https://api.example.com/failed"}},
+	})
+	assert.Equal(t, 6, stub.Len())
+	assert.Equal(t, []string{"GET /items", "GET /jobs/1", "POST /items"}, stub.Routes())
+
+	t.Run("Query", func(t *testing.T) {
+		_, body := serve(t, stub, "GET", "/items", "")
+		assert.Equal(t, "all", body)
+		_, body = serve(t, stub, "GET", "/items?page=2", "")
+		assert.Equal(t, "page 2", body)
+	})
+
+	t.Run("Body", func(t *testing.T) {
+		code, body := serve(t, stub, "POST", "/items", `{"name":"b"}`)
+		assert.Equal(t, 201, code)
+		assert.Equal(t, "created b", body)
+	})
+
+	t.Run("Sequence", func(t *testing.T) {
+		for _, want := range []string{"pending", "done", "done"} {
+			_, body := serve(t, stub, "GET", "/jobs/1", "")
+			assert.Equal(t, want, body)
+		}
+		stub.Reset()
+		_, body := serve(t, stub, "GET", "/jobs/1", "")
+		assert.Equal(t, "pending", body)
+	})
+
+	t.Run("No match", func(t *testing.T) {
+		var missed bool
+		stub.OnRequest = func(r *http.Request, ex *record.Exchange) { missed = ex == nil }
+		defer func() { stub.OnRequest = nil }()
+
+		code, body := serve(t, stub, "DELETE", "/items", "")
+		assert.Equal(t, http.StatusNotFound, code)
+		assert.Contains(t, body, "no recorded response for DELETE /items")
+		assert.True(t, missed)
+	})
+}
+
+func TestStubContentEncoding(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte("compressed"))
+	w.Close()
+
+	decoded := exchange("GET", "https://api.example.com/decoded", "", 200, "plain")
+	decoded.Response.Header.Set("Content-Encoding", "gzip")
+	encoded := exchange("GET", "https://api.example.com/encoded", "", 200, gz.String())
+	encoded.Response.Header.Set("Content-Encoding", "gzip")
+	stub := record.NewStub([]record.Exchange{decoded, encoded})
+
+	rec := httptest.NewRecorder()
+	stub.ServeHTTP(rec, httptest.NewRequest("GET", "/decoded", nil))
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "plain", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	stub.ServeHTTP(rec, httptest.NewRequest("GET", "/encoded", nil))
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	r, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(r)
+	assert.Equal(t, "compressed", string(body))
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, ex := range map[string]record.Exchange{
+		"a.jsonl": exchange("GET", "https://api.example.com/a", "", 200, "a"),
+		"b.json":  exchange("GET", "https://api.example.com/b", "", 200, "b"),
+	} {
+		recorder := record.NewRecorder()
+		req, _ := http.NewRequest(ex.Request.Method, ex.Request.URL, nil)
+		recorder.Record(req, nil, &http.Response{StatusCode: 200}, []byte(ex.Response.Body), 0)
+		require.NoError(t, recorder.Save(filepath.Join(dir, name)))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644))
+
+	exchanges, err := record.LoadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, exchanges, 2)
+	assert.Equal(t, "https://api.example.com/a", exchanges[0].Request.URL)
+	assert.Equal(t, "https://api.example.com/b", exchanges[1].Request.URL)
+
+	single, err := record.LoadDir(filepath.Join(dir, "a.jsonl"))
+	require.NoError(t, err)
+	assert.Len(t, single, 1)
+
+	_, err = record.LoadDir(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestStubRoundTrip(t *testing.T) {
+	stub := record.NewStub([]record.Exchange{
+		exchange("GET", "https://api.example.com/items", "", 200, "all"),
+		exchange("POST", "https://api.example.com/items", `{"name":"a"}`, 201, "created a"),
+	})
+	client := &http.Client{Transport: stub}
+
+	resp, err := client.Post("https://api.example.com/items", "application/json", strings.NewReader(`{"name":"a"}`))
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, 201, resp.StatusCode)
+	assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "created a", string(body))
+
+	_, err = client.Get("https://api.example.com/missing")
+	assert.ErrorIs(t, err, record.ErrNoRecording)
+}
@@ -0,0 +1,233 @@
+// Package redact masks secrets, such as tokens, passwords and signed URL
+// parameters, in the text gocurl writes out: logs, error messages,
+// recordings and the history of the command line tool.
+package redact
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces redacted values.
+const Mask = "****"
+
+// secretNames matches the names of headers, query parameters and cookies
+// that hold secrets, such as Authorization, X-Api-Key, token or sig.
+var secretNames = regexp.MustCompile(`(?i)auth|token|secret|passw(or)?d|api[-_]?key|cookie|signature|credential|^\s*(key|sig)\s*$`)
+
+// urlPattern finds the URLs of free text, such as error messages.
+var urlPattern = regexp.MustCompile(`https?://[^\s"'<>]+`)
+
+// Redactor is a registry of secrets to mask. It masks the values it was
+// given with AddSecret wherever they appear, and the values of headers,
+// query parameters and URL passwords whose names look secret. Values
+// referring to variables, such as "Bearer $TOKEN", hold no secret and are
+// kept.
+//
+// A Redactor is safe for concurrent use.
+type Redactor struct {
+	mu      sync.RWMutex
+	secrets []string
+	names   []*regexp.Regexp
+}
+
+// New returns a Redactor matching the common secret names.
+func New() *Redactor {
+	return &Redactor{names: []*regexp.Regexp{secretNames}}
+}
+
+// Default is the registry gocurl masks its logs and error messages with.
+var Default = New()
+
+// AddSecret registers values to mask wherever they appear, as is and URL
+// encoded. Empty values are ignored.
+func (r *Redactor) AddSecret(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		r.secrets = append(r.secrets, value)
+		if escaped := url.QueryEscape(value); escaped != value {
+			r.secrets = append(r.secrets, escaped)
+		}
+	}
+	// Longer secrets first, so one containing another is masked whole
+	sort.SliceStable(r.secrets, func(i, j int) bool {
+		return len(r.secrets[i]) > len(r.secrets[j])
+	})
+}
+
+// AddName registers a pattern matching more header, query parameter or
+// cookie names whose values are secret.
+func (r *Redactor) AddName(pattern *regexp.Regexp) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names = append(r.names, pattern)
+}
+
+// IsSecretName reports whether values named name are masked.
+func (r *Redactor) IsSecretName(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, pattern := range r.names {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// String masks the registered secrets of s and the secrets of the URLs it
+// contains.
+func (r *Redactor) String(s string) string {
+	s = r.secretValues(s)
+	if !strings.Contains(s, "://") {
+		return s
+	}
+	return urlPattern.ReplaceAllStringFunc(s, r.maskURL)
+}
+
+// URL masks the password, the secret query parameters and the registered
+// secrets of rawURL.
+func (r *Redactor) URL(rawURL string) string {
+	return r.maskURL(r.secretValues(rawURL))
+}
+
+// Header returns a copy of h with the values of secret headers masked, as
+// well as the registered secrets of the others.
+func (r *Redactor) Header(h http.Header) http.Header {
+	if h == nil {
+		return nil
+	}
+	masked := make(http.Header, len(h))
+	for name, values := range h {
+		secret := r.IsSecretName(name)
+		masked[name] = make([]string, len(values))
+		for i, value := range values {
+			if secret && !isVariable(value) {
+				masked[name][i] = Mask
+			} else {
+				masked[name][i] = r.String(value)
+			}
+		}
+	}
+	return masked
+}
+
+// Args masks the credentials of a curl command line: secret headers, -u
+// passwords, -b cookies, URLs and the registered secrets. It returns a copy
+// of args and whether anything was masked.
+func (r *Redactor) Args(args []string) ([]string, bool) {
+	masked := append([]string(nil), args...)
+	changed := false
+	mask := func(i int, value string) {
+		if masked[i] != value {
+			masked[i] = value
+			changed = true
+		}
+	}
+
+	for i := 0; i < len(masked); i++ {
+		arg := masked[i]
+		if i+1 < len(masked) {
+			switch arg {
+			case "-H", "--header":
+				i++
+				name, value, ok := strings.Cut(masked[i], ":")
+				if ok && r.IsSecretName(name) && !isVariable(value) {
+					mask(i, name+": "+Mask)
+				} else {
+					mask(i, r.String(masked[i]))
+				}
+				continue
+			case "-u", "--user":
+				i++
+				if user, password, ok := strings.Cut(masked[i], ":"); ok && !isVariable(password) {
+					mask(i, user+":"+Mask)
+				}
+				continue
+			case "-b", "--cookie":
+				i++
+				if strings.Contains(masked[i], "=") && !isCookieVariable(masked[i]) {
+					mask(i, Mask)
+				}
+				continue
+			}
+		}
+		mask(i, r.String(arg))
+	}
+	return masked, changed
+}
+
+// secretValues replaces the registered secrets of s with Mask.
+func (r *Redactor) secretValues(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Mask)
+	}
+	return s
+}
+
+// maskURL masks the password and secret query parameters of rawURL.
+func (r *Redactor) maskURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	var userinfo string
+	if password, ok := u.User.Password(); ok && password != Mask && !isVariable(password) {
+		userinfo = url.User(u.User.Username()).String() + ":" + Mask + "@"
+		u.User = nil
+	}
+	changed := userinfo != ""
+	params := strings.Split(u.RawQuery, "&")
+	for i, param := range params {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok || value == Mask || isVariable(value) {
+			continue
+		}
+		if unescaped, err := url.QueryUnescape(name); err == nil && r.IsSecretName(unescaped) {
+			params[i] = name + "=" + Mask
+			changed = true
+		}
+	}
+	if !changed {
+		return rawURL
+	}
+	u.RawQuery = strings.Join(params, "&")
+	if userinfo == "" {
+		return u.String()
+	}
+	// Set the user info by hand, as url.URL would escape the mask
+	scheme, rest, _ := strings.Cut(u.String(), "://")
+	return scheme + "://" + userinfo + rest
+}
+
+// variablePattern matches values that are a variable reference, as in
+// "$TOKEN" or "${TOKEN:-x}", optionally after a scheme such as "Bearer".
+var variablePattern = regexp.MustCompile(`^\s*([A-Za-z][\w-]*\s+)?(\$[A-Za-z_]\w*|\$\{[A-Za-z_]\w*([:?+=-][^}]*)?\})\s*$`)
+
+// isVariable reports whether value is a reference to a variable, as in
+// "Bearer $TOKEN". Values merely containing a $, such as a password
+// "pa$$w0rd", are not.
+func isVariable(value string) bool {
+	return variablePattern.MatchString(value)
+}
+
+// isCookieVariable reports whether the value of every cookie of a -b
+// argument, as in "session=$SESSION", is a variable reference.
+func isCookieVariable(value string) bool {
+	for _, cookie := range strings.Split(value, ";") {
+		if _, v, _ := strings.Cut(cookie, "="); !isVariable(v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,441 @@
+// Package registry is a small client for container registries implementing
+// the Docker Registry HTTP API V2 and the OCI distribution spec, built on
+// gocurl.
+//
+// It handles the token authentication dance, fetches manifests with the
+// Accept headers registries expect and downloads blobs with digest
+// verification, resuming interrupted downloads with range requests.
+//
+//	client := registry.New(registry.DockerHub)
+//	manifest, err := client.Manifest(ctx, "alpine", "latest")
+//	...
+//	err = client.DownloadBlob(ctx, "alpine", layer.Digest, "layer.tar.gz")
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/middlewares"
+	"github.com/maniartech/gocurl/options"
+)
+
+// DockerHub is the registry of Docker Hub images.
+const DockerHub = "https://registry-1.docker.io"
+
+// Manifest media types.
+const (
+	MediaTypeOCIIndex            = "application/vnd.oci.image.index.v1+json"
+	MediaTypeOCIManifest         = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeDockerManifestList  = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeDockerManifest      = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerManifestV1JWS = "application/vnd.docker.distribution.manifest.v1+prettyjws"
+)
+
+// manifestAccept lists the manifest types the client understands, image
+// indexes first so that multi-platform images are not resolved by the
+// registry.
+var manifestAccept = options.AcceptHeader(
+	MediaTypeOCIIndex,
+	MediaTypeDockerManifestList,
+	MediaTypeOCIManifest,
+	MediaTypeDockerManifest,
+)
+
+// Client talks to a registry. It caches the tokens it obtains per scope and
+// is safe for concurrent use.
+type Client struct {
+	// Registry is the base URL of the registry, e.g. DockerHub
+	Registry string
+	// Username and Password authenticate to the token service, or to the
+	// registry itself when it asks for basic authentication. Anonymous
+	// access is used when empty.
+	Username string
+	Password string
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+// New returns a client for the registry at the base URL registry.
+func New(registry string) *Client {
+	return &Client{Registry: strings.TrimSuffix(registry, "/")}
+}
+
+// SetCredentials sets the credentials of the client.
+func (c *Client) SetCredentials(username, password string) *Client {
+	c.Username, c.Password = username, password
+	return c
+}
+
+// Descriptor references a manifest or a blob by digest.
+type Descriptor struct {
+	MediaType string    `json:"mediaType"`
+	Digest    string    `json:"digest"`
+	Size      int64     `json:"size"`
+	Platform  *Platform `json:"platform,omitempty"`
+}
+
+// Platform is the platform of a manifest listed in an image index.
+type Platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// Manifest is an image manifest or, when Manifests is set, an image index
+// listing the manifests of each platform.
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Config        *Descriptor  `json:"config,omitempty"`
+	Layers        []Descriptor `json:"layers,omitempty"`
+	Manifests     []Descriptor `json:"manifests,omitempty"`
+
+	// Digest is the digest of Raw, as the manifest is referenced by
+	Digest string `json:"-"`
+	// Raw is the manifest as served by the registry
+	Raw []byte `json:"-"`
+}
+
+// IsIndex reports whether the manifest is an image index.
+func (m *Manifest) IsIndex() bool {
+	return m.MediaType == MediaTypeOCIIndex || m.MediaType == MediaTypeDockerManifestList || len(m.Manifests) > 0
+}
+
+// Platform returns the manifest of an index for os and arch (with an
+// optional variant, e.g. "arm64/v8"), or nil when there is none.
+func (m *Manifest) Platform(os, arch string) *Descriptor {
+	arch, variant, _ := strings.Cut(arch, "/")
+	for i, d := range m.Manifests {
+		p := d.Platform
+		if p != nil && p.OS == os && p.Architecture == arch && (variant == "" || p.Variant == variant) {
+			return &m.Manifests[i]
+		}
+	}
+	return nil
+}
+
+// repository returns the repository name as the registry knows it: Docker
+// Hub official images live under library/.
+func (c *Client) repository(repo string) string {
+	if c.Registry == DockerHub && !strings.Contains(repo, "/") {
+		return "library/" + repo
+	}
+	return repo
+}
+
+// Manifest fetches the manifest of repo at reference, a tag or a digest.
+// Manifests fetched by digest are verified against it.
+func (c *Client) Manifest(ctx context.Context, repo, reference string) (*Manifest, error) {
+	repo = c.repository(repo)
+	opts := options.NewRequestOptions(c.Registry + "/v2/" + repo + "/manifests/" + url.PathEscape(reference))
+	opts.Headers = http.Header{"Accept": {manifestAccept}}
+
+	resp, body, err := c.do(ctx, opts, repo)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("manifest", repo, reference, resp, body)
+	}
+
+	raw := []byte(body)
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if strings.Contains(reference, ":") {
+		if err := verify(reference, raw); err != nil {
+			return nil, fmt.Errorf("registry: manifest %s@%s: %v", repo, reference, err)
+		}
+		digest = reference
+	} else if digest == "" {
+		sum := sha256.Sum256(raw)
+		digest = "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	m := &Manifest{Digest: digest, Raw: raw}
+	if err := json.Unmarshal(raw, m); err != nil {
+		return nil, fmt.Errorf("registry: invalid manifest %s:%s: %v", repo, reference, err)
+	}
+	if m.MediaType == "" {
+		m.MediaType = strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+	}
+	if m.MediaType == mediaTypeDockerManifestV1JWS {
+		return nil, fmt.Errorf("registry: schema 1 manifests are not supported")
+	}
+	return m, nil
+}
+
+// DownloadBlob downloads the blob of repo with the given digest to path,
+// verifying its content against the digest. The blob is written to path
+// with a .partial suffix first: an interrupted download is resumed from
+// there with a range request, and the file is only renamed to path once
+// verified. A blob that fails verification is removed.
+func (c *Client) DownloadBlob(ctx context.Context, repo, digest, path string) error {
+	repo = c.repository(repo)
+	partial := path + ".partial"
+	file, err := os.OpenFile(partial, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("registry: %v", err)
+	}
+	defer file.Close()
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("registry: %v", err)
+	}
+
+	opts := options.NewRequestOptions(c.Registry + "/v2/" + repo + "/blobs/" + digest)
+	opts.FollowRedirects = true
+	opts.MaxRedirects = 10
+	if offset > 0 {
+		opts.Headers = http.Header{"Range": {fmt.Sprintf("bytes=%d-", offset)}}
+	}
+	// Stream successful responses to the file rather than into memory
+	opts.Interceptors = []middlewares.Interceptor{func(req *http.Request, next middlewares.RoundTripFunc) (*http.Response, error) {
+		resp, err := next(req)
+		if err != nil || resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+			return resp, err
+		}
+		defer resp.Body.Close()
+		start := offset
+		if resp.StatusCode == http.StatusOK {
+			start = 0
+		}
+		if err := file.Truncate(start); err != nil {
+			return nil, err
+		}
+		if _, err := file.Seek(start, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(file, resp.Body); err != nil {
+			return nil, err
+		}
+		resp.Body = http.NoBody
+		return resp, nil
+	}}
+
+	resp, body, err := c.do(ctx, opts, repo)
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file already holds the whole blob
+	default:
+		return statusError("blob", repo, digest, resp, body)
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("registry: %v", err)
+	}
+	if err := verifyReader(digest, file); err != nil {
+		file.Close()
+		os.Remove(partial)
+		return fmt.Errorf("registry: blob %s@%s: %v", repo, digest, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("registry: %v", err)
+	}
+	if err := os.Rename(partial, path); err != nil {
+		return fmt.Errorf("registry: %v", err)
+	}
+	return nil
+}
+
+// process is gocurl.Process returning the body even when it was spooled to
+// disk, which leaves the returned body empty: registry responses decoded
+// from it are small JSON documents.
+func process(ctx context.Context, opts *options.RequestOptions) (*http.Response, string, error) {
+	resp, body, err := gocurl.Process(ctx, opts)
+	if resp != nil && body == "" {
+		b, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr != nil {
+			return nil, "", readErr
+		}
+		body = string(b)
+		resp.Body = io.NopCloser(strings.NewReader(body))
+	}
+	return resp, body, err
+}
+
+// do sends the request with the token of repo, performing the
+// authentication the registry asks for when it answers 401 Unauthorized.
+func (c *Client) do(ctx context.Context, opts *options.RequestOptions, repo string) (*http.Response, string, error) {
+	opts.Silent = true
+	scope := "repository:" + repo + ":pull"
+	if token := c.token(scope); token != "" {
+		opts.BearerToken = token
+	}
+
+	retry := opts.Clone()
+	resp, body, err := process(ctx, opts)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, body, err
+	}
+
+	scheme, params := parseChallenge(resp.Header.Get("WWW-Authenticate"))
+	switch scheme {
+	case "bearer":
+		if params["scope"] != "" {
+			scope = params["scope"]
+		}
+		token, err := c.fetchToken(ctx, params["realm"], params["service"], scope)
+		if err != nil {
+			return nil, "", err
+		}
+		c.mu.Lock()
+		if c.tokens == nil {
+			c.tokens = map[string]string{}
+		}
+		c.tokens[scope] = token
+		c.mu.Unlock()
+		retry.BearerToken = token
+	case "basic":
+		if c.Username == "" {
+			return resp, body, nil
+		}
+		retry.BearerToken = ""
+		retry.SetBasicAuth(c.Username, c.Password)
+	default:
+		return resp, body, nil
+	}
+	return process(ctx, retry)
+}
+
+func (c *Client) token(scope string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens[scope]
+}
+
+// fetchToken obtains a token for scope from the token service at realm.
+func (c *Client) fetchToken(ctx context.Context, realm, service, scope string) (string, error) {
+	if realm == "" {
+		return "", fmt.Errorf("registry: bearer challenge without realm")
+	}
+	u, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("registry: invalid token realm: %v", err)
+	}
+	query := u.Query()
+	if service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", scope)
+	u.RawQuery = query.Encode()
+
+	opts := options.NewRequestOptions(u.String())
+	opts.Silent = true
+	if c.Username != "" {
+		opts.SetBasicAuth(c.Username, c.Password)
+	}
+	resp, body, err := process(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry: token request failed: %s", resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal([]byte(body), &token); err != nil {
+		return "", fmt.Errorf("registry: invalid token response: %v", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return "", fmt.Errorf("registry: token response without token")
+	}
+	return token.Token, nil
+}
+
+// parseChallenge parses a WWW-Authenticate header such as
+// `Bearer realm="https://auth.docker.io/token",service="registry.docker.io"`
+// into its lower-cased scheme and parameters.
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := map[string]string{}
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			params[key] = value
+		}
+	}
+	return strings.ToLower(scheme), params
+}
+
+// digestHash returns the hash of the algorithm of digest and its expected
+// hex value.
+func digestHash(digest string) (hash.Hash, string, error) {
+	algorithm, encoded, ok := strings.Cut(digest, ":")
+	if !ok {
+		return nil, "", fmt.Errorf("invalid digest %q", digest)
+	}
+	switch algorithm {
+	case "sha256":
+		return sha256.New(), encoded, nil
+	case "sha512":
+		return sha512.New(), encoded, nil
+	}
+	return nil, "", fmt.Errorf("unsupported digest algorithm %q", algorithm)
+}
+
+func verify(digest string, data []byte) error {
+	return verifyReader(digest, bytes.NewReader(data))
+}
+
+func verifyReader(digest string, r io.Reader) error {
+	h, want, err := digestHash(digest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return fmt.Errorf("digest mismatch: got %s:%s", strings.Split(digest, ":")[0], got)
+	}
+	return nil
+}
+
+func statusError(kind, repo, reference string, resp *http.Response, body string) error {
+	var errs struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal([]byte(body), &errs) == nil && len(errs.Errors) > 0 {
+		return fmt.Errorf("registry: %s %s:%s: %s: %s %s", kind, repo, reference, resp.Status, errs.Errors[0].Code, errs.Errors[0].Message)
+	}
+	return fmt.Errorf("registry: %s %s:%s: %s", kind, repo, reference, resp.Status)
+}
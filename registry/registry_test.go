@@ -0,0 +1,171 @@
+package registry_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl/registry"
+)
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// fakeRegistry serves one repository behind token authentication.
+type fakeRegistry struct {
+	*httptest.Server
+	manifest, index, blob []byte
+	tokens                int32
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	r := &fakeRegistry{blob: bytes.Repeat([]byte("layer data "), 1000)}
+	r.manifest = []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":%q,"config":{"mediaType":"application/vnd.oci.image.config.v1+json","digest":"sha256:c","size":2},"layers":[{"mediaType":"application/vnd.oci.image.layer.v1.tar+gzip","digest":%q,"size":%d}]}`,
+		registry.MediaTypeOCIManifest, digestOf(r.blob), len(r.blob)))
+	r.index = []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":%q,"manifests":[{"mediaType":%q,"digest":%q,"size":%d,"platform":{"architecture":"arm64","os":"linux","variant":"v8"}}]}`,
+		registry.MediaTypeOCIIndex, registry.MediaTypeOCIManifest, digestOf(r.manifest), len(r.manifest)))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		user, pass, _ := req.BasicAuth()
+		if user != "ada" || pass != "secret" || req.URL.Query().Get("scope") != "repository:team/app:pull" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		atomic.AddInt32(&r.tokens, 1)
+		fmt.Fprint(w, `{"access_token":"t0k3n"}`)
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer t0k3n" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:team/app:pull"`, r.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"errors":[{"code":"UNAUTHORIZED","message":"authentication required"}]}`)
+			return
+		}
+		switch {
+		case req.URL.Path == "/v2/team/app/manifests/latest":
+			if !strings.HasPrefix(req.Header.Get("Accept"), registry.MediaTypeOCIIndex) {
+				t.Errorf("unexpected Accept header %q", req.Header.Get("Accept"))
+			}
+			w.Header().Set("Content-Type", registry.MediaTypeOCIIndex)
+			w.Write(r.index)
+		case req.URL.Path == "/v2/team/app/manifests/"+digestOf(r.manifest):
+			w.Write(r.manifest)
+		case req.URL.Path == "/v2/team/app/manifests/sha256:bad":
+			w.Write(r.manifest)
+		case req.URL.Path == "/v2/team/app/blobs/"+digestOf(r.blob):
+			http.Redirect(w, req, "/cdn/blob", http.StatusTemporaryRedirect)
+		case req.URL.Path == "/v2/team/app/blobs/sha256:0000":
+			w.Write([]byte("corrupt"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`)
+		}
+	})
+	mux.HandleFunc("/cdn/blob", func(w http.ResponseWriter, req *http.Request) {
+		http.ServeContent(w, req, "blob", time.Time{}, bytes.NewReader(r.blob))
+	})
+	r.Server = httptest.NewServer(mux)
+	t.Cleanup(r.Close)
+	return r
+}
+
+func TestManifest(t *testing.T) {
+	fake := newFakeRegistry(t)
+	client := registry.New(fake.URL).SetCredentials("ada", "secret")
+	ctx := context.Background()
+
+	index, err := client.Manifest(ctx, "team/app", "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !index.IsIndex() {
+		t.Fatalf("expected an image index, got %s", index.MediaType)
+	}
+	desc := index.Platform("linux", "arm64/v8")
+	if desc == nil {
+		t.Fatal("expected a linux/arm64 manifest")
+	}
+	if index.Platform("windows", "amd64") != nil {
+		t.Error("expected no windows/amd64 manifest")
+	}
+
+	manifest, err := client.Manifest(ctx, "team/app", desc.Digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.IsIndex() || len(manifest.Layers) != 1 || manifest.Digest != desc.Digest {
+		t.Errorf("unexpected manifest %+v", manifest)
+	}
+	if n := atomic.LoadInt32(&fake.tokens); n != 1 {
+		t.Errorf("expected the token to be fetched once, got %d", n)
+	}
+
+	if _, err := client.Manifest(ctx, "team/app", "sha256:bad"); err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Errorf("expected a digest mismatch, got %v", err)
+	}
+	if _, err := client.Manifest(ctx, "team/app", "missing"); err == nil || !strings.Contains(err.Error(), "MANIFEST_UNKNOWN") {
+		t.Errorf("expected MANIFEST_UNKNOWN, got %v", err)
+	}
+	if _, err := registry.New(fake.URL).Manifest(ctx, "team/app", "latest"); err == nil {
+		t.Error("expected anonymous access to fail")
+	}
+}
+
+func TestDownloadBlob(t *testing.T) {
+	fake := newFakeRegistry(t)
+	client := registry.New(fake.URL).SetCredentials("ada", "secret")
+	ctx := context.Background()
+	digest := digestOf(fake.blob)
+
+	t.Run("Complete", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "layer")
+		if err := client.DownloadBlob(ctx, "team/app", digest, path); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(path)
+		if !bytes.Equal(data, fake.blob) {
+			t.Error("downloaded blob differs")
+		}
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "layer")
+		if err := os.WriteFile(path+".partial", fake.blob[:4000], 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.DownloadBlob(ctx, "team/app", digest, path); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(path)
+		if !bytes.Equal(data, fake.blob) {
+			t.Error("resumed blob differs")
+		}
+		if _, err := os.Stat(path + ".partial"); !os.IsNotExist(err) {
+			t.Error("expected the partial file to be renamed")
+		}
+	})
+
+	t.Run("Digest mismatch", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "layer")
+		err := client.DownloadBlob(ctx, "team/app", "sha256:0000", path)
+		if err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+			t.Fatalf("expected a digest mismatch, got %v", err)
+		}
+		if _, err := os.Stat(path + ".partial"); !os.IsNotExist(err) {
+			t.Error("expected the corrupt blob to be removed")
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package gocurl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/maniartech/gocurl/options"
+	"github.com/maniartech/gocurl/record"
+)
+
+// ReplayOption modifies a captured request before it is re-issued.
+type ReplayOption func(opts *options.RequestOptions) error
+
+// ReplayHost sends the request to host instead of the original one. host may
+// include a scheme ("https://staging.example.com") to change it as well.
+func ReplayHost(host string) ReplayOption {
+	return func(opts *options.RequestOptions) error {
+		u, err := url.Parse(opts.URL)
+		if err != nil {
+			return fmt.Errorf("invalid URL: %v", err)
+		}
+		if strings.Contains(host, "://") {
+			target, err := url.Parse(host)
+			if err != nil {
+				return fmt.Errorf("invalid host: %v", err)
+			}
+			u.Scheme = target.Scheme
+			host = target.Host
+		}
+		u.Host = host
+		opts.URL = u.String()
+		return nil
+	}
+}
+
+// ReplayHeader sets header key to value, replacing any captured values.
+func ReplayHeader(key, value string) ReplayOption {
+	return func(opts *options.RequestOptions) error {
+		opts.Headers.Set(key, value)
+		return nil
+	}
+}
+
+// ReplayBodyField sets the field at the dot separated path of the captured
+// JSON body to value, creating intermediate objects as needed.
+func ReplayBodyField(path string, value interface{}) ReplayOption {
+	return func(opts *options.RequestOptions) error {
+		var body interface{}
+		if opts.Body != "" {
+			if err := json.Unmarshal([]byte(opts.Body), &body); err != nil {
+				return fmt.Errorf("request body is not JSON: %v", err)
+			}
+		}
+		if body == nil {
+			body = map[string]interface{}{}
+		}
+
+		obj, ok := body.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("request body is not a JSON object")
+		}
+		keys := strings.Split(path, ".")
+		for _, key := range keys[:len(keys)-1] {
+			next, ok := obj[key].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				obj[key] = next
+			}
+			obj = next
+		}
+		obj[keys[len(keys)-1]] = value
+
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request body: %v", err)
+		}
+		opts.Body = string(encoded)
+		return nil
+	}
+}
+
+// ReplayRequestOptions converts a captured exchange into request options with
+// modifications applied.
+func ReplayRequestOptions(ex *record.Exchange, modifications ...ReplayOption) (*options.RequestOptions, error) {
+	opts := options.NewRequestOptions(ex.Request.URL)
+	opts.Method = ex.Request.Method
+	opts.Headers = ex.Request.Header.Clone()
+	if opts.Headers == nil {
+		opts.Headers = http.Header{}
+	}
+	opts.Body = ex.Request.Body
+
+	for _, modify := range modifications {
+		if err := modify(opts); err != nil {
+			return nil, err
+		}
+	}
+	return opts, nil
+}
+
+// Replay re-issues a captured request with modifications applied, e.g. to
+// reproduce a production request against staging.
+func Replay(ctx context.Context, ex *record.Exchange, modifications ...ReplayOption) (*http.Response, string, error) {
+	opts, err := ReplayRequestOptions(ex, modifications...)
+	if err != nil {
+		return nil, "", err
+	}
+	opts.Silent = true
+
+	return Process(ctx, opts)
+}
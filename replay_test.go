@@ -0,0 +1,67 @@
+package gocurl_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/maniartech/gocurl/record"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	type received struct {
+		env, body, trace string
+	}
+	var got []received
+	handler := func(env string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			got = append(got, received{env, string(body), r.Header.Get("X-Trace")})
+			w.Write([]byte(env))
+		}
+	}
+	production := httptest.NewServer(handler("production"))
+	defer production.Close()
+	staging := httptest.NewServer(handler("staging"))
+	defer staging.Close()
+
+	recorder := record.NewRecorder()
+	opts := options.NewRequestOptionsBuilder().
+		SetMethod("POST").
+		SetURL(production.URL+"/orders").
+		SetBody(`{"order":{"id":1,"qty":2}}`).
+		AddHeader("Content-Type", "application/json").
+		SetRecorder(recorder).
+		SetSilent(true).
+		Build()
+	_, _, err := gocurl.Process(context.Background(), opts)
+	require.NoError(t, err)
+
+	ex := recorder.Last()
+	require.NotNil(t, ex)
+	assert.Equal(t, production.URL+"/orders", ex.Request.URL)
+	assert.Equal(t, "production", ex.Response.Body)
+
+	_, body, err := gocurl.Replay(context.Background(), ex,
+		gocurl.ReplayHost(staging.URL),
+		gocurl.ReplayHeader("X-Trace", "incident-7"),
+		gocurl.ReplayBodyField("order.qty", 5),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "staging", body)
+
+	require.Len(t, got, 2)
+	assert.Equal(t, received{"staging", `{"order":{"id":1,"qty":5}}`, "incident-7"}, got[1])
+
+	t.Run("Body field on non JSON body", func(t *testing.T) {
+		ex := &record.Exchange{Request: record.Request{Method: "POST", URL: staging.URL, Body: "a=b"}}
+		_, err := gocurl.ReplayRequestOptions(ex, gocurl.ReplayBodyField("a", 1))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,284 @@
+package gocurl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/maniartech/gocurl/options"
+	"gopkg.in/yaml.v3"
+)
+
+// RequestFile is a set of named requests defined in YAML or JSON, so that
+// the requests of a project can be kept in git next to its code:
+//
+//	variables:
+//	  base: https://api.example.com
+//	requests:
+//	  create-user:
+//	    method: POST
+//	    url: ${base}/users
+//	    headers:
+//	      Authorization: Bearer ${TOKEN}
+//	    body: '{"name": "${name|json}"}'
+//	    assert:
+//	      status: 201
+//	      json:
+//	        name: ${name}
+//
+// URLs, query parameters, headers and bodies are templates expanded like
+// the variables of a command: from the variables the request is run with,
+// then those of the file, then the environment.
+type RequestFile struct {
+	Variables Variables                     `yaml:"variables,omitempty" json:"variables,omitempty"`
+	Requests  map[string]*RequestDefinition `yaml:"requests" json:"requests"`
+}
+
+// RequestDefinition is a request of a RequestFile.
+type RequestDefinition struct {
+	Method  string            `yaml:"method,omitempty" json:"method,omitempty"`
+	URL     string            `yaml:"url" json:"url"`
+	Query   map[string]string `yaml:"query,omitempty" json:"query,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty" json:"body,omitempty"`
+	// Assert are the checks the response must pass
+	Assert *RequestAssertions `yaml:"assert,omitempty" json:"assert,omitempty"`
+}
+
+// RequestAssertions are the checks the response of a RequestDefinition must
+// pass. Expected values are templates as well.
+type RequestAssertions struct {
+	// Status is the expected status code
+	Status int `yaml:"status,omitempty" json:"status,omitempty"`
+	// Headers are the expected values of response headers
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	// BodyContains are strings the body must contain
+	BodyContains []string `yaml:"body_contains,omitempty" json:"body_contains,omitempty"`
+	// JSON are the expected values of the fields of a JSON body, by dot
+	// separated path such as "user.roles.0"
+	JSON map[string]interface{} `yaml:"json,omitempty" json:"json,omitempty"`
+}
+
+// AssertionError is returned when a response fails the assertions of its
+// request.
+type AssertionError struct {
+	Request  string
+	Failures []string
+}
+
+func (e *AssertionError) Error() string {
+	return fmt.Sprintf("request %s failed %d assertion(s): %s", e.Request, len(e.Failures), strings.Join(e.Failures, "; "))
+}
+
+// LoadRequests reads the request definitions of a YAML or JSON file.
+func LoadRequests(path string) (*RequestFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := ParseRequests(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return file, nil
+}
+
+// ParseRequests parses request definitions in YAML or JSON, a subset of
+// YAML.
+func ParseRequests(data []byte) (*RequestFile, error) {
+	var file RequestFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid request file: %v", err)
+	}
+	for name, request := range file.Requests {
+		if request == nil || request.URL == "" {
+			return nil, fmt.Errorf("request %s has no url", name)
+		}
+	}
+	return &file, nil
+}
+
+// Names returns the names of the requests of the file, sorted.
+func (f *RequestFile) Names() []string {
+	names := make([]string, 0, len(f.Requests))
+	for name := range f.Requests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RequestOptions returns the options of the named request with its
+// templates expanded with vars.
+func (f *RequestFile) RequestOptions(name string, vars Variables) (*options.RequestOptions, error) {
+	request, ok := f.Requests[name]
+	if !ok {
+		return nil, fmt.Errorf("no request %q", name)
+	}
+	vars = f.variables(vars)
+
+	var firstErr error
+	expand := func(s string) string {
+		expanded, err := vars.Resolve(s)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("request %s: %v", name, err)
+		}
+		return expanded
+	}
+
+	opts := options.NewRequestOptions(expand(request.URL))
+	opts.Method = strings.ToUpper(request.Method)
+	if opts.Method == "" {
+		opts.Method = "GET"
+	}
+	opts.Headers = http.Header{}
+	for key, value := range request.Headers {
+		opts.Headers.Set(key, expand(value))
+	}
+	if len(request.Query) > 0 {
+		opts.QueryParams = url.Values{}
+		for key, value := range request.Query {
+			opts.QueryParams.Set(key, expand(value))
+		}
+	}
+	opts.Body = expand(request.Body)
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return opts, nil
+}
+
+// RunRequest executes the named request with its templates expanded with
+// vars and checks its assertions, returning an *AssertionError along with
+// the response when it fails them.
+func (f *RequestFile) RunRequest(ctx context.Context, name string, vars Variables) (*http.Response, string, error) {
+	opts, err := f.RequestOptions(name, vars)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, body, err := Process(ctx, opts)
+	if err != nil {
+		return resp, body, err
+	}
+	if body, err = loadSpooled(resp, body); err != nil {
+		return resp, "", err
+	}
+	if assert := f.Requests[name].Assert; assert != nil {
+		if failures := assert.check(resp, body, f.variables(vars)); len(failures) > 0 {
+			return resp, body, &AssertionError{Request: name, Failures: failures}
+		}
+	}
+	return resp, body, nil
+}
+
+// variables returns vars layered over the variables of the file.
+func (f *RequestFile) variables(vars Variables) Variables {
+	merged := Variables{}
+	for key, value := range f.Variables {
+		merged[key] = value
+	}
+	for key, value := range vars {
+		merged[key] = value
+	}
+	return merged
+}
+
+// check returns the assertions resp and body fail.
+func (a *RequestAssertions) check(resp *http.Response, body string, vars Variables) []string {
+	var failures []string
+	if a.Status != 0 && resp.StatusCode != a.Status {
+		failures = append(failures, fmt.Sprintf("status: expected %d, got %d", a.Status, resp.StatusCode))
+	}
+	for _, key := range sortedKeys(a.Headers) {
+		if expected, got := vars.Expand(a.Headers[key]), resp.Header.Get(key); got != expected {
+			failures = append(failures, fmt.Sprintf("header %s: expected %q, got %q", key, expected, got))
+		}
+	}
+	for _, s := range a.BodyContains {
+		if s = vars.Expand(s); !strings.Contains(body, s) {
+			failures = append(failures, fmt.Sprintf("body: does not contain %q", s))
+		}
+	}
+	if len(a.JSON) == 0 {
+		return failures
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		return append(failures, fmt.Sprintf("body: not JSON: %v", err))
+	}
+	paths := make([]string, 0, len(a.JSON))
+	for path := range a.JSON {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		got, ok := jsonPathValue(doc, path)
+		if !ok {
+			failures = append(failures, fmt.Sprintf("json %s: missing", path))
+			continue
+		}
+		if expected := a.JSON[path]; !jsonValueEqual(expected, got, vars) {
+			if s, ok := expected.(string); ok {
+				expected = vars.Expand(s)
+			}
+			gotJSON, _ := json.Marshal(got)
+			failures = append(failures, fmt.Sprintf("json %s: expected %v, got %s", path, expected, gotJSON))
+		}
+	}
+	return failures
+}
+
+// jsonPathValue returns the value at the dot separated path of doc, where
+// numeric keys index arrays.
+func jsonPathValue(doc interface{}, path string) (interface{}, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			doc = value
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			doc = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+// jsonValueEqual reports whether the expected value of an assertion matches
+// the decoded JSON value got. Expected strings are expanded and compared to
+// the text of got, so "${id}" matches a numeric id.
+func jsonValueEqual(expected, got interface{}, vars Variables) bool {
+	if s, ok := expected.(string); ok {
+		s = vars.Expand(s)
+		if gotString, ok := got.(string); ok {
+			return s == gotString
+		}
+		gotJSON, _ := json.Marshal(got)
+		return s == string(gotJSON)
+	}
+	expectedJSON, err := json.Marshal(expected)
+	if err != nil {
+		return false
+	}
+	// Round trip so that numbers compare alike
+	var normalized interface{}
+	json.Unmarshal(expectedJSON, &normalized)
+	expectedJSON, _ = json.Marshal(normalized)
+	gotJSON, _ := json.Marshal(got)
+	return string(expectedJSON) == string(gotJSON)
+}
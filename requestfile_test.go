@@ -0,0 +1,102 @@
+package gocurl_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const requestFileYAML = `
+variables:
+  role: admin
+requests:
+  create-user:
+    method: post
+    url: ${base}/users
+    query:
+      notify: "true"
+    headers:
+      Authorization: Bearer ${TOKEN}
+      Content-Type: application/json
+    body: '{"name": "${name|json}", "role": "${role}"}'
+    assert:
+      status: 201
+      headers:
+        Content-Type: application/json
+      body_contains: ['"id"']
+      json:
+        id: 7
+        name: ${name}
+        roles.0: ${role}
+  get-user:
+    url: ${base}/users/${id}
+    assert:
+      status: 200
+`
+
+func TestRequestFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.NotFound(w, r)
+			return
+		}
+		var user map[string]string
+		json.NewDecoder(r.Body).Decode(&user)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":     7,
+			"name":   user["name"],
+			"roles":  []string{user["role"]},
+			"token":  r.Header.Get("Authorization"),
+			"notify": r.URL.Query().Get("notify"),
+		})
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "requests.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(requestFileYAML), 0644))
+	file, err := gocurl.LoadRequests(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"create-user", "get-user"}, file.Names())
+
+	t.Setenv("TOKEN", "abc")
+	vars := gocurl.Variables{"base": server.URL, "name": `Ada "The Countess"`}
+	resp, body, err := file.RunRequest(context.Background(), "create-user", vars)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Contains(t, body, `"token":"Bearer abc"`)
+	assert.Contains(t, body, `"notify":"true"`)
+
+	_, _, err = file.RunRequest(context.Background(), "get-user", gocurl.Variables{"base": server.URL, "id": "7"})
+	var assertErr *gocurl.AssertionError
+	require.ErrorAs(t, err, &assertErr)
+	assert.Equal(t, "get-user", assertErr.Request)
+	assert.Equal(t, []string{"status: expected 200, got 404"}, assertErr.Failures)
+
+	_, _, err = file.RunRequest(context.Background(), "delete-user", vars)
+	assert.EqualError(t, err, `no request "delete-user"`)
+
+	opts, err := file.RequestOptions("get-user", gocurl.Variables{"base": "https://api.example.com", "id": "7"})
+	require.NoError(t, err)
+	assert.Equal(t, "GET", opts.Method)
+	assert.Equal(t, "https://api.example.com/users/7", opts.URL)
+}
+
+func TestParseRequestsJSON(t *testing.T) {
+	file, err := gocurl.ParseRequests([]byte(`{"requests": {"health": {"url": "${base:?base is required}/health", "assert": {"json": {"ok": true}}}}}`))
+	require.NoError(t, err)
+	_, err = file.RequestOptions("health", nil)
+	assert.ErrorContains(t, err, "base is required")
+
+	_, err = gocurl.ParseRequests([]byte(`{"requests": {"health": {}}}`))
+	assert.EqualError(t, err, "request health has no url")
+}
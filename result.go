@@ -0,0 +1,169 @@
+package gocurl
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// Result describes how a request was executed: what was finally fetched,
+// after how many redirects and attempts, how long each phase took and how
+// many bytes were transferred.
+type Result struct {
+	// Response is the final response, nil when none was received
+	Response *http.Response
+	// Body is the response body. It is only set by Execute.
+	Body string
+
+	// EffectiveURL is the URL of the final response, after redirects
+	EffectiveURL string
+	// Redirects is the number of redirects followed
+	Redirects int
+	// Attempts is the number of times the request was sent, retries included
+	Attempts int
+
+	// Timings are the durations of the phases of the last attempt, and the
+	// total duration of the request
+	Timings Timings
+
+	// BytesSent is the size of the request body, -1 when streamed with an
+	// unknown length
+	BytesSent int64
+	// BytesReceived is the size of the response body
+	BytesReceived int64
+
+	// TLS is the state of the TLS connection of the final response, nil
+	// over plain HTTP
+	TLS *tls.ConnectionState
+	// TLSResumed reports whether the TLS connection of the final response
+	// resumed a previous session, saving a full handshake
+	TLSResumed bool
+}
+
+// Retries returns the number of times the request was retried.
+func (r *Result) Retries() int {
+	if r.Attempts == 0 {
+		return 0
+	}
+	return r.Attempts - 1
+}
+
+// Timings are the durations of the phases of a request, like the time_*
+// variables of curl -w. Phases that did not happen, such as the DNS lookup
+// of a reused connection, are zero.
+type Timings struct {
+	DNSLookup       time.Duration
+	Connect         time.Duration
+	TLSHandshake    time.Duration
+	TimeToFirstByte time.Duration
+	Total           time.Duration
+}
+
+// WithResult returns a context making the requests executed with it, such
+// as by Curl, CurlJSON or Process, fill result once done. It gives the high
+// level functions access to the details Execute returns, except for Body.
+func WithResult(ctx context.Context, result *Result) context.Context {
+	return context.WithValue(ctx, resultKey, result)
+}
+
+// Execute executes the request described by opts like Process, and returns
+// its Result. The result is returned even when the request fails, holding
+// what is known of it, such as its attempts.
+func Execute(ctx context.Context, opts *options.RequestOptions) (*Result, error) {
+	result := &Result{}
+	resp, body, err := Process(WithResult(ctx, result), opts)
+	result.Response, result.Body = resp, body
+	return result, err
+}
+
+// Execute executes the request described by opts through the client, like
+// the package level Execute.
+func (c *Client) Execute(ctx context.Context, opts *options.RequestOptions) (*Result, error) {
+	result := &Result{}
+	resp, body, err := c.Process(WithResult(ctx, result), opts)
+	result.Response, result.Body = resp, body
+	return result, err
+}
+
+// finish fills the result of the request, if any, with resp, which may be
+// nil, and the size of its body.
+func (s *requestState) finish(resp *http.Response, sent, received int64) {
+	r := s.result
+	if r == nil {
+		return
+	}
+	if s.total == 0 {
+		s.total = time.Since(s.start)
+	}
+	r.Attempts = int(atomic.LoadInt32(&s.attempts))
+	r.Timings = s.timings.get()
+	r.Timings.Total = s.total
+	r.BytesSent = sent
+	if resp == nil {
+		return
+	}
+	r.Response = resp
+	r.BytesReceived = received
+	r.TLS = resp.TLS
+	r.TLSResumed = resp.TLS != nil && resp.TLS.DidResume
+	if req := resp.Request; req != nil {
+		r.EffectiveURL = req.URL.String()
+		r.Redirects = 0
+		for prev := req.Response; prev != nil && prev.Request != nil; prev = prev.Request.Response {
+			r.Redirects++
+		}
+	}
+}
+
+// timingTrace times the phases of the connections of an attempt.
+type timingTrace struct {
+	mu                                 sync.Mutex
+	start, dnsStart, connect, tlsStart time.Time
+	timings                            Timings
+}
+
+// trace returns the hooks timing a new attempt.
+func (t *timingTrace) trace() *httptrace.ClientTrace {
+	t.mu.Lock()
+	t.start = time.Now()
+	t.timings = Timings{}
+	t.mu.Unlock()
+
+	now := func(at *time.Time) func() {
+		return func() {
+			t.mu.Lock()
+			*at = time.Now()
+			t.mu.Unlock()
+		}
+	}
+	since := func(start *time.Time, d *time.Duration) func() {
+		return func() {
+			t.mu.Lock()
+			if !start.IsZero() {
+				*d = time.Since(*start)
+			}
+			t.mu.Unlock()
+		}
+	}
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { now(&t.dnsStart)() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { since(&t.dnsStart, &t.timings.DNSLookup)() },
+		ConnectStart:         func(string, string) { now(&t.connect)() },
+		ConnectDone:          func(string, string, error) { since(&t.connect, &t.timings.Connect)() },
+		TLSHandshakeStart:    now(&t.tlsStart),
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { since(&t.tlsStart, &t.timings.TLSHandshake)() },
+		GotFirstResponseByte: since(&t.start, &t.timings.TimeToFirstByte),
+	}
+}
+
+func (t *timingTrace) get() Timings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.timings
+}
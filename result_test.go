@@ -0,0 +1,95 @@
+package gocurl_test
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecute(t *testing.T) {
+	var calls int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusFound)
+		case "/flaky":
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			io.WriteString(w, "ok")
+		default:
+			body, _ := io.ReadAll(r.Body)
+			w.Write(append([]byte("hello "), body...))
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	opts := options.NewRequestOptionsBuilder().
+		SetURL(server.URL + "/old").
+		SetBody("ada").
+		SetMethod("POST").
+		SetFollowRedirects(true).
+		SetMaxRedirects(5).
+		SetTLSConfig(&tls.Config{InsecureSkipVerify: true}).
+		SetSilent(true).
+		Build()
+	result, err := gocurl.Execute(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, result.Response.StatusCode)
+	assert.Equal(t, "hello ", result.Body)
+	assert.Equal(t, server.URL+"/new", result.EffectiveURL)
+	assert.Equal(t, 1, result.Redirects)
+	assert.Equal(t, 1, result.Attempts)
+	assert.Equal(t, 0, result.Retries())
+	assert.EqualValues(t, 3, result.BytesSent)
+	assert.EqualValues(t, 6, result.BytesReceived)
+	require.NotNil(t, result.TLS)
+	assert.True(t, result.TLS.HandshakeComplete)
+	assert.Greater(t, result.Timings.Total, time.Duration(0))
+	assert.Greater(t, result.Timings.TimeToFirstByte, time.Duration(0))
+	assert.LessOrEqual(t, result.Timings.TimeToFirstByte, result.Timings.Total)
+
+	t.Run("Retries", func(t *testing.T) {
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(server.URL + "/flaky").
+			SetTLSConfig(&tls.Config{InsecureSkipVerify: true}).
+			SetSilent(true).
+			SetRetryConfig(&options.RetryConfig{MaxRetries: 2, RetryOnHTTP: []int{http.StatusServiceUnavailable}}).
+			Build()
+		result, err := gocurl.NewClient().Execute(ctx, opts)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", result.Body)
+		assert.Equal(t, 2, result.Attempts)
+		assert.Equal(t, 1, result.Retries())
+	})
+
+	t.Run("WithResult", func(t *testing.T) {
+		var result gocurl.Result
+		resp, body, err := gocurl.Curl(gocurl.WithResult(ctx, &result), "-k", "-s", "-d", "grace", server.URL+"/")
+		require.NoError(t, err)
+		assert.Equal(t, "hello grace", body)
+		assert.Same(t, resp, result.Response)
+		assert.Empty(t, result.Body)
+		assert.EqualValues(t, 11, result.BytesReceived)
+		assert.Equal(t, 1, result.Attempts)
+	})
+
+	t.Run("Failures", func(t *testing.T) {
+		result, err := gocurl.Execute(ctx, &options.RequestOptions{URL: "http://127.0.0.1:1/", Silent: true})
+		require.Error(t, err)
+		assert.Nil(t, result.Response)
+		assert.Equal(t, 1, result.Attempts)
+	})
+}
@@ -0,0 +1,479 @@
+package gocurl
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// UploadProtocol is the protocol of a resumable upload.
+type UploadProtocol string
+
+const (
+	// UploadTus is the tus.io resumable upload protocol, version 1.0.0
+	UploadTus UploadProtocol = "tus"
+	// UploadGoogle is the resumable upload protocol of Google Cloud Storage
+	// and other Google APIs
+	UploadGoogle UploadProtocol = "google"
+)
+
+// googleChunkAlign is the size Google resumable upload chunks must be a
+// multiple of, except the last one.
+const googleChunkAlign = 256 << 10
+
+// ResumableUploadOptions configures CurlUploadResumable.
+type ResumableUploadOptions struct {
+	// Protocol is the upload protocol, UploadTus if empty
+	Protocol UploadProtocol
+	// ChunkSize is the size of the chunks sent, 8 MiB if zero. It is rounded
+	// up to a multiple of 256 KiB for UploadGoogle.
+	ChunkSize int64
+
+	// ContentType is the type of the file, sent as X-Upload-Content-Type
+	// by UploadGoogle
+	ContentType string
+	// Metadata is sent as the Upload-Metadata of UploadTus uploads, such as
+	// filename
+	Metadata map[string]string
+
+	// MaxResumes is the number of times in a row a failed chunk is resumed
+	// from the offset the server received, 5 if zero
+	MaxResumes int
+	// RetryDelay is the delay before the first resume, doubled for each
+	// one after it, 1 second if zero
+	RetryDelay time.Duration
+
+	// StateFile, when set, stores the URL of the upload session so that an
+	// upload interrupted by the end of the process is resumed by the next
+	// call rather than restarted. It is removed once the upload completes.
+	StateFile string
+
+	// Client sends the requests, Process when nil
+	Client *Client
+}
+
+// resumableState is the session of an upload, stored in the StateFile.
+type resumableState struct {
+	Endpoint string `json:"endpoint"`
+	Session  string `json:"session"`
+	Size     int64  `json:"size"`
+}
+
+// CurlUploadResumable uploads file to endpoint in chunks through a
+// resumable upload session, so that a failed chunk is resumed from what the
+// server received rather than restarting the whole upload. The session is
+// created with a POST to endpoint, which may also be a curl command adding
+// authentication or, for UploadGoogle, the JSON metadata of the object:
+//
+//	resp, err := gocurl.CurlUploadResumable(ctx, "backup.tar", `curl -H "Authorization: Bearer ${TOKEN}" -H "Content-Type: application/json" -d '{"name":"backup.tar"}' "https://storage.googleapis.com/upload/storage/v1/b/bucket/o?uploadType=resumable"`,
+//		&gocurl.ResumableUploadOptions{Protocol: gocurl.UploadGoogle})
+//
+// The chunks are sent with the headers of the command to the session URL,
+// with PATCH requests and Upload-Offset for UploadTus, and with PUT requests
+// and Content-Range for UploadGoogle. It returns the response to the last
+// chunk. opts may be nil.
+func CurlUploadResumable(ctx context.Context, file, endpoint string, opts *ResumableUploadOptions) (*http.Response, error) {
+	if opts == nil {
+		opts = &ResumableUploadOptions{}
+	}
+	base, err := parseCommand(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file for upload: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file for upload: %v", err)
+	}
+
+	u := &resumableUpload{opts: opts, base: base, size: info.Size()}
+	var protocol uploadProtocol
+	switch opts.Protocol {
+	case UploadTus, "":
+		protocol = tusProtocol{u}
+	case UploadGoogle:
+		protocol = googleProtocol{u}
+	default:
+		return nil, fmt.Errorf("unsupported upload protocol %q", opts.Protocol)
+	}
+
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = 8 << 20
+	}
+	if opts.Protocol == UploadGoogle {
+		chunkSize = (chunkSize + googleChunkAlign - 1) / googleChunkAlign * googleChunkAlign
+	}
+	maxResumes := opts.MaxResumes
+	if maxResumes <= 0 {
+		maxResumes = 5
+	}
+	delay := opts.RetryDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+
+	// Resume the session of an earlier call, unless it expired
+	var session string
+	var offset int64
+	if state, err := u.loadState(); err != nil {
+		return nil, err
+	} else if state != nil {
+		var final *http.Response
+		offset, final, err = protocol.status(ctx, state.Session)
+		if final != nil {
+			return final, u.removeState()
+		}
+		if err == nil {
+			session = state.Session
+		}
+	}
+	if session == "" {
+		if session, err = protocol.create(ctx); err != nil {
+			return nil, err
+		}
+		offset = 0
+		if err := u.saveState(session); err != nil {
+			return nil, err
+		}
+	}
+
+	chunk := make([]byte, min(chunkSize, u.size))
+	resumes := 0
+	for {
+		if offset > u.size {
+			return nil, fmt.Errorf("upload server received %d bytes of %d", offset, u.size)
+		}
+		n, err := f.ReadAt(chunk[:min(chunkSize, u.size-offset)], offset)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read file for upload: %v", err)
+		}
+		next, final, err := protocol.send(ctx, session, offset, chunk[:n])
+		if err == nil && final != nil {
+			return final, u.removeState()
+		}
+		if err == nil {
+			if next <= offset {
+				return nil, fmt.Errorf("upload made no progress at offset %d", offset)
+			}
+			offset, resumes = next, 0
+			continue
+		}
+
+		// Resume from what the server received
+		var statusErr *uploadStatusError
+		if errors.As(err, &statusErr) && !statusErr.retryable() || ctx.Err() != nil || resumes >= maxResumes {
+			return nil, err
+		}
+		select {
+		case <-time.After(delay << resumes):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		resumes++
+		if offset, final, err = protocol.status(ctx, session); err != nil {
+			return nil, err
+		}
+		if final != nil {
+			return final, u.removeState()
+		}
+	}
+}
+
+// uploadProtocol is the protocol of a resumable upload. status and send
+// return the response completing the upload when it is complete, and
+// otherwise the offset the server received up to.
+type uploadProtocol interface {
+	create(ctx context.Context) (session string, err error)
+	status(ctx context.Context, session string) (offset int64, final *http.Response, err error)
+	send(ctx context.Context, session string, offset int64, chunk []byte) (next int64, final *http.Response, err error)
+}
+
+// uploadStatusError reports an unexpected response of an upload server.
+type uploadStatusError struct {
+	Op     string
+	Status string
+	Code   int
+}
+
+func (e *uploadStatusError) Error() string {
+	return fmt.Sprintf("failed to %s: %s", e.Op, e.Status)
+}
+
+// retryable reports whether the upload can resume after the error.
+func (e *uploadStatusError) retryable() bool {
+	switch e.Code {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusLocked, http.StatusTooManyRequests:
+		return true
+	}
+	return e.Code >= 500
+}
+
+// resumableUpload is an upload in progress.
+type resumableUpload struct {
+	opts *ResumableUploadOptions
+	base *options.RequestOptions
+	size int64
+}
+
+// do sends a request of the upload. A session URL replaces the endpoint of
+// the command, and with it its body and query.
+func (u *resumableUpload) do(ctx context.Context, method, session string, header http.Header, body string) (*http.Response, error) {
+	opts := u.base.Clone()
+	opts.Method = method
+	opts.Silent = true
+	opts.OutputFile = ""
+	if opts.Headers == nil {
+		opts.Headers = http.Header{}
+	}
+	if session != "" {
+		opts.URL = session
+		opts.QueryParams = nil
+		opts.Headers.Del("Content-Type")
+		opts.Body, opts.BodyReader = body, nil
+		opts.Form, opts.FileUpload, opts.UploadFile = nil, nil, ""
+	}
+	for key, values := range header {
+		opts.Headers[key] = values
+	}
+
+	var resp *http.Response
+	var err error
+	if u.opts.Client != nil {
+		resp, _, err = u.opts.Client.Process(ctx, opts)
+	} else {
+		resp, _, err = Process(ctx, opts)
+	}
+	return resp, err
+}
+
+// sessionURL returns the session URL in the Location of the response
+// creating it.
+func (u *resumableUpload) sessionURL(resp *http.Response) (string, error) {
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", fmt.Errorf("failed to create upload: no Location in the response")
+	}
+	endpoint, err := url.Parse(u.base.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid upload endpoint: %v", err)
+	}
+	ref, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("failed to create upload: invalid Location: %v", err)
+	}
+	return endpoint.ResolveReference(ref).String(), nil
+}
+
+// loadState returns the session stored in the StateFile for this upload,
+// or nil when there is none.
+func (u *resumableUpload) loadState() (*resumableState, error) {
+	if u.opts.StateFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(u.opts.StateFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload state: %v", err)
+	}
+	var state resumableState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to read upload state: %v", err)
+	}
+	if state.Endpoint != u.base.URL || state.Size != u.size || state.Session == "" {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+func (u *resumableUpload) saveState(session string) error {
+	if u.opts.StateFile == "" {
+		return nil
+	}
+	data, err := json.Marshal(&resumableState{Endpoint: u.base.URL, Session: session, Size: u.size})
+	if err != nil {
+		return fmt.Errorf("failed to write upload state: %v", err)
+	}
+	if err := os.WriteFile(u.opts.StateFile, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write upload state: %v", err)
+	}
+	return nil
+}
+
+func (u *resumableUpload) removeState() error {
+	if u.opts.StateFile == "" {
+		return nil
+	}
+	if err := os.Remove(u.opts.StateFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove upload state: %v", err)
+	}
+	return nil
+}
+
+// tusProtocol implements version 1.0.0 of the tus.io protocol, with its
+// creation extension.
+type tusProtocol struct {
+	*resumableUpload
+}
+
+func (p tusProtocol) create(ctx context.Context) (string, error) {
+	header := http.Header{
+		"Tus-Resumable": {"1.0.0"},
+		"Upload-Length": {strconv.FormatInt(p.size, 10)},
+	}
+	if len(p.opts.Metadata) > 0 {
+		keys := make([]string, 0, len(p.opts.Metadata))
+		for key := range p.opts.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, len(keys))
+		for i, key := range keys {
+			pairs[i] = key + " " + base64.StdEncoding.EncodeToString([]byte(p.opts.Metadata[key]))
+		}
+		header.Set("Upload-Metadata", strings.Join(pairs, ","))
+	}
+	resp, err := p.do(ctx, http.MethodPost, "", header, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to create upload: %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return "", &uploadStatusError{Op: "create upload", Status: resp.Status, Code: resp.StatusCode}
+	}
+	return p.sessionURL(resp)
+}
+
+func (p tusProtocol) status(ctx context.Context, session string) (int64, *http.Response, error) {
+	resp, err := p.do(ctx, http.MethodHead, session, http.Header{"Tus-Resumable": {"1.0.0"}}, "")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to resume upload: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return 0, nil, &uploadStatusError{Op: "resume upload", Status: resp.Status, Code: resp.StatusCode}
+	}
+	offset, err := uploadOffset(resp)
+	if err != nil {
+		return 0, nil, err
+	}
+	if offset == p.size && p.size > 0 {
+		return offset, resp, nil
+	}
+	return offset, nil, nil
+}
+
+func (p tusProtocol) send(ctx context.Context, session string, offset int64, chunk []byte) (int64, *http.Response, error) {
+	header := http.Header{
+		"Tus-Resumable": {"1.0.0"},
+		"Upload-Offset": {strconv.FormatInt(offset, 10)},
+		"Content-Type":  {"application/offset+octet-stream"},
+	}
+	resp, err := p.do(ctx, http.MethodPatch, session, header, string(chunk))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to upload chunk: %v", err)
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return 0, nil, &uploadStatusError{Op: "upload chunk", Status: resp.Status, Code: resp.StatusCode}
+	}
+	next, err := uploadOffset(resp)
+	if err != nil {
+		return 0, nil, err
+	}
+	if next == p.size {
+		return next, resp, nil
+	}
+	return next, nil, nil
+}
+
+// uploadOffset returns the Upload-Offset of a tus response.
+func uploadOffset(resp *http.Response) (int64, error) {
+	offset, err := strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid Upload-Offset %q", resp.Header.Get("Upload-Offset"))
+	}
+	return offset, nil
+}
+
+// googleProtocol implements the resumable uploads of Google APIs.
+type googleProtocol struct {
+	*resumableUpload
+}
+
+func (p googleProtocol) create(ctx context.Context) (string, error) {
+	header := http.Header{"X-Upload-Content-Length": {strconv.FormatInt(p.size, 10)}}
+	if p.opts.ContentType != "" {
+		header.Set("X-Upload-Content-Type", p.opts.ContentType)
+	}
+	method := p.base.Method
+	if method == "" || method == http.MethodGet {
+		method = http.MethodPost
+	}
+	resp, err := p.do(ctx, method, "", header, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to create upload: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", &uploadStatusError{Op: "create upload", Status: resp.Status, Code: resp.StatusCode}
+	}
+	return p.sessionURL(resp)
+}
+
+func (p googleProtocol) status(ctx context.Context, session string) (int64, *http.Response, error) {
+	header := http.Header{"Content-Range": {fmt.Sprintf("bytes */%d", p.size)}}
+	resp, err := p.do(ctx, http.MethodPut, session, header, "")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to resume upload: %v", err)
+	}
+	return p.progress(resp, "resume upload")
+}
+
+func (p googleProtocol) send(ctx context.Context, session string, offset int64, chunk []byte) (int64, *http.Response, error) {
+	contentRange := fmt.Sprintf("bytes */%d", p.size)
+	if len(chunk) > 0 {
+		contentRange = fmt.Sprintf("bytes %d-%d/%d", offset, offset+int64(len(chunk))-1, p.size)
+	}
+	resp, err := p.do(ctx, http.MethodPut, session, http.Header{"Content-Range": {contentRange}}, string(chunk))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to upload chunk: %v", err)
+	}
+	return p.progress(resp, "upload chunk")
+}
+
+// progress interprets the response to a chunk or status request: 308
+// Resume Incomplete with the Range received, or the final response.
+func (p googleProtocol) progress(resp *http.Response, op string) (int64, *http.Response, error) {
+	switch {
+	case resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated:
+		return p.size, resp, nil
+	case resp.StatusCode != http.StatusPermanentRedirect:
+		return 0, nil, &uploadStatusError{Op: op, Status: resp.Status, Code: resp.StatusCode}
+	}
+	received := resp.Header.Get("Range")
+	if received == "" {
+		return 0, nil, nil
+	}
+	_, last, ok := strings.Cut(strings.TrimPrefix(received, "bytes="), "-")
+	end, err := strconv.ParseInt(last, 10, 64)
+	if !ok || err != nil {
+		return 0, nil, fmt.Errorf("invalid Range %q", received)
+	}
+	return end + 1, nil, nil
+}
@@ -0,0 +1,193 @@
+package gocurl_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tusServer is a minimal tus.io server failing the PATCH requests listed in
+// failAt, after storing part of their chunk.
+type tusServer struct {
+	mu       sync.Mutex
+	data     []byte
+	length   int
+	metadata string
+	failAt   map[int]bool
+	patches  int
+	created  int
+}
+
+func (s *tusServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Header.Get("Tus-Resumable") != "1.0.0" {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		s.created++
+		s.length, _ = strconv.Atoi(r.Header.Get("Upload-Length"))
+		s.metadata = r.Header.Get("Upload-Metadata")
+		s.data = nil
+		w.Header().Set("Location", "/files/1")
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodHead:
+		w.Header().Set("Upload-Offset", strconv.Itoa(len(s.data)))
+		w.Header().Set("Upload-Length", strconv.Itoa(s.length))
+	case http.MethodPatch:
+		s.patches++
+		if offset, _ := strconv.Atoi(r.Header.Get("Upload-Offset")); offset != len(s.data) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		chunk, _ := io.ReadAll(r.Body)
+		if s.failAt[s.patches] {
+			s.data = append(s.data, chunk[:len(chunk)/2]...)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		s.data = append(s.data, chunk...)
+		w.Header().Set("Upload-Offset", strconv.Itoa(len(s.data)))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestCurlUploadResumableTus(t *testing.T) {
+	content := strings.Repeat("resumable upload ", 100)
+	file := filepath.Join(t.TempDir(), "upload.txt")
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+
+	tus := &tusServer{failAt: map[int]bool{3: true}}
+	server := httptest.NewServer(tus)
+	defer server.Close()
+
+	resp, err := gocurl.CurlUploadResumable(context.Background(), file, server.URL+"/files", &gocurl.ResumableUploadOptions{
+		ChunkSize:  500,
+		Metadata:   map[string]string{"filename": "upload.txt"},
+		RetryDelay: time.Millisecond,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, content, string(tus.data))
+	assert.Equal(t, "filename dXBsb2FkLnR4dA==", tus.metadata)
+	assert.Equal(t, 1, tus.created)
+
+	t.Run("Fatal error", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				w.Header().Set("Location", "/files/1")
+				w.WriteHeader(http.StatusCreated)
+				return
+			}
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer failing.Close()
+		_, err := gocurl.CurlUploadResumable(context.Background(), file, failing.URL, &gocurl.ResumableUploadOptions{RetryDelay: time.Millisecond})
+		assert.ErrorContains(t, err, "403")
+	})
+
+	t.Run("Resume across calls", func(t *testing.T) {
+		tus := &tusServer{failAt: map[int]bool{2: true}}
+		server := httptest.NewServer(tus)
+		defer server.Close()
+		state := filepath.Join(t.TempDir(), "upload.json")
+		opts := &gocurl.ResumableUploadOptions{ChunkSize: 500, MaxResumes: 1, RetryDelay: time.Millisecond, StateFile: state}
+
+		// The chunk resumed after the first failure fails as well
+		tus.failAt[3] = true
+		_, err := gocurl.CurlUploadResumable(context.Background(), file, server.URL+"/files", opts)
+		require.Error(t, err)
+		assert.FileExists(t, state)
+
+		_, err = gocurl.CurlUploadResumable(context.Background(), file, server.URL+"/files", opts)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(tus.data))
+		assert.Equal(t, 1, tus.created)
+		assert.NoFileExists(t, state)
+	})
+}
+
+func TestCurlUploadResumableGoogle(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789abcdef", 40<<10)) // 640 KiB
+	file := filepath.Join(t.TempDir(), "backup.tar")
+	require.NoError(t, os.WriteFile(file, content, 0o644))
+
+	var mu sync.Mutex
+	var data []byte
+	var initiated http.Header
+	var ranges []string
+	failed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPost {
+			initiated = r.Header.Clone()
+			body, _ := io.ReadAll(r.Body)
+			initiated.Set("Body", string(body))
+			w.Header().Set("Location", "/upload/session?upload_id=42")
+			return
+		}
+		assert.Equal(t, "42", r.URL.Query().Get("upload_id"))
+		contentRange := r.Header.Get("Content-Range")
+		ranges = append(ranges, contentRange)
+		if chunk, _ := io.ReadAll(r.Body); len(chunk) > 0 {
+			if !failed {
+				// Lose the first chunk
+				failed = true
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			var start int
+			fmt.Sscanf(contentRange, "bytes %d-", &start)
+			data = append(data[:start], chunk...)
+		}
+		if len(data) == len(content) {
+			w.WriteHeader(http.StatusOK)
+			io.WriteString(w, `{"name":"backup.tar"}`)
+			return
+		}
+		if len(data) > 0 {
+			w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(data)-1))
+		}
+		w.WriteHeader(http.StatusPermanentRedirect)
+	}))
+	defer server.Close()
+
+	command := fmt.Sprintf(`curl -H "Authorization: Bearer token" -H "Content-Type: application/json" -d '{"name":"backup.tar"}' "%s/upload?uploadType=resumable"`, server.URL)
+	resp, err := gocurl.CurlUploadResumable(context.Background(), file, command, &gocurl.ResumableUploadOptions{
+		Protocol:    gocurl.UploadGoogle,
+		ChunkSize:   100 << 10, // rounded up to 256 KiB
+		ContentType: "application/x-tar",
+		RetryDelay:  time.Millisecond,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, content, data)
+
+	assert.Equal(t, "655360", initiated.Get("X-Upload-Content-Length"))
+	assert.Equal(t, "application/x-tar", initiated.Get("X-Upload-Content-Type"))
+	assert.Equal(t, "Bearer token", initiated.Get("Authorization"))
+	assert.Equal(t, `{"name":"backup.tar"}`, initiated.Get("Body"))
+	assert.Equal(t, []string{
+		"bytes 0-262143/655360",
+		"bytes */655360",
+		"bytes 0-262143/655360",
+		"bytes 262144-524287/655360",
+		"bytes 524288-655359/655360",
+	}, ranges)
+}
@@ -0,0 +1,95 @@
+package gocurl
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// ResumeSupport is what a server supports for resuming the download of a
+// resource, or splitting it into segments.
+type ResumeSupport struct {
+	// Ranges reports whether the server answers Range requests with 206
+	// Partial Content
+	Ranges bool
+	// Size is the size of the resource, or -1 when it is unknown
+	Size int64
+
+	ETag         string
+	LastModified string
+	// Validator is the value to send as If-Range with the requests resuming
+	// the download, so that a resource changed in the meantime is sent
+	// whole instead of patched: the ETag when it is strong, otherwise the
+	// Last-Modified date. It is empty when the server sent neither.
+	Validator string
+}
+
+// SupportsResume probes the resource of the curl command, or URL, for the
+// download managers planning to resume or segment its download. It sends a
+// HEAD request for the metadata of the resource, then asks for its first
+// byte to confirm that ranges are supported, as servers do not always
+// advertise Accept-Ranges, or honour it. The body of a server ignoring the
+// range is not downloaded.
+func SupportsResume(ctx context.Context, command ...string) (*ResumeSupport, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, err
+	}
+	opts.Silent = true
+	opts.OutputFile = ""
+	support := &ResumeSupport{Size: -1}
+
+	// HEAD is not supported by every server, so its failure is not one
+	head := opts.Clone()
+	head.Method = http.MethodHead
+	if resp, err := executeStream(ctx, head, func(*http.Response) error { return nil }); err == nil {
+		support.setValidators(resp.Header)
+		if resp.Header.Get("Content-Encoding") == "" && resp.ContentLength >= 0 {
+			support.Size = resp.ContentLength
+		}
+	}
+
+	probe := opts.Clone()
+	probe.Method = http.MethodGet
+	if probe.Headers == nil {
+		probe.Headers = http.Header{}
+	}
+	probe.Headers.Set("Range", "bytes=0-0")
+	resp, err := executeStream(ctx, probe, func(*http.Response) error { return nil })
+	switch {
+	case resp != nil && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The resource is empty, ranges of it do not exist
+		if size := contentRangeSize(resp.Header.Get("Content-Range")); size >= 0 {
+			support.Ranges, support.Size = true, size
+		}
+		return support, nil
+	case err != nil:
+		return nil, err
+	}
+
+	support.setValidators(resp.Header)
+	if resp.StatusCode == http.StatusPartialContent {
+		support.Ranges = true
+		if size := contentRangeSize(resp.Header.Get("Content-Range")); size >= 0 {
+			support.Size = size
+		}
+	} else if support.Size < 0 && resp.Header.Get("Content-Encoding") == "" && resp.ContentLength >= 0 {
+		support.Size = resp.ContentLength
+	}
+	return support, nil
+}
+
+// setValidators sets the validators of s from header, keeping those already
+// set.
+func (s *ResumeSupport) setValidators(header http.Header) {
+	if s.ETag == "" {
+		s.ETag = header.Get("ETag")
+	}
+	if s.LastModified == "" {
+		s.LastModified = header.Get("Last-Modified")
+	}
+	s.Validator = s.LastModified
+	if s.ETag != "" && !strings.HasPrefix(s.ETag, "W/") {
+		s.Validator = s.ETag
+	}
+}
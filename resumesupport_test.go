@@ -0,0 +1,90 @@
+package gocurl_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupportsResume(t *testing.T) {
+	content := []byte(strings.Repeat("x", 4096))
+	modified := time.Date(2026, 10, 5, 10, 0, 0, 0, time.UTC)
+	var heads int
+	var streamed atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/file":
+			w.Header().Set("ETag", `"v1"`)
+			http.ServeContent(w, r, "file", modified, bytes.NewReader(content))
+		case "/weak":
+			w.Header().Set("ETag", `W/"v1"`)
+			http.ServeContent(w, r, "file", modified, bytes.NewReader(content))
+		case "/empty":
+			// As answered by nginx
+			if r.Header.Get("Range") != "" {
+				w.Header().Set("Content-Range", "bytes */0")
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			}
+		case "/noranges":
+			if r.Method == http.MethodHead {
+				heads++
+				// Advertised but not honoured
+				w.Header().Set("Accept-Ranges", "bytes")
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Length", "268435456")
+			chunk := make([]byte, 64<<10)
+			for i := 0; i < 4096; i++ {
+				n, err := w.Write(chunk)
+				streamed.Add(int64(n))
+				if err != nil {
+					return
+				}
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	support, err := gocurl.SupportsResume(ctx, server.URL+"/file")
+	require.NoError(t, err)
+	assert.Equal(t, &gocurl.ResumeSupport{
+		Ranges:       true,
+		Size:         4096,
+		ETag:         `"v1"`,
+		LastModified: modified.Format(http.TimeFormat),
+		Validator:    `"v1"`,
+	}, support)
+
+	support, err = gocurl.SupportsResume(ctx, server.URL+"/weak")
+	require.NoError(t, err)
+	assert.Equal(t, modified.Format(http.TimeFormat), support.Validator)
+
+	support, err = gocurl.SupportsResume(ctx, server.URL+"/empty")
+	require.NoError(t, err)
+	assert.True(t, support.Ranges)
+	assert.Zero(t, support.Size)
+
+	support, err = gocurl.SupportsResume(ctx, server.URL+"/noranges")
+	require.NoError(t, err)
+	assert.Equal(t, 1, heads)
+	assert.False(t, support.Ranges)
+	assert.Equal(t, int64(268435456), support.Size)
+	assert.Less(t, streamed.Load(), int64(268435456), "the body of a server ignoring the range should not be downloaded")
+	assert.Empty(t, support.Validator)
+
+	_, err = gocurl.SupportsResume(ctx, server.URL+"/missing")
+	assert.Error(t, err)
+}
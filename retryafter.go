@@ -0,0 +1,142 @@
+package gocurl
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// ExecuteRequestWithRetryAfter executes the request with the generic retries
+// of ExecuteRequestWithRetries and, if opts.RetryAfter is set, retries rate
+// limited responses after the delay requested by the server in the same
+// loop.
+func ExecuteRequestWithRetryAfter(client *http.Client, req *http.Request, opts *options.RequestOptions) (*http.Response, error) {
+	return executeWithRetries(client, req, opts, opts.RetryAfter)
+}
+
+// retryAfterInfo describes the wait policy asks for before retrying resp,
+// its attempt-th rate limited response. The wait is the DefaultWait of
+// policy when the server does not ask for one.
+func retryAfterInfo(resp *http.Response, policy *options.RetryAfterPolicy, attempt int) options.RetryAfterWait {
+	now := time.Now()
+	info := options.RetryAfterWait{
+		Attempt:    attempt,
+		StatusCode: resp.StatusCode,
+	}
+	info.Wait, info.Source = RetryAfterDelay(resp.Header, now)
+	info.Remaining, info.Reset = rateLimitQuota(resp.Header, now)
+	if info.Source == "" {
+		info.Wait = policy.DefaultWait
+	}
+	return info
+}
+
+// RetryAfterDelay returns how long the server asks the client to wait, based
+// on the Retry-After header (delay in seconds or an HTTP date) and, failing
+// that, the RateLimit-Reset or X-RateLimit-Reset headers. source is the name
+// of the header the delay was taken from, or empty if there was none.
+func RetryAfterDelay(header http.Header, now time.Time) (delay time.Duration, source string) {
+	if v := strings.TrimSpace(header.Get("Retry-After")); v != "" {
+		if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return clampDelay(time.Duration(secs) * time.Second), "Retry-After"
+		}
+		if at, err := http.ParseTime(v); err == nil {
+			return clampDelay(at.Sub(now)), "Retry-After"
+		}
+	}
+
+	// IETF draft: RateLimit-Reset is a delta in seconds, and the combined
+	// RateLimit header carries it as the "reset" (or "t") parameter.
+	if v := strings.TrimSpace(header.Get("RateLimit-Reset")); v != "" {
+		if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return clampDelay(time.Duration(secs) * time.Second), "RateLimit-Reset"
+		}
+	}
+	if v := rateLimitParam(header.Get("RateLimit"), "reset", "t"); v != "" {
+		if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return clampDelay(time.Duration(secs) * time.Second), "RateLimit"
+		}
+	}
+
+	if v := strings.TrimSpace(header.Get("X-RateLimit-Reset")); v != "" {
+		if reset, ok := parseReset(v, now); ok {
+			return clampDelay(reset.Sub(now)), "X-RateLimit-Reset"
+		}
+	}
+
+	return 0, ""
+}
+
+// rateLimitQuota extracts the remaining quota and its reset time.
+func rateLimitQuota(header http.Header, now time.Time) (remaining int, reset time.Time) {
+	remaining = -1
+	for _, v := range []string{
+		header.Get("RateLimit-Remaining"),
+		rateLimitParam(header.Get("RateLimit"), "remaining", "r"),
+		header.Get("X-RateLimit-Remaining"),
+	} {
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			remaining = n
+			break
+		}
+	}
+
+	for _, v := range []string{
+		header.Get("RateLimit-Reset"),
+		rateLimitParam(header.Get("RateLimit"), "reset", "t"),
+		header.Get("X-RateLimit-Reset"),
+	} {
+		if r, ok := parseReset(strings.TrimSpace(v), now); ok {
+			reset = r
+			break
+		}
+	}
+	return remaining, reset
+}
+
+// parseReset interprets a reset value either as a Unix timestamp (as GitHub
+// sends) or as a delay in seconds.
+func parseReset(v string, now time.Time) (time.Time, bool) {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if n > 1_000_000_000 {
+		return time.Unix(n, 0), true
+	}
+	return now.Add(time.Duration(n) * time.Second), true
+}
+
+// rateLimitParam returns a parameter of the structured RateLimit header,
+// e.g. `limit=100, remaining=0, reset=30`.
+func rateLimitParam(header string, names ...string) string {
+	for _, part := range strings.FieldsFunc(header, func(r rune) bool { return r == ',' || r == ';' }) {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		for _, name := range names {
+			if strings.EqualFold(strings.TrimSpace(key), name) {
+				return strings.Trim(strings.TrimSpace(value), `"`)
+			}
+		}
+	}
+	return ""
+}
+
+func retryAfterStatus(status int, codes []int) bool {
+	if len(codes) == 0 {
+		return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+	}
+	return shouldRetry(status, codes)
+}
+
+func clampDelay(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,140 @@
+package gocurl_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryAfterPolicy(t *testing.T) {
+	t.Run("Retries 429 after Retry-After", func(t *testing.T) {
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte("ok"))
+		}))
+		defer server.Close()
+
+		var waits []options.RetryAfterWait
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(server.URL).
+			SetSilent(true).
+			SetRetryAfterPolicy(&options.RetryAfterPolicy{
+				MaxRetries: 2,
+				OnWait:     func(w options.RetryAfterWait) { waits = append(waits, w) },
+			}).
+			Build()
+
+		resp, body, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, "ok", body)
+		assert.Equal(t, 2, attempts)
+		require.Len(t, waits, 1)
+		assert.Equal(t, 1, waits[0].Attempt)
+		assert.Equal(t, http.StatusTooManyRequests, waits[0].StatusCode)
+		assert.Equal(t, "Retry-After", waits[0].Source)
+		assert.Equal(t, 0, waits[0].Remaining)
+	})
+
+	t.Run("Gives up when the wait exceeds MaxWait", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		opts := &options.RequestOptions{
+			URL:        server.URL,
+			Silent:     true,
+			RetryAfter: &options.RetryAfterPolicy{MaxRetries: 3, MaxWait: time.Second},
+		}
+
+		resp, _, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	})
+
+	t.Run("Retries add up with the generic retries", func(t *testing.T) {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		opts := &options.RequestOptions{
+			URL:         server.URL,
+			Silent:      true,
+			RetryConfig: &options.RetryConfig{MaxRetries: 2, RetryOnHTTP: []int{http.StatusTooManyRequests}},
+			RetryAfter:  &options.RetryAfterPolicy{MaxRetries: 2},
+		}
+
+		resp, _, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, int32(5), atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("Context cancels the wait", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "5")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		opts := &options.RequestOptions{
+			URL:        server.URL,
+			Silent:     true,
+			RetryAfter: &options.RetryAfterPolicy{MaxRetries: 1},
+		}
+		_, _, err := gocurl.Process(ctx, opts)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestRetryAfterDelay(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		delay  time.Duration
+		source string
+	}{
+		{"Seconds", http.Header{"Retry-After": {"7"}}, 7 * time.Second, "Retry-After"},
+		{"HTTP date", http.Header{"Retry-After": {now.Add(90 * time.Second).Format(http.TimeFormat)}}, 90 * time.Second, "Retry-After"},
+		{"Past date", http.Header{"Retry-After": {now.Add(-time.Minute).Format(http.TimeFormat)}}, 0, "Retry-After"},
+		{"RateLimit-Reset", http.Header{"Ratelimit-Reset": {"30"}}, 30 * time.Second, "RateLimit-Reset"},
+		{"Structured RateLimit", http.Header{"Ratelimit": {"limit=100, remaining=0, reset=12"}}, 12 * time.Second, "RateLimit"},
+		{"GitHub epoch reset", http.Header{"X-Ratelimit-Reset": {"1704110460"}}, time.Minute, "X-RateLimit-Reset"},
+		{"No hint", http.Header{}, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, source := gocurl.RetryAfterDelay(tt.header, now)
+			assert.Equal(t, tt.delay, delay)
+			assert.Equal(t, tt.source, source)
+		})
+	}
+}
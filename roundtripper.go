@@ -0,0 +1,50 @@
+package gocurl
+
+import (
+	"net/http"
+)
+
+// RoundTripper is an http.RoundTripper sending requests through a Client,
+// so SDKs that accept an *http.Client, such as the AWS and Google API
+// clients, gain the client's retries, circuit breakers, rate limits,
+// metrics, tracing and policies without changes.
+//
+// Requests are sent as they are: redirects are left to the http.Client
+// using the RoundTripper and responses of every status are returned without
+// error. Response bodies are read before RoundTrip returns, so large
+// downloads should set a SpoolThreshold with SetDefaults.
+type RoundTripper struct {
+	client *Client
+}
+
+// NewRoundTripper returns a RoundTripper sending requests through client. A
+// nil client sends them through a new Client.
+func NewRoundTripper(client *Client) *RoundTripper {
+	if client == nil {
+		client = NewClient()
+	}
+	return &RoundTripper{client: client}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrip must close the body, even on errors
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	opts, err := FromHTTPRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	opts.Silent = true
+
+	resp, _, err := t.client.Process(req.Context(), opts)
+	if err != nil {
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+		return nil, err
+	}
+	resp.Request = req
+	return resp, nil
+}
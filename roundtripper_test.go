@@ -0,0 +1,80 @@
+package gocurl_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTripper(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky":
+			if atomic.AddInt32(&hits, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "/moved":
+			http.Redirect(w, r, "/target", http.StatusFound)
+			return
+		case "/missing":
+			http.NotFound(w, r)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.URL.Path, body)
+	}))
+	defer server.Close()
+
+	client := gocurl.NewClient().AddRoute(gocurl.Route{
+		Path:    "/flaky",
+		Options: &options.RequestOptions{RetryConfig: &options.RetryConfig{MaxRetries: 2, RetryOnHTTP: []int{503}}},
+	})
+	httpClient := &http.Client{Transport: gocurl.NewRoundTripper(client)}
+
+	get := func(path string) (*http.Response, string) {
+		t.Helper()
+		resp, err := httpClient.Get(server.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	resp, body := get("/flaky")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "GET /flaky ", body)
+	assert.EqualValues(t, 2, hits)
+
+	// Redirects are followed by the http.Client
+	resp, body = get("/moved")
+	assert.Equal(t, "GET /target ", body)
+	assert.Equal(t, "/target", resp.Request.URL.Path)
+
+	resp, _ = get("/missing")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err := httpClient.Post(server.URL+"/items", "text/plain", strings.NewReader("a"))
+	require.NoError(t, err)
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "POST /items a", string(data))
+	assert.Contains(t, client.LastCommand(), "/items")
+
+	t.Run("Errors", func(t *testing.T) {
+		offline := &http.Client{Transport: gocurl.NewRoundTripper(gocurl.NewClient().SetOfflineMode(true))}
+		_, err := offline.Get(server.URL)
+		assert.True(t, errors.Is(err, gocurl.ErrOffline))
+	})
+}
@@ -0,0 +1,76 @@
+package gocurl
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// Route applies a set of options to the requests of a Client matching it,
+// so one client can talk to several APIs with distinct requirements, such
+// as their own credentials, retry policy or proxy.
+type Route struct {
+	// Hosts are the hosts the route matches, like those of a Policy:
+	// "api.example.com", "*.example.com" or "10.0.0.0/8". Empty matches
+	// every host.
+	Hosts []string
+	// Path is the pattern of the paths the route matches, as matched by
+	// path.Match, except that a trailing "*" matches everything below:
+	// "/v2/*" matches "/v2/users/1". Empty matches every path.
+	Path string
+	// Options are applied to the matching requests like defaults: the
+	// fields a request leaves unset take their values, and headers are
+	// merged. They take precedence over the defaults set with SetDefaults
+	// and WithDefaults.
+	Options *options.RequestOptions
+}
+
+// AddRoute appends route to the client's routing table. A request uses the
+// first route it matches, in the order they were added.
+func (c *Client) AddRoute(route Route) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.routes = append(c.routes, route)
+	return c
+}
+
+// route returns opts with the options of the first route it matches
+// applied.
+func (c *Client) route(opts *options.RequestOptions) *options.RequestOptions {
+	c.mu.Lock()
+	routes := c.routes
+	c.mu.Unlock()
+	if len(routes) == 0 {
+		return opts
+	}
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return opts
+	}
+	for _, route := range routes {
+		if route.matches(u) {
+			return applyDefaults(opts, route.Options)
+		}
+	}
+	return opts
+}
+
+func (r *Route) matches(u *url.URL) bool {
+	if len(r.Hosts) > 0 && !matchHosts(r.Hosts, u.Hostname()) {
+		return false
+	}
+	if r.Path == "" {
+		return true
+	}
+	p := u.Path
+	if p == "" {
+		p = "/"
+	}
+	if prefix, ok := strings.CutSuffix(r.Path, "*"); ok && !strings.ContainsAny(prefix, "*?[\\") {
+		return strings.HasPrefix(p, prefix)
+	}
+	matched, _ := path.Match(r.Path, p)
+	return matched
+}
@@ -0,0 +1,67 @@
+package gocurl_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRoutes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization") + "|" + r.Header.Get("X-API")))
+	}))
+	defer server.Close()
+
+	client := gocurl.NewClient().
+		AddRoute(gocurl.Route{
+			Hosts:   []string{"127.0.0.1"},
+			Path:    "/v2/*",
+			Options: options.NewRequestOptionsBuilder().SetBearerToken("v2").AddHeader("X-API", "two").Build(),
+		}).
+		AddRoute(gocurl.Route{
+			Path:    "/v1/*",
+			Options: options.NewRequestOptionsBuilder().SetBasicAuth("user", "pass").Build(),
+		}).
+		AddRoute(gocurl.Route{
+			Hosts:   []string{"*.example.com"},
+			Options: options.NewRequestOptionsBuilder().AddHeader("X-API", "example").Build(),
+		})
+	ctx := context.Background()
+
+	tests := []struct {
+		command, want string
+	}{
+		{server.URL + "/v2/users/1", "Bearer v2|two"},
+		{server.URL + "/v1/users", "Basic dXNlcjpwYXNz|"},
+		{server.URL + "/other", "|"},
+		// The request's own options take precedence
+		{"-H 'X-API: mine' " + server.URL + "/v2/users", "Bearer v2|mine"},
+	}
+	for _, tt := range tests {
+		_, body, err := client.Curl(ctx, "curl -s "+tt.command)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, body, tt.command)
+	}
+	assert.Contains(t, client.LastCommand(), "Bearer v2")
+}
+
+func TestClientRouteProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("proxied " + r.URL.String()))
+	}))
+	defer proxy.Close()
+
+	client := gocurl.NewClient().AddRoute(gocurl.Route{
+		Hosts:   []string{"internal.example"},
+		Options: options.NewRequestOptionsBuilder().SetProxy(proxy.URL).Build(),
+	})
+	_, body, err := client.Curl(context.Background(), "-s", "http://internal.example/status")
+	require.NoError(t, err)
+	assert.Equal(t, "proxied http://internal.example/status", body)
+}
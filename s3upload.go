@@ -0,0 +1,325 @@
+package gocurl
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+const (
+	// s3MinPartSize is the smallest size of the parts of a multipart
+	// upload, but the last
+	s3MinPartSize = 5 << 20
+	// s3MaxParts is the largest number of parts of a multipart upload
+	s3MaxParts = 10000
+)
+
+// S3UploadOptions configures CurlUploadS3.
+type S3UploadOptions struct {
+	// Signer signs the requests, typically an *options.SigV4 for the s3
+	// service
+	Signer options.Signer
+
+	// PartSize is the size of the parts, 8 MiB if zero. It is raised to the
+	// 5 MiB minimum of S3, and as needed to stay within 10000 parts.
+	PartSize int64
+	// Concurrency is the number of parts uploaded at once, 4 if zero
+	Concurrency int
+	// MaxRetries is the number of times a failed part is uploaded again, 3
+	// if zero
+	MaxRetries int
+	// RetryDelay is the delay before the first retry of a part, doubled for
+	// each one after it, 1 second if zero
+	RetryDelay time.Duration
+
+	// Client sends the requests, Process when nil
+	Client *Client
+}
+
+// S3UploadError reports an error response of an S3 API.
+type S3UploadError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *S3UploadError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("s3: %s: %s", e.Code, e.Message)
+}
+
+// CurlUploadS3 uploads file to the S3 or S3-compatible object at objectURL
+// with a multipart upload, so that uploads of several gigabytes are sent in
+// parts uploaded in parallel and retried on their own:
+//
+//	signer := &options.SigV4{AccessKeyID: id, SecretAccessKey: secret, Region: "us-east-1", Service: "s3"}
+//	resp, err := gocurl.CurlUploadS3(ctx, "backup.tar", "https://bucket.s3.amazonaws.com/backups/backup.tar",
+//		&gocurl.S3UploadOptions{Signer: signer})
+//
+// objectURL may also be a curl command, whose headers, such as Content-Type
+// or x-amz-meta-*, are sent when the upload is initiated. Server-side
+// encryption headers with customer keys are sent with every part, as S3
+// requires. When the upload fails it is aborted, so that its parts are not
+// kept, and billed, by the object store. It returns the response completing
+// the upload.
+func CurlUploadS3(ctx context.Context, file, objectURL string, opts *S3UploadOptions) (*http.Response, error) {
+	if opts == nil {
+		opts = &S3UploadOptions{}
+	}
+	base, err := parseCommand(objectURL)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Signer != nil {
+		base.Signer = opts.Signer
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file for upload: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file for upload: %v", err)
+	}
+
+	u := &s3Upload{opts: opts, base: base, file: f, size: info.Size()}
+	if u.uploadID, err = u.initiate(ctx); err != nil {
+		return nil, err
+	}
+	parts, err := u.uploadParts(ctx)
+	if err == nil {
+		var resp *http.Response
+		if resp, err = u.complete(ctx, parts); err == nil {
+			return resp, nil
+		}
+	}
+	// The upload is aborted even when ctx is done
+	abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	u.abort(abortCtx)
+	return nil, err
+}
+
+// s3Upload is a multipart upload in progress.
+type s3Upload struct {
+	opts     *S3UploadOptions
+	base     *options.RequestOptions
+	file     *os.File
+	size     int64
+	uploadID string
+}
+
+// s3Part is a part of the CompleteMultipartUpload request.
+type s3Part struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// do sends a request of the upload to the object URL with query. The
+// command's body is only sent when initiating the upload, and its headers
+// only those of server-side encryption with customer keys otherwise.
+func (u *s3Upload) do(ctx context.Context, method string, query url.Values, body string) (*http.Response, string, error) {
+	opts := u.base.Clone()
+	opts.Method = method
+	opts.Silent = true
+	opts.OutputFile = ""
+	opts.QueryParams = query
+	opts.Body, opts.BodyReader = body, nil
+	opts.Form, opts.FileUpload, opts.UploadFile = nil, nil, ""
+	if _, initiate := query["uploads"]; !initiate {
+		header := http.Header{}
+		for key, values := range opts.Headers {
+			if strings.HasPrefix(strings.ToLower(key), "x-amz-server-side-encryption-customer-") {
+				header[key] = values
+			}
+		}
+		opts.Headers = header
+	}
+
+	var resp *http.Response
+	var respBody string
+	var err error
+	if u.opts.Client != nil {
+		resp, respBody, err = u.opts.Client.Process(ctx, opts)
+	} else {
+		resp, respBody, err = Process(ctx, opts)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if respBody, err = loadSpooled(resp, respBody); err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, respBody, s3Error(resp.StatusCode, respBody)
+	}
+	return resp, respBody, nil
+}
+
+// initiate creates the multipart upload, returning its ID.
+func (u *s3Upload) initiate(ctx context.Context) (string, error) {
+	_, body, err := u.do(ctx, http.MethodPost, url.Values{"uploads": {""}}, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to initiate multipart upload: %w", err)
+	}
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal([]byte(body), &result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("failed to initiate multipart upload: no UploadId in the response")
+	}
+	return result.UploadID, nil
+}
+
+// uploadParts uploads the parts of the file, returning them in order.
+func (u *s3Upload) uploadParts(ctx context.Context) ([]s3Part, error) {
+	partSize := u.opts.PartSize
+	if partSize <= 0 {
+		partSize = 8 << 20
+	}
+	partSize = max(partSize, s3MinPartSize, (u.size+s3MaxParts-1)/s3MaxParts)
+	count := int((u.size + partSize - 1) / partSize)
+	if count == 0 {
+		// Empty files are uploaded as a single empty part
+		count = 1
+	}
+	concurrency := u.opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	numbers := make(chan int, count)
+	for n := 1; n <= count; n++ {
+		numbers <- n
+	}
+	close(numbers)
+
+	var (
+		mu       sync.Mutex
+		parts    []s3Part
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	for w := 0; w < min(concurrency, count); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, min(partSize, u.size))
+			for n := range numbers {
+				etag, err := u.uploadPart(ctx, n, partSize, buf)
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					cancel()
+				} else {
+					parts = append(parts, s3Part{PartNumber: n, ETag: etag})
+				}
+				mu.Unlock()
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	return parts, nil
+}
+
+// uploadPart uploads part n, retrying it on failure, and returns its ETag.
+func (u *s3Upload) uploadPart(ctx context.Context, n int, partSize int64, buf []byte) (string, error) {
+	offset := int64(n-1) * partSize
+	size, err := u.file.ReadAt(buf[:min(partSize, u.size-offset)], offset)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read file for upload: %v", err)
+	}
+	query := url.Values{"partNumber": {strconv.Itoa(n)}, "uploadId": {u.uploadID}}
+
+	maxRetries := u.opts.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 3
+	}
+	delay := u.opts.RetryDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		resp, _, err := u.do(ctx, http.MethodPut, query, string(buf[:size]))
+		if err == nil {
+			if etag := resp.Header.Get("ETag"); etag != "" {
+				return etag, nil
+			}
+			err = fmt.Errorf("no ETag in the response")
+		}
+		var s3Err *S3UploadError
+		if errors.As(err, &s3Err) && s3Err.StatusCode < 500 && s3Err.StatusCode != http.StatusRequestTimeout && s3Err.StatusCode != http.StatusTooManyRequests ||
+			attempt >= maxRetries || ctx.Err() != nil {
+			return "", fmt.Errorf("failed to upload part %d: %w", n, err)
+		}
+		select {
+		case <-time.After(delay << attempt):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// complete completes the upload of parts.
+func (u *s3Upload) complete(ctx context.Context, parts []s3Part) (*http.Response, error) {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []s3Part `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete multipart upload: %v", err)
+	}
+	resp, respBody, err := u.do(ctx, http.MethodPost, url.Values{"uploadId": {u.uploadID}}, string(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	// S3 can report a failure to complete in a 200 OK response
+	if strings.Contains(respBody, "<Error>") {
+		return nil, fmt.Errorf("failed to complete multipart upload: %w", s3Error(resp.StatusCode, respBody))
+	}
+	return resp, nil
+}
+
+// abort aborts the upload, discarding its parts.
+func (u *s3Upload) abort(ctx context.Context) {
+	u.do(ctx, http.MethodDelete, url.Values{"uploadId": {u.uploadID}}, "")
+}
+
+// s3Error returns the S3UploadError of a response with body.
+func s3Error(status int, body string) *S3UploadError {
+	var result struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	xml.Unmarshal([]byte(body), &result)
+	return &S3UploadError{StatusCode: status, Code: result.Code, Message: result.Message}
+}
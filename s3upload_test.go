@@ -0,0 +1,152 @@
+package gocurl_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// s3Server is a minimal S3 multipart upload API, failing the first upload
+// of the parts listed in failParts.
+type s3Server struct {
+	mu        sync.Mutex
+	parts     map[int][]byte
+	attempts  map[int]int
+	failParts map[int]int // part number to status
+	initiated http.Header
+	completed []int
+	aborted   bool
+	object    []byte
+}
+
+func (s *s3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		s.initiated = r.Header.Clone()
+		s.parts, s.attempts = map[int][]byte{}, map[int]int{}
+		io.WriteString(w, `<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>backup.tar</Key><UploadId>up-1</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == http.MethodPut && query.Get("uploadId") == "up-1":
+		n, _ := strconv.Atoi(query.Get("partNumber"))
+		s.attempts[n]++
+		body, _ := io.ReadAll(r.Body)
+		if status, ok := s.failParts[n]; ok && s.attempts[n] == 1 {
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `<Error><Code>Failed</Code><Message>part %d failed</Message></Error>`, n)
+			return
+		}
+		if r.Header.Get("X-Amz-Content-Sha256") == "" || r.Header.Get("Content-Type") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.parts[n] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, n))
+	case r.Method == http.MethodPost && query.Get("uploadId") == "up-1":
+		var complete struct {
+			Parts []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		xml.Unmarshal(body, &complete)
+		s.object = nil
+		for _, part := range complete.Parts {
+			if part.ETag != fmt.Sprintf(`"etag-%d"`, part.PartNumber) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			s.completed = append(s.completed, part.PartNumber)
+			s.object = append(s.object, s.parts[part.PartNumber]...)
+		}
+		io.WriteString(w, `<CompleteMultipartUploadResult><Key>backup.tar</Key><ETag>"final"</ETag></CompleteMultipartUploadResult>`)
+	case r.Method == http.MethodDelete && query.Get("uploadId") == "up-1":
+		s.aborted = true
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestCurlUploadS3(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 11<<16) // 11 MiB
+	file := filepath.Join(t.TempDir(), "backup.tar")
+	require.NoError(t, os.WriteFile(file, content, 0o644))
+	signer := &options.SigV4{AccessKeyID: "AKID", SecretAccessKey: "secret", Region: "us-east-1", Service: "s3"}
+
+	s3 := &s3Server{failParts: map[int]int{2: http.StatusInternalServerError}}
+	server := httptest.NewServer(s3)
+	defer server.Close()
+
+	command := fmt.Sprintf(`curl -H "Content-Type: application/x-tar" -H "x-amz-meta-owner: ops" %s/bucket/backup.tar`, server.URL)
+	resp, err := gocurl.CurlUploadS3(context.Background(), file, command, &gocurl.S3UploadOptions{
+		Signer:     signer,
+		PartSize:   1 << 20, // raised to 5 MiB
+		RetryDelay: time.Millisecond,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []int{1, 2, 3}, s3.completed)
+	assert.Equal(t, content, s3.object)
+	assert.Equal(t, 2, s3.attempts[2])
+	assert.Len(t, s3.parts[1], 5<<20)
+	assert.Equal(t, "application/x-tar", s3.initiated.Get("Content-Type"))
+	assert.Equal(t, "ops", s3.initiated.Get("X-Amz-Meta-Owner"))
+	assert.False(t, s3.aborted)
+
+	t.Run("Abort on failure", func(t *testing.T) {
+		s3 := &s3Server{failParts: map[int]int{2: http.StatusBadRequest}}
+		server := httptest.NewServer(s3)
+		defer server.Close()
+
+		_, err := gocurl.CurlUploadS3(context.Background(), file, server.URL+"/bucket/backup.tar", &gocurl.S3UploadOptions{
+			Signer:     signer,
+			RetryDelay: time.Millisecond,
+		})
+		var s3Err *gocurl.S3UploadError
+		require.ErrorAs(t, err, &s3Err)
+		assert.Equal(t, "Failed", s3Err.Code)
+		assert.Contains(t, err.Error(), "part 2")
+		assert.True(t, s3.aborted)
+		assert.Equal(t, 1, s3.attempts[2])
+	})
+
+	t.Run("Error in a 200 response", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Query().Has("uploads"):
+				io.WriteString(w, `<InitiateMultipartUploadResult><UploadId>up-1</UploadId></InitiateMultipartUploadResult>`)
+			case r.Method == http.MethodPut:
+				w.Header().Set("ETag", `"etag"`)
+			case r.Method == http.MethodPost:
+				io.WriteString(w, `<Error><Code>InternalError</Code><Message>We encountered an internal error.</Message></Error>`)
+			}
+		}))
+		defer server.Close()
+
+		_, err := gocurl.CurlUploadS3(context.Background(), file, server.URL+"/bucket/backup.tar", &gocurl.S3UploadOptions{Signer: signer})
+		assert.ErrorContains(t, err, "InternalError")
+	})
+}
@@ -0,0 +1,48 @@
+package gocurl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/maniartech/gocurl/jsonschema"
+)
+
+// ValidateJSON checks the body of resp against the JSON Schema schema. It
+// returns a *jsonschema.ValidationError listing every violation when the body
+// does not conform. The body remains readable afterwards.
+func ValidateJSON(resp *http.Response, schema string) error {
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %v", err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return validateJSON(body, schema)
+}
+
+func validateJSON(body []byte, schema string) error {
+	compiled, err := jsonschema.Compile([]byte(schema))
+	if err != nil {
+		return err
+	}
+	return compiled.Validate(body)
+}
+
+// validateJSONReader is validateJSON decoding the body from r, so a spooled
+// body is never held in memory as raw bytes.
+func validateJSONReader(r io.Reader, schema string) error {
+	compiled, err := jsonschema.Compile([]byte(schema))
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return &jsonschema.ValidationError{Violations: []jsonschema.Violation{{Path: "$", Message: fmt.Sprintf("invalid JSON: %v", err)}}}
+	}
+	return compiled.ValidateValue(doc)
+}
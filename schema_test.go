@@ -0,0 +1,56 @@
+package gocurl_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/jsonschema"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseSchema(t *testing.T) {
+	schema := `{"type":"object","required":["id"],"properties":{"id":{"type":"integer"}}}`
+	body := `{"id":1}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	t.Run("ValidateJSON", func(t *testing.T) {
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		require.NoError(t, gocurl.ValidateJSON(resp, schema))
+
+		// The body is still readable after validation
+		content, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(content))
+	})
+
+	t.Run("SetResponseSchema", func(t *testing.T) {
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(server.URL).
+			SetSilent(true).
+			SetResponseSchema(schema).
+			Build()
+
+		_, _, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+
+		body = `{"id":"1"}`
+		resp, got, err := gocurl.Process(context.Background(), opts)
+		var verr *jsonschema.ValidationError
+		require.True(t, errors.As(err, &verr))
+		assert.Equal(t, []jsonschema.Violation{{Path: "$.id", Message: "expected integer, got string"}}, verr.Violations)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, body, got)
+	})
+}
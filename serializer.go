@@ -0,0 +1,307 @@
+package gocurl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// JSONSerializer is the options.Serializer of JSON bodies using
+// encoding/json.
+var JSONSerializer options.Serializer = NewJSONSerializer(nil, nil)
+
+// NewJSONSerializer returns an options.Serializer of JSON bodies encoding
+// and decoding with marshal and unmarshal, or encoding/json when nil.
+func NewJSONSerializer(marshal JSONMarshalFunc, unmarshal JSONUnmarshalFunc) options.Serializer {
+	if marshal == nil {
+		marshal = json.Marshal
+	}
+	if unmarshal == nil {
+		unmarshal = json.Unmarshal
+	}
+	return &jsonSerializer{marshal: marshal, unmarshal: unmarshal}
+}
+
+type jsonSerializer struct {
+	marshal   JSONMarshalFunc
+	unmarshal JSONUnmarshalFunc
+}
+
+func (s *jsonSerializer) Marshal(v interface{}) ([]byte, error)      { return s.marshal(v) }
+func (s *jsonSerializer) Unmarshal(data []byte, v interface{}) error { return s.unmarshal(data, v) }
+func (s *jsonSerializer) ContentType() string                        { return "application/json" }
+
+// SetSerializer makes Send encode request bodies with s, and registers it
+// like RegisterSerializer. The default is JSONSerializer.
+func (c *Client) SetSerializer(s options.Serializer) *Client {
+	c.RegisterSerializer(s)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.serializer = s
+	return c
+}
+
+// RegisterSerializer makes the client decode the responses of s's media
+// type with s, in Send and the client's CurlDecode. Registering a
+// serializer of application/json also makes CurlJSON and SendJSON use it.
+func (c *Client) RegisterSerializer(s options.Serializer) *Client {
+	mediaType := strings.ToLower(s.ContentType())
+	if parsed, _, err := mime.ParseMediaType(mediaType); err == nil {
+		mediaType = parsed
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.serializers == nil {
+		c.serializers = map[string]options.Serializer{}
+	}
+	c.serializers[mediaType] = s
+	return c
+}
+
+// Send executes the curl command through the client with in encoded by the
+// client's serializer as its body, and decodes the response body into out
+// unless it is nil, with the serializer registered for its Content-Type,
+// or else the client's one. The command's method defaults to POST.
+func (c *Client) Send(ctx context.Context, in, out interface{}, command ...string) (*http.Response, error) {
+	c.mu.Lock()
+	s := c.serializer
+	c.mu.Unlock()
+	if s == nil {
+		s = c.serializerFor("application/json")
+	}
+
+	resp, body, err := c.send(ctx, s, in, command)
+	if err != nil || out == nil {
+		return resp, err
+	}
+	if err := preDecode(withDefaults(ctx, &options.RequestOptions{}), resp, func() io.Reader { return strings.NewReader(body) }); err != nil {
+		return resp, err
+	}
+	if registered := c.serializerFor(resp.Header.Get("Content-Type")); registered != nil {
+		s = registered
+	}
+	if err := s.Unmarshal([]byte(body), out); err != nil {
+		return resp, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return resp, nil
+}
+
+// CurlDecode executes the curl command through the client and decodes the
+// response body into v with the serializer registered for its
+// Content-Type, or else the decoder the package level CurlDecode uses.
+func (c *Client) CurlDecode(ctx context.Context, v interface{}, command ...string) (*http.Response, error) {
+	opts, err := c.decodeRequest(command)
+	if err != nil {
+		return nil, err
+	}
+	resp, body, err := c.Process(ctx, opts)
+	if err != nil {
+		return resp, err
+	}
+	return resp, c.decode(ctx, opts, resp, body, v)
+}
+
+// decodeRequest parses the command for CurlDecode, asking for the media
+// types the client can decode unless the command sets Accept.
+func (c *Client) decodeRequest(command []string) (*options.RequestOptions, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, err
+	}
+	opts.Silent = true
+	if opts.Headers == nil {
+		opts.Headers = http.Header{}
+	}
+	if opts.Headers.Get("Accept") == "" {
+		opts.Headers.Set("Accept", c.accept())
+	}
+	return opts, nil
+}
+
+// decode decodes the body of the response to opts into v as described by
+// CurlDecode.
+func (c *Client) decode(ctx context.Context, opts *options.RequestOptions, resp *http.Response, body string, v interface{}) error {
+	body, err := loadSpooled(resp, body)
+	if err != nil {
+		return err
+	}
+	if err := preDecode(withDefaults(ctx, opts), resp, func() io.Reader { return strings.NewReader(body) }); err != nil {
+		return err
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if s := c.serializerFor(contentType); s != nil {
+		err = s.Unmarshal([]byte(body), v)
+	} else {
+		var decoder Decoder
+		if decoder, err = decoderFor(contentType); err != nil {
+			return err
+		}
+		err = decoder(strings.NewReader(body), v)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
+
+// send executes the command through the client with in encoded by s as
+// its body.
+func (c *Client) send(ctx context.Context, s options.Serializer, in interface{}, command []string) (*http.Response, string, error) {
+	opts, err := serializedRequest(s, in, command)
+	if err != nil {
+		return nil, "", err
+	}
+	return c.Process(ctx, opts)
+}
+
+// serializedRequest parses the command and sets its body to in encoded by
+// s, asking for a response of the same media type.
+func serializedRequest(s options.Serializer, in interface{}, command []string) (*options.RequestOptions, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, err
+	}
+	opts.Silent = true
+	data, err := s.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s body: %v", s.ContentType(), err)
+	}
+	opts.Body = string(data)
+	if opts.Method == "" || opts.Method == "GET" {
+		opts.Method = "POST"
+	}
+	if opts.Headers == nil {
+		opts.Headers = http.Header{}
+	}
+	if opts.Headers.Get("Content-Type") == "" {
+		opts.Headers.Set("Content-Type", s.ContentType())
+	}
+	if opts.Headers.Get("Accept") == "" {
+		opts.Headers.Set("Accept", s.ContentType())
+	}
+	return opts, nil
+}
+
+// matchesMediaType reports whether contentType is of the media type of
+// serialized, directly or through its structured syntax suffix: a
+// serializer of application/cbor matches application/senml+cbor.
+func matchesMediaType(contentType, serialized string) bool {
+	got, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	want, _, err := mime.ParseMediaType(serialized)
+	if err != nil {
+		return false
+	}
+	if got == want {
+		return true
+	}
+	_, subtype, _ := strings.Cut(want, "/")
+	return strings.HasSuffix(got, "+"+subtype)
+}
+
+// serializerFor returns the serializer registered for the media type of
+// contentType, or for its structured syntax suffix such as "+json". JSON
+// falls back to JSONSerializer; other types without one return nil.
+func (c *Client) serializerFor(contentType string) options.Serializer {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+	c.mu.Lock()
+	s, ok := c.serializers[mediaType]
+	if !ok {
+		if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+			s, ok = c.serializers["application/"+mediaType[i+1:]]
+		}
+	}
+	c.mu.Unlock()
+	if !ok && isJSON(mediaType) {
+		return JSONSerializer
+	}
+	return s
+}
+
+// accept returns the Accept header of the client's CurlDecode, preferring
+// the media types of its serializers to those the package decodes.
+func (c *Client) accept() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.serializers) == 0 {
+		return defaultAccept
+	}
+	var types []string
+	for mediaType := range c.serializers {
+		types = append(types, mediaType)
+	}
+	sort.Strings(types)
+	for _, mediaType := range []string{"application/json", "application/xml", "application/yaml"} {
+		if _, ok := c.serializers[mediaType]; !ok {
+			types = append(types, mediaType)
+		}
+	}
+	return options.AcceptHeader(types...)
+}
+
+// CurlWith executes the curl command and decodes the response body into v
+// with s, asking for s's media type in the Accept header unless the
+// command sets one. A response of another Content-Type, such as an HTML
+// error page, returns a *ContentTypeError instead of failing to decode.
+func CurlWith(ctx context.Context, s options.Serializer, v interface{}, command ...string) (*http.Response, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Headers == nil {
+		opts.Headers = http.Header{}
+	}
+	if opts.Headers.Get("Accept") == "" {
+		opts.Headers.Set("Accept", s.ContentType())
+	}
+	return processWith(ctx, s, opts, v)
+}
+
+// SendWith executes the curl command with in encoded by s as its body and
+// decodes the response body into out unless it is nil, like CurlWith. The
+// command's method defaults to POST.
+func SendWith(ctx context.Context, s options.Serializer, in, out interface{}, command ...string) (*http.Response, error) {
+	opts, err := serializedRequest(s, in, command)
+	if err != nil {
+		return nil, err
+	}
+	if out == nil {
+		resp, _, err := Process(ctx, opts)
+		return resp, err
+	}
+	return processWith(ctx, s, opts, out)
+}
+
+// processWith executes opts and decodes the response body into v with s.
+func processWith(ctx context.Context, s options.Serializer, opts *options.RequestOptions, v interface{}) (*http.Response, error) {
+	opts.Silent = true
+	resp, body, err := Process(ctx, opts)
+	if err != nil {
+		return resp, err
+	}
+	if body, err = loadSpooled(resp, body); err != nil {
+		return resp, err
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !matchesMediaType(contentType, s.ContentType()) {
+		return resp, newContentTypeError(contentType, strings.NewReader(body))
+	}
+	if err := preDecode(withDefaults(ctx, opts), resp, func() io.Reader { return strings.NewReader(body) }); err != nil {
+		return resp, err
+	}
+	if err := s.Unmarshal([]byte(body), v); err != nil {
+		return resp, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,39 @@
+// Package cbor adds CBOR (RFC 8949) bodies to gocurl, for IoT and internal
+// RPC endpoints avoiding JSON for its size. It is a module of its own to
+// keep the CBOR implementation out of gocurl's dependencies.
+package cbor
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/maniartech/gocurl"
+)
+
+// ContentType is the media type of CBOR bodies.
+const ContentType = "application/cbor"
+
+// Serializer is the options.Serializer of CBOR bodies, for
+// RequestOptionsBuilder.Encode and Client.SetSerializer.
+var Serializer serializer
+
+type serializer struct{}
+
+func (serializer) Marshal(v interface{}) ([]byte, error)      { return cbor.Marshal(v) }
+func (serializer) Unmarshal(data []byte, v interface{}) error { return cbor.Unmarshal(data, v) }
+func (serializer) ContentType() string                        { return ContentType }
+
+// CurlCBOR executes the curl command, asking for CBOR unless it sets an
+// Accept header, and decodes the CBOR response body into v. A response of
+// another Content-Type returns a *gocurl.ContentTypeError.
+func CurlCBOR(ctx context.Context, v interface{}, command ...string) (*http.Response, error) {
+	return gocurl.CurlWith(ctx, Serializer, v, command...)
+}
+
+// SendCBOR executes the curl command with in encoded as its CBOR body, and
+// decodes the CBOR response body into out unless it is nil. The command's
+// method defaults to POST.
+func SendCBOR(ctx context.Context, in, out interface{}, command ...string) (*http.Response, error) {
+	return gocurl.SendWith(ctx, Serializer, in, out, command...)
+}
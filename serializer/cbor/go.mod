@@ -0,0 +1,17 @@
+module github.com/maniartech/gocurl/serializer/cbor
+
+go 1.22.3
+
+replace github.com/maniartech/gocurl => ../..
+
+require (
+	github.com/fxamacker/cbor/v2 v2.7.0
+	github.com/maniartech/gocurl v0.0.0
+)
+
+require (
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/net v0.30.0 // indirect
+	golang.org/x/text v0.19.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
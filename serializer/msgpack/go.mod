@@ -0,0 +1,17 @@
+module github.com/maniartech/gocurl/serializer/msgpack
+
+go 1.22.3
+
+replace github.com/maniartech/gocurl => ../..
+
+require (
+	github.com/maniartech/gocurl v0.0.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+)
+
+require (
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	golang.org/x/net v0.30.0 // indirect
+	golang.org/x/text v0.19.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
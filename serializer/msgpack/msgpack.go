@@ -0,0 +1,39 @@
+// Package msgpack adds MessagePack bodies to gocurl, for IoT and internal
+// RPC endpoints avoiding JSON for its size. It is a module of its own to
+// keep the MessagePack implementation out of gocurl's dependencies.
+package msgpack
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/maniartech/gocurl"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ContentType is the media type of MessagePack bodies.
+const ContentType = "application/msgpack"
+
+// Serializer is the options.Serializer of MessagePack bodies, for
+// RequestOptionsBuilder.Encode and Client.SetSerializer.
+var Serializer serializer
+
+type serializer struct{}
+
+func (serializer) Marshal(v interface{}) ([]byte, error)      { return msgpack.Marshal(v) }
+func (serializer) Unmarshal(data []byte, v interface{}) error { return msgpack.Unmarshal(data, v) }
+func (serializer) ContentType() string                        { return ContentType }
+
+// CurlMsgpack executes the curl command, asking for MessagePack unless it
+// sets an Accept header, and decodes the MessagePack response body into v.
+// A response of another Content-Type returns a *gocurl.ContentTypeError.
+func CurlMsgpack(ctx context.Context, v interface{}, command ...string) (*http.Response, error) {
+	return gocurl.CurlWith(ctx, Serializer, v, command...)
+}
+
+// SendMsgpack executes the curl command with in encoded as its MessagePack
+// body, and decodes the MessagePack response body into out unless it is
+// nil. The command's method defaults to POST.
+func SendMsgpack(ctx context.Context, in, out interface{}, command ...string) (*http.Response, error) {
+	return gocurl.SendWith(ctx, Serializer, in, out, command...)
+}
@@ -0,0 +1,64 @@
+package msgpack_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/serializer/msgpack"
+)
+
+type reading struct {
+	Sensor string  `msgpack:"sensor"`
+	Value  float64 `msgpack:"value"`
+}
+
+func TestCurlMsgpack(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/html" {
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, "<html>gateway timeout</html>")
+			return
+		}
+		if got := r.Header.Get("Accept"); got != msgpack.ContentType {
+			t.Errorf("Accept = %q", got)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if r.Method == "POST" && r.Header.Get("Content-Type") != msgpack.ContentType {
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+		if len(body) == 0 {
+			body, _ = msgpack.Serializer.Marshal(reading{Sensor: "t1", Value: 21.5})
+		}
+		w.Header().Set("Content-Type", msgpack.ContentType)
+		w.Write(body)
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	var got reading
+	if _, err := msgpack.CurlMsgpack(ctx, &got, server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if got != (reading{Sensor: "t1", Value: 21.5}) {
+		t.Errorf("got %+v", got)
+	}
+
+	sent := reading{Sensor: "t2", Value: -3}
+	if _, err := msgpack.SendMsgpack(ctx, sent, &got, server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if got != sent {
+		t.Errorf("got %+v, want the echo of %+v", got, sent)
+	}
+
+	_, err := msgpack.CurlMsgpack(ctx, &got, server.URL+"/html")
+	var ctErr *gocurl.ContentTypeError
+	if !errors.As(err, &ctErr) || string(ctErr.Prefix) != "<html>gateway timeout</html>" {
+		t.Errorf("err = %v, want a *gocurl.ContentTypeError", err)
+	}
+}
@@ -0,0 +1,112 @@
+package gocurl_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// linesSerializer encodes []string as lines of text.
+type linesSerializer struct{}
+
+func (linesSerializer) Marshal(v interface{}) ([]byte, error) {
+	lines, ok := v.([]string)
+	if !ok {
+		return nil, errors.New("not lines")
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+func (linesSerializer) Unmarshal(data []byte, v interface{}) error {
+	lines, ok := v.(*[]string)
+	if !ok {
+		return errors.New("not lines")
+	}
+	*lines = strings.Split(string(data), "\n")
+	return nil
+}
+
+func (linesSerializer) ContentType() string { return "application/x-lines" }
+
+func TestClientSerializers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/echo":
+			w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+			w.Header().Set("X-Accept", r.Header.Get("Accept"))
+			w.Write(body)
+		case "/lines":
+			w.Header().Set("Content-Type", "application/x-lines; charset=utf-8")
+			w.Write([]byte("a\nb"))
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`["c"]`))
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+	client := gocurl.NewClient().SetSerializer(linesSerializer{})
+
+	var out []string
+	resp, err := client.Send(ctx, []string{"x", "y"}, &out, server.URL+"/echo")
+	require.NoError(t, err)
+	assert.Equal(t, "application/x-lines", resp.Header.Get("X-Accept"))
+	assert.Equal(t, []string{"x", "y"}, out)
+
+	_, err = client.Send(ctx, 1, nil, server.URL+"/echo")
+	assert.ErrorContains(t, err, "failed to encode application/x-lines body")
+
+	// Responses are decoded by their Content-Type
+	_, err = client.Send(ctx, []string{"x"}, &out, server.URL+"/json")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, out)
+
+	_, err = client.CurlDecode(ctx, &out, server.URL+"/lines")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, out)
+	_, err = client.CurlDecode(ctx, &out, server.URL+"/json")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, out)
+
+	// Without serializers, the client decodes like the package
+	_, err = gocurl.NewClient().CurlDecode(ctx, &out, server.URL+"/lines")
+	assert.ErrorContains(t, err, "no decoder")
+}
+
+func TestCurlWith(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/echo":
+			w.Header().Set("Content-Type", "application/vnd.test+x-lines")
+			w.Write([]byte(r.Header.Get("Accept") + "\n" + string(body)))
+		case "/html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html>"))
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	var out []string
+	_, err := gocurl.CurlWith(ctx, linesSerializer{}, &out, server.URL+"/echo")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"application/x-lines", ""}, out)
+
+	resp, err := gocurl.SendWith(ctx, linesSerializer{}, []string{"a"}, &out, server.URL+"/echo")
+	require.NoError(t, err)
+	assert.Equal(t, "POST", resp.Request.Method)
+	assert.Equal(t, []string{"application/x-lines", "a"}, out)
+
+	_, err = gocurl.CurlWith(ctx, linesSerializer{}, &out, server.URL+"/html")
+	assert.True(t, errors.Is(err, gocurl.ErrUnexpectedContentType))
+}
@@ -0,0 +1,311 @@
+package gocurl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+var (
+	contextType  = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+	valuesType   = reflect.TypeOf(url.Values(nil))
+	headerType   = reflect.TypeOf(http.Header(nil))
+	responseType = reflect.TypeOf((*http.Response)(nil))
+)
+
+// servicePlaceholder matches the {name} placeholders of a service route.
+var servicePlaceholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// NewService implements the function fields of the struct service points to
+// as HTTP calls, declared by a gocurl tag holding their method and route:
+//
+//	type GitHub struct {
+//		User        func(ctx context.Context, login string) (*User, error)                     `gocurl:"GET /users/{login}"`
+//		Repos       func(ctx context.Context, login string, q url.Values) ([]Repo, error)      `gocurl:"GET /users/{login}/repos"`
+//		CreateIssue func(ctx context.Context, owner, repo string, issue *Issue) (*Issue, error) `gocurl:"POST /repos/{owner}/{repo}/issues"`
+//	}
+//
+//	var github GitHub
+//	err := gocurl.NewService(&github, nil, options.NewRequestOptionsBuilder().
+//		SetURL("https://api.github.com").SetBearerToken(token).Build())
+//
+// Go cannot implement interfaces at run time, hence the struct of functions.
+// Their parameters are bound by position: an optional leading
+// context.Context, then one argument per placeholder of the route in order of
+// appearance, formatted with fmt.Sprint and escaped. The remaining arguments
+// are bound by type: url.Values are added to the query, http.Header to the
+// headers, and at most one other argument is the body, sent as is when it is
+// a string, []byte or io.Reader and encoded as JSON otherwise.
+//
+// A function returns an error, optionally preceded by a result: the
+// *http.Response itself, the body as a string or []byte, or any other type,
+// decoded like CurlDecode does. Error statuses (400 and above) fail the call
+// with a *HTTPError.
+//
+// Requests are executed through client, a new Client when nil, and start from
+// a copy of defaults, whose URL is the base URL the routes are appended to.
+func NewService(service interface{}, client *Client, defaults *options.RequestOptions) error {
+	v := reflect.ValueOf(service)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("service must be a pointer to a struct, got %T", service)
+	}
+	if client == nil {
+		client = NewClient()
+	}
+	if defaults == nil {
+		defaults = &options.RequestOptions{}
+	}
+
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		tag, ok := field.Tag.Lookup("gocurl")
+		if !ok {
+			continue
+		}
+		if field.Type.Kind() != reflect.Func || !field.IsExported() {
+			return fmt.Errorf("service field %s must be an exported function", field.Name)
+		}
+		call, err := newServiceCall(field.Type, tag)
+		if err != nil {
+			return fmt.Errorf("service field %s: %v", field.Name, err)
+		}
+		v.Field(i).Set(reflect.MakeFunc(field.Type, func(args []reflect.Value) []reflect.Value {
+			return call.invoke(client, defaults, args)
+		}))
+	}
+	return nil
+}
+
+// serviceArg is how a service function argument is bound to the request.
+type serviceArg int
+
+const (
+	argPath serviceArg = iota
+	argQuery
+	argHeader
+	argBody
+)
+
+// serviceCall is a service function bound to its route.
+type serviceCall struct {
+	method     string
+	route      string
+	hasContext bool
+	args       []serviceArg
+	result     reflect.Type // nil when the function only returns an error
+}
+
+func newServiceCall(fn reflect.Type, tag string) (*serviceCall, error) {
+	parts := strings.Fields(tag)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid route %q, expected \"METHOD /path\"", tag)
+	}
+	call := &serviceCall{method: strings.ToUpper(parts[0]), route: parts[1]}
+	if fn.IsVariadic() {
+		return nil, fmt.Errorf("variadic functions are not supported")
+	}
+
+	first := 0
+	if fn.NumIn() > 0 && fn.In(0) == contextType {
+		call.hasContext = true
+		first = 1
+	}
+	placeholders := len(servicePlaceholder.FindAllString(call.route, -1))
+	if fn.NumIn()-first < placeholders {
+		return nil, fmt.Errorf("route %q has %d placeholders but the function only takes %d arguments", call.route, placeholders, fn.NumIn()-first)
+	}
+	hasBody := false
+	for i := first; i < fn.NumIn(); i++ {
+		switch t := fn.In(i); {
+		case i-first < placeholders:
+			call.args = append(call.args, argPath)
+		case t == valuesType:
+			call.args = append(call.args, argQuery)
+		case t == headerType:
+			call.args = append(call.args, argHeader)
+		case hasBody:
+			return nil, fmt.Errorf("argument %d of type %s: only one body argument is allowed", i, t)
+		default:
+			hasBody = true
+			call.args = append(call.args, argBody)
+		}
+	}
+
+	switch fn.NumOut() {
+	case 2:
+		call.result = fn.Out(0)
+		fallthrough
+	case 1:
+		if fn.Out(fn.NumOut()-1) != errorType {
+			return nil, fmt.Errorf("the last result must be an error")
+		}
+	default:
+		return nil, fmt.Errorf("functions must return an error, optionally preceded by a result")
+	}
+	return call, nil
+}
+
+// invoke executes the call with args and returns its results.
+func (call *serviceCall) invoke(client *Client, defaults *options.RequestOptions, args []reflect.Value) []reflect.Value {
+	ctx := context.Background()
+	if call.hasContext {
+		if c, ok := args[0].Interface().(context.Context); ok && c != nil {
+			ctx = c
+		}
+		args = args[1:]
+	}
+
+	opts, err := call.request(defaults, args)
+	if err != nil {
+		return call.results(nil, err)
+	}
+	resp, body, err := client.Process(ctx, opts)
+	if err != nil {
+		return call.results(resp, err)
+	}
+	if call.result == nil {
+		return call.results(nil, nil)
+	}
+
+	if call.result != responseType {
+		if body, err = loadSpooled(resp, body); err != nil {
+			return call.results(resp, err)
+		}
+	}
+	var result reflect.Value
+	switch call.result {
+	case responseType:
+		result = reflect.ValueOf(resp)
+	case reflect.TypeOf(""):
+		result = reflect.ValueOf(body)
+	case reflect.TypeOf([]byte(nil)):
+		result = reflect.ValueOf([]byte(body))
+	default:
+		if result, err = decodeServiceResult(call.result, resp, body); err != nil {
+			return call.results(resp, err)
+		}
+	}
+	return []reflect.Value{result.Convert(call.result), reflect.Zero(errorType)}
+}
+
+// request builds the request of the call from defaults and args, the
+// arguments following the context.
+func (call *serviceCall) request(defaults *options.RequestOptions, args []reflect.Value) (*options.RequestOptions, error) {
+	opts := defaults.Clone()
+	opts.Method = call.method
+	opts.Silent = true
+	opts.Fail = true
+	if opts.Headers == nil {
+		opts.Headers = http.Header{}
+	}
+
+	route, query, _ := strings.Cut(call.route, "?")
+	next := 0
+	expand := func(template string, escape func(string) string) string {
+		return servicePlaceholder.ReplaceAllStringFunc(template, func(string) string {
+			value := fmt.Sprint(args[next].Interface())
+			next++
+			return escape(value)
+		})
+	}
+	route = expand(route, url.PathEscape)
+	if query != "" {
+		route += "?" + expand(query, url.QueryEscape)
+	}
+	opts.URL = strings.TrimSuffix(defaults.URL, "/") + route
+
+	for i, kind := range call.args {
+		arg := args[i]
+		switch kind {
+		case argQuery:
+			for key, values := range arg.Interface().(url.Values) {
+				opts.QueryParams[key] = append(opts.QueryParams[key], values...)
+			}
+		case argHeader:
+			for key, values := range arg.Interface().(http.Header) {
+				opts.Headers[key] = append(opts.Headers[key], values...)
+			}
+		case argBody:
+			if err := setServiceBody(opts, arg); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if call.result != nil && call.result != responseType && opts.Headers.Get("Accept") == "" {
+		opts.Headers.Set("Accept", defaultAccept)
+	}
+	return opts, nil
+}
+
+// setServiceBody sets arg as the body of opts.
+func setServiceBody(opts *options.RequestOptions, arg reflect.Value) error {
+	if (arg.Kind() == reflect.Ptr || arg.Kind() == reflect.Interface) && arg.IsNil() {
+		return nil
+	}
+	switch body := arg.Interface().(type) {
+	case string:
+		opts.Body = body
+	case []byte:
+		opts.Body = string(body)
+	case io.Reader:
+		opts.BodyReader = body
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request body: %v", err)
+		}
+		opts.Body = string(data)
+		if opts.Headers.Get("Content-Type") == "" {
+			opts.Headers.Set("Content-Type", "application/json")
+		}
+	}
+	return nil
+}
+
+// decodeServiceResult decodes body into a new value of type t. Empty bodies
+// leave it zero.
+func decodeServiceResult(t reflect.Type, resp *http.Response, body string) (reflect.Value, error) {
+	target := reflect.New(t)
+	if t.Kind() == reflect.Ptr {
+		target.Elem().Set(reflect.New(t.Elem()))
+	}
+	if strings.TrimSpace(body) == "" {
+		return reflect.Zero(t), nil
+	}
+	decoder, err := decoderFor(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if err := decoder(bytes.NewReader([]byte(body)), target.Interface()); err != nil {
+		return reflect.Value{}, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return target.Elem(), nil
+}
+
+// results returns the results of a failed or result-less call.
+func (call *serviceCall) results(resp *http.Response, err error) []reflect.Value {
+	errValue := reflect.Zero(errorType)
+	if err != nil {
+		errValue = reflect.ValueOf(&err).Elem()
+	}
+	if call.result == nil {
+		return []reflect.Value{errValue}
+	}
+	result := reflect.Zero(call.result)
+	if call.result == responseType && resp != nil {
+		result = reflect.ValueOf(resp)
+	}
+	return []reflect.Value{result, errValue}
+}
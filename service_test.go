@@ -0,0 +1,101 @@
+package gocurl_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type serviceIssue struct {
+	Title string `json:"title"`
+}
+
+type issueService struct {
+	User        func(ctx context.Context, login string) (*decodedUser, error)                              `gocurl:"GET /users/{login}"`
+	Search      func(ctx context.Context, sort string, q url.Values, h http.Header) ([]decodedUser, error) `gocurl:"GET /search/users?sort={sort}"`
+	CreateIssue func(ctx context.Context, owner, repo string, issue serviceIssue) (string, error)          `gocurl:"POST /repos/{owner}/{repo}/issues"`
+	Delete      func(ctx context.Context, id int) error                                                    `gocurl:"DELETE /issues/{id}"`
+	Raw         func(ctx context.Context) (*http.Response, error)                                          `gocurl:"GET /missing"`
+	Ignored     func()
+}
+
+func TestNewService(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.RequestURI()+" "+r.Header.Get("Authorization")+" "+string(body))
+		switch r.URL.Path {
+		case "/users/ada lovelace":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(decodedUser{Name: "ada", Admin: true})
+		case "/search/users":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `[{"name":"`+r.Header.Get("X-Team")+`"}]`)
+		case "/repos/go/gocurl/issues":
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, "created")
+		case "/issues/7":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	var svc issueService
+	defaults := options.NewRequestOptionsBuilder().SetURL(server.URL + "/").SetBearerToken("tok").Build()
+	require.NoError(t, gocurl.NewService(&svc, nil, defaults))
+	assert.Nil(t, svc.Ignored)
+	ctx := context.Background()
+
+	user, err := svc.User(ctx, "ada lovelace")
+	require.NoError(t, err)
+	assert.Equal(t, &decodedUser{Name: "ada", Admin: true}, user)
+
+	users, err := svc.Search(ctx, "stars&more", url.Values{"q": {"go"}}, http.Header{"X-Team": {"core"}})
+	require.NoError(t, err)
+	assert.Equal(t, []decodedUser{{Name: "core"}}, users)
+
+	created, err := svc.CreateIssue(ctx, "go", "gocurl", serviceIssue{Title: "bug"})
+	require.NoError(t, err)
+	assert.Equal(t, "created", created)
+
+	require.NoError(t, svc.Delete(ctx, 7))
+
+	resp, err := svc.Raw(ctx)
+	var httpErr *gocurl.HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	assert.Equal(t, []string{
+		"GET /users/ada%20lovelace Bearer tok ",
+		"GET /search/users?sort=stars%26more&q=go Bearer tok ",
+		`POST /repos/go/gocurl/issues Bearer tok {"title":"bug"}`,
+		"DELETE /issues/7 Bearer tok ",
+		"GET /missing Bearer tok ",
+	}, requests)
+
+	t.Run("Invalid services", func(t *testing.T) {
+		assert.Error(t, gocurl.NewService(issueService{}, nil, nil))
+		assert.Error(t, gocurl.NewService(&struct {
+			F func(id string) `gocurl:"GET /{id}"`
+		}{}, nil, nil))
+		assert.Error(t, gocurl.NewService(&struct {
+			F func(ctx context.Context) error `gocurl:"GET /{id}"`
+		}{}, nil, nil))
+		assert.Error(t, gocurl.NewService(&struct {
+			F func(a, b string) error `gocurl:"POST /"`
+		}{}, nil, nil))
+	})
+}
@@ -0,0 +1,241 @@
+package gocurl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// Session persists cookies, authentication, default headers and pooled
+// connections across requests, much like requests.Session in Python.
+//
+// Options given to NewSession act as defaults: every request made through
+// the session inherits the values it does not set itself. Transport-level
+// settings (TLS, proxy, HTTP/2, compression) are fixed when the session is
+// created so that connections can be reused.
+type Session struct {
+	mu        sync.RWMutex
+	defaults  *options.RequestOptions
+	transport http.RoundTripper
+	jar       http.CookieJar
+	client    *Client
+}
+
+// NewSession creates a session using opts as defaults. opts may be nil.
+func NewSession(opts *options.RequestOptions) (*Session, error) {
+	defaults := &options.RequestOptions{}
+	if opts != nil {
+		defaults = opts.Clone()
+	}
+	if defaults.Headers == nil {
+		defaults.Headers = http.Header{}
+	}
+
+	jar := defaults.CookieJar
+	if jar == nil {
+		var err error
+		jar, err = cookiejar.New(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cookie jar: %v", err)
+		}
+		defaults.CookieJar = jar
+	}
+
+	httpClient, err := CreateHTTPClient(defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		defaults:  defaults,
+		transport: httpClient.Transport,
+		jar:       jar,
+		client:    NewClient(),
+	}, nil
+}
+
+// Client returns the Client requests are executed through, so circuit
+// breakers and rate limits can be configured for the session.
+func (s *Session) Client() *Client {
+	return s.client
+}
+
+// SetHeader sets a default header sent with every request of the session.
+func (s *Session) SetHeader(key, value string) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaults.Headers.Set(key, value)
+	return s
+}
+
+// SetBearerToken sets the bearer token used by the session, e.g. after
+// logging in or refreshing an access token.
+func (s *Session) SetBearerToken(token string) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaults.BearerToken = token
+	s.defaults.BasicAuth = nil
+	return s
+}
+
+// SetBasicAuth sets the basic authentication credentials used by the session.
+func (s *Session) SetBasicAuth(username, password string) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaults.SetBasicAuth(username, password)
+	s.defaults.BearerToken = ""
+	return s
+}
+
+// Cookies returns the cookies the session would send to u.
+func (s *Session) Cookies(u *url.URL) []*http.Cookie {
+	return s.jar.Cookies(u)
+}
+
+// Curl parses the curl command and executes it within the session.
+func (s *Session) Curl(ctx context.Context, command ...string) (*http.Response, string, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.Process(ctx, opts)
+}
+
+// Process executes the request within the session.
+func (s *Session) Process(ctx context.Context, opts *options.RequestOptions) (*http.Response, string, error) {
+	s.mu.RLock()
+	merged := applyDefaults(opts, s.defaults)
+	s.mu.RUnlock()
+
+	merged.CookieJar = s.jar
+	return s.client.process(ctx, newHTTPClient(s.transport, merged), merged)
+}
+
+// Download executes the curl command within the session and writes the
+// response body to path.
+func (s *Session) Download(ctx context.Context, path string, command ...string) (*http.Response, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, err
+	}
+	opts.OutputFile = path
+	opts.Silent = true
+
+	resp, _, err := s.Process(ctx, opts)
+	return resp, err
+}
+
+// applyDefaults returns a copy of opts where every unset field takes its
+// value from defaults. Headers are merged key by key and default middleware
+// runs before the request's own.
+func applyDefaults(opts, defaults *options.RequestOptions) *options.RequestOptions {
+	merged := opts.Clone()
+	if defaults == nil {
+		return merged
+	}
+
+	if merged.Headers == nil {
+		merged.Headers = http.Header{}
+	}
+	for key, values := range defaults.Headers {
+		if _, ok := merged.Headers[key]; !ok {
+			merged.Headers[key] = append([]string(nil), values...)
+		}
+	}
+
+	if merged.BasicAuth == nil && merged.BearerToken == "" {
+		if defaults.BasicAuth != nil {
+			auth := *defaults.BasicAuth
+			merged.BasicAuth = &auth
+		}
+		merged.BearerToken = defaults.BearerToken
+	}
+
+	merged.Cookies = append(append([]*http.Cookie(nil), defaults.Cookies...), merged.Cookies...)
+	merged.Middleware = append(append(merged.Middleware[:0:0], defaults.Middleware...), merged.Middleware...)
+	merged.Interceptors = append(append(merged.Interceptors[:0:0], defaults.Interceptors...), merged.Interceptors...)
+
+	if merged.UserAgent == "" {
+		merged.UserAgent = defaults.UserAgent
+	}
+	if merged.Referer == "" {
+		merged.Referer = defaults.Referer
+	}
+	if merged.Proxy == "" && merged.ProxyPAC == "" {
+		merged.Proxy = defaults.Proxy
+		merged.ProxyPAC = defaults.ProxyPAC
+	}
+	if merged.Timeout == 0 {
+		merged.Timeout = defaults.Timeout
+	}
+	if merged.ConnectTimeout == 0 {
+		merged.ConnectTimeout = defaults.ConnectTimeout
+	}
+	if merged.AttemptTimeout == 0 {
+		merged.AttemptTimeout = defaults.AttemptTimeout
+	}
+	if !merged.FollowRedirects && defaults.FollowRedirects {
+		merged.FollowRedirects = true
+		if merged.MaxRedirects == 0 {
+			merged.MaxRedirects = defaults.MaxRedirects
+		}
+	}
+	if merged.RetryConfig == nil && defaults.RetryConfig != nil {
+		retry := *defaults.RetryConfig
+		merged.RetryConfig = &retry
+	}
+	if merged.Policy == nil {
+		merged.Policy = defaults.Policy
+	}
+	if merged.RetryAfter == nil && defaults.RetryAfter != nil {
+		policy := *defaults.RetryAfter
+		merged.RetryAfter = &policy
+	}
+	if merged.SpoolThreshold == 0 {
+		merged.SpoolThreshold = defaults.SpoolThreshold
+		merged.SpoolDir = defaults.SpoolDir
+	}
+	if merged.Logger == nil {
+		merged.Logger = defaults.Logger
+	}
+	if merged.Signer == nil {
+		merged.Signer = defaults.Signer
+	}
+	if merged.BodyDigest == "" {
+		merged.BodyDigest = defaults.BodyDigest
+	}
+	if merged.Auditor == nil {
+		merged.Auditor = defaults.Auditor
+	}
+	if merged.ErrorModel == nil {
+		merged.ErrorModel = defaults.ErrorModel
+	}
+	if merged.CookieJar == nil {
+		merged.CookieJar = defaults.CookieJar
+	}
+	if merged.TLSSessionCache == nil {
+		merged.TLSSessionCache = defaults.TLSSessionCache
+	}
+	if merged.JSONContentType == "" {
+		merged.JSONContentType = defaults.JSONContentType
+	}
+	if merged.PreDecodeHook == nil {
+		merged.PreDecodeHook = defaults.PreDecodeHook
+	}
+	if merged.AltSvcStore == nil {
+		merged.AltSvcStore = defaults.AltSvcStore
+	}
+	if merged.ETagStore == nil {
+		merged.ETagStore = defaults.ETagStore
+	}
+	if !merged.Silent {
+		merged.Silent = defaults.Silent
+	}
+
+	return merged
+}
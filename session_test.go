@@ -0,0 +1,81 @@
+package gocurl_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession(t *testing.T) {
+	remotes := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remotes[r.RemoteAddr] = true
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+		case "/me":
+			cookie, err := r.Cookie("session")
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, "%s|%s|%s", cookie.Value, r.Header.Get("X-Client"), r.Header.Get("Authorization"))
+		}
+	}))
+	defer server.Close()
+
+	defaults := options.NewRequestOptionsBuilder().
+		AddHeader("X-Client", "gocurl").
+		SetSilent(true).
+		Build()
+	session, err := gocurl.NewSession(defaults)
+	require.NoError(t, err)
+
+	_, _, err = session.Process(context.Background(), &options.RequestOptions{URL: server.URL + "/login", Method: "POST"})
+	require.NoError(t, err)
+
+	session.SetBearerToken("t0k3n")
+	_, body, err := session.Process(context.Background(), &options.RequestOptions{URL: server.URL + "/me"})
+	require.NoError(t, err)
+	assert.Equal(t, "abc|gocurl|Bearer t0k3n", body)
+
+	t.Run("Request values win over defaults", func(t *testing.T) {
+		_, body, err := session.Process(context.Background(), &options.RequestOptions{
+			URL:     server.URL + "/me",
+			Headers: http.Header{"X-Client": {"other"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "abc|other|Bearer t0k3n", body)
+	})
+
+	t.Run("Connections are reused", func(t *testing.T) {
+		assert.Len(t, remotes, 1)
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "me.txt")
+		resp, err := session.Download(context.Background(), path, server.URL+"/me")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "abc|gocurl|Bearer t0k3n", string(content))
+	})
+
+	t.Run("Session cookies are visible", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		cookies := session.Cookies(req.URL)
+		require.Len(t, cookies, 1)
+		assert.Equal(t, "session", cookies[0].Name)
+	})
+}
@@ -0,0 +1,152 @@
+package gocurl
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrInvalidSignature is matched (via errors.Is) by the errors of
+// SignatureVerifiers for signatures that do not verify.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// SignatureVerifier verifies detached signatures, as published next to
+// release artifacts. Errors for signatures that do not verify must match
+// ErrInvalidSignature.
+//
+// CosignPublicKey verifies the signatures of cosign sign-blob --key, the
+// signature/pgp module those of GPG keys, and the signature/sigstore module
+// the keyless sigstore bundles of cosign sign-blob --bundle, which are
+// checked against the identity of their signer.
+type SignatureVerifier interface {
+	VerifySignature(artifact io.Reader, signature []byte) error
+}
+
+// SignatureVerifierFunc adapts a function to a SignatureVerifier.
+type SignatureVerifierFunc func(artifact io.Reader, signature []byte) error
+
+// VerifySignature calls f(artifact, signature).
+func (f SignatureVerifierFunc) VerifySignature(artifact io.Reader, signature []byte) error {
+	return f(artifact, signature)
+}
+
+// VerifySignature checks the file at path, typically just downloaded,
+// against the detached signature at sigURL with verifier. sigURL may also
+// be a curl command.
+func VerifySignature(ctx context.Context, path, sigURL string, verifier SignatureVerifier) error {
+	signature, err := fetchSignature(ctx, sigURL)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to verify signature: %v", err)
+	}
+	defer file.Close()
+	return verifier.VerifySignature(file, signature)
+}
+
+// VerifyFromSignedManifest is VerifyFromManifest for manifests with a
+// detached signature at sigURL, such as SHA256SUMS.sig: the manifest is
+// only trusted once verifier verified it.
+func VerifyFromSignedManifest(ctx context.Context, path, manifestURL, sigURL string, format ChecksumFormat, verifier SignatureVerifier) error {
+	resp, manifest, err := CurlBytes(ctx, manifestURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch checksum manifest: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to fetch checksum manifest: %s", resp.Status)
+	}
+	if manifest, err = loadSpooledBytes(resp, manifest); err != nil {
+		return fmt.Errorf("failed to fetch checksum manifest: %v", err)
+	}
+	signature, err := fetchSignature(ctx, sigURL)
+	if err != nil {
+		return err
+	}
+	if err := verifier.VerifySignature(bytes.NewReader(manifest), signature); err != nil {
+		return fmt.Errorf("checksum manifest: %w", err)
+	}
+	return verifyManifest(path, manifest, format)
+}
+
+// fetchSignature downloads the signature at sigURL.
+func fetchSignature(ctx context.Context, sigURL string) ([]byte, error) {
+	resp, signature, err := CurlBytes(ctx, sigURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signature: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to fetch signature: %s", resp.Status)
+	}
+	if signature, err = loadSpooledBytes(resp, signature); err != nil {
+		return nil, fmt.Errorf("failed to fetch signature: %v", err)
+	}
+	return signature, nil
+}
+
+// CosignPublicKey returns a SignatureVerifier of the base64 signatures
+// written by cosign sign-blob --key for the PEM encoded public key, such as
+// cosign.pub. ECDSA, RSA and Ed25519 keys are supported.
+func CosignPublicKey(pemKey []byte) (SignatureVerifier, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, fmt.Errorf("invalid public key: no PEM block found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+
+	return SignatureVerifierFunc(func(artifact io.Reader, signature []byte) error {
+		sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+		if err != nil {
+			// Raw signatures are accepted as well
+			sig = signature
+		}
+		if key, ok := key.(ed25519.PublicKey); ok {
+			// Ed25519 signs the artifact itself rather than its digest
+			data, err := io.ReadAll(artifact)
+			if err != nil {
+				return fmt.Errorf("failed to read artifact: %v", err)
+			}
+			if !ed25519.Verify(key, data, sig) {
+				return ErrInvalidSignature
+			}
+			return nil
+		}
+
+		h := sha256.New()
+		if _, err := io.Copy(h, artifact); err != nil {
+			return fmt.Errorf("failed to read artifact: %v", err)
+		}
+		digest := h.Sum(nil)
+		switch key := key.(type) {
+		case *ecdsa.PublicKey:
+			if !ecdsa.VerifyASN1(key, digest, sig) {
+				return ErrInvalidSignature
+			}
+		case *rsa.PublicKey:
+			if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, sig); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+			}
+		}
+		return nil
+	}), nil
+}
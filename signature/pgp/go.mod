@@ -0,0 +1,19 @@
+module github.com/maniartech/gocurl/signature/pgp
+
+go 1.22.3
+
+replace github.com/maniartech/gocurl => ../..
+
+require (
+	github.com/ProtonMail/go-crypto v1.1.6
+	github.com/maniartech/gocurl v0.0.0
+)
+
+require (
+	github.com/cloudflare/circl v1.3.7 // indirect
+	golang.org/x/crypto v0.28.0 // indirect
+	golang.org/x/net v0.30.0 // indirect
+	golang.org/x/sys v0.26.0 // indirect
+	golang.org/x/text v0.19.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,47 @@
+// Package pgp verifies the detached GPG signatures of downloads, such as the
+// .asc and .sig files published next to release artifacts, for
+// gocurl.VerifySignature. It is a module of its own to keep the OpenPGP
+// implementation out of gocurl's dependencies.
+package pgp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/maniartech/gocurl"
+)
+
+// Verifier is a gocurl.SignatureVerifier of the signatures made by the keys
+// of a key ring.
+type Verifier struct {
+	keys openpgp.EntityList
+}
+
+// NewVerifier returns a Verifier trusting the public keys of keyRing, in
+// the armored form of gpg --export --armor or binary.
+func NewVerifier(keyRing []byte) (*Verifier, error) {
+	keys, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(keyRing))
+	if err != nil {
+		if keys, err = openpgp.ReadKeyRing(bytes.NewReader(keyRing)); err != nil {
+			return nil, fmt.Errorf("invalid key ring: %v", err)
+		}
+	}
+	return &Verifier{keys: keys}, nil
+}
+
+// VerifySignature verifies the armored or binary detached signature of
+// artifact.
+func (v *Verifier) VerifySignature(artifact io.Reader, signature []byte) error {
+	if block, err := armor.Decode(bytes.NewReader(signature)); err == nil {
+		if signature, err = io.ReadAll(block.Body); err != nil {
+			return fmt.Errorf("invalid signature: %v", err)
+		}
+	}
+	if _, err := openpgp.CheckDetachedSignature(v.keys, artifact, bytes.NewReader(signature), nil); err != nil {
+		return fmt.Errorf("%w: %v", gocurl.ErrInvalidSignature, err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package pgp_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/signature/pgp"
+)
+
+func TestVerifier(t *testing.T) {
+	entity, err := openpgp.NewEntity("Release Signer", "", "release@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keyRing, armored, binary bytes.Buffer
+	w, err := armor.Encode(&keyRing, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if err := openpgp.ArmoredDetachSign(&armored, entity, strings.NewReader("artifact"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := openpgp.DetachSign(&binary, entity, strings.NewReader("artifact"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, err := pgp.NewVerifier(keyRing.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.VerifySignature(strings.NewReader("artifact"), armored.Bytes()); err != nil {
+		t.Errorf("armored signature: %v", err)
+	}
+	if err := verifier.VerifySignature(strings.NewReader("artifact"), binary.Bytes()); err != nil {
+		t.Errorf("binary signature: %v", err)
+	}
+	if err := verifier.VerifySignature(strings.NewReader("tampered"), armored.Bytes()); !errors.Is(err, gocurl.ErrInvalidSignature) {
+		t.Errorf("tampered artifact: got %v, want ErrInvalidSignature", err)
+	}
+
+	// Downloads are verified against the signature published next to them
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(armored.Bytes())
+	}))
+	defer server.Close()
+	artifact := filepath.Join(t.TempDir(), "tool.tar.gz")
+	if err := os.WriteFile(artifact, []byte("artifact"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := gocurl.VerifySignature(context.Background(), artifact, server.URL+"/tool.tar.gz.asc", verifier); err != nil {
+		t.Errorf("VerifySignature: %v", err)
+	}
+
+	if _, err := pgp.NewVerifier([]byte("not a key")); err == nil {
+		t.Error("NewVerifier accepted an invalid key ring")
+	}
+}
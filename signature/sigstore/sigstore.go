@@ -0,0 +1,88 @@
+// Package sigstore verifies the keyless sigstore signatures of downloads,
+// the bundles written by cosign sign-blob --bundle, for
+// gocurl.VerifySignature. Keyless signatures are made with a short lived
+// certificate the Fulcio certificate authority issues to an OIDC identity,
+// and logged in the Rekor transparency log, so they are verified against
+// the identity expected to sign rather than a public key. It is a module of
+// its own to keep sigstore-go out of gocurl's dependencies.
+package sigstore
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/maniartech/gocurl"
+	"github.com/sigstore/sigstore-go/pkg/bundle"
+	"github.com/sigstore/sigstore-go/pkg/root"
+	"github.com/sigstore/sigstore-go/pkg/verify"
+)
+
+// Identity is the signer a keyless signature must have been issued to: the
+// OIDC issuer that authenticated it, such as https://accounts.google.com or
+// https://token.actions.githubusercontent.com, and the subject of its
+// certificate, such as an email address or the URL of a CI workflow. The
+// regular expressions match instead of the values when these are empty.
+type Identity struct {
+	Issuer        string
+	IssuerRegexp  string
+	Subject       string
+	SubjectRegexp string
+}
+
+// Verifier is a gocurl.SignatureVerifier of the keyless signatures of an
+// identity.
+type Verifier struct {
+	verifier *verify.Verifier
+	identity verify.CertificateIdentity
+}
+
+// NewVerifier returns a Verifier of the signatures issued to identity by
+// the certificate authorities of trusted, and logged in its transparency
+// logs. trusted is typically the trusted root PublicGood returns, or that of
+// a private sigstore instance loaded with root.NewTrustedRootFromPath.
+func NewVerifier(trusted root.TrustedMaterial, identity Identity) (*Verifier, error) {
+	return newVerifier(trusted, identity, verify.WithSignedCertificateTimestamps(1))
+}
+
+// newVerifier is NewVerifier requiring the timestamps of opts besides the
+// transparency log entry.
+func newVerifier(trusted root.TrustedMaterial, identity Identity, opts ...verify.VerifierOption) (*Verifier, error) {
+	opts = append(opts, verify.WithTransparencyLog(1), verify.WithObserverTimestamps(1))
+	verifier, err := verify.NewVerifier(trusted, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted root: %v", err)
+	}
+	certID, err := verify.NewShortCertificateIdentity(identity.Issuer, identity.IssuerRegexp, identity.Subject, identity.SubjectRegexp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity: %v", err)
+	}
+	return &Verifier{verifier: verifier, identity: certID}, nil
+}
+
+// PublicGood returns the trusted root of the public sigstore instance,
+// fetched from its TUF repository and cached in the user's home directory.
+func PublicGood() (root.TrustedMaterial, error) {
+	trusted, err := root.FetchTrustedRoot()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch trusted root: %v", err)
+	}
+	return trusted, nil
+}
+
+// VerifySignature verifies the JSON sigstore bundle signature of artifact.
+func (v *Verifier) VerifySignature(artifact io.Reader, signature []byte) error {
+	var b bundle.Bundle
+	if err := b.UnmarshalJSON(signature); err != nil {
+		return fmt.Errorf("invalid sigstore bundle: %v", err)
+	}
+	return v.verify(artifact, &b)
+}
+
+// verify checks that entity signs artifact as identity.
+func (v *Verifier) verify(artifact io.Reader, entity verify.SignedEntity) error {
+	policy := verify.NewPolicy(verify.WithArtifact(artifact), verify.WithCertificateIdentity(v.identity))
+	if _, err := v.verifier.Verify(entity, policy); err != nil {
+		return fmt.Errorf("%w: %v", gocurl.ErrInvalidSignature, err)
+	}
+	return nil
+}
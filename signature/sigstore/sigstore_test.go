@@ -0,0 +1,62 @@
+package sigstore
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/sigstore/sigstore-go/pkg/testing/ca"
+)
+
+func TestVerifier(t *testing.T) {
+	// The virtual sigstore is a Fulcio, Rekor and timestamping authority of
+	// its own, and the trusted root of its signatures. Its certificates have
+	// no signed certificate timestamps, which the verifiers do not require.
+	virtual, err := ca.NewVirtualSigstore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const issuer = "https://token.actions.githubusercontent.com"
+	const subject = "https://github.com/maniartech/gocurl/.github/workflows/release.yml@refs/tags/v1.0.0"
+	entity, err := virtual.Sign(subject, issuer, []byte("artifact"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, err := newVerifier(virtual, Identity{Issuer: issuer, Subject: subject})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.verify(strings.NewReader("artifact"), entity); err != nil {
+		t.Errorf("signature: %v", err)
+	}
+	if err := verifier.verify(strings.NewReader("tampered"), entity); !errors.Is(err, gocurl.ErrInvalidSignature) {
+		t.Errorf("tampered artifact: got %v, want ErrInvalidSignature", err)
+	}
+
+	// Any workflow of the repository may sign with a regular expression
+	verifier, err = newVerifier(virtual, Identity{Issuer: issuer, SubjectRegexp: `^https://github\.com/maniartech/gocurl/`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.verify(strings.NewReader("artifact"), entity); err != nil {
+		t.Errorf("subject regexp: %v", err)
+	}
+
+	// Signatures of anyone else are rejected, however valid
+	verifier, err = newVerifier(virtual, Identity{Issuer: issuer, Subject: "release@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.verify(strings.NewReader("artifact"), entity); !errors.Is(err, gocurl.ErrInvalidSignature) {
+		t.Errorf("other identity: got %v, want ErrInvalidSignature", err)
+	}
+
+	if err := verifier.VerifySignature(strings.NewReader("artifact"), []byte("not a bundle")); err == nil {
+		t.Error("VerifySignature accepted an invalid bundle")
+	}
+	if _, err := NewVerifier(virtual, Identity{Subject: subject}); err == nil {
+		t.Error("NewVerifier accepted an identity without issuer")
+	}
+}
@@ -0,0 +1,98 @@
+package gocurl_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifySignature(t *testing.T) {
+	dir := t.TempDir()
+	artifact := filepath.Join(dir, "tool.tar.gz")
+	require.NoError(t, os.WriteFile(artifact, []byte("artifact"), 0644))
+	digest := sha256.Sum256([]byte("artifact"))
+	manifest := hex.EncodeToString(digest[:]) + "  tool.tar.gz\n"
+	manifestDigest := sha256.Sum256([]byte(manifest))
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	artifactSig, err := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+	require.NoError(t, err)
+	manifestSig, err := ecdsa.SignASN1(rand.Reader, ecKey, manifestDigest[:])
+	require.NoError(t, err)
+
+	files := map[string]string{
+		"/tool.tar.gz.sig": base64.StdEncoding.EncodeToString(artifactSig) + "\n",
+		"/SHA256SUMS":      manifest,
+		"/SHA256SUMS.sig":  base64.StdEncoding.EncodeToString(manifestSig),
+		"/forged.sig":      base64.StdEncoding.EncodeToString(manifestSig),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(file))
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	verifier, err := gocurl.CosignPublicKey(publicKeyPEM(t, &ecKey.PublicKey))
+	require.NoError(t, err)
+	assert.NoError(t, gocurl.VerifySignature(ctx, artifact, server.URL+"/tool.tar.gz.sig", verifier))
+	assert.True(t, errors.Is(gocurl.VerifySignature(ctx, artifact, server.URL+"/forged.sig", verifier), gocurl.ErrInvalidSignature))
+	assert.ErrorContains(t, gocurl.VerifySignature(ctx, artifact, server.URL+"/missing.sig", verifier), "404")
+
+	assert.NoError(t, gocurl.VerifyFromSignedManifest(ctx, artifact, server.URL+"/SHA256SUMS", server.URL+"/SHA256SUMS.sig", gocurl.ChecksumSHA256, verifier))
+	err = gocurl.VerifyFromSignedManifest(ctx, artifact, server.URL+"/SHA256SUMS", server.URL+"/tool.tar.gz.sig", gocurl.ChecksumSHA256, verifier)
+	assert.True(t, errors.Is(err, gocurl.ErrInvalidSignature))
+
+	t.Run("Key types", func(t *testing.T) {
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+		verifier, err := gocurl.CosignPublicKey(publicKeyPEM(t, &rsaKey.PublicKey))
+		require.NoError(t, err)
+		assert.NoError(t, verifier.VerifySignature(strings.NewReader("artifact"), []byte(base64.StdEncoding.EncodeToString(sig))))
+		assert.Error(t, verifier.VerifySignature(strings.NewReader("tampered"), []byte(base64.StdEncoding.EncodeToString(sig))))
+
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		verifier, err = gocurl.CosignPublicKey(publicKeyPEM(t, public))
+		require.NoError(t, err)
+		assert.NoError(t, verifier.VerifySignature(strings.NewReader("artifact"), ed25519.Sign(private, []byte("artifact"))))
+		assert.True(t, errors.Is(verifier.VerifySignature(strings.NewReader("tampered"), ed25519.Sign(private, []byte("artifact"))), gocurl.ErrInvalidSignature))
+
+		_, err = gocurl.CosignPublicKey([]byte("not a key"))
+		assert.Error(t, err)
+	})
+}
+
+func publicKeyPEM(t *testing.T, key interface{}) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
@@ -0,0 +1,82 @@
+package gocurl_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner(t *testing.T) {
+	t.Run("HMAC signature over method, path and body hash", func(t *testing.T) {
+		secret := []byte("s3cr3t")
+		sign := func(method, path, bodyHash string) string {
+			mac := hmac.New(sha256.New, secret)
+			fmt.Fprintf(mac, "%s\n%s\n%s", method, path, bodyHash)
+			return hex.EncodeToString(mac.Sum(nil))
+		}
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			sum := sha256.Sum256(body)
+			expected := sign(r.Method, r.URL.Path, hex.EncodeToString(sum[:]))
+			if r.Header.Get("X-Signature") != expected {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, "signed")
+		}))
+		defer server.Close()
+
+		opts := options.NewRequestOptionsBuilder().
+			POST(server.URL+"/orders", `{"qty":1}`, http.Header{}).
+			SetSilent(true).
+			SetSigner(options.SignerFunc(func(req *http.Request, bodyHash string) error {
+				req.Header.Set("X-Signature", sign(req.Method, req.URL.Path, bodyHash))
+				return nil
+			})).
+			Build()
+
+		resp, body, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "signed", body)
+	})
+
+	t.Run("Empty body hash", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "https://example.com", nil)
+
+		var got string
+		err := gocurl.SignRequest(req, options.SignerFunc(func(req *http.Request, bodyHash string) error {
+			got = bodyHash
+			return nil
+		}))
+
+		require.NoError(t, err)
+		assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", got)
+	})
+
+	t.Run("Signer error aborts the request", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "https://example.com", nil)
+
+		err := gocurl.SignRequest(req, options.SignerFunc(func(req *http.Request, bodyHash string) error {
+			return errors.New("missing key")
+		}))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "missing key")
+	})
+}
@@ -0,0 +1,255 @@
+// Package snapshot provides snapshot testing for API responses.
+//
+// The first run of CurlSnapshot writes the normalized response to
+// testdata/snapshots/<name>.snap; later runs fail the test when the response
+// no longer matches. Set GOCURL_UPDATE_SNAPSHOTS=1 to rewrite snapshots.
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/tokenizer"
+)
+
+// UpdateEnv is the environment variable that, when set to a non-empty value
+// other than "0", makes snapshots be rewritten instead of compared.
+const UpdateEnv = "GOCURL_UPDATE_SNAPSHOTS"
+
+// Masked replaces the values of masked headers and JSON fields.
+const Masked = "<masked>"
+
+// Config controls where snapshots are stored and how responses are
+// normalized before they are compared.
+type Config struct {
+	// Dir is the directory holding the snapshot files
+	Dir string
+
+	// Headers lists the response headers recorded in the snapshot
+	Headers []string
+
+	// MaskHeaders lists recorded headers whose values vary between runs
+	MaskHeaders []string
+
+	// MaskPaths lists JSON body paths whose values vary between runs, such as
+	// "$.id" or "$.items[*].createdAt"
+	MaskPaths []string
+
+	// MaskPatterns are replaced with Masked anywhere in the body
+	MaskPatterns []*regexp.Regexp
+}
+
+// Default is the configuration used by CurlSnapshot.
+var Default = &Config{
+	Dir:     filepath.Join("testdata", "snapshots"),
+	Headers: []string{"Content-Type"},
+}
+
+// CurlSnapshot executes the curl command and matches the response against
+// the snapshot called name using the Default configuration.
+func CurlSnapshot(ctx context.Context, t testing.TB, name string, args ...string) *http.Response {
+	t.Helper()
+	return Default.CurlSnapshot(ctx, t, name, args...)
+}
+
+// CurlSnapshot executes the curl command and matches the normalized response
+// against the snapshot called name, writing it if it does not exist yet.
+func (c *Config) CurlSnapshot(ctx context.Context, t testing.TB, name string, args ...string) *http.Response {
+	t.Helper()
+
+	opts, err := gocurl.ArgsToOptions(commandArgs(args))
+	if err != nil {
+		t.Fatalf("snapshot %s: %v", name, err)
+	}
+	opts.Silent = true
+
+	resp, body, err := gocurl.Process(ctx, opts)
+	if err != nil {
+		t.Fatalf("snapshot %s: request failed: %v", name, err)
+	}
+	if body == "" {
+		// The body was spooled to disk
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("snapshot %s: failed to read body: %v", name, err)
+		}
+		resp.Body.Close()
+		body = string(b)
+		resp.Body = io.NopCloser(strings.NewReader(body))
+	}
+
+	c.Match(t, name, resp, body)
+	return resp
+}
+
+// commandArgs splits a single command string into arguments.
+func commandArgs(args []string) []string {
+	if len(args) == 1 {
+		if split, err := tokenizer.Split(args[0]); err == nil {
+			return split
+		}
+	}
+	return args
+}
+
+// Match compares the normalized response with the snapshot called name.
+func (c *Config) Match(t testing.TB, name string, resp *http.Response, body string) {
+	t.Helper()
+
+	actual := c.Normalize(resp, body)
+	path := filepath.Join(c.Dir, name+".snap")
+
+	expected, err := os.ReadFile(path)
+	if os.IsNotExist(err) || updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("snapshot %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(actual), 0644); err != nil {
+			t.Fatalf("snapshot %s: %v", name, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("snapshot %s: %v", name, err)
+	}
+
+	if string(expected) != actual {
+		t.Errorf("snapshot %s does not match (set %s=1 to update):\n%s", name, UpdateEnv, lineDiff(string(expected), actual))
+	}
+}
+
+func updating() bool {
+	value := os.Getenv(UpdateEnv)
+	return value != "" && value != "0"
+}
+
+// Normalize renders the status, recorded headers and body of a response with
+// volatile values masked. JSON bodies are pretty-printed with sorted keys.
+func (c *Config) Normalize(resp *http.Response, body string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "HTTP %d\n", resp.StatusCode)
+
+	headers := append([]string(nil), c.Headers...)
+	sort.Strings(headers)
+	for _, name := range headers {
+		values := resp.Header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		value := strings.Join(values, ", ")
+		if containsFold(c.MaskHeaders, name) {
+			value = Masked
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", http.CanonicalHeaderKey(name), value)
+	}
+	sb.WriteByte('\n')
+
+	var doc interface{}
+	if json.Unmarshal([]byte(body), &doc) == nil {
+		doc = c.maskJSON("$", doc)
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		enc.Encode(doc)
+		body = buf.String()
+	}
+	for _, pattern := range c.MaskPatterns {
+		body = pattern.ReplaceAllString(body, Masked)
+	}
+	sb.WriteString(body)
+
+	return sb.String()
+}
+
+func (c *Config) maskJSON(path string, v interface{}) interface{} {
+	if c.masked(path) {
+		return Masked
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = c.maskJSON(path+"."+key, value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = c.maskJSON(fmt.Sprintf("%s[%d]", path, i), value)
+		}
+	}
+	return v
+}
+
+var indexPattern = regexp.MustCompile(`\[\d+\]`)
+
+// masked reports whether path matches one of the mask paths, where "[*]"
+// matches any array index.
+func (c *Config) masked(path string) bool {
+	wildcard := indexPattern.ReplaceAllString(path, "[*]")
+	for _, mask := range c.MaskPaths {
+		if mask == path || mask == wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// lineDiff returns a minimal line based diff of a and b, prefixing removed
+// lines with "-" and added lines with "+".
+func lineDiff(a, b string) string {
+	la, lb := strings.Split(a, "\n"), strings.Split(b, "\n")
+
+	// Longest common subsequence table
+	lcs := make([][]int, len(la)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(lb)+1)
+	}
+	for i := len(la) - 1; i >= 0; i-- {
+		for j := len(lb) - 1; j >= 0; j-- {
+			if la[i] == lb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(la) || j < len(lb) {
+		switch {
+		case i < len(la) && j < len(lb) && la[i] == lb[j]:
+			sb.WriteString("  " + la[i] + "\n")
+			i++
+			j++
+		case i < len(la) && (j == len(lb) || lcs[i+1][j] >= lcs[i][j+1]):
+			sb.WriteString("- " + la[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+ " + lb[j] + "\n")
+			j++
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,95 @@
+package snapshot_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/maniartech/gocurl/snapshot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTB captures failures instead of failing the surrounding test.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestCurlSnapshot(t *testing.T) {
+	id := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", fmt.Sprint(id))
+		fmt.Fprintf(w, `{"items":[{"id":%d,"name":"a"}],"token":"tok-%d","total":1}`, id, id)
+	}))
+	defer server.Close()
+
+	config := &snapshot.Config{
+		Dir:          t.TempDir(),
+		Headers:      []string{"Content-Type", "X-Request-Id"},
+		MaskHeaders:  []string{"X-Request-Id"},
+		MaskPaths:    []string{"$.items[*].id"},
+		MaskPatterns: []*regexp.Regexp{regexp.MustCompile(`tok-\d+`)},
+	}
+
+	config.CurlSnapshot(context.Background(), t, "items", "curl "+server.URL)
+	content, err := os.ReadFile(filepath.Join(config.Dir, "items.snap"))
+	require.NoError(t, err)
+	assert.Equal(t, `HTTP 200
+Content-Type: application/json
+X-Request-Id: <masked>
+
+{
+  "items": [
+    {
+      "id": "<masked>",
+      "name": "a"
+    }
+  ],
+  "token": "<masked>",
+  "total": 1
+}
+`, string(content))
+
+	t.Run("Volatile fields are ignored", func(t *testing.T) {
+		id = 2
+		tb := &recordingTB{TB: t}
+		config.CurlSnapshot(context.Background(), tb, "items", "curl", server.URL)
+		assert.Empty(t, tb.errors)
+	})
+
+	t.Run("Changes are reported", func(t *testing.T) {
+		tb := &recordingTB{TB: t}
+		config.CurlSnapshot(context.Background(), tb, "items", "curl", server.URL+"/other", "-X", "POST")
+		assert.Empty(t, tb.errors)
+
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"items":[],"token":"tok-3","total":0}`)
+		})
+		config.CurlSnapshot(context.Background(), tb, "items", "curl "+server.URL)
+		require.Len(t, tb.errors, 1)
+		assert.Contains(t, tb.errors[0], "-   \"total\": 1\n+   \"total\": 0")
+	})
+
+	t.Run("Update mode rewrites snapshots", func(t *testing.T) {
+		t.Setenv(snapshot.UpdateEnv, "1")
+		tb := &recordingTB{TB: t}
+		config.CurlSnapshot(context.Background(), tb, "items", "curl "+server.URL)
+		assert.Empty(t, tb.errors)
+
+		content, err := os.ReadFile(filepath.Join(config.Dir, "items.snap"))
+		require.NoError(t, err)
+		assert.Contains(t, string(content), `"total": 0`)
+	})
+}
@@ -0,0 +1,181 @@
+package gocurl
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+var spoolDefaults struct {
+	sync.RWMutex
+	threshold int64
+	dir       string
+}
+
+// SetSpoolThreshold spools response bodies larger than threshold bytes to a
+// temporary file in dir (the system default when empty) for every request
+// that does not set its own RequestOptions.SpoolThreshold, including those
+// made by Curl and the other command helpers. Spooled bodies are returned
+// empty by the string and byte helpers and read from resp.Body instead.
+// Zero disables spooling.
+func SetSpoolThreshold(threshold int64, dir string) {
+	spoolDefaults.Lock()
+	defer spoolDefaults.Unlock()
+	spoolDefaults.threshold = threshold
+	spoolDefaults.dir = dir
+}
+
+// spoolSettings returns the spool threshold and directory for opts.
+func spoolSettings(opts *options.RequestOptions) (int64, string) {
+	if opts.SpoolThreshold != 0 {
+		return opts.SpoolThreshold, opts.SpoolDir
+	}
+	spoolDefaults.RLock()
+	defer spoolDefaults.RUnlock()
+	return spoolDefaults.threshold, spoolDefaults.dir
+}
+
+// spooledBody is a response body that outgrew the spool threshold and was
+// written to a temporary file. It reads the file lazily and removes it when
+// closed, or when it is garbage collected if the caller never closes it.
+type spooledBody struct {
+	file   *os.File
+	size   int64
+	reader *io.SectionReader
+
+	once sync.Once
+	err  error
+}
+
+func newSpooledBody(file *os.File, size int64) *spooledBody {
+	s := &spooledBody{file: file, size: size, reader: io.NewSectionReader(file, 0, size)}
+	runtime.SetFinalizer(s, (*spooledBody).Close)
+	return s
+}
+
+// readBody reads the response body from r into buf. Once more than threshold
+// bytes have been read, the body read so far is moved out of buf into a
+// temporary file in dir and the rest is streamed there as well. A threshold
+// of zero or less never spools.
+func readBody(r io.Reader, buf *bytes.Buffer, threshold int64, dir string) (*spooledBody, error) {
+	if threshold <= 0 {
+		_, err := buf.ReadFrom(r)
+		return nil, err
+	}
+
+	offset := buf.Len()
+	n, err := io.CopyN(buf, r, threshold+1)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil || n <= threshold {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp(dir, "gocurl-body-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %v", err)
+	}
+	head, err := file.Write(buf.Bytes()[offset:])
+	buf.Truncate(offset)
+	var rest int64
+	if err == nil {
+		rest, err = io.Copy(file, r)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return newSpooledBody(file, int64(head)+rest), nil
+}
+
+// Read reads the next part of the body from the spool file.
+func (s *spooledBody) Read(p []byte) (int, error) {
+	return s.reader.Read(p)
+}
+
+// Close closes and removes the spool file.
+func (s *spooledBody) Close() error {
+	s.once.Do(func() {
+		runtime.SetFinalizer(s, nil)
+		s.err = s.file.Close()
+		os.Remove(s.file.Name())
+	})
+	return s.err
+}
+
+// open returns an independent reader over the whole body, leaving the
+// position of Read untouched.
+func (s *spooledBody) open() io.Reader {
+	return io.NewSectionReader(s.file, 0, s.size)
+}
+
+// output writes the body to the output file of opts, or to stdout unless
+// opts is silent, streaming it from the spool file.
+func (s *spooledBody) output(opts *options.RequestOptions) error {
+	if opts.OutputFile != "" {
+		path, err := outputPath(opts)
+		if err == nil {
+			err = writeOutputFile(path, s.open())
+		}
+		if err != nil {
+			return &Error{Kind: KindWrite, URL: opts.URL, Err: err}
+		}
+	} else if !opts.Silent {
+		if _, err := io.Copy(os.Stdout, s.open()); err != nil {
+			return &Error{Kind: KindWrite, URL: opts.URL, Err: fmt.Errorf("failed to write response to stdout: %v", err)}
+		}
+	}
+	return nil
+}
+
+// loadSpooled returns body, or the body of resp when it was left spooled on
+// disk, read into memory for the callers needing all of it at once, such as
+// the decoders of whole documents. resp.Body then reads the loaded body and
+// the spool file is removed.
+func loadSpooled(resp *http.Response, body string) (string, error) {
+	if resp == nil || !isSpooled(resp) {
+		return body, nil
+	}
+	spool := resp.Body.(*spooledBody)
+	defer spool.Close()
+	var b strings.Builder
+	b.Grow(int(spool.size))
+	if _, err := io.Copy(&b, spool.open()); err != nil {
+		return "", fmt.Errorf("failed to read spooled response body: %v", err)
+	}
+	resp.Body = io.NopCloser(strings.NewReader(b.String()))
+	return b.String(), nil
+}
+
+// loadSpooledBytes is loadSpooled for the bodies returned by CurlBytes.
+func loadSpooledBytes(resp *http.Response, body []byte) ([]byte, error) {
+	if resp == nil || !isSpooled(resp) {
+		return body, nil
+	}
+	s, err := loadSpooled(resp, "")
+	return []byte(s), err
+}
+
+// bodySize returns the size of the body of resp, returned as body unless it
+// was spooled.
+func bodySize(resp *http.Response, body string) int64 {
+	if spool, ok := resp.Body.(*spooledBody); ok {
+		return spool.size
+	}
+	return int64(len(body))
+}
+
+// isSpooled reports whether the body of resp was left spooled on disk.
+func isSpooled(resp *http.Response) bool {
+	_, ok := resp.Body.(*spooledBody)
+	return ok
+}
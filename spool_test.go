@@ -0,0 +1,138 @@
+package gocurl_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpoolLargeBodies(t *testing.T) {
+	payload := `{"data":"` + strings.Repeat("x", 64<<10) + `"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(payload))
+	}))
+	defer server.Close()
+
+	spoolFiles := func(t *testing.T, dir string) int {
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		return len(entries)
+	}
+
+	t.Run("Process reads the spool lazily", func(t *testing.T) {
+		dir := t.TempDir()
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(server.URL).
+			SetSilent(true).
+			SetSpoolThreshold(1024, dir).
+			Build()
+
+		resp, body, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Empty(t, body)
+		assert.Equal(t, 1, spoolFiles(t, dir))
+
+		streamed, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, payload, string(streamed))
+
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, 0, spoolFiles(t, dir))
+	})
+
+	t.Run("small bodies stay in memory", func(t *testing.T) {
+		dir := t.TempDir()
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(server.URL).
+			SetSilent(true).
+			SetSpoolThreshold(int64(len(payload)), dir).
+			Build()
+
+		_, body, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, payload, body)
+		assert.Equal(t, 0, spoolFiles(t, dir))
+	})
+
+	t.Run("output file", func(t *testing.T) {
+		dir := t.TempDir()
+		out := filepath.Join(t.TempDir(), "body.json")
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(server.URL).
+			SetOutputFile(out).
+			SetSpoolThreshold(1024, dir).
+			Build()
+
+		resp, body, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Empty(t, body)
+		resp.Body.Close()
+
+		written, err := os.ReadFile(out)
+		require.NoError(t, err)
+		assert.Equal(t, payload, string(written))
+	})
+
+	t.Run("command helpers", func(t *testing.T) {
+		dir := t.TempDir()
+		gocurl.SetSpoolThreshold(1024, dir)
+		defer gocurl.SetSpoolThreshold(0, "")
+
+		var v struct {
+			Data string `json:"data"`
+		}
+		resp, err := gocurl.CurlJSON(context.Background(), &v, "curl "+server.URL)
+		require.NoError(t, err)
+		assert.Len(t, v.Data, 64<<10)
+		assert.Equal(t, 1, spoolFiles(t, dir))
+		require.NoError(t, resp.Body.Close())
+
+		resp, body, err := gocurl.CurlBytes(context.Background(), "curl "+server.URL)
+		require.NoError(t, err)
+		assert.Empty(t, body)
+		streamed, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, payload, string(streamed))
+		require.NoError(t, resp.Body.Close())
+
+		resp, str, err := gocurl.CurlString(context.Background(), "curl "+server.URL)
+		require.NoError(t, err)
+		assert.Empty(t, str)
+		assert.Equal(t, 1, spoolFiles(t, dir))
+		streamed, err = ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, payload, string(streamed))
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, 0, spoolFiles(t, dir))
+	})
+	t.Run("decoders read spooled bodies", func(t *testing.T) {
+		dir := t.TempDir()
+		gocurl.SetSpoolThreshold(1024, dir)
+		defer gocurl.SetSpoolThreshold(0, "")
+
+		var v struct {
+			Data string `json:"data"`
+		}
+		client := gocurl.NewClient()
+		_, err := client.CurlJSON(context.Background(), &v, "curl "+server.URL)
+		require.NoError(t, err)
+		assert.Len(t, v.Data, 64<<10)
+
+		v.Data = ""
+		_, err = client.CurlDecode(context.Background(), &v, "curl "+server.URL)
+		require.NoError(t, err)
+		assert.Len(t, v.Data, 64<<10)
+		assert.Equal(t, 0, spoolFiles(t, dir))
+	})
+}
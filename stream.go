@@ -0,0 +1,58 @@
+package gocurl
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// executeStream sends the request described by opts and passes the
+// response to handle with its body unread, for helpers that consume large
+// bodies as they arrive. The body is closed once handle returns. Error
+// statuses fail the request with an *HTTPError without calling handle.
+// Recorders are not called, as the body is never held.
+func executeStream(ctx context.Context, opts *options.RequestOptions, handle func(resp *http.Response) error) (*http.Response, error) {
+	opts = withDefaults(ctx, opts)
+	if err := ValidateOptions(opts); err != nil {
+		return nil, err
+	}
+	client, err := CreateHTTPClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withTimeouts(ctx, opts)
+	defer cancel()
+	ctx, state := withRequestState(ctx, opts)
+
+	req, err := prepareRequest(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ExecuteRequestWithRetryAfter(client, req, opts)
+	if err != nil {
+		state.finish(nil, req.ContentLength, 0)
+		return nil, wrapError(err, req.URL.String())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		state.finish(resp, req.ContentLength, 0)
+		return resp, &Error{Kind: KindHTTP, URL: req.URL.String(), Err: &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}}
+	}
+	body := &countingReader{r: resp.Body}
+	resp.Body = body
+	err = handle(resp)
+	state.total = time.Since(state.start)
+	state.finish(resp, req.ContentLength, atomic.LoadInt64(&body.n))
+	if err == nil {
+		err = saveETag(req, resp, opts)
+	}
+	if err == nil {
+		err = recordAltSvc(req, resp, opts)
+	}
+	return resp, err
+}
@@ -0,0 +1,110 @@
+package gocurl
+
+import (
+	"crypto/tls"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// TLSSessionCache is a TLS session cache shared by the connections of a
+// Client, so that new connections to a host resume a previous session
+// instead of doing a full handshake. Resumption can be disabled for some
+// hosts, e.g. those load balancing over servers not sharing session
+// tickets. It counts its hits and misses to measure the handshakes saved.
+type TLSSessionCache struct {
+	cache tls.ClientSessionCache
+
+	mu       sync.RWMutex
+	disabled []string
+
+	hits, misses int64
+}
+
+// NewTLSSessionCache creates a TLSSessionCache holding the sessions of up
+// to capacity hosts, or of a default number of hosts when capacity is zero
+// or less.
+func NewTLSSessionCache(capacity int) *TLSSessionCache {
+	return &TLSSessionCache{cache: tls.NewLRUClientSessionCache(capacity)}
+}
+
+// SetTLSSessionCache makes the connections of the client share cache, for
+// requests that do not set their own TLSSessionCache or disable session
+// resumption. It must be set before the client's first request, as
+// connection settings are fixed once its transports are created. cache is
+// usually a *TLSSessionCache, though any comparable implementation works.
+func (c *Client) SetTLSSessionCache(cache tls.ClientSessionCache) *Client {
+	c.tlsSessions = cache
+	return c
+}
+
+// DisableHosts disables resuming the sessions of the hosts matching
+// patterns, matched like the hosts of a Policy: "example.com",
+// "*.example.com" or "10.0.0.0/8".
+func (c *TLSSessionCache) DisableHosts(patterns ...string) *TLSSessionCache {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disabled = append(c.disabled, patterns...)
+	return c
+}
+
+// Get returns the session cached for sessionKey, the server name or address
+// of the connection.
+func (c *TLSSessionCache) Get(sessionKey string) (*tls.ClientSessionState, bool) {
+	if c.isDisabled(sessionKey) {
+		return nil, false
+	}
+	session, ok := c.cache.Get(sessionKey)
+	if ok {
+		atomic.AddInt64(&c.hits, 1)
+	} else {
+		atomic.AddInt64(&c.misses, 1)
+	}
+	return session, ok
+}
+
+// Put caches session for sessionKey, or removes it when session is nil.
+func (c *TLSSessionCache) Put(sessionKey string, session *tls.ClientSessionState) {
+	if c.isDisabled(sessionKey) {
+		return
+	}
+	c.cache.Put(sessionKey, session)
+}
+
+// Stats returns the number of handshakes that found a session to resume and
+// of those that did not. A hit is not a guaranteed resumption, as the server
+// may refuse the session; Result.TLSResumed tells whether it did.
+func (c *TLSSessionCache) Stats() (hits, misses int64) {
+	return atomic.LoadInt64(&c.hits), atomic.LoadInt64(&c.misses)
+}
+
+func (c *TLSSessionCache) isDisabled(sessionKey string) bool {
+	host := sessionKey
+	if h, _, err := net.SplitHostPort(sessionKey); err == nil {
+		host = h
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return matchHosts(c.disabled, host)
+}
+
+// sessionTLSConfig returns the TLS configuration of opts with its session
+// resumption settings applied, leaving opts.TLSConfig untouched.
+func sessionTLSConfig(opts *options.RequestOptions) *tls.Config {
+	if opts.TLSSessionCache == nil && !opts.NoTLSSessionResumption {
+		return opts.TLSConfig
+	}
+	config := &tls.Config{}
+	if opts.TLSConfig != nil {
+		config = opts.TLSConfig.Clone()
+	}
+	if opts.NoTLSSessionResumption {
+		config.ClientSessionCache = nil
+		config.SessionTicketsDisabled = true
+	} else {
+		config.ClientSessionCache = opts.TLSSessionCache
+	}
+	return config
+}
@@ -0,0 +1,49 @@
+package gocurl_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSSessionResumption(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	// Every request needs a new connection, and so a handshake
+	server.Config.SetKeepAlivesEnabled(false)
+	server.StartTLS()
+	defer server.Close()
+	ctx := context.Background()
+
+	resumed := func(client *gocurl.Client, args ...string) []bool {
+		var results []bool
+		for i := 0; i < 2; i++ {
+			var result gocurl.Result
+			_, _, err := client.Curl(gocurl.WithResult(ctx, &result), append([]string{"-s", "-k"}, append(args, server.URL)...)...)
+			require.NoError(t, err)
+			results = append(results, result.TLSResumed)
+		}
+		return results
+	}
+
+	cache := gocurl.NewTLSSessionCache(0)
+	assert.Equal(t, []bool{false, true}, resumed(gocurl.NewClient().SetTLSSessionCache(cache)))
+	hits, misses := cache.Stats()
+	assert.EqualValues(t, 1, hits)
+	assert.EqualValues(t, 1, misses)
+
+	assert.Equal(t, []bool{false, false}, resumed(gocurl.NewClient()), "no cache")
+	assert.Equal(t, []bool{false, false}, resumed(gocurl.NewClient().SetTLSSessionCache(gocurl.NewTLSSessionCache(0)), "--no-sessionid"))
+
+	disabled := gocurl.NewTLSSessionCache(0).DisableHosts("127.0.0.0/8")
+	assert.Equal(t, []bool{false, false}, resumed(gocurl.NewClient().SetTLSSessionCache(disabled)))
+	hits, misses = disabled.Stats()
+	assert.Zero(t, hits+misses)
+}
@@ -0,0 +1,35 @@
+package tokenizer
+
+import "strings"
+
+// Quote quotes arg for a POSIX shell, so that Split reads it back as a
+// single argument. Arguments made of safe characters are left bare and the
+// others are single-quoted.
+func Quote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	if strings.IndexFunc(arg, unsafeShellRune) < 0 {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// Join quotes args with Quote and joins them with spaces. It is the inverse
+// of Split.
+func Join(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = Quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// unsafeShellRune reports whether r must be quoted in a shell word.
+func unsafeShellRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	}
+	return !strings.ContainsRune("@%+=:,./_-", r)
+}
@@ -0,0 +1,46 @@
+package tokenizer_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/maniartech/gocurl/tokenizer"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		arg      string
+		expected string
+	}{
+		{"https://example.com/a_b?x=1", `'https://example.com/a_b?x=1'`},
+		{"-H", "-H"},
+		{"user@host:8080/path,a+b=%20", "user@host:8080/path,a+b=%20"},
+		{"", "''"},
+		{"it's", `'it'\''s'`},
+		{"Content-Type: application/json", `'Content-Type: application/json'`},
+		{"$HOME ~ #", `'$HOME ~ #'`},
+		{"a\nb", "'a\nb'"},
+	}
+
+	for _, tt := range tests {
+		if got := tokenizer.Quote(tt.arg); got != tt.expected {
+			t.Errorf("Quote(%q) = %s, want %s", tt.arg, got, tt.expected)
+		}
+	}
+}
+
+func FuzzJoin(f *testing.F) {
+	f.Add("curl", "-d", `{"a":'b'}`)
+	f.Add("", "#x", "\\\n")
+
+	f.Fuzz(func(t *testing.T, a, b, c string) {
+		args := []string{a, b, c}
+		got, err := tokenizer.Split(tokenizer.Join(args))
+		if err != nil {
+			t.Fatalf("Split(Join(%q)) error = %v", args, err)
+		}
+		if !reflect.DeepEqual(got, args) {
+			t.Fatalf("Split(Join(%q)) = %q", args, got)
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package tokenizer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Split breaks a command string into arguments the way a POSIX shell would,
+// removing quotes and escapes. It understands single and double quotes,
+// backslash escapes, line continuations, comments and bash's $'...' ANSI-C
+// quoting, as produced by browsers' "Copy as cURL". Variables are left
+// unexpanded.
+func Split(command string) ([]string, error) {
+	var args []string
+	var current []byte
+	inWord := false
+
+	for i := 0; i < len(command); i++ {
+		char := command[i]
+
+		switch {
+		case char == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unmatched ' quote")
+			}
+			current = append(current, command[i+1:i+1+end]...)
+			i += end + 1
+			inWord = true
+		case char == '"':
+			n, err := readDoubleQuoted(command[i+1:], &current)
+			if err != nil {
+				return nil, err
+			}
+			i += n
+			inWord = true
+		case char == '$' && i+1 < len(command) && command[i+1] == '\'':
+			n, err := readANSIQuoted(command[i+2:], &current)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 1
+			inWord = true
+		case char == '$' && i+1 < len(command) && command[i+1] == '"':
+			// $"..." is translated by bash, which is a no-op without a
+			// message catalog
+			n, err := readDoubleQuoted(command[i+2:], &current)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 1
+			inWord = true
+		case char == '\\':
+			if i+1 == len(command) {
+				return nil, fmt.Errorf("unfinished escape sequence at end of command")
+			}
+			if n := lineContinuation(command[i+1:]); n > 0 {
+				i += n
+				continue
+			}
+			i++
+			current = append(current, command[i])
+			inWord = true
+		case char == '#' && !inWord:
+			// A comment runs to the end of the line
+			end := strings.IndexByte(command[i:], '\n')
+			if end < 0 {
+				i = len(command)
+			} else {
+				i += end
+			}
+		case char == ' ' || char == '\t' || char == '\n' || char == '\r':
+			if inWord {
+				args = append(args, string(current))
+				current = current[:0]
+				inWord = false
+			}
+		default:
+			current = append(current, char)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		args = append(args, string(current))
+	}
+	return args, nil
+}
+
+// lineContinuation returns the length of the newline following a backslash,
+// or 0 when s does not start with one.
+func lineContinuation(s string) int {
+	switch {
+	case strings.HasPrefix(s, "\n"):
+		return 1
+	case strings.HasPrefix(s, "\r\n"):
+		return 2
+	}
+	return 0
+}
+
+// readDoubleQuoted appends the contents of the double-quoted string s starts
+// with to current and returns the number of bytes read, including the
+// closing quote. Backslashes only escape ", \, $, ` and newlines.
+func readDoubleQuoted(s string, current *[]byte) (int, error) {
+	for i := 0; i < len(s); i++ {
+		switch char := s[i]; {
+		case char == '"':
+			return i + 1, nil
+		case char == '\\' && i+1 < len(s):
+			if n := lineContinuation(s[i+1:]); n > 0 {
+				i += n
+			} else if strings.IndexByte("\"\\$`", s[i+1]) >= 0 {
+				i++
+				*current = append(*current, s[i])
+			} else {
+				*current = append(*current, char)
+			}
+		default:
+			*current = append(*current, char)
+		}
+	}
+	return 0, fmt.Errorf("unmatched \" quote")
+}
+
+// ansiEscapes are the single character escapes of $'...' strings.
+var ansiEscapes = map[byte]byte{
+	'a': '\a', 'b': '\b', 'e': 0x1b, 'E': 0x1b, 'f': '\f', 'n': '\n', 'r': '\r',
+	't': '\t', 'v': '\v', '\\': '\\', '\'': '\'', '"': '"', '?': '?',
+}
+
+// readANSIQuoted appends the decoded contents of the $'...' string s starts
+// with, after the opening quote, to current and returns the number of bytes
+// read, including the closing quote.
+func readANSIQuoted(s string, current *[]byte) (int, error) {
+	for i := 0; i < len(s); i++ {
+		char := s[i]
+		if char == '\'' {
+			return i + 1, nil
+		}
+		if char != '\\' || i+1 == len(s) {
+			*current = append(*current, char)
+			continue
+		}
+
+		i++
+		escape := s[i]
+		if b, ok := ansiEscapes[escape]; ok {
+			*current = append(*current, b)
+			continue
+		}
+		switch escape {
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			n := digits(s[i:], 3, 8)
+			value, _ := strconv.ParseUint(s[i:i+n], 8, 16)
+			*current = append(*current, byte(value))
+			i += n - 1
+		case 'x', 'u', 'U':
+			max := 2
+			if escape == 'u' {
+				max = 4
+			} else if escape == 'U' {
+				max = 8
+			}
+			n := digits(s[i+1:], max, 16)
+			if n == 0 {
+				*current = append(*current, '\\', escape)
+				continue
+			}
+			value, _ := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+			if escape == 'x' {
+				*current = append(*current, byte(value))
+			} else {
+				*current = utf8.AppendRune(*current, rune(value))
+			}
+			i += n
+		case 'c':
+			// Control character, as in \cA for 0x01
+			if i+1 < len(s) {
+				i++
+				*current = append(*current, s[i]&0x1f)
+			} else {
+				*current = append(*current, '\\', escape)
+			}
+		default:
+			// Unknown escapes are kept as they are
+			*current = append(*current, '\\', escape)
+		}
+	}
+	return 0, fmt.Errorf("unmatched $' quote")
+}
+
+// digits returns the number of leading digits of s in base, up to max.
+func digits(s string, max, base int) int {
+	n := 0
+	for n < len(s) && n < max {
+		if _, err := strconv.ParseUint(s[n:n+1], base, 8); err != nil {
+			break
+		}
+		n++
+	}
+	return n
+}
@@ -0,0 +1,135 @@
+package tokenizer_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl/tokenizer"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		expected []string
+	}{
+		{
+			name:     "Plain arguments",
+			command:  "curl -X POST https://api.example.com",
+			expected: []string{"curl", "-X", "POST", "https://api.example.com"},
+		},
+		{
+			name:     "Single quotes are literal",
+			command:  `curl -H 'Authorization: Bearer $TOKEN' -d '{"a":"b \"c\""}'`,
+			expected: []string{"curl", "-H", "Authorization: Bearer $TOKEN", "-d", `{"a":"b \"c\""}`},
+		},
+		{
+			name:     "Double quotes honour escapes",
+			command:  `curl -d "{\"key\":\"va\\lue\"}" "$API_URL"`,
+			expected: []string{"curl", "-d", `{"key":"va\lue"}`, "$API_URL"},
+		},
+		{
+			name:     "Line continuations and empty arguments",
+			command:  "curl \\\n  -H 'X-Empty;' \\\r\n  -d '' https://api.example.com",
+			expected: []string{"curl", "-H", "X-Empty;", "-d", "", "https://api.example.com"},
+		},
+		{
+			name:     "Adjacent quoted parts form one argument",
+			command:  `curl "https://"'api.example.com'/data\ 1`,
+			expected: []string{"curl", "https://api.example.com/data 1"},
+		},
+		{
+			name:     "Nested and escaped quotes",
+			command:  `curl -d "it's \"quoted\"" -H 'say "hi"' -d 'don'\''t'`,
+			expected: []string{"curl", "-d", `it's "quoted"`, "-H", `say "hi"`, "-d", "don't"},
+		},
+		{
+			name:     "ANSI-C quoting",
+			command:  `curl --data-raw $'{"a":"line\nnext\t\'q\' \x41\101\u20ac\cA"}' $'\z'`,
+			expected: []string{"curl", "--data-raw", "{\"a\":\"line\nnext\t'q' AA\u20ac\x01\"}", `\z`},
+		},
+		{
+			name:     "Locale quoting and literal dollars",
+			command:  `curl $"https://example.com" "$HOST" $`,
+			expected: []string{"curl", "https://example.com", "$HOST", "$"},
+		},
+		{
+			name:     "Embedded newlines and comments",
+			command:  "# fetch the user\ncurl 'https://example.com/#top' \\\n  -d 'a\nb' # trailing comment\n",
+			expected: []string{"curl", "https://example.com/#top", "-d", "a\nb"},
+		},
+		{
+			name: "Copy as cURL from a browser",
+			command: "curl 'https://api.example.com/graphql' \\\n" +
+				"  -H 'accept: */*' \\\n" +
+				"  -H 'cookie: a=1; b=\"2\"' \\\n" +
+				"  --data-raw $'{\"query\":\"{ user(id: \\'1\\') }\"}' \\\n" +
+				"  --compressed",
+			expected: []string{"curl", "https://api.example.com/graphql", "-H", "accept: */*", "-H", `cookie: a=1; b="2"`,
+				"--data-raw", `{"query":"{ user(id: '1') }"}`, "--compressed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := tokenizer.Split(tt.command)
+			if err != nil {
+				t.Fatalf("Split() error = %v", err)
+			}
+			if !reflect.DeepEqual(args, tt.expected) {
+				t.Errorf("Split() got = %q, want %q", args, tt.expected)
+			}
+		})
+	}
+
+	for _, command := range []string{"curl 'unterminated", `curl "unterminated`, `curl \`, `curl $'unterminated`} {
+		if _, err := tokenizer.Split(command); err == nil {
+			t.Errorf("Split(%q) error = nil, want error", command)
+		}
+	}
+}
+
+// quote formats args for a POSIX shell, using every quoting style so Split
+// is exercised with all of them.
+func quote(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		switch i % 3 {
+		case 0:
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		case 1:
+			quoted[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(arg) + `"`
+		default:
+			var b strings.Builder
+			b.WriteString("$'")
+			for j := 0; j < len(arg); j++ {
+				fmt.Fprintf(&b, `\x%02x`, arg[j])
+			}
+			b.WriteString("'")
+			quoted[i] = b.String()
+		}
+	}
+	return strings.Join(quoted, " \\\n ")
+}
+
+func FuzzSplit(f *testing.F) {
+	f.Add("curl", "-H", "X-Test: 'a' \"b\" $c")
+	f.Add("", "\\\n", "$'\\x41'")
+	f.Add("#", "\r\n", "\x00\xff")
+
+	f.Fuzz(func(t *testing.T, a, b, c string) {
+		args := []string{a, b, c}
+		got, err := tokenizer.Split(quote(args))
+		if err != nil {
+			t.Fatalf("Split(quote(%q)) error = %v", args, err)
+		}
+		if !reflect.DeepEqual(got, args) {
+			t.Fatalf("Split(quote(%q)) = %q", args, got)
+		}
+
+		// Arbitrary input must never panic
+		tokenizer.Split(a + b + c)
+	})
+}
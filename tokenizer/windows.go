@@ -0,0 +1,104 @@
+package tokenizer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// windowsPattern matches what only cmd.exe commands contain: a caret ending
+// a line or escaping the quote a word starts with.
+var windowsPattern = regexp.MustCompile(`\^\r?\n|(^|[ \t])\^"`)
+
+// IsWindowsCommand reports whether command uses the cmd.exe syntax of
+// browsers' "Copy as cURL (cmd)", with ^ escapes and line continuations.
+func IsWindowsCommand(command string) bool {
+	return windowsPattern.MatchString(command)
+}
+
+// SplitWindows breaks a cmd.exe command string into arguments. Carets are
+// removed as cmd.exe does, then arguments are split on whitespace outside
+// double quotes. Backslashes escape double quotes and backslashes, as
+// browsers write them, and "" in a quoted argument is a literal quote. A
+// leading curl.exe becomes curl.
+func SplitWindows(command string) ([]string, error) {
+	line, err := unescapeCmd(command)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	var current strings.Builder
+	inWord, quoted := false, false
+	for i := 0; i < len(line); i++ {
+		char := line[i]
+		switch {
+		case char == '\\' && i+1 < len(line) && (line[i+1] == '"' || line[i+1] == '\\'):
+			i++
+			current.WriteByte(line[i])
+			inWord = true
+		case char == '"':
+			if quoted && i+1 < len(line) && line[i+1] == '"' {
+				current.WriteByte('"')
+				i++
+			} else {
+				quoted = !quoted
+			}
+			inWord = true
+		case !quoted && (char == ' ' || char == '\t' || char == '\n' || char == '\r'):
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteByte(char)
+			inWord = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unmatched \" quote")
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+
+	if len(args) > 0 && strings.EqualFold(args[0], "curl.exe") {
+		args[0] = "curl"
+	}
+	return args, nil
+}
+
+// unescapeCmd removes the carets cmd.exe interprets outside of double
+// quotes. A caret escapes the next character; before a line break it
+// continues the line, or stands for a newline when the break is doubled.
+func unescapeCmd(command string) (string, error) {
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(command); i++ {
+		char := command[i]
+		switch {
+		case char == '"':
+			quoted = !quoted
+			b.WriteByte(char)
+		case char == '^' && !quoted:
+			if i+1 == len(command) {
+				return "", fmt.Errorf("unfinished escape sequence at end of command")
+			}
+			i++
+			if n := lineContinuation(command[i:]); n > 0 {
+				i += n - 1
+				// A doubled line break is a newline within the argument
+				if n = lineContinuation(command[i+1:]); n > 0 {
+					b.WriteByte('\n')
+					i += n
+				}
+				continue
+			}
+			b.WriteByte(command[i])
+		default:
+			b.WriteByte(char)
+		}
+	}
+	return b.String(), nil
+}
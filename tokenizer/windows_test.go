@@ -0,0 +1,63 @@
+package tokenizer_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/maniartech/gocurl/tokenizer"
+)
+
+func TestSplitWindows(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		expected []string
+	}{
+		{
+			name: "Chrome Copy as cURL (cmd)",
+			command: "curl ^\"https://api.example.com/search?q=a^%^20b^&page=1^\" ^\r\n" +
+				"  -H ^\"accept: application/json^\" ^\r\n" +
+				"  -H ^\"cookie: id=^\\^\"x^\\^\"^\" ^\r\n" +
+				"  --data-raw ^\"^{^\\^\"path^\\^\":^\\^\"C:^\\^\\dir^\\^\",^\\^\"text^\\^\":^\\^\"line 1^\n\nline 2^\\^\"^}^\" ^\r\n" +
+				"  --compressed",
+			expected: []string{"curl", "https://api.example.com/search?q=a%20b&page=1", "-H", "accept: application/json",
+				"-H", `cookie: id="x"`, "--data-raw", "{\"path\":\"C:\\dir\",\"text\":\"line 1\nline 2\"}", "--compressed"},
+		},
+		{
+			name:     "Plain double quotes with doubled quotes",
+			command:  "curl.exe \"https://example.com\" ^\n -d \"{\"\"a\"\":1}\" -H \"X-Empty:\" \"\"",
+			expected: []string{"curl", "https://example.com", "-d", `{"a":1}`, "-H", "X-Empty:", ""},
+		},
+		{
+			name:     "Carets are literal within quotes",
+			command:  `curl ^"https://example.com^" -d "a^b"`,
+			expected: []string{"curl", "https://example.com", "-d", "a^b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tokenizer.IsWindowsCommand(tt.command) {
+				t.Fatalf("IsWindowsCommand(%q) = false", tt.command)
+			}
+			args, err := tokenizer.SplitWindows(tt.command)
+			if err != nil {
+				t.Fatalf("SplitWindows() error = %v", err)
+			}
+			if !reflect.DeepEqual(args, tt.expected) {
+				t.Errorf("SplitWindows() got = %q, want %q", args, tt.expected)
+			}
+		})
+	}
+
+	for _, command := range []string{`curl 'https://example.com' -d '^"x'`, `curl "https://example.com/^a"`, "curl https://example.com"} {
+		if tokenizer.IsWindowsCommand(command) {
+			t.Errorf("IsWindowsCommand(%q) = true, want false", command)
+		}
+	}
+	for _, command := range []string{`curl ^"unterminated`, `curl ^"x^" ^`} {
+		if _, err := tokenizer.SplitWindows(command); err == nil {
+			t.Errorf("SplitWindows(%q) error = nil, want error", command)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package gocurl
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// streamedBody is a request body read from disk while it is sent.
+type streamedBody interface {
+	open() (io.ReadCloser, error)
+	contentLength() int64
+}
+
+// uploadBody is a request body streamed from a file, as sent by curl -T.
+type uploadBody struct {
+	path string
+	size int64 // -1 when the file size is unknown
+	mmap bool
+}
+
+func newUploadBody(path string, mmap bool) (*uploadBody, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file for upload: %v", err)
+	}
+	size := int64(-1)
+	if info.Mode().IsRegular() {
+		size = info.Size()
+	}
+	return &uploadBody{path: path, size: size, mmap: mmap}, nil
+}
+
+func (u *uploadBody) contentLength() int64 {
+	return u.size
+}
+
+// open returns a fresh reader over the file. It can be called again to
+// resend the body, e.g. on retries.
+func (u *uploadBody) open() (io.ReadCloser, error) {
+	return openUploadFile(u.path, u.mmap && u.size > 0)
+}
+
+// openUploadFile opens path for reading. With mmap set, regular files are
+// memory-mapped where supported so the transport writes straight from the
+// page cache instead of copying through a read buffer; otherwise, or when
+// mapping fails, the file is read normally.
+func openUploadFile(path string, mmap bool) (io.ReadCloser, error) {
+	if mmap && strconv.IntSize == 64 {
+		if data, unmap, err := mmapFile(path); err == nil {
+			return &mappedReader{reader: bytes.NewReader(data), unmap: unmap}, nil
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file for upload: %v", err)
+	}
+	return file, nil
+}
+
+// mappedReader reads a memory-mapped file and unmaps it on Close. The
+// transport may close a request body while another goroutine still reads
+// it, so Close waits for the reads in progress, and reads after it fail
+// with os.ErrClosed rather than touch the unmapped memory.
+type mappedReader struct {
+	mu     sync.RWMutex
+	reader *bytes.Reader
+	unmap  func() error
+}
+
+func (r *mappedReader) Read(p []byte) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.unmap == nil {
+		return 0, os.ErrClosed
+	}
+	return r.reader.Read(p)
+}
+
+func (r *mappedReader) ReadAt(p []byte, off int64) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.unmap == nil {
+		return 0, os.ErrClosed
+	}
+	return r.reader.ReadAt(p, off)
+}
+
+func (r *mappedReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.unmap == nil {
+		return nil
+	}
+	err := r.unmap()
+	r.unmap = nil
+	return err
+}
+
+// newlineStripper drops carriage returns and newlines from r, as curl does
+// for data read with -d @file.
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (s *newlineStripper) Read(p []byte) (int, error) {
+	for {
+		n, err := s.r.Read(p)
+		n = len(stripNewlines(p[:n]))
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// stripNewlines removes carriage returns and newlines from data in place.
+func stripNewlines(data []byte) []byte {
+	out := data[:0]
+	for _, b := range data {
+		if b != '\r' && b != '\n' {
+			out = append(out, b)
+		}
+	}
+	return out
+}
@@ -0,0 +1,192 @@
+package gocurl_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeUploadFile(tb testing.TB, size int) (string, [32]byte) {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	path := filepath.Join(tb.TempDir(), "payload.bin")
+	require.NoError(tb, os.WriteFile(path, data, 0644))
+	return path, sha256.Sum256(data)
+}
+
+func newUploadServer(tb testing.TB) (*httptest.Server, *[32]byte, *string) {
+	var digest [32]byte
+	var target string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hash := sha256.New()
+		io.Copy(hash, r.Body)
+		copy(digest[:], hash.Sum(nil))
+		target = r.Method + " " + r.URL.Path
+	}))
+	tb.Cleanup(server.Close)
+	return server, &digest, &target
+}
+
+func TestUploadFile(t *testing.T) {
+	path, sum := writeUploadFile(t, 1<<20)
+	server, digest, target := newUploadServer(t)
+
+	for _, mmap := range []bool{false, true} {
+		opts := options.NewRequestOptionsBuilder().
+			SetMethod("PUT").
+			SetURL(server.URL + "/files/payload.bin").
+			SetUploadFile(path).
+			SetMmapUploads(mmap).
+			SetSilent(true).
+			Build()
+
+		_, _, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, sum, *digest, "mmap=%v", mmap)
+	}
+
+	t.Run("Parsed from -T", func(t *testing.T) {
+		_, _, err := gocurl.Curl(context.Background(), "curl", "-s", "-T", path, server.URL+"/files/")
+		require.NoError(t, err)
+		assert.Equal(t, "PUT /files/payload.bin", *target)
+		assert.Equal(t, sum, *digest)
+	})
+
+	t.Run("Multipart uploads can be mapped too", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			file, _, err := r.FormFile("upload")
+			require.NoError(t, err)
+			content, _ := ioutil.ReadAll(file)
+			assert.Equal(t, sum, sha256.Sum256(content))
+		}))
+		defer server.Close()
+
+		opts := options.NewRequestOptionsBuilder().
+			SetMethod("POST").
+			SetURL(server.URL).
+			SetFileUpload(&options.FileUpload{FieldName: "upload", FileName: "payload.bin", FilePath: path}).
+			SetMmapUploads(true).
+			SetSilent(true).
+			Build()
+		_, _, err := gocurl.Process(context.Background(), opts)
+		require.NoError(t, err)
+	})
+}
+
+// withStdin runs fn with os.Stdin reading input.
+func withStdin(t *testing.T, input string, fn func()) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	go func() {
+		w.WriteString(input)
+		w.Close()
+	}()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+	fn()
+}
+
+func TestStdinBody(t *testing.T) {
+	var received, transferEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received = r.Method + " " + string(body)
+		transferEncoding = ""
+		if len(r.TransferEncoding) > 0 {
+			transferEncoding = r.TransferEncoding[0]
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+		chunked  bool
+	}{
+		{"Upload file from stdin", []string{"-T", "-"}, "PUT line 1\nline 2\n", true},
+		{"Data from stdin strips newlines", []string{"-d", "@-"}, "POST line 1line 2", true},
+		{"Binary data from stdin", []string{"--data-binary", "@-"}, "POST line 1\nline 2\n", true},
+		{"Data from stdin joined with other fields", []string{"-d", "a=1", "-d", "@-"}, "POST a=1&line 1line 2", false},
+		{"Raw data is not read", []string{"--data-raw", "@-"}, "POST @-", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withStdin(t, "line 1\nline 2\n", func() {
+				_, _, err := gocurl.CurlString(context.Background(), append(append([]string{"curl"}, tt.args...), server.URL)...)
+				require.NoError(t, err)
+			})
+			assert.Equal(t, tt.expected, received)
+			assert.Equal(t, tt.chunked, transferEncoding == "chunked")
+		})
+	}
+
+	t.Run("Data from a file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.txt")
+		require.NoError(t, os.WriteFile(path, []byte("a=1\r\n&b=2\n"), 0644))
+		_, _, err := gocurl.CurlString(context.Background(), "curl", "-d", "@"+path, server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "POST a=1&b=2", received)
+	})
+
+	t.Run("Streamed bodies are not resent", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer failing.Close()
+
+		opts := options.NewRequestOptionsBuilder().
+			SetMethod("PUT").
+			SetURL(failing.URL).
+			SetBodyReader(strings.NewReader("once")).
+			SetRetryConfig(&options.RetryConfig{MaxRetries: 2, RetryOnHTTP: []int{http.StatusServiceUnavailable}}).
+			SetSilent(true).
+			Build()
+		_, _, err := gocurl.Process(context.Background(), opts)
+		assert.ErrorContains(t, err, "a streamed body cannot be resent")
+	})
+}
+
+func benchmarkUpload(b *testing.B, mmap bool) {
+	const size = 32 << 20
+	path, _ := writeUploadFile(b, size)
+	server, _, _ := newUploadServer(b)
+	opts := options.NewRequestOptionsBuilder().
+		SetMethod("PUT").
+		SetURL(server.URL).
+		SetUploadFile(path).
+		SetMmapUploads(mmap).
+		SetSilent(true).
+		Build()
+
+	client := gocurl.NewClient()
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := client.Process(context.Background(), opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUploadFileRead and BenchmarkUploadFileMmap compare reading an
+// upload through os.File with writing it straight from a memory mapping.
+func BenchmarkUploadFileRead(b *testing.B) { benchmarkUpload(b, false) }
+func BenchmarkUploadFileMmap(b *testing.B) { benchmarkUpload(b, true) }
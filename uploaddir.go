@@ -0,0 +1,178 @@
+package gocurl
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DirUpload configures the archive of a directory uploaded by Upload.
+type DirUpload struct {
+	// Gzip compresses the archive into a tar.gz
+	Gzip bool
+
+	// Include, when set, restricts the archive to the entries matching one
+	// of its patterns, and Exclude leaves out the entries matching one of
+	// its patterns. Patterns have the syntax of path.Match; those without a
+	// slash match the names of entries at any depth, like "*.log", and those
+	// with one their paths from the directory, like "build/cache". A pattern
+	// matching a directory matches all of its entries.
+	Include []string
+	Exclude []string
+}
+
+// CurlUploadDir executes the curl command with the directory dir, archived
+// into a tar stream as it is sent, as the request body, for APIs accepting
+// archive uploads such as build contexts or function bundles. It is the
+// counterpart of CurlDownloadExtract.
+//
+// The archive is gzipped when the command's Content-Type is application/gzip
+// or its Content-Encoding gzip. The request is a PUT, like curl -T, unless
+// the command sets another method. Use a DirUpload to filter the entries.
+func CurlUploadDir(ctx context.Context, dir string, command ...string) (*http.Response, string, error) {
+	return (&DirUpload{}).Upload(ctx, dir, command...)
+}
+
+// Upload is CurlUploadDir for the archive configured by d.
+func (d *DirUpload) Upload(ctx context.Context, dir string, command ...string) (*http.Response, string, error) {
+	opts, err := parseCommand(command...)
+	if err != nil {
+		return nil, "", err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open directory for upload: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, "", fmt.Errorf("failed to open directory for upload: %s is not a directory", dir)
+	}
+
+	if opts.Headers == nil {
+		opts.Headers = http.Header{}
+	}
+	compress := d.Gzip || opts.Headers.Get("Content-Encoding") == "gzip"
+	switch opts.Headers.Get("Content-Type") {
+	case "application/gzip", "application/x-gzip":
+		compress = true
+	case "":
+		if compress {
+			opts.Headers.Set("Content-Type", "application/gzip")
+		} else {
+			opts.Headers.Set("Content-Type", "application/x-tar")
+		}
+	}
+	if opts.Method == "" || opts.Method == http.MethodGet {
+		opts.Method = http.MethodPut
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(d.writeArchive(pw, dir, compress))
+	}()
+	// Stops the archive when the request ends before it was sent
+	defer pr.Close()
+
+	opts.BodyReader = pr
+	opts.Body = ""
+	opts.Form, opts.FileUpload, opts.UploadFile = nil, nil, ""
+	return Process(ctx, opts)
+}
+
+// writeArchive writes the tar archive of dir to w.
+func (d *DirUpload) writeArchive(w io.Writer, dir string, compress bool) error {
+	if compress {
+		gz := gzip.NewWriter(w)
+		if err := d.writeTar(gz, dir); err != nil {
+			return err
+		}
+		return gz.Close()
+	}
+	return d.writeTar(w, dir)
+}
+
+func (d *DirUpload) writeTar(w io.Writer, dir string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if matchEntry(d.Exclude, rel) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(d.Include) > 0 && !matchEntry(d.Include, rel) {
+			// Directories are still walked for included entries
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = rel
+		if entry.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive directory: %v", err)
+	}
+	return tw.Close()
+}
+
+// matchEntry reports whether one of patterns matches the entry at the
+// slash separated path rel, or one of the directories containing it.
+func matchEntry(patterns []string, rel string) bool {
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		prefix := strings.Join(parts[:i+1], "/")
+		for _, pattern := range patterns {
+			name := prefix
+			if !strings.Contains(pattern, "/") {
+				name = parts[i]
+			}
+			if ok, _ := path.Match(strings.TrimSuffix(pattern, "/"), name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
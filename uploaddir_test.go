@@ -0,0 +1,108 @@
+package gocurl_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurlUploadDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"Dockerfile":            "FROM scratch",
+		"src/main.go":           "package main",
+		"src/app.log":           "log",
+		"node_modules/x/x.js":   "x",
+		"build/cache/blob":      "cache",
+		"build/output/app.wasm": "wasm",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	var method, contentType string
+	var entries []string
+	var files map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, contentType = r.Method, r.Header.Get("Content-Type")
+		var body io.Reader = r.Body
+		if contentType == "application/gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body = gz
+		}
+		entries, files = nil, map[string]string{}
+		tr := tar.NewReader(body)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			entries = append(entries, header.Name)
+			data, _ := io.ReadAll(tr)
+			files[header.Name] = string(data)
+		}
+		sort.Strings(entries)
+		io.WriteString(w, "accepted")
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	resp, body, err := gocurl.CurlUploadDir(ctx, dir, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "accepted", body)
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "application/x-tar", contentType)
+	assert.Equal(t, []string{
+		"Dockerfile", "build/", "build/cache/", "build/cache/blob", "build/output/", "build/output/app.wasm",
+		"node_modules/", "node_modules/x/", "node_modules/x/x.js", "src/", "src/app.log", "src/main.go",
+	}, entries)
+	assert.Equal(t, "package main", files["src/main.go"])
+
+	t.Run("Gzip and exclude", func(t *testing.T) {
+		upload := &gocurl.DirUpload{Gzip: true, Exclude: []string{"node_modules", "*.log", "build/cache"}}
+		_, _, err := upload.Upload(ctx, dir, "-X", "POST", server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodPost, method)
+		assert.Equal(t, "application/gzip", contentType)
+		assert.Equal(t, []string{"Dockerfile", "build/", "build/output/", "build/output/app.wasm", "src/", "src/main.go"}, entries)
+	})
+
+	t.Run("Include", func(t *testing.T) {
+		upload := &gocurl.DirUpload{Include: []string{"src", "Dockerfile"}, Exclude: []string{"*.log"}}
+		_, _, err := upload.Upload(ctx, dir, server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Dockerfile", "src/", "src/main.go"}, entries)
+	})
+
+	t.Run("Gzip from the Content-Type", func(t *testing.T) {
+		_, _, err := gocurl.CurlUploadDir(ctx, dir, "-H", "Content-Type: application/gzip", server.URL)
+		require.NoError(t, err)
+		assert.Contains(t, entries, "Dockerfile")
+	})
+
+	t.Run("Not a directory", func(t *testing.T) {
+		_, _, err := gocurl.CurlUploadDir(ctx, filepath.Join(dir, "Dockerfile"), server.URL)
+		assert.ErrorContains(t, err, "not a directory")
+	})
+}
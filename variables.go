@@ -0,0 +1,161 @@
+package gocurl
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/maniartech/gocurl/redact"
+)
+
+// Variables holds values for the $VAR and ${VAR} references of a command.
+// References to names missing from the map fall back to the environment.
+//
+// Braced references support the shell's default and error forms and a
+// chain of filters:
+//
+//	${VAR:-default}   default when VAR is unset or empty (${VAR-default}: unset only)
+//	${VAR:?message}   fails with message when VAR is unset or empty (${VAR?message}: unset only)
+//	${VAR|urlencode}  applies each |filter in turn
+//
+// References of the form ${namespace:argument} are resolved by the provider
+// registered for the namespace, see RegisterVariableProvider. As | starts
+// a filter and } ends the reference, arguments cannot contain either.
+//
+// The filters are urlencode (query escaping), pathescape, base64, base64url
+// (unpadded), upper, lower, trim and json (escaping for use inside a JSON
+// string).
+type Variables map[string]string
+
+// variableFilters are the filters of ${VAR|filter} references.
+var variableFilters = map[string]func(string) string{
+	"urlencode":  url.QueryEscape,
+	"pathescape": url.PathEscape,
+	"base64": func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	},
+	"base64url": func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"json": func(s string) string {
+		quoted, _ := json.Marshal(s)
+		return string(quoted[1 : len(quoted)-1])
+	},
+}
+
+// MarkSecret registers the values of the named variables, taken from v or
+// the environment, with redact.Default so they are masked in logs and error
+// messages. It returns v.
+func (v Variables) MarkSecret(names ...string) Variables {
+	for _, name := range names {
+		if value, ok := v.get(name); ok {
+			redact.Default.AddSecret(value)
+		}
+	}
+	return v
+}
+
+// Expand replaces variable references in s. References that fail, such as
+// ${VAR:?message} with VAR unset, expand to an empty string; use Resolve to
+// get their error.
+func (v Variables) Expand(s string) string {
+	expanded, _ := v.Resolve(s)
+	return expanded
+}
+
+// Resolve replaces variable references in s like Expand, returning the
+// first error of a ${VAR:?message} reference or an invalid expression.
+func (v Variables) Resolve(s string) (string, error) {
+	var firstErr error
+	expanded := os.Expand(s, func(ref string) string {
+		value, err := v.resolve(ref)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return value
+	})
+	return expanded, firstErr
+}
+
+// resolve returns the value of the reference ref, the text between the
+// braces of ${...} or the name following $.
+func (v Variables) resolve(ref string) (string, error) {
+	filters := strings.Split(ref, "|")
+	value, err := v.reference(strings.TrimSpace(filters[0]))
+	if err != nil {
+		return "", err
+	}
+	for _, name := range filters[1:] {
+		filter, ok := variableFilters[strings.TrimSpace(name)]
+		if !ok {
+			return "", fmt.Errorf("unknown variable filter %q in ${%s}", strings.TrimSpace(name), ref)
+		}
+		value = filter(value)
+	}
+	return value, nil
+}
+
+// reference evaluates a reference without its filters.
+func (v Variables) reference(expr string) (string, error) {
+	end := 0
+	for end < len(expr) && isNameByte(expr[end]) {
+		end++
+	}
+	name, rest := expr[:end], expr[end:]
+	if name == "" {
+		// Special parameters such as $1 or $@ are not supported
+		return "", nil
+	}
+	if op, ok := strings.CutPrefix(rest, ":"); ok && !strings.HasPrefix(op, "-") && !strings.HasPrefix(op, "?") {
+		if provider := variableProvider(name); provider != nil {
+			value, err := provider(op)
+			if err != nil {
+				return "", fmt.Errorf("variable ${%s}: %v", expr, err)
+			}
+			return value, nil
+		}
+	}
+	value, set := v.get(name)
+
+	colon := strings.HasPrefix(rest, ":")
+	op := strings.TrimPrefix(rest, ":")
+	missing := !set || colon && value == ""
+	switch {
+	case rest == "":
+		return value, nil
+	case strings.HasPrefix(op, "-"):
+		if missing {
+			return op[1:], nil
+		}
+		return value, nil
+	case strings.HasPrefix(op, "?"):
+		if missing {
+			message := op[1:]
+			if message == "" {
+				message = "parameter null or not set"
+			}
+			return "", fmt.Errorf("variable %s: %s", name, message)
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("bad substitution ${%s}", expr)
+}
+
+// get returns the value of name from v or the environment and whether it is
+// set.
+func (v Variables) get(name string) (string, bool) {
+	if value, ok := v[name]; ok {
+		return value, true
+	}
+	return os.LookupEnv(name)
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,80 @@
+package gocurl_test
+
+import (
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariables(t *testing.T) {
+	t.Setenv("GOCURL_TEST_HOST", "env.example.com")
+	vars := gocurl.Variables{"SCHEME": "https"}
+	assert.Equal(t, "https://env.example.com/", vars.Expand("${SCHEME}://$GOCURL_TEST_HOST/"))
+
+	t.Run("Defaults", func(t *testing.T) {
+		vars := gocurl.Variables{"EMPTY": "", "PORT": "8443"}
+		tests := map[string]string{
+			"${PORT:-80}":           "8443",
+			"${MISSING:-80}":        "80",
+			"${EMPTY:-80}":          "80",
+			"${EMPTY-80}":           "",
+			"${MISSING-80}":         "80",
+			"${MISSING:-}":          "",
+			"${MISSING:-a b/c?d=e}": "a b/c?d=e",
+			"${PORT:?port needed}":  "8443",
+			"${EMPTY?set}":          "",
+		}
+		for input, expected := range tests {
+			got, err := vars.Resolve(input)
+			require.NoError(t, err, input)
+			assert.Equal(t, expected, got, input)
+		}
+	})
+
+	t.Run("Required", func(t *testing.T) {
+		vars := gocurl.Variables{"EMPTY": ""}
+		_, err := vars.Resolve("Bearer ${GOCURL_TEST_TOKEN:?set GOCURL_TEST_TOKEN to your API token}")
+		assert.EqualError(t, err, "variable GOCURL_TEST_TOKEN: set GOCURL_TEST_TOKEN to your API token")
+		_, err = vars.Resolve("${EMPTY:?}")
+		assert.EqualError(t, err, "variable EMPTY: parameter null or not set")
+		assert.Equal(t, "Bearer ", vars.Expand("Bearer ${GOCURL_TEST_TOKEN:?missing}"))
+
+		_, err = gocurl.ArgsToOptionsWithVars([]string{"curl", "-H", "Authorization: Bearer ${GOCURL_TEST_TOKEN:?missing}", "https://example.com"}, vars)
+		assert.EqualError(t, err, "variable GOCURL_TEST_TOKEN: missing")
+	})
+
+	t.Run("Secrets", func(t *testing.T) {
+		t.Setenv("GOCURL_TEST_SECRET", "env-s3cret")
+		vars := gocurl.Variables{"PASSWORD": "vars-s3cret"}.MarkSecret("PASSWORD", "GOCURL_TEST_SECRET", "UNSET")
+		assert.Equal(t, "**** and ****", redact.Default.String("vars-s3cret and env-s3cret"))
+		assert.Equal(t, "vars-s3cret", vars.Expand("$PASSWORD"), "values are kept in commands")
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		vars := gocurl.Variables{"Q": "a b&c/é", "CREDS": "user:pass", "MSG": " say \"hi\"\n "}
+		tests := map[string]string{
+			"${Q|urlencode}":              "a+b%26c%2F%C3%A9",
+			"${Q|pathescape}":             "a%20b&c%2F%C3%A9",
+			"${CREDS|base64}":             "dXNlcjpwYXNz",
+			"${CREDS | base64url}":        "dXNlcjpwYXNz",
+			"${CREDS|upper}":              "USER:PASS",
+			"${MSG|trim|json}":            `say \"hi\"`,
+			"${MSG|json}":                 ` say \"hi\"\n `,
+			"${MISSING:-x y|urlencode}":   "x+y",
+			"${MISSING:-ab|base64|lower}": "ywi=",
+		}
+		for input, expected := range tests {
+			got, err := vars.Resolve(input)
+			require.NoError(t, err, input)
+			assert.Equal(t, expected, got, input)
+		}
+
+		_, err := vars.Resolve("${Q|rot13}")
+		assert.EqualError(t, err, `unknown variable filter "rot13" in ${Q|rot13}`)
+		_, err = vars.Resolve("${Q:1:2}")
+		assert.EqualError(t, err, "bad substitution ${Q:1:2}")
+	})
+}
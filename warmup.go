@@ -0,0 +1,74 @@
+package gocurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/maniartech/gocurl/options"
+)
+
+// Warmup pre-resolves DNS and opens connections to hosts (TLS handshake and
+// HTTP/2 session included) so that the first real request does not pay the
+// setup cost. hosts are host names, host:port pairs or URLs; a missing scheme
+// means https. The connections are pooled for requests using default
+// connection settings.
+func (c *Client) Warmup(ctx context.Context, hosts ...string) error {
+	return c.WarmupWith(ctx, options.NewRequestOptions(""), hosts...)
+}
+
+// WarmupWith is Warmup for requests sharing the connection settings (TLS,
+// proxy, HTTP version, compression) of opts.
+func (c *Client) WarmupWith(ctx context.Context, opts *options.RequestOptions, hosts ...string) error {
+	transport, err := c.transport(opts)
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			if err := warmupHost(ctx, transport, host); err != nil {
+				errs[i] = fmt.Errorf("warmup %s: %v", host, err)
+			}
+		}(i, host)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// warmupHost resolves host and sends a HEAD request through transport, which
+// leaves an idle connection in its pool.
+func warmupHost(ctx context.Context, transport http.RoundTripper, host string) error {
+	rawURL := host
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	req, err := http.NewRequestWithContext(ctx, "HEAD", rawURL, nil)
+	if err != nil {
+		return err
+	}
+
+	if hostname := req.URL.Hostname(); net.ParseIP(hostname) == nil {
+		if _, err := net.DefaultResolver.LookupHost(ctx, hostname); err != nil {
+			return err
+		}
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return resp.Body.Close()
+}
@@ -0,0 +1,79 @@
+package gocurl_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientWarmup(t *testing.T) {
+	var connections int64
+	newServer := func(tls bool) *httptest.Server {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Proto))
+		}))
+		server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				atomic.AddInt64(&connections, 1)
+			}
+		}
+		if tls {
+			server.EnableHTTP2 = true
+			server.StartTLS()
+		} else {
+			server.Start()
+		}
+		return server
+	}
+
+	t.Run("Connections are reused by later requests", func(t *testing.T) {
+		atomic.StoreInt64(&connections, 0)
+		server := newServer(false)
+		defer server.Close()
+
+		client := gocurl.NewClient()
+		require.NoError(t, client.Warmup(context.Background(), server.URL))
+		assert.Equal(t, int64(1), atomic.LoadInt64(&connections))
+
+		opts := options.NewRequestOptionsBuilder().SetURL(server.URL).SetSilent(true).Build()
+		_, _, err := client.Process(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), atomic.LoadInt64(&connections))
+	})
+
+	t.Run("TLS with HTTP/2", func(t *testing.T) {
+		atomic.StoreInt64(&connections, 0)
+		server := newServer(true)
+		defer server.Close()
+
+		opts := options.NewRequestOptionsBuilder().
+			SetURL(server.URL).
+			SetTLSConfig(server.Client().Transport.(*http.Transport).TLSClientConfig).
+			SetHTTP2(true).
+			SetSilent(true).
+			Build()
+
+		client := gocurl.NewClient()
+		require.NoError(t, client.WarmupWith(context.Background(), opts, server.URL))
+
+		_, body, err := client.Process(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, "HTTP/2.0", body)
+		assert.Equal(t, int64(1), atomic.LoadInt64(&connections))
+	})
+
+	t.Run("Failures are reported per host", func(t *testing.T) {
+		err := gocurl.NewClient().Warmup(context.Background(), "http://127.0.0.1:1", "no-such-host.invalid")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "warmup http://127.0.0.1:1")
+		assert.Contains(t, err.Error(), "warmup no-such-host.invalid")
+	})
+}
@@ -0,0 +1,69 @@
+package webhooks
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Provider identifies a webhook signature scheme.
+type Provider string
+
+const (
+	GitHub Provider = "github"
+	Stripe Provider = "stripe"
+	Slack  Provider = "slack"
+)
+
+// VerifyRequest reads the body of an inbound webhook request and verifies it
+// against the signature headers of the given provider. On success the body is
+// returned and r.Body is replaced so handlers can read it again.
+func VerifyRequest(r *http.Request, provider Provider, secret string, tolerance time.Duration) ([]byte, error) {
+	payload, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("webhooks: failed to read body: %v", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(payload))
+
+	switch provider {
+	case GitHub:
+		sig := r.Header.Get(GitHubSignatureHeader)
+		if sig == "" {
+			sig = r.Header.Get(GitHubSignatureHeaderSHA1)
+		}
+		err = VerifyGitHub(payload, sig, secret)
+	case Stripe:
+		err = VerifyStripe(payload, r.Header.Get(StripeSignatureHeader), secret, tolerance)
+	case Slack:
+		err = VerifySlack(payload, r.Header.Get(SlackTimestampHeader), r.Header.Get(SlackSignatureHeader), secret, tolerance)
+	default:
+		err = fmt.Errorf("webhooks: unsupported provider %q", provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// SignHeaders returns the headers an outbound webhook for provider must carry
+// for payload, suitable for passing to the -H flags of a gocurl command or to
+// RequestOptions.Headers.
+func SignHeaders(provider Provider, payload []byte, secret string) (http.Header, error) {
+	h := http.Header{}
+	switch provider {
+	case GitHub:
+		h.Set(GitHubSignatureHeader, SignGitHub(payload, secret))
+	case Stripe:
+		h.Set(StripeSignatureHeader, SignStripe(payload, secret, now()))
+	case Slack:
+		ts, sig := SignSlack(payload, secret, now())
+		h.Set(SlackTimestampHeader, ts)
+		h.Set(SlackSignatureHeader, sig)
+	default:
+		return nil, fmt.Errorf("webhooks: unsupported provider %q", provider)
+	}
+	return h, nil
+}
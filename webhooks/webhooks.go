@@ -0,0 +1,180 @@
+// Package webhooks provides helpers to sign and verify webhook payloads for
+// common providers (Stripe, GitHub and Slack).
+//
+// The Verify* functions are meant for inbound webhooks received by an HTTP
+// handler, while the Sign* functions produce the matching headers for
+// outbound webhooks sent with gocurl, which is also handy in tests.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTolerance is the maximum age accepted for timestamped signatures
+// (Stripe and Slack) when no explicit tolerance is given.
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	// ErrInvalidSignature is returned when no signature matches the payload.
+	ErrInvalidSignature = errors.New("webhooks: invalid signature")
+
+	// ErrMalformedHeader is returned when a signature header cannot be parsed.
+	ErrMalformedHeader = errors.New("webhooks: malformed signature header")
+
+	// ErrTimestampOutOfRange is returned when a timestamped signature is older
+	// (or further in the future) than the allowed tolerance.
+	ErrTimestampOutOfRange = errors.New("webhooks: timestamp outside of tolerance")
+)
+
+// now is replaced in tests.
+var now = time.Now
+
+// Header names used by the supported providers.
+const (
+	GitHubSignatureHeader     = "X-Hub-Signature-256"
+	GitHubSignatureHeaderSHA1 = "X-Hub-Signature"
+	StripeSignatureHeader     = "Stripe-Signature"
+	SlackSignatureHeader      = "X-Slack-Signature"
+	SlackTimestampHeader      = "X-Slack-Request-Timestamp"
+)
+
+// SignGitHub returns the X-Hub-Signature-256 header value for payload.
+func SignGitHub(payload []byte, secret string) string {
+	return "sha256=" + hexMAC(sha256.New, secret, payload)
+}
+
+// VerifyGitHub verifies a GitHub webhook signature. The header may be either
+// the X-Hub-Signature-256 value ("sha256=...") or the legacy X-Hub-Signature
+// value ("sha1=...").
+func VerifyGitHub(payload []byte, signatureHeader, secret string) error {
+	algo, sig, ok := strings.Cut(strings.TrimSpace(signatureHeader), "=")
+	if !ok || sig == "" {
+		return ErrMalformedHeader
+	}
+
+	var expected string
+	switch algo {
+	case "sha256":
+		expected = hexMAC(sha256.New, secret, payload)
+	case "sha1":
+		expected = hexMAC(sha1.New, secret, payload)
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrMalformedHeader, algo)
+	}
+
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(sig))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// SignStripe returns the Stripe-Signature header value for payload signed at
+// the given time.
+func SignStripe(payload []byte, secret string, at time.Time) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + ts + ",v1=" + hexMAC(sha256.New, secret, stripeSignedPayload(ts, payload))
+}
+
+// VerifyStripe verifies a Stripe webhook signature header. Any of the v1
+// signatures in the header may match, which supports secret rotation. A zero
+// tolerance uses DefaultTolerance; a negative tolerance disables the
+// timestamp check.
+func VerifyStripe(payload []byte, signatureHeader, secret string, tolerance time.Duration) error {
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(signatureHeader, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			sigs = append(sigs, value)
+		}
+	}
+	if ts == "" || len(sigs) == 0 {
+		return ErrMalformedHeader
+	}
+
+	if err := checkTimestamp(ts, tolerance); err != nil {
+		return err
+	}
+
+	expected := hexMAC(sha256.New, secret, stripeSignedPayload(ts, payload))
+	for _, sig := range sigs {
+		if hmac.Equal([]byte(expected), []byte(sig)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// SignSlack returns the X-Slack-Request-Timestamp and X-Slack-Signature
+// header values for payload signed at the given time.
+func SignSlack(payload []byte, secret string, at time.Time) (timestamp, signature string) {
+	timestamp = strconv.FormatInt(at.Unix(), 10)
+	return timestamp, "v0=" + hexMAC(sha256.New, secret, slackBaseString(timestamp, payload))
+}
+
+// VerifySlack verifies a Slack request signature. A zero tolerance uses
+// DefaultTolerance; a negative tolerance disables the timestamp check.
+func VerifySlack(payload []byte, timestamp, signature, secret string, tolerance time.Duration) error {
+	version, sig, ok := strings.Cut(strings.TrimSpace(signature), "=")
+	if !ok || version != "v0" || sig == "" || timestamp == "" {
+		return ErrMalformedHeader
+	}
+
+	if err := checkTimestamp(timestamp, tolerance); err != nil {
+		return err
+	}
+
+	expected := hexMAC(sha256.New, secret, slackBaseString(timestamp, payload))
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func stripeSignedPayload(ts string, payload []byte) []byte {
+	return append([]byte(ts+"."), payload...)
+}
+
+func slackBaseString(ts string, payload []byte) []byte {
+	return append([]byte("v0:"+ts+":"), payload...)
+}
+
+func checkTimestamp(ts string, tolerance time.Duration) error {
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp %q", ErrMalformedHeader, ts)
+	}
+	if tolerance < 0 {
+		return nil
+	}
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+
+	age := now().Sub(time.Unix(sec, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrTimestampOutOfRange
+	}
+	return nil
+}
+
+func hexMAC(h func() hash.Hash, secret string, data []byte) string {
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
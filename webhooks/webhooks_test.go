@@ -0,0 +1,89 @@
+package webhooks_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl/webhooks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHub(t *testing.T) {
+	// Example taken from GitHub's webhook documentation.
+	payload := []byte("Hello, World!")
+	secret := "It's a Secret to Everybody"
+	sig := "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+
+	assert.Equal(t, sig, webhooks.SignGitHub(payload, secret))
+	assert.NoError(t, webhooks.VerifyGitHub(payload, sig, secret))
+	assert.ErrorIs(t, webhooks.VerifyGitHub([]byte("tampered"), sig, secret), webhooks.ErrInvalidSignature)
+	assert.ErrorIs(t, webhooks.VerifyGitHub(payload, "bogus", secret), webhooks.ErrMalformedHeader)
+}
+
+func TestStripe(t *testing.T) {
+	payload := []byte(`{"id":"evt_1"}`)
+	secret := "whsec_test"
+
+	header := webhooks.SignStripe(payload, secret, time.Now())
+	assert.NoError(t, webhooks.VerifyStripe(payload, header, secret, 0))
+
+	t.Run("Rotated secrets", func(t *testing.T) {
+		other := webhooks.SignStripe(payload, "whsec_old", time.Now())
+		combined := header + "," + other[len("t=1234567890,"):]
+		assert.NoError(t, webhooks.VerifyStripe(payload, combined, "whsec_old", 0))
+	})
+
+	t.Run("Expired timestamp", func(t *testing.T) {
+		old := webhooks.SignStripe(payload, secret, time.Now().Add(-time.Hour))
+		assert.ErrorIs(t, webhooks.VerifyStripe(payload, old, secret, 0), webhooks.ErrTimestampOutOfRange)
+		assert.NoError(t, webhooks.VerifyStripe(payload, old, secret, -1))
+	})
+
+	t.Run("Wrong secret", func(t *testing.T) {
+		assert.ErrorIs(t, webhooks.VerifyStripe(payload, header, "nope", 0), webhooks.ErrInvalidSignature)
+	})
+
+	t.Run("Malformed header", func(t *testing.T) {
+		assert.ErrorIs(t, webhooks.VerifyStripe(payload, "v1=abc", secret, 0), webhooks.ErrMalformedHeader)
+	})
+}
+
+func TestSlack(t *testing.T) {
+	payload := []byte("token=xyz&team_id=T1")
+	secret := "8f742231b10e8888abcd99yyyzzz85a5"
+
+	ts, sig := webhooks.SignSlack(payload, secret, time.Now())
+	assert.NoError(t, webhooks.VerifySlack(payload, ts, sig, secret, 0))
+	assert.ErrorIs(t, webhooks.VerifySlack(payload, ts, "v0=00", secret, 0), webhooks.ErrInvalidSignature)
+	assert.ErrorIs(t, webhooks.VerifySlack(payload, ts, "v1=00", secret, 0), webhooks.ErrMalformedHeader)
+}
+
+func TestVerifyRequest(t *testing.T) {
+	payload := []byte(`{"text":"deployed"}`)
+	secret := "shh"
+
+	for _, provider := range []webhooks.Provider{webhooks.GitHub, webhooks.Stripe, webhooks.Slack} {
+		t.Run(string(provider), func(t *testing.T) {
+			headers, err := webhooks.SignHeaders(provider, payload, secret)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader(payload))
+			for k, v := range headers {
+				req.Header[k] = v
+			}
+
+			body, err := webhooks.VerifyRequest(req, provider, secret, 0)
+			require.NoError(t, err)
+			assert.Equal(t, payload, body)
+
+			// The body remains readable for the handler.
+			again := new(bytes.Buffer)
+			again.ReadFrom(req.Body)
+			assert.Equal(t, payload, again.Bytes())
+		})
+	}
+}
@@ -0,0 +1,279 @@
+// Package webhooktest provides a local HTTPS server capturing the requests
+// it receives, to test code sending outbound webhooks end to end.
+//
+//	srv := webhooktest.NewServer(t, webhooktest.Config{})
+//	opts := options.NewRequestOptions(srv.URL() + "/hooks/slack")
+//	opts.TLSConfig = srv.TLSConfig()
+//	... send the webhook ...
+//	req := srv.Wait(t, time.Second)
+//	req.AssertJSON(t, `{"text":"deployed"}`)
+package webhooktest
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Config customizes a Server.
+type Config struct {
+	// CertFile and KeyFile are the PEM certificate and key served; a
+	// self-signed certificate for 127.0.0.1 and example.com is used when
+	// they are empty
+	CertFile string
+	KeyFile  string
+
+	// Status is the status code of the responses (default 200) and Body
+	// their body
+	Status int
+	Body   string
+
+	// Tunnel, if set, exposes the server publicly, e.g. through ngrok, so
+	// that third-party services can deliver webhooks to it
+	Tunnel Tunnel
+}
+
+// Tunnel exposes a local server on a public URL.
+type Tunnel interface {
+	// Open forwards a public URL to localURL and returns it.
+	Open(localURL string) (publicURL string, err error)
+	// Close stops forwarding.
+	Close() error
+}
+
+// Request is a captured inbound request.
+type Request struct {
+	Time   time.Time
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   []byte
+}
+
+// Server is an HTTPS server capturing the requests it receives.
+type Server struct {
+	server    *httptest.Server
+	config    Config
+	publicURL string
+
+	mu       sync.Mutex
+	requests []Request
+	next     int
+	arrived  chan struct{}
+}
+
+// NewServer starts a server, closed when the test ends. It fails t if the
+// certificate cannot be loaded or the tunnel cannot be opened.
+func NewServer(t testing.TB, config Config) *Server {
+	t.Helper()
+	if config.Status == 0 {
+		config.Status = http.StatusOK
+	}
+	s := &Server{config: config, arrived: make(chan struct{})}
+	s.server = httptest.NewUnstartedServer(http.HandlerFunc(s.handle))
+
+	if config.CertFile != "" || config.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			t.Fatalf("webhooktest: failed to load certificate: %v", err)
+		}
+		s.server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+	s.server.StartTLS()
+	t.Cleanup(s.Close)
+
+	if config.Tunnel != nil {
+		publicURL, err := config.Tunnel.Open(s.server.URL)
+		if err != nil {
+			t.Fatalf("webhooktest: failed to open tunnel: %v", err)
+		}
+		s.publicURL = strings.TrimSuffix(publicURL, "/")
+	}
+	return s
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	req := Request{
+		Time:   time.Now(),
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+		Header: r.Header.Clone(),
+		Body:   body,
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	close(s.arrived)
+	s.arrived = make(chan struct{})
+	s.mu.Unlock()
+
+	w.WriteHeader(s.config.Status)
+	io.WriteString(w, s.config.Body)
+}
+
+// URL returns the base URL webhooks should be sent to: the public URL of
+// the tunnel if there is one, or the local URL.
+func (s *Server) URL() string {
+	if s.publicURL != "" {
+		return s.publicURL
+	}
+	return s.server.URL
+}
+
+// LocalURL returns the https://127.0.0.1:port URL of the server.
+func (s *Server) LocalURL() string {
+	return s.server.URL
+}
+
+// TLSConfig returns a TLS configuration trusting the server certificate, to
+// be set as RequestOptions.TLSConfig.
+func (s *Server) TLSConfig() *tls.Config {
+	return s.server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+}
+
+// Client returns an HTTP client trusting the server certificate.
+func (s *Server) Client() *http.Client {
+	return s.server.Client()
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Reset forgets the requests received so far.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+	s.next = 0
+}
+
+// Next returns the oldest request not yet returned by Next or Wait, waiting
+// up to timeout for one to arrive.
+func (s *Server) Next(timeout time.Duration) (Request, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		if s.next < len(s.requests) {
+			req := s.requests[s.next]
+			s.next++
+			s.mu.Unlock()
+			return req, nil
+		}
+		arrived := s.arrived
+		s.mu.Unlock()
+
+		select {
+		case <-arrived:
+		case <-timer.C:
+			return Request{}, fmt.Errorf("webhooktest: no request received within %s", timeout)
+		}
+	}
+}
+
+// Wait is Next failing t when no request arrives in time.
+func (s *Server) Wait(t testing.TB, timeout time.Duration) Request {
+	t.Helper()
+	req, err := s.Next(timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+// AssertCount reports an error when the server did not receive exactly n
+// requests.
+func (s *Server) AssertCount(t testing.TB, n int) {
+	t.Helper()
+	if got := len(s.Requests()); got != n {
+		t.Errorf("webhooktest: received %d requests, want %d", got, n)
+	}
+}
+
+// Close stops the tunnel, if any, and the server.
+func (s *Server) Close() {
+	if s.config.Tunnel != nil {
+		s.config.Tunnel.Close()
+	}
+	s.server.Close()
+}
+
+// JSON decodes the body into v.
+func (r Request) JSON(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// AssertMethod reports an error when the request method is not method.
+func (r Request) AssertMethod(t testing.TB, method string) {
+	t.Helper()
+	if r.Method != method {
+		t.Errorf("webhooktest: method is %s, want %s", r.Method, method)
+	}
+}
+
+// AssertPath reports an error when the request path is not path.
+func (r Request) AssertPath(t testing.TB, path string) {
+	t.Helper()
+	if r.Path != path {
+		t.Errorf("webhooktest: path is %s, want %s", r.Path, path)
+	}
+}
+
+// AssertHeader reports an error when the request header name is not value.
+func (r Request) AssertHeader(t testing.TB, name, value string) {
+	t.Helper()
+	if got := r.Header.Get(name); got != value {
+		t.Errorf("webhooktest: header %s is %q, want %q", name, got, value)
+	}
+}
+
+// AssertBodyContains reports an error when the body does not contain s.
+func (r Request) AssertBodyContains(t testing.TB, s string) {
+	t.Helper()
+	if !bytes.Contains(r.Body, []byte(s)) {
+		t.Errorf("webhooktest: body %q does not contain %q", r.Body, s)
+	}
+}
+
+// AssertJSON reports an error when the body is not JSON equal to want,
+// ignoring formatting and key order.
+func (r Request) AssertJSON(t testing.TB, want string) {
+	t.Helper()
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(r.Body, &gotValue); err != nil {
+		t.Errorf("webhooktest: body is not JSON: %v", err)
+		return
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Errorf("webhooktest: expected value is not JSON: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("webhooktest: body is %s, want %s", r.Body, want)
+	}
+}
+
+// AssertVerified reports an error when verify, such as a function of the
+// webhooks package, rejects the request.
+func (r Request) AssertVerified(t testing.TB, verify func(header http.Header, body []byte) error) {
+	t.Helper()
+	if err := verify(r.Header, r.Body); err != nil {
+		t.Errorf("webhooktest: request not verified: %v", err)
+	}
+}
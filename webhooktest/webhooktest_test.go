@@ -0,0 +1,153 @@
+package webhooktest_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maniartech/gocurl"
+	"github.com/maniartech/gocurl/options"
+	"github.com/maniartech/gocurl/webhooks"
+	"github.com/maniartech/gocurl/webhooktest"
+)
+
+func TestServer(t *testing.T) {
+	srv := webhooktest.NewServer(t, webhooktest.Config{Status: http.StatusAccepted, Body: "ok"})
+
+	payload := []byte(`{"text": "deployed", "channel": "#ops"}`)
+	timestamp, signature := webhooks.SignSlack(payload, "s3cr3t", time.Now())
+	opts := options.NewRequestOptionsBuilder().
+		POST(srv.URL()+"/hooks/slack?team=1", string(payload), http.Header{
+			"Content-Type":              {"application/json"},
+			"X-Slack-Request-Timestamp": {timestamp},
+			"X-Slack-Signature":         {signature},
+		}).
+		Build()
+	opts.TLSConfig = srv.TLSConfig()
+
+	resp, body, err := gocurl.Process(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusAccepted || body != "ok" {
+		t.Errorf("got %d %q, want the configured response", resp.StatusCode, body)
+	}
+
+	req := srv.Wait(t, time.Second)
+	req.AssertMethod(t, "POST")
+	req.AssertPath(t, "/hooks/slack")
+	req.AssertHeader(t, "Content-Type", "application/json")
+	req.AssertBodyContains(t, "deployed")
+	req.AssertJSON(t, `{"channel":"#ops","text":"deployed"}`)
+	req.AssertVerified(t, func(header http.Header, body []byte) error {
+		return webhooks.VerifySlack(body, header.Get("X-Slack-Request-Timestamp"), header.Get("X-Slack-Signature"), "s3cr3t", 0)
+	})
+	if req.Query != "team=1" {
+		t.Errorf("Query = %q, want team=1", req.Query)
+	}
+	var decoded map[string]string
+	if err := req.JSON(&decoded); err != nil || decoded["channel"] != "#ops" {
+		t.Errorf("JSON() = %v, %v", decoded, err)
+	}
+	srv.AssertCount(t, 1)
+
+	if _, err := srv.Next(10 * time.Millisecond); err == nil {
+		t.Error("Next() returned a request that was already consumed")
+	}
+	srv.Reset()
+	srv.AssertCount(t, 0)
+}
+
+func TestServerWaitsForRequests(t *testing.T) {
+	srv := webhooktest.NewServer(t, webhooktest.Config{})
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		srv.Client().Post(srv.URL(), "text/plain", nil)
+	}()
+	srv.Wait(t, 5*time.Second).AssertMethod(t, "POST")
+}
+
+func TestServerCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCertificate(t, certFile, keyFile)
+
+	srv := webhooktest.NewServer(t, webhooktest.Config{CertFile: certFile, KeyFile: keyFile})
+	resp, err := srv.Client().Get(srv.URL())
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+	if got := resp.TLS.PeerCertificates[0].Subject.CommonName; got != "webhooktest" {
+		t.Errorf("served certificate %q, want the configured one", got)
+	}
+}
+
+// fakeTunnel records how it is used.
+type fakeTunnel struct {
+	local  string
+	closed bool
+}
+
+func (f *fakeTunnel) Open(localURL string) (string, error) {
+	f.local = localURL
+	return "https://hooks.example.com/", nil
+}
+
+func (f *fakeTunnel) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestServerTunnel(t *testing.T) {
+	tunnel := &fakeTunnel{}
+	t.Run("Open", func(t *testing.T) {
+		srv := webhooktest.NewServer(t, webhooktest.Config{Tunnel: tunnel})
+		if srv.URL() != "https://hooks.example.com" {
+			t.Errorf("URL() = %q, want the public URL", srv.URL())
+		}
+		if tunnel.local != srv.LocalURL() {
+			t.Errorf("tunnel forwards to %q, want %q", tunnel.local, srv.LocalURL())
+		}
+	})
+	if !tunnel.closed {
+		t.Error("tunnel was not closed with the server")
+	}
+}
+
+func writeCertificate(t *testing.T, certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "webhooktest"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+}
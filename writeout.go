@@ -0,0 +1,100 @@
+package gocurl
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// FormatWriteOut expands a curl -w format for resp and its body. It supports
+// the \n, \r and \t escapes, %header{name} and these variables:
+//
+//	%{content_type}  %{http_code}  %{response_code}  %{http_version}
+//	%{method}  %{size_download}  %{time_total}  %{url_effective}
+//	%{request_id}  %{tenant}  %{attempt}
+//
+// The last three come from the context of the request; see WithRequestID,
+// WithTenant and AttemptFromContext. Unknown variables expand to nothing.
+func FormatWriteOut(format string, resp *http.Response, body string) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		switch {
+		case c == '\\' && i+1 < len(format) && strings.IndexByte(`nrt\`, format[i+1]) >= 0:
+			i++
+			b.WriteByte(writeOutEscapes[format[i]])
+		case c == '%' && strings.HasPrefix(format[i:], "%%"):
+			i++
+			b.WriteByte('%')
+		case c == '%' && strings.HasPrefix(format[i:], "%{"):
+			end := strings.IndexByte(format[i:], '}')
+			if end < 0 {
+				b.WriteString(format[i:])
+				return b.String()
+			}
+			b.WriteString(writeOutVariable(format[i+2:i+end], resp, body))
+			i += end
+		case c == '%' && strings.HasPrefix(format[i:], "%header{"):
+			end := strings.IndexByte(format[i:], '}')
+			if end < 0 {
+				b.WriteString(format[i:])
+				return b.String()
+			}
+			if resp != nil {
+				b.WriteString(resp.Header.Get(format[i+len("%header{") : i+end]))
+			}
+			i += end
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+var writeOutEscapes = map[byte]byte{'n': '\n', 'r': '\r', 't': '\t', '\\': '\\'}
+
+func writeOutVariable(name string, resp *http.Response, body string) string {
+	if resp == nil {
+		return ""
+	}
+
+	switch name {
+	case "content_type":
+		return resp.Header.Get("Content-Type")
+	case "http_code", "response_code":
+		return fmt.Sprintf("%03d", resp.StatusCode)
+	case "http_version":
+		if resp.ProtoMajor >= 2 {
+			return strconv.Itoa(resp.ProtoMajor)
+		}
+		return fmt.Sprintf("%d.%d", resp.ProtoMajor, resp.ProtoMinor)
+	case "size_download":
+		return strconv.FormatInt(bodySize(resp, body), 10)
+	}
+
+	req := resp.Request
+	if req == nil {
+		return ""
+	}
+	ctx := req.Context()
+	switch name {
+	case "method":
+		return req.Method
+	case "url_effective":
+		return req.URL.String()
+	case "time_total":
+		if state := requestStateFromContext(ctx); state != nil {
+			return fmt.Sprintf("%.6f", state.total.Seconds())
+		}
+	case "request_id":
+		return RequestIDFromContext(ctx)
+	case "tenant":
+		return TenantFromContext(ctx)
+	case "attempt":
+		if attempt := AttemptFromContext(ctx); attempt > 0 {
+			return strconv.Itoa(attempt)
+		}
+	}
+	return ""
+}
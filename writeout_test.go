@@ -0,0 +1,50 @@
+package gocurl_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maniartech/gocurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatWriteOut(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Trace", "abc")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	ctx := gocurl.WithTenant(gocurl.WithRequestID(context.Background(), "req-1"), "acme")
+	resp, body, err := gocurl.CurlString(ctx, "curl", "-X", "POST", server.URL)
+	require.NoError(t, err)
+
+	tests := []struct {
+		format, expected string
+	}{
+		{`%{http_code} %{content_type}\n`, "201 text/plain\n"},
+		{`%{method} %{url_effective}`, "POST " + server.URL},
+		{`%{size_download} bytes, HTTP/%{http_version}`, "5 bytes, HTTP/1.1"},
+		{`%{request_id}/%{tenant}/%{attempt}`, "req-1/acme/1"},
+		{`%header{x-trace} 100%% %{unknown}`, "abc 100% "},
+		{`unterminated %{http_code`, "unterminated %{http_code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			assert.Equal(t, tt.expected, gocurl.FormatWriteOut(tt.format, resp, body))
+		})
+	}
+
+	assert.Regexp(t, `^\d+\.\d{6}$`, gocurl.FormatWriteOut("%{time_total}", resp, body))
+}
+
+func TestWriteOutFlag(t *testing.T) {
+	opts, err := gocurl.ArgsToOptions([]string{"curl", "-w", "%{http_code}", "https://example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "%{http_code}", opts.WriteOut)
+}